@@ -18,6 +18,7 @@ package bind
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ur-technology/go-ur"
@@ -41,6 +42,41 @@ var (
 	ErrNoCodeAfterDeploy = errors.New("no contract code after deployment")
 )
 
+// NotDeployedError is returned by generated contract bindings in place of
+// ErrNoCode, detailing which contract and method was invoked on an address
+// without any code deployed.
+type NotDeployedError struct {
+	Contract string         // Type name of the contract binding
+	Method   string         // Invoked contract method (empty for plain transfers)
+	Address  common.Address // Address the contract was expected at
+}
+
+func (err *NotDeployedError) Error() string {
+	if err.Method == "" {
+		return fmt.Sprintf("%s: %v (%x)", err.Contract, ErrNoCode, err.Address)
+	}
+	return fmt.Sprintf("%s.%s: %v (%x)", err.Contract, err.Method, ErrNoCode, err.Address)
+}
+
+// WrapNoCode converts an ErrNoCode into a NotDeployedError describing the failed
+// invocation, leaving any other error untouched.
+func WrapNoCode(err error, contract, method string, address common.Address) error {
+	if err == ErrNoCode {
+		return &NotDeployedError{Contract: contract, Method: method, Address: address}
+	}
+	return err
+}
+
+// IsNotDeployed reports whether err signals that there is no contract code at
+// the requested address, either as a plain ErrNoCode or a NotDeployedError.
+func IsNotDeployed(err error) bool {
+	if err == ErrNoCode {
+		return true
+	}
+	_, ok := err.(*NotDeployedError)
+	return ok
+}
+
 // ContractCaller defines the methods needed to allow operating with contract on a read
 // only basis.
 type ContractCaller interface {
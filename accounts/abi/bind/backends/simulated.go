@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"time"
 
 	"github.com/ur-technology/go-ur"
 	"github.com/ur-technology/go-ur/accounts/abi/bind"
@@ -29,44 +30,124 @@ import (
 	"github.com/ur-technology/go-ur/core/state"
 	"github.com/ur-technology/go-ur/core/types"
 	"github.com/ur-technology/go-ur/core/vm"
+	"github.com/ur-technology/go-ur/eth/filters"
 	"github.com/ur-technology/go-ur/ethdb"
 	"github.com/ur-technology/go-ur/event"
 	"github.com/ur-technology/go-ur/params"
+	"github.com/ur-technology/go-ur/rpc"
 	"golang.org/x/net/context"
 )
 
-// Default chain configuration which sets homestead phase at block 0 (i.e. no frontier)
-var chainConfig = &params.ChainConfig{HomesteadBlock: big.NewInt(0), EIP150Block: new(big.Int), EIP158Block: new(big.Int)}
+// Default chain configuration which sets homestead phase at block 0 (i.e. no
+// frontier) and activates the UR signups precompiled contract from genesis.
+var chainConfig = &params.ChainConfig{
+	HomesteadBlock: big.NewInt(0),
+	EIP150Block:    new(big.Int),
+	EIP158Block:    new(big.Int),
+	Precompiles:    map[string]*big.Int{params.URSignupsPrecompile: new(big.Int)},
+}
 
-// This nil assignment ensures compile time that SimulatedBackend implements bind.ContractBackend.
-var _ bind.ContractBackend = (*SimulatedBackend)(nil)
+// These nil assignments ensure compile time that SimulatedBackend implements
+// bind.ContractBackend and the full client surface of package ethereum.
+var (
+	_ bind.ContractBackend           = (*SimulatedBackend)(nil)
+	_ ethereum.ChainReader           = (*SimulatedBackend)(nil)
+	_ ethereum.ChainStateReader      = (*SimulatedBackend)(nil)
+	_ ethereum.ChainSyncReader       = (*SimulatedBackend)(nil)
+	_ ethereum.ChainHeadEventer      = (*SimulatedBackend)(nil)
+	_ ethereum.LogFilterer           = (*SimulatedBackend)(nil)
+	_ ethereum.PendingStateReader    = (*SimulatedBackend)(nil)
+	_ ethereum.PendingContractCaller = (*SimulatedBackend)(nil)
+	_ ethereum.PendingStateEventer   = (*SimulatedBackend)(nil)
+	_ ethereum.GasEstimator          = (*SimulatedBackend)(nil)
+)
 
-var errBlockNumberUnsupported = errors.New("SimulatedBackend cannot access blocks other than the latest block")
+var (
+	errBlockNotFound       = errors.New("block not found")
+	errTransactionNotFound = errors.New("transaction not found")
+	errUnknownSnapshot     = errors.New("unknown snapshot")
+	errSnapshotReorged     = errors.New("snapshot head no longer canonical")
+)
 
 // SimulatedBackend implements bind.ContractBackend, simulating a blockchain in
 // the background. Its main purpose is to allow easily testing contract bindings.
 type SimulatedBackend struct {
-	database   ethdb.Database   // In memory database to store our testing data
-	blockchain *core.BlockChain // Ethereum blockchain to handle the consensus
+	database   ethdb.Database       // In memory database to store our testing data
+	blockchain *core.BlockChain     // Ethereum blockchain to handle the consensus
+	mux        *event.TypeMux       // Event mux the blockchain reports its events on
+	events     *filters.EventSystem // Event system to serve log subscriptions
+	pendingMux *event.TypeMux       // Event mux to report pending transactions on
 
 	mu           sync.Mutex
+	parentBlock  *types.Block   // Block the pending block is built on top of
 	pendingBlock *types.Block   // Currently pending block that will be imported on request
 	pendingState *state.StateDB // Currently pending state that will be the active on on request
+	timeOffset   int64          // Seconds to shift the timestamp of the pending block by
+
+	snapshots []*simSnapshot // Snapshots taken of the chain, indexed by id
 
 	config *params.ChainConfig
 }
 
+// simSnapshot is the state of the simulated chain at the time a snapshot was
+// taken.
+type simSnapshot struct {
+	parent     *types.Block         // Block the pending block was built on
+	txs        []*types.Transaction // Pending transactions at the snapshot
+	timeOffset int64                // Pending time adjustment at the snapshot
+}
+
 // NewSimulatedBackend creates a new binding backend using a simulated blockchain
 // for testing purposes.
 func NewSimulatedBackend(accounts ...core.GenesisAccount) *SimulatedBackend {
+	return NewSimulatedBackendWithPrivileged(nil, accounts...)
+}
+
+// NewSimulatedBackendWithPrivileged creates a new simulated binding backend whose
+// chain accepts signups from the given privileged addresses, or from the ones of
+// the main network if nil.
+func NewSimulatedBackendWithPrivileged(privileged map[common.Address]params.PrivilegedReceivers, accounts ...core.GenesisAccount) *SimulatedBackend {
+	config := *chainConfig
+	config.Privileged = privileged
+
 	database, _ := ethdb.NewMemDatabase()
 	core.WriteGenesisBlockForTesting(database, accounts...)
-	blockchain, _ := core.NewBlockChain(database, chainConfig, new(core.FakePow), new(event.TypeMux))
-	backend := &SimulatedBackend{database: database, blockchain: blockchain}
+
+	mux := new(event.TypeMux)
+	blockchain, _ := core.NewBlockChain(database, &config, new(core.FakePow), mux)
+
+	backend := &SimulatedBackend{
+		database:   database,
+		blockchain: blockchain,
+		mux:        mux,
+		pendingMux: new(event.TypeMux),
+		config:     &config,
+	}
+	backend.events = filters.NewEventSystem(mux, &filterBackend{database, blockchain, mux}, false)
+	backend.parentBlock = blockchain.CurrentBlock()
 	backend.rollback()
 	return backend
 }
 
+// PrivilegedAccounts returns genesis allocations funding every privileged
+// signup address of the given privileged set (the main network's if nil) with
+// the given balance, allowing signup transactions to be issued from the
+// simulated chain's genesis.
+func PrivilegedAccounts(privileged map[common.Address]params.PrivilegedReceivers, balance *big.Int) []core.GenesisAccount {
+	config := &params.ChainConfig{Privileged: privileged}
+
+	var accounts []core.GenesisAccount
+	for _, addr := range config.PrivilegedAddresses() {
+		accounts = append(accounts, core.GenesisAccount{Address: addr, Balance: new(big.Int).Set(balance)})
+	}
+	return accounts
+}
+
+// Blockchain returns the underlying blockchain of the simulated backend.
+func (b *SimulatedBackend) Blockchain() *core.BlockChain {
+	return b.blockchain
+}
+
 // Commit imports all the pending transactions as a single block and starts a
 // fresh new state.
 func (b *SimulatedBackend) Commit() {
@@ -76,6 +157,7 @@ func (b *SimulatedBackend) Commit() {
 	if _, err := b.blockchain.InsertChain([]*types.Block{b.pendingBlock}); err != nil {
 		panic(err) // This cannot happen unless the simulator is wrong, fail in that case
 	}
+	b.parentBlock, b.timeOffset = b.pendingBlock, 0
 	b.rollback()
 }
 
@@ -88,20 +170,119 @@ func (b *SimulatedBackend) Rollback() {
 }
 
 func (b *SimulatedBackend) rollback() {
-	blocks, _ := core.GenerateChain(chainConfig, b.blockchain, b.blockchain.CurrentBlock(), b.database, 1, func(int, *core.BlockGen) {})
+	b.generatePending(nil)
+}
+
+// generatePending regenerates the pending block on top of the current parent
+// block with the given transactions.
+func (b *SimulatedBackend) generatePending(txs []*types.Transaction) {
+	blocks, _ := core.GenerateChain(b.config, b.blockchain, b.parentBlock, b.database, 1, func(number int, block *core.BlockGen) {
+		if b.timeOffset != 0 {
+			block.OffsetTime(b.timeOffset)
+		}
+		for _, tx := range txs {
+			block.AddTx(tx)
+		}
+	})
 	b.pendingBlock = blocks[0]
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), b.database)
 }
 
+// AdjustTime shifts the timestamp of the pending block by the given amount,
+// keeping any pending transactions. The adjustment is reset on Commit.
+func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	offset := b.timeOffset + int64(adjustment/time.Second)
+	if new(big.Int).Add(b.parentBlock.Time(), big.NewInt(10+offset)).Cmp(b.parentBlock.Time()) <= 0 {
+		return fmt.Errorf("pending block time would precede its parent")
+	}
+	b.timeOffset = offset
+	b.generatePending(b.pendingBlock.Transactions())
+	return nil
+}
+
+// Fork drops all pending transactions and starts building the pending block on
+// top of the given, already imported block. Committing enough blocks on top of
+// it makes the fork the canonical chain.
+func (b *SimulatedBackend) Fork(parent common.Hash) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	block := b.blockchain.GetBlockByHash(parent)
+	if block == nil {
+		return errBlockNotFound
+	}
+	b.parentBlock, b.timeOffset = block, 0
+	b.rollback()
+	return nil
+}
+
+// Snapshot records the current chain head and pending transactions, returning
+// an identifier that can be used to revert to this point via RevertToSnapshot.
+func (b *SimulatedBackend) Snapshot() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.snapshots = append(b.snapshots, &simSnapshot{
+		parent:     b.parentBlock,
+		txs:        b.pendingBlock.Transactions(),
+		timeOffset: b.timeOffset,
+	})
+	return len(b.snapshots) - 1
+}
+
+// RevertToSnapshot rewinds the chain to the head recorded by the given snapshot
+// and restores its pending transactions. Snapshots taken after the given one
+// are invalidated.
+func (b *SimulatedBackend) RevertToSnapshot(id int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if id < 0 || id >= len(b.snapshots) {
+		return errUnknownSnapshot
+	}
+	snap := b.snapshots[id]
+
+	number := snap.parent.NumberU64()
+	if core.GetCanonicalHash(b.database, number) != snap.parent.Hash() {
+		return errSnapshotReorged
+	}
+	if b.blockchain.CurrentBlock().NumberU64() > number {
+		b.blockchain.SetHead(number)
+	}
+	b.snapshots = b.snapshots[:id]
+	b.parentBlock, b.timeOffset = snap.parent, snap.timeOffset
+	b.generatePending(snap.txs)
+	return nil
+}
+
+// stateByNumber retrieves the block and state at the given canonical block
+// number, or the latest one if number is nil.
+func (b *SimulatedBackend) stateByNumber(number *big.Int) (*types.Block, *state.StateDB, error) {
+	block := b.blockchain.CurrentBlock()
+	if number != nil {
+		if block = b.blockchain.GetBlockByNumber(number.Uint64()); block == nil {
+			return nil, nil, errBlockNotFound
+		}
+	}
+	statedb, err := b.blockchain.StateAt(block.Root())
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, statedb, nil
+}
+
 // CodeAt returns the code associated with a certain account in the blockchain.
 func (b *SimulatedBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	_, statedb, err := b.stateByNumber(blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	statedb, _ := b.blockchain.State()
 	return statedb.GetCode(contract), nil
 }
 
@@ -110,10 +291,10 @@ func (b *SimulatedBackend) BalanceAt(ctx context.Context, contract common.Addres
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	_, statedb, err := b.stateByNumber(blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	statedb, _ := b.blockchain.State()
 	return statedb.GetBalance(contract), nil
 }
 
@@ -122,10 +303,10 @@ func (b *SimulatedBackend) NonceAt(ctx context.Context, contract common.Address,
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return 0, errBlockNumberUnsupported
+	_, statedb, err := b.stateByNumber(blockNumber)
+	if err != nil {
+		return 0, err
 	}
-	statedb, _ := b.blockchain.State()
 	return statedb.GetNonce(contract), nil
 }
 
@@ -134,19 +315,106 @@ func (b *SimulatedBackend) StorageAt(ctx context.Context, contract common.Addres
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	_, statedb, err := b.stateByNumber(blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	statedb, _ := b.blockchain.State()
 	val := statedb.GetState(contract, key)
 	return val[:], nil
 }
 
-// TransactionReceipt returns the receipt of a transaction.
+// BlockByHash retrieves a block based on the block hash.
+func (b *SimulatedBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	if block := b.blockchain.GetBlockByHash(hash); block != nil {
+		return block, nil
+	}
+	return nil, errBlockNotFound
+}
+
+// BlockByNumber retrieves a canonical block by its number, or the latest one if
+// number is nil.
+func (b *SimulatedBackend) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	if number == nil {
+		return b.blockchain.CurrentBlock(), nil
+	}
+	if block := b.blockchain.GetBlockByNumber(number.Uint64()); block != nil {
+		return block, nil
+	}
+	return nil, errBlockNotFound
+}
+
+// HeaderByHash retrieves a header based on the block hash.
+func (b *SimulatedBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	if header := b.blockchain.GetHeaderByHash(hash); header != nil {
+		return header, nil
+	}
+	return nil, errBlockNotFound
+}
+
+// HeaderByNumber retrieves a canonical header by its number, or the latest one
+// if number is nil.
+func (b *SimulatedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		return b.blockchain.CurrentHeader(), nil
+	}
+	if header := b.blockchain.GetHeaderByNumber(number.Uint64()); header != nil {
+		return header, nil
+	}
+	return nil, errBlockNotFound
+}
+
+// TransactionCount returns the number of transactions in the given block.
+func (b *SimulatedBackend) TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error) {
+	block, err := b.BlockByHash(ctx, blockHash)
+	if err != nil {
+		return 0, err
+	}
+	return uint(len(block.Transactions())), nil
+}
+
+// TransactionInBlock returns the transaction at the given index in the block.
+func (b *SimulatedBackend) TransactionInBlock(ctx context.Context, blockHash common.Hash, index uint) (*types.Transaction, error) {
+	block, err := b.BlockByHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if index >= uint(len(txs)) {
+		return nil, errTransactionNotFound
+	}
+	return txs[index], nil
+}
+
+// TransactionByHash returns the transaction with the given hash, either from
+// the pending block or from the canonical chain.
+func (b *SimulatedBackend) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if tx := b.pendingBlock.Transaction(txHash); tx != nil {
+		return tx, nil
+	}
+	if tx, blockHash, number, _ := core.GetTransaction(b.database, txHash); tx != nil && core.GetCanonicalHash(b.database, number) == blockHash {
+		return tx, nil
+	}
+	return nil, errTransactionNotFound
+}
+
+// TransactionReceipt returns the receipt of a transaction included in the
+// canonical chain.
 func (b *SimulatedBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if _, blockHash, number, _ := core.GetTransaction(b.database, txHash); core.GetCanonicalHash(b.database, number) != blockHash {
+		return nil, nil
+	}
 	return core.GetReceipt(b.database, txHash), nil
 }
 
+// SyncProgress implements ethereum.ChainSyncReader. The simulated chain never
+// synchronises, so no progress is ever reported.
+func (b *SimulatedBackend) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, error) {
+	return nil, nil
+}
+
 // PendingCodeAt returns the code associated with an account in the pending state.
 func (b *SimulatedBackend) PendingCodeAt(ctx context.Context, contract common.Address) ([]byte, error) {
 	b.mu.Lock()
@@ -155,19 +423,42 @@ func (b *SimulatedBackend) PendingCodeAt(ctx context.Context, contract common.Ad
 	return b.pendingState.GetCode(contract), nil
 }
 
+// PendingBalanceAt returns the wei balance of an account in the pending state.
+func (b *SimulatedBackend) PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.pendingState.GetBalance(account), nil
+}
+
+// PendingStorageAt returns the value of key in the storage of an account in the
+// pending state.
+func (b *SimulatedBackend) PendingStorageAt(ctx context.Context, account common.Address, key common.Hash) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	val := b.pendingState.GetState(account, key)
+	return val[:], nil
+}
+
+// PendingTransactionCount returns the number of transactions in the pending block.
+func (b *SimulatedBackend) PendingTransactionCount(ctx context.Context) (uint, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return uint(len(b.pendingBlock.Transactions())), nil
+}
+
 // CallContract executes a contract call.
 func (b *SimulatedBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
-	}
-	state, err := b.blockchain.State()
+	block, statedb, err := b.stateByNumber(blockNumber)
 	if err != nil {
 		return nil, err
 	}
-	rval, _, err := b.callContract(ctx, call, b.blockchain.CurrentBlock(), state)
+	rval, _, err := b.callContract(ctx, call, block, statedb)
 	return rval, err
 }
 
@@ -225,7 +516,7 @@ func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallM
 	from.SetBalance(common.MaxBig)
 	// Execute the call.
 	msg := callmsg{call}
-	vmenv := core.NewEnv(statedb, chainConfig, b.blockchain, msg, block.Header(), vm.Config{})
+	vmenv := core.NewEnv(statedb, b.config, b.blockchain, msg, block.Header(), vm.Config{})
 	gaspool := new(core.GasPool).AddGas(common.MaxBig)
 	ret, gasUsed, _, err := core.NewStateTransition(vmenv, msg, gaspool).TransitionDb()
 	return ret, gasUsed, err
@@ -245,18 +536,116 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 	if tx.Nonce() != nonce {
 		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
 	}
+	b.generatePending(append(b.pendingBlock.Transactions(), tx))
 
-	blocks, _ := core.GenerateChain(chainConfig, b.blockchain, b.blockchain.CurrentBlock(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTx(tx)
-		}
-		block.AddTx(tx)
-	})
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), b.database)
+	go b.pendingMux.Post(core.TxPreEvent{Tx: tx})
 	return nil
 }
 
+// FilterLogs executes a log filter operation on the canonical chain, blocking
+// during execution and returning all the results in one batch.
+func (b *SimulatedBackend) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]vm.Log, error) {
+	filter := filters.New(&filterBackend{b.database, b.blockchain, b.mux}, false)
+
+	from, to := int64(0), int64(rpc.LatestBlockNumber)
+	if query.FromBlock != nil {
+		from = query.FromBlock.Int64()
+	}
+	if query.ToBlock != nil {
+		to = query.ToBlock.Int64()
+	}
+	filter.SetBeginBlock(from)
+	filter.SetEndBlock(to)
+	filter.SetAddresses(query.Addresses)
+	filter.SetTopics(query.Topics)
+
+	logs, err := filter.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]vm.Log, len(logs))
+	for i, log := range logs {
+		res[i] = *log.Log
+	}
+	return res, nil
+}
+
+// SubscribeFilterLogs creates a background log filtering operation, returning a
+// subscription immediately, which can be used to stream the found events.
+func (b *SimulatedBackend) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- vm.Log) (ethereum.Subscription, error) {
+	sink := make(chan []filters.Log)
+	sub, err := b.events.SubscribeLogs(filters.FilterCriteria{
+		FromBlock: query.FromBlock,
+		ToBlock:   query.ToBlock,
+		Addresses: query.Addresses,
+		Topics:    query.Topics,
+	}, sink)
+	if err != nil {
+		return nil, err
+	}
+	return newSubscription(sub.Unsubscribe, func(quit <-chan struct{}) {
+		for {
+			select {
+			case logs := <-sink:
+				for _, log := range logs {
+					select {
+					case ch <- *log.Log:
+					case <-quit:
+						return
+					}
+				}
+			case <-quit:
+				return
+			}
+		}
+	}), nil
+}
+
+// SubscribeNewHead streams the headers of the new canonical chain heads.
+func (b *SimulatedBackend) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	sub := b.mux.Subscribe(core.ChainHeadEvent{})
+	return newSubscription(sub.Unsubscribe, func(quit <-chan struct{}) {
+		for {
+			select {
+			case ev, ok := <-sub.Chan():
+				if !ok {
+					return
+				}
+				select {
+				case ch <- ev.Data.(core.ChainHeadEvent).Block.Header():
+				case <-quit:
+					return
+				}
+			case <-quit:
+				return
+			}
+		}
+	}), nil
+}
+
+// SubscribePendingTransactions streams the transactions added to the pending
+// block.
+func (b *SimulatedBackend) SubscribePendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (ethereum.Subscription, error) {
+	sub := b.pendingMux.Subscribe(core.TxPreEvent{})
+	return newSubscription(sub.Unsubscribe, func(quit <-chan struct{}) {
+		for {
+			select {
+			case ev, ok := <-sub.Chan():
+				if !ok {
+					return
+				}
+				select {
+				case ch <- ev.Data.(core.TxPreEvent).Tx:
+				case <-quit:
+					return
+				}
+			case <-quit:
+				return
+			}
+		}
+	}), nil
+}
+
 // callmsg implements core.Message to allow passing it as a transaction simulator.
 type callmsg struct {
 	ethereum.CallMsg
@@ -270,3 +659,68 @@ func (m callmsg) GasPrice() *big.Int   { return m.CallMsg.GasPrice }
 func (m callmsg) Gas() *big.Int        { return m.CallMsg.Gas }
 func (m callmsg) Value() *big.Int      { return m.CallMsg.Value }
 func (m callmsg) Data() []byte         { return m.CallMsg.Data }
+
+// filterBackend implements filters.Backend to support log filtering on top of
+// the simulated chain.
+type filterBackend struct {
+	db  ethdb.Database
+	bc  *core.BlockChain
+	mux *event.TypeMux
+}
+
+func (fb *filterBackend) ChainDb() ethdb.Database          { return fb.db }
+func (fb *filterBackend) EventMux() *event.TypeMux         { return fb.mux }
+func (fb *filterBackend) ChainConfig() *params.ChainConfig { return fb.bc.Config() }
+
+func (fb *filterBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return fb.bc.CurrentHeader(), nil
+	}
+	return fb.bc.GetHeaderByNumber(uint64(number.Int64())), nil
+}
+
+func (fb *filterBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	header := fb.bc.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, nil
+	}
+	return core.GetBlockReceipts(fb.db, hash, header.Number.Uint64()), nil
+}
+
+// subscription implements ethereum.Subscription on top of a goroutine feeding
+// events into a user supplied channel.
+type subscription struct {
+	quit  chan struct{}
+	err   chan error
+	unsub func()
+	once  sync.Once
+}
+
+// newSubscription starts the given feed loop in the background, which must
+// return once quit is closed. The unsub callback releases the event source.
+func newSubscription(unsub func(), feed func(quit <-chan struct{})) *subscription {
+	sub := &subscription{
+		quit:  make(chan struct{}),
+		err:   make(chan error),
+		unsub: unsub,
+	}
+	go func() {
+		defer close(sub.err)
+		feed(sub.quit)
+	}()
+	return sub
+}
+
+// Unsubscribe stops feeding events and closes the error channel.
+func (sub *subscription) Unsubscribe() {
+	sub.once.Do(func() {
+		close(sub.quit)
+		sub.unsub()
+		<-sub.err
+	})
+}
+
+// Err returns the subscription error channel, which is closed on unsubscribe.
+func (sub *subscription) Err() <-chan error {
+	return sub.err
+}
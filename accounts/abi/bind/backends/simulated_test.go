@@ -0,0 +1,169 @@
+// Copyright 2016 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ur-technology/go-ur"
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/core/vm"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/params"
+	"golang.org/x/net/context"
+)
+
+// logCode deploys a contract emitting a single LOG1 with topic 1 on every call.
+var logCode = common.Hex2Bytes("600d600c600039600d6000f3" + "602a60005260016020600" + "0a100")
+
+// Tests that logs and transactions of the simulated chain are reachable through
+// the ethclient style accessors and subscriptions.
+func TestSimulatedLogs(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	sim := NewSimulatedBackend(core.GenesisAccount{Address: addr, Balance: big.NewInt(10000000000)})
+	ctx := context.Background()
+
+	deploy, _ := types.NewContractCreation(0, new(big.Int), big.NewInt(100000), big.NewInt(1), logCode).SignECDSA(types.HomesteadSigner{}, key)
+	sim.SendTransaction(ctx, deploy)
+	sim.Commit()
+
+	receipt, err := sim.TransactionReceipt(ctx, deploy.Hash())
+	if err != nil || receipt == nil {
+		t.Fatalf("failed to retrieve deployment receipt: %v", err)
+	}
+	contract := receipt.ContractAddress
+
+	logs := make(chan vm.Log, 1)
+	sub, err := sim.SubscribeFilterLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{contract}}, logs)
+	if err != nil {
+		t.Fatalf("failed to subscribe to logs: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	call, _ := types.NewTransaction(1, contract, new(big.Int), big.NewInt(100000), big.NewInt(1), nil).SignECDSA(types.HomesteadSigner{}, key)
+	sim.SendTransaction(ctx, call)
+	if tx, err := sim.TransactionByHash(ctx, call.Hash()); err != nil || tx.Hash() != call.Hash() {
+		t.Fatalf("pending transaction mismatch: have %v, want %x", err, call.Hash())
+	}
+	if n, _ := sim.PendingTransactionCount(ctx); n != 1 {
+		t.Fatalf("pending transaction count mismatch: have %d, want %d", n, 1)
+	}
+	sim.Commit()
+
+	select {
+	case log := <-logs:
+		if log.Address != contract || len(log.Topics) != 1 || log.Topics[0] != common.BigToHash(big.NewInt(1)) {
+			t.Errorf("subscribed log mismatch: have %v", log)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for log")
+	}
+	found, err := sim.FilterLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{contract}})
+	if err != nil {
+		t.Fatalf("failed to filter logs: %v", err)
+	}
+	if len(found) != 1 || found[0].TxHash != call.Hash() {
+		t.Fatalf("filtered logs mismatch: have %v", found)
+	}
+	if tx, err := sim.TransactionByHash(ctx, call.Hash()); err != nil || tx.Hash() != call.Hash() {
+		t.Fatalf("mined transaction mismatch: have %v, want %x", err, call.Hash())
+	}
+	header, err := sim.HeaderByNumber(ctx, nil)
+	if err != nil || header.Number.Uint64() != 2 {
+		t.Fatalf("head header mismatch: have %v, want number %d", err, 2)
+	}
+	block, err := sim.BlockByNumber(ctx, big.NewInt(2))
+	if err != nil || block.Hash() != header.Hash() || len(block.Transactions()) != 1 {
+		t.Fatalf("block mismatch: have %v, want %x", err, header.Hash())
+	}
+	if code, err := sim.CodeAt(ctx, contract, big.NewInt(0)); err != nil || len(code) != 0 {
+		t.Errorf("historical code mismatch: have %x, %v, want none", code, err)
+	}
+}
+
+// Tests that time can be adjusted and the chain rewound to a snapshot.
+func TestSimulatedTimeAndSnapshots(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	sim := NewSimulatedBackend(core.GenesisAccount{Address: addr, Balance: big.NewInt(10000000000)})
+	ctx := context.Background()
+
+	parent, _ := sim.HeaderByNumber(ctx, nil)
+	if err := sim.AdjustTime(time.Hour); err != nil {
+		t.Fatalf("failed to adjust time: %v", err)
+	}
+	sim.Commit()
+	head, _ := sim.HeaderByNumber(ctx, nil)
+	if diff := new(big.Int).Sub(head.Time, parent.Time); diff.Int64() < 3600 {
+		t.Fatalf("time adjustment mismatch: have %v, want at least %d", diff, 3600)
+	}
+	id := sim.Snapshot()
+
+	tx, _ := types.NewTransaction(0, common.Address{1}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil).SignECDSA(types.HomesteadSigner{}, key)
+	sim.SendTransaction(ctx, tx)
+	sim.Commit()
+	if nonce, _ := sim.NonceAt(ctx, addr, nil); nonce != 1 {
+		t.Fatalf("nonce mismatch: have %d, want %d", nonce, 1)
+	}
+	if err := sim.RevertToSnapshot(id); err != nil {
+		t.Fatalf("failed to revert to snapshot: %v", err)
+	}
+	if header, _ := sim.HeaderByNumber(ctx, nil); header.Hash() != head.Hash() {
+		t.Fatalf("head mismatch after revert: have %x, want %x", header.Hash(), head.Hash())
+	}
+	if receipt, _ := sim.TransactionReceipt(ctx, tx.Hash()); receipt != nil {
+		t.Errorf("reverted transaction still has a receipt")
+	}
+	if nonce, _ := sim.NonceAt(ctx, addr, nil); nonce != 0 {
+		t.Errorf("nonce mismatch after revert: have %d, want %d", nonce, 0)
+	}
+	if err := sim.RevertToSnapshot(id); err != errUnknownSnapshot {
+		t.Errorf("reverting to a released snapshot: have %v, want %v", err, errUnknownSnapshot)
+	}
+}
+
+// Tests that signup transactions sent through the simulated backend are
+// rewarded the same way as on the live network.
+func TestSimulatedSignup(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	privileged := map[common.Address]params.PrivilegedReceivers{
+		addr: {Receiver: common.Address{0xaa}, URFF: common.Address{0xbb}},
+	}
+	sim := NewSimulatedBackendWithPrivileged(privileged, PrivilegedAccounts(privileged, common.Ether)...)
+	ctx := context.Background()
+
+	member := common.Address{0x01}
+	tx, _ := types.NewTransaction(0, member, big.NewInt(1), big.NewInt(100000), big.NewInt(1), core.SignupData(0, common.Hash{})).SignECDSA(types.HomesteadSigner{}, key)
+	sim.SendTransaction(ctx, tx)
+	sim.Commit()
+
+	if balance, _ := sim.BalanceAt(ctx, member, nil); balance.Cmp(core.SignupReward) != 0 {
+		t.Errorf("member balance mismatch: have %v, want %v", balance, core.SignupReward)
+	}
+	if header, _ := sim.HeaderByNumber(ctx, nil); header.NSignups.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("signup count mismatch: have %v, want %d", header.NSignups, 1)
+	}
+}
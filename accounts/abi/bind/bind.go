@@ -94,10 +94,31 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 				transacts[original.Name] = &tmplMethod{Original: original, Normalized: normalized, Structured: structured(original)}
 			}
 		}
+		// Gather the libraries the bytecode needs to be linked against
+		placeholders, err := LibraryPlaceholders(bytecodes[i])
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", types[i], err)
+		}
+		if len(placeholders) > 0 && lang != LangGo {
+			return "", fmt.Errorf("%s: library linking is only supported for Go bindings", types[i])
+		}
+		var (
+			libraries = make([]*tmplLibrary, len(placeholders))
+			params    = make(map[string]bool)
+		)
+		for j, placeholder := range placeholders {
+			param := decapitalise(libraryIdentifier(placeholder)) + "Addr"
+			if params[param] {
+				param = fmt.Sprintf("%s%d", param, j)
+			}
+			params[param] = true
+			libraries[j] = &tmplLibrary{Placeholder: placeholder, Param: param}
+		}
 		contracts[types[i]] = &tmplContract{
 			Type:        capitalise(types[i]),
 			InputABI:    strings.Replace(strippedABI, "\"", "\\\"", -1),
 			InputBin:    strings.TrimSpace(bytecodes[i]),
+			Libraries:   libraries,
 			Constructor: evmABI.Constructor,
 			Calls:       calls,
 			Transacts:   transacts,
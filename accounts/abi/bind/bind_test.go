@@ -360,8 +360,44 @@ var bindTests = []struct {
 			// Ensure that contract calls fail with the appropriate error
 			if res, err := nonexistent.String(nil); err == nil {
 				t.Fatalf("Call succeeded on non-existent contract: %v", res)
-			} else if (err != bind.ErrNoCode) {
-				t.Fatalf("Error mismatch: have %v, want %v", err, bind.ErrNoCode)
+			} else if nodeploy, ok := err.(*bind.NotDeployedError); !ok {
+				t.Fatalf("Error mismatch: have %v, want %T", err, nodeploy)
+			} else if nodeploy.Contract != "NonExistent" || nodeploy.Method != "String" {
+				t.Fatalf("Error details mismatch: have %s.%s, want %s.%s", nodeploy.Contract, nodeploy.Method, "NonExistent", "String")
+			}
+		`,
+	},
+	// Tests that library placeholders in the bytecode are linked on deployment
+	{
+		`Linked`,
+		`
+		library Math {}
+
+		contract Linked {
+			function Linked() {
+				Math;
+			}
+		}
+		`,
+		`606060405260068060106000396000f360606040520073__lib.sol:Math__________________________`,
+		`[]`,
+		`
+			// Generate a new random account and a funded simulator
+			key, _ := crypto.GenerateKey()
+			auth := bind.NewKeyedTransactor(key)
+			sim := backends.NewSimulatedBackend(core.GenesisAccount{Address: auth.From, Balance: big.NewInt(10000000000)})
+
+			// Deploy the contract, linking it against a library
+			lib := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+			_, tx, _, err := DeployLinked(auth, sim, lib)
+			if err != nil {
+				t.Fatalf("Failed to deploy linked contract: %v", err)
+			}
+			if !bytes.Contains(tx.Data(), lib.Bytes()) {
+				t.Fatalf("Library address missing from deployment code: %x", tx.Data())
+			}
+			if bytes.Contains(tx.Data(), []byte("__")) {
+				t.Fatalf("Library placeholder left in deployment code: %x", tx.Data())
 			}
 		`,
 	},
@@ -0,0 +1,124 @@
+// Copyright 2016 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ur-technology/go-ur/common"
+)
+
+// libraryPlaceholderLength is the length of the placeholders solc leaves in the
+// hex encoded bytecode of contracts referencing unlinked libraries: two
+// underscores, followed by the (possibly truncated) library name, padded with
+// underscores.
+const libraryPlaceholderLength = 40
+
+// libraryPlaceholder is the position and name of a library placeholder.
+type libraryPlaceholder struct {
+	offset int
+	name   string
+}
+
+// LibraryPlaceholders returns the names of the libraries the given hex encoded
+// bytecode needs to be linked against, in order of first appearance.
+func LibraryPlaceholders(bytecode string) ([]string, error) {
+	placeholders, err := findPlaceholders(normalizeBytecode(bytecode))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		names []string
+		seen  = make(map[string]bool)
+	)
+	for _, p := range placeholders {
+		if !seen[p.name] {
+			seen[p.name] = true
+			names = append(names, p.name)
+		}
+	}
+	return names, nil
+}
+
+// LinkBytecode replaces the library placeholders in the given hex encoded
+// bytecode with the addresses of the deployed libraries, keyed by placeholder
+// name, and returns the binary code ready for deployment.
+func LinkBytecode(bytecode string, libs map[string]common.Address) ([]byte, error) {
+	code := normalizeBytecode(bytecode)
+
+	placeholders, err := findPlaceholders(code)
+	if err != nil {
+		return nil, err
+	}
+	linked := []byte(code)
+	for _, p := range placeholders {
+		addr, ok := libs[p.name]
+		if !ok {
+			return nil, fmt.Errorf("missing address for library %q", p.name)
+		}
+		hex.Encode(linked[p.offset:p.offset+libraryPlaceholderLength], addr[:])
+	}
+	return hex.DecodeString(string(linked))
+}
+
+// findPlaceholders returns all library placeholders in the given hex encoded
+// bytecode.
+func findPlaceholders(code string) ([]libraryPlaceholder, error) {
+	var placeholders []libraryPlaceholder
+	for i := 0; ; {
+		idx := strings.Index(code[i:], "__")
+		if idx < 0 {
+			return placeholders, nil
+		}
+		offset := i + idx
+		if offset+libraryPlaceholderLength > len(code) {
+			return nil, fmt.Errorf("truncated library placeholder at offset %d", offset)
+		}
+		name := strings.TrimRight(code[offset+2:offset+libraryPlaceholderLength], "_")
+		if name == "" {
+			return nil, fmt.Errorf("empty library placeholder at offset %d", offset)
+		}
+		placeholders = append(placeholders, libraryPlaceholder{offset: offset, name: name})
+		i = offset + libraryPlaceholderLength
+	}
+}
+
+// normalizeBytecode strips any whitespace and hex prefix from the bytecode.
+func normalizeBytecode(bytecode string) string {
+	code := strings.TrimSpace(bytecode)
+	if strings.HasPrefix(code, "0x") || strings.HasPrefix(code, "0X") {
+		code = code[2:]
+	}
+	return code
+}
+
+// libraryIdentifier converts the placeholder name of a library into a valid
+// identifier, stripping any source file prefix and replacing invalid characters.
+func libraryIdentifier(name string) string {
+	if idx := strings.LastIndex(name, ":"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,70 @@
+// Copyright 2016 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+)
+
+var (
+	mathPlaceholder    = "__lib.sol:Math__________________________"
+	stringsPlaceholder = "__Strings_______________________________"
+	placeholdersCode   = "0x6060" + mathPlaceholder + "73" + stringsPlaceholder + "00" + mathPlaceholder
+)
+
+// Tests that library placeholders are detected in bytecode.
+func TestLibraryPlaceholders(t *testing.T) {
+	names, err := LibraryPlaceholders(placeholdersCode)
+	if err != nil {
+		t.Fatalf("failed to find placeholders: %v", err)
+	}
+	if want := []string{"lib.sol:Math", "Strings"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("placeholder mismatch: have %v, want %v", names, want)
+	}
+	if names, err := LibraryPlaceholders("0x6060604052"); err != nil || len(names) != 0 {
+		t.Errorf("unlinked code placeholders mismatch: have %v/%v, want none", names, err)
+	}
+	if _, err := LibraryPlaceholders("0x6060" + mathPlaceholder[:20]); err == nil {
+		t.Errorf("truncated placeholder accepted")
+	}
+	if ident := libraryIdentifier("lib/math.sol:Safe-Math"); ident != "Safe_Math" {
+		t.Errorf("identifier mismatch: have %s, want %s", ident, "Safe_Math")
+	}
+}
+
+// Tests that library addresses are correctly linked into bytecode.
+func TestLinkBytecode(t *testing.T) {
+	var (
+		math       = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		stringsLib = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	)
+	code, err := LinkBytecode(placeholdersCode, map[string]common.Address{"lib.sol:Math": math, "Strings": stringsLib})
+	if err != nil {
+		t.Fatalf("failed to link bytecode: %v", err)
+	}
+	want := append(append(append(append(append([]byte{0x60, 0x60}, math[:]...), 0x73), stringsLib[:]...), 0x00), math[:]...)
+	if !bytes.Equal(code, want) {
+		t.Errorf("linked code mismatch: have %x, want %x", code, want)
+	}
+	if _, err := LinkBytecode(placeholdersCode, map[string]common.Address{"Strings": stringsLib}); err == nil {
+		t.Errorf("linking succeeded with missing library")
+	}
+}
@@ -29,11 +29,19 @@ type tmplContract struct {
 	Type        string                 // Type name of the main contract binding
 	InputABI    string                 // JSON ABI used as the input to generate the binding from
 	InputBin    string                 // Optional EVM bytecode used to denetare deploy code from
+	Libraries   []*tmplLibrary         // Libraries the bytecode needs to be linked against on deploy
 	Constructor abi.Method             // Contract constructor for deploy parametrization
 	Calls       map[string]*tmplMethod // Contract calls that only read state data
 	Transacts   map[string]*tmplMethod // Contract calls that write state data
 }
 
+// tmplLibrary is a library referenced by a contract's bytecode through a link
+// placeholder, which needs to be deployed separately.
+type tmplLibrary struct {
+	Placeholder string // Name of the library within the bytecode placeholder
+	Param       string // Name of the deploy parameter holding the library address
+}
+
 // tmplMethod is a wrapper around an abi.Method that contains a few preprocessed
 // and cached data fields.
 type tmplMethod struct {
@@ -65,13 +73,21 @@ package {{.Package}}
 		// {{.Type}}Bin is the compiled bytecode used for deploying new contracts.
 		const {{.Type}}Bin = ` + "`" + `{{.InputBin}}` + "`" + `
 
-		// Deploy{{.Type}} deploys a new Ethereum contract, binding an instance of {{.Type}} to it.
-		func Deploy{{.Type}}(auth *bind.TransactOpts, backend bind.ContractBackend {{range .Constructor.Inputs}}, {{.Name}} {{bindtype .Type}}{{end}}) (common.Address, *types.Transaction, *{{.Type}}, error) {
+		// Deploy{{.Type}} deploys a new Ethereum contract, binding an instance of {{.Type}} to it.{{if .Libraries}}
+		// The bytecode is linked against the given addresses of the deployed libraries.{{end}}
+		func Deploy{{.Type}}(auth *bind.TransactOpts, backend bind.ContractBackend {{range .Libraries}}, {{.Param}} common.Address{{end}} {{range .Constructor.Inputs}}, {{.Name}} {{bindtype .Type}}{{end}}) (common.Address, *types.Transaction, *{{.Type}}, error) {
 		  parsed, err := abi.JSON(strings.NewReader({{.Type}}ABI))
 		  if err != nil {
 		    return common.Address{}, nil, nil, err
 		  }
-		  address, tx, contract, err := bind.DeployContract(auth, parsed, common.FromHex({{.Type}}Bin), backend {{range .Constructor.Inputs}}, {{.Name}}{{end}})
+		  {{if .Libraries}}bytecode, err := bind.LinkBytecode({{.Type}}Bin, map[string]common.Address{
+		    {{range .Libraries}}"{{.Placeholder}}": {{.Param}},
+		    {{end}}
+		  })
+		  if err != nil {
+		    return common.Address{}, nil, nil, err
+		  }{{else}}bytecode := common.FromHex({{.Type}}Bin){{end}}
+		  address, tx, contract, err := bind.DeployContract(auth, parsed, bytecode, backend {{range .Constructor.Inputs}}, {{.Name}}{{end}})
 		  if err != nil {
 		    return common.Address{}, nil, nil, err
 		  }
@@ -173,18 +189,23 @@ package {{.Package}}
 	// returns, a slice of interfaces for anonymous returns and a struct for named
 	// returns.
 	func (_{{$contract.Type}} *{{$contract.Type}}Raw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
-		return _{{$contract.Type}}.Contract.{{$contract.Type}}Caller.contract.Call(opts, result, method, params...)
+		contract := _{{$contract.Type}}.Contract.{{$contract.Type}}Caller.contract
+		return bind.WrapNoCode(contract.Call(opts, result, method, params...), "{{$contract.Type}}", method, contract.Address())
 	}
 
 	// Transfer initiates a plain transaction to move funds to the contract, calling
 	// its default method if one is available.
 	func (_{{$contract.Type}} *{{$contract.Type}}Raw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
-		return _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract.Transfer(opts)
+		contract := _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract
+		tx, err := contract.Transfer(opts)
+		return tx, bind.WrapNoCode(err, "{{$contract.Type}}", "", contract.Address())
 	}
 
 	// Transact invokes the (paid) contract method with params as input values.
 	func (_{{$contract.Type}} *{{$contract.Type}}Raw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
-		return _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract.Transact(opts, method, params...)
+		contract := _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract
+		tx, err := contract.Transact(opts, method, params...)
+		return tx, bind.WrapNoCode(err, "{{$contract.Type}}", method, contract.Address())
 	}
 
 	// Call invokes the (constant) contract method with params as input values and
@@ -192,18 +213,23 @@ package {{.Package}}
 	// returns, a slice of interfaces for anonymous returns and a struct for named
 	// returns.
 	func (_{{$contract.Type}} *{{$contract.Type}}CallerRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
-		return _{{$contract.Type}}.Contract.contract.Call(opts, result, method, params...)
+		contract := _{{$contract.Type}}.Contract.contract
+		return bind.WrapNoCode(contract.Call(opts, result, method, params...), "{{$contract.Type}}", method, contract.Address())
 	}
 
 	// Transfer initiates a plain transaction to move funds to the contract, calling
 	// its default method if one is available.
 	func (_{{$contract.Type}} *{{$contract.Type}}TransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
-		return _{{$contract.Type}}.Contract.contract.Transfer(opts)
+		contract := _{{$contract.Type}}.Contract.contract
+		tx, err := contract.Transfer(opts)
+		return tx, bind.WrapNoCode(err, "{{$contract.Type}}", "", contract.Address())
 	}
 
 	// Transact invokes the (paid) contract method with params as input values.
 	func (_{{$contract.Type}} *{{$contract.Type}}TransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
-		return _{{$contract.Type}}.Contract.contract.Transact(opts, method, params...)
+		contract := _{{$contract.Type}}.Contract.contract
+		tx, err := contract.Transact(opts, method, params...)
+		return tx, bind.WrapNoCode(err, "{{$contract.Type}}", method, contract.Address())
 	}
 
 	{{range .Calls}}
@@ -223,6 +249,7 @@ package {{.Package}}
 				{{end}}
 			}{{end}}{{end}}
 			err := _{{$contract.Type}}.contract.Call(opts, out, "{{.Original.Name}}" {{range .Normalized.Inputs}}, {{.Name}}{{end}})
+			err = bind.WrapNoCode(err, "{{$contract.Type}}", "{{.Original.Name}}", _{{$contract.Type}}.contract.Address())
 			return {{if .Structured}}*ret,{{else}}{{range $i, $_ := .Normalized.Outputs}}*ret{{$i}},{{end}}{{end}} err
 		}
 
@@ -246,7 +273,8 @@ package {{.Package}}
 		//
 		// Solidity: {{.Original.String}}
 		func (_{{$contract.Type}} *{{$contract.Type}}Transactor) {{.Normalized.Name}}(opts *bind.TransactOpts {{range .Normalized.Inputs}}, {{.Name}} {{bindtype .Type}} {{end}}) (*types.Transaction, error) {
-			return _{{$contract.Type}}.contract.Transact(opts, "{{.Original.Name}}" {{range .Normalized.Inputs}}, {{.Name}}{{end}})
+			tx, err := _{{$contract.Type}}.contract.Transact(opts, "{{.Original.Name}}" {{range .Normalized.Inputs}}, {{.Name}}{{end}})
+			return tx, bind.WrapNoCode(err, "{{$contract.Type}}", "{{.Original.Name}}", _{{$contract.Type}}.contract.Address())
 		}
 
 		// {{.Normalized.Name}} is a paid mutator transaction binding the contract method 0x{{printf "%x" .Original.Id}}.
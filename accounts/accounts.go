@@ -0,0 +1,148 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package accounts implements high level account management on top of
+// pluggable wallet backends.
+//
+// The bundled keystore backend stores secp256k1 private keys as encrypted JSON
+// files according to the Web3 Secret Storage specification.
+// See https://github.com/ur-technology/wiki/wiki/Web3-Secret-Storage-Definition for more information.
+package accounts
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/event"
+)
+
+var (
+	// ErrUnknownAccount is returned for any requested operation for which no
+	// backend provides the specified account.
+	ErrUnknownAccount = errors.New("unknown account")
+
+	// ErrUnknownWallet is returned for any requested operation for which no
+	// backend provides the specified wallet.
+	ErrUnknownWallet = errors.New("unknown wallet")
+
+	// ErrNotSupported is returned when an operation is requested from a wallet
+	// backend that it does not support.
+	ErrNotSupported = errors.New("not supported")
+
+	// ErrWalletClosed is returned if a wallet is attempted to be used before
+	// it was opened or after it was closed.
+	ErrWalletClosed = errors.New("wallet closed")
+
+	// ErrNoKeyStore is returned if the keystore is requested from an account
+	// manager configured without one.
+	ErrNoKeyStore = errors.New("no keystore backend")
+
+	// ErrNoHDStore is returned if the HD wallet store is requested from an
+	// account manager configured without one.
+	ErrNoHDStore = errors.New("no HD wallet backend")
+)
+
+// Account represents a stored key.
+// When used as an argument, it selects a unique key file to act on.
+type Account struct {
+	Address common.Address // Ethereum account address derived from the key
+
+	// File contains the key file name.
+	// When Acccount is used as an argument to select a key, File can be left blank to
+	// select just by address or set to the basename or absolute path of a file in the key
+	// directory. Accounts returned by a KeyStore will always contain an absolute path.
+	// Accounts of hardware wallets have no file.
+	File string
+}
+
+func (acc *Account) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + acc.Address.Hex() + `"`), nil
+}
+
+func (acc *Account) UnmarshalJSON(raw []byte) error {
+	return json.Unmarshal(raw, &acc.Address)
+}
+
+// Wallet represents a software or hardware wallet that might contain one or
+// more accounts (derived from the same seed).
+type Wallet interface {
+	// URL retrieves the canonical path under which this wallet is reachable. It
+	// is used by upper layers to define a sorting order over all wallets from
+	// multiple backends.
+	URL() string
+
+	// Status returns a textual status to aid the user in the current state of
+	// the wallet.
+	Status() string
+
+	// Open initializes access to a wallet instance. The passphrase is only used
+	// by wallets requiring one (e.g. a hardware wallet PIN) and may be empty.
+	Open(passphrase string) error
+
+	// Close releases any resources held by an open wallet instance.
+	Close() error
+
+	// Accounts retrieves the list of signing accounts the wallet is currently
+	// aware of. For hierarchical deterministic wallets, the list is not
+	// exhaustive, rather only contains the accounts explicitly derived.
+	Accounts() []Account
+
+	// Contains returns whether an account is part of this particular wallet.
+	Contains(account Account) bool
+
+	// Derive attempts to explicitly derive a hierarchical deterministic account
+	// at the specified path. If pin is set to true, the account will be added
+	// to the list of tracked accounts.
+	Derive(path DerivationPath, pin bool) (Account, error)
+
+	// SignHash requests the wallet to sign the given hash, producing a
+	// signature in the Ethereum yellow paper format.
+	SignHash(account Account, hash []byte) ([]byte, error)
+
+	// SignTx requests the wallet to sign the given transaction. If chainID is
+	// nil, a signature without replay protection is produced.
+	SignTx(account Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+
+	// SignHashWithPassphrase requests the wallet to sign the given hash with
+	// the given passphrase as extra authentication information.
+	SignHashWithPassphrase(account Account, passphrase string, hash []byte) ([]byte, error)
+
+	// SignTxWithPassphrase requests the wallet to sign the given transaction
+	// with the given passphrase as extra authentication information.
+	SignTxWithPassphrase(account Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// Backend is a "wallet provider" that may contain a batch of accounts they can
+// sign transactions with and upon request, do so.
+type Backend interface {
+	// Wallets retrieves the list of wallets the backend is currently aware of,
+	// sorted alphabetically by their URL.
+	Wallets() []Wallet
+
+	// Subscribe creates a subscription receiving a WalletEvent whenever the
+	// backend detects the arrival or departure of a wallet.
+	Subscribe() event.Subscription
+}
+
+// WalletEvent is an event fired by an account backend when a wallet arrival or
+// departure is detected.
+type WalletEvent struct {
+	Wallet Wallet // Wallet instance arrived or departed
+	Arrive bool   // Whether the wallet was added or removed
+}
@@ -29,11 +29,11 @@ import (
 
 var testSigData = make([]byte, 32)
 
-func TestManager(t *testing.T) {
-	dir, am := tmpManager(t, true)
+func TestKeyStore(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
 	defer os.RemoveAll(dir)
 
-	a, err := am.NewAccount("foo")
+	a, err := ks.NewAccount("foo")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -47,132 +47,132 @@ func TestManager(t *testing.T) {
 	if runtime.GOOS != "windows" && stat.Mode() != 0600 {
 		t.Fatalf("account file has wrong mode: got %o, want %o", stat.Mode(), 0600)
 	}
-	if !am.HasAddress(a.Address) {
+	if !ks.HasAddress(a.Address) {
 		t.Errorf("HasAccount(%x) should've returned true", a.Address)
 	}
-	if err := am.Update(a, "foo", "bar"); err != nil {
+	if err := ks.Update(a, "foo", "bar"); err != nil {
 		t.Errorf("Update error: %v", err)
 	}
-	if err := am.DeleteAccount(a, "bar"); err != nil {
+	if err := ks.DeleteAccount(a, "bar"); err != nil {
 		t.Errorf("DeleteAccount error: %v", err)
 	}
 	if common.FileExist(a.File) {
 		t.Errorf("account file %s should be gone after DeleteAccount", a.File)
 	}
-	if am.HasAddress(a.Address) {
+	if ks.HasAddress(a.Address) {
 		t.Errorf("HasAccount(%x) should've returned true after DeleteAccount", a.Address)
 	}
 }
 
 func TestSign(t *testing.T) {
-	dir, am := tmpManager(t, true)
+	dir, ks := tmpKeyStore(t, true)
 	defer os.RemoveAll(dir)
 
 	pass := "" // not used but required by API
-	a1, err := am.NewAccount(pass)
+	a1, err := ks.NewAccount(pass)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := am.Unlock(a1, ""); err != nil {
+	if err := ks.Unlock(a1, ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := am.Sign(a1.Address, testSigData); err != nil {
+	if _, err := ks.Sign(a1.Address, testSigData); err != nil {
 		t.Fatal(err)
 	}
 }
 
 func TestSignWithPassphrase(t *testing.T) {
-	dir, am := tmpManager(t, true)
+	dir, ks := tmpKeyStore(t, true)
 	defer os.RemoveAll(dir)
 
 	pass := "passwd"
-	acc, err := am.NewAccount(pass)
+	acc, err := ks.NewAccount(pass)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if _, unlocked := am.unlocked[acc.Address]; unlocked {
+	if _, unlocked := ks.unlocked[acc.Address]; unlocked {
 		t.Fatal("expected account to be locked")
 	}
 
-	_, err = am.SignWithPassphrase(acc.Address, pass, testSigData)
+	_, err = ks.SignWithPassphrase(acc.Address, pass, testSigData)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if _, unlocked := am.unlocked[acc.Address]; unlocked {
+	if _, unlocked := ks.unlocked[acc.Address]; unlocked {
 		t.Fatal("expected account to be locked")
 	}
 
-	if _, err = am.SignWithPassphrase(acc.Address, "invalid passwd", testSigData); err == nil {
+	if _, err = ks.SignWithPassphrase(acc.Address, "invalid passwd", testSigData); err == nil {
 		t.Fatal("expected SignHash to fail with invalid password")
 	}
 }
 
 func TestTimedUnlock(t *testing.T) {
-	dir, am := tmpManager(t, true)
+	dir, ks := tmpKeyStore(t, true)
 	defer os.RemoveAll(dir)
 
 	pass := "foo"
-	a1, err := am.NewAccount(pass)
+	a1, err := ks.NewAccount(pass)
 
 	// Signing without passphrase fails because account is locked
-	_, err = am.Sign(a1.Address, testSigData)
+	_, err = ks.Sign(a1.Address, testSigData)
 	if err != ErrLocked {
 		t.Fatal("Signing should've failed with ErrLocked before unlocking, got ", err)
 	}
 
 	// Signing with passphrase works
-	if err = am.TimedUnlock(a1, pass, 100*time.Millisecond); err != nil {
+	if err = ks.TimedUnlock(a1, pass, 100*time.Millisecond); err != nil {
 		t.Fatal(err)
 	}
 
 	// Signing without passphrase works because account is temp unlocked
-	_, err = am.Sign(a1.Address, testSigData)
+	_, err = ks.Sign(a1.Address, testSigData)
 	if err != nil {
 		t.Fatal("Signing shouldn't return an error after unlocking, got ", err)
 	}
 
 	// Signing fails again after automatic locking
 	time.Sleep(250 * time.Millisecond)
-	_, err = am.Sign(a1.Address, testSigData)
+	_, err = ks.Sign(a1.Address, testSigData)
 	if err != ErrLocked {
 		t.Fatal("Signing should've failed with ErrLocked timeout expired, got ", err)
 	}
 }
 
 func TestOverrideUnlock(t *testing.T) {
-	dir, am := tmpManager(t, false)
+	dir, ks := tmpKeyStore(t, false)
 	defer os.RemoveAll(dir)
 
 	pass := "foo"
-	a1, err := am.NewAccount(pass)
+	a1, err := ks.NewAccount(pass)
 
 	// Unlock indefinitely.
-	if err = am.TimedUnlock(a1, pass, 5*time.Minute); err != nil {
+	if err = ks.TimedUnlock(a1, pass, 5*time.Minute); err != nil {
 		t.Fatal(err)
 	}
 
 	// Signing without passphrase works because account is temp unlocked
-	_, err = am.Sign(a1.Address, testSigData)
+	_, err = ks.Sign(a1.Address, testSigData)
 	if err != nil {
 		t.Fatal("Signing shouldn't return an error after unlocking, got ", err)
 	}
 
 	// reset unlock to a shorter period, invalidates the previous unlock
-	if err = am.TimedUnlock(a1, pass, 100*time.Millisecond); err != nil {
+	if err = ks.TimedUnlock(a1, pass, 100*time.Millisecond); err != nil {
 		t.Fatal(err)
 	}
 
 	// Signing without passphrase still works because account is temp unlocked
-	_, err = am.Sign(a1.Address, testSigData)
+	_, err = ks.Sign(a1.Address, testSigData)
 	if err != nil {
 		t.Fatal("Signing shouldn't return an error after unlocking, got ", err)
 	}
 
 	// Signing fails again after automatic locking
 	time.Sleep(250 * time.Millisecond)
-	_, err = am.Sign(a1.Address, testSigData)
+	_, err = ks.Sign(a1.Address, testSigData)
 	if err != ErrLocked {
 		t.Fatal("Signing should've failed with ErrLocked timeout expired, got ", err)
 	}
@@ -180,21 +180,21 @@ func TestOverrideUnlock(t *testing.T) {
 
 // This test should fail under -race if signing races the expiration goroutine.
 func TestSignRace(t *testing.T) {
-	dir, am := tmpManager(t, false)
+	dir, ks := tmpKeyStore(t, false)
 	defer os.RemoveAll(dir)
 
 	// Create a test account.
-	a1, err := am.NewAccount("")
+	a1, err := ks.NewAccount("")
 	if err != nil {
 		t.Fatal("could not create the test account", err)
 	}
 
-	if err := am.TimedUnlock(a1, "", 15*time.Millisecond); err != nil {
+	if err := ks.TimedUnlock(a1, "", 15*time.Millisecond); err != nil {
 		t.Fatal("could not unlock the test account", err)
 	}
 	end := time.Now().Add(500 * time.Millisecond)
 	for time.Now().Before(end) {
-		if _, err := am.Sign(a1.Address, testSigData); err == ErrLocked {
+		if _, err := ks.Sign(a1.Address, testSigData); err == ErrLocked {
 			return
 		} else if err != nil {
 			t.Errorf("Sign error: %v", err)
@@ -205,14 +205,32 @@ func TestSignRace(t *testing.T) {
 	t.Errorf("Account did not lock within the timeout")
 }
 
-func tmpManager(t *testing.T, encrypted bool) (string, *Manager) {
+// Tests that the keystore is retrieved from an account manager, and that an error
+// is returned instead if the manager has none.
+func TestManagerKeyStore(t *testing.T) {
+	dir, ks := tmpKeyStore(t, false)
+	defer os.RemoveAll(dir)
+
+	am := NewManager(ks)
+	defer am.Close()
+	if have, err := am.KeyStore(); err != nil || have != ks {
+		t.Errorf("keystore mismatch: have %p (%v), want %p", have, err, ks)
+	}
+	empty := NewManager()
+	defer empty.Close()
+	if _, err := empty.KeyStore(); err != ErrNoKeyStore {
+		t.Errorf("missing keystore error mismatch: have %v, want %v", err, ErrNoKeyStore)
+	}
+}
+
+func tmpKeyStore(t *testing.T, encrypted bool) (string, *KeyStore) {
 	d, err := ioutil.TempDir("", "eth-keystore-test")
 	if err != nil {
 		t.Fatal(err)
 	}
-	new := NewPlaintextManager
+	new := NewPlaintextKeyStore
 	if encrypted {
-		new = func(kd string) *Manager { return NewManager(kd, veryLightScryptN, veryLightScryptP) }
+		new = func(kd string) *KeyStore { return NewKeyStore(kd, veryLightScryptN, veryLightScryptP) }
 	}
 	return d, new(d)
 }
@@ -0,0 +1,267 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package external implements an accounts backend delegating signing to an
+// external signer process, such as ursigner, reachable over RPC.
+package external
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ur-technology/go-ur/accounts"
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/common/hexutil"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/event"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+	"github.com/ur-technology/go-ur/rlp"
+	"github.com/ur-technology/go-ur/rpc"
+	"github.com/ur-technology/go-ur/signer"
+)
+
+// ExternalScheme is the protocol scheme prefixing the URL of an external signer.
+const ExternalScheme = "extapi"
+
+// ExternalBackendType is the reflect type of an external signer backend.
+var ExternalBackendType = reflect.TypeOf(&ExternalBackend{})
+
+// refreshThrottling is the minimum time between two account list retrievals
+// from the external signer.
+const refreshThrottling = time.Second
+
+// ErrSignerMismatch is returned if the external signer returned a transaction
+// that differs from the requested one or is signed by another account.
+var ErrSignerMismatch = errors.New("external signer returned mismatching transaction")
+
+// ExternalBackend is an accounts.Backend exposing a single wallet backed by an
+// external signer.
+type ExternalBackend struct {
+	signer *ExternalSigner
+	mux    *event.TypeMux
+}
+
+// NewExternalBackend creates a backend delegating signing to the external
+// signer listening on endpoint, an IPC path or HTTP URL. The signer is dialed
+// lazily so that it may be started after the node.
+func NewExternalBackend(endpoint string) *ExternalBackend {
+	return newExternalBackend(endpoint, func() (*rpc.Client, error) { return rpc.Dial(endpoint) })
+}
+
+func newExternalBackend(endpoint string, dial func() (*rpc.Client, error)) *ExternalBackend {
+	return &ExternalBackend{
+		signer: &ExternalSigner{url: ExternalScheme + "://" + endpoint, dial: dial},
+		mux:    new(event.TypeMux),
+	}
+}
+
+// Wallets implements accounts.Backend, returning the external signer.
+func (eb *ExternalBackend) Wallets() []accounts.Wallet {
+	return []accounts.Wallet{eb.signer}
+}
+
+// Subscribe implements accounts.Backend. The external signer wallet is always
+// present, so no events are ever fired.
+func (eb *ExternalBackend) Subscribe() event.Subscription {
+	return eb.mux.Subscribe(accounts.WalletEvent{})
+}
+
+// ExternalSigner is an accounts.Wallet whose keys are held by an external
+// signer process, which decides on every signing request on its own.
+type ExternalSigner struct {
+	url  string
+	dial func() (*rpc.Client, error)
+
+	client    *rpc.Client        // RPC client to the signer, nil until dialed
+	accounts  []accounts.Account // Accounts last reported by the signer
+	refreshed time.Time          // Time instance when the account list was last refreshed
+	err       error              // Error of the last account list retrieval
+	lock      sync.Mutex
+}
+
+// URL implements accounts.Wallet, returning the endpoint of the signer.
+func (es *ExternalSigner) URL() string {
+	return es.url
+}
+
+// Status implements accounts.Wallet, reporting whether the signer could be
+// reached the last time it was asked for its accounts.
+func (es *ExternalSigner) Status() string {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+
+	switch {
+	case es.err != nil:
+		return "Failed: " + es.err.Error()
+	case es.client == nil:
+		return "Disconnected"
+	}
+	return "Connected"
+}
+
+// Open implements accounts.Wallet, connecting to the signer. The passphrase is
+// ignored, the signer authorizing requests itself.
+func (es *ExternalSigner) Open(passphrase string) error {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+
+	_, err := es.connect()
+	return err
+}
+
+// Close implements accounts.Wallet, disconnecting from the signer.
+func (es *ExternalSigner) Close() error {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+
+	if es.client != nil {
+		es.client.Close()
+		es.client = nil
+	}
+	return nil
+}
+
+// connect returns the RPC client to the signer, dialing it if needed. The lock
+// must be held.
+func (es *ExternalSigner) connect() (*rpc.Client, error) {
+	if es.client == nil {
+		client, err := es.dial()
+		if err != nil {
+			es.err = err
+			return nil, err
+		}
+		es.client, es.err = client, nil
+	}
+	return es.client, nil
+}
+
+// Accounts implements accounts.Wallet, returning the accounts of the signer.
+// The list is cached for a short while to avoid hammering the signer.
+func (es *ExternalSigner) Accounts() []accounts.Account {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+
+	if time.Since(es.refreshed) < refreshThrottling {
+		return append([]accounts.Account{}, es.accounts...)
+	}
+	es.refreshed = time.Now()
+
+	client, err := es.connect()
+	if err != nil {
+		glog.V(logger.Debug).Infof("Failed to connect to external signer %s: %v", es.url, err)
+		return append([]accounts.Account{}, es.accounts...)
+	}
+	var addresses []common.Address
+	if err := client.Call(&addresses, "account_list"); err != nil {
+		glog.V(logger.Debug).Infof("Failed to list external signer accounts: %v", err)
+		es.err = err
+		return append([]accounts.Account{}, es.accounts...)
+	}
+	es.err = nil
+	es.accounts = make([]accounts.Account, len(addresses))
+	for i, address := range addresses {
+		es.accounts[i] = accounts.Account{Address: address}
+	}
+	return append([]accounts.Account{}, es.accounts...)
+}
+
+// Contains implements accounts.Wallet, returning whether the signer holds the
+// key of the account.
+func (es *ExternalSigner) Contains(account accounts.Account) bool {
+	for _, known := range es.Accounts() {
+		if known.Address == account.Address {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements accounts.Wallet. External signers can't derive accounts.
+func (es *ExternalSigner) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SignHash implements accounts.Wallet, requesting the signer to sign the hash.
+// Signers only approve raw hashes if their rules explicitly allow it.
+func (es *ExternalSigner) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	es.lock.Lock()
+	client, err := es.connect()
+	es.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var signature hexutil.Bytes
+	if err := client.Call(&signature, "account_signHash", account.Address, hexutil.Bytes(hash)); err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
+
+// SignTx implements accounts.Wallet, requesting the signer to sign the
+// transaction and verifying the returned one before accepting it.
+func (es *ExternalSigner) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := signer.SendTxArgs{
+		From:     account.Address,
+		To:       tx.To(),
+		Gas:      hexutil.Big(*tx.Gas()),
+		GasPrice: hexutil.Big(*tx.GasPrice()),
+		Value:    hexutil.Big(*tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     tx.Data(),
+		ChainID:  (*hexutil.Big)(chainID),
+	}
+	es.lock.Lock()
+	client, err := es.connect()
+	es.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var res signer.SignTxResult
+	if err := client.Call(&res, "account_signTransaction", &args); err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := rlp.DecodeBytes(res.Raw, signed); err != nil {
+		return nil, err
+	}
+	var txSigner types.Signer = types.HomesteadSigner{}
+	if chainID != nil {
+		txSigner = types.NewEIP155Signer(chainID)
+	}
+	if txSigner.Hash(signed) != txSigner.Hash(tx) {
+		return nil, ErrSignerMismatch
+	}
+	if sender, err := types.Sender(txSigner, signed); err != nil || sender != account.Address {
+		return nil, ErrSignerMismatch
+	}
+	return signed, nil
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, ignoring the passphrase as
+// the signer authorizes requests by its own rules.
+func (es *ExternalSigner) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return es.SignHash(account, hash)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, ignoring the passphrase as
+// the signer authorizes requests by its own rules.
+func (es *ExternalSigner) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return es.SignTx(account, tx, chainID)
+}
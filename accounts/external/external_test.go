@@ -0,0 +1,98 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ur-technology/go-ur/accounts"
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/rpc"
+	"github.com/ur-technology/go-ur/signer"
+)
+
+// Tests that a node side account manager can sign through an in-process signer
+// and that the signer's rules are enforced.
+func TestExternalSigning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ur-external-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := accounts.NewKeyStore(dir, 2, 1)
+	account, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.Unlock(account, "foo")
+
+	allowed := common.HexToAddress("0x01")
+	rules, err := signer.ParseRuleset([]byte(`{"rules": [
+		{"to": ["` + allowed.Hex() + `"], "action": "approve"},
+		{"methods": ["account_signHash"], "from": ["` + account.Address.Hex() + `"], "action": "approve"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName(signer.Namespace, signer.NewSignerAPI(accounts.NewManager(ks), rules, nil)); err != nil {
+		t.Fatal(err)
+	}
+	backend := newExternalBackend("test", func() (*rpc.Client, error) { return rpc.DialInProc(server), nil })
+	am := accounts.NewManager(backend)
+	defer am.Close()
+
+	wallet, err := am.Find(accounts.Account{Address: account.Address})
+	if err != nil {
+		t.Fatalf("signer account not found: %v", err)
+	}
+	if wallet.URL() != "extapi://test" {
+		t.Errorf("wallet URL mismatch: have %s", wallet.URL())
+	}
+	tx := types.NewTransaction(1, allowed, big.NewInt(10), big.NewInt(21000), big.NewInt(1), []byte{1})
+	signed, err := wallet.SignTx(account, tx, big.NewInt(18))
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if sender, err := types.Sender(types.NewEIP155Signer(big.NewInt(18)), signed); err != nil || sender != account.Address {
+		t.Errorf("sender mismatch: have %x, %v, want %x", sender, err, account.Address)
+	}
+	denied := types.NewTransaction(1, common.Address{}, big.NewInt(10), big.NewInt(21000), big.NewInt(1), nil)
+	if _, err := wallet.SignTx(account, denied, nil); err == nil || err.Error() != signer.ErrRequestDenied.Error() {
+		t.Errorf("denied transaction: have %v, want %v", err, signer.ErrRequestDenied)
+	}
+	hash := crypto.Keccak256([]byte("hello ur"))
+	sig, err := wallet.SignHash(account, hash)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	sig[64] -= 27 // Signatures are in yellow paper format
+	pubkey, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if signer := common.BytesToAddress(crypto.Keccak256(pubkey[1:])[12:]); signer != account.Address {
+		t.Errorf("hash signer mismatch: have %x, want %x", signer, account.Address)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/crypto/secp256k1"
+)
+
+// errInvalidChild is returned if a BIP-32 derivation step produces an invalid
+// key, which the spec says should be skipped by moving on to the next index.
+var errInvalidChild = errors.New("invalid child key, use the next index")
+
+// hdKey is a BIP-32 extended private key.
+type hdKey struct {
+	key   *big.Int // Private key scalar
+	chain []byte   // Chain code of the extended key
+}
+
+// newMasterKey derives the BIP-32 master key from a seed.
+func newMasterKey(seed []byte) (*hdKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key := new(big.Int).SetBytes(sum[:32])
+	if key.Sign() == 0 || key.Cmp(secp256k1.S256().Params().N) >= 0 {
+		return nil, errors.New("invalid master key, use another seed")
+	}
+	return &hdKey{key: key, chain: sum[32:]}, nil
+}
+
+// child derives the private child key at the given index. Indexes with the top
+// bit set produce hardened children.
+func (k *hdKey) child(index uint32) (*hdKey, error) {
+	var data []byte
+	if index >= 0x80000000 {
+		data = append([]byte{0x00}, common.LeftPadBytes(k.key.Bytes(), 32)...)
+	} else {
+		data = compressPubkey(k.key)
+	}
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+	mac := hmac.New(sha512.New, k.chain)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := secp256k1.S256().Params().N
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(n) >= 0 {
+		return nil, errInvalidChild
+	}
+	key := tweak.Add(tweak, k.key)
+	key.Mod(key, n)
+	if key.Sign() == 0 {
+		return nil, errInvalidChild
+	}
+	return &hdKey{key: key, chain: sum[32:]}, nil
+}
+
+// derive walks the derivation path from the current key.
+func (k *hdKey) derive(path DerivationPath) (*hdKey, error) {
+	var err error
+	for _, index := range path {
+		if k, err = k.child(index); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// privateKey converts the extended key into a plain secp256k1 private key.
+func (k *hdKey) privateKey() *ecdsa.PrivateKey {
+	return crypto.ToECDSA(common.LeftPadBytes(k.key.Bytes(), 32))
+}
+
+// compressPubkey returns the 33 byte compressed public key of a private scalar.
+func compressPubkey(key *big.Int) []byte {
+	x, y := secp256k1.S256().ScalarBaseMult(common.LeftPadBytes(key.Bytes(), 32))
+
+	pub := make([]byte, 33)
+	pub[0] = 0x02 + byte(y.Bit(0))
+	copy(pub[1:], common.LeftPadBytes(x.Bytes(), 32))
+	return pub
+}
+
+// DeriveKey derives the private key at the given path from a BIP-39 seed.
+func DeriveKey(seed []byte, path DerivationPath) (*ecdsa.PrivateKey, error) {
+	master, err := newMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	child, err := master.derive(path)
+	if err != nil {
+		return nil, err
+	}
+	return child.privateKey(), nil
+}
@@ -0,0 +1,218 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/event"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+)
+
+// HDScheme is the protocol scheme prefixing the URLs of mnemonic backed
+// hierarchical deterministic wallets.
+const HDScheme = "hd"
+
+// HDStoreType is the reflect type of a HD wallet store backend.
+var HDStoreType = reflect.TypeOf(&HDStore{})
+
+// ErrWalletExists is returned if a mnemonic is imported whose wallet is already
+// tracked by the store.
+var ErrWalletExists = errors.New("wallet already exists")
+
+// hdWalletVersion is the version of the on-disk HD wallet format.
+const hdWalletVersion = 1
+
+// hdWalletJSON is the on-disk format of a HD wallet: the encrypted BIP-39 seed
+// and the cache of already derived accounts, which can be listed without the
+// passphrase.
+type hdWalletJSON struct {
+	Address  string          `json:"address"` // Address of the master key, identifying the wallet
+	Crypto   cryptoJSON      `json:"crypto"`
+	Accounts []hdAccountJSON `json:"accounts"`
+	Version  int             `json:"version"`
+}
+
+type hdAccountJSON struct {
+	Address string `json:"address"`
+	Path    string `json:"path"`
+}
+
+// HDStore is an accounts.Backend managing mnemonic backed HD wallets stored as
+// encrypted files in a directory.
+type HDStore struct {
+	dir     string // Directory holding the HD wallet files
+	scryptN int    // Scrypt N parameter to encrypt new seeds with
+	scryptP int    // Scrypt P parameter to encrypt new seeds with
+
+	wallets []Wallet       // Wallets loaded from disk, sorted by URL
+	mux     *event.TypeMux // Event mux to report wallet arrivals on
+	lock    sync.Mutex     // Lock protecting the wallet list
+}
+
+// NewHDStore creates a HD wallet store for the given directory, loading all the
+// wallets already present in it.
+func NewHDStore(dir string, scryptN, scryptP int) *HDStore {
+	dir, _ = filepath.Abs(dir)
+	hs := &HDStore{
+		dir:     dir,
+		scryptN: scryptN,
+		scryptP: scryptP,
+		mux:     new(event.TypeMux),
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		glog.V(logger.Warn).Infof("Failed to read HD wallet directory %s: %v", dir, err)
+	}
+	for _, fi := range files {
+		if skipKeyFile(fi) {
+			continue
+		}
+		wallet, err := loadHDWallet(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			glog.V(logger.Debug).Infof("Failed to load HD wallet %s: %v", fi.Name(), err)
+			continue
+		}
+		hs.wallets = append(hs.wallets, wallet)
+	}
+	sort.Sort(walletsByURL(hs.wallets))
+	return hs
+}
+
+// Wallets implements Backend, returning all the HD wallets in the store.
+func (hs *HDStore) Wallets() []Wallet {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+
+	cpy := make([]Wallet, len(hs.wallets))
+	copy(cpy, hs.wallets)
+	return cpy
+}
+
+// Subscribe implements Backend, creating a subscription receiving a WalletEvent
+// whenever a HD wallet is created or imported.
+func (hs *HDStore) Subscribe() event.Subscription {
+	return hs.mux.Subscribe(WalletEvent{})
+}
+
+// NewWallet generates a fresh mnemonic and stores the wallet backed by it,
+// encrypted with passphrase. The mnemonic is returned only here and must be
+// backed up by the user.
+func (hs *HDStore) NewWallet(passphrase string) (string, Wallet, error) {
+	mnemonic, err := NewMnemonic(DefaultMnemonicBits)
+	if err != nil {
+		return "", nil, err
+	}
+	wallet, err := hs.ImportMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return "", nil, err
+	}
+	return mnemonic, wallet, nil
+}
+
+// ImportMnemonic stores the wallet backed by an existing BIP-39 mnemonic,
+// encrypting its seed with passphrase.
+func (hs *HDStore) ImportMnemonic(mnemonic, passphrase string) (Wallet, error) {
+	seed, err := MnemonicToSeed(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(seed)
+
+	master, err := newMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := crypto.PubkeyToAddress(master.privateKey().PublicKey)
+
+	sealed, err := encryptData(seed, passphrase, hs.scryptN, hs.scryptP)
+	if err != nil {
+		return nil, err
+	}
+	wallet := &hdWallet{
+		url:    HDScheme + "://" + hex.EncodeToString(fingerprint[:]),
+		file:   filepath.Join(hs.dir, hex.EncodeToString(fingerprint[:])+".json"),
+		crypto: sealed,
+		paths:  make(map[common.Address]DerivationPath),
+	}
+	hs.lock.Lock()
+	for _, existing := range hs.wallets {
+		if existing.URL() == wallet.url {
+			hs.lock.Unlock()
+			return nil, ErrWalletExists
+		}
+	}
+	if err := wallet.save(); err != nil {
+		hs.lock.Unlock()
+		return nil, err
+	}
+	hs.wallets = append(hs.wallets, wallet)
+	sort.Sort(walletsByURL(hs.wallets))
+	hs.lock.Unlock()
+
+	hs.mux.Post(WalletEvent{Wallet: wallet, Arrive: true})
+	return wallet, nil
+}
+
+// loadHDWallet parses a HD wallet file, restoring its derived account cache.
+func loadHDWallet(file string) (*hdWallet, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var enc hdWalletJSON
+	if err := json.Unmarshal(blob, &enc); err != nil {
+		return nil, err
+	}
+	if enc.Version != hdWalletVersion {
+		return nil, errors.New("unsupported HD wallet version")
+	}
+	wallet := &hdWallet{
+		url:    HDScheme + "://" + enc.Address,
+		file:   file,
+		crypto: enc.Crypto,
+		paths:  make(map[common.Address]DerivationPath),
+	}
+	for _, account := range enc.Accounts {
+		path, err := ParseDerivationPath(account.Path)
+		if err != nil {
+			return nil, err
+		}
+		address := common.HexToAddress(account.Address)
+		wallet.accounts = append(wallet.accounts, Account{Address: address})
+		wallet.paths[address] = path
+	}
+	return wallet, nil
+}
+
+// zeroBytes overwrites a secret in memory.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
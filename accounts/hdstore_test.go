@@ -0,0 +1,216 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/crypto"
+	"golang.org/x/net/context"
+)
+
+// Tests BIP-32 private key derivation against test vector 1 of the spec.
+func TestHDKeyDerivation(t *testing.T) {
+	seed := common.FromHex("000102030405060708090a0b0c0d0e0f")
+	tests := []struct {
+		path string
+		key  string
+	}{
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"m/0'/1/2'", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+		{"m/0'/1/2'/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	master, err := newMasterKey(seed)
+	if err != nil {
+		t.Fatalf("failed to create master key: %v", err)
+	}
+	if key := hex.EncodeToString(crypto.FromECDSA(master.privateKey())); key != "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35" {
+		t.Errorf("master key mismatch: have %s", key)
+	}
+	for i, tt := range tests {
+		path, err := ParseDerivationPath(tt.path)
+		if err != nil {
+			t.Fatalf("test %d: invalid path %s: %v", i, tt.path, err)
+		}
+		key, err := DeriveKey(seed, path)
+		if err != nil {
+			t.Fatalf("test %d: failed to derive %s: %v", i, tt.path, err)
+		}
+		if have := hex.EncodeToString(crypto.FromECDSA(key)); have != tt.key {
+			t.Errorf("test %d: key mismatch at %s: have %s, want %s", i, tt.path, have, tt.key)
+		}
+	}
+}
+
+func tmpHDStore(t *testing.T) (string, *HDStore) {
+	dir, err := ioutil.TempDir("", "ur-hdstore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, NewHDStore(dir, veryLightScryptN, veryLightScryptP)
+}
+
+// Tests that the HD wallet store is retrieved from an account manager, and that
+// an error is returned instead if the manager has none.
+func TestManagerHDStore(t *testing.T) {
+	dir, store := tmpHDStore(t)
+	defer os.RemoveAll(dir)
+
+	am := NewManager(store)
+	defer am.Close()
+	if have, err := am.HDStore(); err != nil || have != store {
+		t.Errorf("HD store mismatch: have %p (%v), want %p", have, err, store)
+	}
+	empty := NewManager()
+	defer empty.Close()
+	if _, err := empty.HDStore(); err != ErrNoHDStore {
+		t.Errorf("missing HD store error mismatch: have %v, want %v", err, ErrNoHDStore)
+	}
+}
+
+// Tests that HD wallets can be created, derived from, signed with and that the
+// derived account cache survives a restart.
+func TestHDStoreLifecycle(t *testing.T) {
+	dir, store := tmpHDStore(t)
+	defer os.RemoveAll(dir)
+
+	mnemonic, wallet, err := store.NewWallet("foo")
+	if err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	if _, err := store.ImportMnemonic(mnemonic, "bar"); err != ErrWalletExists {
+		t.Errorf("duplicate import: have %v, want %v", err, ErrWalletExists)
+	}
+	if _, err := wallet.Derive(DefaultURBaseDerivationPath, true); err != ErrLocked {
+		t.Errorf("derivation from locked wallet: have %v, want %v", err, ErrLocked)
+	}
+	if err := wallet.Open("bar"); err != ErrDecrypt {
+		t.Errorf("open with wrong passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+	if err := wallet.Open("foo"); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	account, err := wallet.Derive(DefaultURBaseDerivationPath, true)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	seed, _ := MnemonicToSeed(mnemonic, "")
+	key, _ := DeriveKey(seed, DefaultURBaseDerivationPath)
+	if want := crypto.PubkeyToAddress(key.PublicKey); account.Address != want {
+		t.Fatalf("derived address mismatch: have %x, want %x", account.Address, want)
+	}
+	// Sign with the open wallet, then with the passphrase after closing it
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil)
+	for _, sign := range []func() (*types.Transaction, error){
+		func() (*types.Transaction, error) { return wallet.SignTx(account, tx, big.NewInt(18)) },
+		func() (*types.Transaction, error) {
+			wallet.Close()
+			return wallet.SignTxWithPassphrase(account, "foo", tx, big.NewInt(18))
+		},
+	} {
+		signed, err := sign()
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		if sender, err := types.Sender(types.NewEIP155Signer(big.NewInt(18)), signed); err != nil || sender != account.Address {
+			t.Errorf("sender mismatch: have %x, %v, want %x", sender, err, account.Address)
+		}
+	}
+	if _, err := wallet.SignTx(account, tx, nil); err != ErrLocked {
+		t.Errorf("signing with closed wallet: have %v, want %v", err, ErrLocked)
+	}
+	// Reload the store and ensure the wallet and its accounts are restored
+	reloaded := NewHDStore(dir, veryLightScryptN, veryLightScryptP).Wallets()
+	if len(reloaded) != 1 || reloaded[0].URL() != wallet.URL() {
+		t.Fatalf("reloaded wallets mismatch: have %v", reloaded)
+	}
+	if accounts := reloaded[0].Accounts(); !reflect.DeepEqual(accounts, []Account{account}) {
+		t.Errorf("reloaded accounts mismatch: have %v, want %v", accounts, []Account{account})
+	}
+	if _, err := reloaded[0].SignTxWithPassphrase(account, "foo", tx, nil); err != nil {
+		t.Errorf("failed to sign with reloaded wallet: %v", err)
+	}
+}
+
+// testChainState is a ChainStateReader reporting a nonce for a set of accounts.
+type testChainState map[common.Address]uint64
+
+func (s testChainState) BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error) {
+	return new(big.Int), nil
+}
+
+func (s testChainState) StorageAt(ctx context.Context, account common.Address, key common.Hash, number *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (s testChainState) CodeAt(ctx context.Context, account common.Address, number *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (s testChainState) NonceAt(ctx context.Context, account common.Address, number *big.Int) (uint64, error) {
+	return s[account], nil
+}
+
+// Tests that self-derivation discovers all used accounts plus a fresh one.
+func TestHDSelfDerivation(t *testing.T) {
+	dir, store := tmpHDStore(t)
+	defer os.RemoveAll(dir)
+
+	wallet, err := store.ImportMnemonic(mnemonicTests[1].mnemonic, "")
+	if err != nil {
+		t.Fatalf("failed to import mnemonic: %v", err)
+	}
+	if err := wallet.Open(""); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	seed, _ := MnemonicToSeed(mnemonicTests[1].mnemonic, "")
+
+	state := make(testChainState)
+	for i := 0; i < 3; i++ {
+		path := append(DerivationPath{}, DefaultURBaseDerivationPath...)
+		path[len(path)-1] = uint32(i)
+		key, _ := DeriveKey(seed, path)
+		state[crypto.PubkeyToAddress(key.PublicKey)] = uint64(i + 1)
+	}
+	found, err := SelfDerive(wallet, DefaultURBaseDerivationPath, state)
+	if err != nil {
+		t.Fatalf("self-derivation failed: %v", err)
+	}
+	if len(found) != 4 {
+		t.Fatalf("discovered account count mismatch: have %d, want 4", len(found))
+	}
+	for i, account := range found[:3] {
+		if _, ok := state[account.Address]; !ok {
+			t.Errorf("account %d: unused account %x reported as used", i, account.Address)
+		}
+	}
+	if _, ok := state[found[3].Address]; ok {
+		t.Errorf("fresh account is already used")
+	}
+	if accounts := wallet.Accounts(); !reflect.DeepEqual(accounts, found) {
+		t.Errorf("pinned accounts mismatch: have %v, want %v", accounts, found)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/crypto"
+)
+
+// hdWallet implements the Wallet interface for a mnemonic backed hierarchical
+// deterministic wallet. Opening the wallet decrypts the seed, after which any
+// number of accounts can be derived and used for signing.
+type hdWallet struct {
+	url    string     // Unique URL of the wallet, derived from the master key
+	file   string     // File the encrypted seed and account cache are stored in
+	crypto cryptoJSON // Encrypted BIP-39 seed
+
+	accounts []Account                         // Derived accounts pinned to the wallet
+	paths    map[common.Address]DerivationPath // Derivation paths of the pinned accounts
+	seed     []byte                            // Decrypted seed while the wallet is open
+
+	lock sync.RWMutex // Lock protecting the seed and the account cache
+}
+
+// URL implements Wallet, returning the URL of the HD wallet.
+func (w *hdWallet) URL() string {
+	return w.url
+}
+
+// Status implements Wallet, reporting whether the seed is decrypted.
+func (w *hdWallet) Status() string {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.seed != nil {
+		return "Unlocked"
+	}
+	return "Locked"
+}
+
+// Open implements Wallet, decrypting the seed with the given passphrase.
+func (w *hdWallet) Open(passphrase string) error {
+	seed, err := decryptData(w.crypto, passphrase)
+	if err != nil {
+		return err
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.seed != nil {
+		zeroBytes(w.seed)
+	}
+	w.seed = seed
+	return nil
+}
+
+// Close implements Wallet, wiping the decrypted seed from memory.
+func (w *hdWallet) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.seed != nil {
+		zeroBytes(w.seed)
+		w.seed = nil
+	}
+	return nil
+}
+
+// Accounts implements Wallet, returning the cached list of derived accounts.
+func (w *hdWallet) Accounts() []Account {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	cpy := make([]Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	return cpy
+}
+
+// Contains implements Wallet, returning whether an account was derived and
+// pinned to this wallet.
+func (w *hdWallet) Contains(account Account) bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	_, exists := w.paths[account.Address]
+	return exists
+}
+
+// Derive implements Wallet, deriving the account at the given path from the
+// decrypted seed. Pinned accounts are persisted into the wallet's account cache.
+func (w *hdWallet) Derive(path DerivationPath, pin bool) (Account, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.seed == nil {
+		return Account{}, ErrLocked
+	}
+	key, err := DeriveKey(w.seed, path)
+	if err != nil {
+		return Account{}, err
+	}
+	account := Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+	zeroKey(key)
+
+	if _, ok := w.paths[account.Address]; pin && !ok {
+		w.accounts = append(w.accounts, account)
+		w.paths[account.Address] = append(DerivationPath{}, path...)
+		if err := w.save(); err != nil {
+			w.accounts = w.accounts[:len(w.accounts)-1]
+			delete(w.paths, account.Address)
+			return Account{}, err
+		}
+	}
+	return account, nil
+}
+
+// SignHash implements Wallet, signing the hash with the derived key of the
+// account. The wallet must be open.
+func (w *hdWallet) SignHash(account Account, hash []byte) ([]byte, error) {
+	key, err := w.key(account, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return crypto.SignEthereum(hash, key)
+}
+
+// SignTx implements Wallet, signing the transaction with the derived key of the
+// account. The wallet must be open.
+func (w *hdWallet) SignTx(account Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := w.key(account, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return types.SignECDSA(txSigner(chainID), tx, key)
+}
+
+// SignHashWithPassphrase implements Wallet, signing the hash with the derived
+// key of the account, decrypting the seed with passphrase just for this call.
+func (w *hdWallet) SignHashWithPassphrase(account Account, passphrase string, hash []byte) ([]byte, error) {
+	key, err := w.key(account, &passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return crypto.SignEthereum(hash, key)
+}
+
+// SignTxWithPassphrase implements Wallet, signing the transaction with the
+// derived key of the account, decrypting the seed with passphrase just for this
+// call.
+func (w *hdWallet) SignTxWithPassphrase(account Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := w.key(account, &passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return types.SignECDSA(txSigner(chainID), tx, key)
+}
+
+// key derives the private key of a pinned account, either from the open seed or
+// by decrypting the seed with the passphrase if one is given.
+func (w *hdWallet) key(account Account, passphrase *string) (*ecdsa.PrivateKey, error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	path, ok := w.paths[account.Address]
+	if !ok {
+		return nil, ErrUnknownAccount
+	}
+	seed := w.seed
+	if passphrase != nil {
+		var err error
+		if seed, err = decryptData(w.crypto, *passphrase); err != nil {
+			return nil, err
+		}
+		defer zeroBytes(seed)
+	}
+	if seed == nil {
+		return nil, ErrLocked
+	}
+	return DeriveKey(seed, path)
+}
+
+// save flushes the encrypted seed and the account cache to disk. The caller
+// must hold the wallet lock if the wallet is already shared.
+func (w *hdWallet) save() error {
+	enc := hdWalletJSON{
+		Address:  strings.TrimPrefix(w.url, HDScheme+"://"),
+		Crypto:   w.crypto,
+		Accounts: make([]hdAccountJSON, len(w.accounts)),
+		Version:  hdWalletVersion,
+	}
+	for i, account := range w.accounts {
+		enc.Accounts[i] = hdAccountJSON{
+			Address: common.Bytes2Hex(account.Address[:]),
+			Path:    w.paths[account.Address].String(),
+		}
+	}
+	blob, err := json.Marshal(enc)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(w.file, blob)
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/ur-technology/go-ur/crypto"
 	"github.com/ur-technology/go-ur/crypto/randentropy"
 	"github.com/pborman/uuid"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/scrypt"
 )
@@ -62,10 +63,86 @@ const (
 	// memory and taking approximately 100ms CPU time on a modern processor.
 	LightScryptP = 6
 
+	// StandardArgon2Time is the number of passes of the Argon2id encryption
+	// algorithm, using 256MB memory and taking approximately 1s CPU time.
+	StandardArgon2Time = 3
+
+	// StandardArgon2Memory is the memory in KiB used by the Argon2id encryption
+	// algorithm, using 256MB memory and taking approximately 1s CPU time.
+	StandardArgon2Memory = 256 * 1024
+
+	// LightArgon2Time is the number of passes of the Argon2id encryption
+	// algorithm, using 4MB memory and taking approximately 100ms CPU time.
+	LightArgon2Time = 4
+
+	// LightArgon2Memory is the memory in KiB used by the Argon2id encryption
+	// algorithm, using 4MB memory and taking approximately 100ms CPU time.
+	LightArgon2Memory = 4 * 1024
+
+	// Argon2Threads is the parallelism of the Argon2id encryption algorithm.
+	Argon2Threads = 4
+
 	scryptR     = 8
 	scryptDKLen = 32
 )
 
+// Key derivation functions supported for key encryption.
+const (
+	KDFScrypt   = "scrypt"
+	KDFArgon2id = "argon2id"
+)
+
+// KDFConfig selects the key derivation function and its cost parameters to
+// encrypt keys with.
+type KDFConfig struct {
+	KDF string // Key derivation function, KDFScrypt or KDFArgon2id
+
+	ScryptN int // Scrypt CPU/memory cost parameter
+	ScryptP int // Scrypt parallelization parameter
+
+	Argon2Time    uint32 // Argon2id number of passes over the memory
+	Argon2Memory  uint32 // Argon2id memory size in KiB
+	Argon2Threads uint8  // Argon2id degree of parallelism
+}
+
+// ScryptKDF returns a scrypt key derivation config with the given parameters.
+func ScryptKDF(n, p int) KDFConfig {
+	return KDFConfig{KDF: KDFScrypt, ScryptN: n, ScryptP: p}
+}
+
+// Argon2idKDF returns an Argon2id key derivation config with the given time
+// and memory (in KiB) costs.
+func Argon2idKDF(time, memory uint32) KDFConfig {
+	return KDFConfig{KDF: KDFArgon2id, Argon2Time: time, Argon2Memory: memory, Argon2Threads: Argon2Threads}
+}
+
+// deriveKey derives a fresh encryption key from auth, returning it along with
+// the KDF parameters to store for decryption.
+func (c KDFConfig) deriveKey(auth string, salt []byte) ([]byte, map[string]interface{}, error) {
+	params := map[string]interface{}{
+		"dklen": scryptDKLen,
+		"salt":  hex.EncodeToString(salt),
+	}
+	switch c.KDF {
+	case KDFScrypt:
+		derivedKey, err := scrypt.Key([]byte(auth), salt, c.ScryptN, scryptR, c.ScryptP, scryptDKLen)
+		if err != nil {
+			return nil, nil, err
+		}
+		params["n"], params["r"], params["p"] = c.ScryptN, scryptR, c.ScryptP
+		return derivedKey, params, nil
+
+	case KDFArgon2id:
+		if c.Argon2Time < 1 || c.Argon2Threads < 1 || c.Argon2Memory < 8*uint32(c.Argon2Threads) {
+			return nil, nil, fmt.Errorf("invalid Argon2id parameters: t=%d m=%d p=%d", c.Argon2Time, c.Argon2Memory, c.Argon2Threads)
+		}
+		derivedKey := argon2.IDKey([]byte(auth), salt, c.Argon2Time, c.Argon2Memory, c.Argon2Threads, scryptDKLen)
+		params["t"], params["m"], params["p"] = int(c.Argon2Time), int(c.Argon2Memory), int(c.Argon2Threads)
+		return derivedKey, params, nil
+	}
+	return nil, nil, fmt.Errorf("Unsupported KDF: %s", c.KDF)
+}
+
 type keyStorePassphrase struct {
 	keysDirPath string
 	scryptN     int
@@ -108,49 +185,60 @@ func (ks keyStorePassphrase) JoinPath(filename string) string {
 // EncryptKey encrypts a key using the specified scrypt parameters into a json
 // blob that can be decrypted later on.
 func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
-	authArray := []byte(auth)
-	salt := randentropy.GetEntropyCSPRNG(32)
-	derivedKey, err := scrypt.Key(authArray, salt, scryptN, scryptR, scryptP, scryptDKLen)
+	return EncryptKeyKDF(key, auth, ScryptKDF(scryptN, scryptP))
+}
+
+// EncryptKeyKDF encrypts a key using the specified key derivation function into
+// a json blob that can be decrypted later on.
+func EncryptKeyKDF(key *Key, auth string, kdf KDFConfig) ([]byte, error) {
+	keyBytes := common.LeftPadBytes(crypto.FromECDSA(key.PrivateKey), 32)
+	cryptoStruct, err := encryptDataKDF(keyBytes, auth, kdf)
 	if err != nil {
 		return nil, err
 	}
+	encryptedKeyJSONV3 := encryptedKeyJSONV3{
+		hex.EncodeToString(key.Address[:]),
+		cryptoStruct,
+		key.Id.String(),
+		version,
+	}
+	return json.Marshal(encryptedKeyJSONV3)
+}
+
+// encryptData encrypts an arbitrary blob with a key derived from auth using the
+// scrypt parameters, returning the Web3 Secret Storage crypto section.
+func encryptData(data []byte, auth string, scryptN, scryptP int) (cryptoJSON, error) {
+	return encryptDataKDF(data, auth, ScryptKDF(scryptN, scryptP))
+}
+
+// encryptDataKDF encrypts an arbitrary blob with a key derived from auth using
+// the given key derivation function.
+func encryptDataKDF(data []byte, auth string, kdf KDFConfig) (cryptoJSON, error) {
+	salt := randentropy.GetEntropyCSPRNG(32)
+	derivedKey, kdfParams, err := kdf.deriveKey(auth, salt)
+	if err != nil {
+		return cryptoJSON{}, err
+	}
 	encryptKey := derivedKey[:16]
-	keyBytes0 := crypto.FromECDSA(key.PrivateKey)
-	keyBytes := common.LeftPadBytes(keyBytes0, 32)
 
 	iv := randentropy.GetEntropyCSPRNG(aes.BlockSize) // 16
-	cipherText, err := aesCTRXOR(encryptKey, keyBytes, iv)
+	cipherText, err := aesCTRXOR(encryptKey, data, iv)
 	if err != nil {
-		return nil, err
+		return cryptoJSON{}, err
 	}
 	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
 
-	scryptParamsJSON := make(map[string]interface{}, 5)
-	scryptParamsJSON["n"] = scryptN
-	scryptParamsJSON["r"] = scryptR
-	scryptParamsJSON["p"] = scryptP
-	scryptParamsJSON["dklen"] = scryptDKLen
-	scryptParamsJSON["salt"] = hex.EncodeToString(salt)
-
 	cipherParamsJSON := cipherparamsJSON{
 		IV: hex.EncodeToString(iv),
 	}
-
-	cryptoStruct := cryptoJSON{
+	return cryptoJSON{
 		Cipher:       "aes-128-ctr",
 		CipherText:   hex.EncodeToString(cipherText),
 		CipherParams: cipherParamsJSON,
-		KDF:          "scrypt",
-		KDFParams:    scryptParamsJSON,
+		KDF:          kdf.KDF,
+		KDFParams:    kdfParams,
 		MAC:          hex.EncodeToString(mac),
-	}
-	encryptedKeyJSONV3 := encryptedKeyJSONV3{
-		hex.EncodeToString(key.Address[:]),
-		cryptoStruct,
-		key.Id.String(),
-		version,
-	}
-	return json.Marshal(encryptedKeyJSONV3)
+	}, nil
 }
 
 // DecryptKey decrypts a key from a json blob, returning the private key itself.
@@ -194,42 +282,45 @@ func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byt
 	if keyProtected.Version != version {
 		return nil, nil, fmt.Errorf("Version not supported: %v", keyProtected.Version)
 	}
-
-	if keyProtected.Crypto.Cipher != "aes-128-ctr" {
-		return nil, nil, fmt.Errorf("Cipher not supported: %v", keyProtected.Crypto.Cipher)
-	}
-
 	keyId = uuid.Parse(keyProtected.Id)
-	mac, err := hex.DecodeString(keyProtected.Crypto.MAC)
+	plainText, err := decryptData(keyProtected.Crypto, auth)
 	if err != nil {
 		return nil, nil, err
 	}
+	return plainText, keyId, err
+}
 
-	iv, err := hex.DecodeString(keyProtected.Crypto.CipherParams.IV)
+// decryptData decrypts the blob protected by a Web3 Secret Storage crypto
+// section, verifying the MAC against the key derived from auth.
+func decryptData(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
+	if cryptoJSON.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("Cipher not supported: %v", cryptoJSON.Cipher)
+	}
+	mac, err := hex.DecodeString(cryptoJSON.MAC)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	cipherText, err := hex.DecodeString(keyProtected.Crypto.CipherText)
+	iv, err := hex.DecodeString(cryptoJSON.CipherParams.IV)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	derivedKey, err := getKDFKey(keyProtected.Crypto, auth)
+	cipherText, err := hex.DecodeString(cryptoJSON.CipherText)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	calculatedMAC := crypto.Keccak256(derivedKey[16:32], cipherText)
-	if !bytes.Equal(calculatedMAC, mac) {
-		return nil, nil, ErrDecrypt
+	derivedKey, err := getKDFKey(cryptoJSON, auth)
+	if err != nil {
+		return nil, err
 	}
 
-	plainText, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
-	if err != nil {
-		return nil, nil, err
+	calculatedMAC := crypto.Keccak256(derivedKey[16:32], cipherText)
+	if !bytes.Equal(calculatedMAC, mac) {
+		return nil, ErrDecrypt
 	}
-	return plainText, keyId, err
+	return aesCTRXOR(derivedKey[:16], cipherText, iv)
 }
 
 func decryptKeyV1(keyProtected *encryptedKeyJSONV1, auth string) (keyBytes []byte, keyId []byte, err error) {
@@ -288,6 +379,15 @@ func getKDFKey(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
 		}
 		key := pbkdf2.Key(authArray, salt, c, dkLen, sha256.New)
 		return key, nil
+
+	} else if cryptoJSON.KDF == KDFArgon2id {
+		t := ensureInt(cryptoJSON.KDFParams["t"])
+		m := ensureInt(cryptoJSON.KDFParams["m"])
+		p := ensureInt(cryptoJSON.KDFParams["p"])
+		if t < 1 || p < 1 || p > 255 || m < 8*p {
+			return nil, fmt.Errorf("Invalid Argon2id parameters: t=%d m=%d p=%d", t, m, p)
+		}
+		return argon2.IDKey(authArray, salt, uint32(t), uint32(m), uint8(p), uint32(dkLen)), nil
 	}
 
 	return nil, fmt.Errorf("Unsupported KDF: %s", cryptoJSON.KDF)
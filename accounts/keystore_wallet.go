@@ -0,0 +1,112 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"math/big"
+
+	"github.com/ur-technology/go-ur/core/types"
+)
+
+// keystoreWallet implements the Wallet interface for the original keystore,
+// wrapping a single key file.
+type keystoreWallet struct {
+	account  Account   // Single account contained in this wallet
+	keystore *KeyStore // Keystore where the account originates from
+}
+
+// keystoreURL returns the URL of the wallet wrapping the given account.
+func keystoreURL(account Account) string {
+	return KeyStoreScheme + "://" + account.File
+}
+
+// URL implements Wallet, returning the URL of the key file.
+func (w *keystoreWallet) URL() string {
+	return keystoreURL(w.account)
+}
+
+// Status implements Wallet, reporting whether the key is unlocked.
+func (w *keystoreWallet) Status() string {
+	w.keystore.mu.RLock()
+	defer w.keystore.mu.RUnlock()
+
+	if _, ok := w.keystore.unlocked[w.account.Address]; ok {
+		return "Unlocked"
+	}
+	return "Locked"
+}
+
+// Open implements Wallet, but is a noop for plain wallets since there is no
+// connection or decryption step necessary to access the list of accounts.
+func (w *keystoreWallet) Open(passphrase string) error { return nil }
+
+// Close implements Wallet, but is a noop for plain wallets.
+func (w *keystoreWallet) Close() error { return nil }
+
+// Accounts implements Wallet, returning an account list consisting of a single
+// account that the plain kestore wallet contains.
+func (w *keystoreWallet) Accounts() []Account {
+	return []Account{w.account}
+}
+
+// Contains implements Wallet, returning whether a particular account is or is
+// not wrapped by this wallet instance.
+func (w *keystoreWallet) Contains(account Account) bool {
+	return account.Address == w.account.Address && (account.File == "" || account.File == w.account.File)
+}
+
+// Derive implements Wallet, but is a noop for plain wallets since there is no
+// notion of hierarchical account derivation for plain keystore accounts.
+func (w *keystoreWallet) Derive(path DerivationPath, pin bool) (Account, error) {
+	return Account{}, ErrNotSupported
+}
+
+// SignHash implements Wallet, attempting to sign the given hash with the given
+// account. The account must be unlocked.
+func (w *keystoreWallet) SignHash(account Account, hash []byte) ([]byte, error) {
+	if !w.Contains(account) {
+		return nil, ErrUnknownAccount
+	}
+	return w.keystore.SignEthereum(account.Address, hash)
+}
+
+// SignTx implements Wallet, attempting to sign the given transaction with the
+// given account. The account must be unlocked.
+func (w *keystoreWallet) SignTx(account Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if !w.Contains(account) {
+		return nil, ErrUnknownAccount
+	}
+	return w.keystore.SignTx(w.account, tx, chainID)
+}
+
+// SignHashWithPassphrase implements Wallet, attempting to sign the given hash
+// with the given account using passphrase as extra authentication.
+func (w *keystoreWallet) SignHashWithPassphrase(account Account, passphrase string, hash []byte) ([]byte, error) {
+	if !w.Contains(account) {
+		return nil, ErrUnknownAccount
+	}
+	return w.keystore.SignWithPassphrase(account.Address, passphrase, hash)
+}
+
+// SignTxWithPassphrase implements Wallet, attempting to sign the given
+// transaction with the given account using passphrase as extra authentication.
+func (w *keystoreWallet) SignTxWithPassphrase(account Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if !w.Contains(account) {
+		return nil, ErrUnknownAccount
+	}
+	return w.keystore.SignTxWithPassphrase(w.account, passphrase, tx, chainID)
+}
@@ -0,0 +1,192 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/event"
+)
+
+// Manager is an overarching account manager that can communicate with various
+// backends for signing transactions.
+type Manager struct {
+	backends map[reflect.Type][]Backend // Index of backends currently registered
+	all      []Backend                  // All backends in registration order
+	subs     []event.Subscription       // Wallet update subscriptions of all backends
+	mux      *event.TypeMux             // Event mux to forward wallet updates on
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewManager creates a generic account manager to sign transaction via various
+// supported backends.
+func NewManager(backends ...Backend) *Manager {
+	am := &Manager{
+		backends: make(map[reflect.Type][]Backend),
+		all:      backends,
+		mux:      new(event.TypeMux),
+		quit:     make(chan struct{}),
+	}
+	for _, backend := range backends {
+		kind := reflect.TypeOf(backend)
+		am.backends[kind] = append(am.backends[kind], backend)
+
+		sub := backend.Subscribe()
+		am.subs = append(am.subs, sub)
+
+		am.wg.Add(1)
+		go am.forward(sub)
+	}
+	return am
+}
+
+// forward relays the wallet events of a single backend to the subscribers of
+// the manager.
+func (am *Manager) forward(sub event.Subscription) {
+	defer am.wg.Done()
+	for {
+		select {
+		case ev, ok := <-sub.Chan():
+			if !ok {
+				return
+			}
+			am.mux.Post(ev.Data)
+		case <-am.quit:
+			return
+		}
+	}
+}
+
+// Close terminates the account manager's internal notification processes.
+func (am *Manager) Close() error {
+	select {
+	case <-am.quit:
+		return nil
+	default:
+	}
+	close(am.quit)
+	for _, sub := range am.subs {
+		sub.Unsubscribe()
+	}
+	am.wg.Wait()
+	am.mux.Stop()
+	return nil
+}
+
+// Backends retrieves the backend(s) with the given type from the account manager.
+func (am *Manager) Backends(kind reflect.Type) []Backend {
+	return am.backends[kind]
+}
+
+// KeyStore retrieves the encrypted keystore backend from the account manager, or
+// ErrNoKeyStore if it has none.
+func (am *Manager) KeyStore() (*KeyStore, error) {
+	for _, backend := range am.backends[KeyStoreType] {
+		if ks, ok := backend.(*KeyStore); ok {
+			return ks, nil
+		}
+	}
+	return nil, ErrNoKeyStore
+}
+
+// HDStore retrieves the HD wallet store backend from the account manager, or
+// ErrNoHDStore if it has none.
+func (am *Manager) HDStore() (*HDStore, error) {
+	for _, backend := range am.backends[HDStoreType] {
+		if hs, ok := backend.(*HDStore); ok {
+			return hs, nil
+		}
+	}
+	return nil, ErrNoHDStore
+}
+
+// Wallets returns all signer accounts registered under this account manager,
+// sorted alphabetically by their URL.
+func (am *Manager) Wallets() []Wallet {
+	var wallets []Wallet
+	for _, backend := range am.all {
+		wallets = append(wallets, backend.Wallets()...)
+	}
+	sort.Sort(walletsByURL(wallets))
+	return wallets
+}
+
+// Wallet retrieves the wallet associated with a particular URL.
+func (am *Manager) Wallet(url string) (Wallet, error) {
+	for _, wallet := range am.Wallets() {
+		if wallet.URL() == url {
+			return wallet, nil
+		}
+	}
+	return nil, ErrUnknownWallet
+}
+
+// Accounts returns all the accounts of all the wallets registered under this
+// account manager.
+func (am *Manager) Accounts() []Account {
+	var accounts []Account
+	for _, wallet := range am.Wallets() {
+		accounts = append(accounts, wallet.Accounts()...)
+	}
+	return accounts
+}
+
+// HasAddress reports whether any wallet contains an account with the given
+// address.
+func (am *Manager) HasAddress(addr common.Address) bool {
+	_, err := am.Find(Account{Address: addr})
+	return err == nil
+}
+
+// Find attempts to locate the wallet corresponding to a specific account. Since
+// accounts can be dynamically added to and removed from wallets, this method has
+// a linear runtime in the number of wallets.
+func (am *Manager) Find(account Account) (Wallet, error) {
+	for _, wallet := range am.Wallets() {
+		if wallet.Contains(account) {
+			return wallet, nil
+		}
+	}
+	return nil, ErrUnknownAccount
+}
+
+// AccountByIndex returns the ith account across all wallets.
+func (am *Manager) AccountByIndex(i int) (Account, error) {
+	accounts := am.Accounts()
+	if i < 0 || i >= len(accounts) {
+		return Account{}, fmt.Errorf("account index %d out of range [0, %d]", i, len(accounts)-1)
+	}
+	return accounts[i], nil
+}
+
+// Subscribe creates a subscription receiving a WalletEvent whenever any of the
+// backends detects the arrival or departure of a wallet.
+func (am *Manager) Subscribe() event.Subscription {
+	return am.mux.Subscribe(WalletEvent{})
+}
+
+type walletsByURL []Wallet
+
+func (s walletsByURL) Len() int           { return len(s) }
+func (s walletsByURL) Less(i, j int) bool { return s[i].URL() < s[j].URL() }
+func (s walletsByURL) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
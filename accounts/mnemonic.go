@@ -0,0 +1,127 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ur-technology/go-ur/crypto/randentropy"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// DefaultMnemonicBits is the entropy size of generated mnemonics, resulting in
+// a 24 word phrase.
+const DefaultMnemonicBits = 256
+
+var (
+	ErrInvalidMnemonic  = errors.New("invalid mnemonic")
+	ErrMnemonicChecksum = errors.New("mnemonic checksum mismatch")
+)
+
+// mnemonicIndex maps every BIP-39 word to its 11 bit value.
+var mnemonicIndex = make(map[string]int, len(mnemonicWords))
+
+func init() {
+	for i, word := range mnemonicWords {
+		mnemonicIndex[word] = i
+	}
+}
+
+// NewMnemonic generates a new random BIP-39 mnemonic with the given number of
+// entropy bits, which must be a multiple of 32 between 128 and 256.
+func NewMnemonic(bits int) (string, error) {
+	if bits%32 != 0 || bits < 128 || bits > 256 {
+		return "", fmt.Errorf("invalid entropy size %d: must be a multiple of 32 within [128, 256]", bits)
+	}
+	return EntropyToMnemonic(randentropy.GetEntropyCSPRNG(bits / 8))
+}
+
+// EntropyToMnemonic encodes the raw entropy as a BIP-39 mnemonic, appending the
+// checksum bits taken from the entropy hash.
+func EntropyToMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if bits%32 != 0 || bits < 128 || bits > 256 {
+		return "", fmt.Errorf("invalid entropy size %d: must be a multiple of 32 within [128, 256]", bits)
+	}
+	checksum := sha256.Sum256(entropy)
+	checkbits := uint(bits / 32)
+
+	// Append the checksum to the entropy and slice it up into 11 bit words
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, checkbits)
+	data.Or(data, big.NewInt(int64(checksum[0]>>(8-checkbits))))
+
+	words := make([]string, (bits+int(checkbits))/11)
+	mask := big.NewInt(2047)
+	for i := len(words) - 1; i >= 0; i-- {
+		words[i] = mnemonicWords[new(big.Int).And(data, mask).Int64()]
+		data.Rsh(data, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes a BIP-39 mnemonic into its raw entropy, verifying
+// the embedded checksum.
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words)%3 != 0 || len(words) < 12 || len(words) > 24 {
+		return nil, ErrInvalidMnemonic
+	}
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := mnemonicIndex[word]
+		if !ok {
+			return nil, fmt.Errorf("%v: unknown word %q", ErrInvalidMnemonic, word)
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+	checkbits := uint(len(words) / 3)
+	checksum := byte(new(big.Int).And(data, big.NewInt(1<<checkbits-1)).Int64())
+	data.Rsh(data, checkbits)
+
+	entropy := make([]byte, (len(words)*11-int(checkbits))/8)
+	blob := data.Bytes()
+	copy(entropy[len(entropy)-len(blob):], blob)
+
+	if hash := sha256.Sum256(entropy); hash[0]>>(8-checkbits) != checksum {
+		return nil, ErrMnemonicChecksum
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic reports whether the mnemonic consists of known words with a
+// matching checksum.
+func ValidateMnemonic(mnemonic string) error {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err
+}
+
+// MnemonicToSeed derives the 512 bit BIP-39 seed from a mnemonic and an optional
+// passphrase. The mnemonic is validated before the seed is stretched.
+func MnemonicToSeed(mnemonic, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+passphrase), 2048, 64, sha512.New), nil
+}
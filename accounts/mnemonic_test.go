@@ -0,0 +1,126 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+)
+
+// Reference vectors from the BIP-39 spec, seeds using the passphrase "TREZOR".
+var mnemonicTests = []struct {
+	entropy  string
+	mnemonic string
+	seed     string
+}{
+	{
+		"00000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		"legal winner thank year wave sausage worth useful legal winner thank yellow",
+		"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		"80808080808080808080808080808080",
+		"letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		"d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+	},
+	{
+		"ffffffffffffffffffffffffffffffff",
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+	},
+	{
+		"9e885d952ad362caeb4efe34a8e91bd2",
+		"ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic",
+		"274ddc525802f7c828d8ef7ddbcdc5304e87ac3535913611fbbfa986d0c9e5476c91689f9c8a54fd55bd38606aa6a8595ad213d4c9c9f9aca3fb217069a41028",
+	},
+	{
+		"f585c11aec520db57dd353c69554b21a89b20fb0650966fa0a9d6f74fd989d8f",
+		"void come effort suffer camp survey warrior heavy shoot primary clutch crush open amazing screen patrol group space point ten exist slush involve unfold",
+		"",
+	},
+}
+
+func TestMnemonicVectors(t *testing.T) {
+	for i, tt := range mnemonicTests {
+		entropy := common.FromHex(tt.entropy)
+
+		mnemonic, err := EntropyToMnemonic(entropy)
+		if err != nil {
+			t.Fatalf("test %d: failed to encode entropy: %v", i, err)
+		}
+		if mnemonic != tt.mnemonic {
+			t.Errorf("test %d: mnemonic mismatch: have %q, want %q", i, mnemonic, tt.mnemonic)
+		}
+		decoded, err := MnemonicToEntropy(tt.mnemonic)
+		if err != nil {
+			t.Fatalf("test %d: failed to decode mnemonic: %v", i, err)
+		}
+		if !bytes.Equal(decoded, entropy) {
+			t.Errorf("test %d: entropy mismatch: have %x, want %x", i, decoded, entropy)
+		}
+		if tt.seed == "" {
+			continue
+		}
+		seed, err := MnemonicToSeed(tt.mnemonic, "TREZOR")
+		if err != nil {
+			t.Fatalf("test %d: failed to derive seed: %v", i, err)
+		}
+		if hex.EncodeToString(seed) != tt.seed {
+			t.Errorf("test %d: seed mismatch: have %x, want %s", i, seed, tt.seed)
+		}
+	}
+}
+
+func TestMnemonicValidation(t *testing.T) {
+	valid := mnemonicTests[0].mnemonic
+	if err := ValidateMnemonic("  " + strings.Replace(valid, " ", "\t ", -1) + "\n"); err != nil {
+		t.Errorf("whitespace rejected: %v", err)
+	}
+	if err := ValidateMnemonic(strings.Replace(valid, "about", "abandon", 1)); err != ErrMnemonicChecksum {
+		t.Errorf("checksum mismatch: have %v, want %v", err, ErrMnemonicChecksum)
+	}
+	if err := ValidateMnemonic(strings.Replace(valid, "about", "bitcoins", 1)); err == nil {
+		t.Errorf("unknown word accepted")
+	}
+	if err := ValidateMnemonic("abandon abandon about"); err != ErrInvalidMnemonic {
+		t.Errorf("short mnemonic: have %v, want %v", err, ErrInvalidMnemonic)
+	}
+	for _, bits := range []int{128, 160, 192, 224, 256} {
+		mnemonic, err := NewMnemonic(bits)
+		if err != nil {
+			t.Fatalf("%d bits: failed to generate mnemonic: %v", bits, err)
+		}
+		if words := len(strings.Fields(mnemonic)); words != (bits+bits/32)/11 {
+			t.Errorf("%d bits: word count mismatch: have %d", bits, words)
+		}
+		if err := ValidateMnemonic(mnemonic); err != nil {
+			t.Errorf("%d bits: generated mnemonic invalid: %v", bits, err)
+		}
+	}
+	if _, err := NewMnemonic(100); err == nil {
+		t.Errorf("invalid entropy size accepted")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+)
+
+// ErrPlaintextKeyStore is returned if a plaintext keystore is asked to
+// re-encrypt its keys.
+var ErrPlaintextKeyStore = errors.New("keystore is not encrypted")
+
+// ReencryptAuth returns the current and the new passphrase of an account whose
+// key is being re-encrypted.
+type ReencryptAuth func(account Account) (passphrase, newPassphrase string, err error)
+
+// reencryptedKey is a key file re-encrypted in memory, waiting to be written.
+type reencryptedKey struct {
+	account Account
+	old     []byte // Original content of the key file
+	new     []byte // Re-encrypted content of the key file
+}
+
+// Reencrypt re-encrypts every key of the keystore with the given key derivation
+// function and the new passphrases returned by auth. All the keys are decrypted,
+// re-encrypted and verified to round-trip before any file is touched; the
+// original files are copied into a backup directory within the keystore (hidden
+// from the keystore itself) whose path is returned. If replacing a file fails,
+// the already replaced ones are restored.
+func (ks *KeyStore) Reencrypt(kdf KDFConfig, auth ReencryptAuth) (string, error) {
+	if _, ok := ks.keyStore.(*keyStorePassphrase); !ok {
+		return "", ErrPlaintextKeyStore
+	}
+	// Re-encrypt and verify all the keys in memory
+	var keys []reencryptedKey
+	for _, account := range ks.Accounts() {
+		rekeyed, err := reencryptKey(account, kdf, auth)
+		if err != nil {
+			return "", fmt.Errorf("account %x: %v", account.Address, err)
+		}
+		keys = append(keys, rekeyed)
+	}
+	if len(keys) == 0 {
+		return "", nil
+	}
+	// Back up the original key files, then replace them one by one
+	backup := filepath.Join(ks.keyStore.JoinPath(""), ".backup-"+time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z"))
+	for _, key := range keys {
+		if err := writeKeyFile(filepath.Join(backup, filepath.Base(key.account.File)), key.old); err != nil {
+			return "", fmt.Errorf("failed to back up %s: %v", key.account.File, err)
+		}
+	}
+	for i, key := range keys {
+		if err := writeKeyFile(key.account.File, key.new); err != nil {
+			for _, done := range keys[:i] {
+				if err := writeKeyFile(done.account.File, done.old); err != nil {
+					glog.V(logger.Error).Infof("Failed to restore %s, backup in %s: %v", done.account.File, backup, err)
+				}
+			}
+			return backup, fmt.Errorf("failed to write %s: %v", key.account.File, err)
+		}
+	}
+	return backup, nil
+}
+
+// ReencryptKDF returns the named key derivation function with the cost matching
+// the keystore, i.e. the light parameters if new keys are encrypted with light
+// scrypt and the standard ones otherwise.
+func (ks *KeyStore) ReencryptKDF(name string) (KDFConfig, error) {
+	ksp, ok := ks.keyStore.(*keyStorePassphrase)
+	if !ok {
+		return KDFConfig{}, ErrPlaintextKeyStore
+	}
+	light := ksp.scryptN < StandardScryptN
+	switch name {
+	case KDFScrypt:
+		if light {
+			return ScryptKDF(LightScryptN, LightScryptP), nil
+		}
+		return ScryptKDF(StandardScryptN, StandardScryptP), nil
+	case KDFArgon2id:
+		if light {
+			return Argon2idKDF(LightArgon2Time, LightArgon2Memory), nil
+		}
+		return Argon2idKDF(StandardArgon2Time, StandardArgon2Memory), nil
+	}
+	return KDFConfig{}, fmt.Errorf("unknown KDF %q, use %s or %s", name, KDFScrypt, KDFArgon2id)
+}
+
+// reencryptKey decrypts a key file, re-encrypts it and checks that the result
+// decrypts to the very same key.
+func reencryptKey(account Account, kdf KDFConfig, auth ReencryptAuth) (reencryptedKey, error) {
+	keyjson, err := ioutil.ReadFile(account.File)
+	if err != nil {
+		return reencryptedKey{}, err
+	}
+	passphrase, newPassphrase, err := auth(account)
+	if err != nil {
+		return reencryptedKey{}, err
+	}
+	key, err := DecryptKey(keyjson, passphrase)
+	if err != nil {
+		return reencryptedKey{}, err
+	}
+	defer zeroKey(key.PrivateKey)
+
+	if key.Address != account.Address {
+		return reencryptedKey{}, fmt.Errorf("key content mismatch: have account %x, want %x", key.Address, account.Address)
+	}
+	rekeyed, err := EncryptKeyKDF(key, newPassphrase, kdf)
+	if err != nil {
+		return reencryptedKey{}, err
+	}
+	check, err := DecryptKey(rekeyed, newPassphrase)
+	if err != nil {
+		return reencryptedKey{}, fmt.Errorf("round-trip failed: %v", err)
+	}
+	defer zeroKey(check.PrivateKey)
+
+	if check.Address != key.Address || !bytes.Equal(check.Id, key.Id) || !bytes.Equal(crypto.FromECDSA(check.PrivateKey), crypto.FromECDSA(key.PrivateKey)) {
+		return reencryptedKey{}, errors.New("round-trip failed: key mismatch")
+	}
+	return reencryptedKey{account: account, old: keyjson, new: rekeyed}, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests that a keystore can be re-encrypted with Argon2id under new passphrases
+// and that the original files are backed up.
+func TestReencrypt(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	passphrases := make(map[Account]string)
+	for _, pass := range []string{"foo", "bar"} {
+		account, err := ks.NewAccount(pass)
+		if err != nil {
+			t.Fatalf("failed to create account: %v", err)
+		}
+		passphrases[account] = pass
+	}
+	originals := make(map[string][]byte)
+	for account := range passphrases {
+		originals[account.File], _ = ioutil.ReadFile(account.File)
+	}
+	// Ensure nothing is touched if any of the keys fails to decrypt
+	wrong := func(account Account) (string, string, error) { return "foo", "new", nil }
+	if _, err := ks.Reencrypt(Argon2idKDF(1, 64), wrong); err == nil {
+		t.Fatalf("re-encryption with wrong passphrase succeeded")
+	}
+	for file, blob := range originals {
+		if have, _ := ioutil.ReadFile(file); !bytes.Equal(have, blob) {
+			t.Errorf("key file %s modified by failed re-encryption", file)
+		}
+	}
+	// Re-encrypt all the keys and ensure they decrypt with the new passphrases
+	auth := func(account Account) (string, string, error) {
+		return passphrases[account], passphrases[account] + "-new", nil
+	}
+	backup, err := ks.Reencrypt(Argon2idKDF(1, 64), auth)
+	if err != nil {
+		t.Fatalf("failed to re-encrypt keystore: %v", err)
+	}
+	for account, pass := range passphrases {
+		blob, _ := ioutil.ReadFile(account.File)
+
+		var keyjson encryptedKeyJSONV3
+		if err := json.Unmarshal(blob, &keyjson); err != nil {
+			t.Fatalf("invalid re-encrypted key file: %v", err)
+		}
+		if keyjson.Crypto.KDF != KDFArgon2id {
+			t.Errorf("KDF mismatch: have %s, want %s", keyjson.Crypto.KDF, KDFArgon2id)
+		}
+		if _, err := DecryptKey(blob, pass); err != ErrDecrypt {
+			t.Errorf("old passphrase: have %v, want %v", err, ErrDecrypt)
+		}
+		if key, err := DecryptKey(blob, pass+"-new"); err != nil || key.Address != account.Address {
+			t.Errorf("failed to decrypt re-encrypted key: %v", err)
+		}
+		if saved, _ := ioutil.ReadFile(filepath.Join(backup, filepath.Base(account.File))); !bytes.Equal(saved, originals[account.File]) {
+			t.Errorf("backup of %s mismatch", account.File)
+		}
+	}
+	if accounts := ks.Accounts(); len(accounts) != len(passphrases) {
+		t.Errorf("backup picked up as accounts: have %d, want %d", len(accounts), len(passphrases))
+	}
+}
+
+// Tests that the re-encryption KDF follows the cost of the keystore.
+func TestReencryptKDF(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	if kdf, err := ks.ReencryptKDF(KDFArgon2id); err != nil || kdf != Argon2idKDF(LightArgon2Time, LightArgon2Memory) {
+		t.Errorf("light argon2id mismatch: have %+v, %v", kdf, err)
+	}
+	heavy := NewKeyStore(dir, StandardScryptN, StandardScryptP)
+	if kdf, err := heavy.ReencryptKDF(KDFScrypt); err != nil || kdf != ScryptKDF(StandardScryptN, StandardScryptP) {
+		t.Errorf("standard scrypt mismatch: have %+v, %v", kdf, err)
+	}
+	if _, err := ks.ReencryptKDF("pbkdf2"); err == nil {
+		t.Errorf("unknown KDF accepted")
+	}
+	if _, err := NewPlaintextKeyStore(dir).ReencryptKDF(KDFScrypt); err != ErrPlaintextKeyStore {
+		t.Errorf("plaintext keystore: have %v, want %v", err, ErrPlaintextKeyStore)
+	}
+}
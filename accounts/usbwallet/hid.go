@@ -0,0 +1,61 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package usbwallet implements support for USB hardware wallets.
+package usbwallet
+
+import "fmt"
+
+// hidReportSize is the size of a single HID report exchanged with the wallets.
+const hidReportSize = 64
+
+// DeviceInfo describes a USB HID device found during enumeration.
+type DeviceInfo struct {
+	Path      string // Platform specific path used to open the device
+	VendorID  uint16 // USB vendor identifier of the device
+	ProductID uint16 // USB product identifier of the device
+	Serial    string // Serial number of the device, if known
+}
+
+// String implements fmt.Stringer, returning a short description of the device.
+func (info DeviceInfo) String() string {
+	return fmt.Sprintf("%04x:%04x@%s", info.VendorID, info.ProductID, info.Path)
+}
+
+// Device is an open USB HID device, exchanging fixed size reports.
+type Device interface {
+	// Write sends a single HID report of hidReportSize bytes to the device.
+	Write(report []byte) (int, error)
+
+	// Read blocks until a single HID report of hidReportSize bytes arrives
+	// from the device.
+	Read(report []byte) (int, error)
+
+	// Close releases the device.
+	Close() error
+}
+
+// Transport abstracts the platform USB HID layer, allowing the wallet drivers
+// to be exercised against simulated devices.
+type Transport interface {
+	// Enumerate lists all the HID devices currently attached matching the given
+	// vendor and product identifiers. A zero product identifier matches all the
+	// devices of the vendor.
+	Enumerate(vendorID, productID uint16) ([]DeviceInfo, error)
+
+	// Open opens a previously enumerated device.
+	Open(info DeviceInfo) (Device, error)
+}
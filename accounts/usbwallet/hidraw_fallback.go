@@ -0,0 +1,39 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build !linux
+
+// This is the fallback USB HID transport for platforms without native support.
+// It never finds any devices.
+
+package usbwallet
+
+import "errors"
+
+type nullTransport struct{}
+
+// NativeTransport returns the USB HID transport of the host platform.
+func NativeTransport() Transport {
+	return nullTransport{}
+}
+
+func (nullTransport) Enumerate(vendorID, productID uint16) ([]DeviceInfo, error) {
+	return nil, nil
+}
+
+func (nullTransport) Open(info DeviceInfo) (Device, error) {
+	return nil, errors.New("usb hid not supported on this platform")
+}
@@ -0,0 +1,125 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build linux
+
+package usbwallet
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// hidrawSysfs is the sysfs directory listing the hidraw devices of the system.
+const hidrawSysfs = "/sys/class/hidraw"
+
+// hidrawTransport accesses USB HID devices through the Linux hidraw driver.
+type hidrawTransport struct{}
+
+// NativeTransport returns the USB HID transport of the host platform.
+func NativeTransport() Transport {
+	return hidrawTransport{}
+}
+
+// Enumerate implements Transport, scanning sysfs for hidraw devices with the
+// requested USB identifiers.
+func (hidrawTransport) Enumerate(vendorID, productID uint16) ([]DeviceInfo, error) {
+	nodes, err := filepath.Glob(filepath.Join(hidrawSysfs, "hidraw*"))
+	if err != nil {
+		return nil, err
+	}
+	var infos []DeviceInfo
+	for _, node := range nodes {
+		info, ok := hidrawInfo(node)
+		if !ok || info.VendorID != vendorID || (productID != 0 && info.ProductID != productID) {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// hidrawInfo parses the uevent file of a hidraw sysfs node.
+func hidrawInfo(node string) (DeviceInfo, bool) {
+	file, err := os.Open(filepath.Join(node, "device", "uevent"))
+	if err != nil {
+		return DeviceInfo{}, false
+	}
+	defer file.Close()
+
+	info := DeviceInfo{Path: filepath.Join("/dev", filepath.Base(node))}
+	found := false
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "HID_ID="):
+			// Format is bus:vendor:product, all hexadecimal
+			parts := strings.Split(strings.TrimPrefix(line, "HID_ID="), ":")
+			if len(parts) != 3 || parts[0] != "0003" { // USB bus only
+				return DeviceInfo{}, false
+			}
+			vendor, err1 := strconv.ParseUint(parts[1], 16, 32)
+			product, err2 := strconv.ParseUint(parts[2], 16, 32)
+			if err1 != nil || err2 != nil {
+				return DeviceInfo{}, false
+			}
+			info.VendorID, info.ProductID, found = uint16(vendor), uint16(product), true
+
+		case strings.HasPrefix(line, "HID_UNIQ="):
+			info.Serial = strings.TrimPrefix(line, "HID_UNIQ=")
+		}
+	}
+	return info, found
+}
+
+// Open implements Transport, opening the hidraw device node for read/write.
+func (hidrawTransport) Open(info DeviceInfo) (Device, error) {
+	file, err := os.OpenFile(info.Path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &hidrawDevice{file}, nil
+}
+
+// hidrawDevice is an open hidraw device node.
+type hidrawDevice struct {
+	file *os.File
+}
+
+// Write implements Device. Devices without numbered reports expect the report
+// number 0 to precede the report data.
+func (dev *hidrawDevice) Write(report []byte) (int, error) {
+	n, err := dev.file.Write(append([]byte{0x00}, report...))
+	if n > 0 {
+		n--
+	}
+	return n, err
+}
+
+// Read implements Device.
+func (dev *hidrawDevice) Read(report []byte) (int, error) {
+	return dev.file.Read(report)
+}
+
+// Close implements Device.
+func (dev *hidrawDevice) Close() error {
+	return dev.file.Close()
+}
@@ -0,0 +1,170 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ur-technology/go-ur/accounts"
+	"github.com/ur-technology/go-ur/event"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+)
+
+// LedgerScheme is the protocol scheme prefixing account and wallet URLs.
+const LedgerScheme = "ledger"
+
+// HubType is the reflect type of a USB hardware wallet hub.
+var HubType = reflect.TypeOf(&Hub{})
+
+// refreshThrottling is the minimum time between wallet refreshes to avoid USB
+// trashing.
+const refreshThrottling = 500 * time.Millisecond
+
+// ledgerDeviceIDs are the known USB vendor and product identifiers of the
+// Ledger devices. A zero product identifier matches any product of the vendor.
+var ledgerDeviceIDs = []struct{ vendor, product uint16 }{
+	{0x2581, 0x3b7c}, // Ledger Blue and Nano S, legacy firmware
+	{0x2c97, 0x0000}, // Ledger Blue and Nano S
+}
+
+// Hub is a accounts.Backend that can find and handle Ledger hardware wallets.
+type Hub struct {
+	transport Transport     // USB HID transport to discover and open devices with
+	throttle  time.Duration // Minimum time between two device enumerations
+
+	refreshed time.Time         // Time instance when the list of wallets was last refreshed
+	wallets   []accounts.Wallet // List of Ledger devices currently tracking
+	mux       *event.TypeMux    // Event mux to report wallet arrivals and departures on
+	lock      sync.Mutex        // Lock protecting the wallet list and refresh timestamp
+}
+
+// NewLedgerHub creates a new hardware wallet manager for Ledger devices attached
+// to the host's USB ports.
+func NewLedgerHub() *Hub {
+	return NewLedgerHubWithTransport(NativeTransport())
+}
+
+// NewLedgerHubWithTransport creates a new hardware wallet manager for Ledger
+// devices reachable through the given transport.
+func NewLedgerHubWithTransport(transport Transport) *Hub {
+	return &Hub{
+		transport: transport,
+		throttle:  refreshThrottling,
+		mux:       new(event.TypeMux),
+	}
+}
+
+// Wallets implements accounts.Backend, returning all the currently tracked USB
+// devices that appear to be Ledger hardware wallets.
+func (hub *Hub) Wallets() []accounts.Wallet {
+	// Make sure the list of wallets is up to date
+	hub.refreshWallets()
+
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	cpy := make([]accounts.Wallet, len(hub.wallets))
+	copy(cpy, hub.wallets)
+	return cpy
+}
+
+// Subscribe implements accounts.Backend, creating a subscription to receive
+// notifications on the addition or removal of Ledger wallets.
+func (hub *Hub) Subscribe() event.Subscription {
+	return hub.mux.Subscribe(accounts.WalletEvent{})
+}
+
+// refreshWallets scans the USB devices attached to the machine and updates the
+// list of wallets based on the found devices.
+func (hub *Hub) refreshWallets() {
+	hub.lock.Lock()
+	if time.Since(hub.refreshed) < hub.throttle {
+		hub.lock.Unlock()
+		return
+	}
+	hub.refreshed = time.Now()
+	hub.lock.Unlock()
+
+	// Retrieve the current list of Ledger devices
+	var devices []DeviceInfo
+	for _, id := range ledgerDeviceIDs {
+		infos, err := hub.transport.Enumerate(id.vendor, id.product)
+		if err != nil {
+			glog.V(logger.Debug).Infof("Failed to enumerate USB devices: %v", err)
+			return
+		}
+		devices = append(devices, infos...)
+	}
+	sort.Sort(devicesByPath(devices))
+
+	// Transform the current list of wallets into the new one
+	hub.lock.Lock()
+
+	var (
+		wallets = make([]accounts.Wallet, 0, len(devices))
+		events  []accounts.WalletEvent
+	)
+	for _, device := range devices {
+		url := ledgerURL(device)
+
+		// Drop wallets in front of the next device or those that failed for some reason
+		for len(hub.wallets) > 0 && hub.wallets[0].URL() < url {
+			events = append(events, accounts.WalletEvent{Wallet: hub.wallets[0], Arrive: false})
+			hub.wallets = hub.wallets[1:]
+		}
+		// If there are no more wallets or the device is before the next, wrap new wallet
+		if len(hub.wallets) == 0 || hub.wallets[0].URL() > url {
+			wallet := &ledgerWallet{hub: hub, info: device, url: url}
+
+			events = append(events, accounts.WalletEvent{Wallet: wallet, Arrive: true})
+			wallets = append(wallets, wallet)
+			continue
+		}
+		// If the device is the same as the first wallet, keep it
+		wallets = append(wallets, hub.wallets[0])
+		hub.wallets = hub.wallets[1:]
+	}
+	// Drop any leftover wallets and set the new batch
+	for _, wallet := range hub.wallets {
+		events = append(events, accounts.WalletEvent{Wallet: wallet, Arrive: false})
+	}
+	hub.wallets = wallets
+	hub.lock.Unlock()
+
+	// Release departed devices and fire all wallet events
+	for _, ev := range events {
+		if !ev.Arrive {
+			ev.Wallet.Close()
+		}
+		hub.mux.Post(ev)
+	}
+}
+
+// ledgerURL returns the URL of the wallet wrapping the given device.
+func ledgerURL(device DeviceInfo) string {
+	return LedgerScheme + "://" + device.Path
+}
+
+type devicesByPath []DeviceInfo
+
+func (s devicesByPath) Len() int           { return len(s) }
+func (s devicesByPath) Less(i, j int) bool { return s[i].Path < s[j].Path }
+func (s devicesByPath) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
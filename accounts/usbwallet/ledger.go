@@ -0,0 +1,557 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// This file contains the implementation for interacting with the Ledger hardware
+// wallets. The wire protocol spec can be found in the Ledger Blue GitHub repo:
+// https://raw.githubusercontent.com/LedgerHQ/blue-app-eth/master/doc/ethapp.asc
+
+package usbwallet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/ur-technology/go-ur/accounts"
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+	"github.com/ur-technology/go-ur/rlp"
+)
+
+// ledgerOpcode is an enumeration encoding the supported Ledger opcodes.
+type ledgerOpcode byte
+
+// ledgerParam1 is an enumeration encoding the supported Ledger parameters for
+// specific opcodes. The same parameter values may be reused between opcodes.
+type ledgerParam1 byte
+
+// ledgerParam2 is an enumeration encoding the supported Ledger parameters for
+// specific opcodes. The same parameter values may be reused between opcodes.
+type ledgerParam2 byte
+
+const (
+	ledgerOpRetrieveAddress  ledgerOpcode = 0x02 // Returns the public key and Ethereum address for a given BIP 32 path
+	ledgerOpSignTransaction  ledgerOpcode = 0x04 // Signs an Ethereum transaction after having the user validate the parameters
+	ledgerOpGetConfiguration ledgerOpcode = 0x06 // Returns specific wallet application configuration
+
+	ledgerP1DirectlyFetchAddress    ledgerParam1 = 0x00 // Return address directly from the wallet
+	ledgerP1InitTransactionData     ledgerParam1 = 0x00 // First transaction data block for signing
+	ledgerP1ContTransactionData     ledgerParam1 = 0x80 // Subsequent transaction data block for signing
+	ledgerP2DiscardAddressChainCode ledgerParam2 = 0x00 // Do not return the chain code along with the address
+)
+
+const (
+	ledgerClass   = 0xe0   // APDU instruction class of the Ethereum app
+	ledgerChannel = 0x0101 // HID communication channel used by the wallet
+	ledgerTag     = 0x05   // HID command tag of APDU messages
+
+	ledgerStatusOK       = 0x9000 // Command executed successfully
+	ledgerStatusDenied   = 0x6985 // User rejected the request on the device
+	ledgerStatusNoApp    = 0x6e00 // Instruction class not supported, app not running
+	ledgerStatusNoInstr  = 0x6d00 // Instruction not supported, app not running
+	ledgerMaxChunkLength = 255    // Maximum data length of a single APDU
+)
+
+var (
+	// errLedgerReplyInvalidHeader is the error message returned by a Ledger data
+	// exchange if the device replies with a mismatching header. This usually
+	// means the device is in browser mode.
+	errLedgerReplyInvalidHeader = errors.New("ledger: invalid reply header")
+
+	// errLedgerInvalidReply is returned if the device reply is malformed.
+	errLedgerInvalidReply = errors.New("ledger: invalid reply")
+
+	// errLedgerDenied is returned if the user rejected a request on the device.
+	errLedgerDenied = errors.New("ledger: request denied by user")
+
+	// errLedgerAppOffline is returned if the Ethereum app is not running on the
+	// device.
+	errLedgerAppOffline = errors.New("ledger: ethereum app not running")
+)
+
+// ledgerWallet represents a live USB Ledger hardware wallet.
+type ledgerWallet struct {
+	hub  *Hub       // USB hub the device originates from
+	info DeviceInfo // Device description used to open the wallet
+	url  string     // Textual URL uniquely identifying this wallet
+
+	device   Device                                     // USB device advertising itself as a Ledger wallet, nil if closed
+	version  [3]byte                                    // Current version of the Ledger Ethereum app
+	accounts []accounts.Account                         // List of derive accounts pinned on the Ledger
+	paths    map[common.Address]accounts.DerivationPath // Known derivation paths for signing operations
+
+	lock sync.RWMutex // Lock protecting the wallet fields and serializing device access
+}
+
+// URL implements accounts.Wallet, returning the URL of the Ledger device.
+func (w *ledgerWallet) URL() string {
+	return w.url
+}
+
+// Status implements accounts.Wallet, always whether the Ledger is opened, closed
+// or whether the Ethereum app was not started on it.
+func (w *ledgerWallet) Status() string {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.device == nil {
+		return "Closed"
+	}
+	return fmt.Sprintf("Ethereum app v%d.%d.%d online", w.version[0], w.version[1], w.version[2])
+}
+
+// Open implements accounts.Wallet, attempting to open a USB connection to the
+// Ledger hardware wallet. The Ledger does not require a user passphrase, as the
+// PIN is entered on the device itself.
+func (w *ledgerWallet) Open(passphrase string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.device != nil {
+		return errors.New("ledger: wallet already open")
+	}
+	device, err := w.hub.transport.Open(w.info)
+	if err != nil {
+		return err
+	}
+	w.device = device
+
+	// Ensure the Ethereum app is running and retrieve its version
+	if err := w.resolveVersion(); err != nil {
+		w.device.Close()
+		w.device = nil
+		return err
+	}
+	w.paths = make(map[common.Address]accounts.DerivationPath)
+	glog.V(logger.Info).Infof("Opened %s, ethereum app v%d.%d.%d", w.url, w.version[0], w.version[1], w.version[2])
+	return nil
+}
+
+// Close implements accounts.Wallet, closing the USB connection to the Ledger.
+func (w *ledgerWallet) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.device == nil {
+		return nil
+	}
+	err := w.device.Close()
+	w.device, w.accounts, w.paths = nil, nil, nil
+	return err
+}
+
+// Accounts implements accounts.Wallet, returning the list of accounts pinned to
+// the Ledger hardware wallet.
+func (w *ledgerWallet) Accounts() []accounts.Account {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	cpy := make([]accounts.Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	return cpy
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// or is not pinned into this Ledger instance.
+func (w *ledgerWallet) Contains(account accounts.Account) bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	_, exists := w.paths[account.Address]
+	return exists && account.File == ""
+}
+
+// Derive implements accounts.Wallet, deriving a new account at the specific
+// derivation path. If pin is set to true, the account will be added to the list
+// of tracked accounts.
+func (w *ledgerWallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.device == nil {
+		return accounts.Account{}, accounts.ErrWalletClosed
+	}
+	address, err := w.ledgerDerive(path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	account := accounts.Account{Address: address}
+	if !pin {
+		return account, nil
+	}
+	if _, ok := w.paths[address]; !ok {
+		w.accounts = append(w.accounts, account)
+		w.paths[address] = append(accounts.DerivationPath{}, path...)
+	}
+	return account, nil
+}
+
+// SignHash implements accounts.Wallet, however signing arbitrary data is not
+// supported for Ledger wallets, so this method will always return an error.
+func (w *ledgerWallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTx implements accounts.Wallet. It sends the transaction over to the Ledger
+// wallet to request a confirmation from the user. It returns either the signed
+// transaction or a failure if the user denied the transaction.
+func (w *ledgerWallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.device == nil {
+		return nil, accounts.ErrWalletClosed
+	}
+	path, ok := w.paths[account.Address]
+	if !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return w.ledgerSign(path, account.Address, tx, chainID)
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, however signing arbitrary
+// data is not supported for Ledger wallets, so this method will always return
+// an error.
+func (w *ledgerWallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTxWithPassphrase implements accounts.Wallet. The passphrase is ignored as
+// the user confirms the transaction on the device itself.
+func (w *ledgerWallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.SignTx(account, tx, chainID)
+}
+
+// resolveVersion retrieves the current version of the Ethereum wallet app
+// running on the Ledger wallet.
+//
+// The version retrieval protocol is defined as follows:
+//
+//	CLA | INS | P1 | P2 | Lc | Le
+//	----+-----+----+----+----+---
+//	 E0 | 06  | 00 | 00 | 00 | 04
+//
+// With no input data, and the output data being:
+//
+//	Description                                        | Length
+//	---------------------------------------------------+--------
+//	Flags 01: arbitrary data signature enabled by user | 1 byte
+//	Application major version                          | 1 byte
+//	Application minor version                          | 1 byte
+//	Application patch version                          | 1 byte
+func (w *ledgerWallet) resolveVersion() error {
+	reply, err := w.ledgerExchange(ledgerOpGetConfiguration, 0, 0, nil)
+	if err != nil {
+		return err
+	}
+	if len(reply) != 4 {
+		return errLedgerInvalidReply
+	}
+	copy(w.version[:], reply[1:])
+	return nil
+}
+
+// ledgerDerive retrieves the currently active Ethereum address from a Ledger
+// wallet at the specified derivation path.
+//
+// The address derivation protocol is defined as follows:
+//
+//	CLA | INS | P1 | P2 | Lc  | Le
+//	----+-----+----+----+-----+---
+//	 E0 | 02  | 00 return address
+//	            01 display address and confirm before returning
+//	               | 00: do not return the chain code
+//	               | 01: return the chain code
+//	                    | var | 00
+//
+// Where the input data is:
+//
+//	Description                                      | Length
+//	-------------------------------------------------+--------
+//	Number of BIP 32 derivations to perform (max 10) | 1 byte
+//	First derivation index (big endian)              | 4 bytes
+//	...                                              | 4 bytes
+//	Last derivation index (big endian)               | 4 bytes
+//
+// And the output data is:
+//
+//	Description             | Length
+//	------------------------+-------------------
+//	Public Key length       | 1 byte
+//	Uncompressed Public Key | arbitrary
+//	Ethereum address length | 1 byte
+//	Ethereum address        | 40 bytes hex ascii
+//	Chain code if requested | 32 bytes
+func (w *ledgerWallet) ledgerDerive(derivationPath []uint32) (common.Address, error) {
+	reply, err := w.ledgerExchange(ledgerOpRetrieveAddress, ledgerP1DirectlyFetchAddress, ledgerP2DiscardAddressChainCode, encodeDerivationPath(derivationPath))
+	if err != nil {
+		return common.Address{}, err
+	}
+	// Discard the public key, we don't need that for now
+	if len(reply) < 1 || len(reply) < 1+int(reply[0]) {
+		return common.Address{}, errLedgerInvalidReply
+	}
+	reply = reply[1+int(reply[0]):]
+
+	// Extract the Ethereum hex address string
+	if len(reply) < 1 || len(reply) < 1+int(reply[0]) {
+		return common.Address{}, errLedgerInvalidReply
+	}
+	hexstr := reply[1 : 1+int(reply[0])]
+
+	// Decode the hex sting into an Ethereum address and return
+	var address common.Address
+	if len(hexstr) != 2*common.AddressLength {
+		return common.Address{}, errLedgerInvalidReply
+	}
+	if _, err := hex.Decode(address[:], hexstr); err != nil {
+		return common.Address{}, err
+	}
+	return address, nil
+}
+
+// ledgerSign sends the transaction to the Ledger wallet, and waits for the user
+// to confirm or deny the transaction.
+//
+// The transaction signing protocol is defined as follows:
+//
+//	CLA | INS | P1 | P2 | Lc  | Le
+//	----+-----+----+----+-----+---
+//	 E0 | 04  | 00: first transaction data block
+//	            80: subsequent transaction data block
+//	               | 00 | variable | variable
+//
+// Where the input for the first transaction block (first 255 bytes) is:
+//
+//	Description                                      | Length
+//	-------------------------------------------------+----------
+//	Number of BIP 32 derivations to perform (max 10) | 1 byte
+//	First derivation index (big endian)              | 4 bytes
+//	...                                              | 4 bytes
+//	Last derivation index (big endian)               | 4 bytes
+//	RLP transaction chunk                            | arbitrary
+//
+// And the input for subsequent transaction blocks (first 255 bytes) are:
+//
+//	Description           | Length
+//	----------------------+----------
+//	RLP transaction chunk | arbitrary
+//
+// And the output data is:
+//
+//	Description | Length
+//	------------+---------
+//	signature V | 1 byte
+//	signature R | 32 bytes
+//	signature S | 32 bytes
+func (w *ledgerWallet) ledgerSign(derivationPath []uint32, address common.Address, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	// Ensure the wallet is capable of signing the given transaction
+	if chainID != nil && w.version[0] <= 1 && w.version[1] <= 0 && w.version[2] <= 2 {
+		return nil, fmt.Errorf("ledger v%d.%d.%d doesn't support signing this transaction, please update to v1.0.3 at least", w.version[0], w.version[1], w.version[2])
+	}
+	// Create the transaction RLP based on whether legacy or EIP155 signing was requested
+	var (
+		txrlp []byte
+		err   error
+	)
+	if chainID == nil {
+		if txrlp, err = rlp.EncodeToBytes([]interface{}{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data()}); err != nil {
+			return nil, err
+		}
+	} else {
+		if txrlp, err = rlp.EncodeToBytes([]interface{}{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), chainID, big.NewInt(0), big.NewInt(0)}); err != nil {
+			return nil, err
+		}
+	}
+	payload := append(encodeDerivationPath(derivationPath), txrlp...)
+
+	// Send the request and wait for the response
+	var (
+		op    = ledgerP1InitTransactionData
+		reply []byte
+	)
+	for len(payload) > 0 {
+		// Calculate the size of the next data chunk
+		chunk := ledgerMaxChunkLength
+		if chunk > len(payload) {
+			chunk = len(payload)
+		}
+		// Send the chunk over, ensuring it's processed correctly
+		reply, err = w.ledgerExchange(ledgerOpSignTransaction, op, 0, payload[:chunk])
+		if err != nil {
+			return nil, err
+		}
+		// Shift the payload and ensure subsequent chunks are marked as such
+		payload = payload[chunk:]
+		op = ledgerP1ContTransactionData
+	}
+	// Extract the Ethereum signature and do a sanity validation
+	if len(reply) != 65 {
+		return nil, errLedgerInvalidReply
+	}
+	signature := append(reply[1:], reply[0])
+
+	// Create the correct signer and signature transform based on the chain ID.
+	// The wallet reports V as a single byte, normalize it to the 27/28 form.
+	var signer types.Signer
+	if chainID == nil {
+		signer = types.HomesteadSigner{}
+	} else {
+		signer = types.NewEIP155Signer(chainID)
+		signature[64] = signature[64] - byte(chainID.Uint64()*2+35) + 27
+	}
+	signed, err := tx.WithSignature(signer, signature)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, err
+	}
+	if sender != address {
+		return nil, fmt.Errorf("ledger: signer mismatch: expected %x, got %x", address, sender)
+	}
+	return signed, nil
+}
+
+// ledgerExchange performs a data exchange with the Ledger wallet, sending it a
+// message and retrieving the response.
+//
+// The common transport header is defined as follows:
+//
+//	Description                           | Length
+//	--------------------------------------+----------
+//	Communication channel ID (big endian) | 2 bytes
+//	Command tag                           | 1 byte
+//	Packet sequence index (big endian)    | 2 bytes
+//	Payload                               | arbitrary
+//
+// The Communication channel ID allows commands multiplexing over the same
+// physical link. It is not used for the time being, and should be set to 0101
+// to avoid compatibility issues with implementations ignoring a leading 00 byte.
+//
+// The Command tag describes the message content. Use TAG_APDU (0x05) for standard
+// APDU payloads, or TAG_PING (0x02) for a simple link test.
+//
+// The Packet sequence index describes the current sequence for fragmented
+// payloads. The first fragment index is 0x00.
+//
+// APDU Command payloads are encoded as follows:
+//
+//	Description              | Length
+//	-----------------------------------
+//	APDU length (big endian) | 2 bytes
+//	APDU CLA                 | 1 byte
+//	APDU INS                 | 1 byte
+//	APDU P1                  | 1 byte
+//	APDU P2                  | 1 byte
+//	APDU length              | 1 byte
+//	Optional APDU data       | arbitrary
+func (w *ledgerWallet) ledgerExchange(opcode ledgerOpcode, p1 ledgerParam1, p2 ledgerParam2, data []byte) ([]byte, error) {
+	// Construct the message payload, possibly split into multiple chunks
+	apdu := make([]byte, 2, 7+len(data))
+
+	binary.BigEndian.PutUint16(apdu, uint16(5+len(data)))
+	apdu = append(apdu, []byte{ledgerClass, byte(opcode), byte(p1), byte(p2), byte(len(data))}...)
+	apdu = append(apdu, data...)
+
+	// Stream all the chunks to the device
+	header := []byte{ledgerChannel >> 8, ledgerChannel & 0xff, ledgerTag, 0x00, 0x00} // Channel ID and command tag appended
+	chunk := make([]byte, hidReportSize)
+
+	for i := 0; len(apdu) > 0; i++ {
+		// Construct the new message to stream
+		for j := range chunk {
+			chunk[j] = 0
+		}
+		copy(chunk, header)
+		binary.BigEndian.PutUint16(chunk[3:], uint16(i))
+
+		n := copy(chunk[len(header):], apdu)
+		apdu = apdu[n:]
+
+		// Send over to the device
+		if glog.V(logger.Detail) {
+			glog.Infof("-> %s: %x", w.url, chunk)
+		}
+		if _, err := w.device.Write(chunk); err != nil {
+			return nil, err
+		}
+	}
+
+	// Stream the reply back from the wallet in 64 byte chunks
+	var reply []byte
+	for {
+		// Read the next chunk from the Ledger wallet
+		if _, err := io.ReadFull(w.device, chunk); err != nil {
+			return nil, err
+		}
+		if glog.V(logger.Detail) {
+			glog.Infof("<- %s: %x", w.url, chunk)
+		}
+		// Make sure the transport header matches
+		if chunk[0] != ledgerChannel>>8 || chunk[1] != ledgerChannel&0xff || chunk[2] != ledgerTag {
+			return nil, errLedgerReplyInvalidHeader
+		}
+		// If it's the first chunk, retrieve the total message length
+		var payload []byte
+
+		if chunk[3] == 0x00 && chunk[4] == 0x00 {
+			reply = make([]byte, 0, int(binary.BigEndian.Uint16(chunk[5:7])))
+			payload = chunk[7:]
+		} else {
+			payload = chunk[5:]
+		}
+		// Append to the reply and stop when filled up
+		if left := cap(reply) - len(reply); left > len(payload) {
+			reply = append(reply, payload...)
+		} else {
+			reply = append(reply, payload[:left]...)
+			break
+		}
+	}
+	if len(reply) < 2 {
+		return nil, errLedgerInvalidReply
+	}
+	switch status := binary.BigEndian.Uint16(reply[len(reply)-2:]); status {
+	case ledgerStatusOK:
+		return reply[:len(reply)-2], nil
+	case ledgerStatusDenied:
+		return nil, errLedgerDenied
+	case ledgerStatusNoApp, ledgerStatusNoInstr:
+		return nil, errLedgerAppOffline
+	default:
+		return nil, fmt.Errorf("ledger: unexpected status %04x", status)
+	}
+}
+
+// encodeDerivationPath flattens a derivation path into the wire format used by
+// the Ledger protocol: the number of components followed by each of them in
+// big endian byte order.
+func encodeDerivationPath(path []uint32) []byte {
+	blob := make([]byte, 1+4*len(path))
+	blob[0] = byte(len(path))
+	for i, component := range path {
+		binary.BigEndian.PutUint32(blob[1+4*i:], component)
+	}
+	return blob
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ur-technology/go-ur/accounts"
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/crypto"
+)
+
+// newTestHub creates a hub over a simulated transport without refresh throttling.
+func newTestHub() (*Hub, *SimulatedTransport) {
+	transport := NewSimulatedTransport()
+	hub := NewLedgerHubWithTransport(transport)
+	hub.throttle = 0
+	return hub, transport
+}
+
+// Tests that plugged and unplugged devices are detected and reported.
+func TestLedgerDiscovery(t *testing.T) {
+	hub, transport := newTestHub()
+
+	sub := hub.Subscribe()
+	defer sub.Unsubscribe()
+
+	events := make(chan accounts.WalletEvent, 4)
+	go func() {
+		for ev := range sub.Chan() {
+			events <- ev.Data.(accounts.WalletEvent)
+		}
+	}()
+	if wallets := hub.Wallets(); len(wallets) != 0 {
+		t.Fatalf("wallets found without devices: %v", wallets)
+	}
+	transport.Plug("sim/1", NewSimulatedLedger([]byte("one")))
+	transport.Plug("sim/0", NewSimulatedLedger([]byte("zero")))
+
+	wallets := hub.Wallets()
+	if len(wallets) != 2 || wallets[0].URL() != "ledger://sim/0" || wallets[1].URL() != "ledger://sim/1" {
+		t.Fatalf("wallet list mismatch: have %v", wallets)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-events:
+			if !ev.Arrive {
+				t.Errorf("event %d: departure reported for arrival", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d: timeout waiting for arrival", i)
+		}
+	}
+	transport.Unplug("sim/0")
+	if wallets := hub.Wallets(); len(wallets) != 1 || wallets[0].URL() != "ledger://sim/1" {
+		t.Fatalf("wallet list mismatch after unplug: have %v", wallets)
+	}
+	select {
+	case ev := <-events:
+		if ev.Arrive || ev.Wallet.URL() != "ledger://sim/0" {
+			t.Errorf("departure mismatch: have %v/%v", ev.Wallet.URL(), ev.Arrive)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for departure")
+	}
+}
+
+// Tests that accounts can be derived and transactions signed on a device.
+func TestLedgerSigning(t *testing.T) {
+	hub, transport := newTestHub()
+
+	device := NewSimulatedLedger([]byte("seed"))
+	transport.Plug("sim/0", device)
+
+	wallet := hub.Wallets()[0]
+	if _, err := wallet.Derive(accounts.DefaultBaseDerivationPath, true); err != accounts.ErrWalletClosed {
+		t.Fatalf("derivation on closed wallet: have %v, want %v", err, accounts.ErrWalletClosed)
+	}
+	if err := wallet.Open(""); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	defer wallet.Close()
+
+	if status := wallet.Status(); status != "Ethereum app v1.0.3 online" {
+		t.Errorf("status mismatch: have %q", status)
+	}
+	account, err := wallet.Derive(accounts.DefaultBaseDerivationPath, true)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	if want := crypto.PubkeyToAddress(device.Key(accounts.DefaultBaseDerivationPath).PublicKey); account.Address != want {
+		t.Fatalf("derived address mismatch: have %x, want %x", account.Address, want)
+	}
+	if !wallet.Contains(account) || len(wallet.Accounts()) != 1 {
+		t.Fatalf("derived account not pinned")
+	}
+	// Sign both a legacy and a replay protected transaction, the latter one
+	// large enough to be split across multiple APDUs
+	for _, chainID := range []*big.Int{nil, big.NewInt(18)} {
+		tx := types.NewTransaction(3, common.Address{0x01}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), bytes.Repeat([]byte{0xaa}, 600))
+		signed, err := wallet.SignTx(account, tx, chainID)
+		if err != nil {
+			t.Fatalf("chain %v: failed to sign transaction: %v", chainID, err)
+		}
+		var signer types.Signer = types.HomesteadSigner{}
+		if chainID != nil {
+			signer = types.NewEIP155Signer(chainID)
+		}
+		if sender, err := types.Sender(signer, signed); err != nil || sender != account.Address {
+			t.Errorf("chain %v: sender mismatch: have %x, %v, want %x", chainID, sender, err, account.Address)
+		}
+	}
+	// Ensure rejections and unsupported operations are reported
+	device.Deny = true
+	if _, err := wallet.SignTx(account, types.NewTransaction(0, common.Address{}, new(big.Int), new(big.Int), new(big.Int), nil), nil); err != errLedgerDenied {
+		t.Errorf("denied signing: have %v, want %v", err, errLedgerDenied)
+	}
+	if _, err := wallet.SignHash(account, make([]byte, 32)); err != accounts.ErrNotSupported {
+		t.Errorf("hash signing: have %v, want %v", err, accounts.ErrNotSupported)
+	}
+}
+
+// Tests that opening a device without the Ethereum app running fails.
+func TestLedgerAppOffline(t *testing.T) {
+	hub, transport := newTestHub()
+
+	device := NewSimulatedLedger([]byte("seed"))
+	device.Offline = true
+	transport.Plug("sim/0", device)
+
+	wallet := hub.Wallets()[0]
+	if err := wallet.Open(""); err != errLedgerAppOffline {
+		t.Fatalf("open mismatch: have %v, want %v", err, errLedgerAppOffline)
+	}
+	if status := wallet.Status(); status != "Closed" {
+		t.Errorf("status mismatch: have %q, want %q", status, "Closed")
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/rlp"
+)
+
+// SimulatedTransport is an in-memory USB HID transport, allowing the wallet
+// drivers to be exercised without physical devices attached.
+type SimulatedTransport struct {
+	devices map[string]*SimulatedLedger
+	lock    sync.Mutex
+}
+
+// NewSimulatedTransport creates a USB HID transport without any devices.
+func NewSimulatedTransport() *SimulatedTransport {
+	return &SimulatedTransport{devices: make(map[string]*SimulatedLedger)}
+}
+
+// Plug attaches a simulated device at the given path.
+func (t *SimulatedTransport) Plug(path string, device *SimulatedLedger) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.devices[path] = device
+}
+
+// Unplug detaches the simulated device from the given path.
+func (t *SimulatedTransport) Unplug(path string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.devices, path)
+}
+
+// Enumerate implements Transport, listing the plugged simulated devices. All of
+// them advertise themselves as Ledger Nano S wallets.
+func (t *SimulatedTransport) Enumerate(vendorID, productID uint16) ([]DeviceInfo, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if vendorID != 0x2c97 {
+		return nil, nil
+	}
+	var infos []DeviceInfo
+	for path := range t.devices {
+		infos = append(infos, DeviceInfo{Path: path, VendorID: 0x2c97, ProductID: 0x0001})
+	}
+	return infos, nil
+}
+
+// Open implements Transport, connecting to a plugged simulated device.
+func (t *SimulatedTransport) Open(info DeviceInfo) (Device, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	device, ok := t.devices[info.Path]
+	if !ok {
+		return nil, fmt.Errorf("no device at %s", info.Path)
+	}
+	device.lock.Lock()
+	device.request, device.replies = nil, nil
+	device.lock.Unlock()
+
+	return device, nil
+}
+
+// SimulatedLedger emulates the Ethereum app of a Ledger wallet, deriving keys
+// deterministically from a seed.
+type SimulatedLedger struct {
+	Version [3]byte // Version of the emulated Ethereum app
+	Offline bool    // Whether the Ethereum app is not running
+	Deny    bool    // Whether the user rejects every signing request
+
+	seed    []byte
+	request []byte   // Reassembled APDU stream of the current request
+	txdata  []byte   // Accumulated payload of the transaction being signed
+	replies [][]byte // HID reports waiting to be read
+	lock    sync.Mutex
+}
+
+// NewSimulatedLedger creates a simulated Ledger wallet deriving its keys from
+// the given seed.
+func NewSimulatedLedger(seed []byte) *SimulatedLedger {
+	return &SimulatedLedger{Version: [3]byte{1, 0, 3}, seed: common.CopyBytes(seed)}
+}
+
+// Key returns the private key the simulated wallet derives at the given path.
+func (dev *SimulatedLedger) Key(path []uint32) *ecdsa.PrivateKey {
+	return crypto.ToECDSA(crypto.Keccak256(dev.seed, encodeDerivationPath(path)))
+}
+
+// Write implements Device, reassembling the APDU request from HID reports and
+// processing it once complete.
+func (dev *SimulatedLedger) Write(report []byte) (int, error) {
+	dev.lock.Lock()
+	defer dev.lock.Unlock()
+
+	if len(report) != hidReportSize || report[0] != ledgerChannel>>8 || report[1] != ledgerChannel&0xff || report[2] != ledgerTag {
+		return 0, errors.New("simulated ledger: invalid report")
+	}
+	if binary.BigEndian.Uint16(report[3:5]) == 0 {
+		dev.request = common.CopyBytes(report[5:])
+	} else {
+		dev.request = append(dev.request, report[5:]...)
+	}
+	if len(dev.request) >= 2 {
+		if size := int(binary.BigEndian.Uint16(dev.request)); len(dev.request) >= 2+size {
+			dev.respond(dev.process(dev.request[2 : 2+size]))
+			dev.request = nil
+		}
+	}
+	return len(report), nil
+}
+
+// Read implements Device, returning the next queued reply report.
+func (dev *SimulatedLedger) Read(report []byte) (int, error) {
+	dev.lock.Lock()
+	defer dev.lock.Unlock()
+
+	if len(dev.replies) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(report, dev.replies[0])
+	dev.replies = dev.replies[1:]
+	return n, nil
+}
+
+// Close implements Device.
+func (dev *SimulatedLedger) Close() error {
+	return nil
+}
+
+// respond splits an APDU reply into HID reports and queues them for reading.
+func (dev *SimulatedLedger) respond(reply []byte) {
+	payload := make([]byte, 2, 2+len(reply))
+	binary.BigEndian.PutUint16(payload, uint16(len(reply)))
+	payload = append(payload, reply...)
+
+	for seq := 0; len(payload) > 0; seq++ {
+		report := make([]byte, hidReportSize)
+		report[0], report[1], report[2] = ledgerChannel>>8, ledgerChannel&0xff, ledgerTag
+		binary.BigEndian.PutUint16(report[3:], uint16(seq))
+
+		n := copy(report[5:], payload)
+		payload = payload[n:]
+		dev.replies = append(dev.replies, report)
+	}
+}
+
+// process executes a single APDU command, returning the reply data followed by
+// the status word.
+func (dev *SimulatedLedger) process(apdu []byte) []byte {
+	if len(apdu) < 5 || len(apdu) < 5+int(apdu[4]) {
+		return status(nil, 0x6700) // Wrong length
+	}
+	if dev.Offline || apdu[0] != ledgerClass {
+		return status(nil, ledgerStatusNoApp)
+	}
+	data := apdu[5 : 5+int(apdu[4])]
+
+	switch ledgerOpcode(apdu[1]) {
+	case ledgerOpGetConfiguration:
+		return status([]byte{0x00, dev.Version[0], dev.Version[1], dev.Version[2]}, ledgerStatusOK)
+
+	case ledgerOpRetrieveAddress:
+		path, _, err := decodeDerivationPath(data)
+		if err != nil {
+			return status(nil, 0x6a80) // Invalid data
+		}
+		key := dev.Key(path)
+		pubkey := crypto.FromECDSAPub(&key.PublicKey)
+		address := []byte(hex.EncodeToString(crypto.PubkeyToAddress(key.PublicKey).Bytes()))
+
+		reply := append([]byte{byte(len(pubkey))}, pubkey...)
+		reply = append(reply, byte(len(address)))
+		reply = append(reply, address...)
+		return status(reply, ledgerStatusOK)
+
+	case ledgerOpSignTransaction:
+		if ledgerParam1(apdu[2]) == ledgerP1InitTransactionData {
+			dev.txdata = nil
+		}
+		dev.txdata = append(dev.txdata, data...)
+
+		path, txrlp, err := decodeDerivationPath(dev.txdata)
+		if err != nil {
+			return status(nil, 0x6a80)
+		}
+		// Wait for further chunks until the transaction RLP is complete
+		if _, rest, err := rlp.SplitList(txrlp); err != nil || len(rest) != 0 {
+			if err == rlp.ErrValueTooLarge || err == io.ErrUnexpectedEOF {
+				return status(nil, ledgerStatusOK)
+			}
+			return status(nil, 0x6a80)
+		}
+		if dev.Deny {
+			return status(nil, ledgerStatusDenied)
+		}
+		sig, err := crypto.Sign(crypto.Keccak256(txrlp), dev.Key(path))
+		if err != nil {
+			return status(nil, 0x6f00)
+		}
+		// The wallet reports V in the signature scheme of the transaction
+		v := sig[64] + 27
+		var fields []rlp.RawValue
+		if err := rlp.DecodeBytes(txrlp, &fields); err == nil && len(fields) == 9 {
+			chainID := new(big.Int).SetBytes(decodeRLPString(fields[6]))
+			v = sig[64] + byte(chainID.Uint64()*2+35)
+		}
+		return status(append([]byte{v}, sig[:64]...), ledgerStatusOK)
+
+	default:
+		return status(nil, ledgerStatusNoInstr)
+	}
+}
+
+// status appends the status word to an APDU reply.
+func status(reply []byte, sw uint16) []byte {
+	return append(reply, byte(sw>>8), byte(sw))
+}
+
+// decodeDerivationPath is the inverse of encodeDerivationPath, returning the
+// decoded path and the remaining data.
+func decodeDerivationPath(blob []byte) ([]uint32, []byte, error) {
+	if len(blob) < 1 || len(blob) < 1+4*int(blob[0]) {
+		return nil, nil, errors.New("invalid derivation path")
+	}
+	path := make([]uint32, blob[0])
+	for i := range path {
+		path[i] = binary.BigEndian.Uint32(blob[1+4*i:])
+	}
+	return path, blob[1+4*len(path):], nil
+}
+
+// decodeRLPString returns the content of a single RLP encoded string.
+func decodeRLPString(raw rlp.RawValue) []byte {
+	content, _, err := rlp.SplitString(raw)
+	if err != nil {
+		return nil
+	}
+	return content
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/ur-technology/go-ur/accounts/abi/bind"
@@ -37,6 +38,8 @@ var (
 	solcFlag = flag.String("solc", "solc", "Solidity compiler to use if source builds are requested")
 	excFlag  = flag.String("exc", "", "Comma separated types to exclude from binding")
 
+	jsonFlag = flag.String("combined-json", "", "Path to the Solidity compiler's --combined-json output (abi,bin) to bind")
+
 	pkgFlag  = flag.String("pkg", "", "Package name to generate the binding into")
 	outFlag  = flag.String("out", "", "Output file for the generated binding (default = stdout)")
 	langFlag = flag.String("lang", "go", "Destination language for the bindings (go, java, objc)")
@@ -46,11 +49,14 @@ func main() {
 	// Parse and ensure all needed inputs are specified
 	flag.Parse()
 
-	if *abiFlag == "" && *solFlag == "" {
-		fmt.Printf("No contract ABI (--abi) or Solidity source (--sol) specified\n")
+	if *abiFlag == "" && *solFlag == "" && *jsonFlag == "" {
+		fmt.Printf("No contract ABI (--abi), Solidity source (--sol) or combined-json (--combined-json) specified\n")
+		os.Exit(-1)
+	} else if (*abiFlag != "" || *binFlag != "" || *typFlag != "") && (*solFlag != "" || *jsonFlag != "") {
+		fmt.Printf("Contract ABI (--abi), bytecode (--bin) and type (--type) flags are mutually exclusive with the Solidity source (--sol) and combined-json (--combined-json) flags\n")
 		os.Exit(-1)
-	} else if (*abiFlag != "" || *binFlag != "" || *typFlag != "") && *solFlag != "" {
-		fmt.Printf("Contract ABI (--abi), bytecode (--bin) and type (--type) flags are mutually exclusive with the Solidity source (--sol) flag\n")
+	} else if *solFlag != "" && *jsonFlag != "" {
+		fmt.Printf("Solidity source (--sol) and combined-json (--combined-json) flags are mutually exclusive\n")
 		os.Exit(-1)
 	}
 	if *pkgFlag == "" {
@@ -75,19 +81,45 @@ func main() {
 		bins  []string
 		types []string
 	)
-	if *solFlag != "" {
+	if *solFlag != "" || *jsonFlag != "" {
 		// Generate the list of types to exclude from binding
 		exclude := make(map[string]bool)
 		for _, kind := range strings.Split(*excFlag, ",") {
 			exclude[strings.ToLower(kind)] = true
 		}
-		contracts, err := compiler.CompileSolidity(*solcFlag, *solFlag)
-		if err != nil {
-			fmt.Printf("Failed to build Solidity contract: %v\n", err)
-			os.Exit(-1)
+		var (
+			contracts map[string]*compiler.Contract
+			err       error
+		)
+		if *solFlag != "" {
+			if contracts, err = compiler.CompileSolidity(*solcFlag, *solFlag); err != nil {
+				fmt.Printf("Failed to build Solidity contract: %v\n", err)
+				os.Exit(-1)
+			}
+		} else {
+			combined, err := ioutil.ReadFile(*jsonFlag)
+			if err != nil {
+				fmt.Printf("Failed to read combined-json: %v\n", err)
+				os.Exit(-1)
+			}
+			if contracts, err = compiler.ParseCombinedJSON(combined, "", ""); err != nil {
+				fmt.Printf("Failed to parse combined-json: %v\n", err)
+				os.Exit(-1)
+			}
 		}
-		// Gather all non-excluded contract for binding
-		for name, contract := range contracts {
+		// Gather all non-excluded contract for binding, stripping any source
+		// file prefixes newer compilers add to the contract names
+		names := make([]string, 0, len(contracts))
+		for name := range contracts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			contract := contracts[name]
+			if idx := strings.LastIndex(name, ":"); idx >= 0 {
+				name = name[idx+1:]
+			}
 			if exclude[strings.ToLower(name)] {
 				continue
 			}
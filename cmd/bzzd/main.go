@@ -208,16 +208,20 @@ func getAccount(ctx *cli.Context, stack *node.Node) *ecdsa.PrivateKey {
 		return key
 	}
 	// Otherwise try getting it from the keystore.
-	return decryptStoreAccount(stack.AccountManager(), keyid)
+	ks, err := stack.AccountManager().KeyStore()
+	if err != nil {
+		utils.Fatalf("Failed to access the keystore: %v", err)
+	}
+	return decryptStoreAccount(ks, keyid)
 }
 
-func decryptStoreAccount(accman *accounts.Manager, account string) *ecdsa.PrivateKey {
+func decryptStoreAccount(ks *accounts.KeyStore, account string) *ecdsa.PrivateKey {
 	var a accounts.Account
 	var err error
 	if common.IsHexAddress(account) {
-		a, err = accman.Find(accounts.Account{Address: common.HexToAddress(account)})
+		a, err = ks.Find(accounts.Account{Address: common.HexToAddress(account)})
 	} else if ix, ixerr := strconv.Atoi(account); ixerr == nil {
-		a, err = accman.AccountByIndex(ix)
+		a, err = ks.AccountByIndex(ix)
 	} else {
 		utils.Fatalf("Can't find swarm account key %s", account)
 	}
@@ -18,7 +18,9 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/big"
 	"os"
@@ -98,6 +100,19 @@ var (
 		Name:  "create",
 		Usage: "indicates the action should be create rather than call",
 	}
+	ProfileFlag = cli.BoolFlag{
+		Name:  "profile",
+		Usage: "collects a gas profile per opcode, pc and contract",
+	}
+	ProfileFormatFlag = cli.StringFlag{
+		Name:  "profile.format",
+		Usage: "format of the gas profile (json or pprof)",
+		Value: "json",
+	}
+	ProfileOutputFlag = cli.StringFlag{
+		Name:  "profile.output",
+		Usage: "file to write the gas profile into (default: stdout)",
+	}
 )
 
 func init() {
@@ -115,6 +130,9 @@ func init() {
 		ValueFlag,
 		DumpFlag,
 		InputFlag,
+		ProfileFlag,
+		ProfileFormatFlag,
+		ProfileOutputFlag,
 	}
 	app.Action = run
 }
@@ -128,13 +146,22 @@ func run(ctx *cli.Context) error {
 	sender := statedb.CreateAccount(common.StringToAddress("sender"))
 
 	logger := vm.NewStructLogger(nil)
+	profiler := vm.NewGasProfiler()
 
-	vmenv := NewEnv(statedb, common.StringToAddress("evmuser"), common.Big(ctx.GlobalString(ValueFlag.Name)), vm.Config{
+	config := vm.Config{
 		Debug:     ctx.GlobalBool(DebugFlag.Name),
 		ForceJit:  ctx.GlobalBool(ForceJitFlag.Name),
 		EnableJit: !ctx.GlobalBool(DisableJitFlag.Name),
 		Tracer:    logger,
-	})
+	}
+	if ctx.GlobalBool(ProfileFlag.Name) {
+		format := ctx.GlobalString(ProfileFormatFlag.Name)
+		if format != "json" && format != "pprof" {
+			utils.Fatalf("Unknown profile format %q", format)
+		}
+		config.Debug, config.Tracer = true, profiler
+	}
+	vmenv := NewEnv(statedb, common.StringToAddress("evmuser"), common.Big(ctx.GlobalString(ValueFlag.Name)), config)
 
 	tstart := time.Now()
 
@@ -194,7 +221,13 @@ func run(ctx *cli.Context) error {
 		statedb.Commit(true)
 		fmt.Println(string(statedb.Dump()))
 	}
-	vm.StdErrFormat(logger.StructLogs())
+	if ctx.GlobalBool(ProfileFlag.Name) {
+		if err := writeProfile(ctx, profiler); err != nil {
+			utils.Fatalf("Failed to write gas profile: %v", err)
+		}
+	} else {
+		vm.StdErrFormat(logger.StructLogs())
+	}
 
 	if ctx.GlobalBool(SysStatFlag.Name) {
 		var mem runtime.MemStats
@@ -217,6 +250,29 @@ num gc:     %d
 	return nil
 }
 
+// writeProfile dumps the gas profile collected during execution in the format
+// and into the destination requested on the command line.
+func writeProfile(ctx *cli.Context, profiler *vm.GasProfiler) error {
+	out := io.Writer(os.Stdout)
+	if path := ctx.GlobalString(ProfileOutputFlag.Name); path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	if ctx.GlobalString(ProfileFormatFlag.Name) == "pprof" {
+		return profiler.WritePprof(out)
+	}
+	blob, err := json.MarshalIndent(profiler.Profile(0), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(blob))
+	return err
+}
+
 func main() {
 	if err := app.Run(os.Args); err != nil {
 		fmt.Fprintln(os.Stderr, err)
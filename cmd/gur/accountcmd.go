@@ -19,17 +19,31 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"math/big"
+	"strings"
 
 	"github.com/ur-technology/go-ur/accounts"
 	"github.com/ur-technology/go-ur/cmd/utils"
+	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/console"
+	"github.com/ur-technology/go-ur/core/state"
 	"github.com/ur-technology/go-ur/crypto"
 	"github.com/ur-technology/go-ur/logger"
 	"github.com/ur-technology/go-ur/logger/glog"
+	"golang.org/x/net/context"
 	"gopkg.in/urfave/cli.v1"
 )
 
 var (
+	reencryptKDFFlag = cli.StringFlag{
+		Name:  "kdf",
+		Value: accounts.KDFScrypt,
+		Usage: "Key derivation function to re-encrypt with (scrypt, argon2id)",
+	}
+	reencryptKeepPasswordFlag = cli.BoolFlag{
+		Name:  "keeppassword",
+		Usage: "Keep the current passphrase of every account",
+	}
 	walletCommand = cli.Command{
 		Name:      "wallet",
 		Usage:     "Manage Ethereum presale wallets",
@@ -101,6 +115,80 @@ And finally. DO NOT FORGET YOUR PASSWORD.
 TODO: Please write this
 `,
 			},
+			{
+				Action:    walletList,
+				Name:      "wallets",
+				Usage:     "Print the software and hardware wallets",
+				ArgsUsage: " ",
+				Description: `
+    gur account wallets
+
+Prints all the wallets known to the node: the keystore backed ones as well as
+any connected USB hardware wallets (unless --nousb is set), with their status.
+`,
+			},
+			{
+				Action:    walletDerive,
+				Name:      "derive",
+				Usage:     "Derive an account from a hardware or HD wallet",
+				ArgsUsage: "<url> <path>",
+				Description: `
+    gur account derive ledger:///dev/hidraw0 "m/44'/60'/0'/0"
+
+Opens the wallet identified by <url> and derives the account at the given
+hierarchical deterministic path, pinning it to the wallet. Prints the address.
+Hardware devices may ask for confirmation, mnemonic backed HD wallets prompt for
+their passphrase.
+`,
+			},
+			{
+				Name:  "hd",
+				Usage: "Manage mnemonic backed HD wallets",
+				Subcommands: []cli.Command{
+					{
+						Action:    hdWalletCreate,
+						Name:      "new",
+						Usage:     "Create a new HD wallet",
+						ArgsUsage: " ",
+						Description: `
+    gur account hd new
+
+Generates a new 24 word BIP-39 mnemonic and stores the HD wallet backed by it,
+encrypted with a passphrase you are prompted for. Prints the wallet URL, the
+mnemonic and the first UR account (m/44'/21842'/0'/0/0).
+
+Write the mnemonic down and keep it safe, it is the only backup of every account
+derived from the wallet and it is never shown again.
+`,
+					},
+					{
+						Action:    hdWalletImport,
+						Name:      "import",
+						Usage:     "Import a HD wallet from a mnemonic",
+						ArgsUsage: "<mnemonicFile>",
+						Description: `
+    gur account hd import <mnemonicfile>
+
+Restores the HD wallet backed by the BIP-39 mnemonic in <mnemonicfile>, or read
+interactively if no file is given, encrypting it with a passphrase you are
+prompted for. Prints the wallet URL.
+`,
+					},
+					{
+						Action:    hdWalletScan,
+						Name:      "scan",
+						Usage:     "Discover the used accounts of a HD wallet",
+						ArgsUsage: "<url> [<basePath>]",
+						Description: `
+    gur account hd scan hd://<fingerprint> [<basePath>]
+
+Derives sequential accounts from <basePath> (m/44'/21842'/0'/0 by default) and
+pins every account that has a nonce or balance in the local chain, stopping at
+the first unused one. Prints the discovered addresses.
+`,
+					},
+				},
+			},
 			{
 				Action:    accountCreate,
 				Name:      "new",
@@ -145,6 +233,32 @@ For non-interactive use the passphrase can be specified with the --password flag
 
 Since only one password can be given, only format update can be performed,
 changing your password is only possible interactively.
+`,
+			},
+			{
+				Action:    accountReencrypt,
+				Name:      "reencrypt",
+				Usage:     "Re-encrypt all the accounts with a new KDF or passphrase",
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					reencryptKDFFlag,
+					reencryptKeepPasswordFlag,
+				},
+				Description: `
+    gur account reencrypt [--kdf scrypt|argon2id] [--keeppassword]
+
+Re-encrypts every key in the keystore with the standard (or with --lightkdf the
+light) parameters of the requested key derivation function, e.g. to upgrade the
+scrypt parameters of old keys or to switch to argon2id.
+
+You are prompted for the passphrase of every account not unlocked by one of the
+lines of the --password file, and once for the new passphrase to protect all the
+accounts with, unless --keeppassword is given.
+
+Every key is verified to decrypt to the same key under its new passphrase before
+any file is replaced. The original key files are kept in a hidden backup folder
+inside the keystore, which should be deleted once the new keys are confirmed to
+be working.
 `,
 			},
 			{
@@ -179,30 +293,213 @@ nodes.
 )
 
 func accountList(ctx *cli.Context) error {
-	stack := utils.MakeNode(ctx, clientIdentifier, gitCommit)
-	for i, acct := range stack.AccountManager().Accounts() {
+	stack, _ := makeConfigNode(ctx)
+	for i, acct := range fetchKeystore(stack.AccountManager()).Accounts() {
 		fmt.Printf("Account #%d: {%x} %s\n", i, acct.Address, acct.File)
 	}
 	return nil
 }
 
+// walletList prints all the wallets tracked by the account backends, together
+// with their status and any accounts already known within them.
+func walletList(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	for i, wallet := range stack.AccountManager().Wallets() {
+		fmt.Printf("Wallet #%d: %s (%s)\n", i, wallet.URL(), wallet.Status())
+		for _, acct := range wallet.Accounts() {
+			fmt.Printf("  {%x}\n", acct.Address)
+		}
+	}
+	return nil
+}
+
+// walletDerive opens a hardware wallet and derives the account at the requested
+// HD path, printing its address.
+func walletDerive(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("Wallet URL and derivation path must be given as arguments")
+	}
+	path, err := accounts.ParseDerivationPath(ctx.Args()[1])
+	if err != nil {
+		utils.Fatalf("Invalid derivation path: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	wallet, err := stack.AccountManager().Wallet(ctx.Args()[0])
+	if err != nil {
+		utils.Fatalf("Failed to find wallet: %v", err)
+	}
+	openWallet(ctx, wallet)
+	defer wallet.Close()
+
+	account, err := wallet.Derive(path, true)
+	if err != nil {
+		utils.Fatalf("Failed to derive account: %v", err)
+	}
+	fmt.Printf("Address: {%x}\n", account.Address)
+	return nil
+}
+
+// openWallet opens a wallet, prompting for the passphrase of HD wallets.
+func openWallet(ctx *cli.Context, wallet accounts.Wallet) {
+	passphrase := ""
+	if strings.HasPrefix(wallet.URL(), accounts.HDScheme+"://") {
+		passphrase = getPassPhrase("Unlocking HD wallet "+wallet.URL(), false, 0, utils.MakePasswordList(ctx))
+	}
+	if err := wallet.Open(passphrase); err != nil {
+		utils.Fatalf("Failed to open wallet: %v", err)
+	}
+}
+
+// hdWalletCreate generates a new mnemonic backed HD wallet.
+func hdWalletCreate(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	password := getPassPhrase("Your new HD wallet is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+
+	mnemonic, wallet, err := fetchHDStore(stack.AccountManager()).NewWallet(password)
+	if err != nil {
+		utils.Fatalf("Failed to create HD wallet: %v", err)
+	}
+	if err := wallet.Open(password); err != nil {
+		utils.Fatalf("Failed to open HD wallet: %v", err)
+	}
+	defer wallet.Close()
+
+	account, err := wallet.Derive(accounts.DefaultURBaseDerivationPath, true)
+	if err != nil {
+		utils.Fatalf("Failed to derive account: %v", err)
+	}
+	fmt.Printf("Wallet:   %s\n", wallet.URL())
+	fmt.Printf("Mnemonic: %s\n", mnemonic)
+	fmt.Printf("Address:  {%x}\n", account.Address)
+	return nil
+}
+
+// hdWalletImport restores a HD wallet from an existing mnemonic.
+func hdWalletImport(ctx *cli.Context) error {
+	var mnemonic string
+	if file := ctx.Args().First(); file != "" {
+		blob, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Could not read mnemonic file: %v", err)
+		}
+		mnemonic = string(blob)
+	} else {
+		input, err := console.Stdin.PromptPassword("Mnemonic: ")
+		if err != nil {
+			utils.Fatalf("Failed to read mnemonic: %v", err)
+		}
+		mnemonic = input
+	}
+	if err := accounts.ValidateMnemonic(mnemonic); err != nil {
+		utils.Fatalf("Invalid mnemonic: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	password := getPassPhrase("Your HD wallet is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+
+	wallet, err := fetchHDStore(stack.AccountManager()).ImportMnemonic(mnemonic, password)
+	if err != nil {
+		utils.Fatalf("Failed to import HD wallet: %v", err)
+	}
+	fmt.Printf("Wallet: %s\n", wallet.URL())
+	return nil
+}
+
+// hdWalletScan self-derives the used accounts of a HD wallet against the state
+// of the local chain.
+func hdWalletScan(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("Wallet URL must be given as argument")
+	}
+	base := accounts.DefaultURBaseDerivationPath
+	if len(ctx.Args()) > 1 {
+		var err error
+		if base, err = accounts.ParseDerivationPath(ctx.Args()[1]); err != nil {
+			utils.Fatalf("Invalid derivation path: %v", err)
+		}
+	}
+	stack, _ := makeConfigNode(ctx)
+	wallet, err := stack.AccountManager().Wallet(ctx.Args()[0])
+	if err != nil {
+		utils.Fatalf("Failed to find wallet: %v", err)
+	}
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	statedb, err := chain.State()
+	if err != nil {
+		utils.Fatalf("Failed to open chain state: %v", err)
+	}
+	openWallet(ctx, wallet)
+	defer wallet.Close()
+
+	found, err := accounts.SelfDerive(wallet, base, stateReader{statedb})
+	if err != nil {
+		utils.Fatalf("Failed to discover accounts: %v", err)
+	}
+	for i, account := range found {
+		fmt.Printf("Account #%d: {%x}\n", i, account.Address)
+	}
+	return nil
+}
+
+// stateReader exposes a state database as a chain state reader, ignoring the
+// requested block numbers.
+type stateReader struct {
+	*state.StateDB
+}
+
+func (r stateReader) BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error) {
+	return r.GetBalance(account), nil
+}
+
+func (r stateReader) StorageAt(ctx context.Context, account common.Address, key common.Hash, number *big.Int) ([]byte, error) {
+	value := r.GetState(account, key)
+	return value[:], nil
+}
+
+func (r stateReader) CodeAt(ctx context.Context, account common.Address, number *big.Int) ([]byte, error) {
+	return r.GetCode(account), nil
+}
+
+func (r stateReader) NonceAt(ctx context.Context, account common.Address, number *big.Int) (uint64, error) {
+	return r.GetNonce(account), nil
+}
+
+// fetchKeystore retrieves the encrypted keystore from the account manager.
+func fetchKeystore(am *accounts.Manager) *accounts.KeyStore {
+	ks, err := am.KeyStore()
+	if err != nil {
+		utils.Fatalf("Failed to access the keystore: %v", err)
+	}
+	return ks
+}
+
+// fetchHDStore retrieves the HD wallet store from the account manager.
+func fetchHDStore(am *accounts.Manager) *accounts.HDStore {
+	hs, err := am.HDStore()
+	if err != nil {
+		utils.Fatalf("Failed to access the HD wallet store: %v", err)
+	}
+	return hs
+}
+
 // tries unlocking the specified account a few times.
-func unlockAccount(ctx *cli.Context, accman *accounts.Manager, address string, i int, passwords []string) (accounts.Account, string) {
-	account, err := utils.MakeAddress(accman, address)
+func unlockAccount(ctx *cli.Context, ks *accounts.KeyStore, address string, i int, passwords []string) (accounts.Account, string) {
+	account, err := utils.MakeAddress(ks, address)
 	if err != nil {
 		utils.Fatalf("Could not list accounts: %v", err)
 	}
 	for trials := 0; trials < 3; trials++ {
 		prompt := fmt.Sprintf("Unlocking account %s | Attempt %d/%d", address, trials+1, 3)
 		password := getPassPhrase(prompt, false, i, passwords)
-		err = accman.Unlock(account, password)
+		err = ks.Unlock(account, password)
 		if err == nil {
 			glog.V(logger.Info).Infof("Unlocked account %x", account.Address)
 			return account, password
 		}
 		if err, ok := err.(*accounts.AmbiguousAddrError); ok {
 			glog.V(logger.Info).Infof("Unlocked account %x", account.Address)
-			return ambiguousAddrRecovery(accman, err, password), password
+			return ambiguousAddrRecovery(ks, err, password), password
 		}
 		if err != accounts.ErrDecrypt {
 			// No need to prompt again if the error is not decryption-related.
@@ -244,7 +541,7 @@ func getPassPhrase(prompt string, confirmation bool, i int, passwords []string)
 	return password
 }
 
-func ambiguousAddrRecovery(am *accounts.Manager, err *accounts.AmbiguousAddrError, auth string) accounts.Account {
+func ambiguousAddrRecovery(ks *accounts.KeyStore, err *accounts.AmbiguousAddrError, auth string) accounts.Account {
 	fmt.Printf("Multiple key files exist for address %x:\n", err.Addr)
 	for _, a := range err.Matches {
 		fmt.Println("  ", a.File)
@@ -252,7 +549,7 @@ func ambiguousAddrRecovery(am *accounts.Manager, err *accounts.AmbiguousAddrErro
 	fmt.Println("Testing your passphrase against all of them...")
 	var match *accounts.Account
 	for _, a := range err.Matches {
-		if err := am.Unlock(a, auth); err == nil {
+		if err := ks.Unlock(a, auth); err == nil {
 			match = &a
 			break
 		}
@@ -272,10 +569,10 @@ func ambiguousAddrRecovery(am *accounts.Manager, err *accounts.AmbiguousAddrErro
 
 // accountCreate creates a new account into the keystore defined by the CLI flags.
 func accountCreate(ctx *cli.Context) error {
-	stack := utils.MakeNode(ctx, clientIdentifier, gitCommit)
+	stack, _ := makeConfigNode(ctx)
 	password := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
 
-	account, err := stack.AccountManager().NewAccount(password)
+	account, err := fetchKeystore(stack.AccountManager()).NewAccount(password)
 	if err != nil {
 		utils.Fatalf("Failed to create account: %v", err)
 	}
@@ -289,15 +586,78 @@ func accountUpdate(ctx *cli.Context) error {
 	if len(ctx.Args()) == 0 {
 		utils.Fatalf("No accounts specified to update")
 	}
-	stack := utils.MakeNode(ctx, clientIdentifier, gitCommit)
-	account, oldPassword := unlockAccount(ctx, stack.AccountManager(), ctx.Args().First(), 0, nil)
+	stack, _ := makeConfigNode(ctx)
+	ks := fetchKeystore(stack.AccountManager())
+
+	account, oldPassword := unlockAccount(ctx, ks, ctx.Args().First(), 0, nil)
 	newPassword := getPassPhrase("Please give a new password. Do not forget this password.", true, 0, nil)
-	if err := stack.AccountManager().Update(account, oldPassword, newPassword); err != nil {
+	if err := ks.Update(account, oldPassword, newPassword); err != nil {
 		utils.Fatalf("Could not update the account: %v", err)
 	}
 	return nil
 }
 
+// accountReencrypt re-encrypts every key of the keystore with new KDF
+// parameters and optionally a new passphrase.
+func accountReencrypt(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	ks := fetchKeystore(stack.AccountManager())
+
+	kdf, err := ks.ReencryptKDF(ctx.String(reencryptKDFFlag.Name))
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	var newPassword string
+	keep := ctx.Bool(reencryptKeepPasswordFlag.Name)
+	if !keep {
+		newPassword = getPassPhrase("Please give a new password for all the accounts. Do not forget this password.", true, 0, nil)
+	}
+	passwords := utils.MakePasswordList(ctx)
+	auth := func(account accounts.Account) (string, string, error) {
+		password, err := findPassword(account, passwords)
+		if err != nil {
+			return "", "", err
+		}
+		if keep {
+			return password, password, nil
+		}
+		return password, newPassword, nil
+	}
+	backup, err := ks.Reencrypt(kdf, auth)
+	if err != nil {
+		utils.Fatalf("Failed to re-encrypt accounts: %v", err)
+	}
+	if backup == "" {
+		fmt.Println("No accounts to re-encrypt")
+		return nil
+	}
+	fmt.Printf("Re-encrypted %d accounts with %s\n", len(ks.Accounts()), kdf.KDF)
+	fmt.Printf("Original key files backed up to %s\n", backup)
+	return nil
+}
+
+// findPassword returns the passphrase decrypting the key of an account, trying
+// the given passwords first and prompting for it otherwise.
+func findPassword(account accounts.Account, passwords []string) (string, error) {
+	keyjson, err := ioutil.ReadFile(account.File)
+	if err != nil {
+		return "", err
+	}
+	for _, password := range passwords {
+		if _, err := accounts.DecryptKey(keyjson, password); err == nil {
+			return password, nil
+		}
+	}
+	for trials := 0; trials < 3; trials++ {
+		prompt := fmt.Sprintf("Unlocking account %s | Attempt %d/%d", account.Address.Hex(), trials+1, 3)
+		password := getPassPhrase(prompt, false, 0, nil)
+		if _, err := accounts.DecryptKey(keyjson, password); err == nil {
+			return password, nil
+		}
+	}
+	return "", accounts.ErrDecrypt
+}
+
 func importWallet(ctx *cli.Context) error {
 	keyfile := ctx.Args().First()
 	if len(keyfile) == 0 {
@@ -308,9 +668,9 @@ func importWallet(ctx *cli.Context) error {
 		utils.Fatalf("Could not read wallet file: %v", err)
 	}
 
-	stack := utils.MakeNode(ctx, clientIdentifier, gitCommit)
+	stack, _ := makeConfigNode(ctx)
 	passphrase := getPassPhrase("", false, 0, utils.MakePasswordList(ctx))
-	acct, err := stack.AccountManager().ImportPreSaleKey(keyJson, passphrase)
+	acct, err := fetchKeystore(stack.AccountManager()).ImportPreSaleKey(keyJson, passphrase)
 	if err != nil {
 		utils.Fatalf("%v", err)
 	}
@@ -327,9 +687,9 @@ func accountImport(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Failed to load the private key: %v", err)
 	}
-	stack := utils.MakeNode(ctx, clientIdentifier, gitCommit)
+	stack, _ := makeConfigNode(ctx)
 	passphrase := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
-	acct, err := stack.AccountManager().ImportECDSA(key, passphrase)
+	acct, err := fetchKeystore(stack.AccountManager()).ImportECDSA(key, passphrase)
 	if err != nil {
 		utils.Fatalf("Could not create the account: %v", err)
 	}
@@ -29,8 +29,10 @@ import (
 	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/console"
 	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/archive"
 	"github.com/ur-technology/go-ur/core/state"
 	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/eth/downloader"
 	"github.com/ur-technology/go-ur/ethdb"
 	"github.com/ur-technology/go-ur/logger/glog"
 	"github.com/ur-technology/go-ur/trie"
@@ -39,27 +41,58 @@ import (
 )
 
 var (
+	importExecuteFlag = cli.BoolFlag{
+		Name:  "execute",
+		Usage: "Execute all blocks of an archive, even if it has a state snapshot",
+	}
+	exportArchiveFlag = cli.BoolFlag{
+		Name:  "archive",
+		Usage: "Export into a chunked archive directory with a checksum manifest",
+	}
+	exportStateFlag = cli.BoolFlag{
+		Name:  "state",
+		Usage: "Include a snapshot of the state at the last block in the archive",
+	}
 	importCommand = cli.Command{
 		Action:    importChain,
 		Name:      "import",
-		Usage:     "Import a blockchain file",
-		ArgsUsage: "<filename>",
-		Category:  "BLOCKCHAIN COMMANDS",
+		Usage:     "Import a blockchain file or archive",
+		ArgsUsage: "<filename | archive>",
+		Flags: []cli.Flag{
+			importExecuteFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
-TODO: Please write this
+Imports the blocks of a file written by the export command, or of an archive
+directory written by "export --archive". The checksums of the archive files are
+verified before their blocks are imported.
+
+If the archive has a state snapshot verifying against the root of its last
+block and the local chain is behind it, the blocks are inserted without being
+executed, unless --execute is given. Interrupted archive imports can be resumed
+by running the command again.
 `,
 	}
 	exportCommand = cli.Command{
 		Action:    exportChain,
 		Name:      "export",
-		Usage:     "Export blockchain into file",
-		ArgsUsage: "<filename> [<blockNumFirst> <blockNumLast>]",
-		Category:  "BLOCKCHAIN COMMANDS",
+		Usage:     "Export blockchain into file or archive",
+		ArgsUsage: "<filename | archive> [<blockNumFirst> <blockNumLast>]",
+		Flags: []cli.Flag{
+			exportArchiveFlag,
+			exportStateFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 Requires a first argument of the file to write to.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing.
+
+With --archive, the blocks are written together with their receipts into a new
+archive directory, split into chunks listed with their checksums in a manifest.
+--state additionally includes a snapshot of the state at the last block, which
+allows importing the archive without executing its blocks.
 `,
 	}
 	upgradedbCommand = cli.Command{
@@ -91,6 +124,19 @@ TODO: Please write this
 		Description: `
 The arguments are interpreted as block numbers or hashes.
 Use "ethereum dump 0" to dump the genesis block.
+`,
+	}
+	checkpointCommand = cli.Command{
+		Action:    printCheckpoint,
+		Name:      "checkpoint",
+		Usage:     "Print a block as a trusted sync checkpoint",
+		ArgsUsage: "[<blockHash> | <blockNum>]",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+Prints the current head block, or the given one, in the format accepted by the
+--checkpoint flag. Nodes started with the checkpoint fast sync from it, without
+verifying the seals of the chain before it, and refuse peers not containing it.
+Only share checkpoints deep enough in the chain not to be reorganised.
 `,
 	}
 )
@@ -120,7 +166,11 @@ func importChain(ctx *cli.Context) error {
 	}()
 	// Import the chain
 	start := time.Now()
-	if err := utils.ImportChain(chain, ctx.Args().First()); err != nil {
+	if fn := ctx.Args().First(); archive.IsArchive(fn) {
+		if err := utils.ImportArchive(chain, chainDb, fn, ctx.Bool(importExecuteFlag.Name)); err != nil {
+			utils.Fatalf("Import error: %v", err)
+		}
+	} else if err := utils.ImportChain(chain, fn); err != nil {
 		utils.Fatalf("Import error: %v", err)
 	}
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
@@ -166,15 +216,30 @@ func exportChain(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an argument.")
 	}
+	if ctx.Bool(exportStateFlag.Name) && !ctx.Bool(exportArchiveFlag.Name) {
+		utils.Fatalf("Export error: --%s requires --%s\n", exportStateFlag.Name, exportArchiveFlag.Name)
+	}
 	stack := makeFullNode(ctx)
-	chain, _ := utils.MakeChain(ctx, stack)
+	chain, chainDb := utils.MakeChain(ctx, stack)
 	start := time.Now()
 
 	var err error
 	fp := ctx.Args().First()
-	if len(ctx.Args()) < 3 {
+	switch {
+	case ctx.Bool(exportArchiveFlag.Name):
+		first, last := uint64(0), chain.CurrentBlock().NumberU64()
+		if len(ctx.Args()) >= 3 {
+			var ferr, lerr error
+			first, ferr = strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+			last, lerr = strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+			if ferr != nil || lerr != nil {
+				utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+			}
+		}
+		err = utils.ExportArchive(chain, chainDb, fp, first, last, ctx.Bool(exportStateFlag.Name))
+	case len(ctx.Args()) < 3:
 		err = utils.ExportChain(chain, fp)
-	} else {
+	default:
 		// This can be improved to allow for numbers larger than 9223372036854775807
 		first, ferr := strconv.ParseInt(ctx.Args().Get(1), 10, 64)
 		last, lerr := strconv.ParseInt(ctx.Args().Get(2), 10, 64)
@@ -195,7 +260,7 @@ func exportChain(ctx *cli.Context) error {
 }
 
 func removeDB(ctx *cli.Context) error {
-	stack := utils.MakeNode(ctx, clientIdentifier, gitCommit)
+	stack, _ := makeConfigNode(ctx)
 	dbdir := stack.ResolvePath(utils.ChainDbName(ctx))
 	if !common.FileExist(dbdir) {
 		fmt.Println(dbdir, "does not exist")
@@ -221,7 +286,7 @@ func removeDB(ctx *cli.Context) error {
 func upgradeDB(ctx *cli.Context) error {
 	glog.Infoln("Upgrading blockchain database")
 
-	stack := utils.MakeNode(ctx, clientIdentifier, gitCommit)
+	stack, _ := makeConfigNode(ctx)
 	chain, chainDb := utils.MakeChain(ctx, stack)
 	bcVersion := core.GetBlockChainVersion(chainDb)
 	if bcVersion == 0 {
@@ -287,6 +352,30 @@ func dump(ctx *cli.Context) error {
 	return nil
 }
 
+func printCheckpoint(ctx *cli.Context) error {
+	if len(ctx.Args()) > 1 {
+		utils.Fatalf("This command requires at most one argument.")
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	block := chain.CurrentBlock()
+	if arg := ctx.Args().First(); arg != "" {
+		if hashish(arg) {
+			block = chain.GetBlockByHash(common.HexToHash(arg))
+		} else {
+			num, _ := strconv.ParseUint(arg, 10, 64)
+			block = chain.GetBlockByNumber(num)
+		}
+	}
+	if block == nil {
+		utils.Fatalf("block not found")
+	}
+	fmt.Println(downloader.NewCheckpoint(block.Header(), chain.GetTd(block.Hash(), block.NumberU64())))
+	return nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
@@ -0,0 +1,136 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of go-ur.
+//
+// go-ur is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ur is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ur. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+
+	"github.com/ur-technology/go-ur/cmd/utils"
+	"github.com/ur-technology/go-ur/eth"
+	"github.com/ur-technology/go-ur/node"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dumpConfigCommand = cli.Command{
+		Action:    dumpConfig,
+		Name:      "dumpconfig",
+		Usage:     "Show configuration values",
+		ArgsUsage: " ",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `
+The dumpconfig command prints the effective configuration in TOML format: the
+defaults, overridden by the file given with --config, overridden in turn by any
+other command line flags. The output can be used as a configuration file. No
+node is created, so an etherbase defaulting to the first account of the keystore
+is left unset.
+`,
+	}
+
+	configFileFlag = cli.StringFlag{
+		Name:  "config",
+		Usage: "TOML configuration file",
+	}
+)
+
+// ethstatsConfig is the configuration of the network statistics reporter.
+type ethstatsConfig struct {
+	URL string `toml:",omitempty"`
+}
+
+// whisperConfig is the configuration of the Whisper messaging service.
+type whisperConfig struct {
+	Enabled bool
+}
+
+// gurConfig is the configuration file layout, one table per service.
+type gurConfig struct {
+	Eth      eth.Config
+	Shh      whisperConfig
+	Node     node.Config
+	Ethstats ethstatsConfig
+}
+
+// defaultNodeConfig returns the default node settings of gur.
+func defaultNodeConfig() node.Config {
+	cfg := node.DefaultConfig
+	cfg.Name = clientIdentifier
+	cfg.Version = utils.MakeVersion(gitCommit)
+	return cfg
+}
+
+// makeConfig assembles the configuration from the defaults, the config file and
+// the command line flags (in increasing order of precedence). The eth settings
+// are only resolved when the service is needed, see makeFullNode.
+func makeConfig(ctx *cli.Context) gurConfig {
+	cfg := gurConfig{
+		Eth:  eth.DefaultConfig,
+		Node: defaultNodeConfig(),
+	}
+	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
+		if err := utils.LoadConfig(file, &cfg); err != nil {
+			utils.Fatalf("%v", err)
+		}
+	}
+	// Light clients and servers find each other through the topic discovery
+	if cfg.Eth.LightMode {
+		cfg.Node.NoDiscovery = true
+		cfg.Node.DiscoveryV5 = true
+	}
+	if cfg.Eth.LightServ > 0 {
+		cfg.Node.DiscoveryV5 = true
+	}
+	utils.SetNodeConfig(ctx, &cfg.Node)
+
+	// The eth protocol shares the peer limit of the node
+	cfg.Eth.MaxPeers = cfg.Node.MaxPeers
+
+	// Whisper must be explicitly enabled, but is auto-enabled in --dev mode.
+	if ctx.GlobalIsSet(utils.WhisperEnabledFlag.Name) {
+		cfg.Shh.Enabled = ctx.GlobalBool(utils.WhisperEnabledFlag.Name)
+	} else if ctx.GlobalBool(utils.DevModeFlag.Name) {
+		cfg.Shh.Enabled = true
+	}
+	if ctx.GlobalIsSet(utils.EthStatsURLFlag.Name) {
+		cfg.Ethstats.URL = ctx.GlobalString(utils.EthStatsURLFlag.Name)
+	}
+	return cfg
+}
+
+// makeConfigNode assembles the configuration and creates a node with no services
+// from it.
+func makeConfigNode(ctx *cli.Context) (*node.Node, gurConfig) {
+	cfg := makeConfig(ctx)
+	stack, err := node.New(&cfg.Node)
+	if err != nil {
+		utils.Fatalf("Failed to create the protocol stack: %v", err)
+	}
+	return stack, cfg
+}
+
+// dumpConfig is the dumpconfig command.
+func dumpConfig(ctx *cli.Context) error {
+	cfg := makeConfig(ctx)
+	utils.ApplyEthFlags(ctx, &cfg.Eth)
+
+	out, err := utils.TOMLSettings.Marshal(&cfg)
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(out)
+	return nil
+}
@@ -69,12 +69,14 @@ func init() {
 		upgradedbCommand,
 		removedbCommand,
 		dumpCommand,
+		checkpointCommand,
 		monitorCommand,
 		accountCommand,
 		walletCommand,
 		consoleCommand,
 		attachCommand,
 		javascriptCommand,
+		dumpConfigCommand,
 		{
 			Action:    makedag,
 			Name:      "makedag",
@@ -120,6 +122,7 @@ participating.
 	}
 
 	app.Flags = []cli.Flag{
+		configFileFlag,
 		utils.IdentityFlag,
 		utils.UnlockedAccountFlag,
 		utils.PasswordFileFlag,
@@ -127,11 +130,15 @@ participating.
 		utils.DataDirFlag,
 		utils.KeyStoreDirFlag,
 		utils.OlympicFlag,
+		utils.SyncModeFlag,
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
+		utils.CheckpointFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
+		utils.NoUSBFlag,
+		utils.ExternalSignerFlag,
 		utils.CacheFlag,
 		utils.TrieCacheGenFlag,
 		utils.JSpathFlag,
@@ -163,13 +170,26 @@ participating.
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.RPCJWTSecretFlag,
+		utils.RPCAllowMethodsFlag,
+		utils.RPCDenyMethodsFlag,
+		utils.RPCBatchLimitFlag,
+		utils.RPCRequestLimitFlag,
+		utils.RPCResponseLimitFlag,
+		utils.RPCTimeoutFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateBurstFlag,
+		utils.RPCMethodRateLimitFlag,
+		utils.GraphQLEnabledFlag,
 		utils.IPCDisabledFlag,
+		utils.StdIORPCFlag,
 		utils.IPCApiFlag,
 		utils.IPCPathFlag,
 		utils.ExecFlag,
 		utils.PreloadJSFlag,
 		utils.WhisperEnabledFlag,
 		utils.DevModeFlag,
+		utils.NetworkFlag,
 		utils.TestNetFlag,
 		utils.VMForceJitFlag,
 		utils.VMJitCacheFlag,
@@ -181,13 +201,12 @@ participating.
 		utils.SolcPathFlag,
 		utils.GpoMinGasPriceFlag,
 		utils.GpoMaxGasPriceFlag,
-		utils.GpoFullBlockRatioFlag,
-		utils.GpobaseStepDownFlag,
-		utils.GpobaseStepUpFlag,
-		utils.GpobaseCorrectionFactorFlag,
+		utils.GpoBlocksFlag,
+		utils.GpoPercentileFlag,
 		// utils.ExtraDataFlag,
 	}
 	app.Flags = append(app.Flags, debug.Flags...)
+	app.Flags = append(app.Flags, utils.DeprecatedFlags...)
 
 	app.Before = func(ctx *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
@@ -273,18 +292,22 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		glog.V(logger.Debug).Infof("extra: %x\n", extra)
 		extra = nil
 	}
-	stack := utils.MakeNode(ctx, clientIdentifier, gitCommit)
-	utils.RegisterEthService(ctx, stack, extra)
+	stack, cfg := makeConfigNode(ctx)
 
-	// Whisper must be explicitly enabled, but is auto-enabled in --dev mode.
-	shhEnabled := ctx.GlobalBool(utils.WhisperEnabledFlag.Name)
-	shhAutoEnabled := !ctx.GlobalIsSet(utils.WhisperEnabledFlag.Name) && ctx.GlobalIsSet(utils.DevModeFlag.Name)
-	if shhEnabled || shhAutoEnabled {
+	cfg.Eth.ExtraData = extra
+	utils.SetEthConfig(ctx, stack, &cfg.Eth)
+	utils.RegisterEthService(stack, &cfg.Eth)
+
+	if cfg.Shh.Enabled {
 		utils.RegisterShhService(stack)
 	}
+	// Add the GraphQL endpoint if requested
+	if ctx.GlobalBool(utils.GraphQLEnabledFlag.Name) {
+		utils.RegisterGraphQLService(stack)
+	}
 	// Add the Ethereum Stats daemon if requested
-	if url := ctx.GlobalString(utils.EthStatsURLFlag.Name); url != "" {
-		utils.RegisterEthStatsService(stack, url)
+	if cfg.Ethstats.URL != "" {
+		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
 	}
 	// Add the release oracle service so it boots along with node.
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
@@ -311,12 +334,12 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 	utils.StartNode(stack)
 
 	// Unlock any account specifically requested
-	accman := stack.AccountManager()
+	ks := fetchKeystore(stack.AccountManager())
 	passwords := utils.MakePasswordList(ctx)
 	accounts := strings.Split(ctx.GlobalString(utils.UnlockedAccountFlag.Name), ",")
 	for i, account := range accounts {
 		if trimmed := strings.TrimSpace(account); trimmed != "" {
-			unlockAccount(ctx, accman, trimmed, i, passwords)
+			unlockAccount(ctx, ks, trimmed, i, passwords)
 		}
 	}
 	// Start auxiliary services if enabled
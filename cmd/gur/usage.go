@@ -64,18 +64,24 @@ var AppHelpFlagGroups = []flagGroup{
 	{
 		Name: "UR",
 		Flags: []cli.Flag{
+			configFileFlag,
 			utils.DataDirFlag,
 			utils.KeyStoreDirFlag,
+			utils.NetworkFlag,
 			utils.NetworkIdFlag,
 			utils.OlympicFlag,
 			utils.TestNetFlag,
 			utils.DevModeFlag,
 			utils.IdentityFlag,
+			utils.SyncModeFlag,
 			utils.FastSyncFlag,
 			utils.LightModeFlag,
+			utils.CheckpointFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
 			utils.LightKDFFlag,
+			utils.NoUSBFlag,
+			utils.ExternalSignerFlag,
 		},
 	},
 	{
@@ -104,7 +110,19 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.RPCJWTSecretFlag,
+			utils.RPCAllowMethodsFlag,
+			utils.RPCDenyMethodsFlag,
+			utils.RPCBatchLimitFlag,
+			utils.RPCRequestLimitFlag,
+			utils.RPCResponseLimitFlag,
+			utils.RPCTimeoutFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCRateBurstFlag,
+			utils.RPCMethodRateLimitFlag,
+			utils.GraphQLEnabledFlag,
 			utils.IPCDisabledFlag,
+			utils.StdIORPCFlag,
 			utils.IPCApiFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
@@ -145,10 +163,8 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.GpoMinGasPriceFlag,
 			utils.GpoMaxGasPriceFlag,
-			utils.GpoFullBlockRatioFlag,
-			utils.GpobaseStepDownFlag,
-			utils.GpobaseStepUpFlag,
-			utils.GpobaseCorrectionFactorFlag,
+			utils.GpoBlocksFlag,
+			utils.GpoPercentileFlag,
 		},
 	},
 	{
@@ -202,6 +218,9 @@ func init() {
 					categorized[flag.String()] = struct{}{}
 				}
 			}
+			for _, flag := range utils.DeprecatedFlags {
+				categorized[flag.String()] = struct{}{}
+			}
 			uncategorized := []cli.Flag{}
 			for _, flag := range data.(*cli.App).Flags {
 				if _, ok := categorized[flag.String()]; !ok {
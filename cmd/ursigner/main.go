@@ -0,0 +1,211 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of go-ur.
+//
+// go-ur is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ur is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ur. If not, see <http://www.gnu.org/licenses/>.
+
+// ursigner is an external signer owning a keystore, signing the requests of a
+// gur node (or any other client) that its rule set approves.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/ur-technology/go-ur/accounts"
+	"github.com/ur-technology/go-ur/cmd/utils"
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/console"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+	"github.com/ur-technology/go-ur/node"
+	"github.com/ur-technology/go-ur/params"
+	"github.com/ur-technology/go-ur/rpc"
+	"github.com/ur-technology/go-ur/signer"
+)
+
+func main() {
+	var (
+		keystore  = flag.String("keystore", filepath.Join(node.DefaultDataDir(), "keystore"), "directory of the keystore to sign with")
+		lightKDF  = flag.Bool("lightkdf", false, "reduce key-derivation RAM & CPU usage at some expense of KDF strength")
+		rulesFile = flag.String("rules", "", "JSON rule file deciding on signing requests (default: reject everything)")
+		network   = flag.String("network", params.MainnetNetwork.Name, "network whose privileged addresses signup rules match, by name or profile file")
+		auditFile = flag.String("audit", "audit.log", "file to append the audit log of all requests to")
+		ipcPath   = flag.String("ipcpath", filepath.Join(node.DefaultDataDir(), "ursigner.ipc"), "IPC endpoint to serve the signer API on (empty to disable)")
+		httpAddr  = flag.String("http", "", "HTTP listen address to serve the signer API on, localhost if no host is given (e.g. :8550)")
+		jwtSecret = flag.String("jwtsecret", "", "file of the hex encoded JWT secret authenticating HTTP requests, required for non-local addresses")
+		unlock    = flag.String("unlock", "", "comma separated list of accounts to unlock for signing")
+		password  = flag.String("password", "", "password file with one line per unlocked account")
+	)
+	flag.Var(glog.GetVerbosity(), "verbosity", "log verbosity (0-9)")
+	flag.Var(glog.GetVModule(), "vmodule", "log verbosity pattern")
+	glog.SetToStderr(true)
+	flag.Parse()
+
+	// Assemble the keystore and unlock the requested accounts
+	scryptN, scryptP := accounts.StandardScryptN, accounts.StandardScryptP
+	if *lightKDF {
+		scryptN, scryptP = accounts.LightScryptN, accounts.LightScryptP
+	}
+	ks := accounts.NewKeyStore(*keystore, scryptN, scryptP)
+	am := accounts.NewManager(ks)
+	defer am.Close()
+
+	passwords := readPasswords(*password)
+	if *unlock != "" {
+		for i, address := range strings.Split(*unlock, ",") {
+			unlockAccount(ks, strings.TrimSpace(address), i, passwords)
+		}
+	}
+	// Load the rules and open the audit log
+	rules, err := signer.ParseRuleset([]byte("{}"))
+	if *rulesFile != "" {
+		rules, err = signer.LoadRuleset(*rulesFile)
+	}
+	if err != nil {
+		utils.Fatalf("-rules: %v", err)
+	}
+	profile, err := params.LookupNetwork(*network)
+	if err != nil && common.FileExist(*network) {
+		profile, err = params.LoadNetwork(*network)
+	}
+	if err != nil {
+		utils.Fatalf("-network: %v", err)
+	}
+	rules.SetChainConfig(profile.Config)
+	audit, err := os.OpenFile(*auditFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		utils.Fatalf("-audit: %v", err)
+	}
+	defer audit.Close()
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName(signer.Namespace, signer.NewSignerAPI(am, rules, signer.NewAuditLog(audit))); err != nil {
+		utils.Fatalf("Failed to register signer API: %v", err)
+	}
+	if *ipcPath == "" && *httpAddr == "" {
+		utils.Fatalf("Use -ipcpath or -http to serve the signer API")
+	}
+	if *ipcPath != "" {
+		listener, err := rpc.CreateIPCListener(*ipcPath)
+		if err != nil {
+			utils.Fatalf("-ipcpath: %v", err)
+		}
+		defer listener.Close()
+		go server.ServeListener(listener)
+		glog.V(logger.Info).Infof("IPC endpoint opened: %s", *ipcPath)
+	}
+	if *httpAddr != "" {
+		endpoint, local, err := httpEndpoint(*httpAddr)
+		if err != nil {
+			utils.Fatalf("-http: %v", err)
+		}
+		var httpServer *http.Server
+		switch {
+		case *jwtSecret != "":
+			secret, err := rpc.LoadJWTSecret(*jwtSecret)
+			if err != nil {
+				utils.Fatalf("-jwtsecret: %v", err)
+			}
+			httpServer = rpc.NewAuthHTTPServer("", server, rpc.NewAuthenticator(secret, nil))
+		case local:
+			httpServer = rpc.NewHTTPServer("", server)
+		default:
+			utils.Fatalf("-http: serving the signer API on the non-local address %s requires -jwtsecret", endpoint)
+		}
+		listener, err := net.Listen("tcp", endpoint)
+		if err != nil {
+			utils.Fatalf("-http: %v", err)
+		}
+		defer listener.Close()
+		go httpServer.Serve(listener)
+		glog.V(logger.Info).Infof("HTTP endpoint opened: http://%s", endpoint)
+	}
+	// Serve until interrupted
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt)
+	<-sigc
+	glog.V(logger.Info).Infof("Got interrupt, shutting down...")
+}
+
+// httpEndpoint resolves the HTTP listen address, binding to localhost if no host
+// is given, and reports whether only local clients can connect to it.
+func httpEndpoint(addr string) (string, bool, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", false, err
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	local := host == "localhost"
+	if ip := net.ParseIP(host); ip != nil {
+		local = ip.IsLoopback()
+	}
+	return net.JoinHostPort(host, port), local, nil
+}
+
+// readPasswords reads the lines of a password file, if given.
+func readPasswords(path string) []string {
+	if path == "" {
+		return nil
+	}
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		utils.Fatalf("-password: %v", err)
+	}
+	lines := strings.Split(string(text), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+	return lines
+}
+
+// unlockAccount unlocks a keystore account indefinitely, taking its passphrase
+// from the password file or prompting for it.
+func unlockAccount(ks *accounts.KeyStore, address string, i int, passwords []string) {
+	if !common.IsHexAddress(address) {
+		utils.Fatalf("Invalid account to unlock: %s", address)
+	}
+	account := accounts.Account{Address: common.HexToAddress(address)}
+	var err error
+	if len(passwords) > 0 {
+		// Reuse the last password for accounts without a line of their own
+		if i >= len(passwords) {
+			i = len(passwords) - 1
+		}
+		if err = ks.Unlock(account, passwords[i]); err == nil {
+			return
+		}
+	} else {
+		for trials := 1; trials <= 3; trials++ {
+			fmt.Printf("Unlocking account %s [%d/3]\n", address, trials)
+			passphrase, perr := console.Stdin.PromptPassword("Passphrase: ")
+			if perr != nil {
+				utils.Fatalf("Failed to read passphrase: %v", perr)
+			}
+			if err = ks.Unlock(account, passphrase); err == nil {
+				return
+			}
+		}
+	}
+	utils.Fatalf("Failed to unlock account %s: %v", address, err)
+}
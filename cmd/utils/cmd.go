@@ -27,7 +27,9 @@ import (
 
 	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/archive"
 	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/ethdb"
 	"github.com/ur-technology/go-ur/internal/debug"
 	"github.com/ur-technology/go-ur/logger"
 	"github.com/ur-technology/go-ur/logger/glog"
@@ -216,3 +218,32 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 	glog.Infoln("Exported blockchain to ", fn)
 	return nil
 }
+
+// ImportArchive imports a chain archive, stopping after the current chunk on
+// Ctrl-C. The import can be resumed by running it again.
+func ImportArchive(chain *core.BlockChain, db ethdb.Database, dir string, execute bool) error {
+	interrupt := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			glog.Info("caught interrupt during import, will stop at next chunk")
+		}
+		close(stop)
+	}()
+	glog.Infoln("Importing blockchain archive ", dir)
+	return archive.Import(chain, db, dir, execute, stop)
+}
+
+// ExportArchive exports a range of the chain into a new archive directory,
+// optionally with a snapshot of the state at the last block.
+func ExportArchive(chain *core.BlockChain, db ethdb.Database, dir string, first, last uint64, state bool) error {
+	glog.Infoln("Exporting blockchain archive to ", dir)
+	if _, err := archive.Export(chain, db, dir, first, last, state); err != nil {
+		return err
+	}
+	glog.Infoln("Exported blockchain archive to ", dir)
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of go-ur.
+//
+// go-ur is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ur is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ur. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/eth"
+	"github.com/ur-technology/go-ur/node"
+)
+
+const testConfig = `
+[Eth]
+NetworkId = 77
+Etherbase = "0x1111111111111111111111111111111111111111"
+
+[Node]
+HTTPHost = "0.0.0.0"
+HTTPPort = 8000
+MaxPeers = 7
+`
+
+// testConfigFile is a configuration file layout with the services of gur.
+type testConfigFile struct {
+	Eth  eth.Config
+	Node node.Config
+}
+
+func writeConfig(t *testing.T, content string) (string, string) {
+	dir, err := ioutil.TempDir("", "gur-config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return dir, file
+}
+
+// Tests that the configuration file is loaded on top of the defaults.
+func TestLoadConfig(t *testing.T) {
+	dir, file := writeConfig(t, testConfig)
+	defer os.RemoveAll(dir)
+
+	cfg := testConfigFile{Eth: eth.DefaultConfig, Node: node.DefaultConfig}
+	if err := LoadConfig(file, &cfg); err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Eth.NetworkId != 77 {
+		t.Errorf("network id mismatch: have %d, want 77", cfg.Eth.NetworkId)
+	}
+	if want := common.HexToAddress("0x1111111111111111111111111111111111111111"); cfg.Eth.Etherbase != want {
+		t.Errorf("etherbase mismatch: have %x, want %x", cfg.Eth.Etherbase, want)
+	}
+	if cfg.Node.HTTPHost != "0.0.0.0" || cfg.Node.HTTPPort != 8000 || cfg.Node.MaxPeers != 7 {
+		t.Errorf("node settings mismatch: have %s:%d with %d peers, want 0.0.0.0:8000 with 7 peers", cfg.Node.HTTPHost, cfg.Node.HTTPPort, cfg.Node.MaxPeers)
+	}
+	// Settings missing from the file keep their defaults
+	if cfg.Eth.DatabaseCache != eth.DefaultConfig.DatabaseCache {
+		t.Errorf("database cache mismatch: have %d, want %d", cfg.Eth.DatabaseCache, eth.DefaultConfig.DatabaseCache)
+	}
+	if cfg.Node.WSPort != node.DefaultConfig.WSPort {
+		t.Errorf("websocket port mismatch: have %d, want %d", cfg.Node.WSPort, node.DefaultConfig.WSPort)
+	}
+}
+
+// Tests that unknown settings in the configuration file are rejected.
+func TestLoadConfigUnknownField(t *testing.T) {
+	dir, file := writeConfig(t, "[Node]\nMaxPeer = 7\n")
+	defer os.RemoveAll(dir)
+
+	cfg := testConfigFile{Eth: eth.DefaultConfig, Node: node.DefaultConfig}
+	err := LoadConfig(file, &cfg)
+	if err == nil {
+		t.Fatal("unknown field accepted")
+	}
+	if !strings.HasPrefix(err.Error(), file) || !strings.Contains(err.Error(), "field 'MaxPeer' is not defined in node.Config") {
+		t.Errorf("error mismatch: have %q", err)
+	}
+}
+
+// Tests that a dumped configuration loads back into the same settings.
+func TestConfigRoundTrip(t *testing.T) {
+	cfg := testConfigFile{Eth: eth.DefaultConfig, Node: node.DefaultConfig}
+	cfg.Eth.NetworkId = 77
+	cfg.Node.HTTPHost = "0.0.0.0"
+
+	out, err := TOMLSettings.Marshal(&cfg)
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+	dir, file := writeConfig(t, string(out))
+	defer os.RemoveAll(dir)
+
+	var loaded testConfigFile
+	if err := LoadConfig(file, &loaded); err != nil {
+		t.Fatalf("failed to load dumped config: %v", err)
+	}
+	again, err := TOMLSettings.Marshal(&loaded)
+	if err != nil {
+		t.Fatalf("failed to marshal loaded config: %v", err)
+	}
+	if !bytes.Equal(out, again) {
+		t.Errorf("config mismatch after round trip:\nhave:\n%s\nwant:\n%s", again, out)
+	}
+}
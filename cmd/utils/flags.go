@@ -18,14 +18,15 @@
 package utils
 
 import (
+	"bytes"
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"math/big"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strconv"
 	"strings"
 
@@ -35,9 +36,11 @@ import (
 	"github.com/ur-technology/go-ur/core/state"
 	"github.com/ur-technology/go-ur/crypto"
 	"github.com/ur-technology/go-ur/eth"
+	"github.com/ur-technology/go-ur/eth/downloader"
 	"github.com/ur-technology/go-ur/ethdb"
 	"github.com/ur-technology/go-ur/ethstats"
 	"github.com/ur-technology/go-ur/event"
+	"github.com/ur-technology/go-ur/graphql"
 	"github.com/ur-technology/go-ur/les"
 	"github.com/ur-technology/go-ur/logger"
 	"github.com/ur-technology/go-ur/logger/glog"
@@ -114,10 +117,15 @@ var (
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
 	}
+	NetworkFlag = cli.StringFlag{
+		Name:  "network",
+		Usage: `Network profile to join: "mainnet", "testnet" or the path to a JSON profile of a private network`,
+		Value: params.MainnetNetwork.Name,
+	}
 	NetworkIdFlag = cli.IntFlag{
 		Name:  "networkid",
-		Usage: "Network identifier (integer, 0=Olympic (disused), 1=Frontier, 2=Morden (disused), 3=Ropsten)",
-		Value: eth.NetworkId,
+		Usage: "Network identifier (integer, default = the one of the network profile)",
+		Value: eth.DefaultConfig.NetworkId,
 	}
 	OlympicFlag = cli.BoolFlag{
 		Name:  "olympic",
@@ -125,7 +133,7 @@ var (
 	}
 	TestNetFlag = cli.BoolFlag{
 		Name:  "testnet",
-		Usage: "Morden network: pre-configured test network with modified starting nonces (replay protection)",
+		Usage: "UR test network: pre-configured test network (same as --network=testnet)",
 	}
 	DevModeFlag = cli.BoolFlag{
 		Name:  "dev",
@@ -144,13 +152,22 @@ var (
 		Usage: "Document Root for HTTPClient file scheme",
 		Value: DirectoryString{homeDir()},
 	}
+	SyncModeFlag = cli.StringFlag{
+		Name:  "syncmode",
+		Usage: `Blockchain sync mode ("full", "fast", "snap" or "light")`,
+		Value: downloader.FullSync.String(),
+	}
 	FastSyncFlag = cli.BoolFlag{
 		Name:  "fast",
-		Usage: "Enable fast syncing through state downloads",
+		Usage: "Enable fast syncing through state downloads (same as --syncmode=fast)",
 	}
 	LightModeFlag = cli.BoolFlag{
 		Name:  "light",
-		Usage: "Enable light client mode",
+		Usage: "Enable light client mode (same as --syncmode=light)",
+	}
+	CheckpointFlag = cli.StringFlag{
+		Name:  "checkpoint",
+		Usage: `Trusted block to sync from instead of the genesis ("<number>:<hash>:<td>", see "gur checkpoint")`,
 	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
@@ -160,17 +177,25 @@ var (
 	LightPeersFlag = cli.IntFlag{
 		Name:  "lightpeers",
 		Usage: "Maximum number of LES client peers",
-		Value: 20,
+		Value: eth.DefaultConfig.LightPeers,
 	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
 	}
+	NoUSBFlag = cli.BoolFlag{
+		Name:  "nousb",
+		Usage: "Disables monitoring for and managing USB hardware wallets",
+	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer to delegate signing to (IPC path or HTTP URL)",
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
 		Usage: "Megabytes of memory allocated to internal caching (min 16MB / database forced)",
-		Value: 128,
+		Value: eth.DefaultConfig.DatabaseCache,
 	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
@@ -194,7 +219,7 @@ var (
 	MinerThreadsFlag = cli.IntFlag{
 		Name:  "minerthreads",
 		Usage: "Number of CPU threads to use for mining",
-		Value: runtime.NumCPU(),
+		Value: eth.DefaultConfig.MinerThreads,
 	}
 	TargetGasLimitFlag = cli.StringFlag{
 		Name:  "targetgaslimit",
@@ -218,7 +243,7 @@ var (
 	GasPriceFlag = cli.StringFlag{
 		Name:  "gasprice",
 		Usage: "Minimal gas price to accept for mining a transactions",
-		Value: new(big.Int).Mul(big.NewInt(20), common.Shannon).String(),
+		Value: eth.DefaultConfig.GasPrice.String(),
 	}
 	// ExtraDataFlag = cli.StringFlag{
 	// 	Name:  "extradata",
@@ -292,6 +317,10 @@ var (
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
 	}
+	StdIORPCFlag = cli.BoolFlag{
+		Name:  "stdiorpc",
+		Usage: "Serve JSON-RPC with all APIs on the standard input and output (for running as a subprocess)",
+	}
 	IPCApiFlag = cli.StringFlag{
 		Name:  "ipcapi",
 		Usage: "APIs offered over the IPC-RPC interface",
@@ -326,6 +355,60 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpcjwtsecret",
+		Usage: "File holding a hex encoded 32 byte secret authenticating HS256 bearer tokens on HTTP and WS-RPC",
+		Value: "",
+	}
+	RPCAllowMethodsFlag = cli.StringFlag{
+		Name:  "rpcallow",
+		Usage: "Comma separated list of methods (or namespace_* wildcards) allowed on HTTP and WS-RPC",
+		Value: "",
+	}
+	RPCDenyMethodsFlag = cli.StringFlag{
+		Name:  "rpcdeny",
+		Usage: "Comma separated list of methods (or namespace_* wildcards) denied on HTTP and WS-RPC",
+		Value: "",
+	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpcbatchlimit",
+		Usage: "Maximum number of requests in an HTTP or WS-RPC batch (0 = unlimited)",
+		Value: 0,
+	}
+	RPCRequestLimitFlag = cli.Int64Flag{
+		Name:  "rpcrequestlimit",
+		Usage: "Maximum size of an HTTP or WS-RPC request in bytes (0 = default)",
+		Value: 0,
+	}
+	RPCResponseLimitFlag = cli.IntFlag{
+		Name:  "rpcresponselimit",
+		Usage: "Maximum size of an HTTP or WS-RPC response in bytes (0 = unlimited)",
+		Value: 0,
+	}
+	RPCTimeoutFlag = cli.DurationFlag{
+		Name:  "rpctimeout",
+		Usage: "Maximum execution time of HTTP and WS-RPC method calls (0 = unlimited)",
+		Value: 0,
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpcratelimit",
+		Usage: "Maximum HTTP and WS-RPC requests per second of each client IP (0 = unlimited)",
+		Value: 0,
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpcrateburst",
+		Usage: "Maximum burst of HTTP and WS-RPC requests of each client IP (0 = one second worth of requests)",
+		Value: 0,
+	}
+	RPCMethodRateLimitFlag = cli.StringFlag{
+		Name:  "rpcmethodratelimit",
+		Usage: "Comma separated method=rate[:burst] limits shared by all HTTP and WS-RPC clients (e.g. eth_getLogs=10:20)",
+		Value: "",
+	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL query endpoint at /graphql on the HTTP-RPC server (requires the eth API)",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement (only in combination with console/attach)",
@@ -339,7 +422,7 @@ var (
 	MaxPeersFlag = cli.IntFlag{
 		Name:  "maxpeers",
 		Usage: "Maximum number of network peers (network disabled if set to 0)",
-		Value: 25,
+		Value: node.DefaultConfig.MaxPeers,
 	}
 	MaxPendingPeersFlag = cli.IntFlag{
 		Name:  "maxpendpeers",
@@ -396,50 +479,72 @@ var (
 	SolcPathFlag = cli.StringFlag{
 		Name:  "solc",
 		Usage: "Solidity compiler command to be used",
-		Value: "solc",
+		Value: eth.DefaultConfig.SolcPath,
 	}
 
 	// Gas price oracle settings
 	GpoMinGasPriceFlag = cli.StringFlag{
 		Name:  "gpomin",
 		Usage: "Minimum suggested gas price",
-		Value: new(big.Int).Mul(big.NewInt(20), common.Shannon).String(),
+		Value: eth.DefaultConfig.GpoMinGasPrice.String(),
 	}
 	GpoMaxGasPriceFlag = cli.StringFlag{
 		Name:  "gpomax",
 		Usage: "Maximum suggested gas price",
-		Value: new(big.Int).Mul(big.NewInt(500), common.Shannon).String(),
+		Value: eth.DefaultConfig.GpoMaxGasPrice.String(),
 	}
+	GpoBlocksFlag = cli.IntFlag{
+		Name:  "gpoblocks",
+		Usage: "Number of recent blocks to sample for gas price suggestions",
+		Value: eth.DefaultConfig.GpoBlocks,
+	}
+	GpoPercentileFlag = cli.IntFlag{
+		Name:  "gpopercentile",
+		Usage: "Suggested gas price is the given percentile of the sampled prices",
+		Value: eth.DefaultConfig.GpoPercentile,
+	}
+
+	// Deprecated settings of the former gas price oracle, accepted for the
+	// compatibility of existing scripts but ignored
 	GpoFullBlockRatioFlag = cli.IntFlag{
-		Name:  "gpofull",
-		Usage: "Full block threshold for gas price calculation (%)",
-		Value: 80,
+		Name:   "gpofull",
+		Usage:  "Deprecated, has no effect",
+		Hidden: true,
 	}
 	GpobaseStepDownFlag = cli.IntFlag{
-		Name:  "gpobasedown",
-		Usage: "Suggested gas price base step down ratio (1/1000)",
-		Value: 10,
+		Name:   "gpobasedown",
+		Usage:  "Deprecated, has no effect",
+		Hidden: true,
 	}
 	GpobaseStepUpFlag = cli.IntFlag{
-		Name:  "gpobaseup",
-		Usage: "Suggested gas price base step up ratio (1/1000)",
-		Value: 100,
+		Name:   "gpobaseup",
+		Usage:  "Deprecated, has no effect",
+		Hidden: true,
 	}
 	GpobaseCorrectionFactorFlag = cli.IntFlag{
-		Name:  "gpobasecf",
-		Usage: "Suggested gas price base correction factor (%)",
-		Value: 110,
+		Name:   "gpobasecf",
+		Usage:  "Deprecated, has no effect",
+		Hidden: true,
 	}
 )
 
+// DeprecatedFlags are the flags still accepted but no longer having any effect.
+var DeprecatedFlags = []cli.Flag{
+	GpoFullBlockRatioFlag,
+	GpobaseStepDownFlag,
+	GpobaseStepUpFlag,
+	GpobaseCorrectionFactorFlag,
+}
+
 // MakeDataDir retrieves the currently requested data directory, terminating
-// if none (or the empty string) is specified. If the node is starting a testnet,
-// the a subdirectory of the specified datadir will be used.
+// if none (or the empty string) is specified. If the node is joining a network
+// other than the main one, a subdirectory of the specified datadir named after
+// the network will be used.
 func MakeDataDir(ctx *cli.Context) string {
 	if path := ctx.GlobalString(DataDirFlag.Name); path != "" {
 		// TODO: choose a different location outside of the regular datadir.
-		if ctx.GlobalBool(TestNetFlag.Name) {
-			return filepath.Join(path, "testnet")
+		if network := MakeNetwork(ctx); network != nil && network != params.MainnetNetwork {
+			return filepath.Join(path, network.Name)
 		}
 		return path
 	}
@@ -501,10 +606,12 @@ func makeNodeUserIdent(ctx *cli.Context) string {
 func MakeBootstrapNodes(ctx *cli.Context) []*discover.Node {
 	// Return pre-configured nodes if none were manually requested
 	if !ctx.GlobalIsSet(BootnodesFlag.Name) {
-		if ctx.GlobalBool(TestNetFlag.Name) {
-			return params.TestnetBootnodes
+		network := MakeNetwork(ctx)
+		if network == nil {
+			network = params.MainnetNetwork
 		}
-		return params.MainnetBootnodes
+		nodes, _ := network.BootstrapNodes() // validated when loading the network
+		return nodes
 	}
 	// Otherwise parse and use the CLI bootstrap nodes
 	bootnodes := []*discover.Node{}
@@ -525,7 +632,12 @@ func MakeBootstrapNodes(ctx *cli.Context) []*discover.Node {
 func MakeBootstrapNodesV5(ctx *cli.Context) []*discv5.Node {
 	// Return pre-configured nodes if none were manually requested
 	if !ctx.GlobalIsSet(BootnodesFlag.Name) {
-		return params.DiscoveryV5Bootnodes
+		network := MakeNetwork(ctx)
+		if network == nil {
+			network = params.MainnetNetwork
+		}
+		nodes, _ := network.BootstrapNodesV5() // validated when loading the network
+		return nodes
 	}
 	// Otherwise parse and use the CLI bootstrap nodes
 	bootnodes := []*discv5.Node{}
@@ -580,22 +692,80 @@ func MakeRPCModules(input string) []string {
 	return result
 }
 
-// MakeHTTPRpcHost creates the HTTP RPC listener interface string from the set
-// command line flags, returning empty if the HTTP endpoint is disabled.
-func MakeHTTPRpcHost(ctx *cli.Context) string {
-	if !ctx.GlobalBool(RPCEnabledFlag.Name) {
-		return ""
+// MakeRPCMethods splits a comma separated list of RPC methods, dropping any
+// empty entries.
+func MakeRPCMethods(input string) []string {
+	var result []string
+	for _, method := range strings.Split(input, ",") {
+		if method = strings.TrimSpace(method); method != "" {
+			result = append(result, method)
+		}
 	}
-	return ctx.GlobalString(RPCListenAddrFlag.Name)
+	return result
 }
 
-// MakeWSRpcHost creates the WebSocket RPC listener interface string from the set
-// command line flags, returning empty if the HTTP endpoint is disabled.
-func MakeWSRpcHost(ctx *cli.Context) string {
-	if !ctx.GlobalBool(WSEnabledFlag.Name) {
-		return ""
+// setRPCLimits applies the resource limits of the HTTP and WS-RPC interfaces
+// set on the command line, keeping the configured ones otherwise.
+func setRPCLimits(ctx *cli.Context, limits *rpc.Limits) {
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		limits.MaxBatchItems = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRequestLimitFlag.Name) {
+		limits.MaxRequestSize = ctx.GlobalInt64(RPCRequestLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseLimitFlag.Name) {
+		limits.MaxResponseSize = ctx.GlobalInt(RPCResponseLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTimeoutFlag.Name) {
+		limits.Timeout = ctx.GlobalDuration(RPCTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) || ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+		rate, burst := limits.ClientRate.PerSecond, limits.ClientRate.Burst
+		if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+			rate = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+		}
+		if ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+			burst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+		}
+		limits.ClientRate = rpc.Rate{}
+		if rate > 0 {
+			limits.ClientRate = makeRate(rate, burst)
+		}
+	}
+	if !ctx.GlobalIsSet(RPCMethodRateLimitFlag.Name) {
+		return
 	}
-	return ctx.GlobalString(WSListenAddrFlag.Name)
+	limits.MethodRates = nil
+	for _, limit := range MakeRPCMethods(ctx.GlobalString(RPCMethodRateLimitFlag.Name)) {
+		parts := strings.SplitN(limit, "=", 2)
+		if len(parts) != 2 {
+			Fatalf("Invalid method rate limit %q, want method=rate[:burst]", limit)
+		}
+		spec := strings.SplitN(parts[1], ":", 2)
+		rate, err := strconv.ParseFloat(spec[0], 64)
+		if err != nil || rate <= 0 {
+			Fatalf("Invalid rate in method rate limit %q", limit)
+		}
+		burst := 0
+		if len(spec) == 2 {
+			if burst, err = strconv.Atoi(spec[1]); err != nil || burst <= 0 {
+				Fatalf("Invalid burst in method rate limit %q", limit)
+			}
+		}
+		if limits.MethodRates == nil {
+			limits.MethodRates = make(map[string]rpc.Rate)
+		}
+		limits.MethodRates[strings.TrimSpace(parts[0])] = makeRate(rate, burst)
+	}
+}
+
+// makeRate creates a rate limit, defaulting the burst to one second worth of
+// requests (but at least one).
+func makeRate(rate float64, burst int) rpc.Rate {
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return rpc.Rate{PerSecond: rate, Burst: burst}
 }
 
 // MakeDatabaseHandles raises out the number of allowed file handles per process
@@ -616,7 +786,7 @@ func MakeDatabaseHandles() int {
 
 // MakeAddress converts an account specified directly as a hex encoded string or
 // a key index in the key store to an internal account representation.
-func MakeAddress(accman *accounts.Manager, account string) (accounts.Account, error) {
+func MakeAddress(ks *accounts.KeyStore, account string) (accounts.Account, error) {
 	// If the specified account is a valid address, return it
 	if common.IsHexAddress(account) {
 		return accounts.Account{Address: common.HexToAddress(account)}, nil
@@ -626,13 +796,17 @@ func MakeAddress(accman *accounts.Manager, account string) (accounts.Account, er
 	if err != nil {
 		return accounts.Account{}, fmt.Errorf("invalid account address or index %q", account)
 	}
-	return accman.AccountByIndex(index)
+	return ks.AccountByIndex(index)
 }
 
 // MakeEtherbase retrieves the etherbase either from the directly specified
 // command line flags or from the keystore if CLI indexed.
-func MakeEtherbase(accman *accounts.Manager, ctx *cli.Context) common.Address {
-	accounts := accman.Accounts()
+func MakeEtherbase(am *accounts.Manager, ctx *cli.Context) common.Address {
+	ks, err := am.KeyStore()
+	if err != nil {
+		Fatalf("Failed to access the keystore: %v", err)
+	}
+	accounts := ks.Accounts()
 	urFlg := ctx.GlobalIsSet(UrbaseFlag.Name)
 	ethFlg := ctx.GlobalIsSet(EtherbaseFlag.Name)
 	if !urFlg && !ethFlg && len(accounts) == 0 {
@@ -651,7 +825,7 @@ func MakeEtherbase(accman *accounts.Manager, ctx *cli.Context) common.Address {
 	if urbase == "" {
 		return common.Address{}
 	}
-	account, err := MakeAddress(accman, urbase)
+	account, err := MakeAddress(ks, urbase)
 	if err != nil {
 		Fatalf("Option %q: %v", flg, err)
 	}
@@ -685,66 +859,271 @@ func MakePasswordList(ctx *cli.Context) []string {
 	return lines
 }
 
-// MakeNode configures a node with no services from command line flags.
-func MakeNode(ctx *cli.Context, name, gitCommit string) *node.Node {
+// MakeSyncMode retrieves the chain synchronisation mode from the command line,
+// falling back to the --fast and --light shorthands if no mode is given.
+func MakeSyncMode(ctx *cli.Context) downloader.SyncMode {
+	switch {
+	case ctx.GlobalIsSet(SyncModeFlag.Name):
+		var mode downloader.SyncMode
+		if err := mode.UnmarshalText([]byte(ctx.GlobalString(SyncModeFlag.Name))); err != nil {
+			Fatalf("Option %q: %v", SyncModeFlag.Name, err)
+		}
+		return mode
+	case ctx.GlobalBool(LightModeFlag.Name):
+		return downloader.LightSync
+	case ctx.GlobalBool(FastSyncFlag.Name):
+		return downloader.FastSync
+	default:
+		return downloader.FullSync
+	}
+}
+
+// MakeCheckpoint retrieves the trusted sync checkpoint from the command line,
+// falling back to the one of the network profile, or nil if neither is given.
+func MakeCheckpoint(ctx *cli.Context) *downloader.Checkpoint {
+	if !ctx.GlobalIsSet(CheckpointFlag.Name) {
+		if network := MakeNetwork(ctx); network != nil && network.Checkpoint != nil {
+			return &downloader.Checkpoint{
+				Number: network.Checkpoint.Number,
+				Hash:   network.Checkpoint.Hash,
+				Td:     new(big.Int).Set(network.Checkpoint.Td),
+			}
+		}
+		return nil
+	}
+	checkpoint, err := downloader.ParseCheckpoint(ctx.GlobalString(CheckpointFlag.Name))
+	if err != nil {
+		Fatalf("Option %q: %v", CheckpointFlag.Name, err)
+	}
+	return checkpoint
+}
+
+// MakeNetwork retrieves the network profile selected on the command line, either
+// by name or as a path to a JSON profile, defaulting to the main network. It's
+// nil for the ad-hoc development and Olympic networks.
+func MakeNetwork(ctx *cli.Context) *params.Network {
+	testnet, dev, olympic := ctx.GlobalBool(TestNetFlag.Name), ctx.GlobalBool(DevModeFlag.Name), ctx.GlobalBool(OlympicFlag.Name)
+	if ctx.GlobalIsSet(NetworkFlag.Name) && (testnet || dev || olympic) {
+		Fatalf("Option %q conflicts with --%s, --%s and --%s", NetworkFlag.Name, TestNetFlag.Name, DevModeFlag.Name, OlympicFlag.Name)
+	}
+	if dev || olympic {
+		return nil
+	}
+	name := ctx.GlobalString(NetworkFlag.Name)
+	if testnet {
+		name = params.TestnetNetwork.Name
+	}
+	network, err := params.LookupNetwork(name)
+	if err != nil {
+		if !common.FileExist(name) {
+			Fatalf("Option %q: %v", NetworkFlag.Name, err)
+		}
+		if network, err = params.LoadNetwork(name); err != nil {
+			Fatalf("Option %q: %v", NetworkFlag.Name, err)
+		}
+	}
+	if _, err := core.NetworkGenesisBlock(network); err != nil {
+		Fatalf("Option %q: %v", NetworkFlag.Name, err)
+	}
+	return network
+}
+
+// networkSelected reports whether a network profile was explicitly requested on
+// the command line, as opposed to the main network by default.
+func networkSelected(ctx *cli.Context) bool {
+	return ctx.GlobalIsSet(NetworkFlag.Name) || ctx.GlobalBool(TestNetFlag.Name)
+}
+
+// MakeVersion creates the version string the node advertises, suffixed with the
+// abbreviated git commit hash if one is known.
+func MakeVersion(gitCommit string) string {
 	vsn := params.Version
 	if gitCommit != "" {
 		vsn += "-" + gitCommit[:8]
 	}
+	return vsn
+}
+
+// MakeNode configures a node with no services from command line flags.
+func MakeNode(ctx *cli.Context, name, gitCommit string) *node.Node {
+	config := node.DefaultConfig
+	config.Name = name
+	config.Version = MakeVersion(gitCommit)
+	SetNodeConfig(ctx, &config)
 
-	config := &node.Config{
-		DataDir:           MakeDataDir(ctx),
-		KeyStoreDir:       ctx.GlobalString(KeyStoreDirFlag.Name),
-		UseLightweightKDF: ctx.GlobalBool(LightKDFFlag.Name),
-		PrivateKey:        MakeNodeKey(ctx),
-		Name:              name,
-		Version:           vsn,
-		UserIdent:         makeNodeUserIdent(ctx),
-		NoDiscovery:       ctx.GlobalBool(NoDiscoverFlag.Name) || ctx.GlobalBool(LightModeFlag.Name),
-		DiscoveryV5:       ctx.GlobalBool(DiscoveryV5Flag.Name) || ctx.GlobalBool(LightModeFlag.Name) || ctx.GlobalInt(LightServFlag.Name) > 0,
-		DiscoveryV5Addr:   MakeDiscoveryV5Address(ctx),
-		BootstrapNodes:    MakeBootstrapNodes(ctx),
-		BootstrapNodesV5:  MakeBootstrapNodesV5(ctx),
-		ListenAddr:        MakeListenAddress(ctx),
-		NAT:               MakeNAT(ctx),
-		MaxPeers:          ctx.GlobalInt(MaxPeersFlag.Name),
-		MaxPendingPeers:   ctx.GlobalInt(MaxPendingPeersFlag.Name),
-		IPCPath:           MakeIPCPath(ctx),
-		HTTPHost:          MakeHTTPRpcHost(ctx),
-		HTTPPort:          ctx.GlobalInt(RPCPortFlag.Name),
-		HTTPCors:          ctx.GlobalString(RPCCORSDomainFlag.Name),
-		HTTPModules:       MakeRPCModules(ctx.GlobalString(RPCApiFlag.Name)),
-		WSHost:            MakeWSRpcHost(ctx),
-		WSPort:            ctx.GlobalInt(WSPortFlag.Name),
-		WSOrigins:         ctx.GlobalString(WSAllowedOriginsFlag.Name),
-		WSModules:         MakeRPCModules(ctx.GlobalString(WSApiFlag.Name)),
+	stack, err := node.New(&config)
+	if err != nil {
+		Fatalf("Failed to create the protocol stack: %v", err)
 	}
-	if ctx.GlobalBool(DevModeFlag.Name) {
-		if !ctx.GlobalIsSet(DataDirFlag.Name) {
-			config.DataDir = filepath.Join(os.TempDir(), "/ethereum_dev_mode")
-		}
-		// --dev mode does not need p2p networking.
-		config.MaxPeers = 0
-		config.ListenAddr = ":0"
+	return stack
+}
+
+// SetNodeConfig applies the node related command line flags to a configuration,
+// overriding any preset values. Settings whose flags were not given are left as
+// configured, so flags take precedence over a loaded configuration file.
+func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
+	setDataDir(ctx, cfg)
+
+	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
+		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
+	}
+	if ctx.GlobalIsSet(LightKDFFlag.Name) {
+		cfg.UseLightweightKDF = ctx.GlobalBool(LightKDFFlag.Name)
+	}
+	if ctx.GlobalIsSet(NoUSBFlag.Name) {
+		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
+	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
+	if ctx.GlobalIsSet(IdentityFlag.Name) || ctx.GlobalBool(VMEnableJitFlag.Name) {
+		cfg.UserIdent = makeNodeUserIdent(ctx)
+	}
+	setP2P(ctx, cfg)
+
+	if ctx.GlobalBool(IPCDisabledFlag.Name) || ctx.GlobalIsSet(IPCPathFlag.Name) {
+		cfg.IPCPath = MakeIPCPath(ctx)
+	}
+	if ctx.GlobalIsSet(StdIORPCFlag.Name) {
+		cfg.StdIORPC = ctx.GlobalBool(StdIORPCFlag.Name)
+	}
+	setHTTP(ctx, cfg)
+	setWS(ctx, cfg)
+
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAllowMethodsFlag.Name) {
+		cfg.RPCAllowMethods = MakeRPCMethods(ctx.GlobalString(RPCAllowMethodsFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCDenyMethodsFlag.Name) {
+		cfg.RPCDenyMethods = MakeRPCMethods(ctx.GlobalString(RPCDenyMethodsFlag.Name))
+	}
+	setRPCLimits(ctx, &cfg.RPCLimits)
+}
+
+// setDataDir resolves the data directory of the node. An explicit --datadir or
+// network selection overrides the configured one, whereas --dev falls back to a
+// temporary directory.
+func setDataDir(ctx *cli.Context, cfg *node.Config) {
+	switch {
+	case ctx.GlobalIsSet(DataDirFlag.Name) || networkSelected(ctx):
+		cfg.DataDir = MakeDataDir(ctx)
+	case ctx.GlobalBool(DevModeFlag.Name):
+		cfg.DataDir = filepath.Join(os.TempDir(), "/ethereum_dev_mode")
+	}
+}
+
+// setP2P applies the peer-to-peer networking flags to the node configuration.
+func setP2P(ctx *cli.Context, cfg *node.Config) {
+	if key := MakeNodeKey(ctx); key != nil {
+		cfg.PrivateKey = key
+	}
+	if ctx.GlobalIsSet(ListenPortFlag.Name) {
+		cfg.ListenAddr = MakeListenAddress(ctx)
+		cfg.DiscoveryV5Addr = MakeDiscoveryV5Address(ctx)
+	}
+	// Bootstrap from the selected network unless the nodes are preconfigured
+	if ctx.GlobalIsSet(BootnodesFlag.Name) || networkSelected(ctx) || cfg.BootstrapNodes == nil {
+		cfg.BootstrapNodes = MakeBootstrapNodes(ctx)
+	}
+	if ctx.GlobalIsSet(BootnodesFlag.Name) || networkSelected(ctx) || cfg.BootstrapNodesV5 == nil {
+		cfg.BootstrapNodesV5 = MakeBootstrapNodesV5(ctx)
+	}
+	if ctx.GlobalIsSet(NATFlag.Name) || cfg.NAT == nil {
+		cfg.NAT = MakeNAT(ctx)
+	}
+	if ctx.GlobalIsSet(MaxPeersFlag.Name) {
+		cfg.MaxPeers = ctx.GlobalInt(MaxPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaxPendingPeersFlag.Name) {
+		cfg.MaxPendingPeers = ctx.GlobalInt(MaxPendingPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(NoDiscoverFlag.Name) {
+		cfg.NoDiscovery = ctx.GlobalBool(NoDiscoverFlag.Name)
+	}
+	if ctx.GlobalIsSet(DiscoveryV5Flag.Name) {
+		cfg.DiscoveryV5 = ctx.GlobalBool(DiscoveryV5Flag.Name)
+	}
+	// Light clients and servers find each other through the topic discovery
+	if MakeSyncMode(ctx) == downloader.LightSync {
+		cfg.NoDiscovery = true
+		cfg.DiscoveryV5 = true
+	}
+	if ctx.GlobalInt(LightServFlag.Name) > 0 {
+		cfg.DiscoveryV5 = true
 	}
 	if netrestrict := ctx.GlobalString(NetrestrictFlag.Name); netrestrict != "" {
 		list, err := netutil.ParseNetlist(netrestrict)
 		if err != nil {
 			Fatalf("Option %q: %v", NetrestrictFlag.Name, err)
 		}
-		config.NetRestrict = list
+		cfg.NetRestrict = list
+	}
+	if ctx.GlobalBool(DevModeFlag.Name) {
+		// --dev mode does not need p2p networking.
+		cfg.MaxPeers = 0
+		cfg.ListenAddr = ":0"
 	}
+}
 
-	stack, err := node.New(config)
-	if err != nil {
-		Fatalf("Failed to create the protocol stack: %v", err)
+// setHTTP applies the HTTP-RPC flags to the node configuration. The endpoint is
+// enabled either by --rpc or by a configured listener interface.
+func setHTTP(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(RPCEnabledFlag.Name) && cfg.HTTPHost == "" {
+		cfg.HTTPHost = node.DefaultHTTPHost
 	}
-	return stack
+	if ctx.GlobalIsSet(RPCListenAddrFlag.Name) && cfg.HTTPHost != "" {
+		cfg.HTTPHost = ctx.GlobalString(RPCListenAddrFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCPortFlag.Name) {
+		cfg.HTTPPort = ctx.GlobalInt(RPCPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCCORSDomainFlag.Name) {
+		cfg.HTTPCors = ctx.GlobalString(RPCCORSDomainFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCApiFlag.Name) {
+		cfg.HTTPModules = MakeRPCModules(ctx.GlobalString(RPCApiFlag.Name))
+	}
+}
+
+// setWS applies the WS-RPC flags to the node configuration. The endpoint is
+// enabled either by --ws or by a configured listener interface.
+func setWS(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(WSEnabledFlag.Name) && cfg.WSHost == "" {
+		cfg.WSHost = node.DefaultWSHost
+	}
+	if ctx.GlobalIsSet(WSListenAddrFlag.Name) && cfg.WSHost != "" {
+		cfg.WSHost = ctx.GlobalString(WSListenAddrFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSPortFlag.Name) {
+		cfg.WSPort = ctx.GlobalInt(WSPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSAllowedOriginsFlag.Name) {
+		cfg.WSOrigins = ctx.GlobalString(WSAllowedOriginsFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSApiFlag.Name) {
+		cfg.WSModules = MakeRPCModules(ctx.GlobalString(WSApiFlag.Name))
+	}
+}
+
+// SetEthConfig applies the eth related command line flags to a configuration,
+// overriding any preset values, resolves the etherbase from the keystore and the
+// chain rules from the database of the given node. The peer limit is shared with
+// the node and left to the caller.
+func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
+	ApplyEthFlags(ctx, cfg)
+
+	if ctx.GlobalIsSet(UrbaseFlag.Name) || ctx.GlobalIsSet(EtherbaseFlag.Name) || cfg.Etherbase == (common.Address{}) {
+		cfg.Etherbase = MakeEtherbase(stack.AccountManager(), ctx)
+	}
+	cfg.ChainConfig = MakeChainConfig(ctx, stack)
 }
 
-// RegisterEthService configures eth.Ethereum from command line flags and adds it to the
-// given node.
-func RegisterEthService(ctx *cli.Context, stack *node.Node, extra []byte) {
+// ApplyEthFlags applies the eth related command line flags to a configuration,
+// overriding any preset values, without accessing the keystore or the database.
+// The etherbase is only set if given as an address.
+func ApplyEthFlags(ctx *cli.Context, cfg *eth.Config) {
 	// Avoid conflicting network flags
 	networks, netFlags := 0, []cli.BoolFlag{DevModeFlag, TestNetFlag, OlympicFlag}
 	for _, flag := range netFlags {
@@ -755,70 +1134,113 @@ func RegisterEthService(ctx *cli.Context, stack *node.Node, extra []byte) {
 	if networks > 1 {
 		Fatalf("The %v flags are mutually exclusive", netFlags)
 	}
+	for _, flag := range []cli.StringFlag{EtherbaseFlag, UrbaseFlag} {
+		if base := ctx.GlobalString(flag.Name); ctx.GlobalIsSet(flag.Name) && common.IsHexAddress(base) {
+			cfg.Etherbase = common.HexToAddress(base)
+		}
+	}
+	if ctx.GlobalIsSet(SyncModeFlag.Name) || ctx.GlobalBool(FastSyncFlag.Name) || ctx.GlobalBool(LightModeFlag.Name) {
+		syncmode := MakeSyncMode(ctx)
+		cfg.FastSync = syncmode == downloader.FastSync || syncmode == downloader.SnapSync
+		cfg.SnapSync = syncmode == downloader.SnapSync
+		cfg.LightMode = syncmode == downloader.LightSync
+	}
+	if ctx.GlobalIsSet(LightServFlag.Name) {
+		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
+	}
+	if ctx.GlobalIsSet(LightPeersFlag.Name) {
+		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(CheckpointFlag.Name) || cfg.Checkpoint == nil {
+		cfg.Checkpoint = MakeCheckpoint(ctx)
+	}
+	if ctx.GlobalIsSet(CacheFlag.Name) {
+		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name)
+	}
+	cfg.DatabaseHandles = MakeDatabaseHandles()
 
-	ethConf := &eth.Config{
-		Etherbase:               MakeEtherbase(stack.AccountManager(), ctx),
-		ChainConfig:             MakeChainConfig(ctx, stack),
-		FastSync:                ctx.GlobalBool(FastSyncFlag.Name),
-		LightMode:               ctx.GlobalBool(LightModeFlag.Name),
-		LightServ:               ctx.GlobalInt(LightServFlag.Name),
-		LightPeers:              ctx.GlobalInt(LightPeersFlag.Name),
-		MaxPeers:                ctx.GlobalInt(MaxPeersFlag.Name),
-		DatabaseCache:           ctx.GlobalInt(CacheFlag.Name),
-		DatabaseHandles:         MakeDatabaseHandles(),
-		NetworkId:               ctx.GlobalInt(NetworkIdFlag.Name),
-		MinerThreads:            ctx.GlobalInt(MinerThreadsFlag.Name),
-		ExtraData:               MakeMinerExtra(extra, ctx),
-		NatSpec:                 ctx.GlobalBool(NatspecEnabledFlag.Name),
-		DocRoot:                 ctx.GlobalString(DocRootFlag.Name),
-		GasPrice:                common.String2Big(ctx.GlobalString(GasPriceFlag.Name)),
-		GpoMinGasPrice:          common.String2Big(ctx.GlobalString(GpoMinGasPriceFlag.Name)),
-		GpoMaxGasPrice:          common.String2Big(ctx.GlobalString(GpoMaxGasPriceFlag.Name)),
-		GpoFullBlockRatio:       ctx.GlobalInt(GpoFullBlockRatioFlag.Name),
-		GpobaseStepDown:         ctx.GlobalInt(GpobaseStepDownFlag.Name),
-		GpobaseStepUp:           ctx.GlobalInt(GpobaseStepUpFlag.Name),
-		GpobaseCorrectionFactor: ctx.GlobalInt(GpobaseCorrectionFactorFlag.Name),
-		SolcPath:                ctx.GlobalString(SolcPathFlag.Name),
-		AutoDAG:                 ctx.GlobalBool(AutoDAGFlag.Name) || ctx.GlobalBool(MiningEnabledFlag.Name),
-	}
-
-	// Override any default configs in dev mode or the test net
-	switch {
-	case ctx.GlobalBool(OlympicFlag.Name):
+	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
+		cfg.NetworkId = ctx.GlobalInt(NetworkIdFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerThreadsFlag.Name) {
+		cfg.MinerThreads = ctx.GlobalInt(MinerThreadsFlag.Name)
+	}
+	cfg.ExtraData = MakeMinerExtra(cfg.ExtraData, ctx)
+
+	if ctx.GlobalIsSet(NatspecEnabledFlag.Name) {
+		cfg.NatSpec = ctx.GlobalBool(NatspecEnabledFlag.Name)
+	}
+	if ctx.GlobalIsSet(DocRootFlag.Name) || cfg.DocRoot == "" {
+		cfg.DocRoot = ctx.GlobalString(DocRootFlag.Name)
+	}
+	if ctx.GlobalIsSet(GasPriceFlag.Name) {
+		cfg.GasPrice = common.String2Big(ctx.GlobalString(GasPriceFlag.Name))
+	}
+	if ctx.GlobalIsSet(GpoMinGasPriceFlag.Name) {
+		cfg.GpoMinGasPrice = common.String2Big(ctx.GlobalString(GpoMinGasPriceFlag.Name))
+	}
+	if ctx.GlobalIsSet(GpoMaxGasPriceFlag.Name) {
+		cfg.GpoMaxGasPrice = common.String2Big(ctx.GlobalString(GpoMaxGasPriceFlag.Name))
+	}
+	if ctx.GlobalIsSet(GpoBlocksFlag.Name) {
+		cfg.GpoBlocks = ctx.GlobalInt(GpoBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(GpoPercentileFlag.Name) {
+		cfg.GpoPercentile = ctx.GlobalInt(GpoPercentileFlag.Name)
+	}
+	for _, flag := range []cli.IntFlag{GpoFullBlockRatioFlag, GpobaseStepDownFlag, GpobaseStepUpFlag, GpobaseCorrectionFactorFlag} {
+		if ctx.GlobalIsSet(flag.Name) {
+			glog.V(logger.Error).Infof("WARNING: the --%s flag is deprecated and has no effect, see --%s and --%s", flag.Name, GpoBlocksFlag.Name, GpoPercentileFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(SolcPathFlag.Name) {
+		cfg.SolcPath = ctx.GlobalString(SolcPathFlag.Name)
+	}
+	if ctx.GlobalBool(AutoDAGFlag.Name) || ctx.GlobalBool(MiningEnabledFlag.Name) {
+		cfg.AutoDAG = true
+	}
+	// Override any default configs with the network profile, or in dev mode
+	if network := MakeNetwork(ctx); network != nil && networkSelected(ctx) {
 		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
-			ethConf.NetworkId = 1
+			cfg.NetworkId = network.NetworkId
 		}
-		ethConf.Genesis = core.OlympicGenesisBlock()
-
-	case ctx.GlobalBool(TestNetFlag.Name):
+		// The database was checked to hold the network's genesis, if any
+		cfg.Genesis = string(network.Genesis)
+	}
+	switch {
+	case ctx.GlobalBool(OlympicFlag.Name):
 		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
-			ethConf.NetworkId = 3
+			cfg.NetworkId = 1
 		}
-		ethConf.Genesis = core.DefaultTestnetGenesisBlock()
+		cfg.Genesis = core.OlympicGenesisBlock()
 
 	case ctx.GlobalBool(DevModeFlag.Name):
-		ethConf.Genesis = core.OlympicGenesisBlock()
+		cfg.Genesis = core.OlympicGenesisBlock()
 		if !ctx.GlobalIsSet(GasPriceFlag.Name) {
-			ethConf.GasPrice = new(big.Int)
+			cfg.GasPrice = new(big.Int)
 		}
-		ethConf.PowTest = true
+		cfg.PowTest = true
 	}
 	// Override any global options pertaining to the Ethereum protocol
 	if gen := ctx.GlobalInt(TrieCacheGenFlag.Name); gen > 0 {
 		state.MaxTrieCacheGen = uint16(gen)
 	}
+}
 
-	if ethConf.LightMode {
+// RegisterEthService adds an Ethereum client to the stack, either a full node
+// or a light client depending on the configured sync mode.
+func RegisterEthService(stack *node.Node, cfg *eth.Config) {
+	if cfg.LightMode {
 		if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-			return les.New(ctx, ethConf)
+			return les.New(ctx, cfg)
 		}); err != nil {
 			Fatalf("Failed to register the Ethereum light node service: %v", err)
 		}
 	} else {
 		if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-			fullNode, err := eth.New(ctx, ethConf)
-			if fullNode != nil && ethConf.LightServ > 0 {
-				ls, _ := les.NewLesServer(fullNode, ethConf)
+			fullNode, err := eth.New(ctx, cfg)
+			if fullNode != nil && cfg.LightServ > 0 {
+				ls, _ := les.NewLesServer(fullNode, cfg)
 				fullNode.AddLesServer(ls)
 			}
 			return fullNode, err
@@ -852,6 +1274,24 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 	}
 }
 
+// RegisterGraphQLService adds the GraphQL query endpoint to the HTTP-RPC server
+// of the node, resolving queries through the full or light client backend.
+func RegisterGraphQLService(stack *node.Node) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err == nil {
+			return graphql.New(ethServ.ApiBackend), nil
+		}
+		var lesServ *les.LightEthereum
+		if err := ctx.Service(&lesServ); err == nil {
+			return graphql.New(lesServ.ApiBackend), nil
+		}
+		return nil, errors.New("no UR service to query")
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	switch {
@@ -880,9 +1320,12 @@ func MakeChainConfigFromDb(ctx *cli.Context, db ethdb.Database) *params.ChainCon
 	// If the chain is already initialized, use any existing chain configs
 	config := new(params.ChainConfig)
 
+	var storedConfig *params.ChainConfig
+
 	genesis := core.GetBlock(db, core.GetCanonicalHash(db, 0), 0)
 	if genesis != nil {
-		storedConfig, err := core.GetChainConfig(db, genesis.Hash())
+		var err error
+		storedConfig, err = core.GetChainConfig(db, genesis.Hash())
 		switch err {
 		case nil:
 			config = storedConfig
@@ -896,31 +1339,21 @@ func MakeChainConfigFromDb(ctx *cli.Context, db ethdb.Database) *params.ChainCon
 	if config.ChainId == nil {
 		config.ChainId = new(big.Int)
 	}
-	// Check whether we are allowed to set default config params or not:
-	//  - If no genesis is set, we're running either mainnet or testnet (private nets use `gur init`)
-	//  - If a genesis is already set, ensure we have a configuration for it (mainnet or testnet)
-	defaults := genesis == nil ||
-		(genesis.Hash() == params.MainNetGenesisHash && !ctx.GlobalBool(TestNetFlag.Name)) ||
-		(genesis.Hash() == params.TestNetGenesisHash && ctx.GlobalBool(TestNetFlag.Name))
-
-	if defaults {
-		if ctx.GlobalBool(TestNetFlag.Name) {
-			config = params.TestnetChainConfig
-		} else {
-			// Homestead fork
-			config.HomesteadBlock = params.MainNetHomesteadBlock
-			// DAO fork
-			config.DAOForkBlock = params.MainNetDAOForkBlock
-			config.DAOForkSupport = true
-
-			// DoS reprice fork
-			config.EIP150Block = params.MainNetHomesteadGasRepriceBlock
-			config.EIP150Hash = params.MainNetHomesteadGasRepriceHash
-
-			// DoS state cleanup fork
-			config.EIP155Block = params.MainNetSpuriousDragon
-			config.EIP158Block = params.MainNetSpuriousDragon
-			config.ChainId = params.MainNetChainID
+	// Use the rules of the network profile, unless the database holds a private
+	// network set up with `gur init` and no profile was explicitly requested
+	if network := MakeNetwork(ctx); network != nil {
+		if genesis == nil || genesis.Hash() == network.GenesisHash || networkSelected(ctx) {
+			if genesis != nil {
+				head := core.GetBlockNumber(db, core.GetHeadHeaderHash(db))
+				if head == ^uint64(0) {
+					head = 0
+				}
+				if err := network.CheckCompatible(genesis.Hash(), storedConfig, head); err != nil {
+					Fatalf("Database incompatible with network %q: %v", network.Name, err)
+				}
+			}
+			rules := *network.Config
+			config = &rules
 		}
 	}
 	// Force override any existing configs if explicitly requested
@@ -934,7 +1367,7 @@ func MakeChainConfigFromDb(ctx *cli.Context, db ethdb.Database) *params.ChainCon
 }
 
 func ChainDbName(ctx *cli.Context) string {
-	if ctx.GlobalBool(LightModeFlag.Name) {
+	if MakeSyncMode(ctx) == downloader.LightSync {
 		return "lightchaindata"
 	} else {
 		return "chaindata"
@@ -961,6 +1394,12 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	var err error
 	chainDb = MakeChainDatabase(ctx, stack)
 
+	// Seed an empty database with the genesis of an explicitly requested network
+	if network := MakeNetwork(ctx); network != nil && networkSelected(ctx) && core.GetCanonicalHash(chainDb, 0) == (common.Hash{}) {
+		if _, err := core.WriteGenesisBlock(chainDb, bytes.NewReader(network.Genesis)); err != nil {
+			Fatalf("Could not write genesis of network %q: %v", network.Name, err)
+		}
+	}
 	if ctx.GlobalBool(OlympicFlag.Name) {
 		_, err := core.WriteTestNetGenesisBlock(chainDb)
 		if err != nil {
@@ -65,8 +65,11 @@ type Solidity struct {
 
 // --combined-output format
 type solcOutput struct {
-	Contracts map[string]struct{ Bin, Abi, Devdoc, Userdoc string }
-	Version   string
+	Contracts map[string]struct {
+		Bin                  string
+		Abi, Devdoc, Userdoc json.RawMessage
+	}
+	Version string
 }
 
 // SolidityVersion runs solc and parses its version output.
@@ -134,8 +137,16 @@ func CompileSolidity(solc string, sourcefiles ...string) (map[string]*Contract,
 	if err := cmd.Run(); err != nil {
 		return nil, fmt.Errorf("solc: %v\n%s", err, stderr.Bytes())
 	}
+	return ParseCombinedJSON(stdout.Bytes(), source, strings.Join(solcParams, " "))
+}
+
+// ParseCombinedJSON takes the output of `solc --combined-json` and assembles the
+// contracts it contains. Both the legacy format (ABI and docs encoded as JSON
+// strings) and the newer one (embedded JSON objects) are accepted, and any of
+// the documentation fields may be missing.
+func ParseCombinedJSON(combinedJSON []byte, source string, compilerOptions string) (map[string]*Contract, error) {
 	var output solcOutput
-	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+	if err := json.Unmarshal(combinedJSON, &output); err != nil {
 		return nil, err
 	}
 	shortVersion := versionRegexp.FindString(output.Version)
@@ -144,16 +155,19 @@ func CompileSolidity(solc string, sourcefiles ...string) (map[string]*Contract,
 	contracts := make(map[string]*Contract)
 	for name, info := range output.Contracts {
 		// Parse the individual compilation results.
-		var abi interface{}
-		if err := json.Unmarshal([]byte(info.Abi), &abi); err != nil {
+		abi, err := parseCombinedField(info.Abi)
+		if err != nil {
 			return nil, fmt.Errorf("solc: error reading abi definition (%v)", err)
 		}
-		var userdoc interface{}
-		if err := json.Unmarshal([]byte(info.Userdoc), &userdoc); err != nil {
+		if abi == nil {
+			return nil, fmt.Errorf("solc: missing abi definition for %s", name)
+		}
+		userdoc, err := parseCombinedField(info.Userdoc)
+		if err != nil {
 			return nil, fmt.Errorf("solc: error reading user doc: %v", err)
 		}
-		var devdoc interface{}
-		if err := json.Unmarshal([]byte(info.Devdoc), &devdoc); err != nil {
+		devdoc, err := parseCombinedField(info.Devdoc)
+		if err != nil {
 			return nil, fmt.Errorf("solc: error reading dev doc: %v", err)
 		}
 		contracts[name] = &Contract{
@@ -163,7 +177,7 @@ func CompileSolidity(solc string, sourcefiles ...string) (map[string]*Contract,
 				Language:        "Solidity",
 				LanguageVersion: shortVersion,
 				CompilerVersion: shortVersion,
-				CompilerOptions: strings.Join(solcParams, " "),
+				CompilerOptions: compilerOptions,
 				AbiDefinition:   abi,
 				UserDoc:         userdoc,
 				DeveloperDoc:    devdoc,
@@ -173,6 +187,29 @@ func CompileSolidity(solc string, sourcefiles ...string) (map[string]*Contract,
 	return contracts, nil
 }
 
+// parseCombinedField decodes a single field of the combined-json output, which
+// is either a JSON object or a string containing the encoded object.
+func parseCombinedField(field json.RawMessage) (interface{}, error) {
+	if len(field) == 0 || string(field) == "null" {
+		return nil, nil
+	}
+	if field[0] == '"' {
+		var blob string
+		if err := json.Unmarshal(field, &blob); err != nil {
+			return nil, err
+		}
+		if blob == "" {
+			return nil, nil
+		}
+		field = json.RawMessage(blob)
+	}
+	var res interface{}
+	if err := json.Unmarshal(field, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func slurpFiles(files []string) (string, error) {
 	var concat bytes.Buffer
 	for _, file := range files {
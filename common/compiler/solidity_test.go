@@ -108,3 +108,37 @@ func TestSaveInfo(t *testing.T) {
 		t.Errorf("content hash for info is incorrect. expected %v, got %v", wantHash.Hex(), cinfohash.Hex())
 	}
 }
+
+func TestParseCombinedJSON(t *testing.T) {
+	abi := `[{"constant":false,"inputs":[{"name":"a","type":"uint256"}],"name":"multiply","outputs":[{"name":"d","type":"uint256"}],"type":"function"}]`
+	tests := []string{
+		// Legacy output, with the ABI and docs encoded as strings
+		`{"contracts":{"test.sol:test":{"abi":` + jsonString(abi) + `,"bin":"6060","devdoc":"{\"methods\":{}}","userdoc":""}},"version":"0.4.9+commit.364da425"}`,
+		// Newer output, with embedded objects and no docs
+		`{"contracts":{"test.sol:test":{"abi":` + abi + `,"bin":"6060"}},"version":"0.4.9+commit.364da425"}`,
+	}
+	for i, combined := range tests {
+		contracts, err := ParseCombinedJSON([]byte(combined), testSource, "--combined-json abi,bin")
+		if err != nil {
+			t.Fatalf("test %d: failed to parse combined json: %v", i, err)
+		}
+		c, ok := contracts["test.sol:test"]
+		if !ok {
+			t.Fatalf("test %d: contract missing from %v", i, contracts)
+		}
+		if c.Code != "0x6060" {
+			t.Errorf("test %d: code mismatch: have %s, want %s", i, c.Code, "0x6060")
+		}
+		if c.Info.CompilerVersion != "0.4.9" {
+			t.Errorf("test %d: version mismatch: have %s, want %s", i, c.Info.CompilerVersion, "0.4.9")
+		}
+		if blob, _ := json.Marshal(c.Info.AbiDefinition); string(blob) != abi {
+			t.Errorf("test %d: abi mismatch: have %s, want %s", i, blob, abi)
+		}
+	}
+}
+
+func jsonString(s string) string {
+	blob, _ := json.Marshal(s)
+	return string(blob)
+}
@@ -154,6 +154,24 @@ func (a *Address) UnmarshalJSON(input []byte) error {
 	return hexutil.UnmarshalJSON("Address", input, a[:])
 }
 
+// MarshalText returns the hex representation of a.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.Hex()), nil
+}
+
+// UnmarshalText parses an address in hex syntax.
+func (a *Address) UnmarshalText(input []byte) error {
+	dec, err := hexutil.Decode(string(input))
+	if err != nil {
+		return err
+	}
+	if len(dec) != AddressLength {
+		return fmt.Errorf("hex string has length %d, want %d for Address", len(dec)*2, AddressLength*2)
+	}
+	copy(a[:], dec)
+	return nil
+}
+
 // PP Pretty Prints a byte slice in the following format:
 // 	hex(value[:4])...(hex[len(value)-4:])
 func PP(value []byte) string {
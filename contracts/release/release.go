@@ -127,7 +127,7 @@ func (r *ReleaseService) checker() {
 			opts := &bind.CallOpts{Context: ctx}
 			version, err := r.oracle.CurrentVersion(opts)
 			if err != nil {
-				if err == bind.ErrNoCode {
+				if bind.IsNotDeployed(err) {
 					glog.V(logger.Debug).Infof("Release oracle not found at %x", r.config.Oracle)
 					continue
 				}
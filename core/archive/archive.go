@@ -0,0 +1,203 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package archive implements a portable, verifiable chain archive format.
+//
+// An archive is a directory holding a range of canonical blocks split into
+// chunk files, optionally followed by a snapshot of the state at the last block,
+// and a JSON manifest listing the size and SHA-256 checksum of every file. Each
+// chunk is an RLP stream of headers with their bodies and consensus receipts,
+// so the chain can be imported either by executing every block, or, if the
+// snapshot verifies against the state root of the last header, by inserting the
+// headers, bodies and receipts the way fast sync does, without execution.
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+)
+
+const (
+	// Version is the archive format version written into the manifest.
+	Version = 1
+
+	// ManifestFile is the name of the manifest within an archive directory.
+	ManifestFile = "manifest.json"
+)
+
+var (
+	chunkBlocks    = uint64(8192)     // Number of blocks stored in a single chunk file
+	stateFileBytes = 64 * 1024 * 1024 // Size after which a new state snapshot file is started
+)
+
+var (
+	// ErrInterrupted is returned if an import is stopped before completion.
+	ErrInterrupted = errors.New("interrupted")
+
+	errArchiveExists = errors.New("archive already exists")
+)
+
+// File is a single data file of an archive.
+type File struct {
+	Name     string `json:"file"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"sha256"`
+}
+
+// Chunk is a block chunk file of an archive, holding a contiguous range of
+// canonical blocks.
+type Chunk struct {
+	File
+	First uint64      `json:"first"`
+	Last  uint64      `json:"last"`
+	Hash  common.Hash `json:"hash"` // Hash of the last block of the chunk
+}
+
+// Snapshot is the state snapshot of an archive, taken at its last block.
+type Snapshot struct {
+	Root  common.Hash `json:"root"`
+	Files []File      `json:"files"`
+}
+
+// Manifest describes the contents of an archive.
+type Manifest struct {
+	Version int         `json:"version"`
+	Genesis common.Hash `json:"genesis"`
+	First   uint64      `json:"first"`
+	Last    uint64      `json:"last"`
+	Chunks  []Chunk     `json:"chunks"`
+	State   *Snapshot   `json:"state,omitempty"`
+}
+
+// ReadManifest loads and sanity checks the manifest of an archive.
+func ReadManifest(dir string) (*Manifest, error) {
+	blob, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(Manifest)
+	if err := json.Unmarshal(blob, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if manifest.Version != Version {
+		return nil, fmt.Errorf("unsupported archive version %d, want %d", manifest.Version, Version)
+	}
+	if len(manifest.Chunks) == 0 {
+		return nil, errors.New("archive contains no blocks")
+	}
+	next := manifest.First
+	for _, chunk := range manifest.Chunks {
+		if chunk.First != next || chunk.Last < chunk.First {
+			return nil, fmt.Errorf("chunk %s: invalid range #%d-#%d, want from #%d", chunk.Name, chunk.First, chunk.Last, next)
+		}
+		next = chunk.Last + 1
+	}
+	if next != manifest.Last+1 {
+		return nil, fmt.Errorf("chunks end at #%d, want #%d", next-1, manifest.Last)
+	}
+	return manifest, nil
+}
+
+// IsArchive reports whether the path is an archive directory.
+func IsArchive(path string) bool {
+	return common.FileExist(filepath.Join(path, ManifestFile))
+}
+
+// entry is a single block stored in a chunk file, with the consensus fields of
+// its receipts.
+type entry struct {
+	Header   *types.Header
+	Txs      []*types.Transaction
+	Uncles   []*types.Header
+	Receipts []*types.Receipt
+}
+
+// checksumWriter is a file being written into an archive, tracking its size and
+// checksum.
+type checksumWriter struct {
+	file *os.File
+	name string
+	size int64
+	hash hash.Hash
+}
+
+// createFile creates a new data file within an archive.
+func createFile(dir, name string) (*checksumWriter, error) {
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &checksumWriter{file: file, name: name, hash: sha256.New()}, nil
+}
+
+// Write implements io.Writer, writing the data both to disk and the hasher.
+func (w *checksumWriter) Write(data []byte) (int, error) {
+	n, err := w.file.Write(data)
+	w.size += int64(n)
+	w.hash.Write(data[:n])
+	return n, err
+}
+
+// Close flushes the file to disk and returns its manifest entry.
+func (w *checksumWriter) Close() (File, error) {
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		return File{}, err
+	}
+	if err := w.file.Close(); err != nil {
+		return File{}, err
+	}
+	return File{Name: w.name, Size: w.size, Checksum: hex.EncodeToString(w.hash.Sum(nil))}, nil
+}
+
+// openFile opens a data file of an archive, verifying its size and checksum
+// against the manifest before handing it out.
+func openFile(dir string, file File) (*os.File, error) {
+	in, err := os.Open(filepath.Join(dir, file.Name))
+	if err != nil {
+		return nil, err
+	}
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, in)
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+	if size != file.Size {
+		in.Close()
+		return nil, fmt.Errorf("%s: size mismatch: have %d, want %d", file.Name, size, file.Size)
+	}
+	if sum := hex.EncodeToString(hasher.Sum(nil)); sum != file.Checksum {
+		in.Close()
+		return nil, fmt.Errorf("%s: checksum mismatch: have %s, want %s", file.Name, sum, file.Checksum)
+	}
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		in.Close()
+		return nil, err
+	}
+	return in, nil
+}
@@ -0,0 +1,282 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package archive
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/state"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/ethdb"
+	"github.com/ur-technology/go-ur/event"
+	"github.com/ur-technology/go-ur/params"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+)
+
+const testBlocks = 100
+
+func init() {
+	chunkBlocks, stateFileBytes = 16, 4096
+}
+
+// newTestChain creates a blockchain containing only the test genesis block.
+func newTestChain(t *testing.T) (*core.BlockChain, ethdb.Database) {
+	db, _ := ethdb.NewMemDatabase()
+	core.WriteGenesisBlockForTesting(db, core.GenesisAccount{Address: testAddress, Balance: big.NewInt(1000000000)})
+
+	chain, err := core.NewBlockChain(db, params.TestChainConfig, new(core.FakePow), new(event.TypeMux))
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	return chain, db
+}
+
+// newFilledChain creates a blockchain of testBlocks blocks with value transfers
+// and a few contracts with storage.
+func newFilledChain(t *testing.T) (*core.BlockChain, ethdb.Database) {
+	chain, db := newTestChain(t)
+
+	blocks, _ := core.GenerateChain(params.TestChainConfig, chain, chain.Genesis(), db, testBlocks, func(i int, block *core.BlockGen) {
+		block.SetCoinbase(common.Address{byte(i % 4)})
+
+		signer := types.MakeSigner(params.TestChainConfig, block.Number())
+		tx := types.NewTransaction(block.TxNonce(testAddress), common.Address{0xff, byte(i)}, big.NewInt(1000), params.TxGas, nil, nil)
+		if i%10 == 0 {
+			var code []byte
+			for j := 0; j < 16; j++ {
+				code = append(code, 0x61, byte(i), byte(j+1), 0x60, byte(j), 0x55) // PUSH2 val PUSH1 slot SSTORE
+			}
+			code = append(code, 0x60, byte(i+1), 0x60, 0x00, 0x52) // PUSH1 i+1 PUSH1 0 MSTORE
+			code = append(code, 0x60, 0x20, 0x60, 0x00, 0xf3)      // PUSH1 32 PUSH1 0 RETURN
+
+			tx = types.NewContractCreation(block.TxNonce(testAddress), new(big.Int), big.NewInt(1000000), nil, code)
+		}
+		tx, err := tx.SignECDSA(signer, testKey)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block #%d: %v", n, err)
+	}
+	return chain, db
+}
+
+// exportTestArchive exports the whole test chain into a new temporary directory.
+func exportTestArchive(t *testing.T, chain *core.BlockChain, db ethdb.Database, snapshot bool) string {
+	dir, err := ioutil.TempDir("", "archive-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	if _, err := Export(chain, db, dir, 0, testBlocks, snapshot); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to export chain: %v", err)
+	}
+	return dir
+}
+
+// assertImported checks that the chain has the same head block as the source
+// one, with its state and the receipts of all blocks.
+func assertImported(t *testing.T, chain *core.BlockChain, db ethdb.Database, source *core.BlockChain) {
+	if have, want := chain.CurrentBlock().Hash(), source.CurrentBlock().Hash(); have != want {
+		t.Fatalf("head block mismatch: have %x, want %x", have, want)
+	}
+	if _, err := chain.State(); err != nil {
+		t.Fatalf("head state unavailable: %v", err)
+	}
+	for number := uint64(1); number <= testBlocks; number++ {
+		block := source.GetBlockByNumber(number)
+		if receipts := core.GetBlockReceipts(db, block.Hash(), number); len(receipts) != len(block.Transactions()) {
+			t.Fatalf("block #%d: receipts mismatch: have %d, want %d", number, len(receipts), len(block.Transactions()))
+		}
+	}
+}
+
+// Tests that an exported chain can be imported by executing all its blocks.
+func TestExportImport(t *testing.T) {
+	source, sourceDb := newFilledChain(t)
+	dir := exportTestArchive(t, source, sourceDb, false)
+	defer os.RemoveAll(dir)
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if want := (testBlocks + int(chunkBlocks)) / int(chunkBlocks); len(manifest.Chunks) != want {
+		t.Fatalf("chunk count mismatch: have %d, want %d", len(manifest.Chunks), want)
+	}
+	if manifest.State != nil {
+		t.Fatalf("unrequested state snapshot exported")
+	}
+	chain, db := newTestChain(t)
+	if err := Import(chain, db, dir, false, nil); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	assertImported(t, chain, db, source)
+
+	// Executed blocks should all have their states
+	if _, err := state.New(chain.GetBlockByNumber(testBlocks/2).Root(), db); err != nil {
+		t.Fatalf("intermediate state missing: %v", err)
+	}
+}
+
+// Tests that a chain exported with a state snapshot is imported without the
+// execution of its blocks.
+func TestImportSnapshot(t *testing.T) {
+	source, sourceDb := newFilledChain(t)
+	dir := exportTestArchive(t, source, sourceDb, true)
+	defer os.RemoveAll(dir)
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if manifest.State == nil || len(manifest.State.Files) < 2 {
+		t.Fatalf("state snapshot not split over files: %v", manifest.State)
+	}
+	chain, db := newTestChain(t)
+	if err := Import(chain, db, dir, false, nil); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	assertImported(t, chain, db, source)
+
+	// Unexecuted blocks should have no states
+	if _, err := state.New(chain.GetBlockByNumber(testBlocks/2).Root(), db); err == nil {
+		t.Fatalf("intermediate state present, blocks executed")
+	}
+	// The imported head should survive a restart
+	restarted, err := core.NewBlockChain(db, params.TestChainConfig, new(core.FakePow), new(event.TypeMux))
+	if err != nil {
+		t.Fatalf("failed to reopen blockchain: %v", err)
+	}
+	if have, want := restarted.CurrentBlock().Hash(), source.CurrentBlock().Hash(); have != want {
+		t.Fatalf("head block mismatch after restart: have %x, want %x", have, want)
+	}
+}
+
+// Tests that a state snapshot not matching the chain or incomplete is rejected,
+// and the blocks are executed instead.
+func TestImportBadSnapshot(t *testing.T) {
+	source, sourceDb := newFilledChain(t)
+
+	tests := []func(*Manifest){
+		func(m *Manifest) { m.State.Files = m.State.Files[1:] },
+		func(m *Manifest) { m.State.Root = source.GetBlockByNumber(testBlocks - 1).Root() },
+	}
+	for i, tamper := range tests {
+		dir := exportTestArchive(t, source, sourceDb, true)
+		defer os.RemoveAll(dir)
+
+		manifest, _ := ReadManifest(dir)
+		tamper(manifest)
+		blob, _ := json.Marshal(manifest)
+		ioutil.WriteFile(filepath.Join(dir, ManifestFile), blob, 0644)
+
+		chain, db := newTestChain(t)
+		if err := Import(chain, db, dir, false, nil); err != nil {
+			t.Fatalf("test %d: failed to import chain: %v", i, err)
+		}
+		assertImported(t, chain, db, source)
+
+		if _, err := state.New(chain.GetBlockByNumber(testBlocks/2).Root(), db); err != nil {
+			t.Fatalf("test %d: intermediate state missing, blocks not executed: %v", i, err)
+		}
+	}
+}
+
+// Tests that corrupted chunks are detected before importing anything from them.
+func TestImportCorrupted(t *testing.T) {
+	source, sourceDb := newFilledChain(t)
+	dir := exportTestArchive(t, source, sourceDb, true)
+	defer os.RemoveAll(dir)
+
+	manifest, _ := ReadManifest(dir)
+	corrupt := manifest.Chunks[2]
+
+	path := filepath.Join(dir, corrupt.Name)
+	blob, _ := ioutil.ReadFile(path)
+	blob[len(blob)/2] ^= 0x01
+	ioutil.WriteFile(path, blob, 0644)
+
+	chain, db := newTestChain(t)
+	if err := Import(chain, db, dir, false, nil); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("corrupted chunk error mismatch: have %v, want checksum mismatch", err)
+	}
+	if chain.HasHeader(source.GetBlockByNumber(corrupt.First).Hash()) {
+		t.Fatalf("block of corrupted chunk imported")
+	}
+}
+
+// Tests that interrupted imports can be resumed, skipping the chunks already
+// imported.
+func TestImportResume(t *testing.T) {
+	source, sourceDb := newFilledChain(t)
+	dir := exportTestArchive(t, source, sourceDb, true)
+	defer os.RemoveAll(dir)
+
+	chain, db := newTestChain(t)
+
+	stop := make(chan struct{})
+	close(stop)
+	if err := Import(chain, db, dir, false, stop); err != ErrInterrupted {
+		t.Fatalf("stopped import error mismatch: have %v, want %v", err, ErrInterrupted)
+	}
+	// Hide a chunk to abort the import midway
+	manifest, _ := ReadManifest(dir)
+	missing := filepath.Join(dir, manifest.Chunks[3].Name)
+	if err := os.Rename(missing, missing+".bak"); err != nil {
+		t.Fatalf("failed to hide chunk: %v", err)
+	}
+	if err := Import(chain, db, dir, false, nil); err == nil {
+		t.Fatalf("import succeeded with missing chunk")
+	}
+	if !chain.HasBlock(manifest.Chunks[2].Hash) {
+		t.Fatalf("chunks before the missing one not imported")
+	}
+	// Corrupt an already imported chunk and restore the missing one, resuming
+	// should not touch the former
+	path := filepath.Join(dir, manifest.Chunks[0].Name)
+	if err := ioutil.WriteFile(path, []byte("corrupted"), 0644); err != nil {
+		t.Fatalf("failed to corrupt chunk: %v", err)
+	}
+	if err := os.Rename(missing+".bak", missing); err != nil {
+		t.Fatalf("failed to restore chunk: %v", err)
+	}
+	if err := Import(chain, db, dir, false, nil); err != nil {
+		t.Fatalf("failed to resume import: %v", err)
+	}
+	assertImported(t, chain, db, source)
+
+	// Importing a completed archive again should be a noop
+	if err := Import(chain, db, dir, false, nil); err != nil {
+		t.Fatalf("failed to reimport chain: %v", err)
+	}
+}
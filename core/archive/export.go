@@ -0,0 +1,188 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/state"
+	"github.com/ur-technology/go-ur/ethdb"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+	"github.com/ur-technology/go-ur/rlp"
+)
+
+// Export writes the canonical blocks first to last (inclusive) into a new
+// archive in dir, together with their receipts and, if requested, a snapshot of
+// the state at the last block. The manifest is written last, so an interrupted
+// export never leaves a valid archive behind.
+func Export(chain *core.BlockChain, db ethdb.Database, dir string, first, last uint64, snapshot bool) (*Manifest, error) {
+	if first > last {
+		return nil, fmt.Errorf("first (%d) is greater than last (%d)", first, last)
+	}
+	if head := chain.CurrentBlock().NumberU64(); last > head {
+		return nil, fmt.Errorf("last (%d) is beyond the current head (%d)", last, head)
+	}
+	if IsArchive(dir) {
+		return nil, errArchiveExists
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	glog.V(logger.Info).Infof("exporting blocks #%d-#%d into %s", first, last, dir)
+	start := time.Now()
+
+	manifest := &Manifest{
+		Version: Version,
+		Genesis: chain.Genesis().Hash(),
+		First:   first,
+		Last:    last,
+	}
+	for from := first; from <= last; from += chunkBlocks {
+		to := from + chunkBlocks - 1
+		if to > last || to < from {
+			to = last
+		}
+		chunk, err := exportChunk(chain, db, dir, from, to)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Chunks = append(manifest.Chunks, chunk)
+		glog.V(logger.Info).Infof("exported chunk #%d-#%d [%x…] (%d bytes)", from, to, chunk.Hash[:4], chunk.Size)
+
+		if to == last {
+			break
+		}
+	}
+	if snapshot {
+		head := chain.GetBlockByNumber(last)
+		files, err := exportState(db, dir, head.Root())
+		if err != nil {
+			return nil, fmt.Errorf("state snapshot: %v", err)
+		}
+		manifest.State = &Snapshot{Root: head.Root(), Files: files}
+	}
+	blob, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ManifestFile), blob, 0644); err != nil {
+		return nil, err
+	}
+	glog.V(logger.Info).Infof("exported %d blocks in %v", last-first+1, common.PrettyDuration(time.Since(start)))
+	return manifest, nil
+}
+
+// exportChunk writes a range of canonical blocks and their receipts into a new
+// chunk file.
+func exportChunk(chain *core.BlockChain, db ethdb.Database, dir string, first, last uint64) (Chunk, error) {
+	out, err := createFile(dir, fmt.Sprintf("blocks-%08d-%08d.rlp", first, last))
+	if err != nil {
+		return Chunk{}, err
+	}
+	var hash common.Hash
+	for number := first; number <= last; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			out.Close()
+			return Chunk{}, fmt.Errorf("block #%d not found", number)
+		}
+		receipts := core.GetBlockReceipts(db, block.Hash(), number)
+		if len(receipts) != len(block.Transactions()) {
+			out.Close()
+			return Chunk{}, fmt.Errorf("block #%d: receipts not found", number)
+		}
+		if err := rlp.Encode(out, &entry{Header: block.Header(), Txs: block.Transactions(), Uncles: block.Uncles(), Receipts: receipts}); err != nil {
+			out.Close()
+			return Chunk{}, err
+		}
+		hash = block.Hash()
+	}
+	file, err := out.Close()
+	if err != nil {
+		return Chunk{}, err
+	}
+	return Chunk{File: file, First: first, Last: last, Hash: hash}, nil
+}
+
+// exportState writes all the trie nodes and contract codes of a state into a
+// series of snapshot files, each a stream of RLP encoded blobs.
+func exportState(db ethdb.Database, dir string, root common.Hash) ([]File, error) {
+	statedb, err := state.New(root, db)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		files []File
+		out   *checksumWriter
+		count int
+	)
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		// Nodes embedded into their parents are exported along with them
+		if it.Hash == (common.Hash{}) {
+			continue
+		}
+		blob, err := db.Get(it.Hash[:])
+		if err != nil {
+			if out != nil {
+				out.Close()
+			}
+			return nil, fmt.Errorf("state entry %x: %v", it.Hash, err)
+		}
+		if out == nil {
+			if out, err = createFile(dir, fmt.Sprintf("state-%04d.rlp", len(files))); err != nil {
+				return nil, err
+			}
+		}
+		if err := rlp.Encode(out, blob); err != nil {
+			out.Close()
+			return nil, err
+		}
+		count++
+
+		if out.size >= int64(stateFileBytes) {
+			file, err := out.Close()
+			if err != nil {
+				return nil, err
+			}
+			files, out = append(files, file), nil
+		}
+	}
+	if it.Error != nil {
+		if out != nil {
+			out.Close()
+		}
+		return nil, it.Error
+	}
+	if out != nil {
+		file, err := out.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	glog.V(logger.Info).Infof("exported state %x… with %d entries in %d files", root[:4], count, len(files))
+	return files, nil
+}
@@ -0,0 +1,271 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/state"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/ethdb"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+	"github.com/ur-technology/go-ur/rlp"
+)
+
+// headerCheckFrequency is the frequency of the seal verification of the headers
+// inserted without execution, matching the one of fast sync.
+const headerCheckFrequency = 100
+
+// Import inserts the blocks of an archive into the chain. If the archive holds a
+// state snapshot which verifies against the root of its last header and the
+// local chain is behind it, the blocks are inserted without execution and the
+// snapshot becomes the new head state. Otherwise, or if execute is set, every
+// block is executed. Chunks already present in the chain are skipped, so an
+// interrupted import can be resumed by running it again. Closing stop aborts
+// the import between two files.
+func Import(chain *core.BlockChain, db ethdb.Database, dir string, execute bool, stop <-chan struct{}) error {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+	if genesis := chain.Genesis().Hash(); manifest.Genesis != genesis {
+		return fmt.Errorf("genesis mismatch: have %x, want %x", manifest.Genesis, genesis)
+	}
+	head := manifest.Chunks[len(manifest.Chunks)-1].Hash
+	if chain.HasBlockAndState(head) && chain.CurrentBlock().NumberU64() >= manifest.Last {
+		glog.V(logger.Info).Infof("archive already imported, head #%d [%x…] present", manifest.Last, head[:4])
+		return nil
+	}
+	glog.V(logger.Info).Infof("importing blocks #%d-#%d from %s", manifest.First, manifest.Last, dir)
+	start := time.Now()
+
+	if manifest.State != nil && !execute && chain.CurrentBlock().NumberU64() < manifest.Last {
+		if err := importChunks(chain, dir, manifest, false, stop); err != nil {
+			return err
+		}
+		err := importState(chain, db, dir, manifest, stop)
+		if err == nil {
+			glog.V(logger.Info).Infof("imported %d blocks without execution in %v", manifest.Last-manifest.First+1, common.PrettyDuration(time.Since(start)))
+			return nil
+		}
+		if err == ErrInterrupted {
+			return err
+		}
+		glog.V(logger.Warn).Infof("state snapshot rejected, executing blocks: %v", err)
+	}
+	if err := importChunks(chain, dir, manifest, true, stop); err != nil {
+		return err
+	}
+	glog.V(logger.Info).Infof("imported %d blocks in %v", manifest.Last-manifest.First+1, common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// interrupted checks whether the stop channel has been closed.
+func interrupted(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// importChunks inserts the blocks of all chunks not yet present in the chain,
+// either executing them or inserting their headers, bodies and receipts.
+func importChunks(chain *core.BlockChain, dir string, manifest *Manifest, execute bool, stop <-chan struct{}) error {
+	for _, chunk := range manifest.Chunks {
+		if interrupted(stop) {
+			return ErrInterrupted
+		}
+		if (execute && chain.HasBlockAndState(chunk.Hash)) || (!execute && chain.HasBlock(chunk.Hash)) {
+			glog.V(logger.Info).Infof("skipping chunk #%d-#%d, all blocks present", chunk.First, chunk.Last)
+			continue
+		}
+		blocks, receipts, err := readChunk(dir, chunk)
+		if err != nil {
+			return err
+		}
+		// Leave out the genesis block, it's already known locally
+		if blocks[0].NumberU64() == 0 {
+			if hash := blocks[0].Hash(); hash != manifest.Genesis {
+				return fmt.Errorf("%s: genesis mismatch: have %x, want %x", chunk.Name, hash, manifest.Genesis)
+			}
+			blocks, receipts = blocks[1:], receipts[1:]
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if execute {
+			if n, err := chain.InsertChain(blocks); err != nil {
+				return fmt.Errorf("block #%d: %v", blocks[n].NumberU64(), err)
+			}
+			continue
+		}
+		headers := make([]*types.Header, len(blocks))
+		for i, block := range blocks {
+			headers[i] = block.Header()
+		}
+		if n, err := chain.InsertHeaderChain(headers, headerCheckFrequency); err != nil {
+			return fmt.Errorf("header #%d: %v", headers[n].Number, err)
+		}
+		if n, err := chain.InsertReceiptChain(blocks, receipts); err != nil {
+			return fmt.Errorf("block #%d: %v", blocks[n].NumberU64(), err)
+		}
+	}
+	return nil
+}
+
+// readChunk loads the blocks of a chunk file after verifying its checksum, and
+// checks the bodies and receipts against the headers they belong to.
+func readChunk(dir string, chunk Chunk) (types.Blocks, []types.Receipts, error) {
+	in, err := openFile(dir, chunk.File)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer in.Close()
+
+	var (
+		blocks   types.Blocks
+		receipts []types.Receipts
+	)
+	stream := rlp.NewStream(in, 0)
+	for number := chunk.First; number <= chunk.Last; number++ {
+		var e entry
+		if err := stream.Decode(&e); err != nil {
+			return nil, nil, fmt.Errorf("%s: block #%d: %v", chunk.Name, number, err)
+		}
+		if e.Header.Number == nil || e.Header.Number.Uint64() != number {
+			return nil, nil, fmt.Errorf("%s: block number mismatch: have %v, want %d", chunk.Name, e.Header.Number, number)
+		}
+		if len(blocks) > 0 && e.Header.ParentHash != blocks[len(blocks)-1].Hash() {
+			return nil, nil, fmt.Errorf("%s: block #%d: non contiguous chain", chunk.Name, number)
+		}
+		if hash := types.DeriveSha(types.Transactions(e.Txs)); hash != e.Header.TxHash {
+			return nil, nil, fmt.Errorf("%s: block #%d: transaction root mismatch", chunk.Name, number)
+		}
+		if hash := types.CalcUncleHash(e.Uncles); hash != e.Header.UncleHash {
+			return nil, nil, fmt.Errorf("%s: block #%d: uncle hash mismatch", chunk.Name, number)
+		}
+		if hash := types.DeriveSha(types.Receipts(e.Receipts)); hash != e.Header.ReceiptHash {
+			return nil, nil, fmt.Errorf("%s: block #%d: receipt root mismatch", chunk.Name, number)
+		}
+		blocks = append(blocks, types.NewBlockWithHeader(e.Header).WithBody(e.Txs, e.Uncles))
+		receipts = append(receipts, e.Receipts)
+	}
+	if _, err := stream.Raw(); err != io.EOF {
+		return nil, nil, fmt.Errorf("%s: trailing data after block #%d", chunk.Name, chunk.Last)
+	}
+	if hash := blocks[len(blocks)-1].Hash(); hash != chunk.Hash {
+		return nil, nil, fmt.Errorf("%s: last block hash mismatch: have %x, want %x", chunk.Name, hash, chunk.Hash)
+	}
+	return blocks, receipts, nil
+}
+
+// importState writes the state snapshot of an archive into the database after
+// checking it against the last imported header, verifies that the state is
+// complete and sets the last block as the new head.
+func importState(chain *core.BlockChain, db ethdb.Database, dir string, manifest *Manifest, stop <-chan struct{}) error {
+	last := manifest.Chunks[len(manifest.Chunks)-1]
+	if hash := core.GetCanonicalHash(db, manifest.Last); hash != last.Hash {
+		return fmt.Errorf("block #%d [%x…] is not canonical", manifest.Last, last.Hash[:4])
+	}
+	header := chain.GetHeader(last.Hash, manifest.Last)
+	if header.Root != manifest.State.Root {
+		return fmt.Errorf("state root mismatch: have %x, want %x", manifest.State.Root, header.Root)
+	}
+	// Hold back the root node until the state is known to be complete, otherwise
+	// a partial state would be mistaken for a present one
+	pending := &rootDatabase{Database: db, root: header.Root}
+	for _, file := range manifest.State.Files {
+		if interrupted(stop) {
+			return ErrInterrupted
+		}
+		if err := importStateFile(pending, dir, file); err != nil {
+			return err
+		}
+	}
+	statedb, err := state.New(header.Root, pending)
+	if err != nil {
+		return err
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		return fmt.Errorf("incomplete state: %v", it.Error)
+	}
+	if pending.blob != nil {
+		if err := db.Put(header.Root[:], pending.blob); err != nil {
+			return err
+		}
+	}
+	return chain.FastSyncCommitHead(last.Hash)
+}
+
+// importStateFile writes the entries of a state snapshot file into the database,
+// keyed by their hashes, so that any entry not belonging to the state is simply
+// never referenced by it.
+func importStateFile(db *rootDatabase, dir string, file File) error {
+	in, err := openFile(dir, file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	batch := db.NewBatch()
+	stream := rlp.NewStream(in, 0)
+	for {
+		blob, err := stream.Bytes()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%s: %v", file.Name, err)
+		}
+		hash := crypto.Keccak256Hash(blob)
+		if hash == db.root {
+			db.blob = blob
+			continue
+		}
+		if err := batch.Put(hash[:], blob); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// rootDatabase is a database whose state root node is kept in memory, serving it
+// for the verification of the state without persisting it.
+type rootDatabase struct {
+	ethdb.Database
+	root common.Hash
+	blob []byte
+}
+
+// Get retrieves the given key, serving the state root node from memory.
+func (db *rootDatabase) Get(key []byte) ([]byte, error) {
+	if db.blob != nil && bytes.Equal(key, db.root[:]) {
+		return db.blob, nil
+	}
+	return db.Database.Get(key)
+}
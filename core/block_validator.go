@@ -136,7 +136,7 @@ func (v *BlockValidator) ValidateState(block, parent *types.Block, statedb *stat
 	if err != nil {
 		return err
 	}
-	vfyNSignups, vfyTotalWei := calculateBlockTotals(parent.NSignups(), parent.TotalWei(), header, block.Uncles(), msgs)
+	vfyNSignups, vfyTotalWei := calculateBlockTotals(v.config, parent.NSignups(), parent.TotalWei(), header, block.Uncles(), msgs)
 	if vfyNSignups.Cmp(header.NSignups) != 0 {
 		return fmt.Errorf("number of signups mismatch: got %s, expected %s", header.NSignups, vfyNSignups)
 	}
@@ -280,6 +280,10 @@ func (self *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	}
 	// If all checks out, manually set the head block
 	self.mu.Lock()
+	if err := WriteHeadBlockHash(self.chainDb, hash); err != nil {
+		self.mu.Unlock()
+		return err
+	}
 	self.currentBlock = block
 	self.mu.Unlock()
 
@@ -0,0 +1,108 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bloombits implements the bit-rotated bloom index used to search the
+// logs of long block ranges without scanning every header.
+//
+// The header blooms of a section of consecutive blocks are rotated into one bit
+// vector per bloom bit, the n-th bit of the vector telling whether the bloom of
+// the n-th block of the section has that bit set. Checking a value against a
+// whole section then takes three vector lookups and two ANDs instead of one
+// bloom test per block.
+package bloombits
+
+import (
+	"errors"
+
+	"github.com/ur-technology/go-ur/core/types"
+)
+
+const (
+	// BloomByteLength is the number of bytes of a header bloom (types.Bloom).
+	BloomByteLength = 256
+
+	// BloomBitLength is the number of bits of a header bloom, which is also the
+	// number of bit vectors of an indexed section.
+	BloomBitLength = 8 * BloomByteLength
+)
+
+var (
+	errSectionOutOfBounds  = errors.New("section out of bounds")
+	errBloomBitOutOfBounds = errors.New("bloom bit out of bounds")
+	errSectionIncomplete   = errors.New("section incomplete")
+)
+
+// Generator rotates the header blooms of a section into bit vectors.
+type Generator struct {
+	blooms    [BloomBitLength][]byte // Rotated blooms, one vector per bloom bit
+	sections  uint                   // Number of blocks of the section
+	nextBloom uint                   // Index of the next bloom to add
+}
+
+// NewGenerator creates a generator for sections of the given number of blocks,
+// which must be a multiple of 8.
+func NewGenerator(sections uint) (*Generator, error) {
+	if sections%8 != 0 {
+		return nil, errors.New("section count not multiple of 8")
+	}
+	b := &Generator{sections: sections}
+	for i := 0; i < BloomBitLength; i++ {
+		b.blooms[i] = make([]byte, sections/8)
+	}
+	return b, nil
+}
+
+// AddBloom rotates the bloom of the block with the given index within the
+// section into the bit vectors. Blooms must be added in order.
+func (b *Generator) AddBloom(index uint, bloom types.Bloom) error {
+	if b.nextBloom >= b.sections {
+		return errSectionOutOfBounds
+	}
+	if b.nextBloom != index {
+		return errors.New("bloom filter with unexpected index")
+	}
+	byteIndex := b.nextBloom / 8
+	bitMask := byte(1) << byte(7-b.nextBloom%8)
+
+	// Bloom bit i is bit i%8 of the i/8-th byte counted from the end, just like
+	// the bits of the big integer the bloom is built from
+	for i := 0; i < BloomByteLength; i++ {
+		bloomByte := bloom[BloomByteLength-1-i]
+		if bloomByte == 0 {
+			continue
+		}
+		base := 8 * i
+		for j := 0; j < 8; j++ {
+			if bloomByte&(1<<uint(j)) != 0 {
+				b.blooms[base+j][byteIndex] |= bitMask
+			}
+		}
+	}
+	b.nextBloom++
+	return nil
+}
+
+// Bitset returns the bit vector of the given bloom bit once all the blooms of
+// the section have been added.
+func (b *Generator) Bitset(idx uint) ([]byte, error) {
+	if b.nextBloom != b.sections {
+		return nil, errSectionIncomplete
+	}
+	if idx >= BloomBitLength {
+		return nil, errBloomBitOutOfBounds
+	}
+	return b.blooms[idx], nil
+}
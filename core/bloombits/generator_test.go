@@ -0,0 +1,80 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package bloombits
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ur-technology/go-ur/core/types"
+)
+
+// Tests that the rotated bit vectors of a section have the bits of the blocks
+// whose bloom contains a value set, and only those.
+func TestGenerator(t *testing.T) {
+	const sections = 64
+
+	values := [][]byte{[]byte("alpha"), []byte("beta"), []byte("gamma")}
+	gen, err := NewGenerator(sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < sections; i++ {
+		var bloom types.Bloom
+		if i%3 != 0 {
+			bloom.Add(new(big.Int).SetBytes(values[i%len(values)]))
+		}
+		if err := gen.AddBloom(uint(i), bloom); err != nil {
+			t.Fatalf("bloom %d: %v", i, err)
+		}
+	}
+	if err := gen.AddBloom(sections, types.Bloom{}); err != errSectionOutOfBounds {
+		t.Fatalf("overflowing bloom error mismatch: have %v, want %v", err, errSectionOutOfBounds)
+	}
+	for i := 0; i < sections; i++ {
+		for j, value := range values {
+			want := i%3 != 0 && i%len(values) == j
+			have := true
+			for _, bit := range BloomIndexes(value) {
+				vector, err := gen.Bitset(bit)
+				if err != nil {
+					t.Fatalf("bit %d: %v", bit, err)
+				}
+				if vector[i/8]&(0x80>>uint(i%8)) == 0 {
+					have = false
+				}
+			}
+			// Another value can't set all three bits here, so no false positives
+			if have != want {
+				t.Errorf("block %d value %q: match %v, want %v", i, value, have, want)
+			}
+		}
+	}
+}
+
+// Tests that the bloom indexes match the bits of the header blooms.
+func TestBloomIndexes(t *testing.T) {
+	value := []byte("test value")
+
+	var bloom types.Bloom
+	bloom.Add(new(big.Int).SetBytes(value))
+	for _, bit := range BloomIndexes(value) {
+		if bloom[BloomByteLength-1-bit/8]&(1<<(bit%8)) == 0 {
+			t.Errorf("bloom bit %d not set", bit)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package bloombits
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ur-technology/go-ur/crypto"
+	"golang.org/x/net/context"
+)
+
+// BloomIndexes returns the three bloom bits set by a value in a header bloom.
+func BloomIndexes(data []byte) [3]uint {
+	hash := crypto.Keccak256(data)
+
+	var idxs [3]uint
+	for i := 0; i < len(idxs); i++ {
+		idxs[i] = (uint(hash[2*i])<<8 + uint(hash[2*i+1])) & (BloomBitLength - 1)
+	}
+	return idxs
+}
+
+// RetrievalWorkers is the number of sections searched in parallel by the filters.
+const RetrievalWorkers = 16
+
+// Retriever fetches the bit vectors of the given bloom bits of a section, in the
+// order of the bits.
+type Retriever func(ctx context.Context, section uint64, bits []uint) ([][]byte, error)
+
+// Matcher searches the bloom bits index for the blocks possibly containing logs
+// matching a filter. The filter is a list of groups, a block matching if its
+// bloom matches at least one value of every group.
+type Matcher struct {
+	sectionSize uint64
+	filters     [][][3]uint // Bloom bits of the values of each group
+	bits        []uint      // Distinct bloom bits of all the values, sorted
+}
+
+// NewMatcher creates a matcher for sections of the given number of blocks. Empty
+// groups match everything and are dropped.
+func NewMatcher(sectionSize uint64, filters [][][]byte) *Matcher {
+	m := &Matcher{sectionSize: sectionSize}
+
+	seen := make(map[uint]bool)
+	for _, group := range filters {
+		if len(group) == 0 {
+			continue
+		}
+		idxs := make([][3]uint, len(group))
+		for i, value := range group {
+			idxs[i] = BloomIndexes(value)
+			for _, bit := range idxs[i] {
+				if !seen[bit] {
+					seen[bit] = true
+					m.bits = append(m.bits, bit)
+				}
+			}
+		}
+		m.filters = append(m.filters, idxs)
+	}
+	sort.Sort(uintSlice(m.bits))
+	return m
+}
+
+// Match searches the sections covering the block range [begin, end], with up to
+// the given number of sections retrieved in parallel. The numbers of the blocks
+// whose bloom might match are sent on the results channel in ascending order,
+// which is closed once the search ends. The error is that of the first failed
+// retrieval, or that of the context.
+func (m *Matcher) Match(ctx context.Context, begin, end uint64, workers int, retrieve Retriever, results chan<- uint64) error {
+	defer close(results)
+
+	if begin > end {
+		return nil
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Start the retrieval of the sections in order, the channel of in-flight
+	// sections limiting the parallelism
+	type result struct {
+		matches []uint64
+		err     error
+	}
+	pending := make(chan chan result, workers)
+	go func() {
+		defer close(pending)
+
+		for section := begin / m.sectionSize; section <= end/m.sectionSize; section++ {
+			res := make(chan result, 1)
+			select {
+			case pending <- res:
+			case <-ctx.Done():
+				return
+			}
+			go func(section uint64) {
+				matches, err := m.matchSection(ctx, section, begin, end, retrieve)
+				res <- result{matches, err}
+			}(section)
+		}
+	}()
+	// Deliver the results of the sections in order
+	for res := range pending {
+		var r result
+		select {
+		case r = <-res:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if r.err != nil {
+			return r.err
+		}
+		for _, number := range r.matches {
+			select {
+			case results <- number:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// matchSection returns the numbers of the blocks of a section within the range
+// [begin, end] whose bloom might match.
+func (m *Matcher) matchSection(ctx context.Context, section, begin, end uint64, retrieve Retriever) ([]uint64, error) {
+	size := int(m.sectionSize / 8)
+
+	// Retrieve the vectors of all the bits needed
+	vectors := make(map[uint][]byte, len(m.bits))
+	if len(m.bits) > 0 {
+		bitsets, err := retrieve(ctx, section, m.bits)
+		if err != nil {
+			return nil, err
+		}
+		if len(bitsets) != len(m.bits) {
+			return nil, fmt.Errorf("section %d: %d bit vectors retrieved, %d requested", section, len(bitsets), len(m.bits))
+		}
+		for i, bit := range m.bits {
+			if len(bitsets[i]) != size {
+				return nil, fmt.Errorf("section %d bit %d: vector length %d, want %d", section, bit, len(bitsets[i]), size)
+			}
+			vectors[bit] = bitsets[i]
+		}
+	}
+	// AND the groups, OR-ing their values
+	match := make([]byte, size)
+	for i := range match {
+		match[i] = 0xff
+	}
+	for _, group := range m.filters {
+		union := make([]byte, size)
+		for _, idxs := range group {
+			a, b, c := vectors[idxs[0]], vectors[idxs[1]], vectors[idxs[2]]
+			for i := range union {
+				union[i] |= a[i] & b[i] & c[i]
+			}
+		}
+		for i := range match {
+			match[i] &= union[i]
+		}
+	}
+	// Collect the matching blocks within the range
+	var (
+		first   = section * m.sectionSize
+		matches []uint64
+	)
+	for i, bits := range match {
+		if bits == 0 {
+			continue
+		}
+		for j := uint64(0); j < 8; j++ {
+			if bits&(0x80>>j) == 0 {
+				continue
+			}
+			if number := first + uint64(i)*8 + j; number >= begin && number <= end {
+				matches = append(matches, number)
+			}
+		}
+	}
+	return matches, nil
+}
+
+type uintSlice []uint
+
+func (s uintSlice) Len() int           { return len(s) }
+func (s uintSlice) Less(i, j int) bool { return s[i] < s[j] }
+func (s uintSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,168 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package bloombits
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/ur-technology/go-ur/core/types"
+	"golang.org/x/net/context"
+)
+
+const testSectionSize = 128
+
+// testIndex is a bloom bits index of randomly generated blooms.
+type testIndex struct {
+	blooms  []types.Bloom
+	vectors [][][]byte // Bit vectors of every section
+}
+
+func newTestIndex(t *testing.T, sections int, values [][]byte) *testIndex {
+	rand := rand.New(rand.NewSource(1))
+
+	index := &testIndex{blooms: make([]types.Bloom, sections*testSectionSize)}
+	for i := range index.blooms {
+		for _, value := range values {
+			if rand.Intn(8) == 0 {
+				index.blooms[i].Add(new(big.Int).SetBytes(value))
+			}
+		}
+	}
+	for s := 0; s < sections; s++ {
+		gen, err := NewGenerator(testSectionSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < testSectionSize; i++ {
+			if err := gen.AddBloom(uint(i), index.blooms[s*testSectionSize+i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		vectors := make([][]byte, BloomBitLength)
+		for i := range vectors {
+			vectors[i], _ = gen.Bitset(uint(i))
+		}
+		index.vectors = append(index.vectors, vectors)
+	}
+	return index
+}
+
+func (index *testIndex) retrieve(ctx context.Context, section uint64, bits []uint) ([][]byte, error) {
+	if section >= uint64(len(index.vectors)) {
+		return nil, fmt.Errorf("section %d not indexed", section)
+	}
+	vectors := make([][]byte, len(bits))
+	for i, bit := range bits {
+		vectors[i] = index.vectors[section][bit]
+	}
+	return vectors, nil
+}
+
+// rawBytes is a value looked up in the blooms as is.
+type rawBytes []byte
+
+func (b rawBytes) Bytes() []byte { return b }
+
+// matches returns the blocks of a range whose bloom matches the filters.
+func (index *testIndex) matches(begin, end uint64, filters [][][]byte) []uint64 {
+	var matches []uint64
+	for number := begin; number <= end; number++ {
+		bloom := index.blooms[number]
+		match := true
+		for _, group := range filters {
+			if len(group) == 0 {
+				continue
+			}
+			any := false
+			for _, value := range group {
+				if types.BloomLookup(bloom, rawBytes(value)) {
+					any = true
+				}
+			}
+			match = match && any
+		}
+		if match {
+			matches = append(matches, number)
+		}
+	}
+	return matches
+}
+
+func collect(m *Matcher, begin, end uint64, retrieve Retriever) ([]uint64, error) {
+	var (
+		results = make(chan uint64)
+		errc    = make(chan error, 1)
+		matches []uint64
+	)
+	go func() { errc <- m.Match(context.Background(), begin, end, 4, retrieve, results) }()
+	for number := range results {
+		matches = append(matches, number)
+	}
+	return matches, <-errc
+}
+
+// Tests that the matcher finds the same blocks as checking every bloom.
+func TestMatcher(t *testing.T) {
+	var (
+		a, b, c, d = []byte("a"), []byte("b"), []byte("c"), []byte("d")
+		index      = newTestIndex(t, 6, [][]byte{a, b, c, d})
+	)
+	tests := []struct {
+		begin, end uint64
+		filters    [][][]byte
+	}{
+		{0, 6*testSectionSize - 1, [][][]byte{{a}}},
+		{0, 6*testSectionSize - 1, [][][]byte{{a, b}}},
+		{0, 6*testSectionSize - 1, [][][]byte{{a}, {b}}},
+		{0, 6*testSectionSize - 1, [][][]byte{{a, b}, {}, {c, d}}},
+		{100, 300, [][][]byte{{a}, {c, d}}},
+		{testSectionSize, testSectionSize, [][][]byte{{b}}},
+		{5, 10, [][][]byte{{}}},
+	}
+	for i, tt := range tests {
+		have, err := collect(NewMatcher(testSectionSize, tt.filters), tt.begin, tt.end, index.retrieve)
+		if err != nil {
+			t.Errorf("test %d: match failed: %v", i, err)
+			continue
+		}
+		if want := index.matches(tt.begin, tt.end, tt.filters); !reflect.DeepEqual(have, want) {
+			t.Errorf("test %d: matches mismatch:\nhave %v\nwant %v", i, have, want)
+		}
+	}
+}
+
+// Tests that retrieval failures abort the search.
+func TestMatcherRetrievalFailure(t *testing.T) {
+	index := newTestIndex(t, 2, [][]byte{[]byte("a")})
+
+	_, err := collect(NewMatcher(testSectionSize, [][][]byte{{[]byte("a")}}), 0, 4*testSectionSize-1, index.retrieve)
+	if err == nil {
+		t.Fatal("matching unindexed sections succeeded")
+	}
+	failure := errors.New("retrieval failure")
+	_, err = collect(NewMatcher(testSectionSize, [][][]byte{{[]byte("a")}}), 0, testSectionSize-1, func(context.Context, uint64, []uint) ([][]byte, error) {
+		return nil, failure
+	})
+	if err != failure {
+		t.Fatalf("error mismatch: have %v, want %v", err, failure)
+	}
+}
@@ -210,7 +210,7 @@ func GenerateChain(config *params.ChainConfig, blockchain *BlockChain, parent *t
 		if err != nil {
 			panic(err)
 		}
-		UpdateBlockTotals(parent.Header(), h, b.uncles, msgs)
+		UpdateBlockTotals(config, parent.Header(), h, b.uncles, msgs)
 
 		AccumulateRewards(statedb, h, b.uncles)
 		root, err := statedb.Commit(config.IsEIP158(h.Number))
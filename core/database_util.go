@@ -51,6 +51,10 @@ var (
 	mipmapPre    = []byte("mipmap-log-bloom-")
 	MIPMapLevels = []uint64{1000000, 500000, 100000, 50000, 1000}
 
+	bloomBitsPrefix    = []byte("bloomBits-")    // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + head hash -> bit vector
+	bloomSectionPrefix = []byte("bloomSection-") // bloomSectionPrefix + section (uint64 big endian) -> section head hash
+	bloomSectionsKey   = []byte("BloomSections") // number of indexed bloom bits sections
+
 	configPrefix = []byte("ethereum-config-") // config prefix for the db
 
 	// used by old (non-sequential keys) db, now only used for conversion
@@ -589,6 +593,78 @@ func GetMipmapBloom(db ethdb.Database, number, level uint64) types.Bloom {
 	return types.BytesToBloom(bloomDat)
 }
 
+// bloomBitsKey returns the database key of the bit vector of a bloom bit in the
+// section with the given head.
+func bloomBitsKey(bit uint, section uint64, head common.Hash) []byte {
+	key := make([]byte, len(bloomBitsPrefix)+2+8+common.HashLength)
+	copy(key, bloomBitsPrefix)
+	binary.BigEndian.PutUint16(key[len(bloomBitsPrefix):], uint16(bit))
+	binary.BigEndian.PutUint64(key[len(bloomBitsPrefix)+2:], section)
+	copy(key[len(bloomBitsPrefix)+10:], head[:])
+	return key
+}
+
+// GetBloomBits retrieves the bit vector of a bloom bit in the section with the
+// given head, nil if the section wasn't indexed with that head.
+func GetBloomBits(db ethdb.Database, bit uint, section uint64, head common.Hash) []byte {
+	bits, _ := db.Get(bloomBitsKey(bit, section, head))
+	return bits
+}
+
+// WriteBloomBits stores the bit vector of a bloom bit in the section with the
+// given head, caching the vectors retrieved by light clients.
+func WriteBloomBits(db ethdb.Database, bit uint, section uint64, head common.Hash, bits []byte) error {
+	if err := db.Put(bloomBitsKey(bit, section, head), bits); err != nil {
+		glog.Fatalf("failed to store bloom bits into database: %v", err)
+	}
+	return nil
+}
+
+// GetBloomSectionHead retrieves the hash of the last block of an indexed bloom
+// bits section.
+func GetBloomSectionHead(db ethdb.Database, section uint64) common.Hash {
+	data, _ := db.Get(append(bloomSectionPrefix, encodeBlockNumber(section)...))
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// GetBloomSections retrieves the number of consecutive bloom bits sections
+// indexed from the genesis.
+func GetBloomSections(db ethdb.Database) uint64 {
+	data, _ := db.Get(bloomSectionsKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteBloomSections stores the number of indexed bloom bits sections, used to
+// roll back the sections invalidated by a reorg.
+func WriteBloomSections(db ethdb.Database, sections uint64) error {
+	if err := db.Put(bloomSectionsKey, encodeBlockNumber(sections)); err != nil {
+		glog.Fatalf("failed to store bloom section count into database: %v", err)
+	}
+	return nil
+}
+
+// WriteBloomSection atomically stores the bit vectors of an indexed section with
+// its head, extending the number of indexed sections to include it. The vectors
+// are sparse, leaving their compression to the database.
+func WriteBloomSection(db ethdb.Database, section uint64, head common.Hash, bits [][]byte) error {
+	batch := db.NewBatch()
+	for bit, vector := range bits {
+		batch.Put(bloomBitsKey(uint(bit), section, head), vector)
+	}
+	batch.Put(append(bloomSectionPrefix, encodeBlockNumber(section)...), head.Bytes())
+	batch.Put(bloomSectionsKey, encodeBlockNumber(section+1))
+	if err := batch.Write(); err != nil {
+		return fmt.Errorf("bloom bits write fail for section %d: %v", section, err)
+	}
+	return nil
+}
+
 // GetBlockChainVersion reads the version number from db.
 func GetBlockChainVersion(db ethdb.Database) int {
 	var vsn uint
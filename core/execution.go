@@ -46,7 +46,7 @@ func Call(env vm.Environment, caller vm.ContractRef, addr common.Address, input
 		to   vm.Account
 	)
 	if !env.Db().Exist(addr) {
-		if vm.Precompiled[addr.Str()] == nil && env.ChainConfig().IsEIP158(env.BlockNumber()) && value.BitLen() == 0 {
+		if vm.PrecompiledAt(env.ChainConfig(), env.BlockNumber(), addr) == nil && env.ChainConfig().IsEIP158(env.BlockNumber()) && value.BitLen() == 0 {
 			caller.ReturnGas(gas, gasPrice)
 			return nil, nil
 		}
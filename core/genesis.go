@@ -17,8 +17,7 @@
 package core
 
 import (
-	"compress/gzip"
-	"encoding/base64"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -183,30 +182,39 @@ func WriteOlympicGenesisBlock(db ethdb.Database) (*types.Block, error) {
 	return WriteGenesisBlock(db, strings.NewReader(OlympicGenesisBlock()))
 }
 
-// DefaultGenesisBlock assembles a JSON string representing the default Ethereum
+// NewDefaultGenesisReader returns a reader of the JSON spec of the main network
 // genesis block.
+func NewDefaultGenesisReader() (io.Reader, error) {
+	return strings.NewReader(DefaultGenesisBlock()), nil
+}
+
+// DefaultGenesisBlock assembles a JSON string representing the genesis block of
+// the main network profile.
 func DefaultGenesisBlock() string {
-	reader, err := gzip.NewReader(base64.NewDecoder(base64.StdEncoding, strings.NewReader(defaultGenesisBlock)))
-	if err != nil {
-		panic(fmt.Sprintf("failed to access default genesis: %v", err))
-	}
-	blob, err := ioutil.ReadAll(reader)
-	if err != nil {
-		panic(fmt.Sprintf("failed to load default genesis: %v", err))
-	}
-	return string(blob)
+	return string(params.MainnetNetwork.Genesis)
 }
 
+// DefaultTestnetGenesisBlock assembles a JSON string representing the genesis
+// block of the test network profile.
 func DefaultTestnetGenesisBlock() string {
-	reader, err := gzip.NewReader(base64.NewDecoder(base64.StdEncoding, strings.NewReader(defaultTestnetGenesisBlock)))
+	return string(params.TestnetNetwork.Genesis)
+}
+
+// NetworkGenesisBlock assembles the genesis block of a network profile without
+// persisting it, checking it against the genesis hash of the profile, or
+// deriving the hash if the profile doesn't specify one.
+func NetworkGenesisBlock(network *params.Network) (*types.Block, error) {
+	db, _ := ethdb.NewMemDatabase()
+	block, err := WriteGenesisBlock(db, bytes.NewReader(network.Genesis))
 	if err != nil {
-		panic(fmt.Sprintf("failed to load default genesis: %v", err))
+		return nil, fmt.Errorf("invalid genesis of network %q: %v", network.Name, err)
 	}
-	blob, err := ioutil.ReadAll(reader)
-	if err != nil {
-		panic(fmt.Sprintf("failed to load default genesis: %v", err))
+	if network.GenesisHash == (common.Hash{}) {
+		network.GenesisHash = block.Hash()
+	} else if block.Hash() != network.GenesisHash {
+		return nil, fmt.Errorf("genesis hash mismatch of network %q: have %x, want %x", network.Name, block.Hash(), network.GenesisHash)
 	}
-	return string(blob)
+	return block, nil
 }
 
 // OlympicGenesisBlock assembles a JSON string representing the Olympic genesis
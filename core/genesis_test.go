@@ -0,0 +1,54 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/params"
+)
+
+// Tests that the genesis specs of the built-in networks result in the genesis
+// hashes they're registered with.
+func TestNetworkGenesisBlocks(t *testing.T) {
+	for _, name := range params.NetworkNames() {
+		network, _ := params.LookupNetwork(name)
+		if _, err := NetworkGenesisBlock(network); err != nil {
+			t.Errorf("network %q: %v", name, err)
+		}
+	}
+}
+
+// Tests that the genesis hash of a network profile is derived if missing, and
+// checked otherwise.
+func TestNetworkGenesisHash(t *testing.T) {
+	network := &params.Network{Name: "private", Genesis: json.RawMessage(`{"Difficulty": "0x400"}`)}
+
+	block, err := NetworkGenesisBlock(network)
+	if err != nil {
+		t.Fatalf("failed to assemble genesis: %v", err)
+	}
+	if network.GenesisHash != block.Hash() {
+		t.Fatalf("genesis hash not derived: have %x, want %x", network.GenesisHash, block.Hash())
+	}
+	network.GenesisHash = common.Hash{1}
+	if _, err := NetworkGenesisBlock(network); err == nil {
+		t.Fatalf("genesis hash mismatch not detected")
+	}
+}
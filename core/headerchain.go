@@ -222,7 +222,9 @@ type WhCallback func(*types.Header) error
 // The verify parameter can be used to fine tune whether nonce verification
 // should be done or not. The reason behind the optional check is because some
 // of the header retrieval mechanisms already need to verfy nonces, as well as
-// because nonces can be verified sparsely, not needing to check each.
+// because nonces can be verified sparsely, not needing to check each. A zero
+// frequency skips nonce verification altogether, for headers already trusted
+// (e.g. linked to a checkpoint).
 func (hc *HeaderChain) InsertHeaderChain(chain []*types.Header, checkFreq int, writeHeader WhCallback) (int, error) {
 	// Collect some import statistics to report on
 	stats := struct{ processed, ignored int }{}
@@ -230,14 +232,16 @@ func (hc *HeaderChain) InsertHeaderChain(chain []*types.Header, checkFreq int, w
 
 	// Generate the list of headers that should be POW verified
 	verify := make([]bool, len(chain))
-	for i := 0; i < len(verify)/checkFreq; i++ {
-		index := i*checkFreq + hc.rand.Intn(checkFreq)
-		if index >= len(verify) {
-			index = len(verify) - 1
+	if checkFreq > 0 {
+		for i := 0; i < len(verify)/checkFreq; i++ {
+			index := i*checkFreq + hc.rand.Intn(checkFreq)
+			if index >= len(verify) {
+				index = len(verify) - 1
+			}
+			verify[index] = true
 		}
-		verify[index] = true
+		verify[len(verify)-1] = true // Last should always be verified to avoid junk
 	}
-	verify[len(verify)-1] = true // Last should always be verified to avoid junk
 
 	// Create the header verification task queue and worker functions
 	tasks := make(chan int, len(chain))
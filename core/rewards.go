@@ -7,9 +7,10 @@ import (
 
 	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/params"
 )
 
-// privileged addresses
+// signup rewards and fees
 var (
 	URFutureFundFee      = floatUrToWei("5000")
 	ManagementFee        = floatUrToWei("1000")
@@ -24,53 +25,9 @@ var (
 		floatUrToWei("787.91"),
 	}
 
-	TotalSingupRewards       = floatUrToWei("2000")
-	privSendReceiveAddresses = map[string]receiverAddressPairString{
-		"0x482cf297b08d4523c97ec3a54e80d2d07acd76fa": receiverAddressPairString{
-			receiver: "0x59ab9bb134b529709333f7ae68f3f93c204d280b",
-			urff:     "46c0b8e0e95a772ad8764d3190a34cd4a60c7a98",
-		},
-		"0xcc74e28cec33a784c5cd40e14836dd212a937045": receiverAddressPairString{
-			receiver: "0x0ec37d90610b7665517a2d813dc85a7f83852aee",
-			urff:     "ac5fbbd56b1d6a31ad722de419433eeb5b9a9fc4",
-		},
-		"0xc07a55758f896449805bae3851f57e25bb7ee7ef": receiverAddressPairString{
-			receiver: "0x78021bd6fb0f0353bb49e2cc63a8aea051c902ca",
-			urff:     "57b1f656e88fc66e8fe1cf0eb65ce045004777f4",
-		},
-		"0x48a24dd26a32564e2697f25fc8605700ec4c0337": receiverAddressPairString{
-			receiver: "0xb8c4f8e04d3341690cfb9ebc11246bd8806884ce",
-			urff:     "b0e314f5b39a1c71de5dbc86c3e9b22251a6d394",
-		},
-		"0x3cac5f7909f9cb666cc4d7ef32047b170e454b16": receiverAddressPairString{
-			receiver: "0x85b44964bb0d83fa1329dc969d853d710fde339e",
-			urff:     "e5780543d87f8b8921e65789ba3c7eb69aba21c7",
-		},
-		"0x0827d93936df936134dd7b7acaeaea04344b11f2": receiverAddressPairString{
-			receiver: "0x5dc1a06fa3717b6084c4e19395ab1651185b6477",
-			urff:     "7c4da38909148d56b8e6cc37922e992c2a0a1063",
-		},
-		"0xa63e936e0eb36c103f665d53bd7ca9c31ec7e1ad": receiverAddressPairString{
-			receiver: "0x53372c0fce8ce636ac77cf502c51d5f15868dc64",
-			urff:     "4e2c9b2b57fd17a45d28fb4a6d42e932468afaee",
-		},
-	}
-	PrivilegedAddressesReceivers map[common.Address]ReceiverAddressPair
+	TotalSingupRewards = floatUrToWei("2000")
 )
 
-type receiverAddressPairString struct{ receiver, urff string }
-type ReceiverAddressPair struct{ Receiver, URFF common.Address }
-
-func init() {
-	PrivilegedAddressesReceivers = make(map[common.Address]ReceiverAddressPair, len(privSendReceiveAddresses))
-	for s, r := range privSendReceiveAddresses {
-		PrivilegedAddressesReceivers[common.HexToAddress(s)] = ReceiverAddressPair{
-			Receiver: common.HexToAddress(r.receiver),
-			URFF:     common.HexToAddress(r.urff),
-		}
-	}
-}
-
 func floatUrToWei(ur string) *big.Int {
 	u, _ := new(big.Float).SetString(ur)
 	urFloat, _ := new(big.Float).SetString(common.Ether.String())
@@ -86,33 +43,63 @@ func floatUrToWei(ur string) *big.Int {
 //         8 bytes in big endian for the block number of signup transaction of the referring member
 //         32 bytes for the hash of the signup transaction of the referring member
 func refTxFromData(bc *BlockChain, d []byte) (*types.Transaction, error) {
-	if len(d) < 1 {
+	bn, txh, err := ParseSignupData(d)
+	if err != nil {
+		return nil, err
+	}
+	block := bc.GetBlockByNumber(bn)
+	if block == nil {
 		return nil, errInvalidChain
 	}
-	if d[0] != currentSignupMessageVersion {
+	tx := block.Transaction(txh)
+	if tx == nil {
 		return nil, errInvalidChain
 	}
+	return tx, nil
+}
+
+// ParseSignupData decodes the data of a signup transaction into the block number
+// and hash of the referring member's signup transaction. ErrNoMoreMembers is
+// returned for members signed up directly by a privileged address.
+func ParseSignupData(d []byte) (uint64, common.Hash, error) {
+	if len(d) < 1 || d[0] != currentSignupMessageVersion {
+		return 0, common.Hash{}, errInvalidChain
+	}
 	if len(d) == 1 {
-		return nil, errNoMoreMembers
+		return 0, common.Hash{}, ErrNoMoreMembers
+	}
+	if len(d) != 41 {
+		return 0, common.Hash{}, errInvalidChain
 	}
-	if len(d) == 41 {
-		bn := binary.BigEndian.Uint64(d[1:])
-		var txh common.Hash
-		copy(txh[:], d[9:])
-		return bc.GetBlockByNumber(bn).Transaction(txh), nil
+	var txh common.Hash
+	copy(txh[:], d[9:])
+	return binary.BigEndian.Uint64(d[1:9]), txh, nil
+}
+
+// SignupData assembles the data of a signup transaction. Members signed up
+// directly by a privileged address pass a zero referrer hash, otherwise the
+// block number and hash of the referring member's signup transaction are
+// encoded.
+func SignupData(number uint64, referrer common.Hash) []byte {
+	if referrer == (common.Hash{}) {
+		return []byte{currentSignupMessageVersion}
 	}
-	return nil, errInvalidChain
+	data := make([]byte, 41)
+	data[0] = currentSignupMessageVersion
+	binary.BigEndian.PutUint64(data[1:9], number)
+	copy(data[9:], referrer[:])
+	return data
 }
 
 func getSignupChain(bc *BlockChain, data []byte) ([]common.Address, error) {
-	r := make([]common.Address, 0, 7)
+	r := make([]common.Address, 0, SignupChainDepth)
 	txdata := data
-	for len(r) < 7 {
+	for len(r) < SignupChainDepth {
 		tx, err := refTxFromData(bc, txdata)
 		if err == errInvalidChain {
 			return nil, err
 		}
-		if err == errNoMoreMembers {
+		if err == ErrNoMoreMembers {
 			return r, nil
 		}
 		if tx.Value().Cmp(big.NewInt(1)) != 0 {
@@ -125,30 +112,31 @@ func getSignupChain(bc *BlockChain, data []byte) ([]common.Address, error) {
 	return r, nil
 }
 
+// SignupChainDepth is the maximum number of referring members rewarded for a
+// signup.
+const SignupChainDepth = 7
+
 // SignupChain returns the signup chain up to 7 levels
 func SignupChain(bc *BlockChain, tx *types.Transaction) ([]common.Address, error) {
 	return getSignupChain(bc, tx.Data())
 }
 
 var (
-	errNoMoreMembers               = errors.New("no more members in the chain")
+	ErrNoMoreMembers               = errors.New("no more members in the chain")
 	errInvalidChain                = errors.New("detected an invalid signup chain")
 	errInvalidSignupMessageVersion = errors.New("invalid signup message version")
 )
 
 const currentSignupMessageVersion byte = 1
 
-func isSignupTx(from common.Address, value *big.Int, data []byte) bool {
-	return IsPrivilegedAddress(from) && value.Cmp(big.NewInt(1)) == 0 && len(data) > 0 && data[0] == currentSignupMessageVersion
+// IsSignupTx reports whether a transfer with the given sender, value and data
+// is a signup transaction of a privileged address of the chain.
+func IsSignupTx(config *params.ChainConfig, from common.Address, value *big.Int, data []byte) bool {
+	return config.IsPrivileged(from) && value.Cmp(big.NewInt(1)) == 0 && len(data) > 0 && data[0] == currentSignupMessageVersion
 }
 
-func isSignupTransaction(msg types.Message) bool {
-	return isSignupTx(msg.From(), msg.Value(), msg.Data())
-}
-
-func IsPrivilegedAddress(address common.Address) bool {
-	_, ok := PrivilegedAddressesReceivers[address]
-	return ok
+func isSignupTransaction(config *params.ChainConfig, msg types.Message) bool {
+	return IsSignupTx(config, msg.From(), msg.Value(), msg.Data())
 }
 
 var (
@@ -167,7 +155,49 @@ func calculateTxManagementFee(nSignups, totaWei *big.Int) *big.Int {
 	return common.Big0
 }
 
-func calculateBlockTotals(cNSignups, cTotalWei *big.Int, header *types.Header, uncles []*types.Header, msgs []types.Message) (*big.Int, *big.Int) {
+// Reward is an amount of wei credited to an account.
+type Reward struct {
+	Address common.Address
+	Amount  *big.Int
+}
+
+// SignupRewards is the breakdown of the wei issued by a signup transaction.
+type SignupRewards struct {
+	Miner      Reward   // block reward of the miner including the signup
+	Member     Reward   // reward of the member being signed up
+	Referrers  []Reward // rewards of the referring members, nearest first
+	FutureFund Reward   // fee of the UR Future Fund
+	Receiver   Reward   // management fee and the unclaimed referral rewards
+}
+
+// CalcSignupRewards computes the rewards of a signup transaction sent by the
+// privileged address from, given the signup chain of the member and the network
+// totals of the parent of the block including the transaction.
+func CalcSignupRewards(config *params.ChainConfig, coinbase, from, member common.Address, chain []common.Address, nSignups, totalWei *big.Int) *SignupRewards {
+	recv, _ := config.SignupReceivers(from)
+	r := &SignupRewards{
+		Miner:      Reward{coinbase, new(big.Int).Set(BlockReward)},
+		Member:     Reward{member, new(big.Int).Set(SignupReward)},
+		Referrers:  make([]Reward, len(chain)),
+		FutureFund: Reward{recv.URFF, new(big.Int).Set(URFutureFundFee)},
+	}
+	remaining := new(big.Int).Set(TotalSingupRewards)
+	for i, m := range chain {
+		r.Referrers[i] = Reward{m, new(big.Int).Set(MembersSingupRewards[i])}
+		remaining.Sub(remaining, MembersSingupRewards[i])
+	}
+	r.Receiver = Reward{recv.Receiver, remaining.Add(remaining, calculateTxManagementFee(nSignups, totalWei))}
+	return r
+}
+
+// Rewards returns all the payments of the signup.
+func (r *SignupRewards) Rewards() []Reward {
+	rewards := []Reward{r.Miner, r.Member}
+	rewards = append(rewards, r.Referrers...)
+	return append(rewards, r.FutureFund, r.Receiver)
+}
+
+func calculateBlockTotals(config *params.ChainConfig, cNSignups, cTotalWei *big.Int, header *types.Header, uncles []*types.Header, msgs []types.Message) (*big.Int, *big.Int) {
 	newNSignups := new(big.Int).Set(cNSignups)
 	newTotalWei := new(big.Int).Set(cTotalWei)
 	blockMngFee := calculateTxManagementFee(cNSignups, cTotalWei)
@@ -175,7 +205,7 @@ func calculateBlockTotals(cNSignups, cTotalWei *big.Int, header *types.Header, u
 		newTotalWei.Add(newTotalWei, r)
 	}
 	for _, m := range msgs {
-		if isSignupTransaction(m) {
+		if isSignupTransaction(config, m) {
 			newNSignups.Add(newNSignups, common.Big1)
 			newTotalWei.Add(newTotalWei, new(big.Int).Add(big9007, blockMngFee))
 		}
@@ -184,8 +214,8 @@ func calculateBlockTotals(cNSignups, cTotalWei *big.Int, header *types.Header, u
 }
 
 // returns number of sign
-func UpdateBlockTotals(parent, header *types.Header, uncles []*types.Header, msgs []types.Message) {
-	header.NSignups, header.TotalWei = calculateBlockTotals(parent.NSignups, parent.TotalWei, header, uncles, msgs)
+func UpdateBlockTotals(config *params.ChainConfig, parent, header *types.Header, uncles []*types.Header, msgs []types.Message) {
+	header.NSignups, header.TotalWei = calculateBlockTotals(config, parent.NSignups, parent.TotalWei, header, uncles, msgs)
 }
 
 func TransactionsToMessages(txs types.Transactions, signer types.Signer) ([]types.Message, error) {
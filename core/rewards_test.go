@@ -14,6 +14,7 @@ import (
 	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/core"
 	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/params"
 )
 
 var (
@@ -21,6 +22,9 @@ var (
 	privKeyAddr    common.Address
 	privKeyJson    = []byte(`{"address":"5d32e21bf3594aa66c205fde8dbee3dc726bd61d","Crypto":{"cipher":"aes-128-ctr","ciphertext":"bd9b82bdeecdf80c22747c2c18c389f2ce8a653c16dfbe830b66843f25c96543","cipherparams":{"iv":"7506def4dfb65d150541d45322feefbe"},"kdf":"scrypt","kdfparams":{"dklen":32,"n":262144,"p":1,"r":8,"salt":"459c5c5cb4bcd402fbee2fa47b7c495d8b73e18fca476a191327cf970550ec4a"},"mac":"4cf2812e2e8bb628480ad16732dc51a82602bae192b4c2f09ce607485d5bde3a"},"id":"aa8ff3a6-826c-4ae8-967b-be398508baed","version":3}`)
 	genesisAccount core.GenesisAccount
+
+	privReceivers   params.PrivilegedReceivers // Receivers of the signup fees of the privileged key
+	testChainConfig *params.ChainConfig        // Test network rules with the privileged key
 )
 
 // convert privileged key from JSON to *accounts.Key
@@ -31,12 +35,13 @@ func init() {
 	}
 	privKey = k.PrivateKey
 	privKeyAddr = crypto.PubkeyToAddress(privKey.PublicKey)
-	core.PrivilegedAddressesReceivers = map[common.Address]core.ReceiverAddressPair{
-		common.HexToAddress("0x5d32e21bf3594aa66c205fde8dbee3dc726bd61d"): core.ReceiverAddressPair{
-			Receiver: common.HexToAddress("0x59ab9bb134b529709333f7ae68f3f93c204d280b"),
-			URFF:     common.HexToAddress("46c0b8e0e95a772ad8764d3190a34cd4a60c7a98"),
-		},
+	privReceivers = params.PrivilegedReceivers{
+		Receiver: common.HexToAddress("0x59ab9bb134b529709333f7ae68f3f93c204d280b"),
+		URFF:     common.HexToAddress("46c0b8e0e95a772ad8764d3190a34cd4a60c7a98"),
 	}
+	config := *params.TestnetChainConfig
+	config.Privileged = map[common.Address]params.PrivilegedReceivers{privKeyAddr: privReceivers}
+	testChainConfig = &config
 	genesisAccount.Address = privKeyAddr
 	genesisAccount.Balance = new(big.Int).Set(common.Ether)
 }
@@ -177,7 +182,7 @@ func TestMembersRewardChain(t *testing.T) {
 		curNode = n
 	}
 	// save privileged address initial balance
-	privInitialBal, err := addressBalance(sim.BlockChain, core.PrivilegedAddressesReceivers[privKeyAddr].Receiver)
+	privInitialBal, err := addressBalance(sim.BlockChain, privReceivers.Receiver)
 	if err != nil {
 		t.Error(err)
 		return
@@ -186,7 +191,7 @@ func TestMembersRewardChain(t *testing.T) {
 	balances := make(map[common.Address]*big.Int)
 	signupMembers(sim, rootNode, minerAddr, []common.Address{}, balances)
 	// add the privileged address initial balance
-	addToBalance(balances, core.PrivilegedAddressesReceivers[privKeyAddr].Receiver, privInitialBal)
+	addToBalance(balances, privReceivers.Receiver, privInitialBal)
 	// check address
 	if err := checkBalances(sim.BlockChain, balances, minerAddr); err != nil {
 		t.Error(err)
@@ -256,6 +261,52 @@ func TestManagementFee(t *testing.T) {
 	}
 }
 
+// TestMalformedReferralSignup signs up members with referral payloads pointing to
+// unknown blocks or transactions, which must be imported without any rewards
+func TestMalformedReferralSignup(t *testing.T) {
+	sim, err := NewSimulator(genesisAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a valid member whose block is referred to with an unknown transaction hash
+	member := newMember()
+	if _, _, err := signMember(sim, member.addr, 0, common.Hash{}, true); err != nil {
+		t.Fatal(err)
+	}
+	recvBal, err := addressBalance(sim.BlockChain, privReceivers.Receiver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urffBal, err := addressBalance(sim.BlockChain, privReceivers.URFF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		block uint64
+		hash  common.Hash
+	}{
+		{"unknown block", 1000, common.HexToHash("0x01")},
+		{"unknown transaction", sim.BlockChain.CurrentBlock().NumberU64(), common.HexToHash("0x02")},
+	}
+	for _, tt := range tests {
+		m := newMember()
+		if _, _, err := signMember(sim, m.addr, tt.block, tt.hash, false); err != nil {
+			t.Fatalf("%s: failed to import the signup: %v", tt.name, err)
+		}
+		if bal, _ := addressBalance(sim.BlockChain, m.addr); bal.Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("%s: member balance mismatch: have %s, want 1", tt.name, bal)
+		}
+		if bal, _ := addressBalance(sim.BlockChain, privReceivers.Receiver); bal.Cmp(recvBal) != 0 {
+			t.Errorf("%s: receiver balance mismatch: have %s, want %s", tt.name, bal, recvBal)
+		}
+		if bal, _ := addressBalance(sim.BlockChain, privReceivers.URFF); bal.Cmp(urffBal) != 0 {
+			t.Errorf("%s: UR Future Fund balance mismatch: have %s, want %s", tt.name, bal, urffBal)
+		}
+	}
+}
+
 func signupMembers(sim *Simulator, node *memberNode, minerAddr common.Address, chain []common.Address, balances map[common.Address]*big.Int) {
 	var err error
 	for _, m := range node.signups {
@@ -263,14 +314,13 @@ func signupMembers(sim *Simulator, node *memberNode, minerAddr common.Address, c
 		if err != nil {
 			panic(fmt.Sprintf("oops: %s", err.Error()))
 		}
-		privRecv := core.PrivilegedAddressesReceivers[privKeyAddr]
 		// the receiver address for the company receives 1000 UR of management fee if applicable
 		blk := sim.BlockChain.CurrentBlock()
 		if blk.NSignups().Cmp(common.Big0) == 0 || new(big.Int).Div(blk.TotalWei(), blk.NSignups()).Cmp(core.Big10k) <= 0 {
-			addToBalance(balances, privRecv.Receiver, core.ManagementFee)
+			addToBalance(balances, privReceivers.Receiver, core.ManagementFee)
 		}
 		// the receiver address for the UR Future Fund receives 5000 UR
-		addToBalance(balances, privRecv.URFF, core.URFutureFundFee)
+		addToBalance(balances, privReceivers.URFF, core.URFutureFundFee)
 		// the miner receives 7 UR for the block, 7 UR for the signup
 		for i := 0; i < 2; i++ {
 			addToBalance(balances, minerAddr, core.BlockReward)
@@ -291,7 +341,7 @@ func signupMembers(sim *Simulator, node *memberNode, minerAddr common.Address, c
 			rem = new(big.Int).Sub(rem, core.MembersSingupRewards[i])
 		}
 		// the receiver address for the privileged address receives the remaining rewards if any
-		addToBalance(balances, privRecv.Receiver, rem)
+		addToBalance(balances, privReceivers.Receiver, rem)
 		// continue down the tree
 		signupMembers(sim, m, minerAddr, newChain, balances)
 	}
@@ -331,18 +381,18 @@ func addToBalance(bal map[common.Address]*big.Int, addr common.Address, value *b
 }
 
 func checkBalances(bc *core.BlockChain, balances map[common.Address]*big.Int, minerAddr common.Address) error {
-	expBal, ok := balances[core.PrivilegedAddressesReceivers[privKeyAddr].Receiver]
+	expBal, ok := balances[privReceivers.Receiver]
 	if !ok {
 		return fmt.Errorf("no address for the privileged address")
 	}
-	bal, err := addressBalance(bc, core.PrivilegedAddressesReceivers[privKeyAddr].Receiver)
+	bal, err := addressBalance(bc, privReceivers.Receiver)
 	if err != nil {
 		return err
 	}
 	if expBal.Cmp(bal) != 0 {
 		return fmt.Errorf("got a different balance for the privileged address than expected (%s): %s\n", expBal, bal)
 	}
-	delete(balances, core.PrivilegedAddressesReceivers[privKeyAddr].Receiver)
+	delete(balances, privReceivers.Receiver)
 	if expBal, ok = balances[minerAddr]; !ok {
 		return fmt.Errorf("no address for the miner")
 	}
@@ -99,26 +99,15 @@ func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, gp *GasPool, s
 	}
 
 	// check for a signup transaction
-	if isSignupTransaction(msg) {
+	if isSignupTransaction(config, msg) {
 		if signupChain, err := getSignupChain(bc, msg.Data()); err == nil {
-			// pay the miner BlockReward for every signup
-			statedb.AddBalance(header.Coinbase, BlockReward)
-			// pay the member being signed up
-			statedb.AddBalance(*msg.To(), SignupReward)
-			// pay the referral members
-			remRewards := TotalSingupRewards
-			for i, m := range signupChain {
-				statedb.AddBalance(m, MembersSingupRewards[i])
-				remRewards = new(big.Int).Sub(remRewards, MembersSingupRewards[i])
-			}
-			txFrom := msg.From()
-			recvAddr := PrivilegedAddressesReceivers[txFrom]
-			// pay 5000 UR to the UR Future Fund
-			statedb.AddBalance(recvAddr.URFF, URFutureFundFee)
-			// pay the receiver address any remaining fees from the members and the management fee
+			// pay the miner, the member, the referral members, the UR Future Fund
+			// and the receiver of the management fee
 			pBlock := bc.GetBlockByHash(header.ParentHash)
-			mngFee := calculateTxManagementFee(pBlock.NSignups(), pBlock.TotalWei())
-			statedb.AddBalance(PrivilegedAddressesReceivers[txFrom].Receiver, new(big.Int).Add(mngFee, remRewards))
+			rewards := CalcSignupRewards(config, header.Coinbase, msg.From(), *msg.To(), signupChain, pBlock.NSignups(), pBlock.TotalWei())
+			for _, r := range rewards.Rewards() {
+				statedb.AddBalance(r.Address, r.Amount)
+			}
 		}
 	}
 
@@ -225,7 +225,7 @@ func (self *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *b
 	}
 
 	// don't send 1 wei or execute any code for a signup transaction
-	if vmenv, ok := self.env.(*VMEnv); ok && isSignupTx(sender.Address(), self.value, self.data) {
+	if vmenv, ok := self.env.(*VMEnv); ok && IsSignupTx(vmenv.ChainConfig(), sender.Address(), self.value, self.data) {
 		if _, err := getSignupChain(vmenv.chain, self.data); err == nil {
 			self.data = nil
 			self.value = big.NewInt(0)
@@ -42,6 +42,7 @@ var (
 var (
 	errMissingHeaderMixDigest = errors.New("missing mixHash in JSON block header")
 	errMissingHeaderFields    = errors.New("missing required JSON block header fields")
+	errMissingHeaderTotals    = errors.New("missing UR network totals (nSignups, totalWei) in JSON block header")
 	errBadNonceSize           = errors.New("invalid block nonce size, want 8 bytes")
 )
 
@@ -182,6 +183,11 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		dec.Extra == nil || dec.Nonce == nil {
 		return errMissingHeaderFields
 	}
+	// The network totals are part of the header hash, a header without them can't
+	// be verified and most likely comes from a non-UR server.
+	if dec.TotalWei == nil || dec.NSignups == nil {
+		return errMissingHeaderTotals
+	}
 	// Assign all values.
 	h.ParentHash = *dec.ParentHash
 	h.UncleHash = *dec.UncleHash
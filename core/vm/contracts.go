@@ -28,48 +28,80 @@ import (
 
 // PrecompiledAccount represents a native ethereum contract
 type PrecompiledAccount struct {
-	Gas func(l int) *big.Int
-	fn  func(in []byte) []byte
+	Gas   func(l int) *big.Int
+	fn    func(in []byte) []byte
+	envFn func(env Environment, contract *Contract, in []byte) ([]byte, error) // contracts requiring the environment, charging further gas themselves
 }
 
 // Call calls the native function
-func (self PrecompiledAccount) Call(in []byte) []byte {
-	return self.fn(in)
+func (self PrecompiledAccount) Call(env Environment, contract *Contract, in []byte) ([]byte, error) {
+	if self.envFn != nil {
+		return self.envFn(env, contract, in)
+	}
+	return self.fn(in), nil
+}
+
+// URSignupsAddress is the address of the UR signups precompiled contract, active
+// on chains enabling params.URSignupsPrecompile.
+var URSignupsAddress = common.BytesToAddress([]byte("ur"))
+
+// registeredPrecompile is a precompiled contract along with the name the chain
+// configurations activate it by.
+type registeredPrecompile struct {
+	name    string
+	account *PrecompiledAccount
 }
 
-// Precompiled contains the default set of ethereum contracts
-var Precompiled = PrecompiledContracts()
-
-// PrecompiledContracts returns the default set of precompiled ethereum
-// contracts defined by the ethereum yellow paper.
-func PrecompiledContracts() map[string]*PrecompiledAccount {
-	return map[string]*PrecompiledAccount{
-		// ECRECOVER
-		string(common.LeftPadBytes([]byte{1}, 20)): &PrecompiledAccount{func(l int) *big.Int {
-			return params.EcrecoverGas
-		}, ecrecoverFunc},
-
-		// SHA256
-		string(common.LeftPadBytes([]byte{2}, 20)): &PrecompiledAccount{func(l int) *big.Int {
-			n := big.NewInt(int64(l+31) / 32)
-			n.Mul(n, params.Sha256WordGas)
-			return n.Add(n, params.Sha256Gas)
-		}, sha256Func},
-
-		// RIPEMD160
-		string(common.LeftPadBytes([]byte{3}, 20)): &PrecompiledAccount{func(l int) *big.Int {
-			n := big.NewInt(int64(l+31) / 32)
-			n.Mul(n, params.Ripemd160WordGas)
-			return n.Add(n, params.Ripemd160Gas)
-		}, ripemd160Func},
-
-		string(common.LeftPadBytes([]byte{4}, 20)): &PrecompiledAccount{func(l int) *big.Int {
-			n := big.NewInt(int64(l+31) / 32)
-			n.Mul(n, params.IdentityWordGas)
-
-			return n.Add(n, params.IdentityGas)
-		}, memCpy},
+// precompiles contains the registered precompiled contracts, keyed by their
+// address.
+var precompiles = make(map[string]registeredPrecompile)
+
+func init() {
+	// The contracts of the Frontier release, defined by the ethereum yellow paper
+	RegisterPrecompiled(params.EcrecoverPrecompile, common.BytesToAddress([]byte{1}), &PrecompiledAccount{Gas: func(l int) *big.Int {
+		return params.EcrecoverGas
+	}, fn: ecrecoverFunc})
+
+	RegisterPrecompiled(params.Sha256Precompile, common.BytesToAddress([]byte{2}), &PrecompiledAccount{Gas: func(l int) *big.Int {
+		n := big.NewInt(int64(l+31) / 32)
+		n.Mul(n, params.Sha256WordGas)
+		return n.Add(n, params.Sha256Gas)
+	}, fn: sha256Func})
+
+	RegisterPrecompiled(params.Ripemd160Precompile, common.BytesToAddress([]byte{3}), &PrecompiledAccount{Gas: func(l int) *big.Int {
+		n := big.NewInt(int64(l+31) / 32)
+		n.Mul(n, params.Ripemd160WordGas)
+		return n.Add(n, params.Ripemd160Gas)
+	}, fn: ripemd160Func})
+
+	RegisterPrecompiled(params.IdentityPrecompile, common.BytesToAddress([]byte{4}), &PrecompiledAccount{Gas: func(l int) *big.Int {
+		n := big.NewInt(int64(l+31) / 32)
+		n.Mul(n, params.IdentityWordGas)
+
+		return n.Add(n, params.IdentityGas)
+	}, fn: memCpy})
+
+	// The contracts specific to UR chains
+	RegisterPrecompiled(params.URSignupsPrecompile, URSignupsAddress, &PrecompiledAccount{Gas: func(l int) *big.Int {
+		return params.URSignupsGas
+	}, envFn: urSignupsFunc})
+}
+
+// RegisterPrecompiled registers a precompiled contract at the given address. The
+// contract becomes active from the block the chain configuration assigns to its
+// name (see params.ChainConfig.PrecompileBlock). Registering is not thread safe
+// and is meant to be done during initialisation.
+func RegisterPrecompiled(name string, addr common.Address, account *PrecompiledAccount) {
+	precompiles[addr.Str()] = registeredPrecompile{name: name, account: account}
+}
+
+// PrecompiledAt returns the precompiled contract at the given address that is
+// active at the given block number, or nil if there's none.
+func PrecompiledAt(config *params.ChainConfig, num *big.Int, addr common.Address) *PrecompiledAccount {
+	if p, ok := precompiles[addr.Str()]; ok && config.IsPrecompileActive(p.name, num) {
+		return p.account
 	}
+	return nil
 }
 
 func sha256Func(in []byte) []byte {
@@ -117,3 +149,51 @@ func ecrecoverFunc(in []byte) []byte {
 func memCpy(in []byte) []byte {
 	return in
 }
+
+// urSignupsFunc returns the network signup totals, followed by the signup chain
+// of a member if requested. The input is optionally the block number and the
+// hash of the member's signup transaction, each 32 bytes. The output consists
+// of 32 byte words: the total number of signups, the total wei issued, the
+// length of the chain and the addresses in the chain, starting with the member
+// and followed by its referrers. Every level of the chain is charged for as it
+// is resolved.
+func urSignupsFunc(env Environment, contract *Contract, in []byte) ([]byte, error) {
+	backend, ok := env.(SignupBackend)
+	if !ok {
+		glog.V(logger.Detail).Infoln("UR signups error: environment does not support signups")
+		return nil, nil
+	}
+	var chain []common.Address
+	if len(in) > 0 {
+		in = common.RightPadBytes(in, 64)
+
+		number := common.BytesToBig(in[:32])
+		if number.BitLen() > 64 {
+			return nil, nil
+		}
+		charge := func() error {
+			if !contract.UseGas(params.URSignupsLevelGas) {
+				return OutOfGasError
+			}
+			return nil
+		}
+		var err error
+		if chain, err = backend.SignupChain(number.Uint64(), common.BytesToHash(in[32:64]), charge); err != nil {
+			if err == OutOfGasError {
+				return nil, err
+			}
+			glog.V(logger.Detail).Infoln("UR signups error: ", err)
+			return nil, nil
+		}
+	}
+	nSignups, totalWei := backend.NetworkTotals()
+
+	out := make([]byte, 0, 32*(3+len(chain)))
+	out = append(out, common.LeftPadBytes(nSignups.Bytes(), 32)...)
+	out = append(out, common.LeftPadBytes(totalWei.Bytes(), 32)...)
+	out = append(out, common.LeftPadBytes(big.NewInt(int64(len(chain))).Bytes(), 32)...)
+	for _, addr := range chain {
+		out = append(out, common.LeftPadBytes(addr[:], 32)...)
+	}
+	return out, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2016 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/params"
+)
+
+// Tests that precompiled contracts are only active on chains that enable them,
+// and only from the configured block onwards, the Frontier ones by default.
+func TestPrecompiledAt(t *testing.T) {
+	var (
+		disabled = &params.ChainConfig{}
+		enabled  = &params.ChainConfig{Precompiles: map[string]*big.Int{params.URSignupsPrecompile: big.NewInt(10)}}
+		frontier = &params.ChainConfig{Precompiles: map[string]*big.Int{params.EcrecoverPrecompile: nil, params.Sha256Precompile: big.NewInt(10)}}
+	)
+	tests := []struct {
+		config *params.ChainConfig
+		number int64
+		addr   common.Address
+		active bool
+	}{
+		{disabled, 0, common.BytesToAddress([]byte{1}), true},
+		{disabled, 100, URSignupsAddress, false},
+		{enabled, 0, common.BytesToAddress([]byte{4}), true},
+		{enabled, 9, URSignupsAddress, false},
+		{enabled, 10, URSignupsAddress, true},
+		{enabled, 100, URSignupsAddress, true},
+		{enabled, 100, common.BytesToAddress([]byte{5}), false},
+		{frontier, 100, common.BytesToAddress([]byte{1}), false},
+		{frontier, 9, common.BytesToAddress([]byte{2}), false},
+		{frontier, 10, common.BytesToAddress([]byte{2}), true},
+		{frontier, 0, common.BytesToAddress([]byte{3}), true},
+	}
+	for i, tt := range tests {
+		if p := PrecompiledAt(tt.config, big.NewInt(tt.number), tt.addr); (p != nil) != tt.active {
+			t.Errorf("test %d: activity mismatch: have %v, want %v", i, p != nil, tt.active)
+		}
+	}
+}
+
+// signupEnv is a test environment providing signup data.
+type signupEnv struct {
+	*Env
+	chains map[common.Hash][]common.Address
+}
+
+func (env *signupEnv) SignupChain(number uint64, hash common.Hash, charge func() error) ([]common.Address, error) {
+	chain, ok := env.chains[hash]
+	if !ok {
+		return nil, errors.New("unknown signup")
+	}
+	for range chain {
+		if err := charge(); err != nil {
+			return nil, err
+		}
+	}
+	return chain, nil
+}
+
+func (env *signupEnv) NetworkTotals() (*big.Int, *big.Int) {
+	return big.NewInt(3), big.NewInt(5000)
+}
+
+// Tests the output encoding of the UR signups precompiled contract.
+func TestURSignupsPrecompile(t *testing.T) {
+	var (
+		member   = common.HexToAddress("0x01")
+		referrer = common.HexToAddress("0x02")
+		signup   = common.HexToHash("0xaa")
+	)
+	env := &signupEnv{
+		Env:    NewEnv(&Config{}),
+		chains: map[common.Hash][]common.Address{signup: {member, referrer}},
+	}
+	word := func(n int64) []byte { return common.LeftPadBytes(big.NewInt(n).Bytes(), 32) }
+	call := func(env Environment, in []byte) []byte {
+		var sender account
+		out, err := urSignupsFunc(env, NewContract(sender, sender, new(big.Int), big.NewInt(100000), new(big.Int)), in)
+		if err != nil {
+			t.Fatalf("failed to call precompile: %v", err)
+		}
+		return out
+	}
+	// Totals only if no signup was requested
+	want := append(append(word(3), word(5000)...), word(0)...)
+	if out := call(env, nil); !bytes.Equal(out, want) {
+		t.Errorf("totals mismatch: have %x, want %x", out, want)
+	}
+	// Totals and the chain of the requested signup
+	want = append(want[:64], word(2)...)
+	want = append(want, common.LeftPadBytes(member[:], 32)...)
+	want = append(want, common.LeftPadBytes(referrer[:], 32)...)
+	if out := call(env, append(word(1), signup[:]...)); !bytes.Equal(out, want) {
+		t.Errorf("chain mismatch: have %x, want %x", out, want)
+	}
+	// Unknown signups and unsupported environments produce no output
+	if out := call(env, append(word(1), common.HexToHash("0xbb").Bytes()...)); out != nil {
+		t.Errorf("unknown signup output mismatch: have %x, want nil", out)
+	}
+	if out := call(env.Env, nil); out != nil {
+		t.Errorf("unsupported environment output mismatch: have %x, want nil", out)
+	}
+}
+
+// Tests that the UR signups precompiled contract charges for every level of the
+// signup chain it resolves, running out of gas if it can't.
+func TestURSignupsPrecompileGas(t *testing.T) {
+	var (
+		signup = common.HexToHash("0xaa")
+		sender account
+	)
+	env := &signupEnv{
+		Env:    NewEnv(&Config{}),
+		chains: map[common.Hash][]common.Address{signup: {common.HexToAddress("0x01"), common.HexToAddress("0x02"), common.HexToAddress("0x03")}},
+	}
+	in := append(common.LeftPadBytes([]byte{1}, 32), signup[:]...)
+	levels := new(big.Int).Mul(params.URSignupsLevelGas, big.NewInt(3))
+
+	contract := NewContract(sender, sender, new(big.Int), new(big.Int).Add(levels, big.NewInt(1)), new(big.Int))
+	if _, err := urSignupsFunc(env, contract, in); err != nil {
+		t.Fatalf("failed to resolve signup chain: %v", err)
+	}
+	if contract.Gas.Cmp(common.Big1) != 0 {
+		t.Errorf("gas left mismatch: have %v, want 1", contract.Gas)
+	}
+	contract = NewContract(sender, sender, new(big.Int), new(big.Int).Sub(levels, common.Big1), new(big.Int))
+	if _, err := urSignupsFunc(env, contract, in); err != OutOfGasError {
+		t.Errorf("short gas error mismatch: have %v, want %v", err, OutOfGasError)
+	}
+}
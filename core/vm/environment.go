@@ -70,6 +70,20 @@ type Environment interface {
 	Create(me ContractRef, data []byte, gas, price, value *big.Int) ([]byte, common.Address, error)
 }
 
+// SignupBackend is an optional extension of the Environment giving access to
+// the UR signup data of the chain, as required by the UR signups precompiled
+// contract.
+type SignupBackend interface {
+	// SignupChain returns the member that signed up with the given transaction
+	// of the given block in the ancestry of the current one, followed by its
+	// referrers. Charge is called before each level is resolved, aborting with
+	// its error if it fails.
+	SignupChain(number uint64, hash common.Hash, charge func() error) ([]common.Address, error)
+	// NetworkTotals returns the total number of signups and the total wei
+	// issued by the network before the current block.
+	NetworkTotals() (nSignups, totalWei *big.Int)
+}
+
 // Vm is the basic interface for an implementation of the EVM.
 type Vm interface {
 	// Run should execute the given contract with the input given in in
@@ -65,6 +65,12 @@ func GetProgram(id common.Hash) *Program {
 	return nil
 }
 
+// SetProgram inserts the given program into the program cache under the given
+// id, replacing any previously cached program.
+func SetProgram(id common.Hash, program *Program) {
+	programs.Add(id, program)
+}
+
 // GenProgramStatus returns the status of the given program id
 func GetProgramStatus(id common.Hash) progStatus {
 	program := GetProgram(id)
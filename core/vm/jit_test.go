@@ -17,6 +17,7 @@
 package vm
 
 import (
+	"bytes"
 	"math/big"
 	"testing"
 	"time"
@@ -105,6 +106,36 @@ func TestPcMappingToInstruction(t *testing.T) {
 	}
 }
 
+func TestProgramVmMatchesInterpreter(t *testing.T) {
+	var sender account
+
+	// PUSH1 2 PUSH1 3 ADD PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	code := common.Hex2Bytes("600260030160005260206000f3")
+	run := func(vm Vm) ([]byte, *big.Int) {
+		contract := NewContract(sender, sender, big.NewInt(0), big.NewInt(10000), big.NewInt(0))
+		contract.SetCallCode(&common.Address{}, crypto.Keccak256Hash(code), code)
+
+		ret, err := vm.Run(contract, nil)
+		if err != nil {
+			t.Fatalf("execution failed: %v", err)
+		}
+		return ret, contract.Gas
+	}
+	env := NewEnv(&Config{})
+	want, wantGas := run(env.Vm())
+	have, haveGas := run(NewProgramVm(env))
+
+	if !bytes.Equal(have, want) {
+		t.Errorf("return mismatch: have %x, want %x", have, want)
+	}
+	if haveGas.Cmp(wantGas) != 0 {
+		t.Errorf("gas mismatch: have %v, want %v", haveGas, wantGas)
+	}
+	if GetProgram(crypto.Keccak256Hash(code)) == nil {
+		t.Errorf("compiled program not cached")
+	}
+}
+
 var benchmarks = map[string]vmBench{
 	"pushes": vmBench{
 		false, false, false,
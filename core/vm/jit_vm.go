@@ -0,0 +1,73 @@
+// Copyright 2016 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/crypto"
+)
+
+// ProgramVm is a virtual machine that executes every contract through the JIT
+// program cache, compiling programs synchronously whenever they are missing.
+//
+// Contrary to the EVM it never falls back to the interpreter, which makes it
+// suitable for differential testing and benchmarking of the JIT against the
+// interpreter. It is not used by any consensus code path.
+type ProgramVm struct {
+	env Environment
+}
+
+// NewProgramVm returns a new JIT only virtual machine.
+func NewProgramVm(env Environment) *ProgramVm {
+	return &ProgramVm{env: env}
+}
+
+// Run compiles (or retrieves from the program cache) the contract's code and
+// executes it with the given input.
+func (vm *ProgramVm) Run(contract *Contract, input []byte) ([]byte, error) {
+	vm.env.SetDepth(vm.env.Depth() + 1)
+	defer vm.env.SetDepth(vm.env.Depth() - 1)
+
+	if contract.CodeAddr != nil {
+		if p := PrecompiledAt(vm.env.ChainConfig(), vm.env.BlockNumber(), *contract.CodeAddr); p != nil {
+			if !contract.UseGas(p.Gas(len(input))) {
+				return nil, OutOfGasError
+			}
+			return p.Call(vm.env, contract, input)
+		}
+	}
+	// Don't bother with the execution if there's no code.
+	if len(contract.Code) == 0 {
+		return nil, nil
+	}
+	codehash := contract.CodeHash
+	if codehash == (common.Hash{}) {
+		codehash = crypto.Keccak256Hash(contract.Code)
+	}
+	program := GetProgram(codehash)
+	if program == nil || progStatus(atomic.LoadInt32(&program.status)) != progReady {
+		program = NewProgram(contract.Code)
+		if err := CompileProgram(program); err != nil {
+			return nil, fmt.Errorf("jit compilation failed: %v", err)
+		}
+		SetProgram(codehash, program)
+	}
+	return RunProgram(program, vm.env, contract, input)
+}
@@ -0,0 +1,345 @@
+// Copyright 2016 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ur-technology/go-ur/common"
+)
+
+// GasProfiler is a Tracer that aggregates the gas consumed by every executed
+// instruction per opcode, per program counter and per contract address. It can
+// be reused across many transactions (and blocks) to build up a profile of a
+// whole chain segment.
+//
+// The gas reported for the CALL family of opcodes is exclusive: the gas spent
+// by the callee is attributed to the callee's own instructions, and only the
+// remainder (call stipend, value transfer, new account costs and gas burnt by
+// failing callees) is charged to the call site itself.
+type GasProfiler struct {
+	ops       map[OpCode]*opProfile
+	contracts map[common.Address]*contractProfile
+	stacks    map[string]*stackProfile
+
+	env    Environment    // environment of the transaction being traced
+	frames []profileFrame // code address of each active call frame
+	calls  []*pendingCall // unresolved calls of each active call frame
+	total  uint64         // total gas consumed by all traced instructions
+	steps  uint64         // total number of traced instructions
+
+	lock sync.Mutex
+}
+
+// profileFrame is a single entry in a call stack used for pprof sampling.
+type profileFrame struct {
+	addr common.Address
+	pc   uint64
+	op   OpCode
+}
+
+// pendingCall tracks a CALL, CALLCODE, DELEGATECALL or CREATE instruction whose
+// exclusive cost can only be determined after the callee returned.
+type pendingCall struct {
+	stack     []profileFrame // call stack at the call site, leaf included
+	gasBefore uint64         // available gas before the call was charged
+	cost      uint64         // gas charged by the interpreter for the call
+	children  uint64         // gas consumed by the callee and its descendants
+}
+
+type opProfile struct {
+	count uint64
+	gas   uint64
+}
+
+type pcProfile struct {
+	op    OpCode
+	count uint64
+	gas   uint64
+}
+
+type contractProfile struct {
+	count uint64
+	gas   uint64
+	pcs   map[uint64]*pcProfile
+}
+
+type stackProfile struct {
+	frames []profileFrame
+	count  uint64
+	gas    uint64
+}
+
+// NewGasProfiler creates a new, empty gas profiler.
+func NewGasProfiler() *GasProfiler {
+	return &GasProfiler{
+		ops:       make(map[OpCode]*opProfile),
+		contracts: make(map[common.Address]*contractProfile),
+		stacks:    make(map[string]*stackProfile),
+	}
+}
+
+// CaptureState implements the Tracer interface, accounting the gas used by the
+// current instruction.
+func (p *GasProfiler) CaptureState(env Environment, pc uint64, op OpCode, gas, cost *big.Int, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	// Failing instructions are not charged by the interpreter, their gas is
+	// accounted for at the call site that started the failing frame.
+	if err != nil || cost == nil {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// A new environment means a new transaction, flush anything left over
+	if env != p.env {
+		p.flush(0)
+		p.env = env
+	}
+	if depth < 1 {
+		depth = 1
+	}
+	before := gas.Uint64() + cost.Uint64()
+
+	// Resolve all calls that returned since the last step. The call pending at
+	// the current depth is resolved exactly from the gas difference, anything
+	// deeper ended abruptly and is resolved from the charged cost.
+	p.flush(depth)
+	if len(p.calls) >= depth {
+		if call := p.calls[depth-1]; call != nil {
+			p.calls[depth-1] = nil
+
+			used := call.cost
+			if call.gasBefore >= before {
+				used = call.gasBefore - before
+			}
+			p.resolve(call, used, depth)
+		}
+	}
+	// Update the current call stack with the executing frame
+	addr := contract.Address()
+	if contract.CodeAddr != nil {
+		addr = *contract.CodeAddr
+	}
+	for len(p.frames) < depth {
+		p.frames = append(p.frames, profileFrame{})
+		p.calls = append(p.calls, nil)
+	}
+	p.frames, p.calls = p.frames[:depth], p.calls[:depth]
+	p.frames[depth-1] = profileFrame{addr: addr, pc: pc, op: op}
+
+	frames := make([]profileFrame, depth)
+	copy(frames, p.frames)
+
+	switch op {
+	case CALL, CALLCODE, DELEGATECALL, CREATE:
+		p.calls[depth-1] = &pendingCall{stack: frames, gasBefore: before, cost: cost.Uint64()}
+	default:
+		p.account(frames, cost.Uint64(), depth)
+	}
+	return nil
+}
+
+// flush resolves all pending calls at or above the given depth (1 based) using
+// the gas charged by the interpreter as their inclusive cost. It must be called
+// with the lock held.
+func (p *GasProfiler) flush(depth int) {
+	for i := len(p.calls) - 1; i >= depth && i >= 0; i-- {
+		if call := p.calls[i]; call != nil {
+			p.calls[i] = nil
+			p.resolve(call, call.cost, i+1)
+		}
+	}
+}
+
+// resolve accounts the exclusive gas of a finished call given its inclusive
+// gas usage.
+func (p *GasProfiler) resolve(call *pendingCall, used uint64, depth int) {
+	if used > call.children {
+		used -= call.children
+	} else {
+		used = 0
+	}
+	p.account(call.stack, used, depth)
+}
+
+// account charges gas to the leaf frame of the given call stack and adds it to
+// the children gas of all enclosing pending calls.
+func (p *GasProfiler) account(frames []profileFrame, gas uint64, depth int) {
+	leaf := frames[len(frames)-1]
+
+	p.total += gas
+	p.steps++
+
+	opp := p.ops[leaf.op]
+	if opp == nil {
+		opp = new(opProfile)
+		p.ops[leaf.op] = opp
+	}
+	opp.count++
+	opp.gas += gas
+
+	cp := p.contracts[leaf.addr]
+	if cp == nil {
+		cp = &contractProfile{pcs: make(map[uint64]*pcProfile)}
+		p.contracts[leaf.addr] = cp
+	}
+	cp.count++
+	cp.gas += gas
+
+	pcp := cp.pcs[leaf.pc]
+	if pcp == nil {
+		pcp = &pcProfile{op: leaf.op}
+		cp.pcs[leaf.pc] = pcp
+	}
+	pcp.count++
+	pcp.gas += gas
+
+	key := stackKey(frames)
+	sp := p.stacks[key]
+	if sp == nil {
+		sp = &stackProfile{frames: frames}
+		p.stacks[key] = sp
+	}
+	sp.count++
+	sp.gas += gas
+
+	for i := 0; i < depth-1 && i < len(p.calls); i++ {
+		if p.calls[i] != nil {
+			p.calls[i].children += gas
+		}
+	}
+}
+
+// stackKey generates a unique identifier for a call stack.
+func stackKey(frames []profileFrame) string {
+	parts := make([]string, len(frames))
+	for i, frame := range frames {
+		parts[i] = fmt.Sprintf("%x:%d", frame.addr, frame.pc)
+	}
+	return strings.Join(parts, "/")
+}
+
+// OpGasProfile is the aggregated gas usage of a single opcode.
+type OpGasProfile struct {
+	Op    string `json:"op"`
+	Count uint64 `json:"count"`
+	Gas   uint64 `json:"gas"`
+}
+
+// PcGasProfile is the aggregated gas usage of a single instruction within a
+// contract's code.
+type PcGasProfile struct {
+	Pc    uint64 `json:"pc"`
+	Op    string `json:"op"`
+	Count uint64 `json:"count"`
+	Gas   uint64 `json:"gas"`
+}
+
+// ContractGasProfile is the aggregated gas usage of a single contract, along
+// with its instructions ordered by gas consumption.
+type ContractGasProfile struct {
+	Address  common.Address `json:"address"`
+	Count    uint64         `json:"count"`
+	Gas      uint64         `json:"gas"`
+	HotSpots []PcGasProfile `json:"hotSpots"`
+}
+
+// GasProfile is the JSON report generated by a GasProfiler. Opcodes, contracts
+// and instructions are all ordered by descending gas consumption.
+type GasProfile struct {
+	TotalGas  uint64               `json:"totalGas"`
+	Steps     uint64               `json:"steps"`
+	Ops       []OpGasProfile       `json:"ops"`
+	Contracts []ContractGasProfile `json:"contracts"`
+}
+
+// Profile assembles the gas profile gathered so far. If limit is positive, the
+// number of hot spots reported per contract is capped to it.
+func (p *GasProfiler) Profile(limit int) *GasProfile {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.flush(0)
+
+	profile := &GasProfile{
+		TotalGas:  p.total,
+		Steps:     p.steps,
+		Ops:       make([]OpGasProfile, 0, len(p.ops)),
+		Contracts: make([]ContractGasProfile, 0, len(p.contracts)),
+	}
+	for op, opp := range p.ops {
+		profile.Ops = append(profile.Ops, OpGasProfile{Op: op.String(), Count: opp.count, Gas: opp.gas})
+	}
+	sort.Sort(opsByGas(profile.Ops))
+
+	for addr, cp := range p.contracts {
+		contract := ContractGasProfile{
+			Address:  addr,
+			Count:    cp.count,
+			Gas:      cp.gas,
+			HotSpots: make([]PcGasProfile, 0, len(cp.pcs)),
+		}
+		for pc, pcp := range cp.pcs {
+			contract.HotSpots = append(contract.HotSpots, PcGasProfile{Pc: pc, Op: pcp.op.String(), Count: pcp.count, Gas: pcp.gas})
+		}
+		sort.Sort(pcsByGas(contract.HotSpots))
+		if limit > 0 && len(contract.HotSpots) > limit {
+			contract.HotSpots = contract.HotSpots[:limit]
+		}
+		profile.Contracts = append(profile.Contracts, contract)
+	}
+	sort.Sort(contractsByGas(profile.Contracts))
+
+	return profile
+}
+
+type opsByGas []OpGasProfile
+
+func (s opsByGas) Len() int      { return len(s) }
+func (s opsByGas) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s opsByGas) Less(i, j int) bool {
+	if s[i].Gas != s[j].Gas {
+		return s[i].Gas > s[j].Gas
+	}
+	return s[i].Op < s[j].Op
+}
+
+type pcsByGas []PcGasProfile
+
+func (s pcsByGas) Len() int      { return len(s) }
+func (s pcsByGas) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s pcsByGas) Less(i, j int) bool {
+	if s[i].Gas != s[j].Gas {
+		return s[i].Gas > s[j].Gas
+	}
+	return s[i].Pc < s[j].Pc
+}
+
+type contractsByGas []ContractGasProfile
+
+func (s contractsByGas) Len() int      { return len(s) }
+func (s contractsByGas) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s contractsByGas) Less(i, j int) bool {
+	if s[i].Gas != s[j].Gas {
+		return s[i].Gas > s[j].Gas
+	}
+	return s[i].Address.Hex() < s[j].Address.Hex()
+}
@@ -0,0 +1,236 @@
+// Copyright 2016 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Field numbers of the pprof profile.proto messages used by the encoder.
+const (
+	pprofProfileSampleType = 1
+	pprofProfileSample     = 2
+	pprofProfileLocation   = 4
+	pprofProfileFunction   = 5
+	pprofProfileStrings    = 6
+
+	pprofValueTypeType = 1
+	pprofValueTypeUnit = 2
+
+	pprofSampleLocation = 1
+	pprofSampleValue    = 2
+
+	pprofLocationID   = 1
+	pprofLocationLine = 4
+
+	pprofLineFunction = 1
+	pprofLineLine     = 2
+
+	pprofFunctionID       = 1
+	pprofFunctionName     = 2
+	pprofFunctionSysName  = 3
+	pprofFunctionFilename = 4
+)
+
+// WritePprof writes the gathered call stacks as a gzipped pprof protocol buffer,
+// which can be consumed by `go tool pprof` and any flame graph tooling built on
+// top of it.
+//
+// Every contract is represented as a function named after its address, with
+// the program counters of its instructions mapped to line numbers. The leaf of
+// each sample is a pseudo-function named after the executed opcode. Samples
+// carry two values: the number of executed instructions and the gas they used.
+func (p *GasProfiler) WritePprof(w io.Writer) error {
+	p.lock.Lock()
+	p.flush(0)
+	keys := make([]string, 0, len(p.stacks))
+	stacks := make(map[string]*stackProfile, len(p.stacks))
+	for key, sp := range p.stacks {
+		keys = append(keys, key)
+		stacks[key] = &stackProfile{frames: sp.frames, count: sp.count, gas: sp.gas}
+	}
+	p.lock.Unlock()
+
+	// Order the stacks to generate deterministic output
+	sort.Strings(keys)
+
+	b := newPprofBuilder()
+	b.sampleType("steps", "count")
+	b.sampleType("gas", "gas")
+
+	for _, key := range keys {
+		sp := stacks[key]
+		locs := make([]uint64, 0, len(sp.frames)+1)
+		leaf := sp.frames[len(sp.frames)-1]
+		locs = append(locs, b.location(b.function(leaf.op.String(), "evm"), 0))
+		for i := len(sp.frames) - 1; i >= 0; i-- {
+			name := fmt.Sprintf("0x%x", sp.frames[i].addr)
+			locs = append(locs, b.location(b.function(name, name), sp.frames[i].pc))
+		}
+		b.sample(locs, []int64{int64(sp.count), int64(sp.gas)})
+	}
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(b.encode()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// pprofBuilder assembles a minimal pprof profile without pulling in a protobuf
+// dependency.
+type pprofBuilder struct {
+	body protoBuffer // encoded samples and sample types
+
+	strings   []string
+	stringIDs map[string]int64
+
+	functions   protoBuffer
+	functionIDs map[string]uint64
+
+	locations   protoBuffer
+	locationIDs map[pprofLocationKey]uint64
+}
+
+type pprofLocationKey struct {
+	function uint64
+	line     uint64
+}
+
+func newPprofBuilder() *pprofBuilder {
+	return &pprofBuilder{
+		strings:     []string{""},
+		stringIDs:   map[string]int64{"": 0},
+		functionIDs: make(map[string]uint64),
+		locationIDs: make(map[pprofLocationKey]uint64),
+	}
+}
+
+// str interns a string in the string table of the profile.
+func (b *pprofBuilder) str(s string) int64 {
+	if id, ok := b.stringIDs[s]; ok {
+		return id
+	}
+	id := int64(len(b.strings))
+	b.strings = append(b.strings, s)
+	b.stringIDs[s] = id
+	return id
+}
+
+func (b *pprofBuilder) sampleType(typ, unit string) {
+	var msg protoBuffer
+	msg.int64(pprofValueTypeType, b.str(typ))
+	msg.int64(pprofValueTypeUnit, b.str(unit))
+	b.body.message(pprofProfileSampleType, msg)
+}
+
+func (b *pprofBuilder) function(name, file string) uint64 {
+	if id, ok := b.functionIDs[name]; ok {
+		return id
+	}
+	id := uint64(len(b.functionIDs) + 1)
+	b.functionIDs[name] = id
+
+	var msg protoBuffer
+	msg.uint64(pprofFunctionID, id)
+	msg.int64(pprofFunctionName, b.str(name))
+	msg.int64(pprofFunctionSysName, b.str(name))
+	msg.int64(pprofFunctionFilename, b.str(file))
+	b.functions.message(pprofProfileFunction, msg)
+
+	return id
+}
+
+func (b *pprofBuilder) location(function uint64, line uint64) uint64 {
+	key := pprofLocationKey{function, line}
+	if id, ok := b.locationIDs[key]; ok {
+		return id
+	}
+	id := uint64(len(b.locationIDs) + 1)
+	b.locationIDs[key] = id
+
+	var ln protoBuffer
+	ln.uint64(pprofLineFunction, function)
+	ln.int64(pprofLineLine, int64(line))
+
+	var msg protoBuffer
+	msg.uint64(pprofLocationID, id)
+	msg.message(pprofLocationLine, ln)
+	b.locations.message(pprofProfileLocation, msg)
+
+	return id
+}
+
+func (b *pprofBuilder) sample(locations []uint64, values []int64) {
+	var locs, vals, msg protoBuffer
+	for _, loc := range locations {
+		locs.varint(loc)
+	}
+	for _, val := range values {
+		vals.varint(uint64(val))
+	}
+	msg.bytes(pprofSampleLocation, locs)
+	msg.bytes(pprofSampleValue, vals)
+	b.body.message(pprofProfileSample, msg)
+}
+
+func (b *pprofBuilder) encode() []byte {
+	var out protoBuffer
+	out = append(out, b.body...)
+	out = append(out, b.locations...)
+	out = append(out, b.functions...)
+	for _, s := range b.strings {
+		out.bytes(pprofProfileStrings, []byte(s))
+	}
+	return out
+}
+
+// protoBuffer is a tiny protocol buffer wire format encoder.
+type protoBuffer []byte
+
+func (buf *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		*buf = append(*buf, byte(x)|0x80)
+		x >>= 7
+	}
+	*buf = append(*buf, byte(x))
+}
+
+func (buf *protoBuffer) key(field int, wire uint64) {
+	buf.varint(uint64(field)<<3 | wire)
+}
+
+func (buf *protoBuffer) uint64(field int, x uint64) {
+	buf.key(field, 0)
+	buf.varint(x)
+}
+
+func (buf *protoBuffer) int64(field int, x int64) {
+	buf.uint64(field, uint64(x))
+}
+
+func (buf *protoBuffer) bytes(field int, data []byte) {
+	buf.key(field, 2)
+	buf.varint(uint64(len(data)))
+	*buf = append(*buf, data...)
+}
+
+func (buf *protoBuffer) message(field int, msg protoBuffer) {
+	buf.bytes(field, msg)
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+)
+
+// Tests that the gas profiler attributes the gas of nested calls exclusively
+// to the instructions that consumed it.
+func TestGasProfilerNestedCall(t *testing.T) {
+	var (
+		env      = NewEnv(&Config{EnableJit: false, ForceJit: false})
+		profiler = NewGasProfiler()
+		mem      = NewMemory()
+		stack    = newstack()
+
+		outerAddr = common.StringToAddress("outer")
+		innerAddr = common.StringToAddress("inner")
+
+		outer = NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), new(big.Int), new(big.Int))
+		inner = NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), new(big.Int), new(big.Int))
+	)
+	outer.CodeAddr, inner.CodeAddr = &outerAddr, &innerAddr
+
+	steps := []struct {
+		contract  *Contract
+		pc        uint64
+		op        OpCode
+		gas, cost int64
+		depth     int
+	}{
+		{outer, 0, PUSH1, 97, 3, 1},
+		{outer, 2, CALL, 47, 50, 1}, // forwards 40 gas to the callee
+		{inner, 0, PUSH1, 37, 3, 2},
+		{inner, 2, STOP, 37, 0, 2},
+		{outer, 3, STOP, 84, 0, 1}, // 37 gas returned by the callee
+	}
+	for i, step := range steps {
+		if err := profiler.CaptureState(env, step.pc, step.op, big.NewInt(step.gas), big.NewInt(step.cost), mem, stack, step.contract, step.depth, nil); err != nil {
+			t.Fatalf("step %d: failed to capture state: %v", i, err)
+		}
+	}
+	profile := profiler.Profile(0)
+	if profile.TotalGas != 16 {
+		t.Errorf("total gas mismatch: have %d, want %d", profile.TotalGas, 16)
+	}
+	if profile.Steps != uint64(len(steps)) {
+		t.Errorf("step count mismatch: have %d, want %d", profile.Steps, len(steps))
+	}
+	if len(profile.Contracts) != 2 {
+		t.Fatalf("contract count mismatch: have %d, want %d", len(profile.Contracts), 2)
+	}
+	if c := profile.Contracts[0]; c.Address != outerAddr || c.Gas != 13 {
+		t.Errorf("outer contract mismatch: have %x/%d, want %x/%d", c.Address, c.Gas, outerAddr, 13)
+	}
+	if c := profile.Contracts[1]; c.Address != innerAddr || c.Gas != 3 {
+		t.Errorf("inner contract mismatch: have %x/%d, want %x/%d", c.Address, c.Gas, innerAddr, 3)
+	}
+	if hot := profile.Contracts[0].HotSpots[0]; hot.Pc != 2 || hot.Op != CALL.String() || hot.Gas != 10 {
+		t.Errorf("call site mismatch: have %d/%s/%d, want %d/%s/%d", hot.Pc, hot.Op, hot.Gas, 2, CALL, 10)
+	}
+	if op := profile.Ops[0]; op.Op != CALL.String() || op.Gas != 10 {
+		t.Errorf("hottest opcode mismatch: have %s/%d, want %s/%d", op.Op, op.Gas, CALL, 10)
+	}
+	// Ensure the pprof output is a valid gzip stream containing the symbols
+	var buf bytes.Buffer
+	if err := profiler.WritePprof(&buf); err != nil {
+		t.Fatalf("failed to write pprof profile: %v", err)
+	}
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("failed to open pprof profile: %v", err)
+	}
+	blob, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to read pprof profile: %v", err)
+	}
+	for _, symbol := range []string{"gas", CALL.String(), PUSH1.String(), common.ToHex(innerAddr[:])} {
+		if !bytes.Contains(blob, []byte(symbol)) {
+			t.Errorf("pprof profile misses symbol %q", symbol)
+		}
+	}
+}
@@ -63,7 +63,7 @@ func (evm *EVM) Run(contract *Contract, input []byte) (ret []byte, err error) {
 	defer evm.env.SetDepth(evm.env.Depth() - 1)
 
 	if contract.CodeAddr != nil {
-		if p := Precompiled[contract.CodeAddr.Str()]; p != nil {
+		if p := PrecompiledAt(evm.env.ChainConfig(), evm.env.BlockNumber(), *contract.CodeAddr); p != nil {
 			return evm.RunPrecompiled(p, input, contract)
 		}
 	}
@@ -452,9 +452,7 @@ func calculateGasAndSize(gasTable params.GasTable, env Environment, contract *Co
 func (evm *EVM) RunPrecompiled(p *PrecompiledAccount, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.Gas(len(input))
 	if contract.UseGas(gas) {
-		ret = p.Call(input)
-
-		return ret, nil
+		return p.Call(evm.env, contract, input)
 	} else {
 		return nil, OutOfGasError
 	}
@@ -116,3 +116,73 @@ func (self *VMEnv) DelegateCall(me vm.ContractRef, addr common.Address, data []b
 func (self *VMEnv) Create(me vm.ContractRef, data []byte, gas, price, value *big.Int) ([]byte, common.Address, error) {
 	return Create(self, me, data, gas, price, value)
 }
+
+// SignupChain implements vm.SignupBackend, resolving the signup chain of the
+// member that signed up with the given transaction of a block in the ancestry
+// of the current one. Blocks are never looked up by canonical number alone, as
+// side chains and reorgs must see the signups of their own ancestry.
+func (self *VMEnv) SignupChain(number uint64, hash common.Hash, charge func() error) ([]common.Address, error) {
+	chain := make([]common.Address, 0, SignupChainDepth+1)
+	for {
+		if err := charge(); err != nil {
+			return nil, err
+		}
+		block := self.ancestor(number)
+		if block == nil {
+			return nil, errInvalidChain
+		}
+		tx := block.Transaction(hash)
+		if tx == nil || tx.To() == nil || tx.Value().Cmp(big.NewInt(1)) != 0 {
+			return nil, errInvalidChain
+		}
+		if chain = append(chain, *tx.To()); len(chain) > SignupChainDepth {
+			return chain, nil
+		}
+		var err error
+		if number, hash, err = ParseSignupData(tx.Data()); err == ErrNoMoreMembers {
+			return chain, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ancestor returns the block with the given number in the ancestry of the
+// current one, or nil if there's none. Side chains are walked back until they
+// join the canonical chain, below which blocks are looked up by number.
+func (self *VMEnv) ancestor(number uint64) *types.Block {
+	if self.chain == nil || number >= self.header.Number.Uint64() {
+		return nil
+	}
+	hash, n := self.header.ParentHash, self.header.Number.Uint64()-1
+	for n > number {
+		if GetCanonicalHash(self.chain.chainDb, n) == hash {
+			hash, n = GetCanonicalHash(self.chain.chainDb, number), number
+			break
+		}
+		header := self.chain.GetHeader(hash, n)
+		if header == nil {
+			return nil
+		}
+		hash, n = header.ParentHash, n-1
+	}
+	return self.chain.GetBlock(hash, number)
+}
+
+// NetworkTotals implements vm.SignupBackend, returning the signup totals of the
+// parent block.
+func (self *VMEnv) NetworkTotals() (*big.Int, *big.Int) {
+	nSignups, totalWei := new(big.Int), new(big.Int)
+	if self.chain == nil {
+		return nSignups, totalWei
+	}
+	if parent := self.chain.GetHeader(self.header.ParentHash, self.header.Number.Uint64()-1); parent != nil {
+		if parent.NSignups != nil {
+			nSignups.Set(parent.NSignups)
+		}
+		if parent.TotalWei != nil {
+			totalWei.Set(parent.TotalWei)
+		}
+	}
+	return nSignups, totalWei
+}
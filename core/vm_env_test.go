@@ -0,0 +1,139 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/ethdb"
+	"github.com/ur-technology/go-ur/event"
+	"github.com/ur-technology/go-ur/params"
+)
+
+// Tests that the signup chains requested from the VM are resolved through the
+// ancestry of the executing block, so side chains don't see canonical signups
+// and vice versa.
+func TestVMEnvSignupChainAncestry(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = WriteGenesisBlockForTesting(db, GenesisAccount{address, big.NewInt(1000000000)})
+		config  = &params.ChainConfig{
+			ChainId:        big.NewInt(1),
+			HomesteadBlock: new(big.Int),
+			Privileged: map[common.Address]params.PrivilegedReceivers{
+				address: {Receiver: common.Address{0xaa}, URFF: common.Address{0xbb}},
+			},
+		}
+		mux event.TypeMux
+
+		member, referred, forked = common.Address{0x01}, common.Address{0x02}, common.Address{0x03}
+		txs                      = make(map[common.Address]*types.Transaction)
+	)
+	blockchain, _ := NewBlockChain(db, config, FakePow{}, &mux)
+
+	signup := func(block *BlockGen, to common.Address, data []byte) {
+		tx, err := types.NewTransaction(block.TxNonce(address), to, big.NewInt(1), big.NewInt(100000), new(big.Int), data).SignECDSA(types.HomesteadSigner{}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(tx)
+		txs[to] = tx
+	}
+	// Sign up a member and a referred one on the canonical chain, and another
+	// member at the same height on a shorter side chain. Canonical blocks are
+	// inserted one by one for the referrers to be found while generating.
+	var canon []*types.Block
+	for i, parent := 0, genesis; i < 3; i++ {
+		blocks, _ := GenerateChain(config, blockchain, parent, db, 1, func(_ int, block *BlockGen) {
+			switch i {
+			case 0:
+				signup(block, member, SignupData(0, common.Hash{}))
+			case 1:
+				signup(block, referred, SignupData(1, txs[member].Hash()))
+			}
+		})
+		if _, err := blockchain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to insert canonical block #%d: %v", i+1, err)
+		}
+		canon, parent = append(canon, blocks[0]), blocks[0]
+	}
+	side, _ := GenerateChain(config, blockchain, genesis, db, 2, func(i int, block *BlockGen) {
+		if i == 0 {
+			signup(block, forked, SignupData(0, common.Hash{}))
+		}
+	})
+	if _, err := blockchain.InsertChain(side); err != nil {
+		t.Fatalf("failed to insert side chain: %v", err)
+	}
+	if head := blockchain.CurrentBlock().Hash(); head != canon[2].Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", head[:4], canon[2].Hash().Bytes()[:4])
+	}
+	envAt := func(parent *types.Block) *VMEnv {
+		return &VMEnv{
+			chainConfig: config,
+			chain:       blockchain,
+			header:      &types.Header{ParentHash: parent.Hash(), Number: new(big.Int).Add(parent.Number(), common.Big1)},
+		}
+	}
+	free := func() error { return nil }
+
+	tests := []struct {
+		env    *VMEnv
+		number uint64
+		tx     *types.Transaction
+		chain  []common.Address
+	}{
+		{envAt(canon[2]), 2, txs[referred], []common.Address{referred, member}},
+		{envAt(canon[2]), 1, txs[member], []common.Address{member}},
+		{envAt(canon[2]), 1, txs[forked], nil},
+		{envAt(side[1]), 1, txs[forked], []common.Address{forked}},
+		{envAt(side[1]), 1, txs[member], nil},
+		{envAt(side[1]), 2, txs[referred], nil},
+		{envAt(canon[0]), 2, txs[referred], nil}, // not prior to the executing block
+	}
+	for i, tt := range tests {
+		chain, err := tt.env.SignupChain(tt.number, tt.tx.Hash(), free)
+		if tt.chain == nil {
+			if err == nil {
+				t.Errorf("test %d: resolved signup chain %x outside the ancestry", i, chain)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(chain, tt.chain) {
+			t.Errorf("test %d: chain mismatch: have %x (%v), want %x", i, chain, err, tt.chain)
+		}
+	}
+	// Make sure every level is charged for and a failed charge aborts
+	var levels int
+	charge := func() error {
+		if levels++; levels > 1 {
+			return errors.New("out of gas")
+		}
+		return nil
+	}
+	if _, err := envAt(canon[2]).SignupChain(2, txs[referred].Hash(), charge); err == nil || levels != 2 {
+		t.Errorf("charge abort mismatch: have %v after %d levels, want error after 2", err, levels)
+	}
+}
@@ -29,7 +29,9 @@ import (
 
 	"github.com/ur-technology/urhash"
 	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/common/hexutil"
 	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/archive"
 	"github.com/ur-technology/go-ur/core/state"
 	"github.com/ur-technology/go-ur/core/types"
 	"github.com/ur-technology/go-ur/core/vm"
@@ -45,6 +47,10 @@ import (
 
 const defaultTraceTimeout = 5 * time.Second
 
+// maxProfileBlocks is the maximum number of blocks a single profiling request
+// may re-execute.
+const maxProfileBlocks = 1024
+
 // PublicEthereumAPI provides an API to access Ethereum full node-related
 // information.
 type PublicEthereumAPI struct {
@@ -208,8 +214,30 @@ func NewPrivateAdminAPI(eth *Ethereum) *PrivateAdminAPI {
 	return &PrivateAdminAPI{eth: eth}
 }
 
-// ExportChain exports the current blockchain into a local file.
-func (api *PrivateAdminAPI) ExportChain(file string) (bool, error) {
+// ArchiveArgs are the optional arguments of admin_exportChain, requesting the
+// chain to be exported into a portable archive instead of a plain block dump.
+type ArchiveArgs struct {
+	First *uint64 `json:"first"` // First block to export (default genesis)
+	Last  *uint64 `json:"last"`  // Last block to export (default current head)
+	State bool    `json:"state"` // Whether to include a snapshot of the last state
+}
+
+// ExportChain exports the current blockchain into a local file, or into an
+// archive directory if archive arguments are given.
+func (api *PrivateAdminAPI) ExportChain(file string, args *ArchiveArgs) (bool, error) {
+	if args != nil {
+		first, last := uint64(0), api.eth.BlockChain().CurrentBlock().NumberU64()
+		if args.First != nil {
+			first = *args.First
+		}
+		if args.Last != nil {
+			last = *args.Last
+		}
+		if _, err := archive.Export(api.eth.BlockChain(), api.eth.ChainDb(), file, first, last, args.State); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
 	// Make sure we can create the file to export into
 	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
 	if err != nil {
@@ -234,8 +262,15 @@ func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	return true
 }
 
-// ImportChain imports a blockchain from a local file.
-func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
+// ImportChain imports a blockchain from a local file or archive directory. The
+// blocks of archives carrying a state snapshot are only executed if requested.
+func (api *PrivateAdminAPI) ImportChain(file string, execute *bool) (bool, error) {
+	if archive.IsArchive(file) {
+		if err := archive.Import(api.eth.BlockChain(), api.eth.ChainDb(), file, execute != nil && *execute, nil); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
 	// Make sure the can access the file to import
 	in, err := os.Open(file)
 	if err != nil {
@@ -422,6 +457,90 @@ func (api *PrivateDebugAPI) traceBlock(block *types.Block, logConfig *vm.LogConf
 	return true, structLogger.StructLogs(), nil
 }
 
+// ProfileArgs holds extra parameters to the block range profiler.
+type ProfileArgs struct {
+	Format *string // output format, either "json" (default) or "pprof"
+	Limit  *int    // maximum number of hot spots reported per contract
+}
+
+// BlockProfileResult is the returned value when profiling the gas usage of a
+// range of blocks. Depending on the requested format, either the aggregated
+// JSON profile or the gzipped pprof protocol buffer is filled in.
+type BlockProfileResult struct {
+	From    uint64         `json:"from"`
+	To      uint64         `json:"to"`
+	Txs     int            `json:"transactions"`
+	Profile *vm.GasProfile `json:"profile,omitempty"`
+	Pprof   hexutil.Bytes  `json:"pprof,omitempty"`
+}
+
+// ProfileBlocks re-executes the canonical blocks in the [from, to] range and
+// aggregates the gas used by every executed instruction per opcode, per program
+// counter and per contract address.
+func (api *PrivateDebugAPI) ProfileBlocks(ctx context.Context, from, to uint64, config *ProfileArgs) (*BlockProfileResult, error) {
+	if from > to {
+		return nil, fmt.Errorf("invalid block range #%d -> #%d", from, to)
+	}
+	if to-from >= maxProfileBlocks {
+		return nil, fmt.Errorf("block range too large (%d>%d blocks)", to-from+1, maxProfileBlocks)
+	}
+	format, limit := "json", 0
+	if config != nil {
+		if config.Format != nil {
+			format = *config.Format
+		}
+		if config.Limit != nil {
+			limit = *config.Limit
+		}
+	}
+	if format != "json" && format != "pprof" {
+		return nil, fmt.Errorf("unknown profile format %q", format)
+	}
+	var (
+		blockchain = api.eth.BlockChain()
+		processor  = blockchain.Processor()
+		profiler   = vm.NewGasProfiler()
+		result     = &BlockProfileResult{From: from, To: to}
+	)
+	for number := from; number <= to; number++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		block := blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		if number == 0 {
+			continue
+		}
+		parent := blockchain.GetBlock(block.ParentHash(), number-1)
+		if parent == nil {
+			return nil, fmt.Errorf("block parent %x not found", block.ParentHash())
+		}
+		statedb, err := blockchain.StateAt(parent.Root())
+		if err != nil {
+			return nil, err
+		}
+		if _, _, _, err := processor.Process(block, statedb, vm.Config{Debug: true, Tracer: profiler}); err != nil {
+			return nil, fmt.Errorf("block #%d: processing failed: %v", number, err)
+		}
+		result.Txs += len(block.Transactions())
+	}
+	switch format {
+	case "pprof":
+		var buf bytes.Buffer
+		if err := profiler.WritePprof(&buf); err != nil {
+			return nil, err
+		}
+		result.Pprof = buf.Bytes()
+	default:
+		result.Profile = profiler.Profile(limit)
+	}
+	return result, nil
+}
+
 // callmsg is the message type used for call transations.
 type callmsg struct {
 	addr          common.Address
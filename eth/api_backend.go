@@ -38,7 +38,7 @@ import (
 // EthApiBackend implements ethapi.Backend for full nodes
 type EthApiBackend struct {
 	eth *Ethereum
-	gpo *gasprice.GasPriceOracle
+	gpo *gasprice.Oracle
 }
 
 func (b *EthApiBackend) ChainConfig() *params.ChainConfig {
@@ -102,6 +102,16 @@ func (b *EthApiBackend) GetReceipts(ctx context.Context, blockHash common.Hash)
 	return core.GetBlockReceipts(b.eth.chainDb, blockHash, core.GetBlockNumber(b.eth.chainDb, blockHash)), nil
 }
 
+// BloomStatus implements filters.BloomBackend.
+func (b *EthApiBackend) BloomStatus() (uint64, uint64) {
+	return b.eth.bloomIndexer.Status()
+}
+
+// GetBloomBits implements filters.BloomBackend.
+func (b *EthApiBackend) GetBloomBits(ctx context.Context, section uint64, bits []uint) ([][]byte, error) {
+	return b.eth.bloomIndexer.GetBloomBits(ctx, section, bits)
+}
+
 func (b *EthApiBackend) GetTd(blockHash common.Hash) *big.Int {
 	return b.eth.blockchain.GetTdByHash(blockHash)
 }
@@ -177,7 +187,11 @@ func (b *EthApiBackend) ProtocolVersion() int {
 }
 
 func (b *EthApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
-	return b.gpo.SuggestPrice(), nil
+	return b.gpo.SuggestPrice(ctx)
+}
+
+func (b *EthApiBackend) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, percentiles)
 }
 
 func (b *EthApiBackend) ChainDb() ethdb.Database {
@@ -0,0 +1,39 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// Tests that block profiling requests are rejected for invalid or too large
+// ranges before touching the chain.
+func TestProfileBlocksRange(t *testing.T) {
+	api := new(PrivateDebugAPI)
+
+	for _, tt := range []struct{ from, to uint64 }{
+		{10, 9},
+		{0, maxProfileBlocks},
+		{1, maxProfileBlocks + 100},
+	} {
+		if _, err := api.ProfileBlocks(context.Background(), tt.from, tt.to, nil); err == nil {
+			t.Errorf("range #%d -> #%d: no error returned", tt.from, tt.to)
+		}
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -62,44 +63,83 @@ var (
 )
 
 type Config struct {
-	ChainConfig *params.ChainConfig // chain configuration
+	ChainConfig *params.ChainConfig `toml:"-"` // chain configuration
 
 	NetworkId  int    // Network ID to use for selecting peers to connect to
-	Genesis    string // Genesis JSON to seed the chain database with
+	Genesis    string `toml:"-"` // Genesis JSON to seed the chain database with
 	FastSync   bool   // Enables the state download based fast synchronisation algorithm
+	SnapSync   bool   // Retrieves the fast sync state in proven ranges instead of trie nodes
 	LightMode  bool   // Running in light client mode
 	LightServ  int    // Maximum percentage of time allowed for serving LES requests
 	LightPeers int    // Maximum number of LES client peers
-	MaxPeers   int    // Maximum number of global peers
+	MaxPeers   int    `toml:"-"` // Maximum number of global peers
 
-	SkipBcVersionCheck bool // e.g. blockchain export
+	Checkpoint *downloader.Checkpoint `toml:",omitempty"` // Trusted block to sync from instead of the genesis
+
+	SkipBcVersionCheck bool `toml:"-"` // e.g. blockchain export
 	DatabaseCache      int
-	DatabaseHandles    int
+	DatabaseHandles    int `toml:"-"`
 
 	NatSpec   bool
 	DocRoot   string
 	AutoDAG   bool
-	PowTest   bool
-	PowShared bool
-	ExtraData []byte
+	PowTest   bool   `toml:",omitempty"`
+	PowShared bool   `toml:",omitempty"`
+	ExtraData []byte `toml:"-"`
 
 	Etherbase    common.Address
 	GasPrice     *big.Int
 	MinerThreads int
 	SolcPath     string
 
-	GpoMinGasPrice          *big.Int
-	GpoMaxGasPrice          *big.Int
-	GpoFullBlockRatio       int
-	GpobaseStepDown         int
-	GpobaseStepUp           int
-	GpobaseCorrectionFactor int
+	GpoMinGasPrice *big.Int
+	GpoMaxGasPrice *big.Int
+	GpoBlocks      int
+	GpoPercentile  int
 
 	EnableJit bool
 	ForceJit  bool
 
-	TestGenesisBlock *types.Block   // Genesis block to seed the chain database with (testing only!)
-	TestGenesisState ethdb.Database // Genesis state to seed the database with (testing only!)
+	TestGenesisBlock *types.Block   `toml:"-"` // Genesis block to seed the chain database with (testing only!)
+	TestGenesisState ethdb.Database `toml:"-"` // Genesis state to seed the database with (testing only!)
+}
+
+// DefaultConfig contains the default settings for use on the UR main net.
+var DefaultConfig = Config{
+	NetworkId:      NetworkId,
+	LightPeers:     20,
+	DatabaseCache:  128,
+	GasPrice:       new(big.Int).Mul(big.NewInt(20), common.Shannon),
+	MinerThreads:   runtime.NumCPU(),
+	SolcPath:       "solc",
+	GpoMinGasPrice: new(big.Int).Mul(big.NewInt(20), common.Shannon),
+	GpoMaxGasPrice: new(big.Int).Mul(big.NewInt(500), common.Shannon),
+	GpoBlocks:      gasprice.DefaultBlocks,
+	GpoPercentile:  gasprice.DefaultPercentile,
+}
+
+// GpoConfig returns the gas price oracle settings of the configuration.
+func (c *Config) GpoConfig() gasprice.Config {
+	return gasprice.Config{
+		Blocks:     c.GpoBlocks,
+		Percentile: c.GpoPercentile,
+		Default:    c.GpoMinGasPrice,
+		MaxPrice:   c.GpoMaxGasPrice,
+	}
+}
+
+// SyncMode returns the chain synchronisation mode of the configuration.
+func (c *Config) SyncMode() downloader.SyncMode {
+	switch {
+	case c.LightMode:
+		return downloader.LightSync
+	case c.SnapSync:
+		return downloader.SnapSync
+	case c.FastSync:
+		return downloader.FastSync
+	default:
+		return downloader.FullSync
+	}
 }
 
 type LesServer interface {
@@ -121,7 +161,8 @@ type Ethereum struct {
 	protocolManager *ProtocolManager
 	lesServer       LesServer
 	// DB interfaces
-	chainDb ethdb.Database // Block chain database
+	chainDb      ethdb.Database // Block chain database
+	bloomIndexer *BloomIndexer  // Bloom bits index builder, for fast log searches
 
 	eventMux       *event.TypeMux
 	pow            *urhash.Ethash
@@ -185,6 +226,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if err := addMipmapBloomBins(chainDb); err != nil {
 		return nil, err
 	}
+	eth.bloomIndexer = NewBloomIndexer(chainDb, eth.eventMux, BloomSectionSize, BloomConfirms)
 
 	glog.V(logger.Info).Infof("Protocol Versions: %v, Network Id: %v", ProtocolVersions, config.NetworkId)
 
@@ -237,23 +279,19 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		}
 	}
 
-	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.FastSync, config.NetworkId, maxPeers, eth.eventMux, eth.txPool, eth.pow, eth.blockchain, chainDb); err != nil {
+	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode(), config.NetworkId, maxPeers, eth.eventMux, eth.txPool, eth.pow, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	if config.Checkpoint != nil {
+		glog.V(logger.Info).Infof("Trusted checkpoint: #%d [%x…]", config.Checkpoint.Number, config.Checkpoint.Hash[:4])
+		eth.protocolManager.downloader.SetCheckpoint(config.Checkpoint)
+	}
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.pow)
 	eth.miner.SetGasPrice(config.GasPrice)
 	eth.miner.SetExtra(config.ExtraData)
 
-	gpoParams := &gasprice.GpoParams{
-		GpoMinGasPrice:          config.GpoMinGasPrice,
-		GpoMaxGasPrice:          config.GpoMaxGasPrice,
-		GpoFullBlockRatio:       config.GpoFullBlockRatio,
-		GpobaseStepDown:         config.GpobaseStepDown,
-		GpobaseStepUp:           config.GpobaseStepUp,
-		GpobaseCorrectionFactor: config.GpobaseCorrectionFactor,
-	}
-	gpo := gasprice.NewGasPriceOracle(eth.blockchain, chainDb, eth.eventMux, gpoParams)
-	eth.ApiBackend = &EthApiBackend{eth, gpo}
+	eth.ApiBackend = &EthApiBackend{eth, nil}
+	eth.ApiBackend.gpo = gasprice.NewOracle(eth.ApiBackend, config.GpoConfig())
 
 	return eth, nil
 }
@@ -425,6 +463,7 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	s.bloomIndexer.Start()
 	return nil
 }
 
@@ -434,6 +473,7 @@ func (s *Ethereum) Stop() error {
 	if s.stopDbUpgrade != nil {
 		s.stopDbUpgrade()
 	}
+	s.bloomIndexer.Stop()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
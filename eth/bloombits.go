@@ -0,0 +1,198 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/bloombits"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/ethdb"
+	"github.com/ur-technology/go-ur/event"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+	"golang.org/x/net/context"
+)
+
+const (
+	// BloomSectionSize is the number of blocks of a bloom bits section.
+	BloomSectionSize = 4096
+
+	// BloomConfirms is the number of confirmations a section needs before being
+	// indexed, making reorgs of indexed sections unlikely.
+	BloomConfirms = 256
+
+	bloomRecheckInterval = 10 * time.Second // Interval of the head checks while no blocks are imported
+)
+
+// BloomIndexer builds the bloom bits index in the background, rotating the
+// header blooms of every section of the canonical chain once it's confirmed.
+type BloomIndexer struct {
+	db          ethdb.Database
+	mux         *event.TypeMux
+	sectionSize uint64
+	confirms    uint64
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewBloomIndexer creates a bloom bits indexer over the chain in the database.
+func NewBloomIndexer(db ethdb.Database, mux *event.TypeMux, sectionSize, confirms uint64) *BloomIndexer {
+	return &BloomIndexer{
+		db:          db,
+		mux:         mux,
+		sectionSize: sectionSize,
+		confirms:    confirms,
+		quit:        make(chan struct{}),
+	}
+}
+
+// Start launches the indexing, catching up with the chain and then following
+// its head.
+func (b *BloomIndexer) Start() {
+	sub := b.mux.Subscribe(core.ChainHeadEvent{})
+
+	b.wg.Add(1)
+	go b.loop(sub)
+}
+
+// Stop terminates the indexing, waiting for the section being indexed.
+func (b *BloomIndexer) Stop() {
+	close(b.quit)
+	b.wg.Wait()
+}
+
+// Status returns the section size and the number of indexed sections.
+func (b *BloomIndexer) Status() (uint64, uint64) {
+	return b.sectionSize, core.GetBloomSections(b.db)
+}
+
+// loop indexes the sections on every new head. Heads are also checked now and
+// then, as the headers imported by fast sync don't post any event.
+func (b *BloomIndexer) loop(sub event.Subscription) {
+	defer b.wg.Done()
+	defer sub.Unsubscribe()
+
+	ticker := time.NewTicker(bloomRecheckInterval)
+	defer ticker.Stop()
+
+	for {
+		b.update()
+
+		select {
+		case _, ok := <-sub.Chan():
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+		case <-b.quit:
+			return
+		}
+	}
+}
+
+// update rolls back the sections reorged out of the canonical chain, then
+// indexes the confirmed sections not indexed yet.
+func (b *BloomIndexer) update() {
+	head := core.GetHeadHeaderHash(b.db)
+	if head == (common.Hash{}) {
+		return
+	}
+	number := core.GetBlockNumber(b.db, head)
+
+	stored := core.GetBloomSections(b.db)
+	sections := stored
+	for sections > 0 && core.GetBloomSectionHead(b.db, sections-1) != core.GetCanonicalHash(b.db, sections*b.sectionSize-1) {
+		sections--
+	}
+	if sections != stored {
+		glog.V(logger.Info).Infof("Bloom bits index reorged, rolling back from %d to %d sections", stored, sections)
+		core.WriteBloomSections(b.db, sections)
+	}
+	var target uint64
+	if number+1 > b.confirms {
+		target = (number + 1 - b.confirms) / b.sectionSize
+	}
+	for ; sections < target; sections++ {
+		select {
+		case <-b.quit:
+			return
+		default:
+		}
+		start := time.Now()
+		if err := b.process(sections); err != nil {
+			glog.V(logger.Warn).Infof("Bloom bits section %d failed: %v", sections, err)
+			return
+		}
+		glog.V(logger.Debug).Infof("Bloom bits section %d indexed in %v", sections, time.Since(start))
+	}
+}
+
+// process indexes a section of the canonical chain. The headers are collected by
+// walking back from the head of the section, so a concurrent reorg can't mix the
+// blooms of different chains.
+func (b *BloomIndexer) process(section uint64) error {
+	last := (section+1)*b.sectionSize - 1
+	head := core.GetCanonicalHash(b.db, last)
+	if head == (common.Hash{}) {
+		return fmt.Errorf("missing canonical block #%d", last)
+	}
+	blooms := make([]types.Bloom, b.sectionSize)
+	hash := head
+	for i := int(b.sectionSize) - 1; i >= 0; i-- {
+		number := section*b.sectionSize + uint64(i)
+		header := core.GetHeader(b.db, hash, number)
+		if header == nil {
+			return fmt.Errorf("missing header #%d [%x…]", number, hash[:4])
+		}
+		blooms[i], hash = header.Bloom, header.ParentHash
+	}
+	gen, err := bloombits.NewGenerator(uint(b.sectionSize))
+	if err != nil {
+		return err
+	}
+	for i, bloom := range blooms {
+		if err := gen.AddBloom(uint(i), bloom); err != nil {
+			return err
+		}
+	}
+	bits := make([][]byte, bloombits.BloomBitLength)
+	for i := range bits {
+		if bits[i], err = gen.Bitset(uint(i)); err != nil {
+			return err
+		}
+	}
+	return core.WriteBloomSection(b.db, section, head, bits)
+}
+
+// GetBloomBits implements filters.BloomBackend, retrieving the bit vectors of
+// the given bloom bits of an indexed section of the canonical chain.
+func (b *BloomIndexer) GetBloomBits(ctx context.Context, section uint64, bits []uint) ([][]byte, error) {
+	head := core.GetCanonicalHash(b.db, (section+1)*b.sectionSize-1)
+	vectors := make([][]byte, len(bits))
+	for i, bit := range bits {
+		if vectors[i] = core.GetBloomBits(b.db, bit, section, head); vectors[i] == nil {
+			return nil, fmt.Errorf("bloom bits section %d not indexed", section)
+		}
+	}
+	return vectors, nil
+}
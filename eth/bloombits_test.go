@@ -0,0 +1,104 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/bloombits"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/core/vm"
+	"github.com/ur-technology/go-ur/ethdb"
+	"github.com/ur-technology/go-ur/event"
+	"github.com/ur-technology/go-ur/params"
+	"golang.org/x/net/context"
+)
+
+// insertBloomChain generates blocks on top of the parent, with a log of the
+// address in every block of the given interval, making them canonical.
+func insertBloomChain(db ethdb.Database, parent *types.Block, n, interval int, addr common.Address) []*types.Block {
+	blocks, receipts := core.GenerateChain(params.TestChainConfig, nil, parent, db, n, func(i int, gen *core.BlockGen) {
+		if (int(parent.NumberU64())+i+1)%interval == 0 {
+			receipt := types.NewReceipt(nil, new(big.Int))
+			receipt.Logs = vm.Logs{&vm.Log{Address: addr}}
+			gen.AddUncheckedReceipt(receipt)
+		}
+	})
+	for i, block := range blocks {
+		core.WriteBlock(db, block)
+		core.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		core.WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	core.WriteHeadHeaderHash(db, blocks[len(blocks)-1].Hash())
+	return blocks
+}
+
+// matchesIndex checks that the indexed sections match the given address in the
+// blocks of the interval only.
+func matchesIndex(t *testing.T, indexer *BloomIndexer, addr common.Address, interval int) {
+	size, sections := indexer.Status()
+	for section := uint64(0); section < sections; section++ {
+		bits := bloombits.BloomIndexes(addr.Bytes())
+		vectors, err := indexer.GetBloomBits(context.Background(), section, bits[:])
+		if err != nil {
+			t.Fatalf("section %d: %v", section, err)
+		}
+		for i := uint64(0); i < size; i++ {
+			match := vectors[0][i/8]&vectors[1][i/8]&vectors[2][i/8]&(0x80>>(i%8)) != 0
+			if want := (section*size+i)%uint64(interval) == 0 && section*size+i > 0; match != want {
+				t.Errorf("block #%d: match %v, want %v", section*size+i, match, want)
+			}
+		}
+	}
+}
+
+// Tests that the confirmed sections are indexed and that the sections reorged
+// out of the canonical chain are reindexed.
+func TestBloomIndexer(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		genesis = core.WriteGenesisBlockForTesting(db)
+		addr1   = common.BytesToAddress([]byte("addr1"))
+		addr2   = common.BytesToAddress([]byte("addr2"))
+	)
+	core.WriteCanonicalHash(db, genesis.Hash(), 0)
+	blocks := insertBloomChain(db, genesis, 75, 5, addr1)
+
+	// Sections are indexed once confirmed
+	indexer := NewBloomIndexer(db, new(event.TypeMux), 16, 8)
+	indexer.update()
+	if _, sections := indexer.Status(); sections != 4 {
+		t.Fatalf("indexed sections mismatch: have %d, want %d", sections, 4)
+	}
+	matchesIndex(t, indexer, addr1, 5)
+
+	// Reorg the last sections, which get reindexed
+	insertBloomChain(db, blocks[37], 50, 7, addr2)
+	indexer.update()
+	if _, sections := indexer.Status(); sections != 5 {
+		t.Fatalf("indexed sections mismatch: have %d, want %d", sections, 5)
+	}
+	if head := core.GetBloomSectionHead(db, 2); head != core.GetCanonicalHash(db, 47) {
+		t.Errorf("section 2 head mismatch: have %x, want %x", head, core.GetCanonicalHash(db, 47))
+	}
+	if _, err := indexer.GetBloomBits(context.Background(), 5, []uint{0}); err == nil {
+		t.Errorf("unindexed section retrieval succeeded")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+)
+
+// Checkpoint is a trusted block of the canonical chain. A node below it fast
+// syncs with the checkpoint as the pivot, without verifying the seals of the
+// headers before it, and refuses to sync from peers not containing it.
+type Checkpoint struct {
+	Number uint64      // Number of the checkpoint block
+	Hash   common.Hash // Hash of the checkpoint block
+	Td     *big.Int    // Total difficulty of the chain up to the checkpoint
+}
+
+// NewCheckpoint creates a checkpoint out of a block of the local chain.
+func NewCheckpoint(header *types.Header, td *big.Int) *Checkpoint {
+	return &Checkpoint{
+		Number: header.Number.Uint64(),
+		Hash:   header.Hash(),
+		Td:     new(big.Int).Set(td),
+	}
+}
+
+// ParseCheckpoint parses a checkpoint in its shareable "number:hash:td" format.
+func ParseCheckpoint(s string) (*Checkpoint, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid checkpoint %q, want <number>:<hash>:<td>", s)
+	}
+	number, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint number %q: %v", parts[0], err)
+	}
+	hash, err := hex.DecodeString(strings.TrimPrefix(parts[1], "0x"))
+	if err != nil || len(hash) != common.HashLength {
+		return nil, fmt.Errorf("invalid checkpoint hash %q", parts[1])
+	}
+	td, ok := new(big.Int).SetString(parts[2], 10)
+	if !ok || td.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid checkpoint total difficulty %q", parts[2])
+	}
+	return &Checkpoint{Number: number, Hash: common.BytesToHash(hash), Td: td}, nil
+}
+
+// String implements fmt.Stringer, returning the shareable format of the checkpoint.
+func (c *Checkpoint) String() string {
+	return fmt.Sprintf("%d:%s:%v", c.Number, c.Hash.Hex(), c.Td)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c *Checkpoint) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Checkpoint) UnmarshalText(text []byte) error {
+	cp, err := ParseCheckpoint(string(text))
+	if err != nil {
+		return err
+	}
+	*c = *cp
+	return nil
+}
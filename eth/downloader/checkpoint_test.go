@@ -0,0 +1,226 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"math/big"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/trie"
+)
+
+// Tests that checkpoints survive a round trip through their shareable format,
+// and that malformed ones are rejected.
+func TestCheckpointParsing(t *testing.T) {
+	checkpoint := &Checkpoint{Number: 1024, Hash: common.HexToHash("0xdeadbeef"), Td: big.NewInt(131072000)}
+
+	parsed, err := ParseCheckpoint(checkpoint.String())
+	if err != nil {
+		t.Fatalf("failed to parse checkpoint %s: %v", checkpoint, err)
+	}
+	if !reflect.DeepEqual(parsed, checkpoint) {
+		t.Fatalf("checkpoint mismatch: have %v, want %v", parsed, checkpoint)
+	}
+	for _, bad := range []string{
+		"",
+		"1024",
+		"1024:0x00000000000000000000000000000000000000000000000000000000deadbeef",
+		"x:0x00000000000000000000000000000000000000000000000000000000deadbeef:131072000",
+		"1024:0xdeadbeef:131072000",
+		"1024:0x00000000000000000000000000000000000000000000000000000000deadbeeg:131072000",
+		"1024:0x00000000000000000000000000000000000000000000000000000000deadbeef:0",
+		"1024:0x00000000000000000000000000000000000000000000000000000000deadbeef:131072000:1",
+	} {
+		if _, err := ParseCheckpoint(bad); err == nil {
+			t.Errorf("malformed checkpoint %q accepted", bad)
+		}
+	}
+}
+
+// checkpointOf creates a trusted checkpoint out of a block of a test chain.
+func checkpointOf(hash common.Hash, headers map[common.Hash]*types.Header) *Checkpoint {
+	td := new(big.Int)
+	for header := headers[hash]; header != nil; header = headers[header.ParentHash] {
+		td.Add(td, header.Difficulty)
+	}
+	return NewCheckpoint(headers[hash], td)
+}
+
+// Tests that a node below a trusted checkpoint fast syncs with the checkpoint as
+// the pivot, skipping the seal verification of the headers before it.
+func TestCheckpointSync63(t *testing.T) { testCheckpointSync(t, 63) }
+func TestCheckpointSync64(t *testing.T) { testCheckpointSync(t, 64) }
+
+func testCheckpointSync(t *testing.T, protocol int) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := 4 * MaxHeaderFetch
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+	tester.newPeer("peer", protocol, hashes, headers, blocks, receipts)
+
+	number := targetBlocks / 2
+	checkpoint := checkpointOf(hashes[len(hashes)-1-number], headers)
+	tester.downloader.SetCheckpoint(checkpoint)
+
+	// Track the seal verification frequencies of the imported headers
+	var (
+		lock     sync.Mutex
+		verified = make(map[uint64]bool)
+	)
+	tester.downloader.insertHeaders = func(headers []*types.Header, checkFreq int) (int, error) {
+		lock.Lock()
+		for _, header := range headers {
+			verified[header.Number.Uint64()] = checkFreq > 0
+		}
+		lock.Unlock()
+		return tester.insertHeaders(headers, checkFreq)
+	}
+	// Synchronise in full sync mode, which should be upgraded below the checkpoint
+	if err := tester.sync("peer", nil, FullSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	if tester.downloader.mode != FastSync {
+		t.Fatalf("sync mode mismatch: have %v, want %v", tester.downloader.mode, FastSync)
+	}
+	if pivot := tester.downloader.queue.FastSyncPivot(); pivot != checkpoint.Number {
+		t.Fatalf("pivot mismatch: have %d, want %d", pivot, checkpoint.Number)
+	}
+	if hs := len(tester.ownHeaders); hs != targetBlocks+1 {
+		t.Fatalf("synchronised headers mismatch: have %v, want %v", hs, targetBlocks+1)
+	}
+	if bs := len(tester.ownBlocks); bs != targetBlocks+1 {
+		t.Fatalf("synchronised blocks mismatch: have %v, want %v", bs, targetBlocks+1)
+	}
+	if _, err := trie.New(headers[checkpoint.Hash].Root, tester.stateDb); err != nil {
+		t.Fatalf("checkpoint state missing: %v", err)
+	}
+	for n := uint64(1); n <= uint64(targetBlocks); n++ {
+		if trusted := n <= checkpoint.Number; verified[n] == trusted {
+			t.Fatalf("header #%d: seal verified %v, want %v", n, verified[n], !trusted)
+		}
+	}
+	// Check that a subsequent sync is a plain full sync
+	if tester.downloader.pendingCheckpoint() != nil {
+		t.Fatalf("checkpoint pending after synchronisation")
+	}
+}
+
+// Tests that peers whose chain doesn't contain the checkpoint are refused, and
+// dropped if they're on a different chain.
+func TestCheckpointMismatch63(t *testing.T) { testCheckpointMismatch(t, 63) }
+func TestCheckpointMismatch64(t *testing.T) { testCheckpointMismatch(t, 64) }
+
+func testCheckpointMismatch(t *testing.T, protocol int) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	prefix, fork := MaxHeaderFetch, 2*MaxHeaderFetch
+	hashesA, hashesB, headersA, headersB, blocksA, blocksB, receiptsA, receiptsB := tester.makeChainFork(prefix+fork, fork, tester.genesis, nil, true)
+
+	tester.newPeer("fork A", protocol, hashesA, headersA, blocksA, receiptsA)
+	tester.newPeer("fork B", protocol, hashesB, headersB, blocksB, receiptsB)
+	tester.newPeer("short", protocol, hashesA[fork:], headersA, blocksA, receiptsA)
+
+	// Trust a block on fork A, past the common prefix
+	checkpoint := checkpointOf(hashesA[fork/2], headersA)
+	tester.downloader.SetCheckpoint(checkpoint)
+
+	if err := tester.sync("short", nil, FullSync); err != errCheckpointMissing {
+		t.Fatalf("short chain error mismatch: have %v, want %v", err, errCheckpointMissing)
+	}
+	if tester.downloader.peers.Peer("short") == nil {
+		t.Fatalf("peer with short chain dropped")
+	}
+	if err := tester.downloader.Synchronise("fork B", hashesB[0], tester.peerChainTds["fork B"][hashesB[0]], FullSync); err != errCheckpointMismatch {
+		t.Fatalf("forked chain error mismatch: have %v, want %v", err, errCheckpointMismatch)
+	}
+	if tester.downloader.peers.Peer("fork B") != nil {
+		t.Fatalf("peer with forked chain not dropped")
+	}
+	if len(tester.ownHashes) != 1 {
+		t.Fatalf("chain imported from refused peers: %d blocks", len(tester.ownHashes)-1)
+	}
+	if err := tester.sync("fork A", nil, FullSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	if head := tester.headBlock().Hash(); head != hashesA[0] {
+		t.Fatalf("head mismatch: have %x, want %x", head[:4], hashesA[0][:4])
+	}
+}
+
+// Tests that the headers imported without seal verification on the premise of
+// linking to the checkpoint are all rolled back if they don't, even beyond the
+// fast sync safety net.
+func TestCheckpointForgery63(t *testing.T)      { testCheckpointForgery(t, 63, FastSync) }
+func TestCheckpointForgery64(t *testing.T)      { testCheckpointForgery(t, 64, FastSync) }
+func TestCheckpointForgery64Light(t *testing.T) { testCheckpointForgery(t, 64, LightSync) }
+
+func testCheckpointForgery(t *testing.T, protocol int, mode SyncMode) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	// Create the canonical chain and a forged one, longer than the safety net
+	// before the checkpoint
+	number := 2*fsHeaderSafetyNet + MaxHeaderFetch/2
+	targetBlocks := number + MaxHeaderFetch
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+	forgedHashes, forgedHeaders, forgedBlocks, forgedReceipts := tester.makeChain(targetBlocks, 1, tester.genesis, nil, false)
+
+	checkpoint := checkpointOf(hashes[len(hashes)-1-number], headers)
+	tester.downloader.SetCheckpoint(checkpoint)
+
+	// Serve the forged chain, answering for the checkpoint with the real one
+	tester.newPeer("forger", protocol, forgedHashes, forgedHeaders, forgedBlocks, forgedReceipts)
+
+	tester.lock.Lock()
+	tester.peerHashes["forger"][len(forgedHashes)-1-number] = checkpoint.Hash
+	tester.peerHeaders["forger"][checkpoint.Hash] = headers[checkpoint.Hash]
+	tester.peerBlocks["forger"][checkpoint.Hash] = blocks[checkpoint.Hash]
+	tester.peerReceipts["forger"][checkpoint.Hash] = receipts[checkpoint.Hash]
+	tester.lock.Unlock()
+
+	if err := tester.sync("forger", nil, mode); err == nil {
+		t.Fatalf("succeeded forged chain synchronisation")
+	}
+	for hash := range tester.ownHeaders {
+		if header, ok := forgedHeaders[hash]; ok && header.Number.Sign() > 0 {
+			t.Fatalf("forged header #%d [%x…] not rolled back", header.Number, hash[:4])
+		}
+	}
+	if head := tester.headHeader().Number.Uint64(); head != 0 {
+		t.Fatalf("head header mismatch: have #%d, want #0", head)
+	}
+	// Make sure the canonical chain can still be synchronised
+	tester.newPeer("peer", protocol, hashes, headers, blocks, receipts)
+	if err := tester.sync("peer", nil, mode); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	if head := tester.headHeader().Hash(); head != hashes[0] {
+		t.Fatalf("head header mismatch: have %x, want %x", head[:4], hashes[0][:4])
+	}
+}
@@ -95,14 +95,23 @@ var (
 	errCancelContentProcessing = errors.New("content processing canceled (requested)")
 	errNoSyncActive            = errors.New("no sync active")
 	errTooOld                  = errors.New("peer doesn't speak recent enough protocol version (need version >= 62)")
+	errCheckpointMissing       = errors.New("peer chain doesn't reach the checkpoint")
+	errCheckpointMismatch      = errors.New("peer chain doesn't contain the checkpoint")
 )
 
 type Downloader struct {
-	mode SyncMode       // Synchronisation mode defining the strategy used (per sync cycle)
-	mux  *event.TypeMux // Event multiplexer to announce sync operation events
+	mode     SyncMode       // Synchronisation mode defining the strategy used (per sync cycle)
+	snapSync bool           // Whether the fast sync retrieves the pivot state in ranges (per sync cycle)
+	mux      *event.TypeMux // Event multiplexer to announce sync operation events
 
-	queue *queue   // Scheduler for selecting the hashes to download
-	peers *peerSet // Set of active peers from which download can proceed
+	queue   *queue         // Scheduler for selecting the hashes to download
+	peers   *peerSet       // Set of active peers from which download can proceed
+	stateDB ethdb.Database // Database to assemble the state retrieved in ranges into
+
+	snapPeers map[string]*snapPeer // [snap/1] Set of peers serving the state in ranges
+	snapLock  sync.RWMutex         // [snap/1] Lock protecting the snap peer set
+
+	checkpoint *Checkpoint // Trusted block to fast sync from while the local chain is below it
 
 	fsPivotLock  *types.Header // Pivot header on critical section entry (cannot change between retries)
 	fsPivotFails uint32        // Number of subsequent fast sync failures in the critical section
@@ -143,9 +152,13 @@ type Downloader struct {
 	bodyCh        chan dataPack        // [eth/62] Channel receiving inbound block bodies
 	receiptCh     chan dataPack        // [eth/63] Channel receiving inbound receipts
 	stateCh       chan dataPack        // [eth/63] Channel receiving inbound node state data
+	accountCh     chan dataPack        // [snap/1] Channel receiving inbound account ranges
+	storageCh     chan dataPack        // [snap/1] Channel receiving inbound storage ranges
+	codeCh        chan dataPack        // [snap/1] Channel receiving inbound contract codes
 	bodyWakeCh    chan bool            // [eth/62] Channel to signal the block body fetcher of new tasks
 	receiptWakeCh chan bool            // [eth/63] Channel to signal the receipt fetcher of new tasks
 	stateWakeCh   chan bool            // [eth/63] Channel to signal the state fetcher of new tasks
+	rangeWakeCh   chan bool            // [snap/1] Channel to signal the state range fetcher of new tasks
 	headerProcCh  chan []*types.Header // [eth/62] Channel to feed the header processor new tasks
 
 	// Cancellation and termination
@@ -174,6 +187,8 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, hasHeader he
 		mux:              mux,
 		queue:            newQueue(stateDb),
 		peers:            newPeerSet(),
+		stateDB:          stateDb,
+		snapPeers:        make(map[string]*snapPeer),
 		rttEstimate:      uint64(rttMaxEstimate),
 		rttConfidence:    uint64(1000000),
 		hasHeader:        hasHeader,
@@ -195,9 +210,13 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, hasHeader he
 		bodyCh:           make(chan dataPack, 1),
 		receiptCh:        make(chan dataPack, 1),
 		stateCh:          make(chan dataPack, 1),
+		accountCh:        make(chan dataPack, 1),
+		storageCh:        make(chan dataPack, 1),
+		codeCh:           make(chan dataPack, 1),
 		bodyWakeCh:       make(chan bool, 1),
 		receiptWakeCh:    make(chan bool, 1),
 		stateWakeCh:      make(chan bool, 1),
+		rangeWakeCh:      make(chan bool, 1),
 		headerProcCh:     make(chan []*types.Header, 1),
 		quitCh:           make(chan struct{}),
 	}
@@ -205,6 +224,32 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, hasHeader he
 	return dl
 }
 
+// SetCheckpoint configures a trusted checkpoint to sync from. While the local
+// chain is below it, the downloader fast syncs with the checkpoint as the pivot,
+// skipping the seal verification of the preceding headers. Peers whose chain
+// doesn't contain the checkpoint are refused as sync sources.
+func (d *Downloader) SetCheckpoint(checkpoint *Checkpoint) {
+	d.checkpoint = checkpoint
+}
+
+// pendingCheckpoint retrieves the trusted checkpoint if the local chain didn't
+// reach it yet, or nil otherwise.
+func (d *Downloader) pendingCheckpoint() *Checkpoint {
+	if d.checkpoint == nil {
+		return nil
+	}
+	var head uint64
+	if d.mode == LightSync {
+		head = d.headHeader().Number.Uint64()
+	} else {
+		head = d.headBlock().NumberU64()
+	}
+	if head >= d.checkpoint.Number {
+		return nil
+	}
+	return d.checkpoint
+}
+
 // Progress retrieves the synchronisation boundaries, specifically the origin
 // block where synchronisation started at (may have failed/suspended); the block
 // or header sync is currently at; and the latest known block which the sync targets.
@@ -244,13 +289,14 @@ func (d *Downloader) Synchronising() bool {
 }
 
 // RegisterPeer injects a new download peer into the set of block source to be
-// used for fetching hashes and blocks from.
+// used for fetching hashes and blocks from. The reputation of the peer, if any,
+// seeds its request sizes and timeouts, and is updated with its performance.
 func (d *Downloader) RegisterPeer(id string, version int, currentHead currentHeadRetrievalFn,
 	getRelHeaders relativeHeaderFetcherFn, getAbsHeaders absoluteHeaderFetcherFn, getBlockBodies blockBodyFetcherFn,
-	getReceipts receiptFetcherFn, getNodeData stateFetcherFn) error {
+	getReceipts receiptFetcherFn, getNodeData stateFetcherFn, rep Reputation) error {
 
 	glog.V(logger.Detail).Infoln("Registering peer", id)
-	if err := d.peers.Register(newPeer(id, version, currentHead, getRelHeaders, getAbsHeaders, getBlockBodies, getReceipts, getNodeData)); err != nil {
+	if err := d.peers.Register(newPeer(id, version, currentHead, getRelHeaders, getAbsHeaders, getBlockBodies, getReceipts, getNodeData, rep)); err != nil {
 		glog.V(logger.Error).Infoln("Register failed:", err)
 		return err
 	}
@@ -295,9 +341,14 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 	case errBusy:
 		glog.V(logger.Detail).Infof("Synchronisation already in progress")
 
-	case errTimeout, errBadPeer, errStallingPeer,
-		errEmptyHeaderSet, errPeersUnavailable, errTooOld,
-		errInvalidAncestor, errInvalidChain:
+	case errEmptyHeaderSet, errInvalidAncestor, errInvalidChain, errCheckpointMismatch:
+		glog.V(logger.Debug).Infof("Removing misbehaving peer %v: %v", id, err)
+		if p := d.peers.Peer(id); p != nil {
+			p.rep.Misbehaved()
+		}
+		d.dropPeer(id)
+
+	case errTimeout, errBadPeer, errStallingPeer, errPeersUnavailable, errTooOld:
 		glog.V(logger.Debug).Infof("Removing peer %v: %v", id, err)
 		d.dropPeer(id)
 
@@ -329,13 +380,13 @@ func (d *Downloader) synchronise(id string, hash common.Hash, td *big.Int, mode
 	d.queue.Reset()
 	d.peers.Reset()
 
-	for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh, d.stateWakeCh} {
+	for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh, d.stateWakeCh, d.rangeWakeCh} {
 		select {
 		case <-ch:
 		default:
 		}
 	}
-	for _, ch := range []chan dataPack{d.headerCh, d.bodyCh, d.receiptCh, d.stateCh} {
+	for _, ch := range []chan dataPack{d.headerCh, d.bodyCh, d.receiptCh, d.stateCh, d.accountCh, d.storageCh, d.codeCh} {
 		for empty := false; !empty; {
 			select {
 			case <-ch:
@@ -359,10 +410,17 @@ func (d *Downloader) synchronise(id string, hash common.Hash, td *big.Int, mode
 
 	defer d.cancel() // No matter what, we can't leave the cancel channel open
 
-	// Set the requested sync mode, unless it's forbidden
-	d.mode = mode
+	// Set the requested sync mode, unless it's forbidden. Snap sync runs as a fast
+	// sync, only retrieving the pivot state differently.
+	d.mode, d.snapSync = mode, false
+	if d.mode == SnapSync {
+		d.mode, d.snapSync = FastSync, true
+	}
+	if d.mode == FullSync && d.pendingCheckpoint() != nil {
+		d.mode = FastSync
+	}
 	if d.mode == FastSync && atomic.LoadUint32(&d.fsPivotFails) >= fsCriticalTrials {
-		d.mode = FullSync
+		d.mode, d.snapSync = FullSync, false
 	}
 	// Retrieve the origin peer and initiate the downloading process
 	p := d.peers.Peer(id)
@@ -400,6 +458,11 @@ func (d *Downloader) syncWithPeer(p *peer, hash common.Hash, td *big.Int) (err e
 	}
 	height := latest.Number.Uint64()
 
+	if cp := d.checkpoint; cp != nil {
+		if err := d.verifyCheckpoint(p, cp, height, td); err != nil {
+			return err
+		}
+	}
 	origin, err := d.findAncestor(p, height)
 	if err != nil {
 		return err
@@ -418,7 +481,10 @@ func (d *Downloader) syncWithPeer(p *peer, hash common.Hash, td *big.Int) (err e
 		pivot = height
 	case FastSync:
 		// Calculate the new fast/slow sync pivot point
-		if d.fsPivotLock == nil {
+		if cp := d.pendingCheckpoint(); cp != nil {
+			// Trusted checkpoint configured, retrieve its state
+			pivot = cp.Number
+		} else if d.fsPivotLock == nil {
 			pivotOffset, err := rand.Int(rand.Reader, big.NewInt(int64(fsPivotInterval)))
 			if err != nil {
 				panic(fmt.Sprintf("Failed to access crypto random source: %v", err))
@@ -440,7 +506,11 @@ func (d *Downloader) syncWithPeer(p *peer, hash common.Hash, td *big.Int) (err e
 		}
 		glog.V(logger.Debug).Infof("Fast syncing until pivot block #%d", pivot)
 	}
-	d.queue.Prepare(origin+1, d.mode, pivot, latest)
+	mode := d.mode
+	if d.snapSync {
+		mode = SnapSync
+	}
+	d.queue.Prepare(origin+1, mode, pivot, latest)
 	if d.syncInitHook != nil {
 		d.syncInitHook(origin, height)
 	}
@@ -450,6 +520,7 @@ func (d *Downloader) syncWithPeer(p *peer, hash common.Hash, td *big.Int) (err e
 		func() error { return d.fetchBodies(origin + 1) },      // Bodies are retrieved during normal and fast sync
 		func() error { return d.fetchReceipts(origin + 1) },    // Receipts are retrieved during fast sync
 		func() error { return d.fetchNodeData() },              // Node state data is retrieved during fast sync
+		func() error { return d.fetchStateRanges() },           // State ranges are retrieved during snap sync
 	)
 }
 
@@ -561,6 +632,52 @@ func (d *Downloader) fetchHeight(p *peer) (*types.Header, error) {
 	}
 }
 
+// verifyCheckpoint ensures that the chain of a remote peer contains the trusted
+// checkpoint, requesting the header at the checkpoint number from it.
+func (d *Downloader) verifyCheckpoint(p *peer, cp *Checkpoint, height uint64, td *big.Int) error {
+	if height < cp.Number || (td != nil && td.Cmp(cp.Td) < 0) {
+		glog.V(logger.Debug).Infof("%v: chain below checkpoint #%d", p, cp.Number)
+		return errCheckpointMissing
+	}
+	glog.V(logger.Debug).Infof("%v: verifying checkpoint #%d [%x…]", p, cp.Number, cp.Hash[:4])
+	go p.getAbsHeaders(cp.Number, 1, 0, false)
+
+	timeout := time.After(d.requestTTL())
+	for {
+		select {
+		case <-d.cancelCh:
+			return errCancelHeaderFetch
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				glog.V(logger.Debug).Infof("Received headers from incorrect peer(%s)", packet.PeerId())
+				break
+			}
+			// Make sure the peer gave the checkpoint header
+			headers := packet.(*headerPack).headers
+			if len(headers) != 1 {
+				glog.V(logger.Debug).Infof("%v: invalid number of checkpoint headers: %d != 1", p, len(headers))
+				return errBadPeer
+			}
+			if hash := headers[0].Hash(); headers[0].Number.Uint64() != cp.Number || hash != cp.Hash {
+				glog.V(logger.Debug).Infof("%v: checkpoint mismatch: have #%v [%x…], want #%d [%x…]", p, headers[0].Number, hash[:4], cp.Number, cp.Hash[:4])
+				return errCheckpointMismatch
+			}
+			return nil
+
+		case <-timeout:
+			glog.V(logger.Debug).Infof("%v: checkpoint header timeout", p)
+			return errTimeout
+
+		case <-d.bodyCh:
+		case <-d.stateCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
 // findAncestor tries to locate the common ancestor link of the local chain and
 // a remote peers blockchain. In the general case when our node was in sync and
 // on the correct chain, checking the top N links should already get us a match.
@@ -819,10 +936,11 @@ func (d *Downloader) fetchHeaders(p *peer, from uint64) error {
 			// Header retrieval timed out, consider the peer bad and drop
 			glog.V(logger.Debug).Infof("%v: header request timed out", p)
 			headerTimeoutMeter.Mark(1)
+			p.rep.TimedOut(headerKind)
 			d.dropPeer(p.id)
 
 			// Finish the sync gracefully instead of dumping the gathered data though
-			for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh, d.stateWakeCh} {
+			for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh, d.stateWakeCh, d.rangeWakeCh} {
 				select {
 				case ch <- false:
 				case <-d.cancelCh:
@@ -855,7 +973,7 @@ func (d *Downloader) fillHeaderSkeleton(from uint64, skeleton []*types.Header) (
 			pack := packet.(*headerPack)
 			return d.queue.DeliverHeaders(pack.peerId, pack.headers, d.headerProcCh)
 		}
-		expire   = func() map[string]int { return d.queue.ExpireHeaders(d.requestTTL()) }
+		expire   = func() map[string]int { return d.queue.ExpireHeaders(d.peerRequestTTL(headerKind)) }
 		throttle = func() bool { return false }
 		reserve  = func(p *peer, count int) (*fetchRequest, bool, error) {
 			return d.queue.ReserveHeaders(p, count), false, nil
@@ -885,7 +1003,7 @@ func (d *Downloader) fetchBodies(from uint64) error {
 			pack := packet.(*bodyPack)
 			return d.queue.DeliverBodies(pack.peerId, pack.transactions, pack.uncles)
 		}
-		expire   = func() map[string]int { return d.queue.ExpireBodies(d.requestTTL()) }
+		expire   = func() map[string]int { return d.queue.ExpireBodies(d.peerRequestTTL(bodyKind)) }
 		fetch    = func(p *peer, req *fetchRequest) error { return p.FetchBodies(req) }
 		capacity = func(p *peer) int { return p.BlockCapacity(d.requestRTT()) }
 		setIdle  = func(p *peer, accepted int) { p.SetBodiesIdle(accepted) }
@@ -909,7 +1027,7 @@ func (d *Downloader) fetchReceipts(from uint64) error {
 			pack := packet.(*receiptPack)
 			return d.queue.DeliverReceipts(pack.peerId, pack.receipts)
 		}
-		expire   = func() map[string]int { return d.queue.ExpireReceipts(d.requestTTL()) }
+		expire   = func() map[string]int { return d.queue.ExpireReceipts(d.peerRequestTTL(receiptKind)) }
 		fetch    = func(p *peer, req *fetchRequest) error { return p.FetchReceipts(req) }
 		capacity = func(p *peer) int { return p.ReceiptCapacity(d.requestRTT()) }
 		setIdle  = func(p *peer, accepted int) { p.SetReceiptsIdle(accepted) }
@@ -967,10 +1085,13 @@ func (d *Downloader) fetchNodeData() error {
 				}
 			})
 		}
-		expire   = func() map[string]int { return d.queue.ExpireNodeData(d.requestTTL()) }
+		expire   = func() map[string]int { return d.queue.ExpireNodeData(d.peerRequestTTL(stateKind)) }
 		throttle = func() bool { return false }
 		reserve  = func(p *peer, count int) (*fetchRequest, bool, error) {
-			return d.queue.ReserveNodeData(p, count), false, nil
+			// While the state is retrieved in ranges there's nothing to heal yet,
+			// but the retrieval is progressing
+			_, ranging := d.queue.StateRange()
+			return d.queue.ReserveNodeData(p, count), ranging, nil
 		}
 		fetch    = func(p *peer, req *fetchRequest) error { return p.FetchNodeData(req) }
 		capacity = func(p *peer) int { return p.NodeDataCapacity(d.requestRTT()) }
@@ -1036,6 +1157,9 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 				if err == errInvalidChain {
 					return err
 				}
+				if err == errInvalidBody || err == errInvalidReceipt {
+					peer.rep.Misbehaved()
+				}
 				// Unless a peer delivered something completely else than requested (usually
 				// caused by a timed out request which came through in the end), set it to
 				// idle. If the delivery's stale, the peer should have already been idled.
@@ -1084,6 +1208,8 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 			// Check for fetch request timeouts and demote the responsible peers
 			for pid, fails := range expire() {
 				if peer := d.peers.Peer(pid); peer != nil {
+					peer.rep.TimedOut(strings.ToLower(kind))
+
 					// If a lot of retrieval elements expired, we might have overestimated the remote peer or perhaps
 					// ourselves. Only reset to minimal throughput but don't drop just yet. If even the minimal times
 					// out that sync wise we need to get rid of the peer.
@@ -1170,14 +1296,20 @@ func (d *Downloader) processHeaders(origin uint64, td *big.Int) error {
 	// Calculate the pivoting point for switching from fast to slow sync
 	pivot := d.queue.FastSyncPivot()
 
-	// Keep a count of uncertain headers to roll back
+	// Keep a count of uncertain headers to roll back. Headers imported without
+	// seal checks on the promise of the trusted checkpoint are tracked separately
+	// and never trimmed, as none of them may stay if the checkpoint isn't reached.
 	rollback := []*types.Header{}
+	unverified := []*types.Header{}
 	defer func() {
-		if len(rollback) > 0 {
+		if len(unverified) > 0 || len(rollback) > 0 {
 			// Flatten the headers and roll them back
-			hashes := make([]common.Hash, len(rollback))
-			for i, header := range rollback {
-				hashes[i] = header.Hash()
+			hashes := make([]common.Hash, 0, len(unverified)+len(rollback))
+			for _, header := range unverified {
+				hashes = append(hashes, header.Hash())
+			}
+			for _, header := range rollback {
+				hashes = append(hashes, header.Hash())
 			}
 			lastHeader, lastFastBlock, lastBlock := d.headHeader().Number, common.Big0, common.Big0
 			if d.headFastBlock != nil {
@@ -1198,7 +1330,7 @@ func (d *Downloader) processHeaders(origin uint64, td *big.Int) error {
 				len(hashes), lastHeader, d.headHeader().Number, lastFastBlock, curFastBlock, lastBlock, curBlock)
 
 			// If we're already past the pivot point, this could be an attack, thread carefully
-			if rollback[len(rollback)-1].Number.Uint64() > pivot {
+			if len(rollback) > 0 && rollback[len(rollback)-1].Number.Uint64() > pivot {
 				// If we didn't ever fail, lock in te pivot header (must! not! change!)
 				if atomic.LoadUint32(&d.fsPivotFails) == 0 {
 					for _, header := range rollback {
@@ -1214,6 +1346,7 @@ func (d *Downloader) processHeaders(origin uint64, td *big.Int) error {
 
 	// Wait for batches of headers to process
 	gotHeaders := false
+	prev := common.Hash{}
 
 	for {
 		select {
@@ -1224,7 +1357,7 @@ func (d *Downloader) processHeaders(origin uint64, td *big.Int) error {
 			// Terminate header processing if we synced up
 			if len(headers) == 0 {
 				// Notify everyone that headers are fully processed
-				for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh, d.stateWakeCh} {
+				for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh, d.stateWakeCh, d.rangeWakeCh} {
 					select {
 					case ch <- false:
 					case <-d.cancelCh:
@@ -1259,6 +1392,12 @@ func (d *Downloader) processHeaders(origin uint64, td *big.Int) error {
 						return errStallingPeer
 					}
 				}
+				// If headers were imported on the promise of the checkpoint, but it never
+				// arrived, the peer bailed out before it could be caught lying
+				if len(unverified) > 0 {
+					glog.V(logger.Warn).Infof("Checkpoint #%d never delivered, dropping %d unverified headers", d.checkpoint.Number, len(unverified))
+					return errCheckpointMissing
+				}
 				// Disable any rollback and return
 				rollback = nil
 				return nil
@@ -1280,6 +1419,22 @@ func (d *Downloader) processHeaders(origin uint64, td *big.Int) error {
 				}
 				chunk := headers[:limit]
 
+				// Headers skipping the seal checks must link up to the checkpoint
+				if len(unverified) > 0 && chunk[0].ParentHash != prev {
+					glog.V(logger.Warn).Infof("Unlinked header #%v [%x…] before checkpoint", chunk[0].Number, chunk[0].Hash().Bytes()[:4])
+					return errInvalidChain
+				}
+				prev = chunk[len(chunk)-1].Hash()
+
+				// If the chunk spans the trusted checkpoint, make sure it's contained
+				reached := false
+				if cp := d.checkpoint; cp != nil && chunk[0].Number.Uint64() <= cp.Number && chunk[len(chunk)-1].Number.Uint64() >= cp.Number {
+					if header := chunk[int(cp.Number-chunk[0].Number.Uint64())]; header.Hash() != cp.Hash {
+						glog.V(logger.Warn).Infof("Checkpoint mismatch: have #%v [%x…], want #%v [%x…]", header.Number, header.Hash().Bytes()[:4], cp.Number, cp.Hash.Bytes()[:4])
+						return errInvalidChain
+					}
+					reached = true
+				}
 				// In case of header only syncing, validate the chunk immediately
 				if d.mode == FastSync || d.mode == LightSync {
 					// Collect the yet unknown headers to mark them as uncertain
@@ -1290,22 +1445,37 @@ func (d *Downloader) processHeaders(origin uint64, td *big.Int) error {
 						}
 					}
 					// If we're importing pure headers, verify based on their recentness
-					frequency := fsHeaderCheckFrequency
-					if chunk[len(chunk)-1].Number.Uint64()+uint64(fsHeaderForceVerify) > pivot {
+					frequency, trusted := fsHeaderCheckFrequency, false
+					switch {
+					case d.checkpoint != nil && chunk[len(chunk)-1].Number.Uint64() <= d.checkpoint.Number:
+						frequency, trusted = 0, true // Linked to the trusted checkpoint, skip the seals
+					case chunk[len(chunk)-1].Number.Uint64()+uint64(fsHeaderForceVerify) > pivot:
 						frequency = 1
 					}
 					if n, err := d.insertHeaders(chunk, frequency); err != nil {
 						// If some headers were inserted, add them too to the rollback list
 						if n > 0 {
-							rollback = append(rollback, chunk[:n]...)
+							if trusted {
+								unverified = append(unverified, chunk[:n]...)
+							} else {
+								rollback = append(rollback, chunk[:n]...)
+							}
 						}
 						glog.V(logger.Debug).Infof("invalid header #%d [%x…]: %v", chunk[n].Number, chunk[n].Hash().Bytes()[:4], err)
 						return errInvalidChain
 					}
 					// All verifications passed, store newly found uncertain headers
-					rollback = append(rollback, unknown...)
-					if len(rollback) > fsHeaderSafetyNet {
-						rollback = append(rollback[:0], rollback[len(rollback)-fsHeaderSafetyNet:]...)
+					if trusted {
+						unverified = append(unverified, unknown...)
+					} else {
+						rollback = append(rollback, unknown...)
+						if len(rollback) > fsHeaderSafetyNet {
+							rollback = append(rollback[:0], rollback[len(rollback)-fsHeaderSafetyNet:]...)
+						}
+					}
+					// Once the checkpoint is in, everything before it is linked to it
+					if reached {
+						unverified = nil
 					}
 				}
 				// If we're fast syncing and just pulled in the pivot, make sure it's the one locked in
@@ -1336,7 +1506,7 @@ func (d *Downloader) processHeaders(origin uint64, td *big.Int) error {
 				origin += uint64(limit)
 			}
 			// Signal the content downloaders of the availablility of new tasks
-			for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh, d.stateWakeCh} {
+			for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh, d.stateWakeCh, d.rangeWakeCh} {
 				select {
 				case ch <- true:
 				default:
@@ -1514,6 +1684,27 @@ func (d *Downloader) requestRTT() time.Duration {
 	return time.Duration(atomic.LoadUint64(&d.rttEstimate)) * 9 / 10
 }
 
+// peerRequestTTL returns the timeout allowance of requests of the given kind by
+// the id of the peer they were sent to. Peers measured to serve them slower than
+// the target round trip time are allowed proportionally more time, instead of
+// being timed out and dropped for it.
+func (d *Downloader) peerRequestTTL(kind string) func(string) time.Duration {
+	ttl := d.requestTTL()
+	conf := float64(atomic.LoadUint64(&d.rttConfidence)) / 1000000.0
+
+	return func(id string) time.Duration {
+		if p := d.peers.Peer(id); p != nil {
+			if scaled := time.Duration(ttlScaling) * time.Duration(float64(p.RequestRTT(kind))/conf); scaled > ttl {
+				if scaled > ttlLimit {
+					return ttlLimit
+				}
+				return scaled
+			}
+		}
+		return ttl
+	}
+}
+
 // requestTTL returns the current timeout allowance for a single download request
 // to finish under.
 func (d *Downloader) requestTTL() time.Duration {
@@ -69,6 +69,7 @@ type downloadTester struct {
 	peerChainTds map[string]map[common.Hash]*big.Int       // Total difficulties of the blocks in the peer chains
 
 	peerMissingStates map[string]map[common.Hash]bool // State entries that fast sync should not return
+	peerReputations   map[string]Reputation           // Reputations to register the test peers with
 
 	lock sync.RWMutex
 }
@@ -92,6 +93,7 @@ func newTester() *downloadTester {
 		peerReceipts:      make(map[string]map[common.Hash]types.Receipts),
 		peerChainTds:      make(map[string]map[common.Hash]*big.Int),
 		peerMissingStates: make(map[string]map[common.Hash]bool),
+		peerReputations:   make(map[string]Reputation),
 	}
 	tester.stateDb, _ = ethdb.NewMemDatabase()
 	tester.stateDb.Put(genesis.Root().Bytes(), []byte{0x00})
@@ -408,11 +410,11 @@ func (dl *downloadTester) newSlowPeer(id string, version int, hashes []common.Ha
 	var err error
 	switch version {
 	case 62:
-		err = dl.downloader.RegisterPeer(id, version, dl.peerCurrentHeadFn(id), dl.peerGetRelHeadersFn(id, delay), dl.peerGetAbsHeadersFn(id, delay), dl.peerGetBodiesFn(id, delay), nil, nil)
+		err = dl.downloader.RegisterPeer(id, version, dl.peerCurrentHeadFn(id), dl.peerGetRelHeadersFn(id, delay), dl.peerGetAbsHeadersFn(id, delay), dl.peerGetBodiesFn(id, delay), nil, nil, dl.peerReputations[id])
 	case 63:
-		err = dl.downloader.RegisterPeer(id, version, dl.peerCurrentHeadFn(id), dl.peerGetRelHeadersFn(id, delay), dl.peerGetAbsHeadersFn(id, delay), dl.peerGetBodiesFn(id, delay), dl.peerGetReceiptsFn(id, delay), dl.peerGetNodeDataFn(id, delay))
+		err = dl.downloader.RegisterPeer(id, version, dl.peerCurrentHeadFn(id), dl.peerGetRelHeadersFn(id, delay), dl.peerGetAbsHeadersFn(id, delay), dl.peerGetBodiesFn(id, delay), dl.peerGetReceiptsFn(id, delay), dl.peerGetNodeDataFn(id, delay), dl.peerReputations[id])
 	case 64:
-		err = dl.downloader.RegisterPeer(id, version, dl.peerCurrentHeadFn(id), dl.peerGetRelHeadersFn(id, delay), dl.peerGetAbsHeadersFn(id, delay), dl.peerGetBodiesFn(id, delay), dl.peerGetReceiptsFn(id, delay), dl.peerGetNodeDataFn(id, delay))
+		err = dl.downloader.RegisterPeer(id, version, dl.peerCurrentHeadFn(id), dl.peerGetRelHeadersFn(id, delay), dl.peerGetAbsHeadersFn(id, delay), dl.peerGetBodiesFn(id, delay), dl.peerGetReceiptsFn(id, delay), dl.peerGetNodeDataFn(id, delay), dl.peerReputations[id])
 	}
 	if err == nil {
 		// Assign the owned hashes, headers and blocks to the peer (deep copy)
@@ -469,6 +471,7 @@ func (dl *downloadTester) dropPeer(id string) {
 	delete(dl.peerChainTds, id)
 
 	dl.downloader.UnregisterPeer(id)
+	dl.downloader.UnregisterSnapPeer(id)
 }
 
 // peerCurrentHeadFn constructs a function to retrieve a peer's current head hash
@@ -1795,3 +1798,142 @@ func testFastCriticalRestarts(t *testing.T, protocol int, progress bool) {
 	// completed using a single mode of operation, whereas fast-then-slow can result
 	// in arbitrary intermediate state that's not cleanly verifiable.
 }
+
+// testReputation is a peer reputation with preset estimates, recording the
+// measurements reported into it.
+type testReputation struct {
+	throughput map[string]float64
+	latency    map[string]time.Duration
+
+	delivered map[string]int
+	timeouts  int
+	invalid   int
+	lock      sync.Mutex
+}
+
+func newTestReputation() *testReputation {
+	return &testReputation{
+		throughput: make(map[string]float64),
+		latency:    make(map[string]time.Duration),
+		delivered:  make(map[string]int),
+	}
+}
+
+func (r *testReputation) Throughput(kind string) float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.throughput[kind]
+}
+
+func (r *testReputation) Latency(kind string) time.Duration {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.latency[kind]
+}
+
+func (r *testReputation) Delivered(kind string, items int, elapsed time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.delivered[kind] += items
+}
+
+func (r *testReputation) TimedOut(kind string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.timeouts++
+}
+
+func (r *testReputation) Misbehaved() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.invalid++
+}
+
+// Tests that the request sizes and timeouts of peers are seeded from their
+// reputations, instead of starting from the minimum.
+func TestReputationSeeding(t *testing.T) {
+	tester := newTester()
+	defer tester.terminate()
+
+	rep := newTestReputation()
+	rep.throughput[headerKind] = 1000
+	rep.latency[""] = 3 * time.Second
+	rep.latency[headerKind] = 10 * time.Second
+	tester.peerReputations["known"] = rep
+
+	tester.newPeer("unknown", 63, []common.Hash{tester.genesis.Hash()}, nil, nil, nil)
+	tester.newPeer("known", 63, []common.Hash{tester.genesis.Hash()}, nil, nil, nil)
+
+	known, unknown := tester.downloader.peers.Peer("known"), tester.downloader.peers.Peer("unknown")
+	if have := known.HeaderCapacity(time.Second); have != MaxHeaderFetch {
+		t.Errorf("reputable header capacity mismatch: have %d, want %d", have, MaxHeaderFetch)
+	}
+	if have := known.BlockCapacity(time.Second); have != 2 {
+		t.Errorf("unmeasured block capacity mismatch: have %d, want %d", have, 2)
+	}
+	if have := unknown.HeaderCapacity(time.Second); have != 2 {
+		t.Errorf("unknown header capacity mismatch: have %d, want %d", have, 2)
+	}
+	if known.rtt != 3*time.Second {
+		t.Errorf("reputable rtt mismatch: have %v, want %v", known.rtt, 3*time.Second)
+	}
+	// Slow peers should be given more time to serve their requests
+	atomic.StoreUint64(&tester.downloader.rttEstimate, uint64(time.Second))
+	atomic.StoreUint64(&tester.downloader.rttConfidence, 1000000)
+
+	base := tester.downloader.requestTTL()
+	if have := tester.downloader.peerRequestTTL(headerKind)("known"); have <= base {
+		t.Errorf("slow peer header ttl not extended: have %v, base %v", have, base)
+	}
+	if have := tester.downloader.peerRequestTTL(bodyKind)("known"); have != base {
+		t.Errorf("unmeasured body ttl mismatch: have %v, want %v", have, base)
+	}
+	if have := tester.downloader.peerRequestTTL(headerKind)("unknown"); have != base {
+		t.Errorf("unknown peer header ttl mismatch: have %v, want %v", have, base)
+	}
+}
+
+// Tests that the deliveries of a peer and its misbehaviours are recorded into
+// its reputation.
+func TestReputationRecording63(t *testing.T) { testReputationRecording(t, 63, FastSync) }
+func TestReputationRecording64(t *testing.T) { testReputationRecording(t, 64, FastSync) }
+
+func testReputationRecording(t *testing.T, protocol int, mode SyncMode) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := blockCacheLimit - 15
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+
+	rep := newTestReputation()
+	tester.peerReputations["peer"] = rep
+	tester.newPeer("peer", protocol, hashes, headers, blocks, receipts)
+
+	if err := tester.sync("peer", nil, mode); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+
+	rep.lock.Lock()
+	for _, kind := range []string{headerKind, bodyKind, receiptKind} {
+		if rep.delivered[kind] == 0 {
+			t.Errorf("%s deliveries not recorded", kind)
+		}
+	}
+	if rep.timeouts != 0 || rep.invalid != 0 {
+		t.Errorf("failures recorded for a good peer: %d timeouts, %d invalid", rep.timeouts, rep.invalid)
+	}
+	rep.lock.Unlock()
+
+	// Peers failing a sync with an invalid chain should be recorded as misbehaving
+	tester.downloader.synchroniseMock = func(string, common.Hash) error { return errInvalidChain }
+	tester.downloader.Synchronise("peer", hashes[0], big.NewInt(1000), mode)
+
+	rep.lock.Lock()
+	if rep.invalid != 1 {
+		t.Errorf("misbehaviour count mismatch: have %d, want 1", rep.invalid)
+	}
+	rep.lock.Unlock()
+}
@@ -42,4 +42,9 @@ var (
 	stateReqTimer     = metrics.NewTimer("eth/downloader/states/req")
 	stateDropMeter    = metrics.NewMeter("eth/downloader/states/drop")
 	stateTimeoutMeter = metrics.NewMeter("eth/downloader/states/timeout")
+
+	rangeInMeter      = metrics.NewMeter("eth/downloader/ranges/in")
+	rangeReqTimer     = metrics.NewTimer("eth/downloader/ranges/req")
+	rangeDropMeter    = metrics.NewMeter("eth/downloader/ranges/drop")
+	rangeTimeoutMeter = metrics.NewMeter("eth/downloader/ranges/timeout")
 )
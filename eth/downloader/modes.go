@@ -16,6 +16,8 @@
 
 package downloader
 
+import "fmt"
+
 // SyncMode represents the synchronisation mode of the downloader.
 type SyncMode int
 
@@ -23,4 +25,51 @@ const (
 	FullSync  SyncMode = iota // Synchronise the entire blockchain history from full blocks
 	FastSync                  // Quickly download the headers, full sync only at the chain head
 	LightSync                 // Download only the headers and terminate afterwards
+	SnapSync                  // Fast sync retrieving the pivot state in proven ranges, healing the gaps
 )
+
+// IsValid tells whether the sync mode is one of the known ones.
+func (mode SyncMode) IsValid() bool {
+	return mode >= FullSync && mode <= SnapSync
+}
+
+// String implements the stringer interface.
+func (mode SyncMode) String() string {
+	switch mode {
+	case FullSync:
+		return "full"
+	case FastSync:
+		return "fast"
+	case LightSync:
+		return "light"
+	case SnapSync:
+		return "snap"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (mode SyncMode) MarshalText() ([]byte, error) {
+	if !mode.IsValid() {
+		return nil, fmt.Errorf("unknown sync mode %d", mode)
+	}
+	return []byte(mode.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (mode *SyncMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "full":
+		*mode = FullSync
+	case "fast":
+		*mode = FastSync
+	case "light":
+		*mode = LightSync
+	case "snap":
+		*mode = SnapSync
+	default:
+		return fmt.Errorf(`unknown sync mode %q, want "full", "fast", "light" or "snap"`, text)
+	}
+	return nil
+}
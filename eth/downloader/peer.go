@@ -48,6 +48,47 @@ type blockBodyFetcherFn func([]common.Hash) error
 type receiptFetcherFn func([]common.Hash) error
 type stateFetcherFn func([]common.Hash) error
 
+// Labels of the request kinds measured in peer reputations.
+const (
+	headerKind  = "header"
+	bodyKind    = "body"
+	receiptKind = "receipt"
+	stateKind   = "state"
+)
+
+// Reputation is the long lived record of how well a remote node served us,
+// surviving disconnects. The downloader seeds the throughput and latency
+// estimates of reconnecting peers from it, and reports its own measurements,
+// timeouts and invalid deliveries back into it.
+type Reputation interface {
+	// Throughput returns the number of items per second the node delivered for
+	// the given kind of requests, zero if unknown.
+	Throughput(kind string) float64
+
+	// Latency returns the response latency of the node for the given kind of
+	// requests, zero if unknown.
+	Latency(kind string) time.Duration
+
+	// Delivered records a request served with a number of items in elapsed time.
+	Delivered(kind string, items int, elapsed time.Duration)
+
+	// TimedOut records a request not served in time.
+	TimedOut(kind string)
+
+	// Misbehaved records an invalid or unrequested response.
+	Misbehaved()
+}
+
+// nopReputation is the reputation of peers registered without one, measuring
+// nothing.
+type nopReputation struct{}
+
+func (nopReputation) Throughput(string) float64            { return 0 }
+func (nopReputation) Latency(string) time.Duration         { return 0 }
+func (nopReputation) Delivered(string, int, time.Duration) {}
+func (nopReputation) TimedOut(string)                      {}
+func (nopReputation) Misbehaved()                          {}
+
 var (
 	errAlreadyFetching   = errors.New("already fetching blocks from peer")
 	errAlreadyRegistered = errors.New("peer is already registered")
@@ -68,7 +109,13 @@ type peer struct {
 	receiptThroughput float64 // Number of receipts measured to be retrievable per second
 	stateThroughput   float64 // Number of node data pieces measured to be retrievable per second
 
-	rtt time.Duration // Request round trip time to track responsiveness (QoS)
+	rtt        time.Duration // Request round trip time to track responsiveness (QoS)
+	headerRTT  time.Duration // Round trip time of header requests, zero if unmeasured
+	blockRTT   time.Duration // Round trip time of block (body) requests, zero if unmeasured
+	receiptRTT time.Duration // Round trip time of receipt requests, zero if unmeasured
+	stateRTT   time.Duration // Round trip time of node data requests, zero if unmeasured
+
+	rep Reputation // Persistent reputation of the remote node
 
 	headerStarted  time.Time // Time instance when the last header fetch was started
 	blockStarted   time.Time // Time instance when the last block (body) fetch was started
@@ -94,10 +141,14 @@ type peer struct {
 // mechanisms.
 func newPeer(id string, version int, currentHead currentHeadRetrievalFn,
 	getRelHeaders relativeHeaderFetcherFn, getAbsHeaders absoluteHeaderFetcherFn, getBlockBodies blockBodyFetcherFn,
-	getReceipts receiptFetcherFn, getNodeData stateFetcherFn) *peer {
+	getReceipts receiptFetcherFn, getNodeData stateFetcherFn, rep Reputation) *peer {
+	if rep == nil {
+		rep = nopReputation{}
+	}
 	return &peer{
 		id:      id,
 		lacking: make(map[common.Hash]struct{}),
+		rep:     rep,
 
 		currentHead:    currentHead,
 		getRelHeaders:  getRelHeaders,
@@ -111,7 +162,8 @@ func newPeer(id string, version int, currentHead currentHeadRetrievalFn,
 	}
 }
 
-// Reset clears the internal state of a peer entity.
+// Reset clears the internal state of a peer entity, falling back to the
+// throughputs recorded in its reputation.
 func (p *peer) Reset() {
 	p.lock.Lock()
 	defer p.lock.Unlock()
@@ -121,10 +173,10 @@ func (p *peer) Reset() {
 	atomic.StoreInt32(&p.receiptIdle, 0)
 	atomic.StoreInt32(&p.stateIdle, 0)
 
-	p.headerThroughput = 0
-	p.blockThroughput = 0
-	p.receiptThroughput = 0
-	p.stateThroughput = 0
+	p.headerThroughput = p.rep.Throughput(headerKind)
+	p.blockThroughput = p.rep.Throughput(bodyKind)
+	p.receiptThroughput = p.rep.Throughput(receiptKind)
+	p.stateThroughput = p.rep.Throughput(stateKind)
 
 	p.lacking = make(map[common.Hash]struct{})
 }
@@ -217,40 +269,41 @@ func (p *peer) FetchNodeData(request *fetchRequest) error {
 // requests. Its estimated header retrieval throughput is updated with that measured
 // just now.
 func (p *peer) SetHeadersIdle(delivered int) {
-	p.setIdle(p.headerStarted, delivered, &p.headerThroughput, &p.headerIdle)
+	p.setIdle(headerKind, p.headerStarted, delivered, &p.headerThroughput, &p.headerRTT, &p.headerIdle)
 }
 
 // SetBlocksIdle sets the peer to idle, allowing it to execute new block retrieval
 // requests. Its estimated block retrieval throughput is updated with that measured
 // just now.
 func (p *peer) SetBlocksIdle(delivered int) {
-	p.setIdle(p.blockStarted, delivered, &p.blockThroughput, &p.blockIdle)
+	p.setIdle(bodyKind, p.blockStarted, delivered, &p.blockThroughput, &p.blockRTT, &p.blockIdle)
 }
 
 // SetBodiesIdle sets the peer to idle, allowing it to execute block body retrieval
 // requests. Its estimated body retrieval throughput is updated with that measured
 // just now.
 func (p *peer) SetBodiesIdle(delivered int) {
-	p.setIdle(p.blockStarted, delivered, &p.blockThroughput, &p.blockIdle)
+	p.setIdle(bodyKind, p.blockStarted, delivered, &p.blockThroughput, &p.blockRTT, &p.blockIdle)
 }
 
 // SetReceiptsIdle sets the peer to idle, allowing it to execute new receipt
 // retrieval requests. Its estimated receipt retrieval throughput is updated
 // with that measured just now.
 func (p *peer) SetReceiptsIdle(delivered int) {
-	p.setIdle(p.receiptStarted, delivered, &p.receiptThroughput, &p.receiptIdle)
+	p.setIdle(receiptKind, p.receiptStarted, delivered, &p.receiptThroughput, &p.receiptRTT, &p.receiptIdle)
 }
 
 // SetNodeDataIdle sets the peer to idle, allowing it to execute new state trie
 // data retrieval requests. Its estimated state retrieval throughput is updated
 // with that measured just now.
 func (p *peer) SetNodeDataIdle(delivered int) {
-	p.setIdle(p.stateStarted, delivered, &p.stateThroughput, &p.stateIdle)
+	p.setIdle(stateKind, p.stateStarted, delivered, &p.stateThroughput, &p.stateRTT, &p.stateIdle)
 }
 
 // setIdle sets the peer to idle, allowing it to execute new retrieval requests.
-// Its estimated retrieval throughput is updated with that measured just now.
-func (p *peer) setIdle(started time.Time, delivered int, throughput *float64, idle *int32) {
+// Its estimated retrieval throughput and round trip time of the given kind are
+// updated with those measured just now, and recorded into its reputation.
+func (p *peer) setIdle(kind string, started time.Time, delivered int, throughput *float64, rtt *time.Duration, idle *int32) {
 	// Irrelevant of the scaling, make sure the peer ends up idle
 	defer atomic.StoreInt32(idle, 0)
 
@@ -268,6 +321,32 @@ func (p *peer) setIdle(started time.Time, delivered int, throughput *float64, id
 
 	*throughput = (1-measurementImpact)*(*throughput) + measurementImpact*measured
 	p.rtt = time.Duration((1-measurementImpact)*float64(p.rtt) + measurementImpact*float64(elapsed))
+
+	if *rtt == 0 {
+		*rtt = elapsed
+	} else {
+		*rtt = time.Duration((1-measurementImpact)*float64(*rtt) + measurementImpact*float64(elapsed))
+	}
+	p.rep.Delivered(kind, delivered, elapsed)
+}
+
+// RequestRTT retrieves the round trip time measured for requests of the given
+// kind, zero if none were served yet.
+func (p *peer) RequestRTT(kind string) time.Duration {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	switch kind {
+	case headerKind:
+		return p.headerRTT
+	case bodyKind:
+		return p.blockRTT
+	case receiptKind:
+		return p.receiptRTT
+	case stateKind:
+		return p.stateRTT
+	}
+	return 0
 }
 
 // HeaderCapacity retrieves the peers header download allowance based on its
@@ -375,12 +454,18 @@ func (ps *peerSet) Reset() {
 // Register injects a new peer into the working set, or returns an error if the
 // peer is already known.
 //
-// The method also sets the starting throughput values of the new peer to the
-// average of all existing peers, to give it a realistic chance of being used
-// for data retrievals.
+// The method also sets the starting throughput values of the new peer to those
+// recorded in its reputation, or if it has none, to the average of all existing
+// peers, to give it a realistic chance of being used for data retrievals.
 func (ps *peerSet) Register(p *peer) error {
-	// Retrieve the current median RTT as a sane default
-	p.rtt = ps.medianRTT()
+	// Retrieve the previously measured or the current median RTT as a sane default
+	if p.rtt = p.rep.Latency(""); p.rtt == 0 {
+		p.rtt = ps.medianRTT()
+	}
+	p.headerRTT = p.rep.Latency(headerKind)
+	p.blockRTT = p.rep.Latency(bodyKind)
+	p.receiptRTT = p.rep.Latency(receiptKind)
+	p.stateRTT = p.rep.Latency(stateKind)
 
 	// Register the new peer with some meaningful defaults
 	ps.lock.Lock()
@@ -405,6 +490,16 @@ func (ps *peerSet) Register(p *peer) error {
 		p.receiptThroughput /= float64(len(ps.peers))
 		p.stateThroughput /= float64(len(ps.peers))
 	}
+	for kind, throughput := range map[string]*float64{
+		headerKind:  &p.headerThroughput,
+		bodyKind:    &p.blockThroughput,
+		receiptKind: &p.receiptThroughput,
+		stateKind:   &p.stateThroughput,
+	} {
+		if known := p.rep.Throughput(kind); known > 0 {
+			*throughput = known
+		}
+	}
 	ps.peers[p.id] = p
 	return nil
 }
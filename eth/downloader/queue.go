@@ -106,6 +106,10 @@ type queue struct {
 	stateProcessors int32            // [eth/63] Number of currently running state processors
 	stateSchedLock  sync.RWMutex     // [eth/63] Lock serialising access to the state scheduler
 
+	stateRanges    bool        // [snap/1] Whether the pivot state is retrieved in ranges before healing
+	stateRanging   bool        // [snap/1] Whether the range retrieval of the pivot state is running
+	stateRangeRoot common.Hash // [snap/1] Root of the pivot state being retrieved in ranges
+
 	resultCache  []*fetchResult // Downloaded but not yet delivered fetch results
 	resultOffset uint64         // Offset of the first cached fetch result in the block chain
 
@@ -170,6 +174,10 @@ func (q *queue) Reset() {
 	q.statePendPool = make(map[string]*fetchRequest)
 	q.stateScheduler = nil
 
+	q.stateRanges = false
+	q.stateRanging = false
+	q.stateRangeRoot = common.Hash{}
+
 	q.resultCache = make([]*fetchResult, blockCacheLimit)
 	q.resultOffset = 0
 }
@@ -208,16 +216,44 @@ func (q *queue) PendingReceipts() int {
 }
 
 // PendingNodeData retrieves the number of node data entries pending for retrieval.
+// While the pivot state is retrieved in ranges, it is counted as a single entry
+// to hold back the pivot block until healing completes.
 func (q *queue) PendingNodeData() int {
 	q.stateSchedLock.RLock()
 	defer q.stateSchedLock.RUnlock()
 
+	if q.stateRanging {
+		return 1
+	}
 	if q.stateScheduler != nil {
 		return q.stateScheduler.Pending()
 	}
 	return 0
 }
 
+// StateRange retrieves the root of the pivot state if it's being retrieved in
+// ranges.
+func (q *queue) StateRange() (common.Hash, bool) {
+	q.stateSchedLock.RLock()
+	defer q.stateSchedLock.RUnlock()
+
+	return q.stateRangeRoot, q.stateRanging
+}
+
+// HealState ends the range retrieval of the pivot state, scheduling whatever is
+// still missing of it for retrieval by node data.
+func (q *queue) HealState() {
+	q.stateSchedLock.Lock()
+	if q.stateRanging {
+		q.stateScheduler = state.NewStateSync(q.stateRangeRoot, q.stateDatabase)
+		q.stateRanging = false
+	}
+	q.stateSchedLock.Unlock()
+
+	// The pivot block might be complete already, wake up WaitResults
+	q.active.Signal()
+}
+
 // InFlightHeaders retrieves whether there are header fetch requests currently
 // in flight.
 func (q *queue) InFlightHeaders() bool {
@@ -268,6 +304,9 @@ func (q *queue) Idle() bool {
 	if q.stateScheduler != nil {
 		queued += q.stateScheduler.Pending()
 	}
+	if q.stateRanging {
+		queued++
+	}
 	q.stateSchedLock.RUnlock()
 
 	return (queued + pending + cached) == 0
@@ -399,7 +438,12 @@ func (q *queue) Schedule(headers []*types.Header, from uint64) []*types.Header {
 			}
 
 			q.stateSchedLock.Lock()
-			q.stateScheduler = state.NewStateSync(header.Root, q.stateDatabase)
+			if q.stateRanges {
+				q.stateScheduler = nil
+				q.stateRanging, q.stateRangeRoot = true, header.Root
+			} else {
+				q.stateScheduler = state.NewStateSync(header.Root, q.stateDatabase)
+			}
 			q.stateSchedLock.Unlock()
 		}
 		inserts = append(inserts, header)
@@ -777,7 +821,7 @@ func (q *queue) Revoke(peerId string) {
 
 // ExpireHeaders checks for in flight requests that exceeded a timeout allowance,
 // canceling them and returning the responsible peers for penalisation.
-func (q *queue) ExpireHeaders(timeout time.Duration) map[string]int {
+func (q *queue) ExpireHeaders(timeout func(string) time.Duration) map[string]int {
 	q.lock.Lock()
 	defer q.lock.Unlock()
 
@@ -786,7 +830,7 @@ func (q *queue) ExpireHeaders(timeout time.Duration) map[string]int {
 
 // ExpireBodies checks for in flight block body requests that exceeded a timeout
 // allowance, canceling them and returning the responsible peers for penalisation.
-func (q *queue) ExpireBodies(timeout time.Duration) map[string]int {
+func (q *queue) ExpireBodies(timeout func(string) time.Duration) map[string]int {
 	q.lock.Lock()
 	defer q.lock.Unlock()
 
@@ -795,7 +839,7 @@ func (q *queue) ExpireBodies(timeout time.Duration) map[string]int {
 
 // ExpireReceipts checks for in flight receipt requests that exceeded a timeout
 // allowance, canceling them and returning the responsible peers for penalisation.
-func (q *queue) ExpireReceipts(timeout time.Duration) map[string]int {
+func (q *queue) ExpireReceipts(timeout func(string) time.Duration) map[string]int {
 	q.lock.Lock()
 	defer q.lock.Unlock()
 
@@ -804,7 +848,7 @@ func (q *queue) ExpireReceipts(timeout time.Duration) map[string]int {
 
 // ExpireNodeData checks for in flight node data requests that exceeded a timeout
 // allowance, canceling them and returning the responsible peers for penalisation.
-func (q *queue) ExpireNodeData(timeout time.Duration) map[string]int {
+func (q *queue) ExpireNodeData(timeout func(string) time.Duration) map[string]int {
 	q.lock.Lock()
 	defer q.lock.Unlock()
 
@@ -812,16 +856,17 @@ func (q *queue) ExpireNodeData(timeout time.Duration) map[string]int {
 }
 
 // expire is the generic check that move expired tasks from a pending pool back
-// into a task pool, returning all entities caught with expired tasks.
+// into a task pool, returning all entities caught with expired tasks. The
+// timeout allowance of each request is retrieved by the id of its peer.
 //
 // Note, this method expects the queue lock to be already held. The
 // reason the lock is not obtained in here is because the parameters already need
 // to access the queue, so they already need a lock anyway.
-func (q *queue) expire(timeout time.Duration, pendPool map[string]*fetchRequest, taskQueue *prque.Prque, timeoutMeter metrics.Meter) map[string]int {
+func (q *queue) expire(timeout func(string) time.Duration, pendPool map[string]*fetchRequest, taskQueue *prque.Prque, timeoutMeter metrics.Meter) map[string]int {
 	// Iterate over the expired requests and return each to the queue
 	expiries := make(map[string]int)
 	for id, request := range pendPool {
-		if time.Since(request.Time) > timeout {
+		if time.Since(request.Time) > timeout(id) {
 			// Update the metrics with the timeout
 			timeoutMeter.Mark(1)
 
@@ -1139,6 +1184,9 @@ func (q *queue) deliverNodeData(results []trie.SyncResult, callback func(int, bo
 
 // Prepare configures the result cache to allow accepting and caching inbound
 // fetch results.
+//
+// Snap sync is queued as a fast sync, only the state of the pivot block being
+// retrieved in ranges first.
 func (q *queue) Prepare(offset uint64, mode SyncMode, pivot uint64, head *types.Header) {
 	q.lock.Lock()
 	defer q.lock.Unlock()
@@ -1147,11 +1195,14 @@ func (q *queue) Prepare(offset uint64, mode SyncMode, pivot uint64, head *types.
 	if q.resultOffset < offset {
 		q.resultOffset = offset
 	}
+	if mode == SnapSync {
+		mode, q.stateRanges = FastSync, true
+	}
 	q.fastSyncPivot = pivot
 	q.mode = mode
 
 	// If long running fast sync, also start up a head stateretrieval immediately
-	if mode == FastSync && pivot > 0 {
+	if mode == FastSync && pivot > 0 && !q.stateRanges {
 		q.stateScheduler = state.NewStateSync(head.Root, q.stateDatabase)
 	}
 }
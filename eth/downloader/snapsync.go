@@ -0,0 +1,657 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the snap sync state retrieval: instead of pulling the state trie of
+// the fast sync pivot node by node, it's pulled in ranges of consecutive accounts
+// and storage slots proven by the merkle proofs of their edges. Whatever the
+// ranges don't cover (peers not serving them, or not having the pivot state)
+// is healed afterwards by the node data retrieval, which skips all the sub-tries
+// already assembled.
+
+package downloader
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/state"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+	"github.com/ur-technology/go-ur/rlp"
+	"github.com/ur-technology/go-ur/trie"
+)
+
+var (
+	MaxStorageFetch = 64  // Amount of accounts whose storage to allow fetching per request
+	MaxCodeFetch    = 64  // Amount of contract codes to allow fetching per request
+	MaxRangeBytes   = 512 // Soft limit in KB on the size of a range response
+
+	snapAccountChunks = 16 // Number of chunks of the account space retrieved concurrently
+)
+
+var (
+	errSnapInvalidRange = errors.New("retrieved state range is invalid")
+	emptyCodeHash       = crypto.Keccak256Hash(nil)
+)
+
+// Snap state range fetchers belonging to snap/1
+type accountRangeFetcherFn func(root common.Hash, origin common.Hash, limit common.Hash, bytes uint64) error
+type storageRangesFetcherFn func(root common.Hash, accounts []common.Hash, origin common.Hash, bytes uint64) error
+type byteCodesFetcherFn func(hashes []common.Hash, bytes uint64) error
+
+// snapPeer is a peer serving the state in proven ranges.
+type snapPeer struct {
+	id string
+
+	getAccountRange  accountRangeFetcherFn  // [snap/1] Method to request a range of accounts
+	getStorageRanges storageRangesFetcherFn // [snap/1] Method to request the storage ranges of accounts
+	getByteCodes     byteCodesFetcherFn     // [snap/1] Method to request a batch of contract codes
+}
+
+// RegisterSnapPeer injects a new peer into the set of peers to retrieve the state
+// ranges from during snap sync.
+func (d *Downloader) RegisterSnapPeer(id string, getAccountRange accountRangeFetcherFn, getStorageRanges storageRangesFetcherFn, getByteCodes byteCodesFetcherFn) error {
+	d.snapLock.Lock()
+	defer d.snapLock.Unlock()
+
+	glog.V(logger.Detail).Infoln("Registering snap peer", id)
+	if _, ok := d.snapPeers[id]; ok {
+		return errAlreadyRegistered
+	}
+	d.snapPeers[id] = &snapPeer{
+		id:               id,
+		getAccountRange:  getAccountRange,
+		getStorageRanges: getStorageRanges,
+		getByteCodes:     getByteCodes,
+	}
+	return nil
+}
+
+// UnregisterSnapPeer removes a peer from the snap peer set. Its pending range
+// requests are rescheduled by the range fetcher.
+func (d *Downloader) UnregisterSnapPeer(id string) error {
+	d.snapLock.Lock()
+	defer d.snapLock.Unlock()
+
+	glog.V(logger.Detail).Infoln("Unregistering snap peer", id)
+	if _, ok := d.snapPeers[id]; !ok {
+		return errNotRegistered
+	}
+	delete(d.snapPeers, id)
+	return nil
+}
+
+// snapPeer retrieves a registered snap peer.
+func (d *Downloader) snapPeer(id string) *snapPeer {
+	d.snapLock.RLock()
+	defer d.snapLock.RUnlock()
+
+	return d.snapPeers[id]
+}
+
+// DeliverAccountRange injects a range of accounts received from a remote node.
+func (d *Downloader) DeliverAccountRange(id string, hashes []common.Hash, accounts [][]byte, proof []rlp.RawValue) error {
+	return d.deliver(id, d.accountCh, &accountRangePack{id, hashes, accounts, proof}, rangeInMeter, rangeDropMeter)
+}
+
+// DeliverStorageRanges injects a batch of storage ranges received from a remote node.
+func (d *Downloader) DeliverStorageRanges(id string, hashes [][]common.Hash, slots [][][]byte, proof []rlp.RawValue) error {
+	return d.deliver(id, d.storageCh, &storageRangesPack{id, hashes, slots, proof}, rangeInMeter, rangeDropMeter)
+}
+
+// DeliverByteCodes injects a batch of contract codes received from a remote node.
+func (d *Downloader) DeliverByteCodes(id string, codes [][]byte) error {
+	return d.deliver(id, d.codeCh, &byteCodesPack{id, codes}, rangeInMeter, rangeDropMeter)
+}
+
+// accountTask is a chunk of the account space to retrieve.
+type accountTask struct {
+	next    common.Hash // Hash of the next account to retrieve
+	last    common.Hash // Hash of the last account of the chunk
+	pending bool        // Whether a request for the chunk is in flight
+	done    bool        // Whether the chunk has been fully retrieved
+}
+
+// storageTask is the storage trie of an account to retrieve.
+type storageTask struct {
+	root    common.Hash // Root of the storage trie
+	next    common.Hash // Hash of the next slot to retrieve
+	trie    *trie.Trie  // Storage trie assembled so far
+	pending bool        // Whether a request for the storage is in flight
+}
+
+// rangeRequest is a state range request in flight.
+type rangeRequest struct {
+	peer     string
+	time     time.Time
+	account  *accountTask  // Account chunk requested, if an account range request
+	storage  []common.Hash // Accounts whose storage was requested, if a storage request
+	codes    []common.Hash // Hashes of the codes requested, if a code request
+	isCode   bool          // Whether the request is a code request
+	isStore  bool          // Whether the request is a storage request
+	isRanged bool          // Whether the storage request continues a partially retrieved trie
+}
+
+// rangeSync is the state of the range retrieval of the pivot state.
+type rangeSync struct {
+	root     common.Hash
+	accounts *trie.Trie // Account trie assembled from the ranges, committed once complete
+
+	accountTasks []*accountTask
+	storageTasks map[common.Hash]*storageTask // Storage retrievals keyed by account hash
+	codeTasks    map[common.Hash]bool         // Contract code retrievals, flagged if in flight
+
+	requests  map[string]*rangeRequest // Requests in flight, keyed by peer
+	stateless map[string]bool          // Peers not serving the pivot state
+
+	started time.Time
+	entries uint64 // Number of state entries retrieved
+	done    bool
+}
+
+// newRangeSync creates the range retrieval of the state with the given root,
+// splitting the account space into equal chunks.
+func (d *Downloader) newRangeSync(root common.Hash) *rangeSync {
+	accounts, _ := trie.New(common.Hash{}, d.stateDB)
+	s := &rangeSync{
+		root:         root,
+		accounts:     accounts,
+		storageTasks: make(map[common.Hash]*storageTask),
+		codeTasks:    make(map[common.Hash]bool),
+		requests:     make(map[string]*rangeRequest),
+		stateless:    make(map[string]bool),
+		started:      time.Now(),
+	}
+	// A state already present (e.g. retrieved by a previous attempt) needs nothing
+	if blob, _ := d.stateDB.Get(root[:]); blob != nil {
+		return s
+	}
+	step := new(big.Int).Div(new(big.Int).Lsh(common.Big1, 256), big.NewInt(int64(snapAccountChunks)))
+	next := new(big.Int)
+	for i := 0; i < snapAccountChunks; i++ {
+		last := new(big.Int).Sub(new(big.Int).Add(next, step), common.Big1)
+		if i == snapAccountChunks-1 {
+			last = new(big.Int).Sub(new(big.Int).Lsh(common.Big1, 256), common.Big1)
+		}
+		s.accountTasks = append(s.accountTasks, &accountTask{
+			next: common.BigToHash(next),
+			last: common.BigToHash(last),
+		})
+		next = new(big.Int).Add(last, common.Big1)
+	}
+	return s
+}
+
+// complete tells whether the whole state has been retrieved.
+func (s *rangeSync) complete() bool {
+	for _, task := range s.accountTasks {
+		if !task.done {
+			return false
+		}
+	}
+	return len(s.storageTasks) == 0 && len(s.codeTasks) == 0
+}
+
+// fetchStateRanges retrieves the state of the fast sync pivot in ranges during
+// snap sync, handing over to the node data retrieval once done or once there's
+// no peer left to serve the ranges. In other modes it only waits for the header
+// processing to end.
+func (d *Downloader) fetchStateRanges() error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	var (
+		ranges   *rangeSync
+		finished bool
+	)
+	for {
+		// Start the range retrieval once the pivot block has been scheduled
+		if ranges == nil && d.snapSync {
+			if root, ok := d.queue.StateRange(); ok {
+				glog.V(logger.Debug).Infof("Retrieving state ranges of [%x…]", root[:4])
+				ranges = d.newRangeSync(root)
+			}
+		}
+		if ranges != nil && !ranges.done {
+			if !d.assignRangeTasks(ranges) && len(ranges.requests) == 0 {
+				d.finishRangeSync(ranges)
+			}
+		}
+		if finished && (ranges == nil || ranges.done) {
+			glog.V(logger.Debug).Infof("State range fetching completed")
+			return nil
+		}
+		select {
+		case <-d.cancelCh:
+			return errCancelStateFetch
+
+		case cont := <-d.rangeWakeCh:
+			if !cont {
+				finished = true
+			}
+
+		case packet := <-d.accountCh:
+			if ranges != nil && !ranges.done {
+				d.processAccountRange(ranges, packet.(*accountRangePack))
+			}
+
+		case packet := <-d.storageCh:
+			if ranges != nil && !ranges.done {
+				d.processStorageRanges(ranges, packet.(*storageRangesPack))
+			}
+
+		case packet := <-d.codeCh:
+			if ranges != nil && !ranges.done {
+				d.processByteCodes(ranges, packet.(*byteCodesPack))
+			}
+
+		case <-ticker.C:
+			if ranges != nil && !ranges.done {
+				d.expireRangeRequests(ranges)
+			}
+		}
+	}
+}
+
+// assignRangeTasks sends a request to every idle snap peer, returning whether
+// the retrieval can make any progress: there's work left and some peer to do it.
+func (d *Downloader) assignRangeTasks(s *rangeSync) bool {
+	if s.complete() {
+		return false
+	}
+	d.snapLock.RLock()
+	peers := make([]*snapPeer, 0, len(d.snapPeers))
+	for _, p := range d.snapPeers {
+		if !s.stateless[p.id] {
+			peers = append(peers, p)
+		}
+	}
+	d.snapLock.RUnlock()
+
+	if len(peers) == 0 {
+		return false
+	}
+	bytes := uint64(MaxRangeBytes) * 1024
+	for _, p := range peers {
+		if _, busy := s.requests[p.id]; busy {
+			continue
+		}
+		req := &rangeRequest{peer: p.id, time: time.Now()}
+		var err error
+
+		if task := s.nextAccountTask(); task != nil {
+			req.account, task.pending = task, true
+			err = p.getAccountRange(s.root, task.next, task.last, bytes)
+		} else if accounts, origin := s.nextStorageTasks(); len(accounts) > 0 {
+			req.storage, req.isStore, req.isRanged = accounts, true, origin != (common.Hash{})
+			err = p.getStorageRanges(s.root, accounts, origin, bytes)
+		} else if hashes := s.nextCodeTasks(); len(hashes) > 0 {
+			req.codes, req.isCode = hashes, true
+			err = p.getByteCodes(hashes, bytes)
+		} else {
+			break
+		}
+		s.requests[p.id] = req
+		if err != nil {
+			glog.V(logger.Debug).Infof("snap peer %s: range request failed: %v", p.id, err)
+			s.revert(req)
+			s.stateless[p.id] = true
+		}
+	}
+	return true
+}
+
+// nextAccountTask retrieves the next account chunk to request.
+func (s *rangeSync) nextAccountTask() *accountTask {
+	for _, task := range s.accountTasks {
+		if !task.done && !task.pending {
+			return task
+		}
+	}
+	return nil
+}
+
+// nextStorageTasks retrieves the next storage tries to request: either one
+// partially retrieved trie, continuing at the returned origin, or a batch of
+// tries yet untouched.
+func (s *rangeSync) nextStorageTasks() ([]common.Hash, common.Hash) {
+	var accounts []common.Hash
+	for hash, task := range s.storageTasks {
+		if task.pending {
+			continue
+		}
+		if task.next != (common.Hash{}) {
+			if len(accounts) == 0 {
+				task.pending = true
+				return []common.Hash{hash}, task.next
+			}
+			continue
+		}
+		task.pending = true
+		if accounts = append(accounts, hash); len(accounts) >= MaxStorageFetch {
+			break
+		}
+	}
+	return accounts, common.Hash{}
+}
+
+// nextCodeTasks retrieves the next batch of contract codes to request.
+func (s *rangeSync) nextCodeTasks() []common.Hash {
+	var hashes []common.Hash
+	for hash, pending := range s.codeTasks {
+		if pending {
+			continue
+		}
+		s.codeTasks[hash] = true
+		if hashes = append(hashes, hash); len(hashes) >= MaxCodeFetch {
+			break
+		}
+	}
+	return hashes
+}
+
+// revert returns the tasks of a failed or expired request for rescheduling.
+func (s *rangeSync) revert(req *rangeRequest) {
+	delete(s.requests, req.peer)
+
+	if req.account != nil {
+		req.account.pending = false
+	}
+	for _, hash := range req.storage {
+		if task := s.storageTasks[hash]; task != nil {
+			task.pending = false
+		}
+	}
+	for _, hash := range req.codes {
+		if _, ok := s.codeTasks[hash]; ok {
+			s.codeTasks[hash] = false
+		}
+	}
+}
+
+// expireRangeRequests reschedules the requests that timed out or whose peer left,
+// excluding the timed out peers from the rest of the retrieval.
+func (d *Downloader) expireRangeRequests(s *rangeSync) {
+	ttl := d.requestTTL()
+	for id, req := range s.requests {
+		switch {
+		case d.snapPeer(id) == nil:
+			s.revert(req)
+		case time.Since(req.time) > ttl:
+			glog.V(logger.Debug).Infof("snap peer %s: state range request timed out", id)
+			rangeTimeoutMeter.Mark(1)
+			s.revert(req)
+			s.stateless[id] = true
+		}
+	}
+}
+
+// takeRequest retrieves and removes the request a delivery answers, checking
+// that it's of the expected kind.
+func (s *rangeSync) takeRequest(id string, account, storage, code bool) *rangeRequest {
+	req := s.requests[id]
+	if req == nil || (req.account != nil) != account || req.isStore != storage || req.isCode != code {
+		return nil
+	}
+	rangeReqTimer.UpdateSince(req.time)
+	return req
+}
+
+// rejectRange reverts a request answered with an invalid range and drops the
+// peer that sent it.
+func (d *Downloader) rejectRange(s *rangeSync, req *rangeRequest, err error) {
+	glog.V(logger.Debug).Infof("snap peer %s: %v", req.peer, err)
+	s.revert(req)
+	s.stateless[req.peer] = true
+	d.dropPeer(req.peer)
+}
+
+// processAccountRange verifies a range of accounts and integrates it into the
+// account trie, scheduling the retrieval of the storage and code of the accounts.
+func (d *Downloader) processAccountRange(s *rangeSync, pack *accountRangePack) {
+	req := s.takeRequest(pack.peerId, true, false, false)
+	if req == nil {
+		return
+	}
+	task := req.account
+
+	// An empty range without a proof means the peer doesn't have the state
+	if len(pack.hashes) == 0 && len(pack.proof) == 0 {
+		glog.V(logger.Detail).Infof("snap peer %s: state [%x…] unavailable", pack.peerId, s.root[:4])
+		s.revert(req)
+		s.stateless[pack.peerId] = true
+		return
+	}
+	keys := make([][]byte, len(pack.hashes))
+	for i, hash := range pack.hashes {
+		keys[i] = common.CopyBytes(hash[:])
+	}
+	more, err := trie.VerifyRangeProof(s.root, task.next[:], keys, pack.accounts, pack.proof)
+	if err != nil {
+		d.rejectRange(s, req, err)
+		return
+	}
+	// Decode all the accounts before touching anything
+	accounts := make([]state.Account, len(pack.accounts))
+	for i, blob := range pack.accounts {
+		if err := rlp.DecodeBytes(blob, &accounts[i]); err != nil {
+			d.rejectRange(s, req, errSnapInvalidRange)
+			return
+		}
+	}
+	delete(s.requests, req.peer)
+	task.pending = false
+
+	// Integrate the accounts of the chunk, those past it belong to the next one
+	for i, hash := range pack.hashes {
+		if bytes.Compare(hash[:], task.last[:]) > 0 {
+			more = false
+			break
+		}
+		s.accounts.Update(hash[:], pack.accounts[i])
+		s.entries++
+
+		if root := accounts[i].Root; root != types.EmptyRootHash {
+			if blob, _ := d.stateDB.Get(root[:]); blob == nil {
+				storage, _ := trie.New(common.Hash{}, d.stateDB)
+				s.storageTasks[hash] = &storageTask{root: root, trie: storage}
+			}
+		}
+		if code := common.BytesToHash(accounts[i].CodeHash); code != emptyCodeHash {
+			if blob, _ := d.stateDB.Get(code[:]); blob == nil {
+				if _, ok := s.codeTasks[code]; !ok {
+					s.codeTasks[code] = false
+				}
+			}
+		}
+	}
+	if more && pack.hashes[len(pack.hashes)-1] != task.last {
+		task.next = incHash(pack.hashes[len(pack.hashes)-1])
+	} else {
+		task.done = true
+	}
+	d.reportRangeProgress(s, len(pack.hashes))
+}
+
+// processStorageRanges verifies a batch of storage ranges and integrates them
+// into the storage tries of their accounts, committing the completed tries.
+func (d *Downloader) processStorageRanges(s *rangeSync, pack *storageRangesPack) {
+	req := s.takeRequest(pack.peerId, false, true, false)
+	if req == nil {
+		return
+	}
+	if len(pack.hashes) == 0 && len(pack.proof) == 0 {
+		glog.V(logger.Detail).Infof("snap peer %s: state [%x…] unavailable", pack.peerId, s.root[:4])
+		s.revert(req)
+		s.stateless[pack.peerId] = true
+		return
+	}
+	if len(pack.hashes) > len(req.storage) || len(pack.hashes) != len(pack.slots) {
+		d.rejectRange(s, req, errSnapInvalidRange)
+		return
+	}
+	// Verify all the ranges before touching anything, only the last one may be
+	// partial (and proven)
+	more := make([]bool, len(pack.hashes))
+	for i, hashes := range pack.hashes {
+		task := s.storageTasks[req.storage[i]]
+
+		var (
+			origin common.Hash
+			proof  []rlp.RawValue
+		)
+		if req.isRanged {
+			origin = task.next
+		}
+		if i == len(pack.hashes)-1 {
+			proof = pack.proof
+		}
+		keys := make([][]byte, len(hashes))
+		for j, hash := range hashes {
+			keys[j] = common.CopyBytes(hash[:])
+		}
+		var err error
+		if more[i], err = trie.VerifyRangeProof(task.root, origin[:], keys, pack.slots[i], proof); err != nil {
+			d.rejectRange(s, req, err)
+			return
+		}
+	}
+	s.revert(req) // Return the accounts not served, the served ones are done with below
+
+	items := 0
+	for i, hashes := range pack.hashes {
+		account := req.storage[i]
+		task := s.storageTasks[account]
+		for j, hash := range hashes {
+			task.trie.Update(hash[:], pack.slots[i][j])
+		}
+		items += len(hashes)
+
+		if more[i] {
+			task.next = incHash(hashes[len(hashes)-1])
+			continue
+		}
+		root, err := task.trie.Commit()
+		if err != nil {
+			glog.V(logger.Error).Infof("Failed to commit storage trie of %x: %v", account[:4], err)
+			d.cancel()
+			return
+		}
+		if root != task.root {
+			// Can't happen with verified ranges, unless the database is broken
+			glog.V(logger.Error).Infof("Storage trie of %x: root mismatch: have %x, want %x", account[:4], root, task.root)
+			d.cancel()
+			return
+		}
+		delete(s.storageTasks, account)
+	}
+	s.entries += uint64(items)
+	d.reportRangeProgress(s, items)
+}
+
+// processByteCodes verifies a batch of contract codes and writes them into the
+// state database.
+func (d *Downloader) processByteCodes(s *rangeSync, pack *byteCodesPack) {
+	req := s.takeRequest(pack.peerId, false, false, true)
+	if req == nil {
+		return
+	}
+	if len(pack.codes) == 0 {
+		glog.V(logger.Detail).Infof("snap peer %s: state [%x…] unavailable", pack.peerId, s.root[:4])
+		s.revert(req)
+		s.stateless[pack.peerId] = true
+		return
+	}
+	requested := make(map[common.Hash]bool, len(req.codes))
+	for _, hash := range req.codes {
+		requested[hash] = true
+	}
+	batch := d.stateDB.NewBatch()
+	for _, code := range pack.codes {
+		hash := crypto.Keccak256Hash(code)
+		if !requested[hash] {
+			d.rejectRange(s, req, errSnapInvalidRange)
+			return
+		}
+		batch.Put(hash[:], code)
+	}
+	if err := batch.Write(); err != nil {
+		glog.V(logger.Error).Infof("Failed to write contract codes: %v", err)
+		d.cancel()
+		return
+	}
+	s.revert(req)
+	for _, code := range pack.codes {
+		delete(s.codeTasks, crypto.Keccak256Hash(code))
+	}
+	s.entries += uint64(len(pack.codes))
+	d.reportRangeProgress(s, len(pack.codes))
+}
+
+// finishRangeSync ends the range retrieval, committing the account trie if the
+// whole state has been retrieved, and hands over to the node data retrieval to
+// heal whatever is missing.
+func (d *Downloader) finishRangeSync(s *rangeSync) {
+	s.done = true
+
+	if s.complete() && len(s.accountTasks) > 0 {
+		root, err := s.accounts.Commit()
+		if err != nil {
+			glog.V(logger.Error).Infof("Failed to commit account trie: %v", err)
+			d.cancel()
+			return
+		}
+		if root != s.root {
+			glog.V(logger.Error).Infof("Account trie root mismatch: have %x, want %x", root, s.root)
+			d.cancel()
+			return
+		}
+	}
+	glog.V(logger.Info).Infof("State ranges retrieved in %v: %d entries, healing the rest", common.PrettyDuration(time.Since(s.started)), s.entries)
+
+	d.queue.HealState()
+	select {
+	case d.stateWakeCh <- true:
+	default:
+	}
+}
+
+// reportRangeProgress updates the sync stats with the delivered state entries.
+func (d *Downloader) reportRangeProgress(s *rangeSync, delivered int) {
+	d.syncStatsLock.Lock()
+	d.syncStatsStateDone += uint64(delivered)
+	d.syncStatsLock.Unlock()
+
+	if delivered > 0 {
+		glog.V(logger.Detail).Infof("imported %3d state range entries: %d so far, storage tries pending %d, codes pending %d", delivered, s.entries, len(s.storageTasks), len(s.codeTasks))
+	}
+}
+
+// incHash returns the hash following the given one.
+func incHash(h common.Hash) common.Hash {
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			break
+		}
+	}
+	return h
+}
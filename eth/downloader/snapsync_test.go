@@ -0,0 +1,357 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"bytes"
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/state"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/params"
+	"github.com/ur-technology/go-ur/rlp"
+	"github.com/ur-technology/go-ur/trie"
+)
+
+// testSnapServeLimit is the number of state entries the simulated snap peers
+// serve per request, small enough to split the test state into many ranges.
+const testSnapServeLimit = 16
+
+// makeStateChain creates a chain of n blocks like makeChain, but deploying a few
+// contracts with storage in the early blocks, so that there's some storage and
+// code in the state to retrieve.
+func (dl *downloadTester) makeStateChain(n int) ([]common.Hash, map[common.Hash]*types.Header, map[common.Hash]*types.Block, map[common.Hash]types.Receipts) {
+	blocks, receipts := core.GenerateChain(params.TestChainConfig, nil, dl.genesis, dl.peerDb, n, func(i int, block *core.BlockGen) {
+		block.SetCoinbase(common.Address{byte(i % 4)})
+
+		if i < 40 && i%5 == 0 {
+			// Store 64 slots and return a unique 32 byte code
+			var code []byte
+			for j := 0; j < 64; j++ {
+				code = append(code, 0x61, byte(i), byte(j+1), 0x60, byte(j), 0x55) // PUSH2 val PUSH1 slot SSTORE
+			}
+			code = append(code, 0x60, byte(i+1), 0x60, 0x00, 0x52) // PUSH1 i+1 PUSH1 0 MSTORE
+			code = append(code, 0x60, 0x20, 0x60, 0x00, 0xf3)      // PUSH1 32 PUSH1 0 RETURN
+
+			signer := types.MakeSigner(params.TestChainConfig, block.Number())
+			tx, err := types.NewContractCreation(block.TxNonce(testAddress), new(big.Int), big.NewInt(2000000), nil, code).SignECDSA(signer, testKey)
+			if err != nil {
+				panic(err)
+			}
+			block.AddTx(tx)
+		}
+	})
+	hashes := make([]common.Hash, n+1)
+	hashes[len(hashes)-1] = dl.genesis.Hash()
+
+	headerm := map[common.Hash]*types.Header{dl.genesis.Hash(): dl.genesis.Header()}
+	blockm := map[common.Hash]*types.Block{dl.genesis.Hash(): dl.genesis}
+	receiptm := map[common.Hash]types.Receipts{dl.genesis.Hash(): nil}
+
+	for i, b := range blocks {
+		hashes[len(hashes)-i-2] = b.Hash()
+		headerm[b.Hash()] = b.Header()
+		blockm[b.Hash()] = b
+		receiptm[b.Hash()] = receipts[i]
+	}
+	return hashes, headerm, blockm, receiptm
+}
+
+// newSnapPeer registers a new block download source also serving the state in
+// ranges. The state entries (trie roots or code hashes) in missing are not served
+// in ranges, although still by node data.
+func (dl *downloadTester) newSnapPeer(id string, version int, hashes []common.Hash, headers map[common.Hash]*types.Header, blocks map[common.Hash]*types.Block, receipts map[common.Hash]types.Receipts, missing map[common.Hash]bool) error {
+	if err := dl.newPeer(id, version, hashes, headers, blocks, receipts); err != nil {
+		return err
+	}
+	return dl.downloader.RegisterSnapPeer(id, dl.peerGetAccountRangeFn(id, missing, false), dl.peerGetStorageRangesFn(id, missing), dl.peerGetByteCodesFn(id, missing))
+}
+
+// peerGetAccountRangeFn constructs a getAccountRange method associated with a
+// particular peer in the download tester, optionally corrupting the accounts.
+func (dl *downloadTester) peerGetAccountRangeFn(id string, missing map[common.Hash]bool, corrupt bool) accountRangeFetcherFn {
+	return func(root common.Hash, origin common.Hash, limit common.Hash, _ uint64) error {
+		var (
+			hashes   []common.Hash
+			accounts [][]byte
+			proof    []rlp.RawValue
+		)
+		if tr, err := trie.New(root, dl.peerDb); err == nil && !missing[root] {
+			tr.Walk(origin[:], func(key, value []byte) bool {
+				hashes = append(hashes, common.BytesToHash(key))
+				accounts = append(accounts, common.CopyBytes(value))
+				return len(hashes) < testSnapServeLimit && bytes.Compare(key, limit[:]) < 0
+			})
+			proof = tr.Prove(origin[:])
+			if len(hashes) > 0 {
+				proof = append(proof, tr.Prove(hashes[len(hashes)-1][:])...)
+			}
+			if corrupt && len(accounts) > 0 {
+				var account state.Account
+				rlp.DecodeBytes(accounts[0], &account)
+				account.Nonce++
+				accounts[0], _ = rlp.EncodeToBytes(&account)
+			}
+		}
+		go dl.downloader.DeliverAccountRange(id, hashes, accounts, proof)
+		return nil
+	}
+}
+
+// peerGetStorageRangesFn constructs a getStorageRanges method associated with a
+// particular peer in the download tester.
+func (dl *downloadTester) peerGetStorageRangesFn(id string, missing map[common.Hash]bool) storageRangesFetcherFn {
+	return func(root common.Hash, accounts []common.Hash, origin common.Hash, _ uint64) error {
+		var (
+			hashes [][]common.Hash
+			slots  [][][]byte
+			proof  []rlp.RawValue
+			served int
+		)
+		if tr, err := trie.New(root, dl.peerDb); err == nil && !missing[root] {
+			for i, hash := range accounts {
+				var account state.Account
+				if err := rlp.DecodeBytes(tr.Get(hash[:]), &account); err != nil || missing[account.Root] || served >= testSnapServeLimit {
+					break
+				}
+				storage, err := trie.New(account.Root, dl.peerDb)
+				if err != nil {
+					break
+				}
+				var start common.Hash
+				if i == 0 {
+					start = origin
+				}
+				var (
+					keys      []common.Hash
+					values    [][]byte
+					truncated bool
+				)
+				storage.Walk(start[:], func(key, value []byte) bool {
+					if served >= testSnapServeLimit {
+						truncated = true
+						return false
+					}
+					keys = append(keys, common.BytesToHash(key))
+					values = append(values, common.CopyBytes(value))
+					served++
+					return true
+				})
+				hashes, slots = append(hashes, keys), append(slots, values)
+
+				if truncated || start != (common.Hash{}) {
+					proof = storage.Prove(start[:])
+					if len(keys) > 0 {
+						proof = append(proof, storage.Prove(keys[len(keys)-1][:])...)
+					}
+					break
+				}
+			}
+		}
+		go dl.downloader.DeliverStorageRanges(id, hashes, slots, proof)
+		return nil
+	}
+}
+
+// peerGetByteCodesFn constructs a getByteCodes method associated with a
+// particular peer in the download tester.
+func (dl *downloadTester) peerGetByteCodesFn(id string, missing map[common.Hash]bool) byteCodesFetcherFn {
+	return func(hashes []common.Hash, _ uint64) error {
+		var codes [][]byte
+		for _, hash := range hashes {
+			if code, err := dl.peerDb.Get(hash[:]); err == nil && !missing[hash] {
+				codes = append(codes, code)
+			}
+		}
+		go dl.downloader.DeliverByteCodes(id, codes)
+		return nil
+	}
+}
+
+// countNodeData wraps the node data retrieval of a peer, counting the state
+// entries requested from it.
+func countNodeData(tester *downloadTester, id string) *int32 {
+	counter := new(int32)
+
+	p := tester.downloader.peers.Peer(id)
+	getNodeData := p.getNodeData
+	p.getNodeData = func(hashes []common.Hash) error {
+		atomic.AddInt32(counter, int32(len(hashes)))
+		return getNodeData(hashes)
+	}
+	return counter
+}
+
+// assertSnapState checks that the state of the fast sync pivot is complete,
+// storage tries and contract codes included.
+func assertSnapState(t *testing.T, tester *downloadTester) {
+	pivot := tester.ownHeaders[tester.ownHashes[tester.downloader.queue.FastSyncPivot()]]
+	statedb, err := state.New(pivot.Root, tester.stateDb)
+	if err != nil {
+		t.Fatalf("pivot state missing: %v", err)
+	}
+	var codes, contracts int
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		if _, ok := it.Entry.([]byte); ok {
+			codes++
+		}
+	}
+	if it.Error != nil {
+		t.Fatalf("pivot state incomplete: %v", it.Error)
+	}
+	for _, hash := range tester.ownHashes[1 : tester.downloader.queue.FastSyncPivot()+1] {
+		for _, tx := range tester.ownBlocks[hash].Transactions() {
+			if tx.To() == nil {
+				contracts++
+			}
+		}
+	}
+	if codes != contracts || codes == 0 {
+		t.Fatalf("contract code count mismatch: have %d, want %d", codes, contracts)
+	}
+}
+
+// Tests that snap sync retrieves the whole pivot state in ranges, without any
+// healing needed.
+func TestSnapSynchronisation63(t *testing.T) { testSnapSynchronisation(t, 63) }
+func TestSnapSynchronisation64(t *testing.T) { testSnapSynchronisation(t, 64) }
+
+func testSnapSynchronisation(t *testing.T, protocol int) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := fsMinFullBlocks + fsPivotInterval + 64
+	hashes, headers, blocks, receipts := tester.makeStateChain(targetBlocks)
+
+	tester.newSnapPeer("peer", protocol, hashes, headers, blocks, receipts, nil)
+	healed := countNodeData(tester, "peer")
+
+	if err := tester.sync("peer", nil, SnapSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+	assertSnapState(t, tester)
+
+	if n := atomic.LoadInt32(healed); n != 0 {
+		t.Fatalf("%d state entries healed, want none", n)
+	}
+}
+
+// Tests that the state a snap peer doesn't serve in ranges is healed by node data.
+func TestSnapSyncHealing63(t *testing.T) { testSnapSyncHealing(t, 63) }
+func TestSnapSyncHealing64(t *testing.T) { testSnapSyncHealing(t, 64) }
+
+func testSnapSyncHealing(t *testing.T, protocol int) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := fsMinFullBlocks + fsPivotInterval + 64
+	hashes, headers, blocks, receipts := tester.makeStateChain(targetBlocks)
+
+	// Withhold the storage and code of all the contracts from the ranges
+	missing := make(map[common.Hash]bool)
+	accounts, _ := trie.NewSecure(headers[hashes[0]].Root, tester.peerDb, 0)
+	for _, block := range blocks {
+		for _, receipt := range receipts[block.Hash()] {
+			if receipt.ContractAddress != (common.Address{}) {
+				var account state.Account
+				if err := rlp.DecodeBytes(accounts.Get(receipt.ContractAddress[:]), &account); err != nil {
+					t.Fatalf("failed to retrieve contract %x: %v", receipt.ContractAddress, err)
+				}
+				missing[account.Root] = true
+				missing[common.BytesToHash(account.CodeHash)] = true
+			}
+		}
+	}
+	tester.newSnapPeer("peer", protocol, hashes, headers, blocks, receipts, missing)
+	healed := countNodeData(tester, "peer")
+
+	if err := tester.sync("peer", nil, SnapSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+	assertSnapState(t, tester)
+
+	if n := atomic.LoadInt32(healed); n == 0 {
+		t.Fatalf("no state entries healed")
+	}
+}
+
+// Tests that snap sync falls back to node data entirely if no peer serves the
+// pivot state in ranges.
+func TestSnapSyncStateless63(t *testing.T) { testSnapSyncStateless(t, 63) }
+func TestSnapSyncStateless64(t *testing.T) { testSnapSyncStateless(t, 64) }
+
+func testSnapSyncStateless(t *testing.T, protocol int) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := fsMinFullBlocks + fsPivotInterval + 64
+	hashes, headers, blocks, receipts := tester.makeStateChain(targetBlocks)
+
+	// Withhold all the states from the ranges
+	missing := make(map[common.Hash]bool)
+	for _, header := range headers {
+		missing[header.Root] = true
+	}
+	tester.newSnapPeer("peer", protocol, hashes, headers, blocks, receipts, missing)
+
+	if err := tester.sync("peer", nil, SnapSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+	assertSnapState(t, tester)
+}
+
+// Tests that a snap peer serving invalid ranges is dropped, the state being
+// retrieved from the honest peers.
+func TestSnapSyncBadRanges63(t *testing.T) { testSnapSyncBadRanges(t, 63) }
+func TestSnapSyncBadRanges64(t *testing.T) { testSnapSyncBadRanges(t, 64) }
+
+func testSnapSyncBadRanges(t *testing.T, protocol int) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := fsMinFullBlocks + fsPivotInterval + 64
+	hashes, headers, blocks, receipts := tester.makeStateChain(targetBlocks)
+
+	tester.newSnapPeer("peer", protocol, hashes, headers, blocks, receipts, nil)
+	tester.downloader.RegisterSnapPeer("attack", tester.peerGetAccountRangeFn("attack", nil, true), tester.peerGetStorageRangesFn("attack", nil), tester.peerGetByteCodesFn("attack", nil))
+
+	if err := tester.sync("peer", nil, SnapSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+	assertSnapState(t, tester)
+
+	if tester.downloader.snapPeer("attack") != nil {
+		t.Fatalf("peer serving bad ranges not dropped")
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/rlp"
 )
 
 // headerCheckFn is a callback type for verifying a header's presence in the local chain.
@@ -118,3 +119,39 @@ type statePack struct {
 func (p *statePack) PeerId() string { return p.peerId }
 func (p *statePack) Items() int     { return len(p.states) }
 func (p *statePack) Stats() string  { return fmt.Sprintf("%d", len(p.states)) }
+
+// accountRangePack is a range of accounts returned by a peer, along with the
+// merkle proofs of its edges.
+type accountRangePack struct {
+	peerId   string
+	hashes   []common.Hash
+	accounts [][]byte
+	proof    []rlp.RawValue
+}
+
+func (p *accountRangePack) PeerId() string { return p.peerId }
+func (p *accountRangePack) Items() int     { return len(p.hashes) }
+func (p *accountRangePack) Stats() string  { return fmt.Sprintf("%d", len(p.hashes)) }
+
+// storageRangesPack is a batch of storage ranges returned by a peer, along with
+// the merkle proofs of the edges of the last one.
+type storageRangesPack struct {
+	peerId string
+	hashes [][]common.Hash
+	slots  [][][]byte
+	proof  []rlp.RawValue
+}
+
+func (p *storageRangesPack) PeerId() string { return p.peerId }
+func (p *storageRangesPack) Items() int     { return len(p.hashes) }
+func (p *storageRangesPack) Stats() string  { return fmt.Sprintf("%d", len(p.hashes)) }
+
+// byteCodesPack is a batch of contract codes returned by a peer.
+type byteCodesPack struct {
+	peerId string
+	codes  [][]byte
+}
+
+func (p *byteCodesPack) PeerId() string { return p.peerId }
+func (p *byteCodesPack) Items() int     { return len(p.codes) }
+func (p *byteCodesPack) Stats() string  { return fmt.Sprintf("%d", len(p.codes)) }
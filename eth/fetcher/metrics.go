@@ -40,4 +40,10 @@ var (
 	headerFilterOutMeter = metrics.NewMeter("eth/fetcher/filter/headers/out")
 	bodyFilterInMeter    = metrics.NewMeter("eth/fetcher/filter/bodies/in")
 	bodyFilterOutMeter   = metrics.NewMeter("eth/fetcher/filter/bodies/out")
+
+	txAnnounceInMeter   = metrics.NewMeter("eth/fetcher/prop/txns/announces/in")
+	txAnnounceDOSMeter  = metrics.NewMeter("eth/fetcher/prop/txns/announces/dos")
+	txDeliveryInMeter   = metrics.NewMeter("eth/fetcher/prop/txns/deliveries/in")
+	txFetchMeter        = metrics.NewMeter("eth/fetcher/fetch/txns")
+	txFetchTimeoutMeter = metrics.NewMeter("eth/fetcher/fetch/txns/timeouts")
 )
@@ -0,0 +1,385 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+)
+
+const (
+	txArriveTimeout = 500 * time.Millisecond // Time allowance before an announced transaction is explicitly requested
+	txFetchTimeout  = 5 * time.Second        // Maximum allotted time to return explicitly requested transactions
+	txHashLimit     = 4096                   // Maximum number of unique transactions a peer may have announced
+
+	// TxFetchLimit is the maximum number of transactions requested from a peer
+	// at once, and served to one.
+	TxFetchLimit = 256
+)
+
+// txRetrievalFn is a callback type for checking whether a transaction is already
+// known locally.
+type txRetrievalFn func(common.Hash) bool
+
+// txRequesterFn is a callback type for sending a transaction retrieval request.
+type txRequesterFn func([]common.Hash) error
+
+// txAnnounce is the hash notification of the availability of a transaction at a
+// remote peer.
+type txAnnounce struct {
+	hash   common.Hash // Hash of the transaction being announced
+	time   time.Time   // Timestamp of the announcement
+	origin string      // Identifier of the peer originating the notification
+
+	fetchTxs txRequesterFn // Fetcher function to retrieve the announced transactions
+}
+
+// txRequest is a transaction retrieval request in flight to a peer.
+type txRequest struct {
+	hashes []common.Hash // Transactions requested
+	time   time.Time     // Timestamp of the request
+}
+
+// txDelivery is a batch of transactions received from a peer, either requested
+// or broadcast.
+type txDelivery struct {
+	origin string
+	hashes []common.Hash
+	reply  bool // Whether the transactions answer a request
+}
+
+// TxFetcher is responsible for retrieving the transactions only announced by
+// peers instead of propagated in full. Announcements are given a chance to be
+// satisfied by a broadcast first, after which every transaction is requested
+// from one of the peers that announced it, at most one request in flight per
+// peer. If a peer fails to deliver in time, the transaction is requested from
+// the next announcer.
+type TxFetcher struct {
+	// Various event channels
+	notify  chan []*txAnnounce
+	deliver chan *txDelivery
+	drop    chan string
+	quit    chan struct{}
+
+	// Announce states
+	announces map[string]int                // Per peer announce counts to prevent memory exhaustion
+	announced map[common.Hash][]*txAnnounce // Announced transactions, by all their announcers
+	fetching  map[common.Hash]string        // Announced transactions, currently fetching from a peer
+	requests  map[string]*txRequest         // Requests in flight, by the peer they're sent to
+
+	// Callbacks
+	hasTx txRetrievalFn // Checks whether a transaction is already known
+
+	// Testing hooks
+	fetchingHook func(string, []common.Hash) // Method to call upon starting a transaction fetch
+}
+
+// NewTxFetcher creates a transaction fetcher to retrieve transactions based on
+// hash announcements.
+func NewTxFetcher(hasTx txRetrievalFn) *TxFetcher {
+	return &TxFetcher{
+		notify:    make(chan []*txAnnounce),
+		deliver:   make(chan *txDelivery),
+		drop:      make(chan string),
+		quit:      make(chan struct{}),
+		announces: make(map[string]int),
+		announced: make(map[common.Hash][]*txAnnounce),
+		fetching:  make(map[common.Hash]string),
+		requests:  make(map[string]*txRequest),
+		hasTx:     hasTx,
+	}
+}
+
+// Start boots up the transaction fetcher, accepting and processing hash
+// notifications until termination requested.
+func (f *TxFetcher) Start() {
+	go f.loop()
+}
+
+// Stop terminates the transaction fetcher, canceling all pending operations.
+func (f *TxFetcher) Stop() {
+	close(f.quit)
+}
+
+// Notify announces the fetcher of the availability of a batch of transactions
+// at a remote peer.
+func (f *TxFetcher) Notify(peer string, hashes []common.Hash, time time.Time, fetchTxs txRequesterFn) error {
+	announces := make([]*txAnnounce, len(hashes))
+	for i, hash := range hashes {
+		announces[i] = &txAnnounce{hash: hash, time: time, origin: peer, fetchTxs: fetchTxs}
+	}
+	select {
+	case f.notify <- announces:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Enqueue informs the fetcher of transactions broadcast by a peer, so that they
+// are not fetched again.
+func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction) error {
+	return f.delivered(peer, txs, false)
+}
+
+// Deliver informs the fetcher of the transactions a peer replied to a request
+// with. The ones requested but not delivered are requested from others.
+func (f *TxFetcher) Deliver(peer string, txs []*types.Transaction) error {
+	return f.delivered(peer, txs, true)
+}
+
+// delivered passes a batch of received transactions to the fetcher loop.
+func (f *TxFetcher) delivered(peer string, txs []*types.Transaction, reply bool) error {
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	select {
+	case f.deliver <- &txDelivery{origin: peer, hashes: hashes, reply: reply}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Drop removes all the announcements of a disconnected peer, requesting any of
+// its transactions in flight from others.
+func (f *TxFetcher) Drop(peer string) error {
+	select {
+	case f.drop <- peer:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// loop is the main transaction fetcher loop, checking and processing various
+// notification events.
+func (f *TxFetcher) loop() {
+	fetchTimer := time.NewTimer(0)
+
+	for {
+		// Wait for an outside event to occur
+		select {
+		case <-f.quit:
+			// Fetcher terminating, abort all operations
+			return
+
+		case announces := <-f.notify:
+			// Transactions were announced, make sure the peer isn't DOSing us
+			txAnnounceInMeter.Mark(int64(len(announces)))
+
+			for _, announce := range announces {
+				count := f.announces[announce.origin] + 1
+				if count > txHashLimit {
+					glog.V(logger.Debug).Infof("Peer %s: exceeded outstanding transaction announces (%d)", announce.origin, txHashLimit)
+					txAnnounceDOSMeter.Mark(1)
+					break
+				}
+				// Skip known transactions and duplicate announcements
+				if f.hasTx(announce.hash) || f.announcedBy(announce.hash, announce.origin) {
+					continue
+				}
+				f.announces[announce.origin] = count
+				f.announced[announce.hash] = append(f.announced[announce.hash], announce)
+			}
+			f.rescheduleFetch(fetchTimer)
+
+		case delivery := <-f.deliver:
+			// Transactions arrived, forget about them and fail anything requested
+			// from the peer but not delivered
+			txDeliveryInMeter.Mark(int64(len(delivery.hashes)))
+
+			for _, hash := range delivery.hashes {
+				f.forgetHash(hash)
+			}
+			if _, ok := f.requests[delivery.origin]; ok && delivery.reply {
+				f.failRequest(delivery.origin, delivery.hashes)
+			}
+			f.rescheduleFetch(fetchTimer)
+
+		case peer := <-f.drop:
+			// A peer disconnected, request its transactions from others
+			if _, ok := f.requests[peer]; ok {
+				f.failRequest(peer, nil)
+			}
+			for hash, announces := range f.announced {
+				for i, announce := range announces {
+					if announce.origin == peer {
+						f.removeAnnounce(hash, i)
+						break
+					}
+				}
+			}
+			delete(f.announces, peer)
+			f.rescheduleFetch(fetchTimer)
+
+		case <-fetchTimer.C:
+			// Reschedule the transactions of any timed out requests
+			for peer, req := range f.requests {
+				if time.Since(req.time) >= txFetchTimeout {
+					glog.V(logger.Detail).Infof("Peer %s: transaction request timed out", peer)
+					txFetchTimeoutMeter.Mark(int64(len(req.hashes)))
+					f.failRequest(peer, nil)
+				}
+			}
+			// Request every announced transaction that didn't arrive by now from
+			// an idle announcer
+			request := make(map[string][]common.Hash)
+
+			for hash, announces := range f.announced {
+				if _, ok := f.fetching[hash]; ok || time.Since(announces[0].time) < txArriveTimeout {
+					continue
+				}
+				if f.hasTx(hash) {
+					f.forgetHash(hash)
+					continue
+				}
+				// Pick a random idle announcer which can take more requests
+				var idle []*txAnnounce
+				for _, announce := range announces {
+					if _, busy := f.requests[announce.origin]; !busy && len(request[announce.origin]) < TxFetchLimit {
+						idle = append(idle, announce)
+					}
+				}
+				if len(idle) == 0 {
+					continue
+				}
+				announce := idle[rand.Intn(len(idle))]
+				request[announce.origin] = append(request[announce.origin], hash)
+				f.fetching[hash] = announce.origin
+			}
+			// Send out all transaction requests
+			for peer, hashes := range request {
+				glog.V(logger.Detail).Infof("Peer %s: fetching %d transactions", peer, len(hashes))
+				txFetchMeter.Mark(int64(len(hashes)))
+
+				f.requests[peer] = &txRequest{hashes: hashes, time: time.Now()}
+
+				fetchTxs := f.announcement(hashes[0], peer).fetchTxs
+				if f.fetchingHook != nil {
+					f.fetchingHook(peer, hashes)
+				}
+				go fetchTxs(hashes)
+			}
+			// Schedule the next fetch if transactions are still pending
+			f.rescheduleFetch(fetchTimer)
+		}
+	}
+}
+
+// rescheduleFetch resets the specified fetch timer to the next announce timeout
+// of a transaction not yet fetched, or the next request timeout, whichever is
+// earlier. Announcements only made by peers busy with a request are woken up by
+// that request terminating instead.
+func (f *TxFetcher) rescheduleFetch(fetch *time.Timer) {
+	var earliest time.Time
+	for hash, announces := range f.announced {
+		if _, ok := f.fetching[hash]; ok {
+			continue
+		}
+		for _, announce := range announces {
+			if _, busy := f.requests[announce.origin]; !busy {
+				if deadline := announces[0].time.Add(txArriveTimeout); earliest.IsZero() || deadline.Before(earliest) {
+					earliest = deadline
+				}
+				break
+			}
+		}
+	}
+	for _, req := range f.requests {
+		if deadline := req.time.Add(txFetchTimeout); earliest.IsZero() || deadline.Before(earliest) {
+			earliest = deadline
+		}
+	}
+	if earliest.IsZero() {
+		return
+	}
+	fetch.Reset(earliest.Sub(time.Now()))
+}
+
+// announcedBy checks whether a transaction was announced by the given peer.
+func (f *TxFetcher) announcedBy(hash common.Hash, peer string) bool {
+	return f.announcement(hash, peer) != nil
+}
+
+// announcement retrieves the announcement of a transaction made by a peer.
+func (f *TxFetcher) announcement(hash common.Hash, peer string) *txAnnounce {
+	for _, announce := range f.announced[hash] {
+		if announce.origin == peer {
+			return announce
+		}
+	}
+	return nil
+}
+
+// failRequest terminates the request in flight to a peer, rescheduling all
+// the transactions not delivered to the other peers announcing them.
+func (f *TxFetcher) failRequest(peer string, delivered []common.Hash) {
+	done := make(map[common.Hash]bool, len(delivered))
+	for _, hash := range delivered {
+		done[hash] = true
+	}
+	for _, hash := range f.requests[peer].hashes {
+		if done[hash] || f.fetching[hash] != peer {
+			continue
+		}
+		delete(f.fetching, hash)
+		for i, announce := range f.announced[hash] {
+			if announce.origin == peer {
+				f.removeAnnounce(hash, i)
+				break
+			}
+		}
+	}
+	delete(f.requests, peer)
+}
+
+// removeAnnounce removes a single announcement of a transaction, forgetting the
+// transaction if it was the last one.
+func (f *TxFetcher) removeAnnounce(hash common.Hash, index int) {
+	announces := f.announced[hash]
+	origin := announces[index].origin
+
+	if f.announces[origin]--; f.announces[origin] <= 0 {
+		delete(f.announces, origin)
+	}
+	announces = append(announces[:index], announces[index+1:]...)
+	if len(announces) == 0 {
+		delete(f.announced, hash)
+		delete(f.fetching, hash)
+		return
+	}
+	f.announced[hash] = announces
+}
+
+// forgetHash removes all traces of a transaction announcement from the fetcher's
+// internal state.
+func (f *TxFetcher) forgetHash(hash common.Hash) {
+	for _, announce := range f.announced[hash] {
+		if f.announces[announce.origin]--; f.announces[announce.origin] <= 0 {
+			delete(f.announces, announce.origin)
+		}
+	}
+	delete(f.announced, hash)
+	delete(f.fetching, hash)
+}
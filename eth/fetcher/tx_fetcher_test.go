@@ -0,0 +1,248 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+)
+
+// txFetch is a single transaction request made by the fetcher.
+type txFetch struct {
+	peer   string
+	hashes []common.Hash
+}
+
+// txFetcherTester is a test simulator for mocking out the transaction pool.
+type txFetcherTester struct {
+	fetcher *TxFetcher
+	known   map[common.Hash]bool
+	fetches chan txFetch
+	lock    sync.RWMutex
+}
+
+// newTxTester creates a new transaction fetcher test mocker.
+func newTxTester() *txFetcherTester {
+	tester := &txFetcherTester{
+		known:   make(map[common.Hash]bool),
+		fetches: make(chan txFetch, 16),
+	}
+	tester.fetcher = NewTxFetcher(tester.hasTx)
+	tester.fetcher.fetchingHook = func(peer string, hashes []common.Hash) {
+		tester.fetches <- txFetch{peer, hashes}
+	}
+	tester.fetcher.Start()
+	return tester
+}
+
+// hasTx is the transaction pool lookup of the fetcher.
+func (f *txFetcherTester) hasTx(hash common.Hash) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.known[hash]
+}
+
+// requester is a transaction request callback doing nothing, the requests are
+// tracked through the fetching hook.
+func requester(hashes []common.Hash) error { return nil }
+
+// makeTxs creates a batch of distinct dummy transactions and their hashes.
+func makeTxs(n int) ([]*types.Transaction, []common.Hash) {
+	txs := make([]*types.Transaction, n)
+	hashes := make([]common.Hash, n)
+	for i := 0; i < n; i++ {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{}, big.NewInt(0), big.NewInt(21000), big.NewInt(0), nil)
+		hashes[i] = txs[i].Hash()
+	}
+	return txs, hashes
+}
+
+// verifyTxFetch verifies that a single request arrives, returning it.
+func verifyTxFetch(t *testing.T, fetches chan txFetch, timeout time.Duration) txFetch {
+	select {
+	case fetch := <-fetches:
+		return fetch
+	case <-time.After(timeout):
+		t.Fatalf("fetching timeout")
+	}
+	return txFetch{}
+}
+
+// verifyNoTxFetch verifies that no requests are made within the given time.
+func verifyNoTxFetch(t *testing.T, fetches chan txFetch, wait time.Duration) {
+	select {
+	case fetch := <-fetches:
+		t.Fatalf("fetched %d transactions from %s", len(fetch.hashes), fetch.peer)
+	case <-time.After(wait):
+	}
+}
+
+// Tests that transactions announced by multiple peers are only requested once,
+// and only after they had the chance to arrive by broadcast.
+func TestTxFetcherDeduplication(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	_, hashes := makeTxs(8)
+
+	tester.fetcher.Notify("first", hashes, time.Now(), requester)
+	tester.fetcher.Notify("second", hashes, time.Now(), requester)
+	tester.fetcher.Notify("second", hashes, time.Now(), requester)
+
+	verifyNoTxFetch(t, tester.fetches, txArriveTimeout/2)
+
+	// The requests may be spread across the announcers, but each transaction
+	// must be requested exactly once
+	fetched := make(map[common.Hash]int)
+	for fetch := verifyTxFetch(t, tester.fetches, txArriveTimeout); ; {
+		for _, hash := range fetch.hashes {
+			fetched[hash]++
+		}
+		select {
+		case fetch = <-tester.fetches:
+			continue
+		case <-time.After(50 * time.Millisecond):
+		}
+		break
+	}
+	if len(fetched) != len(hashes) {
+		t.Fatalf("fetched transaction count mismatch: have %d, want %d", len(fetched), len(hashes))
+	}
+	for hash, count := range fetched {
+		if count != 1 {
+			t.Errorf("transaction %x fetched %d times", hash[:4], count)
+		}
+	}
+}
+
+// Tests that already known and broadcast transactions are not requested.
+func TestTxFetcherKnownSkipping(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(4)
+
+	tester.lock.Lock()
+	tester.known[hashes[0]] = true
+	tester.lock.Unlock()
+
+	tester.fetcher.Notify("peer", hashes, time.Now(), requester)
+	tester.fetcher.Enqueue("other", txs[1:2])
+
+	fetch := verifyTxFetch(t, tester.fetches, time.Second)
+	if len(fetch.hashes) != 2 {
+		t.Fatalf("fetched transaction count mismatch: have %d, want %d", len(fetch.hashes), 2)
+	}
+	for _, hash := range fetch.hashes {
+		if hash == hashes[0] || hash == hashes[1] {
+			t.Fatalf("fetched available transaction %x", hash[:4])
+		}
+	}
+}
+
+// Tests that transactions requested but not delivered by a peer are requested
+// from another announcer instead.
+func TestTxFetcherUndeliveredRetry(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(2)
+
+	tester.fetcher.Notify("first", hashes, time.Now().Add(-txArriveTimeout), requester)
+	fetch := verifyTxFetch(t, tester.fetches, time.Second)
+	tester.fetcher.Notify("second", hashes, time.Now().Add(-txArriveTimeout), requester)
+
+	tester.fetcher.Deliver(fetch.peer, txs[:1])
+
+	retry := verifyTxFetch(t, tester.fetches, time.Second)
+	if retry.peer == fetch.peer {
+		t.Fatalf("transaction re-requested from same peer %s", retry.peer)
+	}
+	if len(retry.hashes) != 1 || retry.hashes[0] != hashes[1] {
+		t.Fatalf("retried transactions mismatch: have %x, want %x", retry.hashes, hashes[1:])
+	}
+}
+
+// Tests that the transactions of a peer dropped mid request are requested from
+// another announcer.
+func TestTxFetcherDropRetry(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	_, hashes := makeTxs(2)
+
+	tester.fetcher.Notify("first", hashes, time.Now().Add(-txArriveTimeout), requester)
+	fetch := verifyTxFetch(t, tester.fetches, time.Second)
+	tester.fetcher.Notify("second", hashes, time.Now().Add(-txArriveTimeout), requester)
+
+	tester.fetcher.Drop(fetch.peer)
+
+	retry := verifyTxFetch(t, tester.fetches, time.Second)
+	if retry.peer == fetch.peer {
+		t.Fatalf("transactions re-requested from dropped peer %s", retry.peer)
+	}
+	if len(retry.hashes) != len(hashes) {
+		t.Fatalf("retried transaction count mismatch: have %d, want %d", len(retry.hashes), len(hashes))
+	}
+}
+
+// Tests that a request not answered in time is retried from another announcer.
+func TestTxFetcherTimeoutRetry(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	_, hashes := makeTxs(1)
+
+	tester.fetcher.Notify("first", hashes, time.Now().Add(-txArriveTimeout), requester)
+	fetch := verifyTxFetch(t, tester.fetches, time.Second)
+	tester.fetcher.Notify("second", hashes, time.Now().Add(-txArriveTimeout), requester)
+
+	verifyNoTxFetch(t, tester.fetches, txFetchTimeout/2)
+
+	retry := verifyTxFetch(t, tester.fetches, txFetchTimeout)
+	if retry.peer == fetch.peer {
+		t.Fatalf("transaction re-requested from timed out peer %s", retry.peer)
+	}
+}
+
+// Tests that a peer can't make the fetcher track an unbounded number of
+// transaction announcements.
+func TestTxFetcherAnnounceDOS(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	_, hashes := makeTxs(txHashLimit + 1)
+	tester.fetcher.Notify("attacker", hashes, time.Now(), requester)
+
+	// Sync with the fetcher loop before inspecting its state
+	tester.fetcher.Notify("attacker", nil, time.Now(), requester)
+	if n := len(tester.fetcher.announced); n != txHashLimit {
+		t.Fatalf("tracked announcement count mismatch: have %d, want %d", n, txHashLimit)
+	}
+	tester.fetcher.Drop("attacker")
+
+	tester.fetcher.Notify("attacker", nil, time.Now(), requester)
+	if n := len(tester.fetcher.announced); n != 0 {
+		t.Fatalf("announcements not cleaned up after drop: %d left", n)
+	}
+}
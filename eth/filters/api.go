@@ -28,6 +28,7 @@ import (
 
 	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/common/hexutil"
+	"github.com/ur-technology/go-ur/core"
 	"github.com/ur-technology/go-ur/core/types"
 	"github.com/ur-technology/go-ur/ethdb"
 	"github.com/ur-technology/go-ur/event"
@@ -232,6 +233,87 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 	return rpcSub, nil
 }
 
+// Signups is the notification of a block signing up new UR members.
+type Signups struct {
+	BlockNumber *hexutil.Big     `json:"blockNumber"`
+	BlockHash   common.Hash      `json:"blockHash"`
+	NewSignups  *hexutil.Big     `json:"newSignups"`
+	NSignups    *hexutil.Big     `json:"nSignups"`
+	TotalWei    *hexutil.Big     `json:"totalWei"`
+	Members     []common.Address `json:"members"` // Signed up members, nil if the block body isn't available (light clients)
+}
+
+// NewSignups creates a subscription that is triggered each time a block signing
+// up new members is added to the chain.
+func (api *PublicFilterAPI) NewSignups(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		headers := make(chan *types.Header)
+		headersSub := api.events.SubscribeNewHeads(headers)
+
+		for {
+			select {
+			case h := <-headers:
+				if signups := api.signups(h); signups != nil {
+					notifier.Notify(rpcSub.ID, signups)
+				}
+			case <-rpcSub.Err():
+				headersSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				headersSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// signups assembles the signup notification of a new block, or nil if the block
+// doesn't sign up any members.
+func (api *PublicFilterAPI) signups(header *types.Header) *Signups {
+	if header.NSignups == nil || header.Number.Sign() == 0 {
+		return nil
+	}
+	number := header.Number.Uint64()
+	parent := core.GetHeader(api.chainDb, header.ParentHash, number-1)
+	if parent == nil || parent.NSignups == nil {
+		return nil
+	}
+	count := new(big.Int).Sub(header.NSignups, parent.NSignups)
+	if count.Sign() <= 0 {
+		return nil
+	}
+	signups := &Signups{
+		BlockNumber: (*hexutil.Big)(header.Number),
+		BlockHash:   header.Hash(),
+		NewSignups:  (*hexutil.Big)(count),
+		NSignups:    (*hexutil.Big)(header.NSignups),
+		TotalWei:    (*hexutil.Big)(header.TotalWei),
+	}
+	if body := core.GetBody(api.chainDb, signups.BlockHash, number); body != nil {
+		signups.Members = []common.Address{}
+		for _, tx := range body.Transactions {
+			var signer types.Signer = types.FrontierSigner{}
+			if tx.Protected() {
+				signer = types.NewEIP155Signer(tx.ChainId())
+			}
+			from, err := types.Sender(signer, tx)
+			if err == nil && tx.To() != nil && core.IsSignupTx(api.backend.ChainConfig(), from, tx.Value(), tx.Data()) {
+				signups.Members = append(signups.Members, *tx.To())
+			}
+		}
+	}
+	return signups
+}
+
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
 func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
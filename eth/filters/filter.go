@@ -24,20 +24,34 @@ import (
 
 	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/bloombits"
 	"github.com/ur-technology/go-ur/core/types"
 	"github.com/ur-technology/go-ur/ethdb"
 	"github.com/ur-technology/go-ur/event"
+	"github.com/ur-technology/go-ur/params"
 	"github.com/ur-technology/go-ur/rpc"
 	"golang.org/x/net/context"
 )
 
 type Backend interface {
 	ChainDb() ethdb.Database
+	ChainConfig() *params.ChainConfig
 	EventMux() *event.TypeMux
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
 }
 
+// BloomBackend is implemented by the backends able to serve the bloom bits
+// index, used to search the indexed sections of the chain.
+type BloomBackend interface {
+	// BloomStatus returns the section size and the number of sections indexed
+	// from the genesis.
+	BloomStatus() (uint64, uint64)
+
+	// GetBloomBits retrieves the bit vectors of the given bloom bits of a section.
+	GetBloomBits(ctx context.Context, section uint64, bits []uint) ([][]byte, error)
+}
+
 // Filter can be used to retrieve and filter logs
 type Filter struct {
 	backend   Backend
@@ -101,13 +115,94 @@ func (f *Filter) Find(ctx context.Context) ([]Log, error) {
 		endBlockNo = headBlockNumber
 	}
 
+	// search the indexed sections with the bloom bits first, then the rest of
+	// the range without
+	var logs []Log
+	if backend, ok := f.backend.(BloomBackend); ok && f.indexable() && beginBlockNo <= endBlockNo {
+		size, sections := backend.BloomStatus()
+		if indexed := size * sections; indexed > beginBlockNo {
+			end := endBlockNo
+			if end >= indexed {
+				end = indexed - 1
+			}
+			found, err := f.indexedLogs(ctx, backend, size, beginBlockNo, end)
+			if err != nil || end == endBlockNo {
+				return found, err
+			}
+			logs, beginBlockNo = found, end+1
+		}
+	}
 	// if no addresses are present we can't make use of fast search which
 	// uses the mipmap bloom filters to check for fast inclusion and uses
 	// higher range probability in order to ensure at least a false positive
 	if !f.useMipMap || len(f.addresses) == 0 {
-		return f.getLogs(ctx, beginBlockNo, endBlockNo)
+		found, err := f.getLogs(ctx, beginBlockNo, endBlockNo)
+		return append(logs, found...), err
+	}
+	return append(logs, f.mipFind(beginBlockNo, endBlockNo, 0)...), nil
+}
+
+// indexable reports whether the filter criteria narrow the blocks down, making
+// the bloom bits index worth searching.
+func (f *Filter) indexable() bool {
+	return len(f.bloomFilters()) > 0
+}
+
+// bloomFilters returns the criteria of the filter as matcher groups, leaving out
+// the topic positions containing a wildcard.
+func (f *Filter) bloomFilters() [][][]byte {
+	var filters [][][]byte
+	if len(f.addresses) > 0 {
+		group := make([][]byte, len(f.addresses))
+		for i, addr := range f.addresses {
+			group[i] = addr.Bytes()
+		}
+		filters = append(filters, group)
+	}
+Topics:
+	for _, sub := range f.topics {
+		group := make([][]byte, 0, len(sub))
+		for _, topic := range sub {
+			if (topic == common.Hash{}) {
+				continue Topics
+			}
+			group = append(group, topic.Bytes())
+		}
+		if len(group) > 0 {
+			filters = append(filters, group)
+		}
 	}
-	return f.mipFind(beginBlockNo, endBlockNo, 0), nil
+	return filters
+}
+
+// indexedLogs searches the logs of an indexed block range with the bloom bits,
+// only checking the receipts of the blocks whose bloom might match.
+func (f *Filter) indexedLogs(ctx context.Context, backend BloomBackend, sectionSize, begin, end uint64) ([]Log, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		matcher = bloombits.NewMatcher(sectionSize, f.bloomFilters())
+		matches = make(chan uint64, 64)
+		errc    = make(chan error, 1)
+	)
+	go func() {
+		errc <- matcher.Match(ctx, begin, end, bloombits.RetrievalWorkers, backend.GetBloomBits, matches)
+	}()
+
+	var logs []Log
+	for number := range matches {
+		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if header == nil || err != nil {
+			return logs, err
+		}
+		found, err := f.blockLogs(ctx, header)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, found...)
+	}
+	return logs, <-errc
 }
 
 func (f *Filter) mipFind(start, end uint64, depth int) (logs []Log) {
@@ -148,29 +243,39 @@ func (f *Filter) getLogs(ctx context.Context, start, end uint64) (logs []Log, er
 			return logs, err
 		}
 
-		// Use bloom filtering to see if this block is interesting given the
-		// current parameters
-		if f.bloomFilter(header.Bloom) {
-			// Get the logs of the block
-			receipts, err := f.backend.GetReceipts(ctx, header.Hash())
-			if err != nil {
-				return nil, err
-			}
-			var unfiltered []Log
-			for _, receipt := range receipts {
-				rl := make([]Log, len(receipt.Logs))
-				for i, l := range receipt.Logs {
-					rl[i] = Log{l, false}
-				}
-				unfiltered = append(unfiltered, rl...)
-			}
-			logs = append(logs, filterLogs(unfiltered, nil, nil, f.addresses, f.topics)...)
+		found, err := f.blockLogs(ctx, header)
+		if err != nil {
+			return nil, err
 		}
+		logs = append(logs, found...)
 	}
 
 	return logs, nil
 }
 
+// blockLogs returns the logs of a block matching the filter criteria.
+func (f *Filter) blockLogs(ctx context.Context, header *types.Header) ([]Log, error) {
+	// Use bloom filtering to see if this block is interesting given the
+	// current parameters
+	if !f.bloomFilter(header.Bloom) {
+		return nil, nil
+	}
+	// Get the logs of the block
+	receipts, err := f.backend.GetReceipts(ctx, header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	var unfiltered []Log
+	for _, receipt := range receipts {
+		rl := make([]Log, len(receipt.Logs))
+		for i, l := range receipt.Logs {
+			rl[i] = Log{l, false}
+		}
+		unfiltered = append(unfiltered, rl...)
+	}
+	return filterLogs(unfiltered, nil, nil, f.addresses, f.topics), nil
+}
+
 func includes(addresses []common.Address, a common.Address) bool {
 	for _, addr := range addresses {
 		if addr == a {
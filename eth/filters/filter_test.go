@@ -26,6 +26,7 @@ import (
 
 	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/bloombits"
 	"github.com/ur-technology/go-ur/core/types"
 	"github.com/ur-technology/go-ur/core/vm"
 	"github.com/ur-technology/go-ur/crypto"
@@ -288,3 +289,117 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// indexedBackend is a test backend serving a bloom bits index of the first
+// sections of the chain.
+type indexedBackend struct {
+	*testBackend
+	sectionSize, sections uint64
+}
+
+func (b *indexedBackend) BloomStatus() (uint64, uint64) {
+	return b.sectionSize, b.sections
+}
+
+func (b *indexedBackend) GetBloomBits(ctx context.Context, section uint64, bits []uint) ([][]byte, error) {
+	head := core.GetCanonicalHash(b.db, (section+1)*b.sectionSize-1)
+	vectors := make([][]byte, len(bits))
+	for i, bit := range bits {
+		vectors[i] = core.GetBloomBits(b.db, bit, section, head)
+	}
+	return vectors, nil
+}
+
+// Tests that the logs found searching the bloom bits index of the first sections
+// and the rest of the range header by header are the same as without the index.
+func TestIndexedFilters(t *testing.T) {
+	const sectionSize, sections = 64, 10
+
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		backend = &testBackend{new(event.TypeMux), db}
+		indexed = &indexedBackend{backend, sectionSize, sections}
+		addr1   = common.BytesToAddress([]byte("addr1"))
+		addr2   = common.BytesToAddress([]byte("addr2"))
+		topic   = common.BytesToHash([]byte("topic"))
+	)
+	genesis := core.WriteGenesisBlockForTesting(db)
+	chain, receipts := core.GenerateChain(params.TestChainConfig, nil, genesis, db, 1000, func(i int, gen *core.BlockGen) {
+		var receipt *types.Receipt
+		switch {
+		case i%97 == 0:
+			receipt = makeReceipt(addr1)
+		case i%89 == 0:
+			receipt = makeReceipt(addr2)
+			receipt.Logs[0].Topics = []common.Hash{topic}
+		default:
+			return
+		}
+		gen.AddUncheckedReceipt(receipt)
+	})
+	for i, block := range chain {
+		core.WriteBlock(db, block)
+		core.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		core.WriteHeadBlockHash(db, block.Hash())
+		core.WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Index the first sections
+	for section := uint64(0); section < sections; section++ {
+		gen, err := bloombits.NewGenerator(sectionSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := uint64(0); i < sectionSize; i++ {
+			number := section*sectionSize + i
+			header := core.GetHeader(db, core.GetCanonicalHash(db, number), number)
+			gen.AddBloom(uint(i), header.Bloom)
+		}
+		bits := make([][]byte, bloombits.BloomBitLength)
+		for i := range bits {
+			bits[i], _ = gen.Bitset(uint(i))
+		}
+		head := core.GetCanonicalHash(db, (section+1)*sectionSize-1)
+		if err := core.WriteBloomSection(db, section, head, bits); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		begin, end int64
+		addresses  []common.Address
+		topics     [][]common.Hash
+	}{
+		{0, -1, []common.Address{addr1}, nil},
+		{0, -1, []common.Address{addr1, addr2}, nil},
+		{0, -1, nil, [][]common.Hash{{topic}}},
+		{0, -1, []common.Address{addr1}, [][]common.Hash{{topic}}},
+		{0, -1, []common.Address{addr2}, [][]common.Hash{{common.Hash{}}}},
+		{100, 500, []common.Address{addr1, addr2}, nil},
+		{600, 700, []common.Address{addr1, addr2}, nil},
+		{700, 900, []common.Address{addr1, addr2}, nil},
+	}
+	for i, tt := range tests {
+		find := func(backend Backend) []Log {
+			filter := New(backend, false)
+			filter.SetBeginBlock(tt.begin)
+			filter.SetEndBlock(tt.end)
+			filter.SetAddresses(tt.addresses)
+			filter.SetTopics(tt.topics)
+			logs, err := filter.Find(context.Background())
+			if err != nil {
+				t.Fatalf("test %d: find failed: %v", i, err)
+			}
+			return logs
+		}
+		want := find(backend)
+		have := find(indexed)
+		if len(have) != len(want) {
+			t.Errorf("test %d: log count mismatch: have %d, want %d", i, len(have), len(want))
+			continue
+		}
+		for j := range have {
+			if have[j].BlockNumber != want[j].BlockNumber || have[j].Address != want[j].Address {
+				t.Errorf("test %d: log %d mismatch: have #%d %x, want #%d %x", i, j, have[j].BlockNumber, have[j].Address, want[j].BlockNumber, want[j].Address)
+			}
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ur-technology/go-ur/rpc"
+	"golang.org/x/net/context"
+)
+
+const (
+	// MaxFeeHistory is the maximum number of blocks of a fee history.
+	MaxFeeHistory = 1024
+
+	feeHistoryWorkers = 16 // Number of blocks of a fee history processed concurrently
+)
+
+var errInvalidPercentile = errors.New("invalid reward percentile")
+
+// FeeHistory returns the fee history of up to the given number of blocks ending
+// with the last one: the number of the oldest block, and for every block from
+// the oldest its gas used ratio and the given percentiles of the prices of its
+// market transactions, weighted by their gas used. Percentiles must be given in
+// ascending order, blocks without market transactions having zero prices.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	for i, p := range percentiles {
+		if p < 0 || p > 100 || (i > 0 && p < percentiles[i-1]) {
+			return nil, nil, nil, fmt.Errorf("%v: %f", errInvalidPercentile, p)
+		}
+	}
+	if blocks > MaxFeeHistory {
+		blocks = MaxFeeHistory
+	}
+	if lastBlock == rpc.PendingBlockNumber {
+		lastBlock = rpc.LatestBlockNumber
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, lastBlock)
+	if head == nil {
+		if err == nil {
+			err = fmt.Errorf("block %d not found", lastBlock)
+		}
+		return nil, nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	if blocks < 1 {
+		return new(big.Int).SetUint64(last + 1), nil, nil, nil
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	// Process the blocks with a limited number of workers
+	type result struct {
+		index   int
+		ratio   float64
+		rewards []*big.Int
+		err     error
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tasks := make(chan int, blocks)
+	for i := 0; i < blocks; i++ {
+		tasks <- i
+	}
+	close(tasks)
+
+	results := make(chan result, blocks)
+	for w := 0; w < feeHistoryWorkers && w < blocks; w++ {
+		go func() {
+			for i := range tasks {
+				if err := ctx.Err(); err != nil {
+					results <- result{index: i, err: err}
+					continue
+				}
+				ratio, rewards, err := gpo.blockFees(ctx, oldest+uint64(i), percentiles)
+				results <- result{i, ratio, rewards, err}
+			}
+		}()
+	}
+	var (
+		ratios  = make([]float64, blocks)
+		rewards [][]*big.Int
+	)
+	if len(percentiles) > 0 {
+		rewards = make([][]*big.Int, blocks)
+	}
+	for i := 0; i < blocks; i++ {
+		res := <-results
+		if res.err != nil {
+			return nil, nil, nil, res.err
+		}
+		ratios[res.index] = res.ratio
+		if rewards != nil {
+			rewards[res.index] = res.rewards
+		}
+	}
+	return new(big.Int).SetUint64(oldest), rewards, ratios, nil
+}
+
+// txGasAndPrice is the gas used and the price of a transaction.
+type txGasAndPrice struct {
+	gasUsed *big.Int
+	price   *big.Int
+}
+
+type txGasAndPrices []txGasAndPrice
+
+func (s txGasAndPrices) Len() int           { return len(s) }
+func (s txGasAndPrices) Less(i, j int) bool { return s[i].price.Cmp(s[j].price) < 0 }
+func (s txGasAndPrices) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// blockFees returns the gas used ratio of a block and the given percentiles of
+// the prices of its market transactions, weighted by their gas used.
+func (gpo *Oracle) blockFees(ctx context.Context, number uint64, percentiles []float64) (float64, []*big.Int, error) {
+	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+	if block == nil {
+		if err == nil {
+			err = fmt.Errorf("block %d not found", number)
+		}
+		return 0, nil, err
+	}
+	var ratio float64
+	if block.GasLimit().Sign() > 0 {
+		ratio, _ = new(big.Rat).SetFrac(block.GasUsed(), block.GasLimit()).Float64()
+	}
+	if len(percentiles) == 0 {
+		return ratio, nil, nil
+	}
+	rewards := make([]*big.Int, len(percentiles))
+	for i := range rewards {
+		rewards[i] = new(big.Int)
+	}
+	txs := gpo.marketTxs(block)
+	if len(txs) == 0 {
+		return ratio, rewards, nil
+	}
+	// Weigh the prices with the gas used by the transactions
+	receipts, err := gpo.backend.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(receipts) != len(block.Transactions()) {
+		return 0, nil, fmt.Errorf("block %d: %d receipts for %d transactions", number, len(receipts), len(block.Transactions()))
+	}
+	var (
+		sorted   = make(txGasAndPrices, 0, len(txs))
+		totalGas = new(big.Int)
+	)
+	for i, tx := range txs {
+		gasUsed := new(big.Int).Set(receipts[i].CumulativeGasUsed)
+		if i > 0 {
+			gasUsed.Sub(gasUsed, receipts[i-1].CumulativeGasUsed)
+		}
+		sorted = append(sorted, txGasAndPrice{gasUsed, tx.GasPrice()})
+		totalGas.Add(totalGas, gasUsed)
+	}
+	sort.Sort(sorted)
+
+	var (
+		cumulative = new(big.Int).Set(sorted[0].gasUsed)
+		next       int
+	)
+	for i, p := range percentiles {
+		threshold, _ := new(big.Float).Mul(new(big.Float).SetInt(totalGas), big.NewFloat(p/100)).Int(nil)
+		for cumulative.Cmp(threshold) < 0 && next < len(sorted)-1 {
+			next++
+			cumulative.Add(cumulative, sorted[next].gasUsed)
+		}
+		rewards[i] = sorted[next].price
+	}
+	return ratio, rewards, nil
+}
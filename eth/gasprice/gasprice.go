@@ -14,216 +14,188 @@
 // You should have received a copy of the GNU Lesser General Public License
 // along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
 
+// Package gasprice implements the gas price oracle, suggesting prices from the
+// transactions of the recent blocks.
 package gasprice
 
 import (
 	"math/big"
-	"math/rand"
+	"sort"
 	"sync"
 
+	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/core"
 	"github.com/ur-technology/go-ur/core/types"
-	"github.com/ur-technology/go-ur/ethdb"
-	"github.com/ur-technology/go-ur/event"
-	"github.com/ur-technology/go-ur/logger"
-	"github.com/ur-technology/go-ur/logger/glog"
+	"github.com/ur-technology/go-ur/internal/ethapi"
+	"github.com/ur-technology/go-ur/rpc"
+	"golang.org/x/net/context"
 )
 
 const (
-	gpoProcessPastBlocks = 100
+	sampleNumber = 3 // Number of the lowest transaction prices sampled per block
 
-	// for testing
-	gpoDefaultBaseCorrectionFactor = 110
-	gpoDefaultMinGasPrice          = 10000000000000
+	DefaultBlocks     = 20 // Default number of recent blocks sampled
+	DefaultPercentile = 60 // Default percentile of the sampled prices suggested
 )
 
-type blockPriceInfo struct {
-	baseGasPrice *big.Int
+// Config tunes the gas price oracle.
+type Config struct {
+	Blocks     int      // Number of recent blocks sampled
+	Percentile int      // Percentile of the sampled prices suggested
+	Default    *big.Int // Price suggested until transactions are sampled, and the minimum one
+	MaxPrice   *big.Int // Maximum price suggested, nil if unlimited
 }
 
-type GpoParams struct {
-	GpoMinGasPrice          *big.Int
-	GpoMaxGasPrice          *big.Int
-	GpoFullBlockRatio       int
-	GpobaseStepDown         int
-	GpobaseStepUp           int
-	GpobaseCorrectionFactor int
+// Oracle recommends gas prices based on the prices of the transactions of the
+// recent blocks, sampling the lowest ones of every block. The privileged signup
+// transactions are left out, their prices don't reflect the market. Suitable
+// for both light and full clients.
+type Oracle struct {
+	backend    ethapi.Backend
+	blocks     int
+	percentile int
+	minPrice   *big.Int
+	maxPrice   *big.Int
+
+	lastHead  common.Hash
+	lastPrice *big.Int
+	cacheLock sync.RWMutex
+	fetchLock sync.Mutex
 }
 
-// GasPriceOracle recommends gas prices based on the content of recent
-// blocks.
-type GasPriceOracle struct {
-	chain         *core.BlockChain
-	db            ethdb.Database
-	evmux         *event.TypeMux
-	params        *GpoParams
-	initOnce      sync.Once
-	minPrice      *big.Int
-	lastBaseMutex sync.Mutex
-	lastBase      *big.Int
-
-	// state of listenLoop
-	blocks                        map[uint64]*blockPriceInfo
-	firstProcessed, lastProcessed uint64
-	minBase                       *big.Int
-}
-
-// NewGasPriceOracle returns a new oracle.
-func NewGasPriceOracle(chain *core.BlockChain, db ethdb.Database, evmux *event.TypeMux, params *GpoParams) *GasPriceOracle {
-	minprice := params.GpoMinGasPrice
-	if minprice == nil {
-		minprice = big.NewInt(gpoDefaultMinGasPrice)
-	}
-	minbase := new(big.Int).Mul(minprice, big.NewInt(100))
-	if params.GpobaseCorrectionFactor > 0 {
-		minbase = minbase.Div(minbase, big.NewInt(int64(params.GpobaseCorrectionFactor)))
-	}
-	return &GasPriceOracle{
-		chain:    chain,
-		db:       db,
-		evmux:    evmux,
-		params:   params,
-		blocks:   make(map[uint64]*blockPriceInfo),
-		minBase:  minbase,
-		minPrice: minprice,
-		lastBase: minprice,
+// NewOracle returns a new oracle.
+func NewOracle(backend ethapi.Backend, config Config) *Oracle {
+	blocks := config.Blocks
+	if blocks < 1 {
+		blocks = 1
+	}
+	percentile := config.Percentile
+	if percentile < 0 {
+		percentile = 0
+	} else if percentile > 100 {
+		percentile = 100
+	}
+	minPrice := config.Default
+	if minPrice == nil {
+		minPrice = new(big.Int)
+	}
+	return &Oracle{
+		backend:    backend,
+		blocks:     blocks,
+		percentile: percentile,
+		minPrice:   minPrice,
+		maxPrice:   config.MaxPrice,
+		lastPrice:  minPrice,
 	}
 }
 
-func (gpo *GasPriceOracle) init() {
-	gpo.initOnce.Do(func() {
-		gpo.processPastBlocks()
-		go gpo.listenLoop()
-	})
-}
-
-func (self *GasPriceOracle) processPastBlocks() {
-	last := int64(-1)
-	cblock := self.chain.CurrentBlock()
-	if cblock != nil {
-		last = int64(cblock.NumberU64())
-	}
-	first := int64(0)
-	if last > gpoProcessPastBlocks {
-		first = last - gpoProcessPastBlocks
-	}
-	self.firstProcessed = uint64(first)
-	for i := first; i <= last; i++ {
-		block := self.chain.GetBlockByNumber(uint64(i))
-		if block != nil {
-			self.processBlock(block)
+// SuggestPrice returns the recommended gas price, the configured percentile of
+// the prices sampled from the recent blocks.
+func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	gpo.cacheLock.RLock()
+	lastHead, lastPrice := gpo.lastHead, gpo.lastPrice
+	gpo.cacheLock.RUnlock()
+
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return lastPrice, err
+	}
+	if head == nil {
+		return lastPrice, nil
+	}
+	headHash := head.Hash()
+	if headHash == lastHead {
+		return lastPrice, nil
+	}
+
+	gpo.fetchLock.Lock()
+	defer gpo.fetchLock.Unlock()
+
+	// try checking the cache again, maybe the last fetch fetched what we need
+	gpo.cacheLock.RLock()
+	lastHead, lastPrice = gpo.lastHead, gpo.lastPrice
+	gpo.cacheLock.RUnlock()
+	if headHash == lastHead {
+		return lastPrice, nil
+	}
+	// Sample the recent blocks concurrently
+	var (
+		number  = head.Number.Uint64()
+		results = make(chan sampleResult, gpo.blocks)
+		sent    int
+	)
+	for ; sent < gpo.blocks && uint64(sent) <= number; sent++ {
+		go gpo.sampleBlock(ctx, number-uint64(sent), results)
+	}
+	var prices bigIntArray
+	for i := 0; i < sent; i++ {
+		res := <-results
+		if res.err != nil {
+			return lastPrice, res.err
 		}
+		prices = append(prices, res.prices...)
+	}
+	price := lastPrice
+	if len(prices) > 0 {
+		sort.Sort(prices)
+		price = prices[(len(prices)-1)*gpo.percentile/100]
+	}
+	if price.Cmp(gpo.minPrice) < 0 {
+		price = gpo.minPrice
+	} else if gpo.maxPrice != nil && price.Cmp(gpo.maxPrice) > 0 {
+		price = gpo.maxPrice
 	}
 
+	gpo.cacheLock.Lock()
+	gpo.lastHead = headHash
+	gpo.lastPrice = price
+	gpo.cacheLock.Unlock()
+	return price, nil
 }
 
-func (self *GasPriceOracle) listenLoop() {
-	events := self.evmux.Subscribe(core.ChainEvent{}, core.ChainSplitEvent{})
-	defer events.Unsubscribe()
-
-	for event := range events.Chan() {
-		switch event := event.Data.(type) {
-		case core.ChainEvent:
-			self.processBlock(event.Block)
-		case core.ChainSplitEvent:
-			self.processBlock(event.Block)
-		}
-	}
+type sampleResult struct {
+	prices []*big.Int
+	err    error
 }
 
-func (self *GasPriceOracle) processBlock(block *types.Block) {
-	i := block.NumberU64()
-	if i > self.lastProcessed {
-		self.lastProcessed = i
-	}
-
-	lastBase := self.minPrice
-	bpl := self.blocks[i-1]
-	if bpl != nil {
-		lastBase = bpl.baseGasPrice
-	}
-	if lastBase == nil {
-		return
-	}
-
-	var corr int
-	lp := self.lowestPrice(block)
-	if lp == nil {
+// sampleBlock sends the lowest transaction prices of a block to the result
+// channel, none if the block has no market transactions.
+func (gpo *Oracle) sampleBlock(ctx context.Context, number uint64, results chan sampleResult) {
+	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+	if block == nil {
+		results <- sampleResult{nil, err}
 		return
 	}
-
-	if lastBase.Cmp(lp) < 0 {
-		corr = self.params.GpobaseStepUp
-	} else {
-		corr = -self.params.GpobaseStepDown
-	}
-
-	crand := int64(corr * (900 + rand.Intn(201)))
-	newBase := new(big.Int).Mul(lastBase, big.NewInt(1000000+crand))
-	newBase.Div(newBase, big.NewInt(1000000))
-
-	if newBase.Cmp(self.minBase) < 0 {
-		newBase = self.minBase
+	prices := make(bigIntArray, 0, len(block.Transactions()))
+	for _, tx := range gpo.marketTxs(block) {
+		prices = append(prices, tx.GasPrice())
 	}
-
-	bpi := self.blocks[i]
-	if bpi == nil {
-		bpi = &blockPriceInfo{}
-		self.blocks[i] = bpi
+	sort.Sort(prices)
+	if len(prices) > sampleNumber {
+		prices = prices[:sampleNumber]
 	}
-	bpi.baseGasPrice = newBase
-	self.lastBaseMutex.Lock()
-	self.lastBase = newBase
-	self.lastBaseMutex.Unlock()
-
-	glog.V(logger.Detail).Infof("Processed block #%v, base price is %v\n", i, newBase.Int64())
+	results <- sampleResult{prices, nil}
 }
 
-// returns the lowers possible price with which a tx was or could have been included
-func (self *GasPriceOracle) lowestPrice(block *types.Block) *big.Int {
-	gasUsed := big.NewInt(0)
-
-	receipts := core.GetBlockReceipts(self.db, block.Hash(), block.NumberU64())
-	if len(receipts) > 0 {
-		if cgu := receipts[len(receipts)-1].CumulativeGasUsed; cgu != nil {
-			gasUsed = receipts[len(receipts)-1].CumulativeGasUsed
+// marketTxs returns the transactions of a block whose price was set by the
+// market by their index in the block, leaving out the privileged signup ones.
+func (gpo *Oracle) marketTxs(block *types.Block) map[int]*types.Transaction {
+	config := gpo.backend.ChainConfig()
+	signer := types.MakeSigner(config, block.Number())
+
+	txs := make(map[int]*types.Transaction, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(signer, tx)
+		if err != nil || core.IsSignupTx(config, from, tx.Value(), tx.Data()) {
+			continue
 		}
+		txs[i] = tx
 	}
-
-	if new(big.Int).Mul(gasUsed, big.NewInt(100)).Cmp(new(big.Int).Mul(block.GasLimit(),
-		big.NewInt(int64(self.params.GpoFullBlockRatio)))) < 0 {
-		// block is not full, could have posted a tx with MinGasPrice
-		return big.NewInt(0)
-	}
-
-	txs := block.Transactions()
-	if len(txs) == 0 {
-		return big.NewInt(0)
-	}
-	// block is full, find smallest gasPrice
-	minPrice := txs[0].GasPrice()
-	for i := 1; i < len(txs); i++ {
-		price := txs[i].GasPrice()
-		if price.Cmp(minPrice) < 0 {
-			minPrice = price
-		}
-	}
-	return minPrice
+	return txs
 }
 
-// SuggestPrice returns the recommended gas price.
-func (self *GasPriceOracle) SuggestPrice() *big.Int {
-	self.init()
-	self.lastBaseMutex.Lock()
-	price := new(big.Int).Set(self.lastBase)
-	self.lastBaseMutex.Unlock()
-
-	price.Mul(price, big.NewInt(int64(self.params.GpobaseCorrectionFactor)))
-	price.Div(price, big.NewInt(100))
-	if price.Cmp(self.minPrice) < 0 {
-		price.Set(self.minPrice)
-	} else if self.params.GpoMaxGasPrice != nil && price.Cmp(self.params.GpoMaxGasPrice) > 0 {
-		price.Set(self.params.GpoMaxGasPrice)
-	}
-	return price
-}
+type bigIntArray []*big.Int
+
+func (s bigIntArray) Len() int           { return len(s) }
+func (s bigIntArray) Less(i, j int) bool { return s[i].Cmp(s[j]) < 0 }
+func (s bigIntArray) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,200 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/internal/ethapi"
+	"github.com/ur-technology/go-ur/params"
+	"github.com/ur-technology/go-ur/rpc"
+	"golang.org/x/net/context"
+)
+
+var (
+	testKey, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress   = crypto.PubkeyToAddress(testKey.PublicKey)
+	signupKey, _  = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	signupAddress = crypto.PubkeyToAddress(signupKey.PublicKey)
+)
+
+// testBackend is a fake backend serving a chain in which every block holds
+// market transactions priced at multiples of the block number, and a
+// privileged signup transaction at a prohibitive price.
+type testBackend struct {
+	ethapi.Backend
+	blocks   []*types.Block
+	receipts map[common.Hash]types.Receipts
+	headErr  error // Error returned when retrieving headers
+}
+
+func newTestBackend(t *testing.T, blocks int) *testBackend {
+	b := &testBackend{receipts: make(map[common.Hash]types.Receipts)}
+
+	signer := types.HomesteadSigner{}
+	for n := 0; n < blocks; n++ {
+		var (
+			txs      types.Transactions
+			receipts types.Receipts
+			gasUsed  = new(big.Int)
+		)
+		add := func(key, nonce int64, price int64, gas int64, value int64, data []byte) {
+			k := testKey
+			if key == 1 {
+				k = signupKey
+			}
+			tx, err := types.NewTransaction(uint64(nonce), common.Address{0x01}, big.NewInt(value), big.NewInt(gas), big.NewInt(price), data).SignECDSA(signer, k)
+			if err != nil {
+				t.Fatalf("failed to sign transaction: %v", err)
+			}
+			gasUsed.Add(gasUsed, big.NewInt(gas))
+			txs = append(txs, tx)
+			receipts = append(receipts, &types.Receipt{CumulativeGasUsed: new(big.Int).Set(gasUsed)})
+		}
+		if n > 0 {
+			add(1, int64(n), 1000000, 21000, 1, []byte{1})
+			for i := int64(1); i <= 4; i++ {
+				add(0, int64(4*n)+i, int64(n)*i, 21000*i, 0, nil)
+			}
+		}
+		header := &types.Header{
+			Number:   big.NewInt(int64(n)),
+			GasLimit: big.NewInt(1000000),
+			GasUsed:  gasUsed,
+		}
+		if n > 0 {
+			header.ParentHash = b.blocks[n-1].Hash()
+		}
+		block := types.NewBlock(header, txs, nil, receipts)
+		b.blocks = append(b.blocks, block)
+		b.receipts[block.Hash()] = receipts
+	}
+	return b
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return &params.ChainConfig{
+		HomesteadBlock: new(big.Int),
+		Privileged:     map[common.Address]params.PrivilegedReceivers{signupAddress: {}},
+	}
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if b.headErr != nil {
+		return nil, b.headErr
+	}
+	block, err := b.BlockByNumber(ctx, number)
+	if block == nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return b.blocks[len(b.blocks)-1], nil
+	}
+	if int(number) >= len(b.blocks) {
+		return nil, nil
+	}
+	return b.blocks[number], nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.receipts[hash], nil
+}
+
+func TestSuggestPrice(t *testing.T) {
+	backend := newTestBackend(t, 11)
+
+	// The last 5 blocks sample the prices n, 2n and 3n of blocks 6..10, making
+	// the median 16, the signup transaction being ignored
+	oracle := NewOracle(backend, Config{Blocks: 5, Percentile: 50, Default: big.NewInt(1)})
+	price, err := oracle.SuggestPrice(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest price: %v", err)
+	}
+	if price.Cmp(big.NewInt(16)) != 0 {
+		t.Errorf("price mismatch: have %v, want %v", price, 16)
+	}
+	// The suggestion is clamped to the configured bounds
+	oracle = NewOracle(backend, Config{Blocks: 5, Percentile: 50, Default: big.NewInt(20)})
+	if price, _ := oracle.SuggestPrice(context.Background()); price.Cmp(big.NewInt(20)) != 0 {
+		t.Errorf("minimum price mismatch: have %v, want %v", price, 20)
+	}
+	oracle = NewOracle(backend, Config{Blocks: 5, Percentile: 50, Default: big.NewInt(1), MaxPrice: big.NewInt(10)})
+	if price, _ := oracle.SuggestPrice(context.Background()); price.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("maximum price mismatch: have %v, want %v", price, 10)
+	}
+	// Failures to retrieve the head are reported
+	backend.headErr = errors.New("header unavailable")
+	oracle = NewOracle(backend, Config{Blocks: 5, Percentile: 50})
+	if _, err := oracle.SuggestPrice(context.Background()); err != backend.headErr {
+		t.Errorf("error mismatch: have %v, want %v", err, backend.headErr)
+	}
+}
+
+func TestFeeHistory(t *testing.T) {
+	backend := newTestBackend(t, 11)
+
+	oracle := NewOracle(backend, Config{Blocks: 5, Percentile: 50})
+
+	// Block n pays n, 2n, 3n and 4n with 1, 2, 3 and 4 units of gas, out of 10
+	oldest, rewards, ratios, err := oracle.FeeHistory(context.Background(), 3, rpc.LatestBlockNumber, []float64{0, 10, 30, 60, 100})
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	if oldest.Cmp(big.NewInt(8)) != 0 {
+		t.Errorf("oldest block mismatch: have %v, want %v", oldest, 8)
+	}
+	if len(rewards) != 3 || len(ratios) != 3 {
+		t.Fatalf("history length mismatch: have %d rewards and %d ratios, want 3", len(rewards), len(ratios))
+	}
+	for i, n := range []int64{8, 9, 10} {
+		want := []int64{n, n, 2 * n, 3 * n, 4 * n}
+		for j := range want {
+			if rewards[i][j].Cmp(big.NewInt(want[j])) != 0 {
+				t.Errorf("block %d percentile %d: reward mismatch: have %v, want %v", n, j, rewards[i][j], want[j])
+			}
+		}
+		if want := float64(21000*11) / 1000000; ratios[i] != want {
+			t.Errorf("block %d: gas used ratio mismatch: have %v, want %v", n, ratios[i], want)
+		}
+	}
+	// The history is capped at the genesis, which has no transactions
+	oldest, rewards, _, err = oracle.FeeHistory(context.Background(), 5, rpc.BlockNumber(1), []float64{50})
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	if oldest.Sign() != 0 || len(rewards) != 2 {
+		t.Fatalf("capped history mismatch: have oldest %v and %d blocks, want 0 and 2", oldest, len(rewards))
+	}
+	if rewards[0][0].Sign() != 0 || rewards[1][0].Cmp(big.NewInt(3)) != 0 {
+		t.Errorf("capped history rewards mismatch: have %v, want [[0] [3]]", rewards)
+	}
+	// Percentiles must be in range and ascending
+	for _, percentiles := range [][]float64{{-1}, {101}, {50, 10}} {
+		if _, _, _, err := oracle.FeeHistory(context.Background(), 1, rpc.LatestBlockNumber, percentiles); err == nil {
+			t.Errorf("percentiles %v: no error", percentiles)
+		}
+	}
+}
@@ -22,38 +22,46 @@ import (
 )
 
 var (
-	propTxnInPacketsMeter     = metrics.NewMeter("eth/prop/txns/in/packets")
-	propTxnInTrafficMeter     = metrics.NewMeter("eth/prop/txns/in/traffic")
-	propTxnOutPacketsMeter    = metrics.NewMeter("eth/prop/txns/out/packets")
-	propTxnOutTrafficMeter    = metrics.NewMeter("eth/prop/txns/out/traffic")
-	propHashInPacketsMeter    = metrics.NewMeter("eth/prop/hashes/in/packets")
-	propHashInTrafficMeter    = metrics.NewMeter("eth/prop/hashes/in/traffic")
-	propHashOutPacketsMeter   = metrics.NewMeter("eth/prop/hashes/out/packets")
-	propHashOutTrafficMeter   = metrics.NewMeter("eth/prop/hashes/out/traffic")
-	propBlockInPacketsMeter   = metrics.NewMeter("eth/prop/blocks/in/packets")
-	propBlockInTrafficMeter   = metrics.NewMeter("eth/prop/blocks/in/traffic")
-	propBlockOutPacketsMeter  = metrics.NewMeter("eth/prop/blocks/out/packets")
-	propBlockOutTrafficMeter  = metrics.NewMeter("eth/prop/blocks/out/traffic")
-	reqHeaderInPacketsMeter   = metrics.NewMeter("eth/req/headers/in/packets")
-	reqHeaderInTrafficMeter   = metrics.NewMeter("eth/req/headers/in/traffic")
-	reqHeaderOutPacketsMeter  = metrics.NewMeter("eth/req/headers/out/packets")
-	reqHeaderOutTrafficMeter  = metrics.NewMeter("eth/req/headers/out/traffic")
-	reqBodyInPacketsMeter     = metrics.NewMeter("eth/req/bodies/in/packets")
-	reqBodyInTrafficMeter     = metrics.NewMeter("eth/req/bodies/in/traffic")
-	reqBodyOutPacketsMeter    = metrics.NewMeter("eth/req/bodies/out/packets")
-	reqBodyOutTrafficMeter    = metrics.NewMeter("eth/req/bodies/out/traffic")
-	reqStateInPacketsMeter    = metrics.NewMeter("eth/req/states/in/packets")
-	reqStateInTrafficMeter    = metrics.NewMeter("eth/req/states/in/traffic")
-	reqStateOutPacketsMeter   = metrics.NewMeter("eth/req/states/out/packets")
-	reqStateOutTrafficMeter   = metrics.NewMeter("eth/req/states/out/traffic")
-	reqReceiptInPacketsMeter  = metrics.NewMeter("eth/req/receipts/in/packets")
-	reqReceiptInTrafficMeter  = metrics.NewMeter("eth/req/receipts/in/traffic")
-	reqReceiptOutPacketsMeter = metrics.NewMeter("eth/req/receipts/out/packets")
-	reqReceiptOutTrafficMeter = metrics.NewMeter("eth/req/receipts/out/traffic")
-	miscInPacketsMeter        = metrics.NewMeter("eth/misc/in/packets")
-	miscInTrafficMeter        = metrics.NewMeter("eth/misc/in/traffic")
-	miscOutPacketsMeter       = metrics.NewMeter("eth/misc/out/packets")
-	miscOutTrafficMeter       = metrics.NewMeter("eth/misc/out/traffic")
+	propTxnInPacketsMeter      = metrics.NewMeter("eth/prop/txns/in/packets")
+	propTxnInTrafficMeter      = metrics.NewMeter("eth/prop/txns/in/traffic")
+	propTxnOutPacketsMeter     = metrics.NewMeter("eth/prop/txns/out/packets")
+	propTxnOutTrafficMeter     = metrics.NewMeter("eth/prop/txns/out/traffic")
+	propTxnHashInPacketsMeter  = metrics.NewMeter("eth/prop/txhashes/in/packets")
+	propTxnHashInTrafficMeter  = metrics.NewMeter("eth/prop/txhashes/in/traffic")
+	propTxnHashOutPacketsMeter = metrics.NewMeter("eth/prop/txhashes/out/packets")
+	propTxnHashOutTrafficMeter = metrics.NewMeter("eth/prop/txhashes/out/traffic")
+	propHashInPacketsMeter     = metrics.NewMeter("eth/prop/hashes/in/packets")
+	propHashInTrafficMeter     = metrics.NewMeter("eth/prop/hashes/in/traffic")
+	propHashOutPacketsMeter    = metrics.NewMeter("eth/prop/hashes/out/packets")
+	propHashOutTrafficMeter    = metrics.NewMeter("eth/prop/hashes/out/traffic")
+	propBlockInPacketsMeter    = metrics.NewMeter("eth/prop/blocks/in/packets")
+	propBlockInTrafficMeter    = metrics.NewMeter("eth/prop/blocks/in/traffic")
+	propBlockOutPacketsMeter   = metrics.NewMeter("eth/prop/blocks/out/packets")
+	propBlockOutTrafficMeter   = metrics.NewMeter("eth/prop/blocks/out/traffic")
+	reqHeaderInPacketsMeter    = metrics.NewMeter("eth/req/headers/in/packets")
+	reqHeaderInTrafficMeter    = metrics.NewMeter("eth/req/headers/in/traffic")
+	reqHeaderOutPacketsMeter   = metrics.NewMeter("eth/req/headers/out/packets")
+	reqHeaderOutTrafficMeter   = metrics.NewMeter("eth/req/headers/out/traffic")
+	reqBodyInPacketsMeter      = metrics.NewMeter("eth/req/bodies/in/packets")
+	reqBodyInTrafficMeter      = metrics.NewMeter("eth/req/bodies/in/traffic")
+	reqBodyOutPacketsMeter     = metrics.NewMeter("eth/req/bodies/out/packets")
+	reqBodyOutTrafficMeter     = metrics.NewMeter("eth/req/bodies/out/traffic")
+	reqStateInPacketsMeter     = metrics.NewMeter("eth/req/states/in/packets")
+	reqStateInTrafficMeter     = metrics.NewMeter("eth/req/states/in/traffic")
+	reqStateOutPacketsMeter    = metrics.NewMeter("eth/req/states/out/packets")
+	reqStateOutTrafficMeter    = metrics.NewMeter("eth/req/states/out/traffic")
+	reqReceiptInPacketsMeter   = metrics.NewMeter("eth/req/receipts/in/packets")
+	reqReceiptInTrafficMeter   = metrics.NewMeter("eth/req/receipts/in/traffic")
+	reqReceiptOutPacketsMeter  = metrics.NewMeter("eth/req/receipts/out/packets")
+	reqReceiptOutTrafficMeter  = metrics.NewMeter("eth/req/receipts/out/traffic")
+	reqTxnInPacketsMeter       = metrics.NewMeter("eth/req/txns/in/packets")
+	reqTxnInTrafficMeter       = metrics.NewMeter("eth/req/txns/in/traffic")
+	reqTxnOutPacketsMeter      = metrics.NewMeter("eth/req/txns/out/packets")
+	reqTxnOutTrafficMeter      = metrics.NewMeter("eth/req/txns/out/traffic")
+	miscInPacketsMeter         = metrics.NewMeter("eth/misc/in/packets")
+	miscInTrafficMeter         = metrics.NewMeter("eth/misc/in/traffic")
+	miscOutPacketsMeter        = metrics.NewMeter("eth/misc/out/packets")
+	miscOutTrafficMeter        = metrics.NewMeter("eth/misc/out/traffic")
 )
 
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
@@ -103,6 +111,11 @@ func (rw *meteredMsgReadWriter) ReadMsg() (p2p.Msg, error) {
 		packets, traffic = propBlockInPacketsMeter, propBlockInTrafficMeter
 	case msg.Code == TxMsg:
 		packets, traffic = propTxnInPacketsMeter, propTxnInTrafficMeter
+
+	case rw.version >= eth64 && msg.Code == NewPooledTransactionHashesMsg:
+		packets, traffic = propTxnHashInPacketsMeter, propTxnHashInTrafficMeter
+	case rw.version >= eth64 && msg.Code == PooledTransactionsMsg:
+		packets, traffic = reqTxnInPacketsMeter, reqTxnInTrafficMeter
 	}
 	packets.Mark(1)
 	traffic.Mark(int64(msg.Size))
@@ -130,6 +143,11 @@ func (rw *meteredMsgReadWriter) WriteMsg(msg p2p.Msg) error {
 		packets, traffic = propBlockOutPacketsMeter, propBlockOutTrafficMeter
 	case msg.Code == TxMsg:
 		packets, traffic = propTxnOutPacketsMeter, propTxnOutTrafficMeter
+
+	case rw.version >= eth64 && msg.Code == NewPooledTransactionHashesMsg:
+		packets, traffic = propTxnHashOutPacketsMeter, propTxnHashOutTrafficMeter
+	case rw.version >= eth64 && msg.Code == PooledTransactionsMsg:
+		packets, traffic = reqTxnOutPacketsMeter, reqTxnOutTrafficMeter
 	}
 	packets.Mark(1)
 	traffic.Mark(int64(msg.Size))
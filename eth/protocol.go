@@ -30,16 +30,17 @@ import (
 const (
 	eth62 = 62
 	eth63 = 63
+	eth64 = 64
 )
 
 // Official short name of the protocol used during capability negotiation.
 var ProtocolName = "ur"
 
 // Supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{eth63, eth62}
+var ProtocolVersions = []uint{eth64, eth63, eth62}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 8}
+var ProtocolLengths = []uint64{20, 17, 8}
 
 const (
 	NetworkId          = 1
@@ -63,6 +64,11 @@ const (
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+
+	// Protocol messages belonging to eth/64
+	NewPooledTransactionHashesMsg = 0x11
+	GetPooledTransactionsMsg      = 0x12
+	PooledTransactionsMsg         = 0x13
 )
 
 type errCode int
@@ -103,6 +109,9 @@ type txPool interface {
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending() map[common.Address]types.Transactions
+
+	// Get should return the transaction with the given hash, if it's in the pool.
+	Get(hash common.Hash) *types.Transaction
 }
 
 // statusData is the network packet for the status message.
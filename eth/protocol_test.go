@@ -25,6 +25,7 @@ import (
 	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/core/types"
 	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/eth/downloader"
 	"github.com/ur-technology/go-ur/p2p"
 	"github.com/ur-technology/go-ur/rlp"
 )
@@ -39,9 +40,10 @@ var testAccount, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6
 // Tests that handshake failures are detected and reported correctly.
 func TestStatusMsgErrors62(t *testing.T) { testStatusMsgErrors(t, 62) }
 func TestStatusMsgErrors63(t *testing.T) { testStatusMsgErrors(t, 63) }
+func TestStatusMsgErrors64(t *testing.T) { testStatusMsgErrors(t, 64) }
 
 func testStatusMsgErrors(t *testing.T, protocol int) {
-	pm := newTestProtocolManagerMust(t, false, 0, nil, nil)
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
 	td, currentBlock, genesis := pm.blockchain.Status()
 	defer pm.Stop()
 
@@ -91,10 +93,11 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 // This test checks that received transactions are added to the local pool.
 func TestRecvTransactions62(t *testing.T) { testRecvTransactions(t, 62) }
 func TestRecvTransactions63(t *testing.T) { testRecvTransactions(t, 63) }
+func TestRecvTransactions64(t *testing.T) { testRecvTransactions(t, 64) }
 
 func testRecvTransactions(t *testing.T, protocol int) {
 	txAdded := make(chan []*types.Transaction)
-	pm := newTestProtocolManagerMust(t, false, 0, nil, txAdded)
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, txAdded)
 	pm.synced = 1 // mark synced to accept transactions
 	p, _ := newTestPeer("peer", protocol, pm, true)
 	defer pm.Stop()
@@ -119,9 +122,10 @@ func testRecvTransactions(t *testing.T, protocol int) {
 // This test checks that pending transactions are sent.
 func TestSendTransactions62(t *testing.T) { testSendTransactions(t, 62) }
 func TestSendTransactions63(t *testing.T) { testSendTransactions(t, 63) }
+func TestSendTransactions64(t *testing.T) { testSendTransactions(t, 64) }
 
 func testSendTransactions(t *testing.T, protocol int) {
-	pm := newTestProtocolManagerMust(t, false, 0, nil, nil)
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
 	defer pm.Stop()
 
 	// Fill the pool with big transactions.
@@ -174,6 +178,115 @@ func testSendTransactions(t *testing.T, protocol int) {
 	wg.Wait()
 }
 
+// This test checks that new transactions are propagated in full only to a
+// square root subset of the eth/64 peers, and announced to the rest.
+func TestBroadcastTransactions63(t *testing.T) { testBroadcastTransactions(t, 63) }
+func TestBroadcastTransactions64(t *testing.T) { testBroadcastTransactions(t, 64) }
+
+func testBroadcastTransactions(t *testing.T, protocol int) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	// Connect a few peers and wait until they are all registered
+	peers := make([]*testPeer, 4)
+	for i := range peers {
+		peers[i], _ = newTestPeer(fmt.Sprintf("peer #%d", i), protocol, pm, true)
+		defer peers[i].close()
+	}
+	for start := time.Now(); pm.peers.Len() < len(peers); time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("peers not registered: have %d, want %d", pm.peers.Len(), len(peers))
+		}
+	}
+	tx := newTestTransaction(testAccount, 0, 0)
+
+	// Gather what each peer received and check the propagation split
+	var (
+		sent, announced int
+		lock            sync.Mutex
+		wg              sync.WaitGroup
+	)
+	checktx := func(p *testPeer) {
+		defer wg.Done()
+
+		msg, err := p.app.ReadMsg()
+		if err != nil {
+			t.Errorf("%v: read error: %v", p.Peer, err)
+			return
+		}
+		switch msg.Code {
+		case TxMsg:
+			var txs []*types.Transaction
+			if err := msg.Decode(&txs); err != nil {
+				t.Errorf("%v: %v", p.Peer, err)
+			} else if len(txs) != 1 || txs[0].Hash() != tx.Hash() {
+				t.Errorf("%v: transactions mismatch: have %v, want %x", p.Peer, txs, tx.Hash())
+			}
+			lock.Lock()
+			sent++
+			lock.Unlock()
+
+		case NewPooledTransactionHashesMsg:
+			var hashes []common.Hash
+			if err := msg.Decode(&hashes); err != nil {
+				t.Errorf("%v: %v", p.Peer, err)
+			} else if len(hashes) != 1 || hashes[0] != tx.Hash() {
+				t.Errorf("%v: announcement mismatch: have %x, want %x", p.Peer, hashes, tx.Hash())
+			}
+			lock.Lock()
+			announced++
+			lock.Unlock()
+
+		default:
+			t.Errorf("%v: unexpected message code %d", p.Peer, msg.Code)
+		}
+	}
+	for _, p := range peers {
+		wg.Add(1)
+		go checktx(p)
+	}
+	pm.BroadcastTx(tx.Hash(), tx)
+	wg.Wait()
+
+	want := len(peers)
+	if protocol >= eth64 {
+		want = 2
+	}
+	if sent != want || announced != len(peers)-want {
+		t.Errorf("propagation mismatch: have %d sent, %d announced, want %d sent, %d announced", sent, announced, want, len(peers)-want)
+	}
+}
+
+// This test checks that announced transactions are retrieved from the peer and
+// added to the local pool.
+func TestRecvTransactionAnnouncements64(t *testing.T) {
+	txAdded := make(chan []*types.Transaction)
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, txAdded)
+	pm.synced = 1 // mark synced to accept transactions
+	p, _ := newTestPeer("peer", 64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	if err := p2p.Send(p.app, NewPooledTransactionHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, GetPooledTransactionsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("transaction request mismatch: %v", err)
+	}
+	if err := p2p.Send(p.app, PooledTransactionsMsg, []interface{}{tx}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case added := <-txAdded:
+		if len(added) != 1 || added[0].Hash() != tx.Hash() {
+			t.Errorf("added transactions mismatch: have %v, want %x", added, tx.Hash())
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("no transactions added within 2 seconds")
+	}
+}
+
 // Tests that the custom union field encoder and decoder works correctly.
 func TestGetBlockHeadersDataEncodeDecode(t *testing.T) {
 	// Create a "random" hash for testing
@@ -0,0 +1,382 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/state"
+	"github.com/ur-technology/go-ur/eth/downloader"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+	"github.com/ur-technology/go-ur/p2p"
+	"github.com/ur-technology/go-ur/rlp"
+	"github.com/ur-technology/go-ur/trie"
+)
+
+// Constants to match up snap protocol versions and messages
+const (
+	snap1 = 1
+)
+
+// Official short name of the state range protocol used during capability negotiation.
+var SnapProtocolName = "snap"
+
+// Supported versions of the snap protocol (first is primary).
+var SnapProtocolVersions = []uint{snap1}
+
+// Number of implemented message corresponding to different snap protocol versions.
+var SnapProtocolLengths = []uint64{6}
+
+// snap protocol message codes
+const (
+	// Protocol messages belonging to snap/1
+	GetAccountRangeMsg  = 0x00
+	AccountRangeMsg     = 0x01
+	GetStorageRangesMsg = 0x02
+	StorageRangesMsg    = 0x03
+	GetByteCodesMsg     = 0x04
+	ByteCodesMsg        = 0x05
+)
+
+// getAccountRangeData represents an account range query.
+type getAccountRangeData struct {
+	Root   common.Hash // State root to retrieve the accounts from
+	Origin common.Hash // Hash of the first account to retrieve
+	Limit  common.Hash // Hash of the last account to retrieve
+	Bytes  uint64      // Soft limit on the size of the response
+}
+
+// accountData is a single account of an account range, keyed by address hash.
+type accountData struct {
+	Hash common.Hash  // Hash of the account address
+	Body rlp.RawValue // Consensus encoding of the account
+}
+
+// accountRangeData is the network packet for an account range response.
+type accountRangeData struct {
+	Accounts []accountData  // Accounts in the requested range
+	Proof    []rlp.RawValue // Merkle proofs of the range edges
+}
+
+// getStorageRangesData represents a storage ranges query.
+type getStorageRangesData struct {
+	Root     common.Hash   // State root to retrieve the accounts from
+	Accounts []common.Hash // Hashes of the accounts to retrieve the storage of
+	Origin   common.Hash   // Hash of the first slot to retrieve of the first account
+	Bytes    uint64        // Soft limit on the size of the response
+}
+
+// storageData is a single slot of a storage range, keyed by slot hash.
+type storageData struct {
+	Hash common.Hash // Hash of the storage slot
+	Body []byte      // Contents of the storage slot
+}
+
+// storageRangesData is the network packet for a storage ranges response.
+type storageRangesData struct {
+	Slots [][]storageData // Storage ranges of the consecutive requested accounts
+	Proof []rlp.RawValue  // Merkle proofs of the last range, if incomplete
+}
+
+// getByteCodesData represents a contract code query.
+type getByteCodesData struct {
+	Hashes []common.Hash // Hashes of the contract codes to retrieve
+	Bytes  uint64        // Soft limit on the size of the response
+}
+
+// snapPeer is the state range side of a remote peer.
+type snapPeer struct {
+	id string
+
+	*p2p.Peer
+	rw p2p.MsgReadWriter
+}
+
+func newSnapPeer(p *p2p.Peer, rw p2p.MsgReadWriter) *snapPeer {
+	id := p.ID()
+
+	return &snapPeer{
+		id:   fmt.Sprintf("%x", id[:8]),
+		Peer: p,
+		rw:   rw,
+	}
+}
+
+// String implements fmt.Stringer.
+func (p *snapPeer) String() string {
+	return fmt.Sprintf("Peer %s [%s]", p.id, fmt.Sprintf("snap/%2d", snap1))
+}
+
+// RequestAccountRange fetches a range of accounts from a remote node.
+func (p *snapPeer) RequestAccountRange(root common.Hash, origin common.Hash, limit common.Hash, bytes uint64) error {
+	glog.V(logger.Debug).Infof("%v fetching accounts [%x…] from [%x…]", p, origin[:4], root[:4])
+	return p2p.Send(p.rw, GetAccountRangeMsg, &getAccountRangeData{Root: root, Origin: origin, Limit: limit, Bytes: bytes})
+}
+
+// RequestStorageRanges fetches the storage ranges of a batch of accounts from
+// a remote node.
+func (p *snapPeer) RequestStorageRanges(root common.Hash, accounts []common.Hash, origin common.Hash, bytes uint64) error {
+	glog.V(logger.Debug).Infof("%v fetching %d storage ranges from [%x…]", p, len(accounts), root[:4])
+	return p2p.Send(p.rw, GetStorageRangesMsg, &getStorageRangesData{Root: root, Accounts: accounts, Origin: origin, Bytes: bytes})
+}
+
+// RequestByteCodes fetches a batch of contract codes from a remote node.
+func (p *snapPeer) RequestByteCodes(hashes []common.Hash, bytes uint64) error {
+	glog.V(logger.Debug).Infof("%v fetching %d contract codes", p, len(hashes))
+	return p2p.Send(p.rw, GetByteCodesMsg, &getByteCodesData{Hashes: hashes, Bytes: bytes})
+}
+
+// makeSnapProtocols creates the state range sub-protocols, served alongside the
+// eth ones regardless of the sync mode.
+func (pm *ProtocolManager) makeSnapProtocols() []p2p.Protocol {
+	protocols := make([]p2p.Protocol, 0, len(SnapProtocolVersions))
+	for i, version := range SnapProtocolVersions {
+		protocols = append(protocols, p2p.Protocol{
+			Name:    SnapProtocolName,
+			Version: version,
+			Length:  SnapProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				select {
+				case <-pm.quitSync:
+					return p2p.DiscQuitting
+				default:
+					return pm.handleSnap(newSnapPeer(p, rw))
+				}
+			},
+		})
+	}
+	return protocols
+}
+
+// handleSnap is the callback invoked to manage the life cycle of a snap peer.
+// When this function terminates, the snap protocol of the peer is torn down.
+func (pm *ProtocolManager) handleSnap(p *snapPeer) error {
+	glog.V(logger.Detail).Infof("%v: adding snap peer", p)
+	if err := pm.downloader.RegisterSnapPeer(p.id, p.RequestAccountRange, p.RequestStorageRanges, p.RequestByteCodes); err != nil {
+		return err
+	}
+	defer pm.downloader.UnregisterSnapPeer(p.id)
+
+	for {
+		if err := pm.handleSnapMsg(p); err != nil {
+			glog.V(logger.Debug).Infof("%v: snap message handling failed: %v", p, err)
+			return err
+		}
+	}
+}
+
+// handleSnapMsg is invoked whenever an inbound snap message is received from a
+// remote peer. The snap connection is torn down upon returning any error.
+func (pm *ProtocolManager) handleSnapMsg(p *snapPeer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case GetAccountRangeMsg:
+		var query getAccountRangeData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p2p.Send(p.rw, AccountRangeMsg, pm.serveAccountRange(&query))
+
+	case AccountRangeMsg:
+		// A range of accounts arrived to one of our previous requests
+		var res accountRangeData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		hashes, accounts := make([]common.Hash, len(res.Accounts)), make([][]byte, len(res.Accounts))
+		for i, account := range res.Accounts {
+			hashes[i], accounts[i] = account.Hash, account.Body
+		}
+		if err := pm.downloader.DeliverAccountRange(p.id, hashes, accounts, res.Proof); err != nil {
+			glog.V(logger.Debug).Infof("failed to deliver account range: %v", err)
+		}
+
+	case GetStorageRangesMsg:
+		var query getStorageRangesData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p2p.Send(p.rw, StorageRangesMsg, pm.serveStorageRanges(&query))
+
+	case StorageRangesMsg:
+		// A batch of storage ranges arrived to one of our previous requests
+		var res storageRangesData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		hashes, slots := make([][]common.Hash, len(res.Slots)), make([][][]byte, len(res.Slots))
+		for i, storage := range res.Slots {
+			hashes[i], slots[i] = make([]common.Hash, len(storage)), make([][]byte, len(storage))
+			for j, slot := range storage {
+				hashes[i][j], slots[i][j] = slot.Hash, slot.Body
+			}
+		}
+		if err := pm.downloader.DeliverStorageRanges(p.id, hashes, slots, res.Proof); err != nil {
+			glog.V(logger.Debug).Infof("failed to deliver storage ranges: %v", err)
+		}
+
+	case GetByteCodesMsg:
+		var query getByteCodesData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p2p.Send(p.rw, ByteCodesMsg, pm.serveByteCodes(&query))
+
+	case ByteCodesMsg:
+		// A batch of contract codes arrived to one of our previous requests
+		var codes [][]byte
+		if err := msg.Decode(&codes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverByteCodes(p.id, codes); err != nil {
+			glog.V(logger.Debug).Infof("failed to deliver contract codes: %v", err)
+		}
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+	return nil
+}
+
+// responseLimit caps the soft response size requested by a remote peer.
+func responseLimit(bytes uint64) int {
+	if bytes > softResponseLimit {
+		return softResponseLimit
+	}
+	return int(bytes)
+}
+
+// serveAccountRange gathers the accounts of a state trie starting at the query
+// origin, until the limit hash or the response size is reached. The range is
+// proven by its edges. If the state is unavailable, an empty response is sent.
+func (pm *ProtocolManager) serveAccountRange(query *getAccountRangeData) *accountRangeData {
+	res := new(accountRangeData)
+
+	tr, err := trie.New(query.Root, pm.chaindb)
+	if err != nil {
+		return res
+	}
+	var (
+		limit = responseLimit(query.Bytes)
+		size  int
+	)
+	err = tr.Walk(query.Origin[:], func(key, value []byte) bool {
+		res.Accounts = append(res.Accounts, accountData{Hash: common.BytesToHash(key), Body: common.CopyBytes(value)})
+		size += len(key) + len(value)
+		return size < limit && bytes.Compare(key, query.Limit[:]) < 0
+	})
+	if err != nil {
+		return new(accountRangeData)
+	}
+	res.Proof = tr.Prove(query.Origin[:])
+	if len(res.Accounts) > 0 {
+		res.Proof = append(res.Proof, tr.Prove(res.Accounts[len(res.Accounts)-1].Hash[:])...)
+	}
+	return res
+}
+
+// serveStorageRanges gathers the storage of consecutive accounts, the first one
+// starting at the query origin, until the response size is reached. Only if the
+// last range is incomplete or doesn't start at the beginning, it is proven by
+// its edges. If the state is unavailable, an empty response is sent.
+func (pm *ProtocolManager) serveStorageRanges(query *getStorageRangesData) *storageRangesData {
+	res := new(storageRangesData)
+
+	accounts, err := trie.New(query.Root, pm.chaindb)
+	if err != nil {
+		return res
+	}
+	var (
+		limit = responseLimit(query.Bytes)
+		size  int
+	)
+	for i, hash := range query.Accounts {
+		if size >= limit || i >= downloader.MaxStorageFetch {
+			break
+		}
+		var account state.Account
+		if err := rlp.DecodeBytes(accounts.Get(hash[:]), &account); err != nil {
+			break
+		}
+		storage, err := trie.New(account.Root, pm.chaindb)
+		if err != nil {
+			break
+		}
+		var origin common.Hash
+		if i == 0 {
+			origin = query.Origin
+		}
+		var (
+			slots     []storageData
+			truncated bool
+		)
+		err = storage.Walk(origin[:], func(key, value []byte) bool {
+			if size >= limit {
+				truncated = true
+				return false
+			}
+			slots = append(slots, storageData{Hash: common.BytesToHash(key), Body: common.CopyBytes(value)})
+			size += len(key) + len(value)
+			return true
+		})
+		if err != nil {
+			break
+		}
+		res.Slots = append(res.Slots, slots)
+
+		// Prove the range if it's only part of the storage
+		if truncated || origin != (common.Hash{}) {
+			res.Proof = storage.Prove(origin[:])
+			if len(slots) > 0 {
+				res.Proof = append(res.Proof, storage.Prove(slots[len(slots)-1].Hash[:])...)
+			}
+			break
+		}
+	}
+	return res
+}
+
+// serveByteCodes gathers the requested contract codes until the response size
+// is reached.
+func (pm *ProtocolManager) serveByteCodes(query *getByteCodesData) [][]byte {
+	var (
+		limit = responseLimit(query.Bytes)
+		size  int
+		codes [][]byte
+	)
+	for _, hash := range query.Hashes {
+		if size >= limit || len(codes) >= downloader.MaxCodeFetch {
+			break
+		}
+		if code, err := pm.chaindb.Get(hash[:]); err == nil {
+			codes = append(codes, code)
+			size += len(code)
+		}
+	}
+	return codes
+}
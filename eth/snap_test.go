@@ -0,0 +1,130 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/eth/downloader"
+	"github.com/ur-technology/go-ur/p2p"
+	"github.com/ur-technology/go-ur/p2p/discover"
+	"github.com/ur-technology/go-ur/trie"
+)
+
+// snapTestGenerator mines every block with a different coinbase, spreading the
+// state over many accounts.
+func snapTestGenerator(i int, block *core.BlockGen) {
+	block.SetCoinbase(common.Address{byte(i), byte(i >> 8)})
+}
+
+// Tests that account ranges can be retrieved from a remote node, proven by the
+// edges against the requested state root.
+func TestGetAccountRange(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 64, snapTestGenerator, nil)
+	defer pm.Stop()
+
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	go pm.handleSnap(newSnapPeer(p2p.NewPeer(discover.NodeID{}, "peer", nil), net))
+
+	root := pm.blockchain.CurrentBlock().Root()
+	tests := []struct {
+		origin common.Hash
+		limit  common.Hash
+		bytes  uint64
+	}{
+		{common.Hash{}, common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), softResponseLimit},
+		{common.Hash{}, common.HexToHash("0x8000000000000000000000000000000000000000000000000000000000000000"), softResponseLimit},
+		{common.HexToHash("0x4000000000000000000000000000000000000000000000000000000000000000"), common.HexToHash("0x8000000000000000000000000000000000000000000000000000000000000000"), softResponseLimit},
+		{common.HexToHash("0x4000000000000000000000000000000000000000000000000000000000000000"), common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), 256},
+	}
+	for i, tt := range tests {
+		if err := p2p.Send(app, GetAccountRangeMsg, &getAccountRangeData{Root: root, Origin: tt.origin, Limit: tt.limit, Bytes: tt.bytes}); err != nil {
+			t.Fatalf("test %d: failed to send request: %v", i, err)
+		}
+		msg, err := app.ReadMsg()
+		if err != nil {
+			t.Fatalf("test %d: failed to read response: %v", i, err)
+		}
+		if msg.Code != AccountRangeMsg {
+			t.Fatalf("test %d: response packet code mismatch: have %x, want %x", i, msg.Code, AccountRangeMsg)
+		}
+		var res accountRangeData
+		if err := msg.Decode(&res); err != nil {
+			t.Fatalf("test %d: failed to decode response: %v", i, err)
+		}
+		if len(res.Accounts) == 0 {
+			t.Fatalf("test %d: no accounts returned", i)
+		}
+		keys, values := make([][]byte, len(res.Accounts)), make([][]byte, len(res.Accounts))
+		for j, account := range res.Accounts {
+			keys[j], values[j] = account.Hash[:], account.Body
+		}
+		more, err := trie.VerifyRangeProof(root, tt.origin[:], keys, values, res.Proof)
+		if err != nil {
+			t.Fatalf("test %d: invalid range: %v", i, err)
+		}
+		if last := res.Accounts[len(res.Accounts)-1].Hash; more && last.Big().Cmp(tt.limit.Big()) < 0 && tt.bytes == softResponseLimit {
+			t.Errorf("test %d: range truncated at %x before limit %x", i, last, tt.limit)
+		}
+	}
+	// Check that requests for unknown states are answered empty
+	if err := p2p.Send(app, GetAccountRangeMsg, &getAccountRangeData{Root: common.Hash{1}, Bytes: softResponseLimit}); err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	if err := p2p.ExpectMsg(app, AccountRangeMsg, &accountRangeData{}); err != nil {
+		t.Fatalf("unknown state: %v", err)
+	}
+}
+
+// Tests that a pristine node retrieves the state in ranges when snap syncing
+// with a remote node.
+func TestSnapSync(t *testing.T) {
+	pmEmpty := newTestProtocolManagerMust(t, downloader.SnapSync, 0, nil, nil)
+	if !pmEmpty.snapSync {
+		t.Fatalf("snap sync disabled on pristine blockchain")
+	}
+	pmFull := newTestProtocolManagerMust(t, downloader.SnapSync, 1024, snapTestGenerator, nil)
+	if pmFull.snapSync {
+		t.Fatalf("snap sync not disabled on non-empty blockchain")
+	}
+	defer pmEmpty.Stop()
+	defer pmFull.Stop()
+
+	// Connect the two nodes on both the eth and snap protocols
+	io1, io2 := p2p.MsgPipe()
+	io3, io4 := p2p.MsgPipe()
+
+	go pmFull.handle(pmFull.newPeer(63, p2p.NewPeer(discover.NodeID{}, "empty", nil), io2))
+	go pmEmpty.handle(pmEmpty.newPeer(63, p2p.NewPeer(discover.NodeID{}, "full", nil), io1))
+	go pmFull.handleSnap(newSnapPeer(p2p.NewPeer(discover.NodeID{}, "empty", nil), io4))
+	go pmEmpty.handleSnap(newSnapPeer(p2p.NewPeer(discover.NodeID{}, "full", nil), io3))
+
+	time.Sleep(250 * time.Millisecond)
+	pmEmpty.synchronise(pmEmpty.peers.BestPeer())
+
+	// Check that the chain was imported with its state
+	if have, want := pmEmpty.blockchain.CurrentBlock().Hash(), pmFull.blockchain.CurrentBlock().Hash(); have != want {
+		t.Fatalf("head block mismatch: have %x, want %x", have, want)
+	}
+	if _, err := pmEmpty.blockchain.State(); err != nil {
+		t.Fatalf("head state unavailable: %v", err)
+	}
+}
@@ -176,6 +176,9 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 	mode := downloader.FullSync
 	if atomic.LoadUint32(&pm.fastSync) == 1 {
 		mode = downloader.FastSync
+		if pm.snapSync {
+			mode = downloader.SnapSync
+		}
 	}
 	if err := pm.downloader.Synchronise(peer.id, pHead, pTd, mode); err != nil {
 		return
@@ -19,6 +19,7 @@ package ethclient
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -51,8 +52,51 @@ func NewClient(c *rpc.Client) *Client {
 	return &Client{c}
 }
 
+// RPCClient returns the underlying RPC client, for raw calls to the methods that
+// have no typed wrapper.
+func (ec *Client) RPCClient() *rpc.Client {
+	return ec.c
+}
+
+// batchCall sends the given requests in a single batch, failing with the first
+// error of the batch or of the individual requests.
+func (ec *Client) batchCall(ctx context.Context, reqs []rpc.BatchElem) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+	if err := ec.c.BatchCallContext(ctx, reqs); err != nil {
+		return err
+	}
+	for i := range reqs {
+		if reqs[i].Error != nil {
+			return reqs[i].Error
+		}
+	}
+	return nil
+}
+
+// NetworkID returns the network ID of the node.
+func (ec *Client) NetworkID(ctx context.Context) (*big.Int, error) {
+	var version string
+	if err := ec.c.CallContext(ctx, &version, "net_version"); err != nil {
+		return nil, err
+	}
+	id, ok := new(big.Int).SetString(version, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid network ID %q", version)
+	}
+	return id, nil
+}
+
 // Blockchain Access
 
+// BlockNumber returns the number of the most recent block of the canonical chain.
+func (ec *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	var number rpc.HexNumber
+	err := ec.c.CallContext(ctx, &number, "eth_blockNumber")
+	return number.Uint64(), err
+}
+
 // BlockByHash returns the given full block.
 //
 // Note that loading full blocks requires two requests. Use HeaderByHash
@@ -116,14 +160,9 @@ func (ec *Client) getBlock(ctx context.Context, method string, args ...interface
 				Result: &uncles[i],
 			}
 		}
-		if err := ec.c.BatchCallContext(ctx, reqs); err != nil {
+		if err := ec.batchCall(ctx, reqs); err != nil {
 			return nil, err
 		}
-		for i := range reqs {
-			if reqs[i].Error != nil {
-				return nil, reqs[i].Error
-			}
-		}
 	}
 	return types.NewBlockWithHeader(head).WithBody(body.Transactions, uncles), nil
 }
@@ -143,6 +182,43 @@ func (ec *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.H
 	return head, err
 }
 
+// HeadersByHash returns the block headers with the given hashes in a single batch
+// request. The headers of unknown blocks are nil.
+func (ec *Client) HeadersByHash(ctx context.Context, hashes []common.Hash) ([]*types.Header, error) {
+	heads := make([]*types.Header, len(hashes))
+	reqs := make([]rpc.BatchElem, len(hashes))
+	for i, hash := range hashes {
+		reqs[i] = rpc.BatchElem{
+			Method: "eth_getBlockByHash",
+			Args:   []interface{}{hash, false},
+			Result: &heads[i],
+		}
+	}
+	if err := ec.batchCall(ctx, reqs); err != nil {
+		return nil, err
+	}
+	return heads, nil
+}
+
+// HeadersByNumber returns block headers from the current canonical chain in a
+// single batch request. A nil number selects the latest known header, and the
+// headers of blocks not yet known are nil.
+func (ec *Client) HeadersByNumber(ctx context.Context, numbers []*big.Int) ([]*types.Header, error) {
+	heads := make([]*types.Header, len(numbers))
+	reqs := make([]rpc.BatchElem, len(numbers))
+	for i, number := range numbers {
+		reqs[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{toBlockNumArg(number), false},
+			Result: &heads[i],
+		}
+	}
+	if err := ec.batchCall(ctx, reqs); err != nil {
+		return nil, err
+	}
+	return heads, nil
+}
+
 // TransactionByHash returns the transaction with the given hash.
 func (ec *Client) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, error) {
 	var tx *types.Transaction
@@ -178,6 +254,23 @@ func (ec *Client) TransactionInBlock(ctx context.Context, blockHash common.Hash,
 	return tx, err
 }
 
+// TransactionSender returns the sender address of the given transaction, as
+// recovered by the server. The transaction must be included at the given index
+// of the given block, e.g. as returned by TransactionInBlock.
+func (ec *Client) TransactionSender(ctx context.Context, tx *types.Transaction, block common.Hash, index uint) (common.Address, error) {
+	var meta struct {
+		Hash common.Hash    `json:"hash"`
+		From common.Address `json:"from"`
+	}
+	if err := ec.c.CallContext(ctx, &meta, "eth_getTransactionByBlockHashAndIndex", block, hexutil.Uint(index)); err != nil {
+		return common.Address{}, err
+	}
+	if meta.Hash == (common.Hash{}) || meta.Hash != tx.Hash() {
+		return common.Address{}, errors.New("wrong inclusion block/index")
+	}
+	return meta.From, nil
+}
+
 // TransactionReceipt returns the receipt of a transaction by transaction hash.
 // Note that the receipt is not available for pending transactions.
 func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
@@ -189,6 +282,29 @@ func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*
 	return r, err
 }
 
+// TransactionReceipts returns the receipts of the given transactions in a single
+// batch request. The receipts of pending or unknown transactions are nil.
+func (ec *Client) TransactionReceipts(ctx context.Context, txHashes []common.Hash) ([]*types.Receipt, error) {
+	receipts := make([]*types.Receipt, len(txHashes))
+	reqs := make([]rpc.BatchElem, len(txHashes))
+	for i, hash := range txHashes {
+		reqs[i] = rpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{hash},
+			Result: &receipts[i],
+		}
+	}
+	if err := ec.batchCall(ctx, reqs); err != nil {
+		return nil, err
+	}
+	for _, r := range receipts {
+		if r != nil && len(r.PostState) == 0 {
+			return nil, fmt.Errorf("server returned receipt without post state")
+		}
+	}
+	return receipts, nil
+}
+
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
@@ -334,7 +450,11 @@ func (ec *Client) PendingTransactionCount(ctx context.Context) (uint, error) {
 	return uint(num), err
 }
 
-// TODO: SubscribePendingTransactions (needs server side)
+// SubscribePendingTransactions subscribes to notifications about the hashes of
+// the transactions entering the transaction pool of the node.
+func (ec *Client) SubscribePendingTransactions(ctx context.Context, ch chan<- common.Hash) (ethereum.Subscription, error) {
+	return ec.c.EthSubscribe(ctx, ch, "newPendingTransactions")
+}
 
 // Contract Calling
 
@@ -16,7 +16,16 @@
 
 package ethclient
 
-import "github.com/ur-technology/go-ur"
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ur-technology/go-ur"
+	"github.com/ur-technology/go-ur/common/hexutil"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/rpc"
+	"golang.org/x/net/context"
+)
 
 // Verify that Client implements the ethereum interfaces.
 var (
@@ -32,3 +41,86 @@ var (
 	// _ = ethereum.PendingStateEventer(&Client{})
 	_ = ethereum.PendingContractCaller(&Client{})
 )
+
+// TestHeaderChain is a fake eth API serving a chain of headers.
+type TestHeaderChain struct {
+	head uint64
+}
+
+func (api *TestHeaderChain) BlockNumber() *big.Int {
+	return new(big.Int).SetUint64(api.head)
+}
+
+func (api *TestHeaderChain) GetBlockByNumber(number string, full bool) (*types.Header, error) {
+	n := api.head
+	if number != "latest" {
+		var err error
+		if n, err = hexutil.DecodeUint64(number); err != nil {
+			return nil, err
+		}
+	}
+	if n > api.head {
+		return nil, nil
+	}
+	header := &types.Header{
+		Number:     new(big.Int).SetUint64(n),
+		Difficulty: new(big.Int),
+		GasLimit:   new(big.Int),
+		GasUsed:    new(big.Int),
+		Time:       new(big.Int),
+		TotalWei:   new(big.Int),
+		NSignups:   new(big.Int).SetUint64(n),
+		Extra:      []byte{},
+	}
+	// Block zero is served without network totals, as a non-UR server would.
+	if n == 0 {
+		header.TotalWei, header.NSignups = nil, nil
+	}
+	return header, nil
+}
+
+// TestNet is a fake net API.
+type TestNet struct{}
+
+func (TestNet) Version() string { return "19" }
+
+// Tests the chain information calls and that header batches are decoded with the
+// UR network totals.
+func TestChainInfoAndBatchHeaders(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &TestHeaderChain{head: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("net", TestNet{}); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client := NewClient(rpc.DialInProc(server))
+	ctx := context.Background()
+
+	if number, err := client.BlockNumber(ctx); err != nil || number != 3 {
+		t.Errorf("block number mismatch: have %d, %v; want 3", number, err)
+	}
+	if id, err := client.NetworkID(ctx); err != nil || id.Int64() != 19 {
+		t.Errorf("network ID mismatch: have %v, %v; want 19", id, err)
+	}
+	heads, err := client.HeadersByNumber(ctx, []*big.Int{big.NewInt(1), big.NewInt(5), nil})
+	if err != nil {
+		t.Fatalf("failed to fetch headers: %v", err)
+	}
+	if len(heads) != 3 || heads[0].Number.Uint64() != 1 || heads[1] != nil || heads[2].Number.Uint64() != 3 {
+		t.Fatalf("header batch mismatch: %v", heads)
+	}
+	if heads[2].NSignups.Uint64() != 3 {
+		t.Errorf("network totals mismatch: have %v signups, want 3", heads[2].NSignups)
+	}
+	if totals, err := client.NetworkTotalsAt(ctx, big.NewInt(2)); err != nil || totals.NSignups.Uint64() != 2 {
+		t.Errorf("network totals mismatch: have %v, %v; want 2 signups", totals, err)
+	}
+	if _, err := client.HeaderByNumber(ctx, big.NewInt(0)); err == nil {
+		t.Errorf("header without network totals accepted")
+	}
+	if _, err := client.HeadersByNumber(ctx, []*big.Int{big.NewInt(1), big.NewInt(0)}); err == nil {
+		t.Errorf("header batch without network totals accepted")
+	}
+}
@@ -0,0 +1,377 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ur-technology/go-ur"
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/core/vm"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+	"github.com/ur-technology/go-ur/rpc"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultMinBackoff  = time.Second      // Default delay before the first reconnection attempt
+	defaultMaxBackoff  = time.Minute      // Default maximum delay between reconnection attempts
+	resubscribeTimeout = 10 * time.Second // Timeout of a single resubscription attempt
+)
+
+// ConnectionState is the state of the connection of a ResilientClient.
+type ConnectionState int
+
+const (
+	Connected    ConnectionState = iota // Subscriptions are established
+	Disconnected                        // Connection lost, reconnecting
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case Connected:
+		return "connected"
+	case Disconnected:
+		return "disconnected"
+	}
+	return "unknown"
+}
+
+// ResilientConfig tunes the reconnection behaviour of a ResilientClient.
+type ResilientConfig struct {
+	MinBackoff time.Duration // Delay before the first reconnection attempt (default 1s)
+	MaxBackoff time.Duration // Maximum delay between reconnection attempts (default 1m)
+
+	// OnStateChange is called whenever the connection is lost (with the error
+	// that broke it) or reestablished (with a nil error). It may be nil.
+	OnStateChange func(state ConnectionState, err error)
+}
+
+// ResilientClient is a Client whose subscriptions survive connection failures.
+// When a subscription breaks, the underlying RPC client is reconnected with an
+// exponential backoff and the subscription transparently reestablished. Log
+// subscriptions backfill the logs of the blocks missed while disconnected.
+//
+// The calls of the embedded Client are not retried, failing while disconnected.
+type ResilientClient struct {
+	*Client
+	config ResilientConfig
+
+	lock  sync.Mutex
+	state ConnectionState
+}
+
+// DialResilient connects a resilient client to the given URL.
+func DialResilient(rawurl string, config ResilientConfig) (*ResilientClient, error) {
+	c, err := rpc.Dial(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return NewResilientClient(c, config), nil
+}
+
+// NewResilientClient creates a resilient client that uses the given RPC client,
+// which must be able to reconnect on its own (all the dialed ones do).
+func NewResilientClient(c *rpc.Client, config ResilientConfig) *ResilientClient {
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultMinBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = defaultMaxBackoff
+		if config.MaxBackoff < config.MinBackoff {
+			config.MaxBackoff = config.MinBackoff
+		}
+	}
+	return &ResilientClient{Client: NewClient(c), config: config}
+}
+
+// State returns the current connection state of the client.
+func (rc *ResilientClient) State() ConnectionState {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	return rc.state
+}
+
+// setState updates the connection state, notifying the hook of any change.
+func (rc *ResilientClient) setState(state ConnectionState, err error) {
+	rc.lock.Lock()
+	changed := rc.state != state
+	rc.state = state
+	rc.lock.Unlock()
+
+	if changed {
+		glog.V(logger.Debug).Infof("RPC connection %v: %v", state, err)
+		if rc.config.OnStateChange != nil {
+			rc.config.OnStateChange(state, err)
+		}
+	}
+}
+
+// SubscribeNewHead subscribes to notifications about the current blockchain head
+// on the given channel, resubscribing whenever the connection is reestablished.
+// Heads mined while disconnected aren't delivered, only the ones following the
+// reconnection.
+func (rc *ResilientClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	heads := make(chan *types.Header)
+	subscribe := func(ctx context.Context) (ethereum.Subscription, error) {
+		return rc.Client.SubscribeNewHead(ctx, heads)
+	}
+	sub, err := subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rs := newResilientSub(rc, subscribe, nil)
+	go rs.loop(sub, reflect.ValueOf(heads), func(head reflect.Value) bool {
+		select {
+		case ch <- head.Interface().(*types.Header):
+			return true
+		case <-rs.unsub:
+			return false
+		}
+	})
+	return rs, nil
+}
+
+// SubscribePendingTransactions subscribes to notifications about the hashes of
+// the transactions entering the transaction pool of the node, resubscribing
+// whenever the connection is reestablished. Transactions arriving while
+// disconnected aren't delivered.
+func (rc *ResilientClient) SubscribePendingTransactions(ctx context.Context, ch chan<- common.Hash) (ethereum.Subscription, error) {
+	hashes := make(chan common.Hash)
+	subscribe := func(ctx context.Context) (ethereum.Subscription, error) {
+		return rc.Client.SubscribePendingTransactions(ctx, hashes)
+	}
+	sub, err := subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rs := newResilientSub(rc, subscribe, nil)
+	go rs.loop(sub, reflect.ValueOf(hashes), func(hash reflect.Value) bool {
+		select {
+		case ch <- hash.Interface().(common.Hash):
+			return true
+		case <-rs.unsub:
+			return false
+		}
+	})
+	return rs, nil
+}
+
+// SubscribeFilterLogs subscribes to the results of a streaming filter query,
+// resubscribing whenever the connection is reestablished. The logs of the blocks
+// mined while disconnected are retrieved and delivered before any new ones. Logs
+// of the blocks preceding the subscription are never delivered.
+func (rc *ResilientClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- vm.Log) (ethereum.Subscription, error) {
+	var (
+		logs = make(chan vm.Log)
+		last uint64                  // Number of the last block whose logs were delivered
+		seen = make(map[logKey]bool) // Logs of the last block already delivered
+		done = true                  // Whether all logs of the last block were delivered
+	)
+	subscribe := func(ctx context.Context) (ethereum.Subscription, error) {
+		return rc.Client.SubscribeFilterLogs(ctx, q, logs)
+	}
+	sub, err := subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	head, err := rc.HeaderByNumber(ctx, nil)
+	if err != nil {
+		sub.Unsubscribe()
+		return nil, err
+	}
+	last = head.Number.Uint64()
+
+	// deliver forwards a log unless it was delivered before, returning false if
+	// unsubscribed meanwhile
+	deliver := func(log vm.Log, unsub <-chan struct{}) bool {
+		switch {
+		case log.BlockNumber < last:
+			return true
+		case log.BlockNumber > last:
+			last, seen, done = log.BlockNumber, make(map[logKey]bool), false
+		}
+		key := logKey{log.TxHash, log.Index}
+		if seen[key] {
+			return true
+		}
+		seen[key] = true
+
+		select {
+		case ch <- log:
+			return true
+		case <-unsub:
+			return false
+		}
+	}
+	// backfill delivers the logs missed while disconnected up to the current head,
+	// starting with the last block if it might have been delivered partially
+	backfill := func(ctx context.Context, unsub <-chan struct{}) error {
+		head, err := rc.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return err
+		}
+		to := head.Number.Uint64()
+		if q.ToBlock != nil && q.ToBlock.Uint64() < to {
+			to = q.ToBlock.Uint64()
+		}
+		from := last
+		if done {
+			from++
+		}
+		if to < from {
+			return nil
+		}
+		query := q
+		query.FromBlock, query.ToBlock = new(big.Int).SetUint64(from), new(big.Int).SetUint64(to)
+		missed, err := rc.FilterLogs(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, log := range missed {
+			if !deliver(log, unsub) {
+				return nil
+			}
+		}
+		if to > last {
+			last, seen = to, make(map[logKey]bool)
+		}
+		done = true
+		return nil
+	}
+	rs := newResilientSub(rc, subscribe, backfill)
+	go rs.loop(sub, reflect.ValueOf(logs), func(log reflect.Value) bool {
+		return deliver(log.Interface().(vm.Log), rs.unsub)
+	})
+	return rs, nil
+}
+
+// logKey identifies a log within a block.
+type logKey struct {
+	tx    common.Hash
+	index uint
+}
+
+// resilientSub is a subscription reestablished whenever it breaks.
+type resilientSub struct {
+	client    *ResilientClient
+	subscribe func(context.Context) (ethereum.Subscription, error) // Establishes the underlying subscription
+	backfill  func(context.Context, <-chan struct{}) error         // Delivers the events missed while disconnected, may be nil
+
+	unsub     chan struct{}
+	unsubOnce sync.Once
+	err       chan error
+}
+
+func newResilientSub(rc *ResilientClient, subscribe func(context.Context) (ethereum.Subscription, error), backfill func(context.Context, <-chan struct{}) error) *resilientSub {
+	return &resilientSub{
+		client:    rc,
+		subscribe: subscribe,
+		backfill:  backfill,
+		unsub:     make(chan struct{}),
+		err:       make(chan error),
+	}
+}
+
+// Unsubscribe implements ethereum.Subscription, ending the subscription. It
+// waits for the underlying subscription to be torn down.
+func (rs *resilientSub) Unsubscribe() {
+	rs.unsubOnce.Do(func() { close(rs.unsub) })
+	for range rs.err {
+	}
+}
+
+// Err implements ethereum.Subscription. The error channel never receives a
+// value as the subscription never fails, it's closed on Unsubscribe.
+func (rs *resilientSub) Err() <-chan error {
+	return rs.err
+}
+
+// loop forwards the events received on the channel of the subscription until
+// unsubscribed, resubscribing after the subscription breaks. Forward returns
+// false if unsubscribed while delivering an event.
+func (rs *resilientSub) loop(sub ethereum.Subscription, events reflect.Value, forward func(reflect.Value) bool) {
+	defer close(rs.err)
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(rs.unsub)},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.Err())},
+		{Dir: reflect.SelectRecv, Chan: events},
+	}
+	for {
+		chosen, recv, _ := reflect.Select(cases)
+		switch chosen {
+		case 0: // <-rs.unsub
+			sub.Unsubscribe()
+			return
+
+		case 1: // <-sub.Err()
+			var err error
+			if !recv.IsNil() {
+				err = recv.Interface().(error)
+			}
+			sub.Unsubscribe()
+			rs.client.setState(Disconnected, err)
+			if sub = rs.resubscribe(); sub == nil {
+				return
+			}
+			rs.client.setState(Connected, nil)
+			cases[1].Chan = reflect.ValueOf(sub.Err())
+
+		case 2: // <-events
+			if !forward(recv) {
+				sub.Unsubscribe()
+				return
+			}
+		}
+	}
+}
+
+// resubscribe reestablishes the subscription with exponential backoff, returning
+// nil if unsubscribed meanwhile.
+func (rs *resilientSub) resubscribe() ethereum.Subscription {
+	delay := rs.client.config.MinBackoff
+	for {
+		select {
+		case <-time.After(delay):
+		case <-rs.unsub:
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), resubscribeTimeout)
+		sub, err := rs.subscribe(ctx)
+		if err == nil && rs.backfill != nil {
+			if err = rs.backfill(ctx, rs.unsub); err != nil {
+				sub.Unsubscribe()
+			}
+		}
+		cancel()
+
+		if err == nil {
+			return sub
+		}
+		glog.V(logger.Debug).Infof("Resubscription failed, retrying in %v: %v", delay, err)
+		if delay *= 2; delay > rs.client.config.MaxBackoff {
+			delay = rs.client.config.MaxBackoff
+		}
+	}
+}
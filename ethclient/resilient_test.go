@@ -0,0 +1,263 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ur-technology/go-ur"
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/common/hexutil"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/core/vm"
+	"github.com/ur-technology/go-ur/rpc"
+	"golang.org/x/net/context"
+)
+
+// TestChain is a fake eth API mining a block with a single log on demand.
+type TestChain struct {
+	lock sync.Mutex
+	head uint64
+	logs []vm.Log
+	subs map[rpc.ID]*rpc.Notifier
+}
+
+func (c *TestChain) GetBlockByNumber(number string, full bool) *types.Header {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return &types.Header{
+		Number:     new(big.Int).SetUint64(c.head),
+		Difficulty: new(big.Int),
+		GasLimit:   new(big.Int),
+		GasUsed:    new(big.Int),
+		Time:       new(big.Int),
+		TotalWei:   new(big.Int),
+		NSignups:   new(big.Int),
+		Extra:      []byte{},
+	}
+}
+
+func (c *TestChain) GetLogs(crit map[string]interface{}) ([]vm.Log, error) {
+	from, err := hexutil.DecodeUint64(crit["fromBlock"].(string))
+	if err != nil {
+		return nil, err
+	}
+	to, err := hexutil.DecodeUint64(crit["toBlock"].(string))
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	logs := []vm.Log{}
+	for _, log := range c.logs {
+		if log.BlockNumber >= from && log.BlockNumber <= to {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func (c *TestChain) Logs(ctx context.Context, crit map[string]interface{}) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+
+	c.lock.Lock()
+	c.subs[sub.ID] = notifier
+	c.lock.Unlock()
+
+	go func() {
+		select {
+		case <-sub.Err():
+		case <-notifier.Closed():
+		}
+		c.lock.Lock()
+		delete(c.subs, sub.ID)
+		c.lock.Unlock()
+	}()
+	return sub, nil
+}
+
+// mine adds a new block with a single log, notifying the subscribers.
+func (c *TestChain) mine() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.head++
+	log := vm.Log{
+		Topics:      []common.Hash{},
+		Data:        []byte{},
+		BlockNumber: c.head,
+		TxHash:      common.BigToHash(new(big.Int).SetUint64(c.head)),
+	}
+	c.logs = append(c.logs, log)
+	for id, notifier := range c.subs {
+		notifier.Notify(id, log)
+	}
+}
+
+// subscribers returns the number of active log subscriptions.
+func (c *TestChain) subscribers() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.subs)
+}
+
+// trackingListener is a listener which can drop all its accepted connections.
+type trackingListener struct {
+	net.Listener
+	lock  sync.Mutex
+	conns []net.Conn
+}
+
+func (l *trackingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.lock.Lock()
+		l.conns = append(l.conns, conn)
+		l.lock.Unlock()
+	}
+	return conn, err
+}
+
+func (l *trackingListener) dropAll() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+}
+
+// Tests that log subscriptions are reestablished after the connection drops and
+// that the logs mined meanwhile are backfilled without duplicates.
+func TestResilientLogSubscription(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chain := &TestChain{subs: make(map[rpc.ID]*rpc.Notifier)}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", chain); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	endpoint := filepath.Join(dir, "test.ipc")
+	listener, err := rpc.CreateIPCListener(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := &trackingListener{Listener: listener}
+	defer tracker.Close()
+	go server.ServeListener(tracker)
+
+	states := make(chan ConnectionState, 10)
+	client, err := rpc.DialIPC(context.Background(), endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	rc := NewResilientClient(client, ResilientConfig{
+		MinBackoff:    10 * time.Millisecond,
+		MaxBackoff:    50 * time.Millisecond,
+		OnStateChange: func(state ConnectionState, err error) { states <- state },
+	})
+
+	// The log of the head block at subscription time must never be delivered
+	chain.mine()
+
+	logs := make(chan vm.Log, 10)
+	sub, err := rc.SubscribeFilterLogs(context.Background(), ethereum.FilterQuery{}, logs)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	expect := func(number uint64) {
+		select {
+		case log := <-logs:
+			if log.BlockNumber != number {
+				t.Fatalf("log block mismatch: have %d, want %d", log.BlockNumber, number)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for log of block %d", number)
+		}
+	}
+	expectState := func(want ConnectionState) {
+		select {
+		case state := <-states:
+			if state != want {
+				t.Fatalf("state mismatch: have %v, want %v", state, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for state %v", want)
+		}
+	}
+	waitSubscribed := func() {
+		for chain.subscribers() == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	// Drop the connection before any log is delivered
+	tracker.dropAll()
+	expectState(Disconnected)
+	chain.mine()
+	expectState(Connected)
+
+	expect(2)
+	waitSubscribed()
+	chain.mine()
+	expect(3)
+
+	// Drop the connection and mine while disconnected
+	tracker.dropAll()
+	expectState(Disconnected)
+	chain.mine()
+	chain.mine()
+	expectState(Connected)
+
+	expect(4)
+	expect(5)
+	waitSubscribed()
+	chain.mine()
+	expect(6)
+
+	select {
+	case log := <-logs:
+		t.Fatalf("unexpected log of block %d", log.BlockNumber)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if state := rc.State(); state != Connected {
+		t.Fatalf("final state mismatch: have %v, want %v", state, Connected)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the typed accessors of the UR extensions of the RPC API.
+
+package ethclient
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/common/hexutil"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/params"
+	"github.com/ur-technology/go-ur/rpc"
+	"golang.org/x/net/context"
+)
+
+var (
+	// ErrInvalidSignupChain is returned for signup transactions whose chain of
+	// referring members can't be resolved. No rewards are issued for them.
+	ErrInvalidSignupChain = errors.New("invalid signup chain")
+
+	errNotSignup     = errors.New("not a signup transaction")
+	errPendingSignup = errors.New("signup transaction is pending")
+	errUnknownSignup = errors.New("unknown signup transaction")
+	errUnknownBlock  = errors.New("block not found")
+)
+
+// NetworkTotals is the number of members signed up and the total wei issued by
+// the network as of a block.
+type NetworkTotals struct {
+	NSignups *big.Int
+	TotalWei *big.Int
+}
+
+// NetworkTotalsAt returns the network totals as of the given block. The block
+// number can be nil, in which case the totals of the latest known block are
+// returned.
+func (ec *Client) NetworkTotalsAt(ctx context.Context, blockNumber *big.Int) (*NetworkTotals, error) {
+	head, err := ec.HeaderByNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, errUnknownBlock
+	}
+	return &NetworkTotals{NSignups: head.NSignups, TotalWei: head.TotalWei}, nil
+}
+
+// rpcSignupTx is the part of a transaction needed to resolve signups.
+type rpcSignupTx struct {
+	BlockHash   *common.Hash    `json:"blockHash"`
+	BlockNumber *rpc.HexNumber  `json:"blockNumber"`
+	From        common.Address  `json:"from"`
+	To          *common.Address `json:"to"`
+	Value       *rpc.HexNumber  `json:"value"`
+	Input       hexutil.Bytes   `json:"input"`
+}
+
+func (ec *Client) signupTx(ctx context.Context, hash common.Hash) (*rpcSignupTx, error) {
+	var tx *rpcSignupTx
+	if err := ec.c.CallContext(ctx, &tx, "eth_getTransactionByHash", hash); err != nil {
+		return nil, err
+	}
+	if tx == nil || tx.Value == nil {
+		return nil, errUnknownSignup
+	}
+	return tx, nil
+}
+
+// SignupChain returns the referring members of the member signed up by the given
+// transaction, nearest first and up to core.SignupChainDepth levels. These are
+// the members rewarded for the signup. The chain rules tell the privileged
+// addresses allowed to send signups.
+func (ec *Client) SignupChain(ctx context.Context, config *params.ChainConfig, txHash common.Hash) ([]common.Address, error) {
+	tx, err := ec.signupTx(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	if !core.IsSignupTx(config, tx.From, tx.Value.BigInt(), tx.Input) {
+		return nil, errNotSignup
+	}
+	return ec.signupChain(ctx, tx.Input)
+}
+
+// signupChain resolves the referring members of a signup transaction with the
+// given data, mirroring core.SignupChain.
+func (ec *Client) signupChain(ctx context.Context, data []byte) ([]common.Address, error) {
+	chain := []common.Address{}
+	for len(chain) < core.SignupChainDepth {
+		number, hash, err := core.ParseSignupData(data)
+		if err == core.ErrNoMoreMembers {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidSignupChain
+		}
+		tx, err := ec.signupTx(ctx, hash)
+		if err == errUnknownSignup {
+			return nil, ErrInvalidSignupChain
+		}
+		if err != nil {
+			return nil, err
+		}
+		if tx.BlockNumber == nil || tx.BlockNumber.Uint64() != number ||
+			tx.To == nil || tx.Value.BigInt().Cmp(big.NewInt(1)) != 0 {
+			return nil, ErrInvalidSignupChain
+		}
+		chain = append(chain, *tx.To)
+		data = tx.Input
+	}
+	return chain, nil
+}
+
+// SignupRewards returns the breakdown of the wei issued by the given signup
+// transaction under the given chain rules. ErrInvalidSignupChain is returned if
+// the signup didn't issue any rewards.
+func (ec *Client) SignupRewards(ctx context.Context, config *params.ChainConfig, txHash common.Hash) (*core.SignupRewards, error) {
+	tx, err := ec.signupTx(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	if !core.IsSignupTx(config, tx.From, tx.Value.BigInt(), tx.Input) || tx.To == nil {
+		return nil, errNotSignup
+	}
+	if tx.BlockHash == nil || *tx.BlockHash == (common.Hash{}) {
+		return nil, errPendingSignup
+	}
+	chain, err := ec.signupChain(ctx, tx.Input)
+	if err != nil {
+		return nil, err
+	}
+	// The management fee depends on the network totals before the block.
+	head, err := ec.HeaderByHash(ctx, *tx.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, errUnknownBlock
+	}
+	parent, err := ec.HeaderByHash(ctx, head.ParentHash)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, errUnknownBlock
+	}
+	return core.CalcSignupRewards(config, head.Coinbase, tx.From, *tx.To, chain, parent.NSignups, parent.TotalWei), nil
+}
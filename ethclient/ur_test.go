@@ -0,0 +1,148 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/common/hexutil"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/params"
+	"github.com/ur-technology/go-ur/rpc"
+	"golang.org/x/net/context"
+)
+
+// SignupTx is the RPC representation of a transaction served by TestSignups.
+type SignupTx struct {
+	BlockHash   common.Hash     `json:"blockHash"`
+	BlockNumber *hexutil.Big    `json:"blockNumber"`
+	From        common.Address  `json:"from"`
+	To          *common.Address `json:"to"`
+	Value       *hexutil.Big    `json:"value"`
+	Input       hexutil.Bytes   `json:"input"`
+}
+
+// TestSignups is a fake eth API serving signup transactions and their blocks.
+type TestSignups struct {
+	headers map[common.Hash]*types.Header
+	txs     map[common.Hash]*SignupTx
+}
+
+func (s *TestSignups) GetBlockByHash(hash common.Hash, full bool) *types.Header {
+	return s.headers[hash]
+}
+
+func (s *TestSignups) GetTransactionByHash(hash common.Hash) *SignupTx {
+	return s.txs[hash]
+}
+
+// addHeader adds a header with the given parent and network totals.
+func (s *TestSignups) addHeader(number uint64, parent common.Hash, coinbase common.Address, nSignups, totalWei int64) *types.Header {
+	header := &types.Header{
+		ParentHash: parent,
+		Coinbase:   coinbase,
+		Number:     new(big.Int).SetUint64(number),
+		Difficulty: new(big.Int),
+		GasLimit:   new(big.Int),
+		GasUsed:    new(big.Int),
+		Time:       new(big.Int),
+		NSignups:   big.NewInt(nSignups),
+		TotalWei:   new(big.Int).Mul(big.NewInt(totalWei), common.Ether),
+		Extra:      []byte{},
+	}
+	s.headers[header.Hash()] = header
+	return header
+}
+
+// addSignup adds a signup transaction of the given member referred by the
+// member signed up in the given referrer transaction.
+func (s *TestSignups) addSignup(hash common.Hash, block *types.Header, from, member common.Address, number uint64, referrer common.Hash) {
+	s.txs[hash] = &SignupTx{
+		BlockHash:   block.Hash(),
+		BlockNumber: (*hexutil.Big)(block.Number),
+		From:        from,
+		To:          &member,
+		Value:       (*hexutil.Big)(big.NewInt(1)),
+		Input:       core.SignupData(number, referrer),
+	}
+}
+
+// Tests that the signup chain and rewards of a signup are resolved through the
+// RPC API the same way as during block processing.
+func TestSignupRewards(t *testing.T) {
+	config := params.MainnetChainConfig
+	privileged := config.PrivilegedAddresses()[0]
+
+	var (
+		coinbase = common.HexToAddress("0xc0")
+		members  = []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02"), common.HexToAddress("0x03")}
+		txs      = []common.Hash{common.HexToHash("0x11"), common.HexToHash("0x12"), common.HexToHash("0x13")}
+		bogus    = common.HexToHash("0x14")
+	)
+	// Members 1 <- 2 <- 3 signed up in blocks 3, 5 and 10, and a signup referring
+	// to the transaction of member 1 with the wrong block number.
+	api := &TestSignups{headers: make(map[common.Hash]*types.Header), txs: make(map[common.Hash]*SignupTx)}
+	api.addSignup(txs[0], api.addHeader(3, common.Hash{}, coinbase, 0, 0), privileged, members[0], 0, common.Hash{})
+	api.addSignup(txs[1], api.addHeader(5, common.Hash{}, coinbase, 1, 10000), privileged, members[1], 3, txs[0])
+	parent := api.addHeader(9, common.Hash{}, coinbase, 2, 20000)
+	block := api.addHeader(10, parent.Hash(), coinbase, 2, 20000)
+	api.addSignup(txs[2], block, privileged, members[2], 5, txs[1])
+	api.addSignup(bogus, block, privileged, members[2], 4, txs[0])
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client := NewClient(rpc.DialInProc(server))
+	ctx := context.Background()
+
+	chain, err := client.SignupChain(ctx, config, txs[2])
+	if err != nil {
+		t.Fatalf("failed to resolve signup chain: %v", err)
+	}
+	if len(chain) != 2 || chain[0] != members[1] || chain[1] != members[0] {
+		t.Fatalf("signup chain mismatch: have %x, want [%x %x]", chain, members[1], members[0])
+	}
+	rewards, err := client.SignupRewards(ctx, config, txs[2])
+	if err != nil {
+		t.Fatalf("failed to resolve signup rewards: %v", err)
+	}
+	want := core.CalcSignupRewards(config, coinbase, privileged, members[2], chain, parent.NSignups, parent.TotalWei)
+	have, exp := rewards.Rewards(), want.Rewards()
+	if len(have) != len(exp) {
+		t.Fatalf("reward count mismatch: have %d, want %d", len(have), len(exp))
+	}
+	for i := range have {
+		if have[i].Address != exp[i].Address || have[i].Amount.Cmp(exp[i].Amount) != 0 {
+			t.Errorf("reward %d mismatch: have %x %v, want %x %v", i, have[i].Address, have[i].Amount, exp[i].Address, exp[i].Amount)
+		}
+	}
+	// The parent averages 10k UR per member, so the management fee is still due.
+	fee := new(big.Int).Sub(core.TotalSingupRewards, core.MembersSingupRewards[0])
+	fee.Sub(fee, core.MembersSingupRewards[1])
+	fee.Add(fee, core.ManagementFee)
+	if rewards.Receiver.Amount.Cmp(fee) != 0 {
+		t.Errorf("receiver reward mismatch: have %v, want %v", rewards.Receiver.Amount, fee)
+	}
+	if _, err := client.SignupRewards(ctx, config, bogus); err != ErrInvalidSignupChain {
+		t.Errorf("invalid signup chain error mismatch: have %v, want %v", err, ErrInvalidSignupChain)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/common/hexutil"
+)
+
+// arguments are the arguments of a field, with variables already substituted.
+type arguments map[string]interface{}
+
+// check ensures that only the given arguments are present and that the required
+// ones (marked with a trailing '!') are not null.
+func (a arguments) check(names ...string) error {
+	known := make(map[string]bool)
+	for _, name := range names {
+		required := strings.HasSuffix(name, "!")
+		name = strings.TrimSuffix(name, "!")
+		known[name] = true
+		if required && a[name] == nil {
+			return fmt.Errorf("missing required argument %q", name)
+		}
+	}
+	for name := range a {
+		if !known[name] {
+			return fmt.Errorf("unknown argument %q", name)
+		}
+	}
+	return nil
+}
+
+// long returns a Long argument, accepting integers and decimal or hex strings.
+func (a arguments) long(name string) (*uint64, error) {
+	return toLong(name, a[name])
+}
+
+// int returns an Int argument.
+func (a arguments) int(name string) (*int, error) {
+	n, err := toLong(name, a[name])
+	if n == nil || err != nil {
+		return nil, err
+	}
+	if *n > math.MaxInt32 {
+		return nil, fmt.Errorf("argument %q out of range", name)
+	}
+	i := int(*n)
+	return &i, nil
+}
+
+// hash returns a Bytes32 argument.
+func (a arguments) hash(name string) (*common.Hash, error) {
+	blob, err := toBytes(name, a[name], common.HashLength)
+	if blob == nil || err != nil {
+		return nil, err
+	}
+	hash := common.BytesToHash(blob)
+	return &hash, nil
+}
+
+// address returns an Address argument.
+func (a arguments) address(name string) (*common.Address, error) {
+	blob, err := toBytes(name, a[name], common.AddressLength)
+	if blob == nil || err != nil {
+		return nil, err
+	}
+	addr := common.BytesToAddress(blob)
+	return &addr, nil
+}
+
+// toLong converts an argument value to a non-negative 64 bit integer.
+func toLong(name string, v interface{}) (*uint64, error) {
+	var n uint64
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case int64:
+		if v < 0 {
+			return nil, fmt.Errorf("argument %q must not be negative", name)
+		}
+		n = uint64(v)
+	case float64:
+		if v < 0 || v != math.Trunc(v) || v > math.MaxUint64 {
+			return nil, fmt.Errorf("argument %q must be a non-negative integer", name)
+		}
+		n = uint64(v)
+	case json.Number:
+		parsed, err := strconv.ParseUint(string(v), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q must be a non-negative integer", name)
+		}
+		n = parsed
+	case string:
+		var err error
+		if strings.HasPrefix(v, "0x") {
+			n, err = hexutil.DecodeUint64(v)
+		} else {
+			n, err = strconv.ParseUint(v, 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("argument %q must be a non-negative integer", name)
+		}
+	default:
+		return nil, fmt.Errorf("argument %q must be an integer", name)
+	}
+	return &n, nil
+}
+
+// toBytes converts a hex string argument value to a byte slice of the given
+// length.
+func toBytes(name string, v interface{}, length int) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	str, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("argument %q must be a hex string", name)
+	}
+	blob, err := hexutil.Decode(str)
+	if err != nil || len(blob) != length {
+		return nil, fmt.Errorf("argument %q must be %d hex encoded bytes", name, length)
+	}
+	return blob, nil
+}
+
+// toAddresses converts a list argument value to addresses.
+func toAddresses(name string, v interface{}) ([]common.Address, error) {
+	if v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+	addrs := make([]common.Address, len(list))
+	for i, item := range list {
+		blob, err := toBytes(name, item, common.AddressLength)
+		if err != nil {
+			return nil, err
+		}
+		if blob == nil {
+			return nil, fmt.Errorf("argument %q must not contain null", name)
+		}
+		addrs[i] = common.BytesToAddress(blob)
+	}
+	return addrs, nil
+}
+
+// toTopics converts a list of topic lists argument value to topics.
+func toTopics(name string, v interface{}) ([][]common.Hash, error) {
+	if v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("argument %q must be a list of topic lists", name)
+	}
+	topics := make([][]common.Hash, len(list))
+	for i, item := range list {
+		if item == nil {
+			continue
+		}
+		alternatives, ok := item.([]interface{})
+		if !ok {
+			alternatives = []interface{}{item}
+		}
+		for _, alt := range alternatives {
+			blob, err := toBytes(name, alt, common.HashLength)
+			if err != nil {
+				return nil, err
+			}
+			if blob == nil {
+				return nil, fmt.Errorf("argument %q must not contain null topics", name)
+			}
+			topics[i] = append(topics[i], common.BytesToHash(blob))
+		}
+	}
+	return topics, nil
+}
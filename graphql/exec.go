@@ -0,0 +1,333 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// errUnknownField is returned by resolvers for fields not in their type.
+var errUnknownField = errors.New("unknown field")
+
+// object is a value of a GraphQL object type, resolving its own fields.
+type object interface {
+	// typeName returns the name of the object's GraphQL type.
+	typeName() string
+
+	// resolve returns the value of a field, which is either nil, a JSON
+	// serializable scalar, an object or a list of objects.
+	resolve(ctx context.Context, name string, args arguments) (interface{}, error)
+}
+
+// queryError is an error of a GraphQL response.
+type queryError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// response is the result of executing a GraphQL query.
+type response struct {
+	Data   interface{}   `json:"data"`
+	Errors []*queryError `json:"errors,omitempty"`
+}
+
+// orderedMap is a JSON object keeping its fields in selection order.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// MarshalJSON implements json.Marshaler, writing the fields in order.
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// executor runs a single operation of a query document.
+type executor struct {
+	doc  *document
+	vars map[string]interface{}
+	errs []*queryError
+}
+
+// execute parses and runs a GraphQL query against the root object.
+func execute(ctx context.Context, root object, query, opName string, vars map[string]interface{}) *response {
+	doc, err := parseDocument(query)
+	if err != nil {
+		return &response{Errors: []*queryError{{Message: err.Error()}}}
+	}
+	op, err := doc.operation(opName)
+	if err != nil {
+		return &response{Errors: []*queryError{{Message: err.Error()}}}
+	}
+	if op.kind != "query" {
+		return &response{Errors: []*queryError{{Message: fmt.Sprintf("unsupported operation type %q", op.kind)}}}
+	}
+	e := &executor{doc: doc, vars: make(map[string]interface{})}
+	for _, v := range op.variables {
+		value, ok := vars[v.name]
+		switch {
+		case ok:
+			e.vars[v.name] = value
+		case v.hasDef:
+			e.vars[v.name] = v.defValue
+		case strings.HasSuffix(v.kind, "!"):
+			return &response{Errors: []*queryError{{Message: fmt.Sprintf("variable $%s of required type %s not provided", v.name, v.kind)}}}
+		}
+	}
+	data := e.executeObject(ctx, root, []*field{{selection: op.selection}}, nil)
+	return &response{Data: data, Errors: e.errs}
+}
+
+// operation picks the operation to execute from a document.
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, errors.New("operation name required for documents with multiple operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// fail records a field error.
+func (e *executor) fail(path []interface{}, err error) {
+	e.errs = append(e.errs, &queryError{Message: err.Error(), Path: append([]interface{}{}, path...)})
+}
+
+// collectFields flattens the selection sets of the given fields for an object,
+// resolving fragments and directives, and groups the selected fields by their
+// response key.
+func (e *executor) collectFields(obj object, fields []*field) ([]string, map[string][]*field, error) {
+	var (
+		keys    []string
+		grouped = make(map[string][]*field)
+		visited = make(map[string]bool)
+	)
+	var collect func(set []selection) error
+	collect = func(set []selection) error {
+		for _, sel := range set {
+			include, err := e.included(sel.directives)
+			if err != nil {
+				return err
+			}
+			if !include {
+				continue
+			}
+			switch {
+			case sel.field != nil:
+				if _, ok := grouped[sel.field.alias]; !ok {
+					keys = append(keys, sel.field.alias)
+				}
+				grouped[sel.field.alias] = append(grouped[sel.field.alias], sel.field)
+
+			case sel.inline != nil:
+				if sel.inline.on == "" || sel.inline.on == obj.typeName() {
+					if err := collect(sel.inline.selection); err != nil {
+						return err
+					}
+				}
+			default:
+				frag, ok := e.doc.fragments[sel.spread]
+				if !ok {
+					return fmt.Errorf("unknown fragment %q", sel.spread)
+				}
+				if visited[sel.spread] {
+					continue
+				}
+				visited[sel.spread] = true
+				if frag.on == obj.typeName() {
+					if err := collect(frag.selection); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+	for _, f := range fields {
+		if err := collect(f.selection); err != nil {
+			return nil, nil, err
+		}
+	}
+	return keys, grouped, nil
+}
+
+// included evaluates the @include and @skip directives of a selection.
+func (e *executor) included(dirs []*directive) (bool, error) {
+	for _, dir := range dirs {
+		if dir.name != "include" && dir.name != "skip" {
+			return false, fmt.Errorf("unknown directive @%s", dir.name)
+		}
+		value, err := e.value(dir.args["if"])
+		if err != nil {
+			return false, err
+		}
+		cond, ok := value.(bool)
+		if !ok {
+			return false, fmt.Errorf("directive @%s requires a boolean 'if' argument", dir.name)
+		}
+		if cond != (dir.name == "include") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// value substitutes the variables within an argument value.
+func (e *executor) value(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case varRef:
+		value, ok := e.vars[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("undefined variable $%s", v)
+		}
+		return value, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if list[i], err = e.value(item); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			var err error
+			if obj[key], err = e.value(item); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case enumValue:
+		return string(v), nil
+	}
+	return v, nil
+}
+
+// executeObject resolves the fields selected on an object.
+func (e *executor) executeObject(ctx context.Context, obj object, fields []*field, path []interface{}) interface{} {
+	keys, grouped, err := e.collectFields(obj, fields)
+	if err != nil {
+		e.fail(path, err)
+		return nil
+	}
+	result := &orderedMap{values: make(map[string]interface{})}
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			e.fail(path, ctx.Err())
+			return nil
+		}
+		group := grouped[key]
+		fieldPath := append(path, key)
+
+		if group[0].name == "__typename" {
+			result.set(key, obj.typeName())
+			continue
+		}
+		args := make(arguments, len(group[0].args))
+		for name, arg := range group[0].args {
+			if args[name], err = e.value(arg); err != nil {
+				break
+			}
+		}
+		var value interface{}
+		if err == nil {
+			value, err = obj.resolve(ctx, group[0].name, args)
+		}
+		if err == errUnknownField {
+			err = fmt.Errorf("unknown field %q on type %s", group[0].name, obj.typeName())
+		}
+		if err != nil {
+			e.fail(fieldPath, err)
+			result.set(key, nil)
+			continue
+		}
+		result.set(key, e.complete(ctx, value, group, fieldPath))
+	}
+	return result
+}
+
+// complete serializes a resolved field value, executing the sub-selections of
+// objects.
+func (e *executor) complete(ctx context.Context, value interface{}, fields []*field, path []interface{}) interface{} {
+	hasSelection := len(fields[0].selection) > 0
+	switch v := value.(type) {
+	case nil:
+		return nil
+
+	case object:
+		if !hasSelection {
+			e.fail(path, fmt.Errorf("field %q of type %s requires a selection", fields[0].name, v.typeName()))
+			return nil
+		}
+		return e.executeObject(ctx, v, fields, path)
+
+	case []object:
+		if !hasSelection {
+			e.fail(path, fmt.Errorf("field %q requires a selection", fields[0].name))
+			return nil
+		}
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = e.executeObject(ctx, item, fields, append(path, i))
+		}
+		return list
+
+	default:
+		if hasSelection {
+			e.fail(path, fmt.Errorf("field %q is a scalar and can't have a selection", fields[0].name))
+			return nil
+		}
+		return v
+	}
+}
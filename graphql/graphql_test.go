@@ -0,0 +1,332 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/core/vm"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/ethdb"
+	"github.com/ur-technology/go-ur/internal/ethapi"
+	"github.com/ur-technology/go-ur/params"
+	"github.com/ur-technology/go-ur/rpc"
+	"golang.org/x/net/context"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+	testMember  = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	testInvitee = common.HexToAddress("0x2000000000000000000000000000000000000002")
+	testTopic   = common.HexToHash("0x0100000000000000000000000000000000000000000000000000000000000001")
+
+	// testChainConfig are the test network rules with the test address privileged.
+	testChainConfig = func() *params.ChainConfig {
+		config := *params.TestnetChainConfig
+		config.Privileged = map[common.Address]params.PrivilegedReceivers{testAddress: {}}
+		return &config
+	}()
+)
+
+// testState is a fake account state, holding the balance of the test address.
+type testState struct{}
+
+func (testState) GetBalance(ctx context.Context, addr common.Address) (*big.Int, error) {
+	if addr == testAddress {
+		return big.NewInt(1000), nil
+	}
+	return new(big.Int), nil
+}
+func (testState) GetCode(ctx context.Context, addr common.Address) ([]byte, error) {
+	return nil, nil
+}
+func (testState) GetState(ctx context.Context, a common.Address, b common.Hash) (common.Hash, error) {
+	return b, nil
+}
+func (testState) GetNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	return 2, nil
+}
+
+// testBackend is a fake backend serving a short chain of signup blocks. The
+// methods not needed by the resolvers are left unimplemented.
+type testBackend struct {
+	ethapi.Backend
+	db       ethdb.Database
+	blocks   []*types.Block
+	receipts map[common.Hash]types.Receipts
+	pool     types.Transactions
+}
+
+// newTestBackend creates a chain in which the test address signs up a member in
+// block 1, who then refers an invitee signed up in block 2.
+func newTestBackend(t *testing.T) *testBackend {
+	db, _ := ethdb.NewMemDatabase()
+	b := &testBackend{db: db, receipts: make(map[common.Hash]types.Receipts)}
+
+	signer := types.FrontierSigner{}
+	sign := func(nonce uint64, to common.Address, value int64, data []byte) *types.Transaction {
+		tx, err := types.NewTransaction(nonce, to, big.NewInt(value), big.NewInt(21000), big.NewInt(1), data).SignECDSA(signer, testKey)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		return tx
+	}
+	add := func(txs []*types.Transaction, receipts []*types.Receipt) *types.Block {
+		header := &types.Header{
+			Number:     big.NewInt(int64(len(b.blocks))),
+			Difficulty: big.NewInt(131072),
+			GasLimit:   big.NewInt(4712388),
+			GasUsed:    big.NewInt(int64(21000 * len(txs))),
+			Time:       big.NewInt(int64(len(b.blocks) * 10)),
+			NSignups:   big.NewInt(int64(len(b.blocks))),
+			TotalWei:   big.NewInt(int64(len(b.blocks) * 1000)),
+		}
+		if len(b.blocks) > 0 {
+			header.ParentHash = b.blocks[len(b.blocks)-1].Hash()
+		}
+		block := types.NewBlock(header, txs, nil, receipts)
+		if err := core.WriteTransactions(db, block); err != nil {
+			t.Fatalf("failed to write transactions: %v", err)
+		}
+		b.blocks = append(b.blocks, block)
+		b.receipts[block.Hash()] = receipts
+		return block
+	}
+	add(nil, nil)
+
+	signup := sign(0, testMember, 1, core.SignupData(0, common.Hash{}))
+	receipt := types.NewReceipt(nil, big.NewInt(21000))
+	receipt.TxHash, receipt.GasUsed = signup.Hash(), big.NewInt(21000)
+	receipt.Logs = []*vm.Log{{Address: testMember, Topics: []common.Hash{testTopic}, Data: []byte{0x01}}}
+	add([]*types.Transaction{signup}, []*types.Receipt{receipt})
+
+	referred := sign(1, testInvitee, 1, core.SignupData(1, signup.Hash()))
+	receipt = types.NewReceipt(nil, big.NewInt(21000))
+	receipt.TxHash, receipt.GasUsed = referred.Hash(), big.NewInt(21000)
+	add([]*types.Transaction{referred}, []*types.Receipt{receipt})
+
+	b.pool = types.Transactions{sign(2, testMember, 5, nil)}
+	return b
+}
+
+func (b *testBackend) block(nr rpc.BlockNumber) *types.Block {
+	if nr == rpc.LatestBlockNumber || nr == rpc.PendingBlockNumber {
+		return b.blocks[len(b.blocks)-1]
+	}
+	if int(nr) < len(b.blocks) {
+		return b.blocks[nr]
+	}
+	return nil
+}
+
+func (b *testBackend) ChainDb() ethdb.Database          { return b.db }
+func (b *testBackend) ProtocolVersion() int             { return 63 }
+func (b *testBackend) ChainConfig() *params.ChainConfig { return testChainConfig }
+func (b *testBackend) GetTd(hash common.Hash) *big.Int  { return big.NewInt(131072) }
+
+func (b *testBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(20000000000), nil
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, nr rpc.BlockNumber) (*types.Header, error) {
+	if block := b.block(nr); block != nil {
+		return block.Header(), nil
+	}
+	return nil, nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, nr rpc.BlockNumber) (*types.Block, error) {
+	return b.block(nr), nil
+}
+
+func (b *testBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	for _, block := range b.blocks {
+		if block.Hash() == hash {
+			return block, nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.receipts[hash], nil
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, nr rpc.BlockNumber) (ethapi.State, *types.Header, error) {
+	if block := b.block(nr); block != nil {
+		return testState{}, block.Header(), nil
+	}
+	return nil, nil, nil
+}
+
+func (b *testBackend) GetPoolTransactions() types.Transactions { return b.pool }
+
+func (b *testBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	for _, tx := range b.pool {
+		if tx.Hash() == hash {
+			return tx
+		}
+	}
+	return nil
+}
+
+// testResponse is a GraphQL response with its data left encoded, retaining the
+// order of the fields.
+type testResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []interface{}   `json:"errors"`
+}
+
+// postQuery posts a GraphQL request to the service and returns the response.
+func postQuery(t *testing.T, srv *httptest.Server, query string, vars map[string]interface{}) (int, *testResponse) {
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+	resp, err := http.Post(srv.URL+"/graphql", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to post query: %v", err)
+	}
+	defer resp.Body.Close()
+
+	result := new(testResponse)
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp.StatusCode, result
+}
+
+// Tests that chain, account and UR signup data is resolved through the backend.
+func TestGraphQLQueries(t *testing.T) {
+	backend := newTestBackend(t)
+	mux := http.NewServeMux()
+	for path, handler := range New(backend).HTTPHandlers() {
+		mux.Handle(path, handler)
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	signup := backend.blocks[1].Transactions()[0]
+	referred := backend.blocks[2].Transactions()[0]
+	pending := backend.pool[0]
+
+	tests := []struct {
+		query string
+		vars  map[string]interface{}
+		want  string
+	}{
+		{
+			query: `{ block { number nSignups totalWei parent { number } } }`,
+			want:  `{"block":{"number":2,"nSignups":"0x2","totalWei":"0x7d0","parent":{"number":1}}}`,
+		},
+		{
+			query: `query ($n: Long) { block(number: $n) { hash transactionCount } }`,
+			vars:  map[string]interface{}{"n": "0x1"},
+			want:  fmt.Sprintf(`{"block":{"hash":"%s","transactionCount":1}}`, backend.blocks[1].Hash().Hex()),
+		},
+		{
+			query: `{ blocks(from: 0) { number } }`,
+			want:  `{"blocks":[{"number":0},{"number":1},{"number":2}]}`,
+		},
+		{
+			query: fmt.Sprintf(`{ transaction(hash: "%s") { index isSignup signupChain from { address balance } block { number } } }`, referred.Hash().Hex()),
+			want:  fmt.Sprintf(`{"transaction":{"index":0,"isSignup":true,"signupChain":["%s"],"from":{"address":"%s","balance":"0x3e8"},"block":{"number":2}}}`, testMember.Hex(), testAddress.Hex()),
+		},
+		{
+			query: fmt.Sprintf(`{ transaction(hash: "%s") { signupChain gasUsed logs { index topics data } } }`, signup.Hash().Hex()),
+			want:  fmt.Sprintf(`{"transaction":{"signupChain":[],"gasUsed":21000,"logs":[{"index":0,"topics":["%s"],"data":"0x01"}]}}`, testTopic.Hex()),
+		},
+		{
+			query: fmt.Sprintf(`{ transaction(hash: "%s") { isSignup signupChain block { number } gasUsed to { transactionCount } } }`, pending.Hash().Hex()),
+			want:  `{"transaction":{"isSignup":false,"signupChain":null,"block":null,"gasUsed":null,"to":{"transactionCount":2}}}`,
+		},
+		{
+			query: `{ pending { transactionCount } }`,
+			want:  `{"pending":{"transactionCount":1}}`,
+		},
+		{
+			query: fmt.Sprintf(`{ logs(filter: {fromBlock: 0, addresses: ["%s"]}) { account { address } transaction { hash } } }`, testMember.Hex()),
+			want:  fmt.Sprintf(`{"logs":[{"account":{"address":"%s"},"transaction":{"hash":"%s"}}]}`, testMember.Hex(), signup.Hash().Hex()),
+		},
+		{
+			query: fmt.Sprintf(`{ logs(filter: {fromBlock: 0, topics: [["%s"]]}) { index } }`, common.Hash{}.Hex()),
+			want:  `{"logs":[]}`,
+		},
+		{
+			query: `{ block(number: 0) { ...header } } fragment header on Block { number __typename }`,
+			want:  `{"block":{"number":0,"__typename":"Block"}}`,
+		},
+		{
+			query: `{ block(number: 9) { number } chainID gasPrice }`,
+			want:  `{"block":null,"chainID":"0x3","gasPrice":"0x4a817c800"}`,
+		},
+	}
+	for i, tt := range tests {
+		status, result := postQuery(t, srv, tt.query, tt.vars)
+		if status != http.StatusOK || result.Errors != nil {
+			t.Errorf("test %d: query failed (status %d): %v", i, status, result.Errors)
+			continue
+		}
+		if have := string(result.Data); have != tt.want {
+			t.Errorf("test %d: data mismatch:\nhave %s\nwant %s", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that invalid queries are reported as GraphQL errors.
+func TestGraphQLErrors(t *testing.T) {
+	srv := httptest.NewServer(New(newTestBackend(t)))
+	defer srv.Close()
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{`{ block { `, http.StatusBadRequest},
+		{`mutation { block { number } }`, http.StatusBadRequest},
+		{`{ block { unknown } }`, http.StatusOK},
+		{`{ block(number: 1, hash: "0x00") { number } }`, http.StatusOK},
+		{`{ blocks(from: 0, to: 5000) { number } }`, http.StatusOK},
+		{`{ block { number(foo: 1) } }`, http.StatusOK},
+		{`{ block }`, http.StatusOK},
+		{`{ block(number: $n) { number } }`, http.StatusOK},
+	}
+	for i, tt := range tests {
+		body, _ := json.Marshal(map[string]interface{}{"query": tt.query})
+		resp, err := http.Post(srv.URL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("test %d: failed to post query: %v", i, err)
+		}
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, resp.StatusCode, tt.status)
+		}
+		if errs, _ := result["errors"].([]interface{}); len(errs) == 0 {
+			t.Errorf("test %d: no error reported for %q", i, tt.query)
+		}
+	}
+}
@@ -0,0 +1,527 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed GraphQL query document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query operation of a document.
+type operation struct {
+	kind      string // Operation type, only "query" is supported
+	name      string
+	variables []*variable
+	selection []selection
+}
+
+// variable is a variable definition of an operation.
+type variable struct {
+	name     string
+	kind     string // Type of the variable as written in the query
+	defValue interface{}
+	hasDef   bool
+}
+
+// fragment is a named fragment definition.
+type fragment struct {
+	name      string
+	on        string
+	selection []selection
+}
+
+// selection is an item of a selection set: a field, a fragment spread or an
+// inline fragment.
+type selection struct {
+	field      *field
+	spread     string    // Name of a spread fragment
+	inline     *fragment // Inline fragment, with an optional type condition
+	directives []*directive
+}
+
+// field is a selected field with its arguments and sub-selection.
+type field struct {
+	alias     string
+	name      string
+	args      map[string]interface{}
+	selection []selection
+}
+
+// directive is a directive (e.g. @include) applied to a selection.
+type directive struct {
+	name string
+	args map[string]interface{}
+}
+
+// varRef is a reference to a variable within an argument value.
+type varRef string
+
+// enumValue is an enum literal within an argument value.
+type enumValue string
+
+// token kinds produced by the lexer.
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// lexer splits a GraphQL document into tokens.
+type lexer struct {
+	src string
+	pos int
+	tok token
+}
+
+// next advances the lexer to the next token.
+func (l *lexer) next() error {
+	// Skip whitespace, commas and comments
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+			continue
+		}
+		if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		break
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		l.tok = token{kind: tokEOF, pos: start}
+		return nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		l.tok = token{kind: tokPunct, text: "...", pos: start}
+
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		l.pos++
+		l.tok = token{kind: tokPunct, text: string(c), pos: start}
+
+	case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		for l.pos < len(l.src) && isNameChar(l.src[l.pos]) {
+			l.pos++
+		}
+		l.tok = token{kind: tokName, text: l.src[start:l.pos], pos: start}
+
+	case c == '-' || (c >= '0' && c <= '9'):
+		kind := tokInt
+		l.pos++
+		for l.pos < len(l.src) {
+			c := l.src[l.pos]
+			if c >= '0' && c <= '9' {
+				l.pos++
+			} else if c == '.' || c == 'e' || c == 'E' || ((c == '+' || c == '-') && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E')) {
+				kind = tokFloat
+				l.pos++
+			} else {
+				break
+			}
+		}
+		l.tok = token{kind: kind, text: l.src[start:l.pos], pos: start}
+
+	case c == '"':
+		text, err := l.readString()
+		if err != nil {
+			return err
+		}
+		l.tok = token{kind: tokString, text: text, pos: start}
+
+	default:
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+		return fmt.Errorf("syntax error at %d: unexpected character %q", start, r)
+	}
+	return nil
+}
+
+// readString reads a quoted string literal, resolving escape sequences.
+func (l *lexer) readString() (string, error) {
+	start := l.pos
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		end := strings.Index(l.src[l.pos+3:], `"""`)
+		if end < 0 {
+			return "", fmt.Errorf("syntax error at %d: unterminated block string", start)
+		}
+		text := l.src[l.pos+3 : l.pos+3+end]
+		l.pos += end + 6
+		return strings.TrimSpace(text), nil
+	}
+	l.pos++
+	var out []byte
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return string(out), nil
+		case c == '\n' || c == '\r':
+			return "", fmt.Errorf("syntax error at %d: unterminated string", start)
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return "", fmt.Errorf("syntax error at %d: unterminated string", start)
+			}
+			esc := l.src[l.pos+1]
+			l.pos += 2
+			switch esc {
+			case '"', '\\', '/':
+				out = append(out, esc)
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return "", fmt.Errorf("syntax error at %d: invalid unicode escape", l.pos)
+				}
+				code, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return "", fmt.Errorf("syntax error at %d: invalid unicode escape", l.pos)
+				}
+				var buf [4]byte
+				out = append(out, buf[:utf8.EncodeRune(buf[:], rune(code))]...)
+				l.pos += 4
+			default:
+				return "", fmt.Errorf("syntax error at %d: invalid escape \\%c", l.pos-1, esc)
+			}
+		default:
+			out = append(out, c)
+			l.pos++
+		}
+	}
+	return "", fmt.Errorf("syntax error at %d: unterminated string", start)
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// parser is a recursive descent parser of GraphQL query documents.
+type parser struct {
+	lex *lexer
+}
+
+// parseDocument parses a GraphQL query document.
+func parseDocument(src string) (doc *document, err error) {
+	p := &parser{lex: &lexer{src: src}}
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, perr.error
+		}
+	}()
+	p.advance()
+
+	doc = &document{fragments: make(map[string]*fragment)}
+	for p.lex.tok.kind != tokEOF {
+		switch {
+		case p.peek("{"):
+			doc.operations = append(doc.operations, &operation{kind: "query", selection: p.parseSelectionSet()})
+
+		case p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+			doc.operations = append(doc.operations, p.parseOperation())
+
+		case p.peekName("fragment"):
+			p.advance()
+			frag := &fragment{name: p.expectName()}
+			if frag.name == "on" {
+				p.fail("invalid fragment name %q", frag.name)
+			}
+			p.expectKeyword("on")
+			frag.on = p.expectName()
+			p.parseDirectives()
+			frag.selection = p.parseSelectionSet()
+			if _, exists := doc.fragments[frag.name]; exists {
+				p.fail("duplicate fragment %q", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+
+		default:
+			p.fail("unexpected %q", p.lex.tok.text)
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document contains no operations")
+	}
+	return doc, nil
+}
+
+// parseError wraps errors raised while parsing, unwound by parseDocument.
+type parseError struct{ error }
+
+func (p *parser) fail(format string, args ...interface{}) {
+	panic(parseError{fmt.Errorf("syntax error at %d: %s", p.lex.tok.pos, fmt.Sprintf(format, args...))})
+}
+
+func (p *parser) advance() {
+	if err := p.lex.next(); err != nil {
+		panic(parseError{err})
+	}
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.lex.tok.kind == tokPunct && p.lex.tok.text == punct
+}
+
+func (p *parser) peekName(name string) bool {
+	return p.lex.tok.kind == tokName && p.lex.tok.text == name
+}
+
+func (p *parser) expect(punct string) {
+	if !p.peek(punct) {
+		p.fail("expected %q, found %q", punct, p.lex.tok.text)
+	}
+	p.advance()
+}
+
+func (p *parser) expectKeyword(name string) {
+	if !p.peekName(name) {
+		p.fail("expected %q, found %q", name, p.lex.tok.text)
+	}
+	p.advance()
+}
+
+func (p *parser) expectName() string {
+	if p.lex.tok.kind != tokName {
+		p.fail("expected name, found %q", p.lex.tok.text)
+	}
+	name := p.lex.tok.text
+	p.advance()
+	return name
+}
+
+// parseOperation parses an operation definition with its variables.
+func (p *parser) parseOperation() *operation {
+	op := &operation{kind: p.expectName()}
+	if p.lex.tok.kind == tokName {
+		op.name = p.expectName()
+	}
+	if p.peek("(") {
+		p.advance()
+		for !p.peek(")") {
+			p.expect("$")
+			v := &variable{name: p.expectName()}
+			p.expect(":")
+			v.kind = p.parseType()
+			if p.peek("=") {
+				p.advance()
+				v.defValue, v.hasDef = p.parseValue(true), true
+			}
+			op.variables = append(op.variables, v)
+		}
+		p.advance()
+	}
+	p.parseDirectives()
+	op.selection = p.parseSelectionSet()
+	return op
+}
+
+// parseType parses a type reference, returning it in its textual form.
+func (p *parser) parseType() string {
+	var kind string
+	if p.peek("[") {
+		p.advance()
+		kind = "[" + p.parseType() + "]"
+		p.expect("]")
+	} else {
+		kind = p.expectName()
+	}
+	if p.peek("!") {
+		p.advance()
+		kind += "!"
+	}
+	return kind
+}
+
+// parseSelectionSet parses a braced selection set.
+func (p *parser) parseSelectionSet() []selection {
+	p.expect("{")
+	var set []selection
+	for !p.peek("}") {
+		if p.lex.tok.kind == tokEOF {
+			p.fail("unterminated selection set")
+		}
+		set = append(set, p.parseSelection())
+	}
+	p.advance()
+	if len(set) == 0 {
+		p.fail("empty selection set")
+	}
+	return set
+}
+
+// parseSelection parses a field, fragment spread or inline fragment.
+func (p *parser) parseSelection() selection {
+	if p.peek("...") {
+		p.advance()
+		if p.lex.tok.kind == tokName && !p.peekName("on") {
+			name := p.expectName()
+			return selection{spread: name, directives: p.parseDirectives()}
+		}
+		frag := new(fragment)
+		if p.peekName("on") {
+			p.advance()
+			frag.on = p.expectName()
+		}
+		sel := selection{inline: frag, directives: p.parseDirectives()}
+		frag.selection = p.parseSelectionSet()
+		return sel
+	}
+	f := &field{name: p.expectName()}
+	if p.peek(":") {
+		p.advance()
+		f.alias, f.name = f.name, p.expectName()
+	}
+	if f.alias == "" {
+		f.alias = f.name
+	}
+	f.args = p.parseArguments()
+	sel := selection{field: f, directives: p.parseDirectives()}
+	if p.peek("{") {
+		f.selection = p.parseSelectionSet()
+	}
+	return sel
+}
+
+// parseArguments parses an optional parenthesized argument list.
+func (p *parser) parseArguments() map[string]interface{} {
+	args := make(map[string]interface{})
+	if !p.peek("(") {
+		return args
+	}
+	p.advance()
+	for !p.peek(")") {
+		name := p.expectName()
+		p.expect(":")
+		if _, exists := args[name]; exists {
+			p.fail("duplicate argument %q", name)
+		}
+		args[name] = p.parseValue(false)
+	}
+	p.advance()
+	return args
+}
+
+// parseDirectives parses the directives applied to a selection.
+func (p *parser) parseDirectives() []*directive {
+	var dirs []*directive
+	for p.peek("@") {
+		p.advance()
+		dirs = append(dirs, &directive{name: p.expectName(), args: p.parseArguments()})
+	}
+	return dirs
+}
+
+// parseValue parses an argument value. Constant values (variable defaults) may
+// not reference variables.
+func (p *parser) parseValue(constant bool) interface{} {
+	tok := p.lex.tok
+	switch tok.kind {
+	case tokPunct:
+		switch tok.text {
+		case "$":
+			if constant {
+				p.fail("variable in constant value")
+			}
+			p.advance()
+			return varRef(p.expectName())
+		case "[":
+			p.advance()
+			list := []interface{}{}
+			for !p.peek("]") {
+				if p.lex.tok.kind == tokEOF {
+					p.fail("unterminated list")
+				}
+				list = append(list, p.parseValue(constant))
+			}
+			p.advance()
+			return list
+		case "{":
+			p.advance()
+			obj := make(map[string]interface{})
+			for !p.peek("}") {
+				name := p.expectName()
+				p.expect(":")
+				obj[name] = p.parseValue(constant)
+			}
+			p.advance()
+			return obj
+		}
+	case tokInt:
+		p.advance()
+		n, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			p.fail("invalid integer %s", tok.text)
+		}
+		return n
+	case tokFloat:
+		p.advance()
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			p.fail("invalid float %s", tok.text)
+		}
+		return f
+	case tokString:
+		p.advance()
+		return tok.text
+	case tokName:
+		p.advance()
+		switch tok.text {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return enumValue(tok.text)
+	}
+	p.fail("unexpected %q", tok.text)
+	return nil
+}
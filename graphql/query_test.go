@@ -0,0 +1,94 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Tests that query documents are parsed into the expected operations.
+func TestParseDocument(t *testing.T) {
+	doc, err := parseDocument(`
+		# Fetch a block along with its transactions
+		query Block($number: Long = 1, $full: Boolean!) {
+			head: block(number: $number) {
+				hash
+				...txs @include(if: $full)
+			}
+			logs(filter: {addresses: ["0x01"], topics: [null, ["0x02"]]}) { data }
+		}
+		fragment txs on Block {
+			transactions { hash value ... on Transaction { isSignup } }
+		}
+	`)
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+	if len(doc.operations) != 1 {
+		t.Fatalf("operation count mismatch: have %d, want 1", len(doc.operations))
+	}
+	op := doc.operations[0]
+	if op.kind != "query" || op.name != "Block" {
+		t.Errorf("operation mismatch: have %s %s, want query Block", op.kind, op.name)
+	}
+	wantVars := []*variable{
+		{name: "number", kind: "Long", defValue: int64(1), hasDef: true},
+		{name: "full", kind: "Boolean!"},
+	}
+	if !reflect.DeepEqual(op.variables, wantVars) {
+		t.Errorf("variables mismatch: have %+v, want %+v", op.variables, wantVars)
+	}
+	head := op.selection[0].field
+	if head.alias != "head" || head.name != "block" || head.args["number"] != varRef("number") {
+		t.Errorf("aliased field mismatch: have %+v", head)
+	}
+	if spread := head.selection[1]; spread.spread != "txs" || len(spread.directives) != 1 || spread.directives[0].name != "include" {
+		t.Errorf("fragment spread mismatch: have %+v", spread)
+	}
+	wantFilter := map[string]interface{}{
+		"addresses": []interface{}{"0x01"},
+		"topics":    []interface{}{nil, []interface{}{"0x02"}},
+	}
+	if filter := op.selection[1].field.args["filter"]; !reflect.DeepEqual(filter, wantFilter) {
+		t.Errorf("object argument mismatch: have %v, want %v", filter, wantFilter)
+	}
+	frag := doc.fragments["txs"]
+	if frag == nil || frag.on != "Block" || len(frag.selection[0].field.selection) != 3 {
+		t.Errorf("fragment mismatch: have %+v", frag)
+	}
+}
+
+// Tests that malformed documents are rejected.
+func TestParseDocumentErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`{`,
+		`{ block { hash }`,
+		`{ block(number: ) { hash } }`,
+		`{ block(number: 1 2) { hash } }`,
+		`query ($n: Long = $m) { block(number: $n) { hash } }`,
+		`{ block { hash } } fragment on on Block { hash }`,
+		`{ ...f } fragment f on Block { hash } fragment f on Block { number }`,
+		`{ transaction(hash: "0x01 }`,
+	}
+	for i, src := range tests {
+		if _, err := parseDocument(src); err == nil {
+			t.Errorf("test %d: no error for %q", i, src)
+		}
+	}
+}
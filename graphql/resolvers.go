@@ -0,0 +1,784 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/common/hexutil"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/core/vm"
+	"github.com/ur-technology/go-ur/internal/ethapi"
+	"github.com/ur-technology/go-ur/rpc"
+	"golang.org/x/net/context"
+)
+
+// maxBlockRange is the maximum number of blocks a single range query (blocks
+// or logs) may span.
+const maxBlockRange = 1024
+
+var (
+	errStateUnavailable = errors.New("state not available")
+	errInvalidSignup    = errors.New("invalid signup chain")
+)
+
+// bigInt converts a possibly nil big integer to its GraphQL representation.
+func bigInt(n *big.Int) *hexutil.Big {
+	if n == nil {
+		n = new(big.Int)
+	}
+	return (*hexutil.Big)(n)
+}
+
+// blockNumber converts a Long to a block number.
+func blockNumber(n uint64) (rpc.BlockNumber, error) {
+	if n > math.MaxInt64 {
+		return 0, fmt.Errorf("block number %d out of range", n)
+	}
+	return rpc.BlockNumber(n), nil
+}
+
+// query is the root object of the schema.
+type query struct {
+	backend ethapi.Backend
+}
+
+func (q *query) typeName() string { return "Query" }
+
+func (q *query) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	switch name {
+	case "block":
+		if err := args.check("number", "hash"); err != nil {
+			return nil, err
+		}
+		number, err := args.long("number")
+		if err != nil {
+			return nil, err
+		}
+		hash, err := args.hash("hash")
+		if err != nil {
+			return nil, err
+		}
+		var block *types.Block
+		switch {
+		case number != nil && hash != nil:
+			return nil, errors.New("only one of number or hash may be given")
+		case hash != nil:
+			block, err = q.backend.GetBlock(ctx, *hash)
+		case number != nil:
+			var nr rpc.BlockNumber
+			if nr, err = blockNumber(*number); err == nil {
+				block, err = q.backend.BlockByNumber(ctx, nr)
+			}
+		default:
+			block, err = q.backend.BlockByNumber(ctx, rpc.LatestBlockNumber)
+		}
+		return newBlock(q.backend, block), err
+
+	case "blocks":
+		if err := args.check("from!", "to"); err != nil {
+			return nil, err
+		}
+		from, to, err := q.blockRange(ctx, args, "from", "to")
+		if err != nil {
+			return nil, err
+		}
+		var blocks []object
+		for n := from; n <= to; n++ {
+			block, err := q.backend.BlockByNumber(ctx, rpc.BlockNumber(n))
+			if err != nil {
+				return nil, err
+			}
+			if block == nil {
+				break
+			}
+			blocks = append(blocks, &blockObject{q.backend, block})
+		}
+		return blocks, nil
+
+	case "pending":
+		return &pending{q.backend}, args.check()
+
+	case "transaction":
+		if err := args.check("hash!"); err != nil {
+			return nil, err
+		}
+		hash, err := args.hash("hash")
+		if err != nil {
+			return nil, err
+		}
+		if tx, blockHash, _, index := core.GetTransaction(q.backend.ChainDb(), *hash); tx != nil {
+			block, err := q.backend.GetBlock(ctx, blockHash)
+			if err != nil {
+				return nil, err
+			}
+			return &transaction{backend: q.backend, tx: tx, block: block, index: int(index)}, nil
+		}
+		if tx := q.backend.GetPoolTransaction(*hash); tx != nil {
+			return &transaction{backend: q.backend, tx: tx, index: -1}, nil
+		}
+		return nil, nil
+
+	case "logs":
+		if err := args.check("filter!"); err != nil {
+			return nil, err
+		}
+		criteria, ok := args["filter"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("argument \"filter\" must be an object")
+		}
+		if err := arguments(criteria).check("fromBlock", "toBlock", "addresses", "topics"); err != nil {
+			return nil, err
+		}
+		filter, err := newLogFilter(criteria)
+		if err != nil {
+			return nil, err
+		}
+		from, to, err := q.blockRange(ctx, arguments(criteria), "fromBlock", "toBlock")
+		if err != nil {
+			return nil, err
+		}
+		var logs []object
+		for n := from; n <= to; n++ {
+			header, err := q.backend.HeaderByNumber(ctx, rpc.BlockNumber(n))
+			if err != nil {
+				return nil, err
+			}
+			if header == nil {
+				break
+			}
+			matches, err := blockLogs(ctx, q.backend, header, filter)
+			if err != nil {
+				return nil, err
+			}
+			logs = append(logs, matches...)
+		}
+		return logs, nil
+
+	case "gasPrice":
+		price, err := q.backend.SuggestPrice(ctx)
+		return bigInt(price), err
+
+	case "protocolVersion":
+		return q.backend.ProtocolVersion(), nil
+
+	case "chainID":
+		return bigInt(q.backend.ChainConfig().ChainId), nil
+	}
+	return nil, errUnknownField
+}
+
+// blockRange resolves a block range from two Long arguments, defaulting to the
+// latest block and capping it at maxBlockRange blocks.
+func (q *query) blockRange(ctx context.Context, args arguments, fromArg, toArg string) (uint64, uint64, error) {
+	from, err := args.long(fromArg)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := args.long(toArg)
+	if err != nil {
+		return 0, 0, err
+	}
+	if from == nil || to == nil {
+		head, err := q.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+		if err != nil {
+			return 0, 0, err
+		}
+		latest := head.Number.Uint64()
+		if from == nil {
+			from = &latest
+		}
+		if to == nil {
+			to = &latest
+		}
+	}
+	if *from > *to {
+		return 0, 0, fmt.Errorf("invalid block range %d-%d", *from, *to)
+	}
+	if *to-*from >= maxBlockRange {
+		return 0, 0, fmt.Errorf("block range too large (%d>%d blocks)", *to-*from+1, maxBlockRange)
+	}
+	if _, err := blockNumber(*to); err != nil {
+		return 0, 0, err
+	}
+	return *from, *to, nil
+}
+
+// blockObject is a block of the chain.
+type blockObject struct {
+	backend ethapi.Backend
+	block   *types.Block
+}
+
+// newBlock wraps a block into a GraphQL object, or nil if there's no block.
+func newBlock(backend ethapi.Backend, block *types.Block) interface{} {
+	if block == nil {
+		return nil
+	}
+	return &blockObject{backend, block}
+}
+
+func (b *blockObject) typeName() string { return "Block" }
+
+func (b *blockObject) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	header := b.block.Header()
+
+	switch name {
+	case "miner", "account":
+		addr := header.Coinbase
+		if name == "miner" {
+			if err := args.check("block"); err != nil {
+				return nil, err
+			}
+		} else {
+			if err := args.check("address!"); err != nil {
+				return nil, err
+			}
+			arg, err := args.address("address")
+			if err != nil {
+				return nil, err
+			}
+			addr = *arg
+		}
+		return newAccount(b.backend, addr, args, rpc.BlockNumber(b.block.NumberU64()))
+
+	case "ommerAt", "transactionAt":
+		if err := args.check("index!"); err != nil {
+			return nil, err
+		}
+		index, err := args.int("index")
+		if err != nil {
+			return nil, err
+		}
+		if name == "ommerAt" {
+			if uncles := b.block.Uncles(); *index < len(uncles) {
+				return &blockObject{b.backend, types.NewBlockWithHeader(uncles[*index])}, nil
+			}
+			return nil, nil
+		}
+		if txs := b.block.Transactions(); *index < len(txs) {
+			return &transaction{backend: b.backend, tx: txs[*index], block: b.block, index: *index}, nil
+		}
+		return nil, nil
+
+	case "logs":
+		if err := args.check("filter!"); err != nil {
+			return nil, err
+		}
+		criteria, ok := args["filter"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("argument \"filter\" must be an object")
+		}
+		if err := arguments(criteria).check("addresses", "topics"); err != nil {
+			return nil, err
+		}
+		filter, err := newLogFilter(criteria)
+		if err != nil {
+			return nil, err
+		}
+		logs, err := blockLogs(ctx, b.backend, header, filter)
+		if logs == nil {
+			logs = []object{}
+		}
+		return logs, err
+	}
+	// The remaining fields take no arguments
+	if err := args.check(); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "number":
+		return b.block.NumberU64(), nil
+	case "hash":
+		return b.block.Hash(), nil
+	case "parent":
+		if b.block.NumberU64() == 0 {
+			return nil, nil
+		}
+		parent, err := b.backend.GetBlock(ctx, b.block.ParentHash())
+		return newBlock(b.backend, parent), err
+	case "nonce":
+		return hexutil.Bytes(header.Nonce[:]), nil
+	case "transactionsRoot":
+		return header.TxHash, nil
+	case "transactionCount":
+		return len(b.block.Transactions()), nil
+	case "stateRoot":
+		return header.Root, nil
+	case "receiptsRoot":
+		return header.ReceiptHash, nil
+	case "extraData":
+		return hexutil.Bytes(header.Extra), nil
+	case "gasLimit":
+		return header.GasLimit.Uint64(), nil
+	case "gasUsed":
+		return header.GasUsed.Uint64(), nil
+	case "timestamp":
+		return bigInt(header.Time), nil
+	case "logsBloom":
+		return hexutil.Bytes(header.Bloom.Bytes()), nil
+	case "mixHash":
+		return header.MixDigest, nil
+	case "difficulty":
+		return bigInt(header.Difficulty), nil
+	case "totalDifficulty":
+		return bigInt(b.backend.GetTd(b.block.Hash())), nil
+	case "ommerCount":
+		return len(b.block.Uncles()), nil
+	case "ommers":
+		ommers := []object{}
+		for _, uncle := range b.block.Uncles() {
+			ommers = append(ommers, &blockObject{b.backend, types.NewBlockWithHeader(uncle)})
+		}
+		return ommers, nil
+	case "ommerHash":
+		return header.UncleHash, nil
+	case "transactions":
+		txs := []object{}
+		for i, tx := range b.block.Transactions() {
+			txs = append(txs, &transaction{backend: b.backend, tx: tx, block: b.block, index: i})
+		}
+		return txs, nil
+	case "nSignups":
+		return bigInt(header.NSignups), nil
+	case "totalWei":
+		return bigInt(header.TotalWei), nil
+	}
+	return nil, errUnknownField
+}
+
+// transaction is a mined or pending transaction.
+type transaction struct {
+	backend ethapi.Backend
+	tx      *types.Transaction
+	block   *types.Block // Block containing the transaction, nil if pending
+	index   int          // Index of the transaction in the block
+}
+
+// sender recovers the sender of the transaction.
+func (t *transaction) sender() (common.Address, error) {
+	var signer types.Signer = types.FrontierSigner{}
+	if t.tx.Protected() {
+		signer = types.NewEIP155Signer(t.tx.ChainId())
+	}
+	return types.Sender(signer, t.tx)
+}
+
+// stateNumber returns the block whose state the accounts of the transaction are
+// resolved at by default.
+func (t *transaction) stateNumber() rpc.BlockNumber {
+	if t.block == nil {
+		return rpc.PendingBlockNumber
+	}
+	return rpc.BlockNumber(t.block.NumberU64())
+}
+
+// receipt retrieves the receipt of a mined transaction.
+func (t *transaction) receipt(ctx context.Context) (*types.Receipt, error) {
+	if t.block == nil {
+		return nil, nil
+	}
+	receipts, err := t.backend.GetReceipts(ctx, t.block.Hash())
+	if err != nil || t.index >= len(receipts) {
+		return nil, err
+	}
+	return receipts[t.index], nil
+}
+
+func (t *transaction) typeName() string { return "Transaction" }
+
+func (t *transaction) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	switch name {
+	case "from":
+		if err := args.check("block"); err != nil {
+			return nil, err
+		}
+		from, err := t.sender()
+		if err != nil {
+			return nil, err
+		}
+		return newAccount(t.backend, from, args, t.stateNumber())
+
+	case "to":
+		if err := args.check("block"); err != nil {
+			return nil, err
+		}
+		if t.tx.To() == nil {
+			return nil, nil
+		}
+		return newAccount(t.backend, *t.tx.To(), args, t.stateNumber())
+
+	case "createdContract":
+		if err := args.check("block"); err != nil {
+			return nil, err
+		}
+		receipt, err := t.receipt(ctx)
+		if receipt == nil || err != nil || t.tx.To() != nil {
+			return nil, err
+		}
+		return newAccount(t.backend, receipt.ContractAddress, args, t.stateNumber())
+	}
+	// The remaining fields take no arguments
+	if err := args.check(); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "hash":
+		return t.tx.Hash(), nil
+	case "nonce":
+		return t.tx.Nonce(), nil
+	case "index":
+		if t.block == nil {
+			return nil, nil
+		}
+		return t.index, nil
+	case "value":
+		return bigInt(t.tx.Value()), nil
+	case "gasPrice":
+		return bigInt(t.tx.GasPrice()), nil
+	case "gas":
+		return t.tx.Gas().Uint64(), nil
+	case "inputData":
+		return hexutil.Bytes(t.tx.Data()), nil
+	case "block":
+		return newBlock(t.backend, t.block), nil
+
+	case "root", "gasUsed", "cumulativeGasUsed", "logs":
+		receipt, err := t.receipt(ctx)
+		if receipt == nil || err != nil {
+			return nil, err
+		}
+		switch name {
+		case "root":
+			return common.BytesToHash(receipt.PostState), nil
+		case "gasUsed":
+			return receipt.GasUsed.Uint64(), nil
+		case "cumulativeGasUsed":
+			return receipt.CumulativeGasUsed.Uint64(), nil
+		default:
+			logs := []object{}
+			for _, log := range receipt.Logs {
+				logs = append(logs, &logObject{t.backend, deriveLog(log, t.block.Header(), t.tx.Hash(), t.index)})
+			}
+			return logs, nil
+		}
+
+	case "isSignup":
+		from, err := t.sender()
+		if err != nil {
+			return nil, err
+		}
+		return core.IsSignupTx(t.backend.ChainConfig(), from, t.tx.Value(), t.tx.Data()), nil
+
+	case "signupChain":
+		from, err := t.sender()
+		if err != nil {
+			return nil, err
+		}
+		if !core.IsSignupTx(t.backend.ChainConfig(), from, t.tx.Value(), t.tx.Data()) {
+			return nil, nil
+		}
+		return signupChain(ctx, t.backend, t.tx.Data())
+	}
+	return nil, errUnknownField
+}
+
+// signupChain resolves the referring members of a signup transaction through
+// the backend, mirroring the reward distribution of core.SignupChain.
+func signupChain(ctx context.Context, backend ethapi.Backend, data []byte) ([]common.Address, error) {
+	chain := []common.Address{}
+	for len(chain) < core.SignupChainDepth {
+		number, hash, err := core.ParseSignupData(data)
+		if err == core.ErrNoMoreMembers {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		nr, err := blockNumber(number)
+		if err != nil {
+			return nil, errInvalidSignup
+		}
+		block, err := backend.BlockByNumber(ctx, nr)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			return nil, errInvalidSignup
+		}
+		tx := block.Transaction(hash)
+		if tx == nil || tx.To() == nil || tx.Value().Cmp(big.NewInt(1)) != 0 {
+			return nil, errInvalidSignup
+		}
+		chain = append(chain, *tx.To())
+		data = tx.Data()
+	}
+	return chain, nil
+}
+
+// account is the state of an account at a given block.
+type account struct {
+	backend ethapi.Backend
+	address common.Address
+	number  rpc.BlockNumber
+}
+
+// newAccount creates an account at the block given in the optional "block"
+// argument, or at the given default block.
+func newAccount(backend ethapi.Backend, address common.Address, args arguments, number rpc.BlockNumber) (interface{}, error) {
+	if arg, err := args.long("block"); err != nil {
+		return nil, err
+	} else if arg != nil {
+		if number, err = blockNumber(*arg); err != nil {
+			return nil, err
+		}
+	}
+	return &account{backend, address, number}, nil
+}
+
+func (a *account) typeName() string { return "Account" }
+
+func (a *account) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	if name == "address" {
+		return a.address, args.check()
+	}
+	if name != "balance" && name != "transactionCount" && name != "code" && name != "storage" {
+		return nil, errUnknownField
+	}
+	var slot *common.Hash
+	if name == "storage" {
+		if err := args.check("slot!"); err != nil {
+			return nil, err
+		}
+		var err error
+		if slot, err = args.hash("slot"); err != nil {
+			return nil, err
+		}
+	} else if err := args.check(); err != nil {
+		return nil, err
+	}
+	state, _, err := a.backend.StateAndHeaderByNumber(ctx, a.number)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, errStateUnavailable
+	}
+	switch name {
+	case "balance":
+		balance, err := state.GetBalance(ctx, a.address)
+		return bigInt(balance), err
+	case "transactionCount":
+		return state.GetNonce(ctx, a.address)
+	case "code":
+		code, err := state.GetCode(ctx, a.address)
+		return hexutil.Bytes(code), err
+	default:
+		return state.GetState(ctx, a.address, *slot)
+	}
+}
+
+// pending is the pending state of the node.
+type pending struct {
+	backend ethapi.Backend
+}
+
+func (p *pending) typeName() string { return "Pending" }
+
+func (p *pending) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	switch name {
+	case "account":
+		if err := args.check("address!"); err != nil {
+			return nil, err
+		}
+		addr, err := args.address("address")
+		if err != nil {
+			return nil, err
+		}
+		return &account{p.backend, *addr, rpc.PendingBlockNumber}, nil
+	}
+	if err := args.check(); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "transactionCount":
+		return len(p.backend.GetPoolTransactions()), nil
+	case "transactions":
+		txs := []object{}
+		for _, tx := range p.backend.GetPoolTransactions() {
+			txs = append(txs, &transaction{backend: p.backend, tx: tx, index: -1})
+		}
+		return txs, nil
+	}
+	return nil, errUnknownField
+}
+
+// logObject is a log emitted by a mined transaction.
+type logObject struct {
+	backend ethapi.Backend
+	log     *vm.Log
+}
+
+func (l *logObject) typeName() string { return "Log" }
+
+func (l *logObject) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	if name == "account" {
+		if err := args.check("block"); err != nil {
+			return nil, err
+		}
+		return newAccount(l.backend, l.log.Address, args, rpc.BlockNumber(l.log.BlockNumber))
+	}
+	if err := args.check(); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "index":
+		return l.log.Index, nil
+	case "topics":
+		topics := make([]common.Hash, len(l.log.Topics))
+		copy(topics, l.log.Topics)
+		return topics, nil
+	case "data":
+		return hexutil.Bytes(l.log.Data), nil
+	case "transaction":
+		block, err := l.backend.GetBlock(ctx, l.log.BlockHash)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil || int(l.log.TxIndex) >= len(block.Transactions()) {
+			return nil, fmt.Errorf("transaction %x not found", l.log.TxHash)
+		}
+		return &transaction{backend: l.backend, tx: block.Transactions()[l.log.TxIndex], block: block, index: int(l.log.TxIndex)}, nil
+	}
+	return nil, errUnknownField
+}
+
+// deriveLog copies a receipt log, filling in the fields derived from the block
+// and transaction it's contained in.
+func deriveLog(log *vm.Log, header *types.Header, txHash common.Hash, txIndex int) *vm.Log {
+	derived := *log
+	derived.BlockNumber = header.Number.Uint64()
+	derived.BlockHash = header.Hash()
+	derived.TxHash = txHash
+	derived.TxIndex = uint(txIndex)
+	return &derived
+}
+
+// logFilter matches logs by their emitting address and topics.
+type logFilter struct {
+	addresses []common.Address
+	topics    [][]common.Hash
+}
+
+// newLogFilter creates a log filter from the addresses and topics criteria.
+func newLogFilter(criteria map[string]interface{}) (*logFilter, error) {
+	addresses, err := toAddresses("addresses", criteria["addresses"])
+	if err != nil {
+		return nil, err
+	}
+	topics, err := toTopics("topics", criteria["topics"])
+	if err != nil {
+		return nil, err
+	}
+	return &logFilter{addresses: addresses, topics: topics}, nil
+}
+
+// bloomMatch reports whether a block may contain matching logs.
+func (f *logFilter) bloomMatch(bloom types.Bloom) bool {
+	if len(f.addresses) > 0 {
+		found := false
+		for _, addr := range f.addresses {
+			if types.BloomLookup(bloom, addr) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, alternatives := range f.topics {
+		found := len(alternatives) == 0
+		for _, topic := range alternatives {
+			if types.BloomLookup(bloom, topic) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// match reports whether a log matches the filter.
+func (f *logFilter) match(log *vm.Log) bool {
+	if len(f.addresses) > 0 {
+		found := false
+		for _, addr := range f.addresses {
+			if addr == log.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.topics) > len(log.Topics) {
+		return false
+	}
+	for i, alternatives := range f.topics {
+		found := len(alternatives) == 0
+		for _, topic := range alternatives {
+			if topic == log.Topics[i] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// blockLogs retrieves the logs of a block matching the filter.
+func blockLogs(ctx context.Context, backend ethapi.Backend, header *types.Header, filter *logFilter) ([]object, error) {
+	if !filter.bloomMatch(header.Bloom) {
+		return nil, nil
+	}
+	receipts, err := backend.GetReceipts(ctx, header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	var logs []object
+	for i, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if filter.match(log) {
+				logs = append(logs, &logObject{backend, deriveLog(log, header, receipt.TxHash, i)})
+			}
+		}
+	}
+	return logs, nil
+}
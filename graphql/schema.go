@@ -0,0 +1,227 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+// Schema is the GraphQL schema served by the service, in the schema definition
+// language. It documents the query API; the resolvers implement it directly.
+const Schema = `
+# Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
+scalar Bytes32
+# Address is a 20 byte UR address, represented as 0x-prefixed hexadecimal.
+scalar Address
+# Bytes is an arbitrary length binary string, represented as 0x-prefixed
+# hexadecimal. An empty byte string is represented as '0x'.
+scalar Bytes
+# BigInt is a large integer, represented as 0x-prefixed hexadecimal.
+scalar BigInt
+# Long is a 64 bit unsigned integer. Inputs may also be given as decimal or
+# 0x-prefixed hexadecimal strings.
+scalar Long
+
+schema {
+    query: Query
+}
+
+# Account is a UR account at a particular block.
+type Account {
+    # Address is the address owning the account.
+    address: Address!
+    # Balance is the balance of the account, in wei.
+    balance: BigInt!
+    # TransactionCount is the number of transactions sent from this account,
+    # or in the case of a contract, the number of contracts created.
+    transactionCount: Long!
+    # Code contains the smart contract code of this account, if any.
+    code: Bytes!
+    # Storage provides access to the storage of a contract account, indexed
+    # by its 32 byte slot identifier.
+    storage(slot: Bytes32!): Bytes32!
+}
+
+# Log is a UR event log.
+type Log {
+    # Index is the index of this log in the block.
+    index: Int!
+    # Account is the account which generated this log, by default at the
+    # block containing it.
+    account(block: Long): Account!
+    # Topics is the list of 0-4 indexed topics of the log.
+    topics: [Bytes32!]!
+    # Data is the unindexed data of the log.
+    data: Bytes!
+    # Transaction is the transaction that generated this log.
+    transaction: Transaction!
+}
+
+# Transaction is a UR transaction, including its receipt once mined.
+type Transaction {
+    # Hash is the hash of this transaction.
+    hash: Bytes32!
+    # Nonce is the nonce of the account this transaction was sent from.
+    nonce: Long!
+    # Index is the index of this transaction in the block, null if pending.
+    index: Int
+    # From is the account that sent this transaction, by default at the block
+    # containing it (or the pending state).
+    from(block: Long): Account!
+    # To is the recipient of this transaction, null for contract creations.
+    to(block: Long): Account
+    # Value is the value, in wei, sent along with this transaction.
+    value: BigInt!
+    # GasPrice is the price offered to miners for gas, in wei per unit.
+    gasPrice: BigInt!
+    # Gas is the maximum amount of gas this transaction can consume.
+    gas: Long!
+    # InputData is the data supplied to the target of the transaction.
+    inputData: Bytes!
+    # Block is the block this transaction was mined in, null if pending.
+    block: Block
+    # Root is the post-transaction state root, null if pending.
+    root: Bytes32
+    # GasUsed is the amount of gas used by this transaction, null if pending.
+    gasUsed: Long
+    # CumulativeGasUsed is the total gas used in the block up to and including
+    # this transaction, null if pending.
+    cumulativeGasUsed: Long
+    # CreatedContract is the account created by this contract creation, null
+    # if pending or not a contract creation.
+    createdContract(block: Long): Account
+    # Logs is the list of logs emitted by this transaction, null if pending.
+    logs: [Log!]
+    # IsSignup is whether this transaction signs up a new UR member.
+    isSignup: Boolean!
+    # SignupChain is the referral chain of a signup transaction: the addresses
+    # of the referring members (closest first, at most 7), null if this isn't
+    # a signup transaction.
+    signupChain: [Address!]
+}
+
+# BlockFilterCriteria encapsulates log filter criteria for a single block.
+input BlockFilterCriteria {
+    # Addresses is a list of addresses whose logs to match, all if empty.
+    addresses: [Address!]
+    # Topics are the topics to match at each position, a null or empty list
+    # matching any topic. A log matches if any of the topics of each position
+    # match.
+    topics: [[Bytes32!]]
+}
+
+# Block is a UR block.
+type Block {
+    # Number is the number of this block, starting at 0 for the genesis block.
+    number: Long!
+    # Hash is the block hash of this block.
+    hash: Bytes32!
+    # Parent is the parent block of this block.
+    parent: Block
+    # Nonce is the block nonce, an 8 byte sequence determined by the miner.
+    nonce: Bytes!
+    # TransactionsRoot is the root of the trie of transactions in this block.
+    transactionsRoot: Bytes32!
+    # TransactionCount is the number of transactions in this block.
+    transactionCount: Int!
+    # StateRoot is the root of the state trie after this block.
+    stateRoot: Bytes32!
+    # ReceiptsRoot is the root of the trie of transaction receipts in this block.
+    receiptsRoot: Bytes32!
+    # Miner is the account that mined this block, by default at this block.
+    miner(block: Long): Account!
+    # ExtraData is an arbitrary data field supplied by the miner.
+    extraData: Bytes!
+    # GasLimit is the maximum amount of gas that was available to transactions.
+    gasLimit: Long!
+    # GasUsed is the amount of gas that was used executing the transactions.
+    gasUsed: Long!
+    # Timestamp is the unix timestamp at which this block was mined.
+    timestamp: BigInt!
+    # LogsBloom is a bloom filter of the log addresses and topics of this block.
+    logsBloom: Bytes!
+    # MixHash is the hash that was used as an input to the PoW process.
+    mixHash: Bytes32!
+    # Difficulty is a measure of the difficulty of mining this block.
+    difficulty: BigInt!
+    # TotalDifficulty is the sum of the difficulties of all blocks up to and
+    # including this one.
+    totalDifficulty: BigInt!
+    # OmmerCount is the number of ommers (uncles) of this block.
+    ommerCount: Int!
+    # Ommers is the list of ommer blocks (headers only) of this block.
+    ommers: [Block]
+    # OmmerAt returns the ommer at the given index, null if out of range.
+    ommerAt(index: Int!): Block
+    # OmmerHash is the hash of the ommers of this block.
+    ommerHash: Bytes32!
+    # Transactions is the list of transactions in this block.
+    transactions: [Transaction!]
+    # TransactionAt returns the transaction at the given index, null if out of
+    # range.
+    transactionAt(index: Int!): Transaction
+    # Logs returns the logs of this block matching the filter.
+    logs(filter: BlockFilterCriteria!): [Log!]!
+    # Account returns the state of an account at this block.
+    account(address: Address!): Account!
+    # NSignups is the number of UR members signed up up to this block.
+    nSignups: BigInt!
+    # TotalWei is the total amount of UR, in wei, issued up to this block.
+    totalWei: BigInt!
+}
+
+# FilterCriteria encapsulates log filter criteria for a range of blocks.
+input FilterCriteria {
+    # FromBlock is the first block to include, the latest block if null.
+    fromBlock: Long
+    # ToBlock is the last block to include, the latest block if null.
+    toBlock: Long
+    # Addresses is a list of addresses whose logs to match, all if empty.
+    addresses: [Address!]
+    # Topics are the topics to match at each position, see BlockFilterCriteria.
+    topics: [[Bytes32!]]
+}
+
+# Pending represents the current pending state.
+type Pending {
+    # TransactionCount is the number of transactions in the pending state.
+    transactionCount: Int!
+    # Transactions is the list of transactions in the pending state.
+    transactions: [Transaction!]
+    # Account returns the pending state of an account.
+    account(address: Address!): Account!
+}
+
+type Query {
+    # Block retrieves a block by number or by hash, the latest block if
+    # neither is given.
+    block(number: Long, hash: Bytes32): Block
+    # Blocks returns the blocks in a range of at most 1024 blocks, up to the
+    # latest one if no end is given.
+    blocks(from: Long!, to: Long): [Block!]!
+    # Pending returns the current pending state.
+    pending: Pending!
+    # Transaction returns a mined or pending transaction by hash.
+    transaction(hash: Bytes32!): Transaction
+    # Logs returns the logs matching the filter, in a range of at most 1024
+    # blocks.
+    logs(filter: FilterCriteria!): [Log!]!
+    # GasPrice returns the node's estimate of a gas price sufficient to ensure
+    # a transaction is mined in a timely fashion.
+    gasPrice: BigInt!
+    # ProtocolVersion returns the current UR wire protocol version.
+    protocolVersion: Int!
+    # ChainID returns the chain id used for replay protected transactions.
+    chainID: BigInt!
+}
+`
@@ -0,0 +1,152 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql provides a GraphQL interface to the chain, account and UR
+// signup data of a full or light node.
+package graphql
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ur-technology/go-ur/internal/ethapi"
+	"github.com/ur-technology/go-ur/p2p"
+	"github.com/ur-technology/go-ur/rpc"
+	"golang.org/x/net/context"
+)
+
+const (
+	maxQuerySize = 128 * 1024       // Maximum size of a GraphQL request body
+	queryTimeout = 30 * time.Second // Maximum time a single query may run
+)
+
+// request is a GraphQL query posted over HTTP.
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Service is a node service serving GraphQL queries over HTTP next to the RPC
+// endpoint.
+type Service struct {
+	backend ethapi.Backend
+}
+
+// New creates a GraphQL service resolving queries through the given backend.
+func New(backend ethapi.Backend) *Service {
+	return &Service{backend: backend}
+}
+
+// Protocols implements node.Service, returning no p2p protocols.
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning no RPC APIs.
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start implements node.Service, the GraphQL endpoint being started by the node
+// along with the HTTP RPC server.
+func (s *Service) Start(server *p2p.Server) error { return nil }
+
+// Stop implements node.Service.
+func (s *Service) Stop() error { return nil }
+
+// HTTPHandlers implements node.HTTPService, serving queries at /graphql and the
+// schema at /graphql/schema.
+func (s *Service) HTTPHandlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		"/graphql":        s,
+		"/graphql/schema": http.HandlerFunc(serveSchema),
+	}
+}
+
+// HTTPMethod implements node.HTTPService, the queries being authorized and rate
+// limited as calls of eth_graphql.
+func (s *Service) HTTPMethod() string { return "eth_graphql" }
+
+// ServeHTTP serves a GraphQL query, given either as a JSON request body or as
+// the query string parameters of a GET request.
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case "GET":
+		params := r.URL.Query()
+		req.Query = params.Get("query")
+		req.OperationName = params.Get("operationName")
+		if vars := params.Get("variables"); vars != "" {
+			dec := json.NewDecoder(strings.NewReader(vars))
+			dec.UseNumber()
+			if err := dec.Decode(&req.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid variables: "+err.Error())
+				return
+			}
+		}
+	case "POST":
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxQuerySize+1))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(body) > maxQuerySize {
+			writeError(w, http.StatusRequestEntityTooLarge, "query too large")
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("content-type"), "application/graphql") {
+			req.Query = string(body)
+			break
+		}
+		dec := json.NewDecoder(strings.NewReader(string(body)))
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	res := execute(ctx, &query{s.backend}, req.Query, req.OperationName, req.Variables)
+	status := http.StatusOK
+	if res.Data == nil && len(res.Errors) > 0 {
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, res)
+}
+
+// serveSchema serves the schema definition of the endpoint.
+func serveSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "text/plain; charset=utf-8")
+	io.WriteString(w, Schema)
+}
+
+// writeError writes a GraphQL response consisting of a single error.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, &response{Errors: []*queryError{{Message: msg}}})
+}
+
+// writeJSON writes a JSON encoded response.
+func writeJSON(w http.ResponseWriter, status int, res *response) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
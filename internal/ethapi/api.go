@@ -39,6 +39,7 @@ import (
 	"github.com/ur-technology/go-ur/p2p"
 	"github.com/ur-technology/go-ur/rlp"
 	"github.com/ur-technology/go-ur/rpc"
+	"github.com/ur-technology/go-ur/signer"
 	"github.com/ur-technology/urhash"
 	"golang.org/x/net/context"
 )
@@ -61,6 +62,40 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*big.Int, error) {
 	return s.b.SuggestPrice(ctx)
 }
 
+// FeeHistoryResult is the fee history of a range of blocks.
+type FeeHistoryResult struct {
+	OldestBlock  *rpc.HexNumber     `json:"oldestBlock"`
+	Reward       [][]*rpc.HexNumber `json:"reward,omitempty"`
+	GasUsedRatio []float64          `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the gas used ratios of up to blockCount blocks ending with
+// lastBlock, along with the given percentiles of the gas prices paid in every
+// block, weighted by gas used. Privileged signup transactions are left out.
+func (s *PublicEthereumAPI) FeeHistory(ctx context.Context, blockCount rpc.HexNumber, lastBlock rpc.BlockNumber, percentiles []float64) (*FeeHistoryResult, error) {
+	oldest, rewards, ratios, err := s.b.FeeHistory(ctx, blockCount.Int(), lastBlock, percentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &FeeHistoryResult{
+		OldestBlock:  rpc.NewHexNumber(oldest),
+		GasUsedRatio: ratios,
+	}
+	if rewards != nil {
+		result.Reward = make([][]*rpc.HexNumber, len(rewards))
+		for i, block := range rewards {
+			result.Reward[i] = make([]*rpc.HexNumber, len(block))
+			for j, reward := range block {
+				result.Reward[i][j] = rpc.NewHexNumber(reward)
+			}
+		}
+	}
+	if result.GasUsedRatio == nil {
+		result.GasUsedRatio = []float64{}
+	}
+	return result, nil
+}
+
 // ProtocolVersion returns the current Ethereum protocol version this node supports
 func (s *PublicEthereumAPI) ProtocolVersion() *rpc.HexNumber {
 	return rpc.NewHexNumber(s.b.ProtocolVersion())
@@ -213,9 +248,119 @@ func (s *PrivateAccountAPI) ListAccounts() []common.Address {
 	return addresses
 }
 
+// RawWallet is a JSON representation of an accounts.Wallet interface, with its
+// data contents extracted into plain fields.
+type RawWallet struct {
+	URL      string             `json:"url"`
+	Status   string             `json:"status"`
+	Accounts []accounts.Account `json:"accounts"`
+}
+
+// ListWallets will return a list of wallets this node manages.
+func (s *PrivateAccountAPI) ListWallets() []RawWallet {
+	wallets := make([]RawWallet, 0) // return [] instead of nil if empty
+	for _, wallet := range s.am.Wallets() {
+		wallets = append(wallets, RawWallet{
+			URL:      wallet.URL(),
+			Status:   wallet.Status(),
+			Accounts: wallet.Accounts(),
+		})
+	}
+	return wallets
+}
+
+// OpenWallet initiates a hardware wallet opening procedure, establishing a USB
+// connection and attempting to authenticate via the provided passphrase. Note,
+// the method may return an extra challenge requiring a second open (e.g. the
+// Trezor PIN matrix challenge).
+func (s *PrivateAccountAPI) OpenWallet(url string, passphrase *string) error {
+	wallet, err := s.am.Wallet(url)
+	if err != nil {
+		return err
+	}
+	pass := ""
+	if passphrase != nil {
+		pass = *passphrase
+	}
+	return wallet.Open(pass)
+}
+
+// DeriveAccount requests a HD wallet to derive a new account, optionally pinning
+// it for later reuse.
+func (s *PrivateAccountAPI) DeriveAccount(url string, path string, pin *bool) (accounts.Account, error) {
+	wallet, err := s.am.Wallet(url)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	derivPath, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	if pin == nil {
+		pin = new(bool)
+	}
+	return wallet.Derive(derivPath, *pin)
+}
+
+// NewHDWalletResult is the reply of personal_newHDWallet, carrying the freshly
+// generated mnemonic which must be backed up by the user.
+type NewHDWalletResult struct {
+	URL      string `json:"url"`
+	Mnemonic string `json:"mnemonic"`
+}
+
+// NewHDWallet generates a new mnemonic backed HD wallet, encrypting its seed
+// with the given passphrase.
+func (s *PrivateAccountAPI) NewHDWallet(passphrase string) (*NewHDWalletResult, error) {
+	hs, err := s.am.HDStore()
+	if err != nil {
+		return nil, err
+	}
+	mnemonic, wallet, err := hs.NewWallet(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &NewHDWalletResult{URL: wallet.URL(), Mnemonic: mnemonic}, nil
+}
+
+// ImportMnemonic restores a HD wallet from an existing BIP-39 mnemonic,
+// encrypting its seed with the given passphrase.
+func (s *PrivateAccountAPI) ImportMnemonic(mnemonic string, passphrase string) (string, error) {
+	hs, err := s.am.HDStore()
+	if err != nil {
+		return "", err
+	}
+	wallet, err := hs.ImportMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return wallet.URL(), nil
+}
+
+// SelfDerive discovers the used accounts of an opened HD wallet by deriving
+// sequential accounts from the base path (the UR default if omitted) until an
+// account without nonce and balance is found.
+func (s *PrivateAccountAPI) SelfDerive(url string, base *string) ([]accounts.Account, error) {
+	wallet, err := s.am.Wallet(url)
+	if err != nil {
+		return nil, err
+	}
+	path := accounts.DefaultURBaseDerivationPath
+	if base != nil {
+		if path, err = accounts.ParseDerivationPath(*base); err != nil {
+			return nil, err
+		}
+	}
+	return accounts.SelfDerive(wallet, path, &chainStateReader{s.b})
+}
+
 // NewAccount will create a new account and returns the address for the new account.
 func (s *PrivateAccountAPI) NewAccount(password string) (common.Address, error) {
-	acc, err := s.am.NewAccount(password)
+	ks, err := s.am.KeyStore()
+	if err != nil {
+		return common.Address{}, err
+	}
+	acc, err := ks.NewAccount(password)
 	if err == nil {
 		return acc.Address, nil
 	}
@@ -230,10 +375,37 @@ func (s *PrivateAccountAPI) ImportRawKey(privkey string, password string) (commo
 		return common.Address{}, err
 	}
 
-	acc, err := s.am.ImportECDSA(crypto.ToECDSA(hexkey), password)
+	ks, err := s.am.KeyStore()
+	if err != nil {
+		return common.Address{}, err
+	}
+	acc, err := ks.ImportECDSA(crypto.ToECDSA(hexkey), password)
 	return acc.Address, err
 }
 
+// Reencrypt re-encrypts every key of the keystore with the given key derivation
+// function (scrypt or argon2id), at the cost the keystore encrypts new keys with.
+// All the keys must decrypt with password; they are encrypted with newPassword
+// or, if it is omitted, with the same password. The path of the backup of the
+// original key files is returned, empty if the keystore holds no keys.
+func (s *PrivateAccountAPI) Reencrypt(kdf string, password string, newPassword *string) (string, error) {
+	ks, err := s.am.KeyStore()
+	if err != nil {
+		return "", err
+	}
+	config, err := ks.ReencryptKDF(kdf)
+	if err != nil {
+		return "", err
+	}
+	next := password
+	if newPassword != nil {
+		next = *newPassword
+	}
+	return ks.Reencrypt(config, func(accounts.Account) (string, string, error) {
+		return password, next, nil
+	})
+}
+
 // UnlockAccount will unlock the account associated with the given address with
 // the given password for duration seconds. If duration is nil it will use a
 // default of 300 seconds. It returns an indication if the account was unlocked.
@@ -241,9 +413,13 @@ func (s *PrivateAccountAPI) UnlockAccount(addr common.Address, password string,
 	if duration == nil {
 		duration = rpc.NewHexNumber(300)
 	}
+	ks, err := s.am.KeyStore()
+	if err != nil {
+		return false, err
+	}
 	a := accounts.Account{Address: addr}
 	d := time.Duration(duration.Int64()) * time.Second
-	if err := s.am.TimedUnlock(a, password, d); err != nil {
+	if err := ks.TimedUnlock(a, password, d); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -251,7 +427,11 @@ func (s *PrivateAccountAPI) UnlockAccount(addr common.Address, password string,
 
 // LockAccount will lock the account associated with the given address when it's unlocked.
 func (s *PrivateAccountAPI) LockAccount(addr common.Address) bool {
-	return s.am.Lock(addr) == nil
+	ks, err := s.am.KeyStore()
+	if err != nil {
+		return false
+	}
+	return ks.Lock(addr) == nil
 }
 
 // SendTransaction will create a transaction from the given arguments and
@@ -279,13 +459,18 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs
 		tx = types.NewTransaction(args.Nonce.Uint64(), *args.To, args.Value.BigInt(), args.Gas.BigInt(), args.GasPrice.BigInt(), common.FromHex(args.Data))
 	}
 
-	signer := types.MakeSigner(s.b.ChainConfig(), s.b.CurrentBlock().Number())
-	signature, err := s.am.SignWithPassphrase(args.From, passwd, signer.Hash(tx).Bytes())
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: args.From}
+
+	wallet, err := s.am.Find(account)
 	if err != nil {
 		return common.Hash{}, err
 	}
-
-	return submitTransaction(ctx, s.b, tx, signature)
+	signed, err := wallet.SignTxWithPassphrase(account, passwd, tx, signerChainID(s.b))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return submitTransaction(ctx, s.b, signed)
 }
 
 // signHash is a helper function that calculates a hash for the given message that can be
@@ -307,8 +492,14 @@ func signHash(message string) []byte {
 //
 // https://github.com/ur-technology/go-ur/wiki/Management-APIs#personal_sign
 func (s *PrivateAccountAPI) Sign(ctx context.Context, message string, addr common.Address, passwd string) (string, error) {
-	hash := signHash(message)
-	signature, err := s.b.AccountManager().SignWithPassphrase(addr, passwd, hash)
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return "0x", err
+	}
+	signature, err := wallet.SignHashWithPassphrase(account, passwd, signHash(message))
 	if err != nil {
 		return "0x", err
 	}
@@ -323,10 +514,44 @@ func (s *PrivateAccountAPI) Sign(ctx context.Context, message string, addr commo
 //
 // https://github.com/ur-technology/go-ur/wiki/Management-APIs#personal_ecRecover
 func (s *PrivateAccountAPI) EcRecover(ctx context.Context, message string, signature string) (common.Address, error) {
-	var (
-		hash = signHash(message)
-		sig  = common.FromHex(signature)
-	)
+	return ecRecover(signHash(message), signature)
+}
+
+// SignTypedData calculates an Ethereum ECDSA signature over EIP-712 typed data:
+// keccak256("\x19\x01" + domainSeparator + hashStruct(message))
+//
+// The key used to calculate the signature is decrypted with the given password.
+func (s *PrivateAccountAPI) SignTypedData(ctx context.Context, data signer.TypedData, addr common.Address, passwd string) (string, error) {
+	hash, err := data.Hash()
+	if err != nil {
+		return "0x", err
+	}
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return "0x", err
+	}
+	signature, err := wallet.SignHashWithPassphrase(account, passwd, hash)
+	if err != nil {
+		return "0x", err
+	}
+	return common.ToHex(signature), nil
+}
+
+// EcRecoverTypedData returns the address for the account that was used to sign
+// the typed data with eth_signTypedData or personal_signTypedData.
+func (s *PrivateAccountAPI) EcRecoverTypedData(ctx context.Context, data signer.TypedData, signature string) (common.Address, error) {
+	hash, err := data.Hash()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return ecRecover(hash, signature)
+}
+
+// ecRecover returns the address of the account whose key signed hash.
+func ecRecover(hash []byte, signature string) (common.Address, error) {
+	sig := common.FromHex(signature)
 
 	if len(sig) != 65 {
 		return common.Address{}, fmt.Errorf("signature must be 65 bytes long")
@@ -990,13 +1215,75 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(txHash common.Hash) (ma
 
 // sign is a helper function that signs a transaction with the private key of the given address.
 func (s *PublicTransactionPoolAPI) sign(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
-	signer := types.MakeSigner(s.b.ChainConfig(), s.b.CurrentBlock().Number())
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
 
-	signature, err := s.b.AccountManager().SignEthereum(addr, signer.Hash(tx).Bytes())
+	wallet, err := s.b.AccountManager().Find(account)
 	if err != nil {
 		return nil, err
 	}
-	return tx.WithSignature(signer, signature)
+	return wallet.SignTx(account, tx, signerChainID(s.b))
+}
+
+// signerChainID returns the chain id transactions need to be signed with at the
+// current head, or nil if replay protection is not active yet.
+func signerChainID(b Backend) *big.Int {
+	config := b.ChainConfig()
+	if config.IsEIP155(b.CurrentBlock().Number()) {
+		return config.ChainId
+	}
+	return nil
+}
+
+// chainStateReader exposes the backend's state as an ethereum.ChainStateReader.
+type chainStateReader struct {
+	b Backend
+}
+
+// state retrieves the state at the given block, the latest if number is nil.
+func (r *chainStateReader) state(ctx context.Context, number *big.Int) (State, error) {
+	blockNr := rpc.LatestBlockNumber
+	if number != nil {
+		blockNr = rpc.BlockNumber(number.Int64())
+	}
+	state, _, err := r.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil && err == nil {
+		err = fmt.Errorf("state of block %v not available", number)
+	}
+	return state, err
+}
+
+func (r *chainStateReader) BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error) {
+	state, err := r.state(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return state.GetBalance(ctx, account)
+}
+
+func (r *chainStateReader) StorageAt(ctx context.Context, account common.Address, key common.Hash, number *big.Int) ([]byte, error) {
+	state, err := r.state(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	value, err := state.GetState(ctx, account, key)
+	return value[:], err
+}
+
+func (r *chainStateReader) CodeAt(ctx context.Context, account common.Address, number *big.Int) ([]byte, error) {
+	state, err := r.state(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return state.GetCode(ctx, account)
+}
+
+func (r *chainStateReader) NonceAt(ctx context.Context, account common.Address, number *big.Int) (uint64, error) {
+	state, err := r.state(ctx, number)
+	if err != nil {
+		return 0, err
+	}
+	return state.GetNonce(ctx, account)
 }
 
 // SendTxArgs represents the arguments to sumbit a new transaction into the transaction pool.
@@ -1028,15 +1315,10 @@ func prepareSendTxArgs(ctx context.Context, args SendTxArgs, b Backend) (SendTxA
 	return args, nil
 }
 
-// submitTransaction is a helper function that submits tx to txPool and creates a log entry.
-func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction, signature []byte) (common.Hash, error) {
+// submitTransaction is a helper function that submits a signed tx to txPool and creates a log entry.
+func submitTransaction(ctx context.Context, b Backend, signedTx *types.Transaction) (common.Hash, error) {
 	signer := types.MakeSigner(b.ChainConfig(), b.CurrentBlock().Number())
 
-	signedTx, err := tx.WithSignature(signer, signature)
-	if err != nil {
-		return common.Hash{}, err
-	}
-
 	if err := b.SendTx(ctx, signedTx); err != nil {
 		return common.Hash{}, err
 	}
@@ -1046,7 +1328,7 @@ func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction, si
 		addr := crypto.CreateAddress(from, signedTx.Nonce())
 		glog.V(logger.Info).Infof("Tx(%s) created: %s\n", signedTx.Hash().Hex(), addr.Hex())
 	} else {
-		glog.V(logger.Info).Infof("Tx(%s) to: %s\n", signedTx.Hash().Hex(), tx.To().Hex())
+		glog.V(logger.Info).Infof("Tx(%s) to: %s\n", signedTx.Hash().Hex(), signedTx.To().Hex())
 	}
 
 	return signedTx.Hash(), nil
@@ -1076,13 +1358,11 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 		tx = types.NewTransaction(args.Nonce.Uint64(), *args.To, args.Value.BigInt(), args.Gas.BigInt(), args.GasPrice.BigInt(), common.FromHex(args.Data))
 	}
 
-	signer := types.MakeSigner(s.b.ChainConfig(), s.b.CurrentBlock().Number())
-	signature, err := s.b.AccountManager().SignEthereum(args.From, signer.Hash(tx).Bytes())
+	signed, err := s.sign(args.From, tx)
 	if err != nil {
 		return common.Hash{}, err
 	}
-
-	return submitTransaction(ctx, s.b, tx, signature)
+	return submitTransaction(ctx, s.b, signed)
 }
 
 // SendRawTransaction will add the signed transaction to the transaction pool.
@@ -1119,8 +1399,33 @@ func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encod
 //
 // https://github.com/ur-technology/wiki/wiki/JSON-RPC#eth_sign
 func (s *PublicTransactionPoolAPI) Sign(addr common.Address, message string) (string, error) {
-	hash := signHash(message)
-	signature, err := s.b.AccountManager().SignEthereum(addr, hash)
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return "0x", err
+	}
+	signature, err := wallet.SignHash(account, signHash(message))
+	return common.ToHex(signature), err
+}
+
+// SignTypedData calculates an ECDSA signature over EIP-712 typed data:
+// keccak256("\x19\x01" + domainSeparator + hashStruct(message)).
+//
+// The account associated with addr must be unlocked.
+func (s *PublicTransactionPoolAPI) SignTypedData(addr common.Address, data signer.TypedData) (string, error) {
+	hash, err := data.Hash()
+	if err != nil {
+		return "0x", err
+	}
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return "0x", err
+	}
+	signature, err := wallet.SignHash(account, hash)
 	return common.ToHex(signature), err
 }
 
@@ -24,6 +24,7 @@ import (
 	"github.com/ur-technology/go-ur/core"
 	"github.com/ur-technology/go-ur/core/types"
 	"github.com/ur-technology/go-ur/core/vm"
+	"github.com/ur-technology/go-ur/eth"
 	"github.com/ur-technology/go-ur/eth/downloader"
 	"github.com/ur-technology/go-ur/eth/gasprice"
 	"github.com/ur-technology/go-ur/ethdb"
@@ -37,7 +38,7 @@ import (
 
 type LesApiBackend struct {
 	eth *LightEthereum
-	gpo *gasprice.LightPriceOracle
+	gpo *gasprice.Oracle
 }
 
 func (b *LesApiBackend) ChainConfig() *params.ChainConfig {
@@ -84,6 +85,23 @@ func (b *LesApiBackend) GetReceipts(ctx context.Context, blockHash common.Hash)
 	return light.GetBlockReceipts(ctx, b.eth.odr, blockHash, core.GetBlockNumber(b.eth.chainDb, blockHash))
 }
 
+// BloomStatus implements filters.BloomBackend. The sections are expected to be
+// indexed by the servers a while after they got confirmed, so the recent ones
+// are left to be searched header by header.
+func (b *LesApiBackend) BloomStatus() (uint64, uint64) {
+	head := b.eth.blockchain.CurrentHeader().Number.Uint64()
+	if head+1 < 2*eth.BloomConfirms {
+		return eth.BloomSectionSize, 0
+	}
+	return eth.BloomSectionSize, (head + 1 - 2*eth.BloomConfirms) / eth.BloomSectionSize
+}
+
+// GetBloomBits implements filters.BloomBackend, retrieving the bit vectors from
+// the servers.
+func (b *LesApiBackend) GetBloomBits(ctx context.Context, section uint64, bits []uint) ([][]byte, error) {
+	return light.GetBloomBits(ctx, b.eth.odr, eth.BloomSectionSize, section, bits)
+}
+
 func (b *LesApiBackend) GetTd(blockHash common.Hash) *big.Int {
 	return b.eth.blockchain.GetTdByHash(blockHash)
 }
@@ -140,6 +158,10 @@ func (b *LesApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *LesApiBackend) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, percentiles)
+}
+
 func (b *LesApiBackend) ChainDb() ethdb.Database {
 	return b.eth.chainDb
 }
@@ -116,9 +116,12 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.LightMode, config.NetworkId, eth.eventMux, eth.pow, eth.blockchain, nil, chainDb, odr, relay); err != nil {
 		return nil, err
 	}
+	if config.Checkpoint != nil {
+		eth.protocolManager.downloader.SetCheckpoint(config.Checkpoint)
+	}
 
 	eth.ApiBackend = &LesApiBackend{eth, nil}
-	eth.ApiBackend.gpo = gasprice.NewLightPriceOracle(eth.ApiBackend)
+	eth.ApiBackend.gpo = gasprice.NewOracle(eth.ApiBackend, config.GpoConfig())
 	return eth, nil
 }
 
@@ -56,6 +56,7 @@ const (
 	MaxCodeFetch         = 64  // Amount of contract codes to allow fetching per request
 	MaxProofsFetch       = 64  // Amount of merkle proofs to be fetched per retrieval request
 	MaxHeaderProofsFetch = 64  // Amount of merkle proofs to be fetched per retrieval request
+	MaxBloomBitsFetch    = 256 // Amount of bloom bit vectors to be fetched per retrieval request
 	MaxTxSend            = 64  // Amount of transactions to be send per request
 
 	disableClientRemovePeer = true
@@ -364,7 +365,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 			return p.RequestHeadersByNumber(reqID, cost, origin, amount, skip, reverse)
 		}
 		if err := pm.downloader.RegisterPeer(p.id, ethVersion, p.HeadAndTd,
-			requestHeadersByHash, requestHeadersByNumber, nil, nil, nil); err != nil {
+			requestHeadersByHash, requestHeadersByNumber, nil, nil, nil, p.Reputation()); err != nil {
 			return err
 		}
 		pm.odr.RegisterPeer(p)
@@ -398,7 +399,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	}
 }
 
-var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsMsg, SendTxMsg, GetHeaderProofsMsg}
+var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsMsg, SendTxMsg, GetHeaderProofsMsg, GetBloomBitsMsg}
 
 // handleMsg is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
@@ -866,6 +867,65 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			Obj:     resp.Data,
 		}
 
+	case GetBloomBitsMsg:
+		glog.V(logger.Debug).Infof("<=== GetBloomBitsMsg from peer %v", p.id)
+		// Decode the retrieval message
+		var req struct {
+			ReqID uint64
+			Reqs  []BloomReq
+		}
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for _, r := range req.Reqs {
+			reqCnt += len(r.Bits)
+		}
+		if reqCnt > maxReqs || reqCnt > MaxBloomBitsFetch {
+			return errResp(ErrRequestRejected, "")
+		}
+		// Gather the bit vectors of the indexed sections, all or none of every
+		// section
+		var vectors [][]byte
+		for _, r := range req.Reqs {
+			head := core.GetCanonicalHash(pm.chainDb, (r.Section+1)*eth.BloomSectionSize-1)
+			section := make([][]byte, 0, len(r.Bits))
+			for _, bit := range r.Bits {
+				bits := core.GetBloomBits(pm.chainDb, bit, r.Section, head)
+				if bits == nil {
+					section = nil
+					break
+				}
+				section = append(section, bits)
+			}
+			if section == nil {
+				break
+			}
+			vectors = append(vectors, section...)
+		}
+		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
+		return p.SendBloomBits(req.ReqID, bv, vectors)
+
+	case BloomBitsMsg:
+		if pm.odr == nil {
+			return errResp(ErrUnexpectedResponse, "")
+		}
+
+		glog.V(logger.Debug).Infof("<=== BloomBitsMsg from peer %v", p.id)
+		var resp struct {
+			ReqID, BV uint64
+			Data      [][]byte
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.fcServer.GotReply(resp.ReqID, resp.BV)
+		deliverMsg = &Msg{
+			MsgType: MsgBloomBits,
+			ReqID:   resp.ReqID,
+			Obj:     resp.Data,
+		}
+
 	case SendTxMsg:
 		if pm.txpool == nil {
 			return errResp(ErrUnexpectedResponse, "")
@@ -22,8 +22,10 @@ import (
 
 	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/core/bloombits"
 	"github.com/ur-technology/go-ur/core/types"
 	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/eth"
 	"github.com/ur-technology/go-ur/eth/downloader"
 	"github.com/ur-technology/go-ur/p2p"
 	"github.com/ur-technology/go-ur/rlp"
@@ -336,3 +338,39 @@ func testGetProofs(t *testing.T, protocol int) {
 		t.Errorf("proofs mismatch: %v", err)
 	}
 }
+
+// Tests that the bloom bits of the indexed sections can be retrieved.
+func TestGetBloomBitsLes2(t *testing.T) { testGetBloomBits(t, 2) }
+
+func testGetBloomBits(t *testing.T, protocol int) {
+	// Assemble the test environment
+	pm, db, _ := newTestProtocolManagerMust(t, false, 4, testChainGen)
+	peer, _ := newTestPeer(t, "peer", protocol, pm, true)
+	defer peer.close()
+
+	// Index a fake first section
+	head := common.BytesToHash([]byte("section head"))
+	core.WriteCanonicalHash(db, head, eth.BloomSectionSize-1)
+
+	bits := make([][]byte, bloombits.BloomBitLength)
+	for i := range bits {
+		bits[i] = make([]byte, eth.BloomSectionSize/8)
+		bits[i][0] = byte(i)
+	}
+	if err := core.WriteBloomSection(db, 0, head, bits); err != nil {
+		t.Fatal(err)
+	}
+	// Request bits of the indexed section, then of an unindexed one
+	reqs := []*BloomReq{{Section: 0, Bits: []uint{1, 5, 2047}}}
+	cost := peer.GetRequestCost(GetBloomBitsMsg, 3)
+	sendRequest(peer.app, GetBloomBitsMsg, 42, cost, reqs)
+	if err := expectResponse(peer.app, BloomBitsMsg, 42, testBufLimit, [][]byte{bits[1], bits[5], bits[2047]}); err != nil {
+		t.Errorf("bloom bits mismatch: %v", err)
+	}
+	reqs = []*BloomReq{{Section: 1, Bits: []uint{1}}}
+	cost = peer.GetRequestCost(GetBloomBitsMsg, 1)
+	sendRequest(peer.app, GetBloomBitsMsg, 43, cost, reqs)
+	if err := expectResponse(peer.app, BloomBitsMsg, 43, testBufLimit, [][]byte{}); err != nil {
+		t.Errorf("unindexed bloom bits mismatch: %v", err)
+	}
+}
@@ -93,6 +93,7 @@ const (
 	MsgReceipts
 	MsgProofs
 	MsgHeaderProofs
+	MsgBloomBits
 )
 
 // Msg encodes a LES message that delivers reply data for a request
@@ -74,6 +74,12 @@ func (ps *odrPeerSet) peerPriority(p *peer, info *odrPeerInfo, req LesOdrRequest
 	return tm
 }
 
+// peerSelector is implemented by the requests that only some of the peers can
+// serve.
+type peerSelector interface {
+	CanSend(*peer) bool
+}
+
 func (ps *odrPeerSet) bestPeer(req LesOdrRequest, exclude map[*peer]struct{}) *peer {
 	var best *peer
 	var bpv uint64
@@ -81,6 +87,9 @@ func (ps *odrPeerSet) bestPeer(req LesOdrRequest, exclude map[*peer]struct{}) *p
 	defer ps.lock.Unlock()
 
 	for p, info := range ps.peers {
+		if r, ok := req.(peerSelector); ok && !r.CanSend(p) {
+			continue
+		}
 		if _, ok := exclude[p]; !ok {
 			pv := ps.peerPriority(p, info, req)
 			if best == nil || pv < bpv {
@@ -26,6 +26,7 @@ import (
 	"github.com/ur-technology/go-ur/core"
 	"github.com/ur-technology/go-ur/core/types"
 	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/eth"
 	"github.com/ur-technology/go-ur/ethdb"
 	"github.com/ur-technology/go-ur/light"
 	"github.com/ur-technology/go-ur/logger"
@@ -52,6 +53,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*CodeRequest)(r)
 	case *light.ChtRequest:
 		return (*ChtRequest)(r)
+	case *light.BloomRequest:
+		return (*BloomRequest)(r)
 	default:
 		return nil
 	}
@@ -323,3 +326,58 @@ func (self *ChtRequest) Valid(db ethdb.Database, msg *Msg) bool {
 	glog.V(logger.Debug).Infof("ODR: validation successful")
 	return true
 }
+
+// BloomReq is the request of the bit vectors of a number of bloom bits of a
+// section.
+type BloomReq struct {
+	Section uint64
+	Bits    []uint
+}
+
+// BloomRequest is the ODR request type for bloom bit vectors
+type BloomRequest light.BloomRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (self *BloomRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetBloomBitsMsg, len(self.Bits))
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request,
+// bloom bits being served since les/2
+func (self *BloomRequest) CanSend(peer *peer) bool {
+	return peer.version >= lpv2
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (self *BloomRequest) Request(reqID uint64, peer *peer) error {
+	glog.V(logger.Debug).Infof("ODR: requesting %d bloom bits of section %d from peer %v", len(self.Bits), self.Section, peer.id)
+	req := &BloomReq{Section: self.Section, Bits: self.Bits}
+	return peer.RequestBloomBits(reqID, self.GetCost(peer), []*BloomReq{req})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (self *BloomRequest) Valid(db ethdb.Database, msg *Msg) bool {
+	glog.V(logger.Debug).Infof("ODR: validating bloom bits of section %d", self.Section)
+	if msg.MsgType != MsgBloomBits {
+		glog.V(logger.Debug).Infof("ODR: invalid message type")
+		return false
+	}
+	vectors := msg.Obj.([][]byte)
+	if len(vectors) != len(self.Bits) {
+		glog.V(logger.Debug).Infof("ODR: invalid number of entries: %d", len(vectors))
+		return false
+	}
+	// The vectors can't be verified, only their size is checked
+	for _, vector := range vectors {
+		if len(vector) != eth.BloomSectionSize/8 {
+			glog.V(logger.Debug).Infof("ODR: invalid bit vector length: %d", len(vector))
+			return false
+		}
+	}
+	self.BitSets = vectors
+	glog.V(logger.Debug).Infof("ODR: validation successful")
+	return true
+}
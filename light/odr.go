@@ -135,6 +135,25 @@ func (req *ReceiptsRequest) StoreResult(db ethdb.Database) {
 	core.WriteBlockReceipts(db, req.Hash, req.Number, req.Receipts)
 }
 
+// BloomRequest is the ODR request type for retrieving bloom bit vectors of a
+// section of the canonical chain. The vectors can't be verified, a server could
+// hide blocks from the searches but the logs found are checked against the
+// receipts.
+type BloomRequest struct {
+	OdrRequest
+	Section     uint64
+	SectionHead common.Hash
+	Bits        []uint
+	BitSets     [][]byte
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *BloomRequest) StoreResult(db ethdb.Database) {
+	for i, bit := range req.Bits {
+		core.WriteBloomBits(db, bit, req.Section, req.SectionHead, req.BitSets[i])
+	}
+}
+
 // TrieRequest is the ODR request type for state/storage trie entries
 type ChtRequest struct {
 	OdrRequest
@@ -184,3 +184,36 @@ func GetBlockReceipts(ctx context.Context, odr OdrBackend, hash common.Hash, num
 		return r.Receipts, nil
 	}
 }
+
+// GetBloomBits retrieves the bit vectors of the given bloom bits of a section of
+// the canonical chain, fetching the ones not cached locally.
+func GetBloomBits(ctx context.Context, odr OdrBackend, sectionSize, section uint64, bits []uint) ([][]byte, error) {
+	db := odr.Database()
+	head := core.GetCanonicalHash(db, (section+1)*sectionSize-1)
+	if head == (common.Hash{}) {
+		return nil, ErrNoHeader
+	}
+	var (
+		vectors = make([][]byte, len(bits))
+		missing []uint
+	)
+	for i, bit := range bits {
+		if vectors[i] = core.GetBloomBits(db, bit, section, head); vectors[i] == nil {
+			missing = append(missing, bit)
+		}
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+	r := &BloomRequest{Section: section, SectionHead: head, Bits: missing}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	for i, j := 0, 0; i < len(bits); i++ {
+		if vectors[i] == nil {
+			vectors[i] = r.BitSets[j]
+			j++
+		}
+	}
+	return vectors, nil
+}
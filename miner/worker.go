@@ -540,7 +540,7 @@ func (self *worker) commitNewWork() {
 	if err != nil {
 		panic(err)
 	}
-	core.UpdateBlockTotals(parent.Header(), header, uncles, msgs)
+	core.UpdateBlockTotals(self.config, parent.Header(), header, uncles, msgs)
 
 	if atomic.LoadInt32(&self.mining) == 1 {
 		// commit state root after all state transitions.
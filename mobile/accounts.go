@@ -21,6 +21,7 @@ package geth
 
 import (
 	"errors"
+	"path/filepath"
 	"time"
 
 	"github.com/ur-technology/go-ur/accounts"
@@ -82,28 +83,35 @@ func (a *Account) GetFile() string {
 	return a.account.File
 }
 
-// AccountManager manages a key storage directory on disk.
-type AccountManager struct{ manager *accounts.Manager }
+// AccountManager manages a key storage directory on disk, together with the
+// mnemonic backed HD wallets stored within it.
+type AccountManager struct {
+	keystore *accounts.KeyStore
+	hdstore  *accounts.HDStore
+}
 
 // NewAccountManager creates a manager for the given directory.
 func NewAccountManager(keydir string, scryptN, scryptP int) *AccountManager {
-	return &AccountManager{manager: accounts.NewManager(keydir, scryptN, scryptP)}
+	return &AccountManager{
+		keystore: accounts.NewKeyStore(keydir, scryptN, scryptP),
+		hdstore:  accounts.NewHDStore(filepath.Join(keydir, "hd"), scryptN, scryptP),
+	}
 }
 
 // HasAddress reports whether a key with the given address is present.
 func (am *AccountManager) HasAddress(addr *Address) bool {
-	return am.manager.HasAddress(addr.address)
+	return am.keystore.HasAddress(addr.address)
 }
 
 // GetAccounts returns all key files present in the directory.
 func (am *AccountManager) GetAccounts() *Accounts {
-	return &Accounts{am.manager.Accounts()}
+	return &Accounts{am.keystore.Accounts()}
 }
 
 // DeleteAccount deletes the key matched by account if the passphrase is correct.
 // If a contains no filename, the address must match a unique key.
 func (am *AccountManager) DeleteAccount(a *Account, passphrase string) error {
-	return am.manager.DeleteAccount(accounts.Account{
+	return am.keystore.DeleteAccount(accounts.Account{
 		Address: a.account.Address,
 		File:    a.account.File,
 	}, passphrase)
@@ -111,23 +119,23 @@ func (am *AccountManager) DeleteAccount(a *Account, passphrase string) error {
 
 // Sign signs hash with an unlocked private key matching the given address.
 func (am *AccountManager) Sign(addr *Address, hash []byte) ([]byte, error) {
-	return am.manager.Sign(addr.address, hash)
+	return am.keystore.Sign(addr.address, hash)
 }
 
 // SignWithPassphrase signs hash if the private key matching the given address can be
 // decrypted with the given passphrase.
 func (am *AccountManager) SignWithPassphrase(addr *Address, passphrase string, hash []byte) ([]byte, error) {
-	return am.manager.SignWithPassphrase(addr.address, passphrase, hash)
+	return am.keystore.SignWithPassphrase(addr.address, passphrase, hash)
 }
 
 // Unlock unlocks the given account indefinitely.
 func (am *AccountManager) Unlock(a *Account, passphrase string) error {
-	return am.manager.TimedUnlock(a.account, passphrase, 0)
+	return am.keystore.TimedUnlock(a.account, passphrase, 0)
 }
 
 // Lock removes the private key with the given address from memory.
 func (am *AccountManager) Lock(addr *Address) error {
-	return am.manager.Lock(addr.address)
+	return am.keystore.Lock(addr.address)
 }
 
 // TimedUnlock unlocks the given account with the passphrase. The account
@@ -138,13 +146,13 @@ func (am *AccountManager) Lock(addr *Address) error {
 // shortens the active unlock timeout. If the address was previously unlocked
 // indefinitely the timeout is not altered.
 func (am *AccountManager) TimedUnlock(a *Account, passphrase string, timeout int64) error {
-	return am.manager.TimedUnlock(a.account, passphrase, time.Duration(timeout))
+	return am.keystore.TimedUnlock(a.account, passphrase, time.Duration(timeout))
 }
 
 // NewAccount generates a new key and stores it into the key directory,
 // encrypting it with the passphrase.
 func (am *AccountManager) NewAccount(passphrase string) (*Account, error) {
-	account, err := am.manager.NewAccount(passphrase)
+	account, err := am.keystore.NewAccount(passphrase)
 	if err != nil {
 		return nil, err
 	}
@@ -153,12 +161,12 @@ func (am *AccountManager) NewAccount(passphrase string) (*Account, error) {
 
 // ExportKey exports as a JSON key, encrypted with newPassphrase.
 func (am *AccountManager) ExportKey(a *Account, passphrase, newPassphrase string) ([]byte, error) {
-	return am.manager.Export(a.account, passphrase, newPassphrase)
+	return am.keystore.Export(a.account, passphrase, newPassphrase)
 }
 
 // ImportKey stores the given encrypted JSON key into the key directory.
 func (am *AccountManager) ImportKey(keyJSON []byte, passphrase, newPassphrase string) (*Account, error) {
-	account, err := am.manager.Import(keyJSON, passphrase, newPassphrase)
+	account, err := am.keystore.Import(keyJSON, passphrase, newPassphrase)
 	if err != nil {
 		return nil, err
 	}
@@ -167,15 +175,120 @@ func (am *AccountManager) ImportKey(keyJSON []byte, passphrase, newPassphrase st
 
 // Update changes the passphrase of an existing account.
 func (am *AccountManager) Update(a *Account, passphrase, newPassphrase string) error {
-	return am.manager.Update(a.account, passphrase, newPassphrase)
+	return am.keystore.Update(a.account, passphrase, newPassphrase)
 }
 
 // ImportPreSaleKey decrypts the given Ethereum presale wallet and stores
 // a key file in the key directory. The key file is encrypted with the same passphrase.
 func (am *AccountManager) ImportPreSaleKey(keyJSON []byte, passphrase string) (*Account, error) {
-	account, err := am.manager.ImportPreSaleKey(keyJSON, passphrase)
+	account, err := am.keystore.ImportPreSaleKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{account}, nil
+}
+
+// NewMnemonic generates a new random 24 word BIP-39 mnemonic. It is not stored,
+// use ImportMnemonic after the user confirmed backing it up.
+func NewMnemonic() (string, error) {
+	return accounts.NewMnemonic(accounts.DefaultMnemonicBits)
+}
+
+// ImportMnemonic stores the HD wallet backed by the given BIP-39 mnemonic,
+// encrypting its seed with passphrase, and returns the URL of the wallet.
+func (am *AccountManager) ImportMnemonic(mnemonic, passphrase string) (string, error) {
+	wallet, err := am.hdstore.ImportMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return wallet.URL(), nil
+}
+
+// GetHDWallets returns the URLs of all the stored HD wallets.
+func (am *AccountManager) GetHDWallets() *Strings {
+	wallets := am.hdstore.Wallets()
+
+	urls := make([]string, len(wallets))
+	for i, wallet := range wallets {
+		urls[i] = wallet.URL()
+	}
+	return &Strings{urls}
+}
+
+// GetHDAccounts returns the accounts already derived from a HD wallet.
+func (am *AccountManager) GetHDAccounts(url string) (*Accounts, error) {
+	wallet, err := am.hdWallet(url)
+	if err != nil {
+		return nil, err
+	}
+	return &Accounts{wallet.Accounts()}, nil
+}
+
+// DeriveAccount derives the account at the given path (e.g. m/44'/21842'/0'/0/0)
+// from a HD wallet and pins it for later use.
+func (am *AccountManager) DeriveAccount(url, path, passphrase string) (*Account, error) {
+	derivPath, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := am.openHDWallet(url, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer wallet.Close()
+
+	account, err := wallet.Derive(derivPath, true)
 	if err != nil {
 		return nil, err
 	}
 	return &Account{account}, nil
 }
+
+// SelfDerive discovers the used UR accounts of a HD wallet by checking their
+// nonce and balance through the given client, pinning all of them and a fresh
+// unused one.
+func (am *AccountManager) SelfDerive(url, passphrase string, client *EthereumClient) (*Accounts, error) {
+	wallet, err := am.openHDWallet(url, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer wallet.Close()
+
+	found, err := accounts.SelfDerive(wallet, accounts.DefaultURBaseDerivationPath, client.client)
+	if err != nil {
+		return nil, err
+	}
+	return &Accounts{found}, nil
+}
+
+// SignHDWithPassphrase signs hash with the key of an account derived from a HD
+// wallet, decrypting the wallet's seed with passphrase just for this call.
+func (am *AccountManager) SignHDWithPassphrase(url string, addr *Address, passphrase string, hash []byte) ([]byte, error) {
+	wallet, err := am.hdWallet(url)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.SignHashWithPassphrase(accounts.Account{Address: addr.address}, passphrase, hash)
+}
+
+// hdWallet retrieves a stored HD wallet by URL.
+func (am *AccountManager) hdWallet(url string) (accounts.Wallet, error) {
+	for _, wallet := range am.hdstore.Wallets() {
+		if wallet.URL() == url {
+			return wallet, nil
+		}
+	}
+	return nil, accounts.ErrUnknownWallet
+}
+
+// openHDWallet retrieves and opens a stored HD wallet by URL.
+func (am *AccountManager) openHDWallet(url, passphrase string) (accounts.Wallet, error) {
+	wallet, err := am.hdWallet(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := wallet.Open(passphrase); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
@@ -89,6 +89,13 @@ func (h *Hash) GetHex() string {
 // Hashes represents a slice of hashes.
 type Hashes struct{ hashes []common.Hash }
 
+// NewHashes creates a slice of zero hashes.
+func NewHashes(size int) *Hashes {
+	return &Hashes{
+		hashes: make([]common.Hash, size),
+	}
+}
+
 // Size returns the number of hashes in the slice.
 func (h *Hashes) Size() int {
 	return len(h.hashes)
@@ -102,6 +109,15 @@ func (h *Hashes) Get(index int) (*Hash, error) {
 	return &Hash{h.hashes[index]}, nil
 }
 
+// Set sets the hash at the given index in the slice.
+func (h *Hashes) Set(index int, hash *Hash) error {
+	if index < 0 || index >= len(h.hashes) {
+		return errors.New("index out of bounds")
+	}
+	h.hashes[index] = hash.hash
+	return nil
+}
+
 // Address represents the 20 byte address of an Ethereum account.
 type Address struct {
 	address common.Address
@@ -21,6 +21,7 @@ package geth
 import (
 	"math/big"
 
+	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/core/types"
 	"github.com/ur-technology/go-ur/core/vm"
 	"github.com/ur-technology/go-ur/ethclient"
@@ -37,6 +38,18 @@ func NewEthereumClient(rawurl string) (*EthereumClient, error) {
 	return &EthereumClient{client}, err
 }
 
+// GetNetworkID returns the network ID of the node.
+func (ec *EthereumClient) GetNetworkID(ctx *Context) (*BigInt, error) {
+	id, err := ec.client.NetworkID(ctx.context)
+	return &BigInt{id}, err
+}
+
+// GetBlockNumber returns the number of the most recent block of the canonical chain.
+func (ec *EthereumClient) GetBlockNumber(ctx *Context) (int64, error) {
+	number, err := ec.client.BlockNumber(ctx.context)
+	return int64(number), err
+}
+
 // GetBlockByHash returns the given full block.
 func (ec *EthereumClient) GetBlockByHash(ctx *Context, hash *Hash) (*Block, error) {
 	block, err := ec.client.BlockByHash(ctx.context, hash.hash)
@@ -71,6 +84,21 @@ func (ec *EthereumClient) GetHeaderByNumber(ctx *Context, number int64) (*Header
 	return &Header{header}, err
 }
 
+// GetHeadersByHash returns the block headers with the given hashes in a single
+// batch request. The headers of unknown blocks are nil.
+func (ec *EthereumClient) GetHeadersByHash(ctx *Context, hashes *Hashes) (*Headers, error) {
+	headers, err := ec.client.HeadersByHash(ctx.context, hashes.hashes)
+	return &Headers{headers}, err
+}
+
+// GetHeadersByNumber returns block headers from the current canonical chain in a
+// single batch request. Unset numbers select the latest known header, and the
+// headers of blocks not yet known are nil.
+func (ec *EthereumClient) GetHeadersByNumber(ctx *Context, numbers *BigInts) (*Headers, error) {
+	headers, err := ec.client.HeadersByNumber(ctx.context, numbers.bigints)
+	return &Headers{headers}, err
+}
+
 // GetTransactionByHash returns the transaction with the given hash.
 func (ec *EthereumClient) GetTransactionByHash(ctx *Context, hash *Hash) (*Transaction, error) {
 	tx, err := ec.client.TransactionByHash(ctx.context, hash.hash)
@@ -90,6 +118,14 @@ func (ec *EthereumClient) GetTransactionInBlock(ctx *Context, hash *Hash, index
 
 }
 
+// GetTransactionSender returns the sender address of the given transaction, as
+// recovered by the server. The transaction must be included at the given index
+// of the given block.
+func (ec *EthereumClient) GetTransactionSender(ctx *Context, tx *Transaction, hash *Hash, index int) (*Address, error) {
+	sender, err := ec.client.TransactionSender(ctx.context, tx.tx, hash.hash, uint(index))
+	return &Address{sender}, err
+}
+
 // GetTransactionReceipt returns the receipt of a transaction by transaction hash.
 // Note that the receipt is not available for pending transactions.
 func (ec *EthereumClient) GetTransactionReceipt(ctx *Context, hash *Hash) (*Receipt, error) {
@@ -97,6 +133,13 @@ func (ec *EthereumClient) GetTransactionReceipt(ctx *Context, hash *Hash) (*Rece
 	return &Receipt{receipt}, err
 }
 
+// GetTransactionReceipts returns the receipts of the given transactions in a
+// single batch request. The receipts of pending or unknown transactions are nil.
+func (ec *EthereumClient) GetTransactionReceipts(ctx *Context, hashes *Hashes) (*Receipts, error) {
+	receipts, err := ec.client.TransactionReceipts(ctx.context, hashes.hashes)
+	return &Receipts{receipts}, err
+}
+
 // SyncProgress retrieves the current progress of the sync algorithm. If there's
 // no sync currently running, it returns nil.
 func (ec *EthereumClient) SyncProgress(ctx *Context) (*SyncProgress, error) {
@@ -259,6 +302,38 @@ func (ec *EthereumClient) GetPendingTransactionCount(ctx *Context) (int, error)
 	return int(count), err
 }
 
+// PendingTransactionHandler is a client-side subscription callback to invoke on
+// events and subscription failure.
+type PendingTransactionHandler interface {
+	OnPendingTransaction(hash *Hash)
+	OnError(failure string)
+}
+
+// SubscribePendingTransactions subscribes to notifications about the hashes of
+// the transactions entering the transaction pool of the node.
+func (ec *EthereumClient) SubscribePendingTransactions(ctx *Context, handler PendingTransactionHandler, buffer int) (*Subscription, error) {
+	// Subscribe to the event internally
+	ch := make(chan common.Hash, buffer)
+	sub, err := ec.client.SubscribePendingTransactions(ctx.context, ch)
+	if err != nil {
+		return nil, err
+	}
+	// Start up a dispatcher to feed into the callback
+	go func() {
+		for {
+			select {
+			case hash := <-ch:
+				handler.OnPendingTransaction(&Hash{hash})
+
+			case err := <-sub.Err():
+				handler.OnError(err.Error())
+				return
+			}
+		}
+	}()
+	return &Subscription{sub}, nil
+}
+
 // Contract Calling
 
 // CallContract executes a message call transaction, which is directly executed in the VM
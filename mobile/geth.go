@@ -26,6 +26,7 @@ import (
 
 	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/eth"
+	"github.com/ur-technology/go-ur/eth/gasprice"
 	"github.com/ur-technology/go-ur/ethclient"
 	"github.com/ur-technology/go-ur/ethstats"
 	"github.com/ur-technology/go-ur/les"
@@ -141,17 +142,15 @@ func NewNode(datadir string, config *NodeConfig) (*Node, error) {
 				EIP155Block:    big.NewInt(config.EthereumChainConfig.EIP155Block),
 				EIP158Block:    big.NewInt(config.EthereumChainConfig.EIP158Block),
 			},
-			Genesis:                 config.EthereumGenesis,
-			LightMode:               true,
-			DatabaseCache:           config.EthereumDatabaseCache,
-			NetworkId:               config.EthereumNetworkID,
-			GasPrice:                new(big.Int).Mul(big.NewInt(20), common.Shannon),
-			GpoMinGasPrice:          new(big.Int).Mul(big.NewInt(20), common.Shannon),
-			GpoMaxGasPrice:          new(big.Int).Mul(big.NewInt(500), common.Shannon),
-			GpoFullBlockRatio:       80,
-			GpobaseStepDown:         10,
-			GpobaseStepUp:           100,
-			GpobaseCorrectionFactor: 110,
+			Genesis:        config.EthereumGenesis,
+			LightMode:      true,
+			DatabaseCache:  config.EthereumDatabaseCache,
+			NetworkId:      config.EthereumNetworkID,
+			GasPrice:       new(big.Int).Mul(big.NewInt(20), common.Shannon),
+			GpoMinGasPrice: new(big.Int).Mul(big.NewInt(20), common.Shannon),
+			GpoMaxGasPrice: new(big.Int).Mul(big.NewInt(500), common.Shannon),
+			GpoBlocks:      gasprice.DefaultBlocks,
+			GpoPercentile:  gasprice.DefaultPercentile,
 		}
 		if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 			return les.New(ctx, ethConf)
@@ -19,7 +19,6 @@
 package geth
 
 import (
-	"github.com/ur-technology/go-ur/core"
 	"github.com/ur-technology/go-ur/p2p/discv5"
 	"github.com/ur-technology/go-ur/params"
 )
@@ -38,8 +37,8 @@ func MainnetChainConfig() *ChainConfig {
 	}
 }
 
-// MainnetGenesis returns the JSON spec to use for the main Ethereum network. It
-// is actually empty since that defaults to the hard coded binary genesis block.
+// MainnetGenesis returns the JSON spec to use for the main network. It is
+// actually empty since that defaults to the genesis of the main network profile.
 func MainnetGenesis() string {
 	return ""
 }
@@ -58,9 +57,9 @@ func TestnetChainConfig() *ChainConfig {
 	}
 }
 
-// TestnetGenesis returns the JSON spec to use for the Ethereum test network.
+// TestnetGenesis returns the JSON spec to use for the test network.
 func TestnetGenesis() string {
-	return core.DefaultTestnetGenesisBlock()
+	return string(params.TestnetNetwork.Genesis)
 }
 
 // ChainConfig is the core config which determines the blockchain settings.
@@ -85,8 +84,8 @@ func NewChainConfig() *ChainConfig {
 // by the foundation running the V5 discovery protocol.
 func FoundationBootnodes() *Enodes {
 	nodes := &Enodes{nodes: make([]*discv5.Node, len(params.DiscoveryV5Bootnodes))}
-	for i, node := range params.DiscoveryV5Bootnodes {
-		nodes.nodes[i] = node
+	for i, url := range params.DiscoveryV5Bootnodes {
+		nodes.nodes[i] = discv5.MustParseNode(url)
 	}
 	return nodes
 }
@@ -0,0 +1,96 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains all the wrappers from the signer package to support typed structured
+// data signing on mobile platforms.
+
+package geth
+
+import (
+	"errors"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/crypto"
+	urSigner "github.com/ur-technology/go-ur/signer"
+)
+
+// TypedData is an EIP-712 typed structured data payload.
+type TypedData struct {
+	data *urSigner.TypedData
+}
+
+// NewTypedDataFromJSON parses and validates a JSON typed data payload.
+func NewTypedDataFromJSON(json string) (*TypedData, error) {
+	data, err := urSigner.ParseTypedData([]byte(json))
+	if err != nil {
+		return nil, err
+	}
+	return &TypedData{data}, nil
+}
+
+// GetPrimaryType returns the struct type of the signed message.
+func (td *TypedData) GetPrimaryType() string {
+	return td.data.PrimaryType
+}
+
+// GetHash returns the hash signed for the typed data.
+func (td *TypedData) GetHash() (*Hash, error) {
+	hash, err := td.data.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return &Hash{common.BytesToHash(hash)}, nil
+}
+
+// RecoverSigner returns the address of the account that signed the typed data.
+func (td *TypedData) RecoverSigner(signature []byte) (*Address, error) {
+	hash, err := td.data.Hash()
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) != 65 {
+		return nil, errors.New("signature must be 65 bytes long")
+	}
+	sig := common.CopyBytes(signature)
+	if sig[64] == 27 || sig[64] == 28 {
+		sig[64] -= 27
+	}
+	pubkey, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		return nil, err
+	}
+	return &Address{crypto.PubkeyToAddress(*crypto.ToECDSAPub(pubkey))}, nil
+}
+
+// SignTypedData signs typed data with an unlocked private key matching the
+// given address.
+func (am *AccountManager) SignTypedData(addr *Address, td *TypedData) ([]byte, error) {
+	hash, err := td.data.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return am.keystore.SignEthereum(addr.address, hash)
+}
+
+// SignTypedDataWithPassphrase signs typed data if the private key matching the
+// given address can be decrypted with the given passphrase.
+func (am *AccountManager) SignTypedDataWithPassphrase(addr *Address, passphrase string, td *TypedData) ([]byte, error) {
+	hash, err := td.data.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return am.keystore.SignWithPassphrase(addr.address, passphrase, hash)
+}
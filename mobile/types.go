@@ -74,6 +74,8 @@ func (h *Header) GetGasLimit() int64     { return h.header.GasLimit.Int64() }
 func (h *Header) GetGasUsed() int64      { return h.header.GasUsed.Int64() }
 func (h *Header) GetTime() int64         { return h.header.Time.Int64() }
 func (h *Header) GetExtra() []byte       { return h.header.Extra }
+func (h *Header) GetNSignups() *BigInt   { return &BigInt{h.header.NSignups} }
+func (h *Header) GetTotalWei() *BigInt   { return &BigInt{h.header.TotalWei} }
 func (h *Header) GetMixDigest() *Hash    { return &Hash{h.header.MixDigest} }
 func (h *Header) GetNonce() *Nonce       { return &Nonce{h.header.Nonce} }
 
@@ -114,6 +116,8 @@ func (b *Block) GetGasLimit() int64     { return b.block.GasLimit().Int64() }
 func (b *Block) GetGasUsed() int64      { return b.block.GasUsed().Int64() }
 func (b *Block) GetTime() int64         { return b.block.Time().Int64() }
 func (b *Block) GetExtra() []byte       { return b.block.Extra() }
+func (b *Block) GetNSignups() *BigInt   { return &BigInt{b.block.NSignups()} }
+func (b *Block) GetTotalWei() *BigInt   { return &BigInt{b.block.TotalWei()} }
 func (b *Block) GetMixDigest() *Hash    { return &Hash{b.block.MixDigest()} }
 func (b *Block) GetNonce() int64        { return int64(b.block.Nonce()) }
 
@@ -187,3 +191,19 @@ func (r *Receipt) GetLogs() *Logs                { return &Logs{r.receipt.Logs}
 func (r *Receipt) GetTxHash() *Hash              { return &Hash{r.receipt.TxHash} }
 func (r *Receipt) GetContractAddress() *Address  { return &Address{r.receipt.ContractAddress} }
 func (r *Receipt) GetGasUsed() *BigInt           { return &BigInt{r.receipt.GasUsed} }
+
+// Receipts represents a slice of receipts.
+type Receipts struct{ receipts []*types.Receipt }
+
+// Size returns the number of receipts in the slice.
+func (r *Receipts) Size() int {
+	return len(r.receipts)
+}
+
+// Get returns the receipt at the given index from the slice.
+func (r *Receipts) Get(index int) (*Receipt, error) {
+	if index < 0 || index >= len(r.receipts) {
+		return nil, errors.New("index out of bounds")
+	}
+	return &Receipt{r.receipts[index]}, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains wrappers for the UR extensions of the Ethereum client.
+
+package geth
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/ethclient"
+	"github.com/ur-technology/go-ur/params"
+)
+
+// NetworkTotals is the number of members signed up and the total wei issued by
+// the network as of a block.
+type NetworkTotals struct {
+	totals *ethclient.NetworkTotals
+}
+
+func (t *NetworkTotals) GetNSignups() *BigInt { return &BigInt{t.totals.NSignups} }
+func (t *NetworkTotals) GetTotalWei() *BigInt { return &BigInt{t.totals.TotalWei} }
+
+// Reward is an amount of wei credited to an account.
+type Reward struct {
+	reward core.Reward
+}
+
+func (r *Reward) GetAddress() *Address { return &Address{r.reward.Address} }
+func (r *Reward) GetAmount() *BigInt   { return &BigInt{r.reward.Amount} }
+
+// Rewards represents a slice of rewards.
+type Rewards struct{ rewards []core.Reward }
+
+// Size returns the number of rewards in the slice.
+func (r *Rewards) Size() int {
+	return len(r.rewards)
+}
+
+// Get returns the reward at the given index from the slice.
+func (r *Rewards) Get(index int) (*Reward, error) {
+	if index < 0 || index >= len(r.rewards) {
+		return nil, errors.New("index out of bounds")
+	}
+	return &Reward{r.rewards[index]}, nil
+}
+
+// SignupRewards is the breakdown of the wei issued by a signup transaction.
+type SignupRewards struct {
+	rewards *core.SignupRewards
+}
+
+func (r *SignupRewards) GetMiner() *Reward      { return &Reward{r.rewards.Miner} }
+func (r *SignupRewards) GetMember() *Reward     { return &Reward{r.rewards.Member} }
+func (r *SignupRewards) GetReferrers() *Rewards { return &Rewards{r.rewards.Referrers} }
+func (r *SignupRewards) GetFutureFund() *Reward { return &Reward{r.rewards.FutureFund} }
+func (r *SignupRewards) GetReceiver() *Reward   { return &Reward{r.rewards.Receiver} }
+func (r *SignupRewards) GetRewards() *Rewards   { return &Rewards{r.rewards.Rewards()} }
+
+// GetNetworkTotalsAt returns the network totals as of the given block. The block
+// number can be <0, in which case the totals of the latest known block are
+// returned.
+func (ec *EthereumClient) GetNetworkTotalsAt(ctx *Context, number int64) (*NetworkTotals, error) {
+	var block *big.Int
+	if number >= 0 {
+		block = big.NewInt(number)
+	}
+	totals, err := ec.client.NetworkTotalsAt(ctx.context, block)
+	if err != nil {
+		return nil, err
+	}
+	return &NetworkTotals{totals}, nil
+}
+
+// GetSignupChain returns the referring members of the member signed up by the
+// given transaction, nearest first. These are the members rewarded for the signup.
+// Signups are recognized by the privileged addresses of the main network.
+func (ec *EthereumClient) GetSignupChain(ctx *Context, hash *Hash) (*Addresses, error) {
+	chain, err := ec.client.SignupChain(ctx.context, params.MainnetChainConfig, hash.hash)
+	if err != nil {
+		return nil, err
+	}
+	return &Addresses{chain}, nil
+}
+
+// GetSignupRewards returns the breakdown of the wei issued by the given signup
+// transaction of the main network.
+func (ec *EthereumClient) GetSignupRewards(ctx *Context, hash *Hash) (*SignupRewards, error) {
+	rewards, err := ec.client.SignupRewards(ctx.context, params.MainnetChainConfig, hash.hash)
+	if err != nil {
+		return nil, err
+	}
+	return &SignupRewards{rewards}, nil
+}
@@ -105,7 +105,11 @@ func (api *PrivateAdminAPI) StartRPC(host *string, port *rpc.HexNumber, cors *st
 		}
 	}
 
-	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, port.Int()), api.node.rpcAPIs, modules, *cors); err != nil {
+	auth, err := api.node.config.RPCAuthenticator()
+	if err != nil {
+		return false, err
+	}
+	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, port.Int()), api.node.rpcAPIs, modules, *cors, auth); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -154,7 +158,11 @@ func (api *PrivateAdminAPI) StartWS(host *string, port *rpc.HexNumber, allowedOr
 		}
 	}
 
-	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, port.Int()), api.node.rpcAPIs, modules, *allowedOrigins); err != nil {
+	auth, err := api.node.config.RPCAuthenticator()
+	if err != nil {
+		return false, err
+	}
+	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, port.Int()), api.node.rpcAPIs, modules, *allowedOrigins, auth); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -27,6 +27,8 @@ import (
 	"strings"
 
 	"github.com/ur-technology/go-ur/accounts"
+	"github.com/ur-technology/go-ur/accounts/external"
+	"github.com/ur-technology/go-ur/accounts/usbwallet"
 	"github.com/ur-technology/go-ur/common"
 	"github.com/ur-technology/go-ur/crypto"
 	"github.com/ur-technology/go-ur/logger"
@@ -35,6 +37,7 @@ import (
 	"github.com/ur-technology/go-ur/p2p/discv5"
 	"github.com/ur-technology/go-ur/p2p/nat"
 	"github.com/ur-technology/go-ur/p2p/netutil"
+	"github.com/ur-technology/go-ur/rpc"
 )
 
 var (
@@ -43,6 +46,7 @@ var (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirHDWallets       = "hd"                 // Path within the keystore to the HD wallets
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -52,14 +56,14 @@ type Config struct {
 	// Name sets the instance name of the node. It must not contain the / character and is
 	// used in the devp2p node identifier. The instance name of gur is "gur". If no
 	// value is specified, the basename of the current executable is used.
-	Name string
+	Name string `toml:"-"`
 
 	// UserIdent, if set, is used as an additional component in the devp2p node identifier.
-	UserIdent string
+	UserIdent string `toml:",omitempty"`
 
 	// Version should be set to the version number of the program. It is used
 	// in the devp2p node identifier.
-	Version string
+	Version string `toml:"-"`
 
 	// DataDir is the file system folder the node should use for any data storage
 	// requirements. The configured data directory will not be directly shared with
@@ -75,23 +79,35 @@ type Config struct {
 	// If KeyStoreDir is empty, the default location is the "keystore" subdirectory of
 	// DataDir. If DataDir is unspecified and KeyStoreDir is empty, an ephemeral directory
 	// is created by New and destroyed when the node is stopped.
-	KeyStoreDir string
+	KeyStoreDir string `toml:",omitempty"`
 
 	// UseLightweightKDF lowers the memory and CPU requirements of the key store
 	// scrypt KDF at the expense of security.
-	UseLightweightKDF bool
+	UseLightweightKDF bool `toml:",omitempty"`
+
+	// NoUSB disables hardware wallet monitoring and connectivity.
+	NoUSB bool `toml:",omitempty"`
+
+	// ExternalSigner is the IPC path or HTTP URL of an external signer to add
+	// as an accounts backend, delegating signing to its rule set.
+	ExternalSigner string `toml:",omitempty"`
 
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
 	// relative), then that specific path is enforced. An empty path disables IPC.
-	IPCPath string
+	IPCPath string `toml:",omitempty"`
+
+	// StdIORPC serves all the APIs of the node (just like over IPC) on the standard
+	// input and output of the process, for nodes run as the subprocess of another
+	// program.
+	StdIORPC bool `toml:",omitempty"`
 
 	// This field should be a valid secp256k1 private key that will be used for both
 	// remote peer identification as well as network traffic encryption. If no key
 	// is configured, the preset one is loaded from the data dir, generating it if
 	// needed.
-	PrivateKey *ecdsa.PrivateKey
+	PrivateKey *ecdsa.PrivateKey `toml:"-"`
 
 	// NoDiscovery specifies whether the peer discovery mechanism should be started
 	// or not. Disabling is usually useful for protocol debugging (manual topology).
@@ -106,7 +122,7 @@ type Config struct {
 
 	// Restrict communication to white listed IP networks.
 	// The whitelist only applies when non-nil.
-	NetRestrict *netutil.Netlist
+	NetRestrict *netutil.Netlist `toml:",omitempty"`
 
 	// BootstrapNodes used to establish connectivity with the rest of the network.
 	BootstrapNodes []*discover.Node
@@ -120,14 +136,14 @@ type Config struct {
 
 	// If set to a non-nil value, the given NAT port mapper is used to make the
 	// listening port available to the Internet.
-	NAT nat.Interface
+	NAT nat.Interface `toml:"-"`
 
 	// If Dialer is set to a non-nil value, the given Dialer is used to dial outbound
 	// peer connections.
-	Dialer *net.Dialer
+	Dialer *net.Dialer `toml:"-"`
 
 	// If NoDial is true, the node will not dial any peers.
-	NoDial bool
+	NoDial bool `toml:",omitempty"`
 
 	// MaxPeers is the maximum number of peers that can be connected. If this is
 	// set to zero, then only the configured static and trusted peers can connect.
@@ -140,7 +156,7 @@ type Config struct {
 
 	// HTTPHost is the host interface on which to start the HTTP RPC server. If this
 	// field is empty, no HTTP API endpoint will be started.
-	HTTPHost string
+	HTTPHost string `toml:",omitempty"`
 
 	// HTTPPort is the TCP port number on which to start the HTTP RPC server. The
 	// default zero value is/ valid and will pick a port number randomly (useful
@@ -150,7 +166,7 @@ type Config struct {
 	// HTTPCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
-	HTTPCors string
+	HTTPCors string `toml:",omitempty"`
 
 	// HTTPModules is a list of API modules to expose via the HTTP RPC interface.
 	// If the module list is empty, all RPC API endpoints designated public will be
@@ -159,7 +175,7 @@ type Config struct {
 
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
-	WSHost string
+	WSHost string `toml:",omitempty"`
 
 	// WSPort is the TCP port number on which to start the websocket RPC server. The
 	// default zero value is/ valid and will pick a port number randomly (useful for
@@ -169,12 +185,54 @@ type Config struct {
 	// WSOrigins is the list of domain to accept websocket requests from. Please be
 	// aware that the server can only act upon the HTTP request the client sends and
 	// cannot verify the validity of the request header.
-	WSOrigins string
+	WSOrigins string `toml:",omitempty"`
 
 	// WSModules is a list of API modules to expose via the websocket RPC interface.
 	// If the module list is empty, all RPC API endpoints designated public will be
 	// exposed.
 	WSModules []string
+
+	// JWTSecret is the path of a file containing a hex encoded 32 byte secret. If
+	// set, the HTTP and websocket RPC endpoints only accept requests carrying a
+	// valid HS256 signed bearer token, which may further restrict the namespaces
+	// and methods its bearer can call.
+	JWTSecret string `toml:",omitempty"`
+
+	// RPCAllowMethods is an optional list of methods (or namespace wildcards such
+	// as "eth_*") which may be called via the HTTP and websocket RPC interfaces.
+	// If the list is empty, all the methods of the exposed modules are allowed.
+	RPCAllowMethods []string `toml:",omitempty"`
+
+	// RPCDenyMethods is an optional list of methods (or namespace wildcards) which
+	// may not be called via the HTTP and websocket RPC interfaces, taking
+	// precedence over RPCAllowMethods.
+	RPCDenyMethods []string `toml:",omitempty"`
+
+	// RPCLimits are the resource limits (batch and message sizes, execution time
+	// and request rates) enforced on the clients of the HTTP and websocket RPC
+	// interfaces.
+	RPCLimits rpc.Limits
+}
+
+// RPCAuthenticator creates the authenticator guarding the network facing RPC
+// endpoints, or nil if neither token authentication nor method filtering is
+// configured.
+func (c *Config) RPCAuthenticator() (*rpc.Authenticator, error) {
+	if c.JWTSecret == "" && len(c.RPCAllowMethods) == 0 && len(c.RPCDenyMethods) == 0 {
+		return nil, nil
+	}
+	var secret []byte
+	if c.JWTSecret != "" {
+		var err error
+		if secret, err = rpc.LoadJWTSecret(c.JWTSecret); err != nil {
+			return nil, err
+		}
+	}
+	var policy *rpc.AccessPolicy
+	if len(c.RPCAllowMethods) > 0 || len(c.RPCDenyMethods) > 0 {
+		policy = &rpc.AccessPolicy{Allow: c.RPCAllowMethods, Deny: c.RPCDenyMethods}
+	}
+	return rpc.NewAuthenticator(secret, policy), nil
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -432,5 +490,16 @@ func makeAccountManager(conf *Config) (am *accounts.Manager, ephemeralKeystore s
 		return nil, "", err
 	}
 
-	return accounts.NewManager(keydir, scryptN, scryptP), ephemeralKeystore, nil
+	// Assemble the account manager and supported backends
+	backends := []accounts.Backend{
+		accounts.NewKeyStore(keydir, scryptN, scryptP),
+		accounts.NewHDStore(filepath.Join(keydir, datadirHDWallets), scryptN, scryptP),
+	}
+	if !conf.NoUSB {
+		backends = append(backends, usbwallet.NewLedgerHub())
+	}
+	if conf.ExternalSigner != "" {
+		backends = append(backends, external.NewExternalBackend(conf.ExternalSigner))
+	}
+	return accounts.NewManager(backends...), ephemeralKeystore, nil
 }
@@ -21,6 +21,8 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+
+	"github.com/ur-technology/go-ur/p2p/nat"
 )
 
 const (
@@ -31,6 +33,20 @@ const (
 	DefaultWSPort    = 9596        // Default TCP port for the websocket RPC server
 )
 
+// DefaultConfig contains reasonable default settings.
+var DefaultConfig = Config{
+	DataDir:         DefaultDataDir(),
+	IPCPath:         DefaultIPCSocket,
+	HTTPPort:        DefaultHTTPPort,
+	HTTPModules:     []string{"eth", "net", "web3"},
+	WSPort:          DefaultWSPort,
+	WSModules:       []string{"eth", "net", "web3"},
+	ListenAddr:      ":19595",
+	DiscoveryV5Addr: ":19596",
+	MaxPeers:        25,
+	NAT:             nat.Any(),
+}
+
 // DefaultDataDir is the default data directory to use for the databases and other
 // persistence requirements.
 func DefaultDataDir() string {
@@ -18,7 +18,9 @@ package node
 
 import (
 	"errors"
+	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -68,10 +70,11 @@ type Node struct {
 	ipcListener net.Listener // IPC RPC listener socket to serve API requests
 	ipcHandler  *rpc.Server  // IPC RPC request handler to process the API requests
 
-	httpEndpoint  string       // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
-	httpWhitelist []string     // HTTP RPC modules to allow through this endpoint
-	httpListener  net.Listener // HTTP RPC listener socket to server API requests
-	httpHandler   *rpc.Server  // HTTP RPC request handler to process the API requests
+	httpEndpoint  string                 // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
+	httpWhitelist []string               // HTTP RPC modules to allow through this endpoint
+	httpListener  net.Listener           // HTTP RPC listener socket to server API requests
+	httpHandler   *rpc.Server            // HTTP RPC request handler to process the API requests
+	httpServices  map[string]httpService // HTTP handlers of the services, mounted next to the RPC handler
 
 	wsEndpoint string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
@@ -81,6 +84,12 @@ type Node struct {
 	lock sync.RWMutex
 }
 
+// httpService is a plain HTTP handler of a service, guarded as calls of method.
+type httpService struct {
+	handler http.Handler
+	method  string
+}
+
 // New creates a new P2P node, ready for protocol registration.
 func New(conf *Config) (*Node, error) {
 	// Copy config and resolve the datadir so future changes to the current
@@ -269,6 +278,21 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	for _, service := range services {
 		apis = append(apis, service.APIs()...)
 	}
+	// Gather the HTTP endpoints of the services, refusing conflicting paths
+	handlers := make(map[string]httpService)
+	for _, service := range services {
+		if service, ok := service.(HTTPService); ok {
+			method := service.HTTPMethod()
+			for path, handler := range service.HTTPHandlers() {
+				if _, ok := handlers[path]; ok || path == "/" {
+					return fmt.Errorf("duplicate HTTP handler for path %q", path)
+				}
+				handlers[path] = httpService{handler, method}
+			}
+		}
+	}
+	n.httpServices = handlers
+
 	// Start the various API endpoints, terminating all in case of errors
 	if err := n.startInProc(apis); err != nil {
 		return err
@@ -277,12 +301,18 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
-	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors); err != nil {
+	auth, err := n.config.RPCAuthenticator()
+	if err != nil {
+		n.stopIPC()
+		n.stopInProc()
+		return err
+	}
+	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, auth); err != nil {
 		n.stopIPC()
 		n.stopInProc()
 		return err
 	}
-	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins); err != nil {
+	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, auth); err != nil {
 		n.stopHTTP()
 		n.stopIPC()
 		n.stopInProc()
@@ -304,6 +334,12 @@ func (n *Node) startInProc(apis []rpc.API) error {
 		glog.V(logger.Debug).Infof("InProc registered %T under '%s'", api.Service, api.Namespace)
 	}
 	n.inprocHandler = handler
+
+	// Serve the in-process handler on the standard streams too if requested
+	if n.config.StdIORPC {
+		go handler.ServeCodec(rpc.NewStdIOCodec(), rpc.OptionMethodInvocation|rpc.OptionSubscriptions)
+		glog.V(logger.Info).Infoln("StdIO endpoint opened")
+	}
 	return nil
 }
 
@@ -378,8 +414,9 @@ func (n *Node) stopIPC() {
 	}
 }
 
-// startHTTP initializes and starts the HTTP RPC endpoint.
-func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors string) error {
+// startHTTP initializes and starts the HTTP RPC endpoint, guarded by auth if
+// it's non-nil.
+func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors string, auth *rpc.Authenticator) error {
 	// Short circuit if the HTTP endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
@@ -391,6 +428,7 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	}
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	handler.SetLimits(n.config.RPCLimits)
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -407,7 +445,25 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if listener, err = net.Listen("tcp", endpoint); err != nil {
 		return err
 	}
-	go rpc.NewHTTPServer(cors, handler).Serve(listener)
+	var rpcHandler http.Handler = handler
+	if auth != nil {
+		rpcHandler = handler.AuthHandler(auth)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", rpcHandler)
+	for path, service := range n.httpServices {
+		namespace := service.method
+		if i := strings.Index(namespace, "_"); i >= 0 {
+			namespace = namespace[:i]
+		}
+		if len(whitelist) > 0 && !whitelist[namespace] {
+			glog.V(logger.Info).Infof("HTTP service endpoint %s disabled, the %s API is not exposed", path, namespace)
+			continue
+		}
+		mux.Handle(path, handler.GuardHandler(service.handler, auth, service.method))
+		glog.V(logger.Info).Infof("HTTP service endpoint opened: http://%s%s", endpoint, path)
+	}
+	go (&http.Server{Handler: rpc.NewCorsHandler(mux, cors)}).Serve(listener)
 	glog.V(logger.Info).Infof("HTTP endpoint opened: http://%s", endpoint)
 
 	// All listeners booted successfully
@@ -432,8 +488,9 @@ func (n *Node) stopHTTP() {
 	}
 }
 
-// startWS initializes and starts the websocket RPC endpoint.
-func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins string) error {
+// startWS initializes and starts the websocket RPC endpoint, guarded by auth if
+// it's non-nil.
+func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins string, auth *rpc.Authenticator) error {
 	// Short circuit if the WS endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
@@ -445,6 +502,7 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	}
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	handler.SetLimits(n.config.RPCLimits)
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -461,7 +519,11 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	if listener, err = net.Listen("tcp", endpoint); err != nil {
 		return err
 	}
-	go rpc.NewWSServer(wsOrigins, handler).Serve(listener)
+	if auth != nil {
+		go rpc.NewAuthWSServer(wsOrigins, handler, auth).Serve(listener)
+	} else {
+		go rpc.NewWSServer(wsOrigins, handler).Serve(listener)
+	}
 	glog.V(logger.Info).Infof("WebSocket endpoint opened: ws://%s", endpoint)
 
 	// All listeners booted successfully
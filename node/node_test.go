@@ -17,9 +17,13 @@
 package node
 
 import (
+	"bytes"
+	"encoding/hex"
 	"errors"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -573,3 +577,67 @@ func TestAPIGather(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the plain HTTP endpoints of the services are only opened if their
+// API is exposed, and are guarded by the authentication of the HTTP RPC server.
+func TestHTTPServiceGuard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	secret := bytes.Repeat([]byte{0x42}, 32)
+	secretFile := filepath.Join(dir, "jwt.hex")
+	if err := ioutil.WriteFile(secretFile, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		t.Fatalf("failed to write JWT secret: %v", err)
+	}
+	tests := []struct {
+		modules []string
+		token   string
+		status  int
+		served  bool
+	}{
+		{[]string{"net"}, rpc.NewJWT(secret, time.Now(), time.Minute, nil, nil), 0, false},
+		{[]string{"eth"}, "", http.StatusUnauthorized, false},
+		{[]string{"eth"}, rpc.NewJWT(secret, time.Now(), time.Minute, []string{"net"}, nil), http.StatusForbidden, false},
+		{[]string{"eth"}, rpc.NewJWT(secret, time.Now(), time.Minute, []string{"eth"}, nil), http.StatusOK, true},
+	}
+	for i, tt := range tests {
+		config := testNodeConfig()
+		config.HTTPHost, config.HTTPModules, config.JWTSecret = "127.0.0.1", tt.modules, secretFile
+
+		stack, err := New(config)
+		if err != nil {
+			t.Fatalf("test %d: failed to create protocol stack: %v", i, err)
+		}
+		service := &HTTPServiceA{served: make(chan struct{}, 1)}
+		if err := stack.Register(func(*ServiceContext) (Service, error) { return service, nil }); err != nil {
+			t.Fatalf("test %d: failed to register service: %v", i, err)
+		}
+		if err := stack.Start(); err != nil {
+			t.Fatalf("test %d: failed to start protocol stack: %v", i, err)
+		}
+		req, _ := http.NewRequest("POST", "http://"+stack.httpListener.Addr().String()+"/plain", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("test %d: request failed: %v", i, err)
+		}
+		resp.Body.Close()
+		if tt.status != 0 && resp.StatusCode != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, resp.StatusCode, tt.status)
+		}
+		select {
+		case <-service.served:
+			if !tt.served {
+				t.Errorf("test %d: request served", i)
+			}
+		default:
+			if tt.served {
+				t.Errorf("test %d: request not served", i)
+			}
+		}
+		stack.Stop()
+	}
+}
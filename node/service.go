@@ -17,6 +17,7 @@
 package node
 
 import (
+	"net/http"
 	"reflect"
 
 	"github.com/ur-technology/go-ur/accounts"
@@ -85,3 +86,17 @@ type Service interface {
 	// are all terminated.
 	Stop() error
 }
+
+// HTTPService is an optional interface of services serving plain HTTP endpoints
+// next to the HTTP RPC server, such as GraphQL.
+type HTTPService interface {
+	// HTTPHandlers retrieves the HTTP handlers of the service, keyed by the URL
+	// path they are mounted at.
+	HTTPHandlers() map[string]http.Handler
+
+	// HTTPMethod returns the RPC method the requests of the handlers count as
+	// (e.g. "eth_graphql"). The handlers are only mounted if the namespace of the
+	// method is exposed over HTTP, and are subject to the authentication, access
+	// policies and limits of the HTTP RPC server.
+	HTTPMethod() string
+}
@@ -20,6 +20,7 @@
 package node
 
 import (
+	"net/http"
 	"reflect"
 
 	"github.com/ur-technology/go-ur/p2p"
@@ -48,6 +49,21 @@ func NewNoopServiceB(*ServiceContext) (Service, error) { return new(NoopServiceB
 func NewNoopServiceC(*ServiceContext) (Service, error) { return new(NoopServiceC), nil }
 func NewNoopServiceD(*ServiceContext) (Service, error) { return new(NoopServiceD), nil }
 
+// HTTPServiceA is a service serving a plain HTTP endpoint at /plain, counting the
+// requests reaching it.
+type HTTPServiceA struct {
+	NoopService
+	served chan struct{}
+}
+
+func (s *HTTPServiceA) HTTPHandlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		"/plain": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { s.served <- struct{}{} }),
+	}
+}
+
+func (s *HTTPServiceA) HTTPMethod() string { return "eth_plain" }
+
 // InstrumentedService is an implementation of Service for which all interface
 // methods can be instrumented both return value as well as event hook wise.
 type InstrumentedService struct {
@@ -56,6 +56,8 @@ type dialstate struct {
 	dialing       map[discover.NodeID]connFlag
 	lookupBuf     []*discover.Node // current discovery lookup results
 	randomNodes   []*discover.Node // filled from Table
+	reputable     []*discover.Node // nodes with good reputation, best first
+	reputableNext time.Time        // time of the next reputable nodes query
 	static        map[discover.NodeID]*dialTask
 	hist          *dialHistory
 }
@@ -66,6 +68,9 @@ type discoverTable interface {
 	Resolve(target discover.NodeID) *discover.Node
 	Lookup(target discover.NodeID) []*discover.Node
 	ReadRandomNodes([]*discover.Node) int
+	Reputation(id discover.NodeID) []byte
+	SetReputation(id discover.NodeID, blob []byte, score float64) error
+	ReputableNodes(n int, min float64) []*discover.Node
 }
 
 // the dial history remembers recent dials.
@@ -171,6 +176,26 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 		}
 	}
 
+	// Redial the nodes which served us well in the past before
+	// any random ones, but only for half of the necessary dynamic
+	// dials so new nodes still get a chance. The query is throttled
+	// like redials.
+	reputableCandidates := needDynDials / 2
+	if reputableCandidates > 0 {
+		if !now.Before(s.reputableNext) {
+			s.reputable = s.ntab.ReputableNodes(s.maxDynDials, reputableScore)
+			s.reputableNext = now.Add(dialHistoryExpiration)
+		}
+		for _, n := range s.reputable {
+			if reputableCandidates == 0 {
+				break
+			}
+			if addDial(dynDialedConn, n) {
+				reputableCandidates--
+				needDynDials--
+			}
+		}
+	}
 	// Use random nodes from the table for half of the necessary
 	// dynamic dials.
 	randomCandidates := needDynDials / 2
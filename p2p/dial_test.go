@@ -83,6 +83,11 @@ func (t fakeTable) Close()                                   {}
 func (t fakeTable) Lookup(discover.NodeID) []*discover.Node  { return nil }
 func (t fakeTable) Resolve(discover.NodeID) *discover.Node   { return nil }
 func (t fakeTable) ReadRandomNodes(buf []*discover.Node) int { return copy(buf, t) }
+func (t fakeTable) Reputation(discover.NodeID) []byte        { return nil }
+func (t fakeTable) SetReputation(discover.NodeID, []byte, float64) error {
+	return nil
+}
+func (t fakeTable) ReputableNodes(int, float64) []*discover.Node { return nil }
 
 // This test checks that dynamic dials are launched from discovery results.
 func TestDialStateDynDial(t *testing.T) {
@@ -314,6 +319,59 @@ func TestDialStateDynDialFromTable(t *testing.T) {
 	})
 }
 
+// reputableTable is a fake table which also knows nodes of good reputation.
+type reputableTable struct {
+	fakeTable
+	reputable []*discover.Node
+}
+
+func (t reputableTable) ReputableNodes(n int, min float64) []*discover.Node {
+	if n < len(t.reputable) {
+		return t.reputable[:n]
+	}
+	return t.reputable
+}
+
+// This test checks that nodes of good reputation are dialed before random ones,
+// but don't take up all the dynamic dials.
+func TestDialStateReputable(t *testing.T) {
+	table := reputableTable{
+		fakeTable: fakeTable{{ID: uintID(5)}, {ID: uintID(6)}},
+		reputable: []*discover.Node{{ID: uintID(1)}, {ID: uintID(2)}, {ID: uintID(3)}},
+	}
+	runDialTest(t, dialtest{
+		init: newDialState(nil, table, 4, nil),
+		rounds: []round{
+			// Reputable nodes are dialed for half of the dynamic dials,
+			// random and discovered nodes for the rest.
+			{
+				new: []task{
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
+					&discoverTask{},
+				},
+			},
+			// Connected reputable nodes are skipped, the next one is
+			// dialed within the limit.
+			{
+				peers: []*Peer{
+					{rw: &conn{flags: dynDialedConn, id: uintID(1)}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2)}},
+				},
+				done: []task{
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
+				},
+				new: []task{
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}},
+				},
+			},
+		},
+	})
+}
+
 // This test checks that candidates that do not match the netrestrict list are not dialed.
 func TestDialStateNetRestrict(t *testing.T) {
 	// This table always returns the same random nodes
@@ -562,3 +620,8 @@ func (t *resolveMock) Close()                                   {}
 func (t *resolveMock) Bootstrap([]*discover.Node)               {}
 func (t *resolveMock) Lookup(discover.NodeID) []*discover.Node  { return nil }
 func (t *resolveMock) ReadRandomNodes(buf []*discover.Node) int { return 0 }
+func (t *resolveMock) Reputation(discover.NodeID) []byte        { return nil }
+func (t *resolveMock) SetReputation(discover.NodeID, []byte, float64) error {
+	return nil
+}
+func (t *resolveMock) ReputableNodes(int, float64) []*discover.Node { return nil }
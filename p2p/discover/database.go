@@ -24,6 +24,7 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -62,8 +63,14 @@ var (
 	nodeDBDiscoverPing      = nodeDBDiscoverRoot + ":lastping"
 	nodeDBDiscoverPong      = nodeDBDiscoverRoot + ":lastpong"
 	nodeDBDiscoverFindFails = nodeDBDiscoverRoot + ":findfail"
+
+	nodeDBReputationRoot  = ":reputation"
+	nodeDBReputationScore = nodeDBReputationRoot + ":score"
 )
 
+// nodeDBScoreScale is the fixed point scaling of the stored reputation scores.
+const nodeDBScoreScale = 1000000
+
 // newNodeDB creates a new node database for storing and retrieving infos about
 // known peers in the network. If no path is given, an in-memory, temporary
 // database is constructed.
@@ -300,6 +307,70 @@ func (db *nodeDB) updateFindFails(id NodeID, fails int) error {
 	return db.storeInt64(makeKey(id, nodeDBDiscoverFindFails), int64(fails))
 }
 
+// reputation retrieves the reputation record stored for a node.
+func (db *nodeDB) reputation(id NodeID) []byte {
+	blob, err := db.lvl.Get(makeKey(id, nodeDBReputationRoot), nil)
+	if err != nil {
+		return nil
+	}
+	return blob
+}
+
+// updateReputation stores the reputation record of a node, along with the score
+// it's ordered by when querying the reputable nodes.
+func (db *nodeDB) updateReputation(id NodeID, blob []byte, score float64) error {
+	if err := db.lvl.Put(makeKey(id, nodeDBReputationRoot), blob, nil); err != nil {
+		return err
+	}
+	return db.storeInt64(makeKey(id, nodeDBReputationScore), int64(score*nodeDBScoreScale))
+}
+
+// scoredNode is a node id along with its stored reputation score.
+type scoredNode struct {
+	id    NodeID
+	score int64
+}
+
+// scoredNodes implements sort.Interface, ordering the nodes best first.
+type scoredNodes []scoredNode
+
+func (s scoredNodes) Len() int           { return len(s) }
+func (s scoredNodes) Less(i, j int) bool { return s[i].score > s[j].score }
+func (s scoredNodes) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// queryReputable retrieves the n known nodes with the highest reputation scores
+// of at least min, best first.
+func (db *nodeDB) queryReputable(n int, min float64) []*Node {
+	var (
+		candidates scoredNodes
+		threshold  = int64(min * nodeDBScoreScale)
+		it         = db.lvl.NewIterator(util.BytesPrefix(nodeDBItemPrefix), nil)
+	)
+	for it.Next() {
+		id, field := splitKey(it.Key())
+		if field != nodeDBReputationScore || id == db.self {
+			continue
+		}
+		if score, read := binary.Varint(it.Value()); read > 0 && score >= threshold {
+			candidates = append(candidates, scoredNode{id, score})
+		}
+	}
+	it.Release()
+	sort.Sort(candidates)
+
+	nodes := make([]*Node, 0, n)
+	for _, candidate := range candidates {
+		if len(nodes) >= n {
+			break
+		}
+		// Only nodes with a known endpoint can be dialed
+		if node := db.node(candidate.id); node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
 // querySeeds retrieves random nodes to be used as potential seed nodes
 // for bootstrapping.
 func (db *nodeDB) querySeeds(n int, maxAge time.Duration) []*Node {
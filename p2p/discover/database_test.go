@@ -254,6 +254,43 @@ func TestNodeDBSeedQuery(t *testing.T) {
 	}
 }
 
+func TestNodeDBReputableQuery(t *testing.T) {
+	nodes := nodeDBSeedQueryNodes
+	db, _ := newNodeDB("", Version, nodes[1].node.ID)
+	defer db.close()
+
+	// Score all but the first node, which only has a reputation but no endpoint
+	scores := []float64{0.9, 0.95, 0.7, 0.3, 0.8}
+	for i, seed := range nodes {
+		if i > 0 {
+			if err := db.updateNode(seed.node); err != nil {
+				t.Fatalf("node %d: failed to insert: %v", i, err)
+			}
+		}
+		if err := db.updateReputation(seed.node.ID, []byte{byte(i)}, scores[i]); err != nil {
+			t.Fatalf("node %d: failed to insert reputation: %v", i, err)
+		}
+	}
+	if blob := db.reputation(nodes[3].node.ID); !bytes.Equal(blob, []byte{3}) {
+		t.Errorf("reputation mismatch: have %x, want %x", blob, []byte{3})
+	}
+	// The local node, unknown endpoints and low scores should be left out
+	want := []NodeID{nodes[4].node.ID, nodes[2].node.ID}
+	have := db.queryReputable(len(nodes), 0.6)
+	if len(have) != len(want) {
+		t.Fatalf("reputable count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i, node := range have {
+		if node.ID != want[i] {
+			t.Errorf("reputable %d: id mismatch: have %x, want %x", i, node.ID[:8], want[i][:8])
+		}
+	}
+	// The result count should be limited
+	if have := db.queryReputable(1, 0.6); len(have) != 1 || have[0].ID != want[0] {
+		t.Errorf("limited query mismatch: have %v, want [%x]", have, want[0][:8])
+	}
+}
+
 func TestNodeDBPersistency(t *testing.T) {
 	root, err := ioutil.TempDir("", "nodedb-")
 	if err != nil {
@@ -171,6 +171,24 @@ func randUint(max uint32) uint32 {
 	return binary.BigEndian.Uint32(b[:]) % max
 }
 
+// Reputation retrieves the reputation record of a node from the node database.
+func (tab *Table) Reputation(id NodeID) []byte {
+	return tab.db.reputation(id)
+}
+
+// SetReputation stores the reputation record of a node in the node database,
+// along with the score used to order the nodes returned by ReputableNodes.
+func (tab *Table) SetReputation(id NodeID, blob []byte, score float64) error {
+	return tab.db.updateReputation(id, blob, score)
+}
+
+// ReputableNodes returns up to n previously seen nodes with a reputation score
+// of at least min, best first. The nodes are copies and can be modified by the
+// caller.
+func (tab *Table) ReputableNodes(n int, min float64) []*Node {
+	return tab.db.queryReputable(n, min)
+}
+
 // Close terminates the network listener and flushes the node database.
 func (tab *Table) Close() {
 	select {
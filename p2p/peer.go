@@ -73,6 +73,8 @@ type Peer struct {
 	protoErr chan error
 	closed   chan struct{}
 	disc     chan DiscReason
+
+	rep *Reputation // Service record of the remote node, persisted across reconnects
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -110,6 +112,12 @@ func (p *Peer) LocalAddr() net.Addr {
 	return p.rw.fd.LocalAddr()
 }
 
+// Reputation returns the service record of the remote node, into which the
+// sub-protocols report their measurements.
+func (p *Peer) Reputation() *Reputation {
+	return p.rep
+}
+
 // Disconnect terminates the peer connection with the given reason.
 // It returns immediately and does not wait until the connection is closed.
 func (p *Peer) Disconnect(reason DiscReason) {
@@ -132,6 +140,7 @@ func newPeer(conn *conn, protocols []Protocol) *Peer {
 		disc:     make(chan DiscReason),
 		protoErr: make(chan error, len(protomap)+1), // protocols + pingLoop
 		closed:   make(chan struct{}),
+		rep:      new(Reputation),
 	}
 	return p
 }
@@ -377,7 +386,8 @@ type PeerInfo struct {
 		LocalAddress  string `json:"localAddress"`  // Local endpoint of the TCP data connection
 		RemoteAddress string `json:"remoteAddress"` // Remote endpoint of the TCP data connection
 	} `json:"network"`
-	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Protocols  map[string]interface{} `json:"protocols"`  // Sub-protocol specific metadata fields
+	Reputation *ReputationInfo        `json:"reputation"` // Service record of the remote node
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -389,10 +399,11 @@ func (p *Peer) Info() *PeerInfo {
 	}
 	// Assemble the generic peer metadata
 	info := &PeerInfo{
-		ID:        p.ID().String(),
-		Name:      p.Name(),
-		Caps:      caps,
-		Protocols: make(map[string]interface{}),
+		ID:         p.ID().String(),
+		Name:       p.Name(),
+		Caps:       caps,
+		Protocols:  make(map[string]interface{}),
+		Reputation: p.rep.Info(),
 	}
 	info.Network.LocalAddress = p.LocalAddr().String()
 	info.Network.RemoteAddress = p.RemoteAddr().String()
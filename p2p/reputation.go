@@ -0,0 +1,212 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ur-technology/go-ur/rlp"
+)
+
+const (
+	reputationImpact  = 0.1         // Impact a single measurement has on the smoothed values
+	reputationLatency = time.Second // Latency at which the responsiveness of a node is halved
+	invalidPenalty    = 10          // Number of served requests an invalid response outweighs
+	reputableScore    = 0.75        // Minimum score of a node to be redialled before random ones
+)
+
+// Reputation tracks how well a remote node served the local one. It's loaded
+// from the node database when the node connects and stored back when it
+// disconnects, so it survives reconnects. The sub-protocols report their
+// measurements into it, and its score decides which of the previously seen
+// nodes are redialled first.
+//
+// Measurements are kept per request kind, a protocol defined label grouping
+// requests of similar size and cost.
+type Reputation struct {
+	rec  reputationRecord
+	lock sync.RWMutex
+}
+
+// reputationRecord is the persisted RLP form of a reputation.
+type reputationRecord struct {
+	Latency   uint64           // Smoothed response latency in nanoseconds
+	Responses uint64           // Number of requests served
+	Timeouts  uint64           // Number of requests timed out
+	Invalid   uint64           // Number of invalid or unrequested responses
+	Kinds     []reputationKind // Measurements of the individual request kinds
+}
+
+// reputationKind is the measurements of a single kind of request.
+type reputationKind struct {
+	Name       string
+	Latency    uint64 // Smoothed response latency in nanoseconds
+	Throughput uint64 // Smoothed number of items delivered per thousand seconds
+	Timeouts   uint64 // Number of requests timed out
+}
+
+// ReputationInfo is the reputation summary of a peer reported by admin_peers.
+type ReputationInfo struct {
+	Score        float64            `json:"score"`        // Score of the peer, in the range [0, 1]
+	Latency      string             `json:"latency"`      // Smoothed response latency
+	Responses    uint64             `json:"responses"`    // Number of requests served
+	Timeouts     uint64             `json:"timeouts"`     // Number of requests timed out
+	Invalid      uint64             `json:"invalid"`      // Number of invalid responses
+	Throughput   map[string]float64 `json:"throughput"`   // Items delivered per second, by request kind
+	KindTimeouts map[string]uint64  `json:"kindTimeouts"` // Requests timed out, by request kind
+}
+
+// decodeReputation loads a stored reputation, starting afresh if there's none or
+// it's corrupted.
+func decodeReputation(blob []byte) *Reputation {
+	rep := new(Reputation)
+	if len(blob) > 0 {
+		if err := rlp.DecodeBytes(blob, &rep.rec); err != nil {
+			rep.rec = reputationRecord{}
+		}
+	}
+	return rep
+}
+
+// encode returns the RLP form of the reputation to store.
+func (r *Reputation) encode() ([]byte, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return rlp.EncodeToBytes(&r.rec)
+}
+
+// kind retrieves the measurements of a request kind, creating them if needed.
+//
+// Note, this method expects the lock to be already held.
+func (r *Reputation) kind(name string, create bool) *reputationKind {
+	for i := range r.rec.Kinds {
+		if r.rec.Kinds[i].Name == name {
+			return &r.rec.Kinds[i]
+		}
+	}
+	if !create {
+		return nil
+	}
+	r.rec.Kinds = append(r.rec.Kinds, reputationKind{Name: name})
+	return &r.rec.Kinds[len(r.rec.Kinds)-1]
+}
+
+// smooth integrates a new measurement into a smoothed value, taking the first
+// measurement as is.
+func smooth(value uint64, measured float64) uint64 {
+	if value == 0 {
+		return uint64(measured)
+	}
+	return uint64((1-reputationImpact)*float64(value) + reputationImpact*measured)
+}
+
+// Delivered records a request of the given kind served with items in elapsed.
+func (r *Reputation) Delivered(kind string, items int, elapsed time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.rec.Responses++
+	r.rec.Latency = smooth(r.rec.Latency, float64(elapsed))
+
+	k := r.kind(kind, true)
+	k.Latency = smooth(k.Latency, float64(elapsed))
+	k.Throughput = smooth(k.Throughput, 1000*float64(items)/(float64(elapsed+1)/float64(time.Second)))
+}
+
+// TimedOut records a request of the given kind not served in time.
+func (r *Reputation) TimedOut(kind string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.rec.Timeouts++
+	r.kind(kind, true).Timeouts++
+}
+
+// Misbehaved records an invalid or unrequested response.
+func (r *Reputation) Misbehaved() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.rec.Invalid++
+}
+
+// Latency returns the smoothed response latency measured for the given kind of
+// requests, or for all requests if kind is empty. Zero means no measurements.
+func (r *Reputation) Latency(kind string) time.Duration {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if kind == "" {
+		return time.Duration(r.rec.Latency)
+	}
+	if k := r.kind(kind, false); k != nil {
+		return time.Duration(k.Latency)
+	}
+	return 0
+}
+
+// Throughput returns the smoothed number of items per second delivered for the
+// given kind of requests. Zero means no measurements.
+func (r *Reputation) Throughput(kind string) float64 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if k := r.kind(kind, false); k != nil {
+		return float64(k.Throughput) / 1000
+	}
+	return 0
+}
+
+// Score rates the node in the range [0, 1] by the share of its requests served
+// correctly and its responsiveness. Unknown nodes score 0.5.
+func (r *Reputation) Score() float64 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.score()
+}
+
+// score is the lockless version of Score.
+func (r *Reputation) score() float64 {
+	reliability := float64(r.rec.Responses+1) / float64(r.rec.Responses+r.rec.Timeouts+invalidPenalty*r.rec.Invalid+2)
+	responsiveness := float64(reputationLatency) / float64(reputationLatency+time.Duration(r.rec.Latency))
+
+	return reliability * (1 + responsiveness) / 2
+}
+
+// Info gathers the reputation summary of the node.
+func (r *Reputation) Info() *ReputationInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	info := &ReputationInfo{
+		Score:        r.score(),
+		Latency:      time.Duration(r.rec.Latency).String(),
+		Responses:    r.rec.Responses,
+		Timeouts:     r.rec.Timeouts,
+		Invalid:      r.rec.Invalid,
+		Throughput:   make(map[string]float64),
+		KindTimeouts: make(map[string]uint64),
+	}
+	for _, k := range r.rec.Kinds {
+		info.Throughput[k.Name] = float64(k.Throughput) / 1000
+		info.KindTimeouts[k.Name] = k.Timeouts
+	}
+	return info
+}
@@ -0,0 +1,108 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Tests that reliable and responsive nodes are considered reputable, while slow
+// or misbehaving ones aren't, even if they served plenty of requests.
+func TestReputationScore(t *testing.T) {
+	unknown := new(Reputation)
+	if score := unknown.Score(); score != 0.5 {
+		t.Fatalf("unknown node score mismatch: have %v, want 0.5", score)
+	}
+	good, slow, timeouts, invalid := new(Reputation), new(Reputation), new(Reputation), new(Reputation)
+	for i := 0; i < 100; i++ {
+		good.Delivered("header", 192, 100*time.Millisecond)
+		slow.Delivered("header", 192, 5*time.Second)
+		timeouts.Delivered("header", 192, 100*time.Millisecond)
+		invalid.Delivered("header", 192, 100*time.Millisecond)
+	}
+	for i := 0; i < 50; i++ {
+		timeouts.TimedOut("header")
+	}
+	for i := 0; i < 5; i++ {
+		invalid.Misbehaved()
+	}
+	if score := good.Score(); score < reputableScore {
+		t.Errorf("good node not reputable: score %v", score)
+	}
+	for name, rep := range map[string]*Reputation{"slow": slow, "timing out": timeouts, "misbehaving": invalid} {
+		if score := rep.Score(); score >= reputableScore || score >= good.Score() {
+			t.Errorf("%s node considered reputable: score %v", name, score)
+		}
+	}
+}
+
+// Tests that the measurements are tracked per request kind.
+func TestReputationKinds(t *testing.T) {
+	rep := new(Reputation)
+	for i := 0; i < 10; i++ {
+		rep.Delivered("header", 100, 100*time.Millisecond)
+		rep.Delivered("body", 10, time.Second)
+	}
+	if tput := rep.Throughput("header"); tput < 999 || tput > 1001 {
+		t.Errorf("header throughput mismatch: have %v, want 1000", tput)
+	}
+	if tput := rep.Throughput("body"); tput < 9.9 || tput > 10.1 {
+		t.Errorf("body throughput mismatch: have %v, want 10", tput)
+	}
+	if tput := rep.Throughput("receipt"); tput != 0 {
+		t.Errorf("unmeasured throughput mismatch: have %v, want 0", tput)
+	}
+	if rtt := rep.Latency("header"); rtt < 99*time.Millisecond || rtt > 101*time.Millisecond {
+		t.Errorf("header latency mismatch: have %v, want 100ms", rtt)
+	}
+	if rtt := rep.Latency(""); rtt <= 100*time.Millisecond || rtt >= time.Second {
+		t.Errorf("overall latency out of range: have %v, want between 100ms and 1s", rtt)
+	}
+	rep.TimedOut("body")
+	rep.TimedOut("receipt")
+
+	info := rep.Info()
+	if info.Timeouts != 2 {
+		t.Errorf("overall timeouts mismatch: have %d, want 2", info.Timeouts)
+	}
+	if want := map[string]uint64{"header": 0, "body": 1, "receipt": 1}; !reflect.DeepEqual(info.KindTimeouts, want) {
+		t.Errorf("timeouts mismatch: have %v, want %v", info.KindTimeouts, want)
+	}
+}
+
+// Tests that reputations survive storing and loading them, and that corrupted
+// ones are reset.
+func TestReputationPersistence(t *testing.T) {
+	rep := new(Reputation)
+	rep.Delivered("header", 192, 300*time.Millisecond)
+	rep.Delivered("state", 384, time.Second)
+	rep.TimedOut("state")
+	rep.Misbehaved()
+
+	blob, err := rep.encode()
+	if err != nil {
+		t.Fatalf("failed to encode reputation: %v", err)
+	}
+	if have, want := decodeReputation(blob).Info(), rep.Info(); !reflect.DeepEqual(have, want) {
+		t.Errorf("reputation mismatch after reload:\nhave %+v\nwant %+v", have, want)
+	}
+	if have, want := decodeReputation([]byte{0xff}).Info(), new(Reputation).Info(); !reflect.DeepEqual(have, want) {
+		t.Errorf("corrupted reputation not reset: have %+v, want %+v", have, want)
+	}
+}
@@ -448,6 +448,31 @@ func (srv *Server) run(dialstate dialer) {
 		runningTasks []task
 		queuedTasks  []task // tasks that can't run yet
 	)
+	// Reputations are kept in the node database of the discovery table, or only
+	// for the lifetime of the server if discovery is disabled.
+	reputations := make(map[discover.NodeID]*Reputation)
+	loadReputation := func(id discover.NodeID) *Reputation {
+		if srv.ntab == nil {
+			if rep, ok := reputations[id]; ok {
+				return rep
+			}
+			return new(Reputation)
+		}
+		return decodeReputation(srv.ntab.Reputation(id))
+	}
+	storeReputation := func(p *Peer) {
+		if srv.ntab == nil {
+			reputations[p.ID()] = p.rep
+			return
+		}
+		blob, err := p.rep.encode()
+		if err == nil {
+			err = srv.ntab.SetReputation(p.ID(), blob, p.rep.Score())
+		}
+		if err != nil {
+			glog.V(logger.Debug).Infof("%v: failed to store reputation: %v", p, err)
+		}
+	}
 	// Put trusted nodes into a map to speed up checks.
 	// Trusted peers are loaded on startup and cannot be
 	// modified while the server is running.
@@ -540,6 +565,7 @@ running:
 			} else {
 				// The handshakes are done and it passed all checks.
 				p := newPeer(c, srv.Protocols)
+				p.rep = loadReputation(c.id)
 				peers[c.id] = p
 				go srv.runPeer(p)
 			}
@@ -550,17 +576,11 @@ running:
 		case p := <-srv.delpeer:
 			// A peer disconnected.
 			glog.V(logger.Detail).Infoln("<-delpeer:", p)
+			storeReputation(p)
 			delete(peers, p.ID())
 		}
 	}
 
-	// Terminate discovery. If there is a running lookup it will terminate soon.
-	if srv.ntab != nil {
-		srv.ntab.Close()
-	}
-	if srv.DiscV5 != nil {
-		srv.DiscV5.Close()
-	}
 	// Disconnect all peers.
 	for _, p := range peers {
 		p.Disconnect(DiscQuitting)
@@ -572,8 +592,17 @@ running:
 	for len(peers) > 0 {
 		p := <-srv.delpeer
 		glog.V(logger.Detail).Infoln("<-delpeer (spindown):", p)
+		storeReputation(p)
 		delete(peers, p.ID())
 	}
+	// Terminate discovery, after the reputations of the peers were stored in
+	// its database. If there is a running lookup it will terminate soon.
+	if srv.ntab != nil {
+		srv.ntab.Close()
+	}
+	if srv.DiscV5 != nil {
+		srv.DiscV5.Close()
+	}
 }
 
 func (srv *Server) protoHandshakeChecks(peers map[discover.NodeID]*Peer, c *conn) error {
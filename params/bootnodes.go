@@ -16,31 +16,24 @@
 
 package params
 
-import (
-	"github.com/ur-technology/go-ur/p2p/discover"
-	"github.com/ur-technology/go-ur/p2p/discv5"
-)
-
 // MainnetBootnodes are the enode URLs of the P2P bootstrap nodes running on
-// the main Ethereum network.
-var MainnetBootnodes = []*discover.Node{
-	// ETH/DEV Go Bootnodes
-	discover.MustParseNode("enode://ffd02ea41bb0d73c099fb323a95be937c91fd51488981d76c59c78e0f3d2b0f13d95747888b369f7f99d133c2d6bf7276af802f613380a31411fac8e68ee4286@159.203.44.174:19595"), // IE
-	discover.MustParseNode("enode://f7c9142dbfe23490389f42738998f9e18103d017fe925e3cac0728701023e420f0839e964f5996bfec296280c5aa267632d6a77ca3dddfd58a7befdcf372afc8@138.197.143.33:19595"), // BR
+// the main UR network.
+var MainnetBootnodes = []string{
+	"enode://ffd02ea41bb0d73c099fb323a95be937c91fd51488981d76c59c78e0f3d2b0f13d95747888b369f7f99d133c2d6bf7276af802f613380a31411fac8e68ee4286@159.203.44.174:19595", // IE
+	"enode://f7c9142dbfe23490389f42738998f9e18103d017fe925e3cac0728701023e420f0839e964f5996bfec296280c5aa267632d6a77ca3dddfd58a7befdcf372afc8@138.197.143.33:19595", // BR
 }
 
 // TestnetBootnodes are the enode URLs of the P2P bootstrap nodes running on the
-// Morden test network.
-var TestnetBootnodes = []*discover.Node{
-// ETH/DEV Go Bootnodes
-// discover.MustParseNode("enode://fcf730cf678d6296ffa75a1b2c06aa07d9558788762d0bbefbc209ccbfb4e840f7dcfc2f7a188eb2e65056d989de3722df3fc4df286eb3690d4586992c1c6d82@138.197.138.155:19595"),
-// discover.MustParseNode("enode://d846b3c0445b7a91cfeb56fbeaece55ca9e559a6e5810cc41c54e2b88790fa7a24444508f16eb983630da1367ab73a6db1b705cc36134d9e61a2df070284d3f4@138.197.138.202:19595"),
+// UR test network.
+var TestnetBootnodes = []string{
+	// "enode://fcf730cf678d6296ffa75a1b2c06aa07d9558788762d0bbefbc209ccbfb4e840f7dcfc2f7a188eb2e65056d989de3722df3fc4df286eb3690d4586992c1c6d82@138.197.138.155:19595",
+	// "enode://d846b3c0445b7a91cfeb56fbeaece55ca9e559a6e5810cc41c54e2b88790fa7a24444508f16eb983630da1367ab73a6db1b705cc36134d9e61a2df070284d3f4@138.197.138.202:19595",
 }
 
 // DiscoveryV5Bootnodes are the enode URLs of the P2P bootstrap nodes for the
 // experimental RLPx v5 topic-discovery network.
-var DiscoveryV5Bootnodes = []*discv5.Node{
-// discv5.MustParseNode("enode://0cc5f5ffb5d9098c8b8c62325f3797f56509bff942704687b6530992ac706e2cb946b90a34f1f19548cd3c7baccbcaea354531e5983c7d1bc0dee16ce4b6440b@40.118.3.223:30305"),
-// discv5.MustParseNode("enode://1c7a64d76c0334b0418c004af2f67c50e36a3be60b5e4790bdac0439d21603469a85fad36f2473c9a80eb043ae60936df905fa28f1ff614c3e5dc34f15dcd2dc@40.118.3.223:30308"),
-// discv5.MustParseNode("enode://85c85d7143ae8bb96924f2b54f1b3e70d8c4d367af305325d30a61385a432f247d2c75c45c6b4a60335060d072d7f5b35dd1d4c45f76941f62a4f83b6e75daaf@40.118.3.223:30309"),
+var DiscoveryV5Bootnodes = []string{
+	// "enode://0cc5f5ffb5d9098c8b8c62325f3797f56509bff942704687b6530992ac706e2cb946b90a34f1f19548cd3c7baccbcaea354531e5983c7d1bc0dee16ce4b6440b@40.118.3.223:30305",
+	// "enode://1c7a64d76c0334b0418c004af2f67c50e36a3be60b5e4790bdac0439d21603469a85fad36f2473c9a80eb043ae60936df905fa28f1ff614c3e5dc34f15dcd2dc@40.118.3.223:30308",
+	// "enode://85c85d7143ae8bb96924f2b54f1b3e70d8c4d367af305325d30a61385a432f247d2c75c45c6b4a60335060d072d7f5b35dd1d4c45f76941f62a4f83b6e75daaf@40.118.3.223:30309",
 }
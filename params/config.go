@@ -33,6 +33,7 @@ var MainnetChainConfig = &ChainConfig{
 	EIP150Hash:     MainNetHomesteadGasRepriceHash,
 	EIP155Block:    MainNetSpuriousDragon,
 	EIP158Block:    MainNetSpuriousDragon,
+	Privileged:     MainnetPrivileged,
 }
 
 // TestnetChainConfig is the chain parameters to run a node on the test network.
@@ -45,6 +46,7 @@ var TestnetChainConfig = &ChainConfig{
 	EIP150Hash:     common.HexToHash("0x41941023680923e0fe4d74a34bdac8141f2540e3ae90623718e47d66d1ca4a2d"),
 	EIP155Block:    big.NewInt(10),
 	EIP158Block:    big.NewInt(10),
+	Privileged:     TestnetPrivileged,
 }
 
 // ChainConfig is the core config which determines the blockchain settings.
@@ -65,11 +67,20 @@ type ChainConfig struct {
 
 	EIP155Block *big.Int `json:"eip155Block"` // EIP155 HF block
 	EIP158Block *big.Int `json:"eip158Block"` // EIP158 HF block
+
+	// Precompiles maps the names of precompiled contracts to the block they are
+	// activated at (nil = never). Absent contracts of the Frontier release are
+	// active from the genesis block, other absent ones never.
+	Precompiles map[string]*big.Int `json:"precompiles,omitempty"`
+
+	// Privileged maps the addresses allowed to sign up members to the receivers
+	// of their signup fees (nil = the privileged addresses of the main network).
+	Privileged map[common.Address]PrivilegedReceivers `json:"privileged,omitempty"`
 }
 
 // String implements the Stringer interface.
 func (c *ChainConfig) String() string {
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Precompiles: %v}",
 		c.ChainId,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -77,11 +88,12 @@ func (c *ChainConfig) String() string {
 		c.EIP150Block,
 		c.EIP155Block,
 		c.EIP158Block,
+		c.Precompiles,
 	)
 }
 
 var (
-	TestChainConfig = &ChainConfig{big.NewInt(1), new(big.Int), new(big.Int), true, new(big.Int), common.Hash{}, new(big.Int), new(big.Int)}
+	TestChainConfig = &ChainConfig{big.NewInt(1), new(big.Int), new(big.Int), true, new(big.Int), common.Hash{}, new(big.Int), new(big.Int), nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -135,6 +147,43 @@ func (c *ChainConfig) IsEIP158(num *big.Int) bool {
 
 }
 
+// Names of the precompiled contracts a chain can activate.
+const (
+	EcrecoverPrecompile = "ecrecover" // Frontier: public key recovery of a signature
+	Sha256Precompile    = "sha256"    // Frontier: SHA-256 hash
+	Ripemd160Precompile = "ripemd160" // Frontier: RIPEMD-160 hash
+	IdentityPrecompile  = "identity"  // Frontier: input copy
+	URSignupsPrecompile = "urSignups" // signup chain and network totals of UR members
+)
+
+// FrontierPrecompiles are the precompiled contracts of the Frontier release,
+// active from the genesis block unless configured otherwise.
+var FrontierPrecompiles = []string{EcrecoverPrecompile, Sha256Precompile, Ripemd160Precompile, IdentityPrecompile}
+
+// PrecompileBlock returns the block the precompiled contract with the given name
+// is activated at, or nil if it never is.
+func (c *ChainConfig) PrecompileBlock(name string) *big.Int {
+	if block, ok := c.Precompiles[name]; ok {
+		return block
+	}
+	for _, frontier := range FrontierPrecompiles {
+		if name == frontier {
+			return common.Big0
+		}
+	}
+	return nil
+}
+
+// IsPrecompileActive returns whether the precompiled contract with the given
+// name is activated at block num.
+func (c *ChainConfig) IsPrecompileActive(name string, num *big.Int) bool {
+	block := c.PrecompileBlock(name)
+	if block == nil || num == nil {
+		return false
+	}
+	return num.Cmp(block) >= 0
+}
+
 // Rules wraps ChainConfig and is merely syntatic sugar or can be used for functions
 // that do not have or require information about the block.
 //
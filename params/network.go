@@ -0,0 +1,303 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/p2p/discover"
+	"github.com/ur-technology/go-ur/p2p/discv5"
+)
+
+// Network is a network profile, bundling everything a node needs to join a
+// network: the genesis to seed the chain with, the chain rules (including the
+// privileged addresses signing up members), the network id to select peers
+// with, the nodes to bootstrap from and an optional trusted sync checkpoint.
+type Network struct {
+	Name        string          `json:"name"`                  // Name to select the network with, also used as its data directory
+	NetworkId   int             `json:"networkId"`             // Network ID to use for selecting peers to connect to
+	Genesis     json.RawMessage `json:"genesis"`               // Genesis JSON spec to seed the chain database with
+	GenesisHash common.Hash     `json:"genesisHash"`           // Hash of the genesis block the spec results in (derived if empty)
+	Config      *ChainConfig    `json:"config"`                // Chain rules, including the UR specific ones
+	Bootnodes   []string        `json:"bootnodes"`             // Enode URLs of the discovery v4 bootstrap nodes
+	BootnodesV5 []string        `json:"bootnodesV5,omitempty"` // Enode URLs of the discovery v5 bootstrap nodes
+	Checkpoint  *Checkpoint     `json:"checkpoint,omitempty"`  // Trusted block to sync from instead of the genesis
+}
+
+// Checkpoint is a trusted block of a network that nodes may sync from instead
+// of the genesis block.
+type Checkpoint struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Td     *big.Int    `json:"td"`
+}
+
+var (
+	// MainnetNetwork is the profile of the main UR network.
+	MainnetNetwork = &Network{
+		Name:        "mainnet",
+		NetworkId:   1,
+		Genesis:     json.RawMessage(mainnetGenesis),
+		GenesisHash: common.HexToHash("0xc59e8bbb73a2b3e1153bb485f668ba7d91fe6c7fb849b77237bee8735ab8f044"),
+		Config:      MainnetChainConfig,
+		Bootnodes:   MainnetBootnodes,
+		BootnodesV5: DiscoveryV5Bootnodes,
+	}
+
+	// TestnetNetwork is the profile of the UR test network. It shares its genesis
+	// block with the main network, the two are told apart by their network and
+	// chain ids.
+	TestnetNetwork = &Network{
+		Name:        "testnet",
+		NetworkId:   3,
+		Genesis:     json.RawMessage(mainnetGenesis),
+		GenesisHash: common.HexToHash("0xc59e8bbb73a2b3e1153bb485f668ba7d91fe6c7fb849b77237bee8735ab8f044"),
+		Config:      TestnetChainConfig,
+		Bootnodes:   TestnetBootnodes,
+		BootnodesV5: DiscoveryV5Bootnodes,
+	}
+)
+
+var (
+	networks     = make(map[string]*Network) // Registered network profiles, by name
+	networksLock sync.RWMutex
+
+	networkName = regexp.MustCompile("^[a-z0-9][a-z0-9_-]*$")
+)
+
+func init() {
+	for _, network := range []*Network{MainnetNetwork, TestnetNetwork} {
+		if err := RegisterNetwork(network); err != nil {
+			panic(fmt.Sprintf("invalid built-in network %q: %v", network.Name, err))
+		}
+	}
+}
+
+// RegisterNetwork adds a network profile to the registry, making it selectable
+// by name.
+func RegisterNetwork(network *Network) error {
+	if err := network.Validate(); err != nil {
+		return err
+	}
+	networksLock.Lock()
+	defer networksLock.Unlock()
+
+	if _, ok := networks[network.Name]; ok {
+		return fmt.Errorf("network %q already registered", network.Name)
+	}
+	networks[network.Name] = network
+	return nil
+}
+
+// LookupNetwork retrieves a registered network profile by name.
+func LookupNetwork(name string) (*Network, error) {
+	networksLock.RLock()
+	defer networksLock.RUnlock()
+
+	if network, ok := networks[name]; ok {
+		return network, nil
+	}
+	return nil, fmt.Errorf("unknown network %q (known: %s)", name, strings.Join(networkNames(), ", "))
+}
+
+// NetworkNames returns the sorted names of all registered network profiles.
+func NetworkNames() []string {
+	networksLock.RLock()
+	defer networksLock.RUnlock()
+
+	return networkNames()
+}
+
+// networkNames is the lockless version of NetworkNames.
+func networkNames() []string {
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadNetwork reads a network profile from a JSON file, for running a private
+// network. If the profile doesn't specify the chain rules, the ones embedded in
+// its genesis spec are used.
+func LoadNetwork(path string) (*Network, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	network := new(Network)
+	if err := json.Unmarshal(blob, network); err != nil {
+		return nil, fmt.Errorf("invalid network profile %s: %v", path, err)
+	}
+	if network.Config == nil && len(network.Genesis) > 0 {
+		var genesis struct {
+			Config *ChainConfig `json:"config"`
+		}
+		if err := json.Unmarshal(network.Genesis, &genesis); err != nil {
+			return nil, fmt.Errorf("invalid genesis in network profile %s: %v", path, err)
+		}
+		network.Config = genesis.Config
+	}
+	if err := network.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid network profile %s: %v", path, err)
+	}
+	return network, nil
+}
+
+// Validate checks that the profile is complete and its bootstrap nodes are well
+// formed.
+func (n *Network) Validate() error {
+	switch {
+	case !networkName.MatchString(n.Name):
+		return fmt.Errorf("invalid name %q, want lowercase letters, digits, '-' and '_'", n.Name)
+	case n.NetworkId <= 0:
+		return fmt.Errorf("invalid network id %d", n.NetworkId)
+	case len(n.Genesis) == 0:
+		return errors.New("missing genesis")
+	case n.Config == nil:
+		return errors.New("missing chain config")
+	case n.Config.ChainId == nil || n.Config.ChainId.Sign() <= 0:
+		return errors.New("missing chain id")
+	}
+	for addr, recv := range n.Config.Privileged {
+		if recv.Receiver == (common.Address{}) || recv.URFF == (common.Address{}) {
+			return fmt.Errorf("missing signup receivers of privileged address %x", addr)
+		}
+	}
+	if n.Checkpoint != nil && (n.Checkpoint.Hash == (common.Hash{}) || n.Checkpoint.Td == nil) {
+		return errors.New("incomplete checkpoint")
+	}
+	if _, err := n.BootstrapNodes(); err != nil {
+		return err
+	}
+	if _, err := n.BootstrapNodesV5(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// BootstrapNodes parses the discovery v4 bootstrap nodes of the network.
+func (n *Network) BootstrapNodes() ([]*discover.Node, error) {
+	nodes := make([]*discover.Node, 0, len(n.Bootnodes))
+	for _, url := range n.Bootnodes {
+		node, err := discover.ParseNode(url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bootnode %q: %v", url, err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// BootstrapNodesV5 parses the discovery v5 bootstrap nodes of the network.
+func (n *Network) BootstrapNodesV5() ([]*discv5.Node, error) {
+	nodes := make([]*discv5.Node, 0, len(n.BootnodesV5))
+	for _, url := range n.BootnodesV5 {
+		node, err := discv5.ParseNode(url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid v5 bootnode %q: %v", url, err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// CheckCompatible verifies that a chain database, described by its genesis hash,
+// the chain rules stored along it and the number of its head block, belongs to
+// the network. Rules not yet in effect at the head may differ, allowing upgrades
+// scheduling new forks; a nil stored config is only checked by genesis. The
+// privileged signup addresses must always match, as they decide the rewards of
+// every signup.
+func (n *Network) CheckCompatible(genesis common.Hash, stored *ChainConfig, head uint64) error {
+	if genesis != n.GenesisHash {
+		return fmt.Errorf("genesis mismatch: database has %x, network %q has %x", genesis, n.Name, n.GenesisHash)
+	}
+	if stored == nil {
+		return nil
+	}
+	if stored.ChainId != nil && stored.ChainId.Sign() > 0 && stored.ChainId.Cmp(n.Config.ChainId) != 0 {
+		return fmt.Errorf("chain id mismatch: database has %v, network %q has %v", stored.ChainId, n.Name, n.Config.ChainId)
+	}
+	if !reflect.DeepEqual(stored.privileged(), n.Config.privileged()) {
+		return fmt.Errorf("privileged addresses mismatch: database has %x, network %q has %x", stored.PrivilegedAddresses(), n.Name, n.Config.PrivilegedAddresses())
+	}
+	number := new(big.Int).SetUint64(head)
+	forks := []forkBlocks{
+		{"homestead", stored.HomesteadBlock, n.Config.HomesteadBlock},
+		{"DAO", stored.DAOForkBlock, n.Config.DAOForkBlock},
+		{"EIP150", stored.EIP150Block, n.Config.EIP150Block},
+		{"EIP155", stored.EIP155Block, n.Config.EIP155Block},
+		{"EIP158", stored.EIP158Block, n.Config.EIP158Block},
+	}
+	for _, name := range precompileNames(stored, n.Config) {
+		forks = append(forks, forkBlocks{name + " precompile", stored.PrecompileBlock(name), n.Config.PrecompileBlock(name)})
+	}
+	for _, fork := range forks {
+		if forkIncompatible(fork.stored, fork.local, number) {
+			return fmt.Errorf("%s fork mismatch: database has block %v, network %q has %v, head at #%d", fork.name, fork.stored, n.Name, fork.local, head)
+		}
+	}
+	return nil
+}
+
+// precompileNames returns the sorted names of the precompiled contracts known
+// to any of the given chain rules.
+func precompileNames(configs ...*ChainConfig) []string {
+	set := make(map[string]bool)
+	for _, name := range FrontierPrecompiles {
+		set[name] = true
+	}
+	for _, config := range configs {
+		for name := range config.Precompiles {
+			set[name] = true
+		}
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// forkBlocks is a fork switch block of the stored and the network chain rules.
+type forkBlocks struct {
+	name          string
+	stored, local *big.Int
+}
+
+// forkIncompatible checks whether two fork blocks differ in a way that affects
+// the chain up to head, i.e. whether either fork already passed and they don't
+// agree.
+func forkIncompatible(stored, local, head *big.Int) bool {
+	passed := func(block *big.Int) bool { return block != nil && block.Cmp(head) <= 0 }
+	if !passed(stored) && !passed(local) {
+		return false
+	}
+	return stored == nil || local == nil || stored.Cmp(local) != 0
+}
@@ -0,0 +1,191 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+)
+
+// Tests that the built-in networks are registered and unknown ones rejected.
+func TestNetworkRegistry(t *testing.T) {
+	if names := NetworkNames(); !reflect.DeepEqual(names, []string{"mainnet", "testnet"}) {
+		t.Fatalf("registered networks mismatch: have %v, want [mainnet testnet]", names)
+	}
+	if network, err := LookupNetwork("testnet"); err != nil || network != TestnetNetwork {
+		t.Fatalf("testnet lookup mismatch: have %v, %v", network, err)
+	}
+	if _, err := LookupNetwork("nonexistent"); err == nil {
+		t.Fatalf("unknown network found")
+	}
+	if err := RegisterNetwork(MainnetNetwork); err == nil {
+		t.Fatalf("duplicate network registered")
+	}
+}
+
+// Tests that private network profiles can be loaded from JSON files, taking the
+// chain rules from the genesis if needed, and that invalid ones are rejected.
+func TestLoadNetwork(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	privileged := common.HexToAddress("0x5d32e21bf3594aa66c205fde8dbee3dc726bd61d")
+
+	tests := []struct {
+		profile    string
+		fail       bool
+		chainId    int64
+		privileged bool // Whether the private privileged address may sign up members
+	}{
+		// Complete profile with explicit rules
+		{profile: `{"name": "private", "networkId": 1337, "genesis": {"Difficulty": "0x400"}, "config": {"chainId": 7}}`, chainId: 7},
+		// Rules taken from the genesis spec
+		{profile: `{"name": "private", "networkId": 1337, "genesis": {"config": {"chainId": 8}, "Difficulty": "0x400"}}`, chainId: 8},
+		// Privileged addresses of the network, in the profile or the genesis rules
+		{profile: `{"name": "private", "networkId": 1337, "genesis": {}, "config": {"chainId": 7, "privileged": {"0x5d32e21bf3594aa66c205fde8dbee3dc726bd61d": {"receiver": "0x59ab9bb134b529709333f7ae68f3f93c204d280b", "urff": "0x46c0b8e0e95a772ad8764d3190a34cd4a60c7a98"}}}}`, chainId: 7, privileged: true},
+		{profile: `{"name": "private", "networkId": 1337, "genesis": {"config": {"chainId": 8, "privileged": {"0x5d32e21bf3594aa66c205fde8dbee3dc726bd61d": {"receiver": "0x59ab9bb134b529709333f7ae68f3f93c204d280b", "urff": "0x46c0b8e0e95a772ad8764d3190a34cd4a60c7a98"}}}}}`, chainId: 8, privileged: true},
+		// Invalid and incomplete profiles
+		{profile: `{"name": "private"`, fail: true},
+		{profile: `{"name": "Private", "networkId": 1337, "genesis": {}, "config": {"chainId": 7}}`, fail: true},
+		{profile: `{"name": "private", "genesis": {}, "config": {"chainId": 7}}`, fail: true},
+		{profile: `{"name": "private", "networkId": 1337, "config": {"chainId": 7}}`, fail: true},
+		{profile: `{"name": "private", "networkId": 1337, "genesis": {}}`, fail: true},
+		{profile: `{"name": "private", "networkId": 1337, "genesis": {}, "config": {"chainId": 7}, "bootnodes": ["enode://invalid"]}`, fail: true},
+		{profile: `{"name": "private", "networkId": 1337, "genesis": {}, "config": {"chainId": 7}, "checkpoint": {"number": 1}}`, fail: true},
+		{profile: `{"name": "private", "networkId": 1337, "genesis": {}, "config": {"chainId": 7, "privileged": {"0x5d32e21bf3594aa66c205fde8dbee3dc726bd61d": {"receiver": "0x59ab9bb134b529709333f7ae68f3f93c204d280b"}}}}`, fail: true},
+	}
+	for i, tt := range tests {
+		path := filepath.Join(dir, "network.json")
+		if err := ioutil.WriteFile(path, []byte(tt.profile), 0600); err != nil {
+			t.Fatal(err)
+		}
+		network, err := LoadNetwork(path)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("test %d: invalid profile loaded", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to load profile: %v", i, err)
+			continue
+		}
+		if network.Config.ChainId.Int64() != tt.chainId {
+			t.Errorf("test %d: chain id mismatch: have %v, want %d", i, network.Config.ChainId, tt.chainId)
+		}
+		if network.Config.IsPrivileged(privileged) != tt.privileged {
+			t.Errorf("test %d: privileged mismatch: have %v, want %v", i, !tt.privileged, tt.privileged)
+		}
+		if tt.privileged {
+			recv, _ := network.Config.SignupReceivers(privileged)
+			if recv.Receiver != common.HexToAddress("0x59ab9bb134b529709333f7ae68f3f93c204d280b") || recv.URFF != common.HexToAddress("0x46c0b8e0e95a772ad8764d3190a34cd4a60c7a98") {
+				t.Errorf("test %d: signup receivers mismatch: have %x", i, recv)
+			}
+			if network.Config.IsPrivileged(MainnetChainConfig.PrivilegedAddresses()[0]) {
+				t.Errorf("test %d: main network address privileged", i)
+			}
+		}
+	}
+}
+
+// Tests that chain rules without privileged addresses fall back to the ones of
+// the main network, while an explicit empty set disables signups.
+func TestPrivilegedFallback(t *testing.T) {
+	addrs := MainnetChainConfig.PrivilegedAddresses()
+	if len(addrs) != len(MainnetPrivileged) {
+		t.Fatalf("main network privileged count mismatch: have %d, want %d", len(addrs), len(MainnetPrivileged))
+	}
+	if !new(ChainConfig).IsPrivileged(addrs[0]) {
+		t.Errorf("main network address not privileged without explicit set")
+	}
+	empty := &ChainConfig{Privileged: map[common.Address]PrivilegedReceivers{}}
+	if empty.IsPrivileged(addrs[0]) || len(empty.PrivilegedAddresses()) != 0 {
+		t.Errorf("main network address privileged with empty set")
+	}
+}
+
+// Tests that databases are checked against a network, tolerating differences of
+// the rules not yet in effect.
+func TestNetworkCompatibility(t *testing.T) {
+	network := &Network{
+		Name:        "private",
+		GenesisHash: common.Hash{1},
+		Config: &ChainConfig{
+			ChainId:        big.NewInt(7),
+			HomesteadBlock: big.NewInt(100),
+			EIP155Block:    big.NewInt(200),
+			Precompiles:    map[string]*big.Int{URSignupsPrecompile: big.NewInt(300)},
+		},
+	}
+	privileged := map[common.Address]PrivilegedReceivers{
+		common.HexToAddress("0x0000000000000000000000000000000000000001"): {
+			Receiver: common.HexToAddress("0x0000000000000000000000000000000000000002"),
+			URFF:     common.HexToAddress("0x0000000000000000000000000000000000000003"),
+		},
+	}
+	stored := func(mutate func(config *ChainConfig)) *ChainConfig {
+		config := *network.Config
+		config.Precompiles = map[string]*big.Int{URSignupsPrecompile: big.NewInt(300)}
+		mutate(&config)
+		return &config
+	}
+	tests := []struct {
+		genesis common.Hash
+		stored  *ChainConfig
+		head    uint64
+		fail    bool
+	}{
+		{genesis: common.Hash{1}, stored: nil, head: 1000},
+		{genesis: common.Hash{2}, stored: nil, head: 0, fail: true},
+		{genesis: common.Hash{1}, stored: stored(func(*ChainConfig) {}), head: 1000},
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.ChainId = big.NewInt(8) }), head: 0, fail: true},
+
+		// Privileged addresses must match from the start, defaulting to the main network's
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.Privileged = MainnetPrivileged }), head: 1000},
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.Privileged = privileged }), head: 0, fail: true},
+
+		// Rescheduling or adding forks is fine while the head is before them
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.EIP155Block = big.NewInt(250) }), head: 150},
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.EIP155Block = big.NewInt(250) }), head: 200, fail: true},
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.EIP155Block = nil }), head: 199},
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.EIP155Block = nil }), head: 200, fail: true},
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.Precompiles = nil }), head: 299},
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.Precompiles = nil }), head: 300, fail: true},
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.Precompiles["other"] = big.NewInt(50) }), head: 49},
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.Precompiles["other"] = big.NewInt(50) }), head: 50, fail: true},
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.Precompiles[EcrecoverPrecompile] = common.Big0 }), head: 1000},
+		{genesis: common.Hash{1}, stored: stored(func(c *ChainConfig) { c.Precompiles[EcrecoverPrecompile] = nil }), head: 0, fail: true},
+	}
+	for i, tt := range tests {
+		err := network.CheckCompatible(tt.genesis, tt.stored, tt.head)
+		if tt.fail && err == nil {
+			t.Errorf("test %d: incompatible database accepted", i)
+		}
+		if !tt.fail && err != nil {
+			t.Errorf("test %d: compatible database rejected: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ur-technology/go-ur/common"
+)
+
+// PrivilegedReceivers are the accounts credited with the fees of the signups
+// sent by a privileged address.
+type PrivilegedReceivers struct {
+	Receiver common.Address `json:"receiver"` // Receiver of the management fee and the unclaimed referral rewards
+	URFF     common.Address `json:"urff"`     // UR Future Fund account
+}
+
+// MainnetPrivileged are the addresses allowed to sign up members on the main
+// network, along with the receivers of their signup fees.
+var MainnetPrivileged = map[common.Address]PrivilegedReceivers{
+	common.HexToAddress("0x482cf297b08d4523c97ec3a54e80d2d07acd76fa"): {
+		Receiver: common.HexToAddress("0x59ab9bb134b529709333f7ae68f3f93c204d280b"),
+		URFF:     common.HexToAddress("0x46c0b8e0e95a772ad8764d3190a34cd4a60c7a98"),
+	},
+	common.HexToAddress("0xcc74e28cec33a784c5cd40e14836dd212a937045"): {
+		Receiver: common.HexToAddress("0x0ec37d90610b7665517a2d813dc85a7f83852aee"),
+		URFF:     common.HexToAddress("0xac5fbbd56b1d6a31ad722de419433eeb5b9a9fc4"),
+	},
+	common.HexToAddress("0xc07a55758f896449805bae3851f57e25bb7ee7ef"): {
+		Receiver: common.HexToAddress("0x78021bd6fb0f0353bb49e2cc63a8aea051c902ca"),
+		URFF:     common.HexToAddress("0x57b1f656e88fc66e8fe1cf0eb65ce045004777f4"),
+	},
+	common.HexToAddress("0x48a24dd26a32564e2697f25fc8605700ec4c0337"): {
+		Receiver: common.HexToAddress("0xb8c4f8e04d3341690cfb9ebc11246bd8806884ce"),
+		URFF:     common.HexToAddress("0xb0e314f5b39a1c71de5dbc86c3e9b22251a6d394"),
+	},
+	common.HexToAddress("0x3cac5f7909f9cb666cc4d7ef32047b170e454b16"): {
+		Receiver: common.HexToAddress("0x85b44964bb0d83fa1329dc969d853d710fde339e"),
+		URFF:     common.HexToAddress("0xe5780543d87f8b8921e65789ba3c7eb69aba21c7"),
+	},
+	common.HexToAddress("0x0827d93936df936134dd7b7acaeaea04344b11f2"): {
+		Receiver: common.HexToAddress("0x5dc1a06fa3717b6084c4e19395ab1651185b6477"),
+		URFF:     common.HexToAddress("0x7c4da38909148d56b8e6cc37922e992c2a0a1063"),
+	},
+	common.HexToAddress("0xa63e936e0eb36c103f665d53bd7ca9c31ec7e1ad"): {
+		Receiver: common.HexToAddress("0x53372c0fce8ce636ac77cf502c51d5f15868dc64"),
+		URFF:     common.HexToAddress("0x4e2c9b2b57fd17a45d28fb4a6d42e932468afaee"),
+	},
+}
+
+// TestnetPrivileged are the addresses allowed to sign up members on the test
+// network. It deliberately shares the set of the main network, so the signup
+// tooling runs unchanged against both; diverging it is a consensus change of
+// the test network.
+var TestnetPrivileged = MainnetPrivileged
+
+// privileged returns the privileged addresses of the chain, falling back to the
+// ones of the main network for configs that don't specify any (e.g. stored
+// before they were configurable).
+func (c *ChainConfig) privileged() map[common.Address]PrivilegedReceivers {
+	if c.Privileged == nil {
+		return MainnetPrivileged
+	}
+	return c.Privileged
+}
+
+// IsPrivileged returns whether addr is allowed to sign up members.
+func (c *ChainConfig) IsPrivileged(addr common.Address) bool {
+	_, ok := c.privileged()[addr]
+	return ok
+}
+
+// PrivilegedAddresses returns the addresses allowed to sign up members, sorted.
+func (c *ChainConfig) PrivilegedAddresses() []common.Address {
+	addrs := make([]common.Address, 0, len(c.privileged()))
+	for addr := range c.privileged() {
+		addrs = append(addrs, addr)
+	}
+	sort.Sort(addressesAscending(addrs))
+	return addrs
+}
+
+// addressesAscending sorts addresses by their bytes.
+type addressesAscending []common.Address
+
+func (a addressesAscending) Len() int           { return len(a) }
+func (a addressesAscending) Less(i, j int) bool { return bytes.Compare(a[i][:], a[j][:]) < 0 }
+func (a addressesAscending) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// SignupReceivers returns the receivers of the signup fees of a privileged
+// address, or false if the address isn't privileged.
+func (c *ChainConfig) SignupReceivers(addr common.Address) (PrivilegedReceivers, bool) {
+	recv, ok := c.privileged()[addr]
+	return recv, ok
+}
@@ -0,0 +1,289 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// jwtSecretLength is the required length of the shared HS256 secret.
+	jwtSecretLength = 32
+
+	// jwtClockSkew is the tolerated difference between the clocks of the token
+	// issuer and the node when checking the validity period of a token.
+	jwtClockSkew = 60 * time.Second
+)
+
+var (
+	errMissingToken = errors.New("missing bearer token")
+	errMalformedJWT = errors.New("malformed token")
+	errJWTAlgorithm = errors.New("unsupported token algorithm, only HS256 is accepted")
+	errJWTSignature = errors.New("invalid token signature")
+	errJWTExpired   = errors.New("token expired")
+	errJWTNotYet    = errors.New("token not valid yet")
+)
+
+// AccessPolicy decides which methods may be invoked through an RPC connection.
+// Methods are matched by their full name (e.g. "personal_unlockAccount") or by
+// namespace wildcards (e.g. "personal_*"). Subscriptions are matched as the
+// "subscribe" method of their namespace (e.g. "eth_subscribe").
+type AccessPolicy struct {
+	Namespaces []string // Namespaces callable through the connection (empty = all)
+	Allow      []string // Method allowlist, if set only these methods are callable
+	Deny       []string // Method denylist, takes precedence over everything else
+}
+
+// Permits returns whether the policy allows calling the given method. A nil
+// policy permits everything.
+func (p *AccessPolicy) Permits(service, method string) bool {
+	if p == nil {
+		return true
+	}
+	name := service + serviceMethodSeparator + method
+	if matchMethod(p.Deny, name) {
+		return false
+	}
+	if len(p.Allow) > 0 && !matchMethod(p.Allow, name) {
+		return false
+	}
+	if len(p.Namespaces) > 0 {
+		for _, namespace := range p.Namespaces {
+			if namespace == service {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// matchMethod reports whether the fully qualified method name is contained in
+// the list of method names and namespace wildcards.
+func matchMethod(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if pattern == name || pattern == "*" {
+			return true
+		}
+		if strings.HasSuffix(pattern, serviceMethodSeparator+"*") && strings.HasPrefix(name, pattern[:len(pattern)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// jwtClaims are the token claims understood by the authenticator. Besides the
+// registered validity claims, a token may restrict its bearer to a subset of
+// the namespaces and methods exposed on the endpoint.
+type jwtClaims struct {
+	IssuedAt   *int64   `json:"iat"`
+	NotBefore  *int64   `json:"nbf"`
+	Expiry     *int64   `json:"exp"`
+	Namespaces []string `json:"namespaces"`
+	Methods    []string `json:"methods"`
+}
+
+// Authenticator validates JWT bearer tokens signed with a shared HS256 secret
+// and enforces the access policy of the endpoint and of the individual tokens.
+// An authenticator without a secret accepts unauthenticated requests and only
+// enforces the endpoint policy.
+type Authenticator struct {
+	secret []byte
+	policy *AccessPolicy
+	now    func() time.Time
+}
+
+// NewAuthenticator creates an authenticator validating tokens against secret
+// (nil to disable token authentication) and restricting all the calls to the
+// given endpoint policy (nil to allow everything).
+func NewAuthenticator(secret []byte, policy *AccessPolicy) *Authenticator {
+	return &Authenticator{secret: secret, policy: policy, now: time.Now}
+}
+
+// LoadJWTSecret reads a hex encoded 32 byte HS256 secret from a file.
+func LoadJWTSecret(file string) ([]byte, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSpace(string(blob))
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		text = text[2:]
+	}
+	secret, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT secret: %v", err)
+	}
+	if len(secret) != jwtSecretLength {
+		return nil, fmt.Errorf("invalid JWT secret length: have %d bytes, want %d", len(secret), jwtSecretLength)
+	}
+	return secret, nil
+}
+
+// Authenticate checks the bearer token of an HTTP request, returning the access
+// policies the calls made with the request have to satisfy.
+func (a *Authenticator) Authenticate(r *http.Request) ([]*AccessPolicy, error) {
+	if a.secret == nil {
+		return []*AccessPolicy{a.policy}, nil
+	}
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return nil, errMissingToken
+	}
+	claims, err := a.verify(strings.TrimSpace(header[7:]))
+	if err != nil {
+		return nil, err
+	}
+	token := &AccessPolicy{Namespaces: claims.Namespaces, Allow: claims.Methods}
+	return []*AccessPolicy{a.policy, token}, nil
+}
+
+// verify checks the signature and validity period of an HS256 token and returns
+// its claims.
+func (a *Authenticator) verify(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errMalformedJWT
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "HS256" {
+		return nil, errJWTAlgorithm
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errMalformedJWT
+	}
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errJWTSignature
+	}
+	claims := new(jwtClaims)
+	if err := decodeJWTSegment(parts[1], claims); err != nil {
+		return nil, err
+	}
+	now := a.now()
+	if claims.Expiry != nil && now.After(time.Unix(*claims.Expiry, 0).Add(jwtClockSkew)) {
+		return nil, errJWTExpired
+	}
+	if claims.NotBefore != nil && now.Before(time.Unix(*claims.NotBefore, 0).Add(-jwtClockSkew)) {
+		return nil, errJWTNotYet
+	}
+	if claims.IssuedAt != nil && now.Before(time.Unix(*claims.IssuedAt, 0).Add(-jwtClockSkew)) {
+		return nil, errJWTNotYet
+	}
+	return claims, nil
+}
+
+// decodeJWTSegment decodes a base64url encoded JSON segment of a token.
+func decodeJWTSegment(segment string, v interface{}) error {
+	blob, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errMalformedJWT
+	}
+	if err := json.Unmarshal(blob, v); err != nil {
+		return errMalformedJWT
+	}
+	return nil
+}
+
+// NewJWT creates an HS256 signed token with the given validity and access
+// restrictions (nil for unrestricted), mostly useful for clients and tests.
+func NewJWT(secret []byte, issued time.Time, expiry time.Duration, namespaces, methods []string) string {
+	iat := issued.Unix()
+	claims := jwtClaims{IssuedAt: &iat, Namespaces: namespaces, Methods: methods}
+	if expiry > 0 {
+		exp := issued.Add(expiry).Unix()
+		claims.Expiry = &exp
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// accessCodec wraps a server codec, rejecting the requests not permitted by all
+// of the access policies of the connection.
+type accessCodec struct {
+	ServerCodec
+	policies []*AccessPolicy
+}
+
+// ReadRequestHeaders reads the next requests from the wrapped codec and marks
+// the ones calling disallowed methods as failed.
+func (c *accessCodec) ReadRequestHeaders() ([]rpcRequest, bool, Error) {
+	reqs, batch, err := c.ServerCodec.ReadRequestHeaders()
+	if err != nil {
+		return reqs, batch, err
+	}
+	for i := range reqs {
+		r := &reqs[i]
+		if r.err != nil || (r.isPubSub && r.method == unsubscribeMethod) {
+			continue
+		}
+		method := r.method
+		if r.isPubSub {
+			method = "subscribe"
+		}
+		for _, policy := range c.policies {
+			if !policy.Permits(r.service, method) {
+				r.err = &methodNotAllowedError{r.service, method}
+				break
+			}
+		}
+	}
+	return reqs, batch, nil
+}
+
+// withAccess wraps a codec to enforce the given policies, if any restrict it.
+func withAccess(codec ServerCodec, policies []*AccessPolicy) ServerCodec {
+	for _, policy := range policies {
+		if policy != nil {
+			return &accessCodec{codec, policies}
+		}
+	}
+	return codec
+}
+
+// writeUnauthorized rejects an HTTP request failing authentication with a
+// JSON-RPC error response.
+func writeUnauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("content-type", "application/json")
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	w.WriteHeader(http.StatusUnauthorized)
+
+	codec := NewJSONCodec(&httpReadWriteNopCloser{strings.NewReader(""), w})
+	codec.Write(codec.CreateErrorResponse(nil, &unauthorizedError{err.Error()}))
+}
@@ -0,0 +1,232 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+var testJWTSecret = bytes.Repeat([]byte{0x42}, jwtSecretLength)
+
+func TestAccessPolicy(t *testing.T) {
+	policy := &AccessPolicy{
+		Namespaces: []string{"eth", "personal"},
+		Allow:      []string{"eth_*", "personal_listAccounts"},
+		Deny:       []string{"eth_sendTransaction"},
+	}
+	tests := []struct {
+		service, method string
+		allowed         bool
+	}{
+		{"eth", "blockNumber", true},
+		{"eth", "sendTransaction", false},
+		{"eth", "subscribe", true},
+		{"personal", "listAccounts", true},
+		{"personal", "unlockAccount", false},
+		{"admin", "peers", false},
+		{"ethx", "foo", false},
+	}
+	for i, tt := range tests {
+		if allowed := policy.Permits(tt.service, tt.method); allowed != tt.allowed {
+			t.Errorf("test %d: %s_%s permission mismatch: have %v, want %v", i, tt.service, tt.method, allowed, tt.allowed)
+		}
+	}
+	var unrestricted *AccessPolicy
+	if !unrestricted.Permits("admin", "peers") {
+		t.Errorf("nil policy denied access")
+	}
+}
+
+func TestJWTVerification(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	auth := NewAuthenticator(testJWTSecret, nil)
+	auth.now = func() time.Time { return now }
+
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte(`{}`)) + "."
+	tests := []struct {
+		token string
+		err   error
+	}{
+		{NewJWT(testJWTSecret, now, time.Minute, nil, nil), nil},
+		{NewJWT(testJWTSecret, now.Add(-time.Hour), 0, nil, nil), nil},
+		{NewJWT(testJWTSecret, now.Add(-time.Hour), time.Minute, nil, nil), errJWTExpired},
+		{NewJWT(testJWTSecret, now.Add(time.Hour), 0, nil, nil), errJWTNotYet},
+		{NewJWT(bytes.Repeat([]byte{0x01}, jwtSecretLength), now, 0, nil, nil), errJWTSignature},
+		{unsigned, errJWTAlgorithm},
+		{"not-a-token", errMalformedJWT},
+	}
+	for i, tt := range tests {
+		if _, err := auth.verify(tt.token); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that the HTTP handler rejects unauthenticated requests and calls not
+// allowed by the token or endpoint policies with the proper error codes.
+func TestAuthHTTP(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+
+	auth := NewAuthenticator(testJWTSecret, &AccessPolicy{Deny: []string{"service_rets"}})
+	hs := httptest.NewServer(server.AuthHandler(auth))
+	defer hs.Close()
+
+	restricted := NewJWT(testJWTSecret, time.Now(), time.Minute, nil, []string{"service_echo", "service_rets"})
+	tests := []struct {
+		token  string
+		method string
+		status int
+		code   int
+	}{
+		{"", "service_echo", http.StatusUnauthorized, -32001},
+		{NewJWT([]byte("wrong"), time.Now(), time.Minute, nil, nil), "service_echo", http.StatusUnauthorized, -32001},
+		{restricted, "service_echo", http.StatusOK, 0},
+		{restricted, "service_noArgsRets", http.StatusOK, -32002},
+		{restricted, "service_rets", http.StatusOK, -32002},
+		{NewJWT(testJWTSecret, time.Now(), time.Minute, []string{"other"}, nil), "service_echo", http.StatusOK, -32002},
+	}
+	for i, tt := range tests {
+		body := `{"jsonrpc":"2.0","id":1,"method":"` + tt.method + `","params":["a",1,{}]}`
+		req, _ := http.NewRequest("POST", hs.URL, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("test %d: request failed: %v", i, err)
+		}
+		var msg jsonrpcMessage
+		err = json.NewDecoder(resp.Body).Decode(&msg)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("test %d: invalid response: %v", i, err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, resp.StatusCode, tt.status)
+		}
+		switch {
+		case tt.code == 0 && msg.Error != nil:
+			t.Errorf("test %d: unexpected error: %v", i, msg.Error)
+		case tt.code != 0 && msg.Error == nil:
+			t.Errorf("test %d: call succeeded, want error %d", i, tt.code)
+		case tt.code != 0 && msg.Error.Code != tt.code:
+			t.Errorf("test %d: error code mismatch: have %d, want %d", i, msg.Error.Code, tt.code)
+		}
+	}
+}
+
+// Tests that plain HTTP handlers served next to the RPC server are subject to
+// the authentication, access policies and limits of the server.
+func TestGuardHandler(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+	server.SetLimits(Limits{
+		MaxRequestSize: 64,
+		MethodRates:    map[string]Rate{"eth_graphql": {PerSecond: 0.001, Burst: 3}},
+	})
+	plain := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	})
+	auth := NewAuthenticator(testJWTSecret, nil)
+	hs := httptest.NewServer(server.GuardHandler(plain, auth, "eth_graphql"))
+	defer hs.Close()
+
+	var (
+		allowed = NewJWT(testJWTSecret, time.Now(), time.Minute, []string{"eth"}, nil)
+		denied  = NewJWT(testJWTSecret, time.Now(), time.Minute, []string{"personal"}, nil)
+		small   = "{}"
+		large   = strings.Repeat("x", 65)
+	)
+	tests := []struct {
+		token  string
+		body   string
+		status int
+	}{
+		{"", small, http.StatusUnauthorized},
+		{denied, small, http.StatusForbidden},
+		{allowed, small, http.StatusOK},
+		{allowed, large, http.StatusRequestEntityTooLarge},
+		{allowed, small, http.StatusOK},
+		{allowed, small, http.StatusTooManyRequests}, // method bucket exhausted
+	}
+	for i, tt := range tests {
+		req, _ := http.NewRequest("POST", hs.URL, strings.NewReader(tt.body))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("test %d: request failed: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+// Tests that websocket connections are authenticated during the handshake and
+// restricted by the policy of their token.
+func TestAuthWebsocket(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+
+	hs := httptest.NewServer(server.AuthWebsocketHandler("*", NewAuthenticator(testJWTSecret, nil)))
+	defer hs.Close()
+	endpoint := "ws" + strings.TrimPrefix(hs.URL, "http")
+
+	config, _ := websocket.NewConfig(endpoint, "http://localhost")
+	if _, err := websocket.DialConfig(config); err == nil {
+		t.Fatalf("unauthenticated connection accepted")
+	}
+	config.Header = http.Header{"Authorization": {"Bearer " + NewJWT(testJWTSecret, time.Now(), time.Minute, nil, []string{"service_echo"})}}
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatalf("authenticated connection rejected: %v", err)
+	}
+	defer conn.Close()
+
+	for _, method := range []string{"service_echo", "service_rets"} {
+		if err := websocket.JSON.Send(conn, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": []interface{}{"a", 1, struct{}{}}}); err != nil {
+			t.Fatalf("failed to send request: %v", err)
+		}
+		var msg jsonrpcMessage
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			t.Fatalf("failed to read response: %v", err)
+		}
+		if method == "service_echo" && msg.Error != nil {
+			t.Errorf("%s: unexpected error: %v", method, msg.Error)
+		}
+		if method == "service_rets" && (msg.Error == nil || msg.Error.Code != -32002) {
+			t.Errorf("%s: error mismatch: have %v, want code -32002", method, msg.Error)
+		}
+	}
+}
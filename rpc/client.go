@@ -361,6 +361,8 @@ func (c *Client) BatchCallContext(ctx context.Context, b []BatchElem) error {
 // before considering the subscriber dead. The subscription Err channel will receive
 // ErrSubscriptionQueueOverflow. Use a sufficiently large buffer on the channel or ensure
 // that the channel usually has at least one reader to prevent this issue.
+//
+// Over HTTP, each subscription is carried by its own server-sent event stream.
 func (c *Client) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*ClientSubscription, error) {
 	// Check type of channel first.
 	chanVal := reflect.ValueOf(channel)
@@ -370,14 +372,18 @@ func (c *Client) EthSubscribe(ctx context.Context, channel interface{}, args ...
 	if chanVal.IsNil() {
 		panic("channel given to EthSubscribe must not be nil")
 	}
-	if c.isHTTP {
-		return nil, ErrNotificationsUnsupported
-	}
-
 	msg, err := c.newMessage(subscribeMethod, args...)
 	if err != nil {
 		return nil, err
 	}
+	// HTTP can't carry notifications, subscribe over an event stream instead
+	if c.isHTTP {
+		sub := newClientSubscription(c, chanVal)
+		if err := c.subscribeHTTP(ctx, sub, msg); err != nil {
+			return nil, err
+		}
+		return sub, nil
+	}
 	op := &requestOp{
 		ids:  []json.RawMessage{msg.ID},
 		resp: make(chan *jsonrpcMessage),
@@ -660,6 +666,8 @@ type ClientSubscription struct {
 	subid   string
 	in      chan json.RawMessage
 
+	cancelStream func() // closes the event stream of subscriptions over HTTP
+
 	quitOnce sync.Once     // ensures quit is closed once
 	quit     chan struct{} // quit is closed when the subscription exits
 	errOnce  sync.Once     // ensures err is closed once
@@ -771,6 +779,12 @@ func (sub *ClientSubscription) unmarshal(result json.RawMessage) (interface{}, e
 }
 
 func (sub *ClientSubscription) requestUnsubscribe() error {
+	if sub.cancelStream != nil {
+		sub.cancelStream()
+		return nil
+	}
 	var result interface{}
-	return sub.client.Call(&result, unsubscribeMethod, sub.subid)
+	ctx, cancel := context.WithTimeout(context.Background(), subscribeTimeout)
+	defer cancel()
+	return sub.client.CallContext(ctx, &result, unsubscribeMethod, sub.subid)
 }
@@ -16,7 +16,10 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // request is for an unknown service
 type methodNotFoundError struct {
@@ -64,3 +67,62 @@ type shutdownError struct{}
 func (e *shutdownError) ErrorCode() int { return -32000 }
 
 func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// access to the requested method is denied by the endpoint or token policy
+type methodNotAllowedError struct{ service, method string }
+
+func (e *methodNotAllowedError) ErrorCode() int { return -32002 }
+
+func (e *methodNotAllowedError) Error() string {
+	return fmt.Sprintf("The method %s%s%s is not allowed", e.service, serviceMethodSeparator, e.method)
+}
+
+// request failed authentication
+type unauthorizedError struct{ message string }
+
+func (e *unauthorizedError) ErrorCode() int { return -32001 }
+
+func (e *unauthorizedError) Error() string { return "unauthorized: " + e.message }
+
+// batch request contains more items than allowed
+type batchTooLargeError struct{ items, max int }
+
+func (e *batchTooLargeError) ErrorCode() int { return -32003 }
+
+func (e *batchTooLargeError) Error() string {
+	return fmt.Sprintf("batch too large (%d>%d items)", e.items, e.max)
+}
+
+// request exceeds the maximum request size
+type requestTooLargeError struct{ size, max int64 }
+
+func (e *requestTooLargeError) ErrorCode() int { return -32004 }
+
+func (e *requestTooLargeError) Error() string {
+	return fmt.Sprintf("request too large (%d>%d bytes)", e.size, e.max)
+}
+
+// response exceeds the maximum response size
+type responseTooLargeError struct{ max int }
+
+func (e *responseTooLargeError) ErrorCode() int { return -32005 }
+
+func (e *responseTooLargeError) Error() string {
+	return fmt.Sprintf("response exceeds %d bytes", e.max)
+}
+
+// method call exceeded its execution time limit
+type timeoutError struct{ timeout time.Duration }
+
+func (e *timeoutError) ErrorCode() int { return -32006 }
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("request timed out after %v", e.timeout)
+}
+
+// request denied by a rate limit
+type rateLimitedError struct{ message string }
+
+func (e *rateLimitedError) ErrorCode() int { return -32007 }
+
+func (e *rateLimitedError) Error() string { return e.message }
@@ -19,7 +19,6 @@ package rpc
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
@@ -144,25 +143,136 @@ func NewHTTPServer(corsString string, srv *Server) *http.Server {
 	return &http.Server{Handler: newCorsHandler(srv, corsString)}
 }
 
+// NewAuthHTTPServer creates a new HTTP RPC server around an API provider, which
+// authenticates the requests and restricts the callable methods with auth.
+func NewAuthHTTPServer(corsString string, srv *Server, auth *Authenticator) *http.Server {
+	return &http.Server{Handler: newCorsHandler(srv.AuthHandler(auth), corsString)}
+}
+
+// AuthHandler returns a handler that serves JSON-RPC requests over HTTP after
+// authenticating them, permitting only the calls allowed by auth.
+func (srv *Server) AuthHandler(auth *Authenticator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policies, err := auth.Authenticate(r)
+		if err != nil {
+			writeUnauthorized(w, err)
+			return
+		}
+		srv.serveHTTP(w, r, policies)
+	})
+}
+
+// GuardHandler wraps a plain HTTP handler served next to the RPC server, such as
+// GraphQL, subjecting its requests to the authentication and limits of the
+// server as calls of the given method: auth (if non-nil) must permit the method,
+// the rate limits of the method and of the client apply, and the request body
+// may not exceed the maximum request size.
+func (srv *Server) GuardHandler(handler http.Handler, auth *Authenticator, method string) http.Handler {
+	service, name := method, ""
+	if i := strings.Index(method, serviceMethodSeparator); i >= 0 {
+		service, name = method[:i], method[i+len(serviceMethodSeparator):]
+	}
+	if timeout := srv.callTimeout(); timeout > 0 {
+		handler = http.TimeoutHandler(handler, timeout, "request timed out")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth != nil {
+			policies, err := auth.Authenticate(r)
+			if err != nil {
+				writeUnauthorized(w, err)
+				return
+			}
+			for _, policy := range policies {
+				if !policy.Permits(service, name) {
+					writeHTTPError(w, http.StatusForbidden, &methodNotAllowedError{service, name})
+					return
+				}
+			}
+		}
+		if srv.limiter != nil {
+			client := r.RemoteAddr
+			if host, _, err := net.SplitHostPort(client); err == nil {
+				client = host
+			}
+			if err := srv.limiter.allow(client, method); err != nil {
+				writeHTTPError(w, http.StatusTooManyRequests, err)
+				return
+			}
+		}
+		max := srv.maxRequestSize()
+		if r.ContentLength > max {
+			writeRequestTooLarge(w, r.ContentLength, max)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// writeHTTPError rejects an HTTP request with the given status and a JSON-RPC
+// error response.
+func writeHTTPError(w http.ResponseWriter, status int, err Error) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+
+	codec := NewJSONCodec(&httpReadWriteNopCloser{strings.NewReader(""), w})
+	codec.Write(codec.CreateErrorResponse(nil, err))
+}
+
 // ServeHTTP serves JSON-RPC requests over HTTP.
 func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.ContentLength > maxHTTPRequestContentLength {
-		http.Error(w,
-			fmt.Sprintf("content length too large (%d>%d)", r.ContentLength, maxHTTPRequestContentLength),
-			http.StatusRequestEntityTooLarge)
+	srv.serveHTTP(w, r, nil)
+}
+
+// serveHTTP serves a JSON-RPC request over HTTP, permitting only the calls
+// allowed by all the given access policies.
+func (srv *Server) serveHTTP(w http.ResponseWriter, r *http.Request, policies []*AccessPolicy) {
+	// read the request body, rejecting it if exceeds the size limit
+	max := srv.maxRequestSize()
+	if r.ContentLength > max {
+		writeRequestTooLarge(w, r.ContentLength, max)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, max+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > max {
+		writeRequestTooLarge(w, int64(len(body)), max)
+		return
+	}
+	if isEventStream(r) {
+		srv.serveEvents(w, r, body, policies)
 		return
 	}
 	w.Header().Set("content-type", "application/json")
 
-	// create a codec that reads direct from the request body until
-	// EOF and writes the response to w and order the server to process
-	// a single request.
-	codec := NewJSONCodec(&httpReadWriteNopCloser{r.Body, w})
+	// create a codec that reads from the request body and writes the
+	// response to w and order the server to process a single request.
+	codec := withAccess(NewJSONCodec(&httpReadWriteNopCloser{bytes.NewReader(body), w}), policies)
 	defer codec.Close()
-	srv.ServeSingleRequest(codec, OptionMethodInvocation)
+	srv.serveRequest(srv.withLimits(codec, r.RemoteAddr), true, OptionMethodInvocation)
+}
+
+// writeRequestTooLarge rejects an oversized HTTP request with a JSON-RPC error.
+func writeRequestTooLarge(w http.ResponseWriter, size, max int64) {
+	requestLimitMeter.Mark(1)
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+
+	codec := NewJSONCodec(&httpReadWriteNopCloser{strings.NewReader(""), w})
+	codec.Write(codec.CreateErrorResponse(nil, &requestTooLargeError{size, max}))
+}
+
+// NewCorsHandler wraps an HTTP handler so it answers cross-origin requests from
+// the comma separated list of allowed domains.
+func NewCorsHandler(srv http.Handler, corsString string) http.Handler {
+	return newCorsHandler(srv, corsString)
 }
 
-func newCorsHandler(srv *Server, corsString string) http.Handler {
+func newCorsHandler(srv http.Handler, corsString string) http.Handler {
 	var allowedOrigins []string
 	for _, domain := range strings.Split(corsString, ",") {
 		allowedOrigins = append(allowedOrigins, strings.TrimSpace(domain))
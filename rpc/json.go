@@ -124,8 +124,14 @@ func (c *jsonCodec) ReadRequestHeaders() ([]rpcRequest, bool, Error) {
 
 	var incomingMsg json.RawMessage
 	if err := c.d.Decode(&incomingMsg); err != nil {
+		if err, ok := err.(*requestTooLargeError); ok {
+			return nil, false, err
+		}
 		return nil, false, &invalidRequestError{err.Error()}
 	}
+	if limited, ok := c.rw.(*sizeLimitedConn); ok {
+		limited.reset()
+	}
 
 	if isBatch(incomingMsg) {
 		return parseBatchRequest(incomingMsg)
@@ -0,0 +1,278 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ur-technology/go-ur/metrics"
+)
+
+var (
+	batchLimitMeter      = metrics.NewMeter("rpc/limits/batch")
+	requestLimitMeter    = metrics.NewMeter("rpc/limits/request")
+	responseLimitMeter   = metrics.NewMeter("rpc/limits/response")
+	timeoutMeter         = metrics.NewMeter("rpc/limits/timeout")
+	clientRateLimitMeter = metrics.NewMeter("rpc/limits/ratelimit/client")
+	methodRateLimitMeter = metrics.NewMeter("rpc/limits/ratelimit/method")
+)
+
+// bucketExpiry is the idle time after which the rate limit state of a client
+// is dropped. Buckets are refilled by then anyway for any sensible rate.
+const bucketExpiry = 10 * time.Minute
+
+// Rate is a token bucket rate limit, allowing Burst requests at once and
+// refilling at PerSecond requests per second.
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+// Limits are the resource limits enforced by a server on its clients. Zero
+// values disable the individual limits.
+type Limits struct {
+	MaxBatchItems   int           // Maximum number of requests in a batch
+	MaxRequestSize  int64         // Maximum size of a request (HTTP body or websocket message) in bytes
+	MaxResponseSize int           // Maximum size of a response (sum of all items of a batch) in bytes
+	Timeout         time.Duration // Maximum execution time of a method call
+
+	ClientRate  Rate            // Request rate limit of each client IP address
+	MethodRates map[string]Rate // Request rate limits of methods (or namespace_* wildcards), shared by all clients
+}
+
+// tokenBucket is a token bucket rate limiter.
+type tokenBucket struct {
+	rate   Rate
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full token bucket.
+func newTokenBucket(rate Rate, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, tokens: float64(rate.Burst), last: now}
+}
+
+// take refills the bucket and takes a token from it, if there is one.
+func (b *tokenBucket) take(now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate.PerSecond
+	if burst := float64(b.rate.Burst); b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// rateLimiter tracks the token buckets of the clients and rate limited methods.
+type rateLimiter struct {
+	limits  *Limits
+	clients map[string]*tokenBucket
+	methods map[string]*tokenBucket
+	swept   time.Time
+	now     func() time.Time
+	lock    sync.Mutex
+}
+
+// newRateLimiter creates a rate limiter for the limits, or nil if they do not
+// contain rate limits.
+func newRateLimiter(limits *Limits) *rateLimiter {
+	if limits.ClientRate.PerSecond <= 0 && len(limits.MethodRates) == 0 {
+		return nil
+	}
+	return &rateLimiter{
+		limits:  limits,
+		clients: make(map[string]*tokenBucket),
+		methods: make(map[string]*tokenBucket),
+		swept:   time.Now(),
+		now:     time.Now,
+	}
+}
+
+// allow checks the client and method rate limits of a call, consuming a token
+// from each of the affected buckets if the call is allowed. Calls from unknown
+// clients (e.g. IPC) are only subject to the method limits.
+func (l *rateLimiter) allow(client, method string) Error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	if now.Sub(l.swept) > bucketExpiry {
+		for ip, bucket := range l.clients {
+			if now.Sub(bucket.last) > bucketExpiry {
+				delete(l.clients, ip)
+			}
+		}
+		l.swept = now
+	}
+	if client != "" && l.limits.ClientRate.PerSecond > 0 {
+		bucket := l.clients[client]
+		if bucket == nil {
+			bucket = newTokenBucket(l.limits.ClientRate, now)
+			l.clients[client] = bucket
+		}
+		if !bucket.take(now) {
+			clientRateLimitMeter.Mark(1)
+			return &rateLimitedError{"client request rate exceeded"}
+		}
+	}
+	for pattern, rate := range l.limits.MethodRates {
+		if !matchMethod([]string{pattern}, method) {
+			continue
+		}
+		bucket := l.methods[pattern]
+		if bucket == nil {
+			bucket = newTokenBucket(rate, now)
+			l.methods[pattern] = bucket
+		}
+		if !bucket.take(now) {
+			methodRateLimitMeter.Mark(1)
+			return &rateLimitedError{"request rate of " + method + " exceeded"}
+		}
+	}
+	return nil
+}
+
+// limitCodec wraps a server codec, rejecting oversized batches and the requests
+// exceeding the rate limits of the server.
+type limitCodec struct {
+	ServerCodec
+	limits  *Limits
+	limiter *rateLimiter
+	client  string
+}
+
+// ReadRequestHeaders reads the next requests from the wrapped codec and marks
+// the ones exceeding the limits as failed.
+func (c *limitCodec) ReadRequestHeaders() ([]rpcRequest, bool, Error) {
+	reqs, batch, err := c.ServerCodec.ReadRequestHeaders()
+	if err != nil {
+		return reqs, batch, err
+	}
+	if max := c.limits.MaxBatchItems; batch && max > 0 && len(reqs) > max {
+		batchLimitMeter.Mark(1)
+		for i := range reqs {
+			reqs[i].err = &batchTooLargeError{len(reqs), max}
+		}
+		return reqs, batch, nil
+	}
+	if c.limiter != nil {
+		for i := range reqs {
+			r := &reqs[i]
+			if r.err != nil || (r.isPubSub && r.method == unsubscribeMethod) {
+				continue
+			}
+			method := r.service + serviceMethodSeparator + r.method
+			if r.isPubSub {
+				method = r.service + serviceMethodSeparator + "subscribe"
+			}
+			r.err = c.limiter.allow(c.client, method)
+		}
+	}
+	return reqs, batch, nil
+}
+
+// sizeLimitedConn wraps a stream connection, failing reads once a single request
+// exceeds the maximum request size. The JSON codec resets the counter after each
+// decoded message.
+type sizeLimitedConn struct {
+	io.ReadWriteCloser
+	max  int64
+	read int64
+}
+
+// Read implements io.Reader, failing if the current request is too large.
+func (c *sizeLimitedConn) Read(b []byte) (int, error) {
+	if c.read > c.max {
+		requestLimitMeter.Mark(1)
+		return 0, &requestTooLargeError{c.read, c.max}
+	}
+	n, err := c.ReadWriteCloser.Read(b)
+	c.read += int64(n)
+	return n, err
+}
+
+// reset starts counting the size of the next request.
+func (c *sizeLimitedConn) reset() {
+	c.read = 0
+}
+
+// limitRequests wraps a stream connection to enforce the maximum request size
+// of the server, if any.
+func (s *Server) limitRequests(conn io.ReadWriteCloser) io.ReadWriteCloser {
+	if s.limits == nil || s.limits.MaxRequestSize <= 0 {
+		return conn
+	}
+	return &sizeLimitedConn{ReadWriteCloser: conn, max: s.limits.MaxRequestSize}
+}
+
+// maxRequestSize returns the maximum size of an HTTP request body.
+func (s *Server) maxRequestSize() int64 {
+	if s.limits == nil || s.limits.MaxRequestSize <= 0 {
+		return maxHTTPRequestContentLength
+	}
+	return s.limits.MaxRequestSize
+}
+
+// SetLimits configures the resource limits enforced on the clients of the
+// server. It must be called before the server starts serving requests.
+func (s *Server) SetLimits(limits Limits) {
+	s.limits = &limits
+	s.limiter = newRateLimiter(s.limits)
+}
+
+// withLimits wraps a codec to enforce the limits of the server on the requests
+// of the given client address (empty if unknown).
+func (s *Server) withLimits(codec ServerCodec, client string) ServerCodec {
+	if s.limits == nil || (s.limits.MaxBatchItems <= 0 && s.limiter == nil) {
+		return codec
+	}
+	if host, _, err := net.SplitHostPort(client); err == nil {
+		client = host
+	}
+	return &limitCodec{ServerCodec: codec, limits: s.limits, limiter: s.limiter, client: client}
+}
+
+// limitResponse replaces a response with an error if its encoding exceeds the
+// remaining response size budget, returning the size of the final response.
+func (s *Server) limitResponse(codec ServerCodec, req *serverRequest, response interface{}, budget int) (interface{}, int) {
+	if s.limits == nil || s.limits.MaxResponseSize <= 0 {
+		return response, 0
+	}
+	blob, err := json.Marshal(response)
+	if err != nil || len(blob) <= budget {
+		return response, len(blob)
+	}
+	responseLimitMeter.Mark(1)
+	response = codec.CreateErrorResponse(&req.id, &responseTooLargeError{s.limits.MaxResponseSize})
+	blob, _ = json.Marshal(response)
+	return response, len(blob)
+}
+
+// callTimeout returns the execution timeout of method calls, zero if unlimited.
+func (s *Server) callTimeout() time.Duration {
+	if s.limits == nil {
+		return 0
+	}
+	return s.limits.Timeout
+}
@@ -0,0 +1,168 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postLimited posts a raw JSON-RPC payload to an HTTP server and returns the
+// status code and error codes of the response items (zero for successes).
+func postLimited(t *testing.T, url, body string) (int, []int) {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	var msgs []jsonrpcMessage
+	if isBatch(raw) {
+		if err := json.Unmarshal(raw, &msgs); err != nil {
+			t.Fatalf("invalid batch response: %v", err)
+		}
+	} else {
+		msgs = make([]jsonrpcMessage, 1)
+		if err := json.Unmarshal(raw, &msgs[0]); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+	}
+	codes := make([]int, len(msgs))
+	for i, msg := range msgs {
+		if msg.Error != nil {
+			codes[i] = msg.Error.Code
+		}
+	}
+	return resp.StatusCode, codes
+}
+
+// echoRequest assembles a service_echo request with the given id and payload.
+func echoRequest(id int, payload string) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"service_echo","params":["%s",1,{}]}`, id, payload)
+}
+
+func TestLimitsBatchAndSize(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+	server.SetLimits(Limits{MaxBatchItems: 2, MaxRequestSize: 1024, MaxResponseSize: 512})
+
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	// Batches above the limit are rejected item by item
+	if _, codes := postLimited(t, hs.URL, "["+echoRequest(1, "a")+","+echoRequest(2, "b")+"]"); codes[0] != 0 || codes[1] != 0 {
+		t.Errorf("batch within limit failed: %v", codes)
+	}
+	_, codes := postLimited(t, hs.URL, "["+echoRequest(1, "a")+","+echoRequest(2, "b")+","+echoRequest(3, "c")+"]")
+	for i, code := range codes {
+		if code != -32003 {
+			t.Errorf("oversized batch item %d: error code mismatch: have %d, want %d", i, code, -32003)
+		}
+	}
+	// Oversized requests are rejected before being parsed
+	status, codes := postLimited(t, hs.URL, echoRequest(1, strings.Repeat("a", 2048)))
+	if status != http.StatusRequestEntityTooLarge || codes[0] != -32004 {
+		t.Errorf("oversized request: have status %d code %d, want %d and %d", status, codes[0], http.StatusRequestEntityTooLarge, -32004)
+	}
+	// Oversized responses are replaced with errors, summed up over batches
+	if _, codes := postLimited(t, hs.URL, echoRequest(1, strings.Repeat("a", 600))); codes[0] != -32005 {
+		t.Errorf("oversized response: error code mismatch: have %d, want %d", codes[0], -32005)
+	}
+	_, codes = postLimited(t, hs.URL, "["+echoRequest(1, strings.Repeat("a", 300))+","+echoRequest(2, strings.Repeat("b", 300))+"]")
+	if codes[0] != 0 || codes[1] != -32005 {
+		t.Errorf("oversized batch response: error codes mismatch: have %v, want [0 -32005]", codes)
+	}
+}
+
+func TestLimitsTimeout(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+	server.SetLimits(Limits{Timeout: 50 * time.Millisecond})
+
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	request := func(d time.Duration) string {
+		return fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"service_sleep","params":[%d]}`, d)
+	}
+	if _, codes := postLimited(t, hs.URL, request(time.Millisecond)); codes[0] != 0 {
+		t.Errorf("short call failed with code %d", codes[0])
+	}
+	start := time.Now()
+	if _, codes := postLimited(t, hs.URL, request(time.Minute)); codes[0] != -32006 {
+		t.Errorf("long call: error code mismatch: have %d, want %d", codes[0], -32006)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timeout not enforced, call took %v", elapsed)
+	}
+}
+
+func TestLimitsRate(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+	server.SetLimits(Limits{
+		ClientRate:  Rate{PerSecond: 0.001, Burst: 3},
+		MethodRates: map[string]Rate{"service_rets": {PerSecond: 0.001, Burst: 1}},
+	})
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	rets := `{"jsonrpc":"2.0","id":1,"method":"service_rets","params":[]}`
+	tests := []struct {
+		body string
+		code int
+	}{
+		{rets, 0},
+		{rets, -32007},                // method bucket exhausted
+		{echoRequest(1, "a"), 0},      // other methods still callable
+		{echoRequest(2, "a"), -32007}, // client bucket exhausted
+	}
+	for i, tt := range tests {
+		if _, codes := postLimited(t, hs.URL, tt.body); codes[0] != tt.code {
+			t.Errorf("request %d: error code mismatch: have %d, want %d", i, codes[0], tt.code)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	bucket := newTokenBucket(Rate{PerSecond: 2, Burst: 2}, now)
+
+	for i, want := range []bool{true, true, false} {
+		if have := bucket.take(now); have != want {
+			t.Errorf("take %d: have %v, want %v", i, have, want)
+		}
+	}
+	if !bucket.take(now.Add(500 * time.Millisecond)) {
+		t.Errorf("bucket not refilled")
+	}
+	if bucket.take(now.Add(500 * time.Millisecond)) {
+		t.Errorf("bucket overdrawn")
+	}
+	if !bucket.take(now.Add(time.Hour)) || !bucket.take(now.Add(time.Hour)) || bucket.take(now.Add(time.Hour)) {
+		t.Errorf("bucket refilled above burst")
+	}
+}
@@ -45,8 +45,15 @@ const (
 
 	// OptionSubscriptions is an indication that the codec suports RPC notifications
 	OptionSubscriptions = 1 << iota // support pub sub
+
+	// optionCloseIdle closes the codec once its responses are written, unless a
+	// subscription keeps it open for notifications (event streams)
+	optionCloseIdle = 1 << iota
 )
 
+// closeIdleKey marks the connection contexts of optionCloseIdle codecs.
+type closeIdleKey struct{}
+
 // NewServer will create a new server instance with no registered handlers.
 func NewServer() *Server {
 	server := &Server{
@@ -168,6 +175,9 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 	if options&OptionSubscriptions == OptionSubscriptions {
 		ctx = context.WithValue(ctx, notifierKey{}, newNotifier(codec))
 	}
+	if options&optionCloseIdle == optionCloseIdle {
+		ctx = context.WithValue(ctx, closeIdleKey{}, true)
+	}
 	s.codecsMu.Lock()
 	if atomic.LoadInt32(&s.run) != 1 { // server stopped
 		s.codecsMu.Unlock()
@@ -221,15 +231,21 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 // response back using the given codec. It will block until the codec is closed or the server is
 // stopped. In either case the codec is closed.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(codec, options, "")
+}
+
+// serveCodec serves the requests of a codec until it's closed, enforcing the
+// server limits as requests of the given client address.
+func (s *Server) serveCodec(codec ServerCodec, options CodecOption, client string) {
 	defer codec.Close()
-	s.serveRequest(codec, false, options)
+	s.serveRequest(s.withLimits(codec, client), false, options)
 }
 
 // ServeSingleRequest reads and processes a single RPC request from the given codec. It will not
 // close the codec unless a non-recoverable error has occurred. Note, this method will return after
 // a single request has been processed!
 func (s *Server) ServeSingleRequest(codec ServerCodec, options CodecOption) {
-	s.serveRequest(codec, true, options)
+	s.serveRequest(s.withLimits(codec, ""), true, options)
 }
 
 // Stop will stop reading new requests, wait for stopPendingRequestTimeout to allow pending requests to finish,
@@ -306,6 +322,12 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, rpcErr), nil
 	}
 
+	timeout := s.callTimeout()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 	arguments := []reflect.Value{req.callb.rcvr}
 	if req.callb.hasCtx {
 		arguments = append(arguments, reflect.ValueOf(ctx))
@@ -315,7 +337,11 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	}
 
 	// execute RPC method and return result
-	reply := req.callb.method.Func.Call(arguments)
+	reply, ok := call(ctx, req.callb, arguments, timeout > 0)
+	if !ok {
+		timeoutMeter.Mark(1)
+		return codec.CreateErrorResponse(&req.id, &timeoutError{timeout}), nil
+	}
 	if len(reply) == 0 {
 		return codec.CreateResponse(req.id, nil), nil
 	}
@@ -330,6 +356,28 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
 }
 
+// call invokes a callback. If the call is time limited and the deadline of the
+// context passes before the callback returns, the call is abandoned and false
+// is returned. Callbacks accepting a context are expected to abort on their own.
+func call(ctx context.Context, callb *callback, arguments []reflect.Value, limited bool) ([]reflect.Value, bool) {
+	if !limited {
+		return callb.method.Func.Call(arguments), true
+	}
+	done := make(chan []reflect.Value, 1)
+	go func() {
+		done <- callb.method.Func.Call(arguments)
+	}()
+	select {
+	case reply := <-done:
+		return reply, true
+	case <-ctx.Done():
+		if ctx.Err() != context.DeadlineExceeded {
+			return <-done, true
+		}
+		return nil, false
+	}
+}
+
 // exec executes the given request and writes the result back using the codec.
 func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest) {
 	var response interface{}
@@ -339,6 +387,9 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 	} else {
 		response, callback = s.handle(ctx, codec, req)
 	}
+	if s.limits != nil {
+		response, _ = s.limitResponse(codec, req, response, s.limits.MaxResponseSize)
+	}
 
 	if err := codec.Write(response); err != nil {
 		glog.V(logger.Error).Infof("%v\n", err)
@@ -349,6 +400,7 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 	if callback != nil {
 		callback()
 	}
+	closeIfIdle(ctx, codec)
 }
 
 // execBatch executes the given requests and writes the result back using the codec.
@@ -356,6 +408,7 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) {
 	responses := make([]interface{}, len(requests))
 	var callbacks []func()
+	var size int
 	for i, req := range requests {
 		if req.err != nil {
 			responses[i] = codec.CreateErrorResponse(&req.id, req.err)
@@ -365,6 +418,11 @@ func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*s
 				callbacks = append(callbacks, callback)
 			}
 		}
+		if s.limits != nil {
+			var n int
+			responses[i], n = s.limitResponse(codec, req, responses[i], s.limits.MaxResponseSize-size)
+			size += n
+		}
 	}
 
 	if err := codec.Write(responses); err != nil {
@@ -376,6 +434,19 @@ func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*s
 	for _, c := range callbacks {
 		c()
 	}
+	closeIfIdle(ctx, codec)
+}
+
+// closeIfIdle closes the codec of an optionCloseIdle connection once the response
+// is written, unless it created subscriptions.
+func closeIfIdle(ctx context.Context, codec ServerCodec) {
+	if idle, _ := ctx.Value(closeIdleKey{}).(bool); !idle {
+		return
+	}
+	if notifier, ok := NotifierFromContext(ctx); ok && notifier.subscriptions() > 0 {
+		return
+	}
+	codec.Close()
 }
 
 // readRequest requests the next (batch) request from the codec. It will return the collection
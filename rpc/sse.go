@@ -0,0 +1,275 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// Subscriptions over HTTP are served as server-sent events: a request posted
+// with "Accept: text/event-stream" is answered with an event stream, the first
+// event carrying the JSON-RPC response and every further event a notification
+// of the subscription. The subscription ends when the stream is closed. Streams
+// of requests creating no subscription end after the response.
+
+const eventStreamType = "text/event-stream"
+
+var errStreamClosed = errors.New("event stream closed")
+
+// isEventStream reports whether an HTTP request asks to be answered with an event
+// stream.
+func isEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("accept"), eventStreamType)
+}
+
+// eventStream is the connection of a JSON-RPC request served over server-sent
+// events. Reads return the request body and then block until the client goes
+// away or the codec is closed, writes are sent as events.
+type eventStream struct {
+	body    io.Reader
+	gone    <-chan bool
+	w       io.Writer
+	flusher http.Flusher
+
+	lock      sync.Mutex
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (s *eventStream) Read(b []byte) (int, error) {
+	if n, err := s.body.Read(b); err != io.EOF {
+		return n, err
+	}
+	select {
+	case <-s.gone:
+	case <-s.closed:
+	}
+	return 0, io.EOF
+}
+
+// Write sends a JSON encoded message as an event.
+func (s *eventStream) Write(b []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	select {
+	case <-s.closed:
+		return 0, errStreamClosed
+	default:
+	}
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", bytes.TrimRight(b, "\n")); err != nil {
+		return 0, err
+	}
+	s.flusher.Flush()
+	return len(b), nil
+}
+
+// Close stops the stream, discarding any further writes.
+func (s *eventStream) Close() error {
+	s.closeOnce.Do(func() {
+		s.lock.Lock()
+		close(s.closed)
+		s.lock.Unlock()
+	})
+	return nil
+}
+
+// serveEvents serves a JSON-RPC request over a server-sent event stream, keeping
+// the connection open for the notifications of the subscriptions it creates.
+func (srv *Server) serveEvents(w http.ResponseWriter, r *http.Request, body []byte, policies []*AccessPolicy) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "event streams not supported", http.StatusInternalServerError)
+		return
+	}
+	notifier, ok := w.(http.CloseNotifier)
+	if !ok {
+		http.Error(w, "event streams not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", eventStreamType)
+	w.Header().Set("cache-control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	stream := &eventStream{
+		body:    bytes.NewReader(body),
+		gone:    notifier.CloseNotify(),
+		w:       w,
+		flusher: flusher,
+		closed:  make(chan struct{}),
+	}
+	// The codec is closed once the response is written if the request created no
+	// subscription, otherwise once the client goes away, ending the subscriptions.
+	// Closing the stream prevents late notifications from being written after
+	// the handler returned.
+	srv.serveCodec(withAccess(NewJSONCodec(stream), policies), OptionMethodInvocation|OptionSubscriptions|optionCloseIdle, r.RemoteAddr)
+	stream.Close()
+}
+
+// eventReader decodes the data of server-sent events.
+type eventReader struct {
+	r *bufio.Reader
+}
+
+// next returns the data of the next event, skipping comments and other fields.
+func (er *eventReader) next() ([]byte, error) {
+	var data []byte
+	for {
+		line, err := er.r.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		switch {
+		case len(line) == 0:
+			if data != nil {
+				return data, nil
+			}
+		case bytes.HasPrefix(line, []byte("data:")):
+			if data != nil {
+				data = append(data, '\n')
+			}
+			data = append(data, bytes.TrimPrefix(line[5:], []byte(" "))...)
+		}
+	}
+}
+
+// subscribeHTTP establishes a subscription over a server-sent event stream.
+func (c *Client) subscribeHTTP(ctx context.Context, sub *ClientSubscription, msg *jsonrpcMessage) error {
+	hc := c.writeConn.(*httpConn)
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	// The stream outlives the context of the subscription request, so it gets
+	// its own one, canceled to unsubscribe.
+	streamCtx, cancel := context.WithCancel(context.Background())
+	client, req := requestWithContext(hc.client, hc.req, streamCtx)
+
+	header := make(http.Header, len(req.Header))
+	for key, values := range req.Header {
+		header[key] = values
+	}
+	header.Set("Accept", eventStreamType)
+	req.Header = header
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	// Open the stream and wait for the subscription response
+	type result struct {
+		events *eventReader
+		resp   *jsonrpcMessage
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := client.Do(req)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		if !strings.HasPrefix(resp.Header.Get("content-type"), eventStreamType) {
+			resp.Body.Close()
+			done <- result{err: fmt.Errorf("no event stream in response: %s", resp.Status)}
+			return
+		}
+		events := &eventReader{bufio.NewReader(resp.Body)}
+		data, err := events.next()
+		if err != nil {
+			resp.Body.Close()
+			done <- result{err: err}
+			return
+		}
+		var respmsg jsonrpcMessage
+		if err := json.Unmarshal(data, &respmsg); err != nil {
+			resp.Body.Close()
+			done <- result{err: err}
+			return
+		}
+		done <- result{events: events, resp: &respmsg}
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	}
+	if res.err == nil && res.resp.Error != nil {
+		res.err = res.resp.Error
+	}
+	if res.err == nil {
+		res.err = json.Unmarshal(res.resp.Result, &sub.subid)
+	}
+	if res.err != nil {
+		cancel()
+		return res.err
+	}
+	sub.cancelStream = cancel
+	go sub.start()
+
+	// Forward the notifications until the stream ends
+	go func() {
+		select {
+		case <-hc.closed:
+			cancel()
+		case <-streamCtx.Done():
+		}
+	}()
+	go func() {
+		for {
+			data, err := res.events.next()
+			if err != nil {
+				select {
+				case <-hc.closed:
+					err = ErrClientQuit
+				default:
+				}
+				sub.quitWithError(err, false)
+				return
+			}
+			var notification struct {
+				Method string `json:"method"`
+				Params struct {
+					ID     string          `json:"subscription"`
+					Result json.RawMessage `json:"result"`
+				} `json:"params"`
+			}
+			if err := json.Unmarshal(data, &notification); err != nil || notification.Method != notificationMethod {
+				continue
+			}
+			if notification.Params.ID == sub.subid && !sub.deliver(notification.Params.Result) {
+				cancel()
+				return
+			}
+		}
+	}()
+	return nil
+}
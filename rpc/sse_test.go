@@ -0,0 +1,122 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Tests that subscriptions over HTTP are carried by event streams, and that
+// closing the stream ends the subscription on the server.
+func TestHTTPEventStreamSubscription(t *testing.T) {
+	t.Parallel()
+
+	service := new(NotificationTestService)
+	server := newTestServer("eth", service)
+	defer server.Stop()
+
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	client, err := DialHTTP(hs.URL)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	nc := make(chan int)
+	sub, err := client.EthSubscribe(context.Background(), nc, "someSubscription", 3, 5)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case val := <-nc:
+			if val != 5+i {
+				t.Fatalf("notification %d mismatch: have %d, want %d", i, val, 5+i)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("notification %d timeout", i)
+		}
+	}
+	sub.Unsubscribe()
+	for start := time.Now(); !service.wasUnsubCallbackCalled(); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("server subscription not ended after closing the stream")
+		}
+	}
+	// Plain calls must still work next to the stream
+	var result int
+	if err := client.Call(&result, "eth_echo", 7); err != nil || result != 7 {
+		t.Fatalf("echo mismatch: have %d (%v), want 7", result, err)
+	}
+	// Subscription errors must be reported in the stream response
+	if _, err := client.EthSubscribe(context.Background(), nc, "unknownSubscription"); err == nil {
+		t.Fatalf("no error for unknown subscription")
+	}
+}
+
+// Tests that event streams of requests creating no subscription end once the
+// response is written.
+func TestHTTPEventStreamWithoutSubscription(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer("eth", new(NotificationTestService))
+	defer server.Stop()
+
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"eth_echo","params":[7]}`
+	req, _ := http.NewRequest("POST", hs.URL, strings.NewReader(body))
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("accept", eventStreamType)
+
+	done := make(chan string, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			done <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			done <- err.Error()
+			return
+		}
+		done <- string(data)
+	}()
+	select {
+	case data := <-done:
+		if !strings.Contains(data, `"result":7`) {
+			t.Fatalf("response mismatch: %q", data)
+		}
+	case <-time.After(5 * time.Second):
+		hs.CloseClientConnections()
+		t.Fatalf("event stream not closed after the response")
+	}
+}
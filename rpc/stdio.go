@@ -0,0 +1,80 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// stdioConn is a connection over a pair of streams, such as the standard input
+// and output of a process.
+type stdioConn struct {
+	in  io.Reader
+	out io.Writer
+}
+
+func (c *stdioConn) Read(b []byte) (int, error)  { return c.in.Read(b) }
+func (c *stdioConn) Write(b []byte) (int, error) { return c.out.Write(b) }
+
+// Close closes the streams if they can be closed, the standard streams being
+// left open.
+func (c *stdioConn) Close() error {
+	if c.in != os.Stdin {
+		if closer, ok := c.in.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	if c.out != os.Stdout {
+		if closer, ok := c.out.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	return nil
+}
+
+func (c *stdioConn) LocalAddr() net.Addr              { return nullAddr }
+func (c *stdioConn) RemoteAddr() net.Addr             { return nullAddr }
+func (c *stdioConn) SetDeadline(time.Time) error      { return nil }
+func (c *stdioConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *stdioConn) SetWriteDeadline(time.Time) error { return nil }
+
+// NewStdIOCodec creates a codec serving JSON-RPC on the standard input and output
+// of the process, for nodes running as the subprocess of another program.
+func NewStdIOCodec() ServerCodec {
+	return NewJSONCodec(&stdioConn{os.Stdin, os.Stdout})
+}
+
+// DialStdIO creates a client talking JSON-RPC over the standard input and output
+// of the process, for programs started as the subprocess of a node.
+func DialStdIO(ctx context.Context) (*Client, error) {
+	return DialIO(ctx, os.Stdin, os.Stdout)
+}
+
+// DialIO creates a client talking JSON-RPC over the given streams, such as the
+// output and input pipes of a node started as a subprocess. Subscriptions are
+// supported just like over IPC.
+func DialIO(ctx context.Context, in io.Reader, out io.Writer) (*Client, error) {
+	conn := &stdioConn{in, out}
+	return newClient(ctx, func(context.Context) (net.Conn, error) {
+		return conn, nil
+	})
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"io"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// Tests that calls and subscriptions work over a pair of streams, as between a
+// node and the program it's a subprocess of.
+func TestStdIOClient(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer("eth", new(NotificationTestService))
+	defer server.Stop()
+
+	// Wire up the streams of the server and the client
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	go server.ServeCodec(NewJSONCodec(&stdioConn{serverIn, serverOut}), OptionMethodInvocation|OptionSubscriptions)
+
+	client, err := DialIO(context.Background(), clientIn, clientOut)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	var result int
+	if err := client.Call(&result, "eth_echo", 42); err != nil || result != 42 {
+		t.Fatalf("echo mismatch: have %d (%v), want 42", result, err)
+	}
+	nc := make(chan int)
+	sub, err := client.EthSubscribe(context.Background(), nc, "someSubscription", 3, 10)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	for i := 0; i < 3; i++ {
+		if val := <-nc; val != 10+i {
+			t.Fatalf("notification %d mismatch: have %d, want %d", i, val, 10+i)
+		}
+	}
+}
@@ -108,6 +108,14 @@ func (n *Notifier) Closed() <-chan interface{} {
 	return n.codec.Closed()
 }
 
+// subscriptions returns the number of subscriptions of the connection, either
+// active or waiting for activation.
+func (n *Notifier) subscriptions() int {
+	n.subMu.RLock()
+	defer n.subMu.RUnlock()
+	return len(n.active) + len(n.inactive)
+}
+
 // unsubscribe a subscription.
 // If the subscription could not be found ErrSubscriptionNotFound is returned.
 func (n *Notifier) unsubscribe(id ID) error {
@@ -81,6 +81,9 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	limits  *Limits      // resource limits enforced on clients, nil if unlimited
+	limiter *rateLimiter // rate limit state of clients and methods
 }
 
 // rpcRequest represents a raw incoming RPC request
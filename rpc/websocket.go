@@ -40,11 +40,45 @@ func (srv *Server) WebsocketHandler(allowedOrigins string) http.Handler {
 	return websocket.Server{
 		Handshake: wsHandshakeValidator(strings.Split(allowedOrigins, ",")),
 		Handler: func(conn *websocket.Conn) {
-			srv.ServeCodec(NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
+			srv.serveCodec(NewJSONCodec(srv.limitRequests(conn)), OptionMethodInvocation|OptionSubscriptions, conn.Request().RemoteAddr)
 		},
 	}
 }
 
+// AuthWebsocketHandler returns a handler that serves JSON-RPC to WebSocket
+// connections authenticated during the handshake, permitting only the calls
+// allowed by auth for the lifetime of the connection.
+func (srv *Server) AuthWebsocketHandler(allowedOrigins string, auth *Authenticator) http.Handler {
+	validateOrigin := wsHandshakeValidator(strings.Split(allowedOrigins, ","))
+	return websocket.Server{
+		Handshake: func(cfg *websocket.Config, req *http.Request) error {
+			if err := validateOrigin(cfg, req); err != nil {
+				return err
+			}
+			if _, err := auth.Authenticate(req); err != nil {
+				glog.V(logger.Debug).Infof("unauthorized WS-RPC connection: %v\n", err)
+				return err
+			}
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			policies, err := auth.Authenticate(conn.Request())
+			if err != nil {
+				conn.Close()
+				return
+			}
+			codec := withAccess(NewJSONCodec(srv.limitRequests(conn)), policies)
+			srv.serveCodec(codec, OptionMethodInvocation|OptionSubscriptions, conn.Request().RemoteAddr)
+		},
+	}
+}
+
+// NewAuthWSServer creates a new websocket RPC server around an API provider,
+// which authenticates the connections and restricts the callable methods.
+func NewAuthWSServer(allowedOrigins string, srv *Server, auth *Authenticator) *http.Server {
+	return &http.Server{Handler: srv.AuthWebsocketHandler(allowedOrigins, auth)}
+}
+
 // NewWSServer creates a new websocket RPC server around an API provider.
 //
 // Deprecated: use Server.WebsocketHandler
@@ -0,0 +1,224 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package signer implements an external signing service owning the keys of a
+// keystore. Requests are decided on by a rule engine and recorded in an audit
+// log before any key is touched.
+package signer
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ur-technology/go-ur/accounts"
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/common/hexutil"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/rlp"
+	"golang.org/x/net/context"
+)
+
+// Namespace is the RPC namespace the signer API is served under.
+const Namespace = "account"
+
+// ErrRequestDenied is returned if the rule set rejected a signing request.
+var ErrRequestDenied = errors.New("request denied")
+
+var errInvalidHash = errors.New("hash must be 32 bytes")
+
+// SendTxArgs are the fields of a transaction to sign.
+type SendTxArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      hexutil.Big     `json:"gas"`
+	GasPrice hexutil.Big     `json:"gasPrice"`
+	Value    hexutil.Big     `json:"value"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	Data     hexutil.Bytes   `json:"data"`
+	ChainID  *hexutil.Big    `json:"chainId"` // Chain id to sign for, nil for homestead signatures
+}
+
+// toTransaction assembles the unsigned transaction described by the arguments.
+func (args *SendTxArgs) toTransaction() *types.Transaction {
+	if args.To == nil {
+		return types.NewContractCreation(uint64(args.Nonce), (*big.Int)(&args.Value), (*big.Int)(&args.Gas), (*big.Int)(&args.GasPrice), args.Data)
+	}
+	return types.NewTransaction(uint64(args.Nonce), *args.To, (*big.Int)(&args.Value), (*big.Int)(&args.Gas), (*big.Int)(&args.GasPrice), args.Data)
+}
+
+// SignTxResult is a signed transaction, both RLP encoded and in its JSON form.
+type SignTxResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// SignerAPI is the RPC API of the signer, served under the account namespace.
+type SignerAPI struct {
+	am    *accounts.Manager
+	rules *Ruleset
+	audit *AuditLog
+}
+
+// NewSignerAPI creates a signer API signing with the accounts of am, deciding
+// on requests with rules and recording them into audit, which may be nil.
+func NewSignerAPI(am *accounts.Manager, rules *Ruleset, audit *AuditLog) *SignerAPI {
+	return &SignerAPI{am: am, rules: rules, audit: audit}
+}
+
+// List returns the addresses of all the accounts the signer can sign with.
+func (api *SignerAPI) List(ctx context.Context) []common.Address {
+	api.audit.Log(&AuditEntry{Time: time.Now(), Method: "account_list"})
+
+	accounts := api.am.Accounts()
+	addresses := make([]common.Address, len(accounts))
+	for i, account := range accounts {
+		addresses[i] = account.Address
+	}
+	return addresses
+}
+
+// SignTransaction signs a transaction if the rule set approves it.
+func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs) (*SignTxResult, error) {
+	req := &Request{
+		Method: "account_signTransaction",
+		From:   args.From,
+		To:     args.To,
+		Value:  (*big.Int)(&args.Value),
+		Data:   args.Data,
+		Time:   time.Now(),
+	}
+	nonce := args.Nonce
+	entry := &AuditEntry{
+		Time:   req.Time,
+		Method: req.Method,
+		From:   &args.From,
+		To:     args.To,
+		Value:  &args.Value,
+		Nonce:  &nonce,
+	}
+	defer api.audit.Log(entry)
+
+	if err := api.decide(req, entry); err != nil {
+		return nil, err
+	}
+	account := accounts.Account{Address: args.From}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, api.fail(req, entry, err)
+	}
+	signed, err := wallet.SignTx(account, args.toTransaction(), (*big.Int)(args.ChainID))
+	if err != nil {
+		return nil, api.fail(req, entry, err)
+	}
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		return nil, api.fail(req, entry, err)
+	}
+	hash := signed.Hash()
+	entry.Hash = &hash
+
+	return &SignTxResult{Raw: raw, Tx: signed}, nil
+}
+
+// SignData signs data if the rule set approves it. The signature is calculated
+// for keccak256("\x19Ethereum Signed Message:\n" + len(data) + data), so that
+// it can't be abused to sign transactions.
+func (api *SignerAPI) SignData(ctx context.Context, addr common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	req := &Request{
+		Method: "account_signData",
+		From:   addr,
+		Data:   data,
+		Time:   time.Now(),
+	}
+	entry := &AuditEntry{Time: req.Time, Method: req.Method, From: &addr}
+	defer api.audit.Log(entry)
+
+	if err := api.decide(req, entry); err != nil {
+		return nil, err
+	}
+	hash := common.BytesToHash(SignDataHash(data))
+	entry.Hash = &hash
+
+	return api.signHash(req, entry, hash)
+}
+
+// SignHash signs a raw 32 byte hash if the rule set approves it. As the hash may
+// be that of a transaction, only rules explicitly listing account_signHash among
+// their methods approve it.
+func (api *SignerAPI) SignHash(ctx context.Context, addr common.Address, hash hexutil.Bytes) (hexutil.Bytes, error) {
+	req := &Request{
+		Method: "account_signHash",
+		From:   addr,
+		Data:   hash,
+		Time:   time.Now(),
+	}
+	entry := &AuditEntry{Time: req.Time, Method: req.Method, From: &addr}
+	defer api.audit.Log(entry)
+
+	if len(hash) != common.HashLength {
+		entry.Error = errInvalidHash.Error()
+		return nil, errInvalidHash
+	}
+	if err := api.decide(req, entry); err != nil {
+		return nil, err
+	}
+	h := common.BytesToHash(hash)
+	entry.Hash = &h
+
+	return api.signHash(req, entry, h)
+}
+
+// signHash signs a hash with the key of the approved request's account.
+func (api *SignerAPI) signHash(req *Request, entry *AuditEntry, hash common.Hash) (hexutil.Bytes, error) {
+	account := accounts.Account{Address: req.From}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, api.fail(req, entry, err)
+	}
+	signature, err := wallet.SignHash(account, hash[:])
+	if err != nil {
+		return nil, api.fail(req, entry, err)
+	}
+	return signature, nil
+}
+
+// fail records the failure of an approved request, which doesn't count against
+// the rate limit of the approving rule.
+func (api *SignerAPI) fail(req *Request, entry *AuditEntry, err error) error {
+	entry.Error = err.Error()
+	api.rules.Refund(req, entry.Rule)
+	return err
+}
+
+// decide evaluates a request against the rule set, recording the decision.
+func (api *SignerAPI) decide(req *Request, entry *AuditEntry) error {
+	action, rule := api.rules.Evaluate(req)
+	entry.Rule, entry.Decision = rule, action
+	if action != ActionApprove {
+		entry.Error = ErrRequestDenied.Error()
+		return ErrRequestDenied
+	}
+	return nil
+}
+
+// SignDataHash calculates the hash signed by account_signData for data.
+func SignDataHash(data []byte) []byte {
+	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(data), data)
+	return crypto.Keccak256([]byte(msg))
+}
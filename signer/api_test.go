@@ -0,0 +1,196 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package signer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ur-technology/go-ur/accounts"
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/common/hexutil"
+	"github.com/ur-technology/go-ur/core/types"
+	"github.com/ur-technology/go-ur/crypto"
+	"github.com/ur-technology/go-ur/rlp"
+	"golang.org/x/net/context"
+)
+
+// testSigner creates a signer API over a temporary keystore with a single
+// unlocked account, approving only transactions to member.
+func testSigner(t *testing.T) (string, *SignerAPI, accounts.Account, *bytes.Buffer) {
+	dir, err := ioutil.TempDir("", "ur-signer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := accounts.NewKeyStore(dir, 2, 1)
+	account, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Unlock(account, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := ParseRuleset([]byte(`{"rules": [
+		{"name": "members", "to": ["` + member.Hex() + `"], "action": "approve"},
+		{"name": "data", "methods": ["account_signData"], "action": "approve"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit := new(bytes.Buffer)
+	return dir, NewSignerAPI(accounts.NewManager(ks), rules, NewAuditLog(audit)), account, audit
+}
+
+// Tests that approved transactions are signed, denied ones aren't and that all
+// requests end up in the audit log.
+func TestSignTransaction(t *testing.T) {
+	dir, api, account, audit := testSigner(t)
+	defer os.RemoveAll(dir)
+
+	if list := api.List(context.Background()); len(list) != 1 || list[0] != account.Address {
+		t.Fatalf("account list mismatch: have %v, want [%x]", list, account.Address)
+	}
+	args := SendTxArgs{
+		From:    account.Address,
+		To:      &member,
+		Gas:     hexutil.Big(*big.NewInt(21000)),
+		Value:   hexutil.Big(*big.NewInt(1)),
+		Nonce:   3,
+		ChainID: (*hexutil.Big)(big.NewInt(18)),
+	}
+	res, err := api.SignTransaction(context.Background(), args)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(res.Raw, tx); err != nil {
+		t.Fatalf("failed to decode signed transaction: %v", err)
+	}
+	if sender, err := types.Sender(types.NewEIP155Signer(big.NewInt(18)), tx); err != nil || sender != account.Address {
+		t.Errorf("sender mismatch: have %x, %v, want %x", sender, err, account.Address)
+	}
+	if tx.Nonce() != 3 || *tx.To() != member {
+		t.Errorf("transaction mismatch: nonce %d, to %x", tx.Nonce(), tx.To())
+	}
+	args.To = &privileged
+	if _, err := api.SignTransaction(context.Background(), args); err != ErrRequestDenied {
+		t.Errorf("unmatched transaction: have %v, want %v", err, ErrRequestDenied)
+	}
+	// Check the audit trail of the three requests
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("audit entry count mismatch: have %d, want 3", len(lines))
+	}
+	var entries [3]AuditEntry
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &entries[i]); err != nil {
+			t.Fatalf("entry %d: invalid audit entry: %v", i, err)
+		}
+	}
+	if entries[0].Method != "account_list" {
+		t.Errorf("entry 0: method mismatch: have %s", entries[0].Method)
+	}
+	if e := entries[1]; e.Decision != ActionApprove || e.Rule != "members" || e.Hash == nil || *e.Hash != tx.Hash() {
+		t.Errorf("entry 1: approval mismatch: %+v", e)
+	}
+	if e := entries[2]; e.Decision != ActionReject || e.Rule != defaultRule || e.Error != ErrRequestDenied.Error() {
+		t.Errorf("entry 2: rejection mismatch: %+v", e)
+	}
+}
+
+func TestSignData(t *testing.T) {
+	dir, api, account, _ := testSigner(t)
+	defer os.RemoveAll(dir)
+
+	data := []byte("hello ur")
+	sig, err := api.SignData(context.Background(), account.Address, data)
+	if err != nil {
+		t.Fatalf("failed to sign data: %v", err)
+	}
+	sig[64] -= 27 // Signatures are in yellow paper format
+	pubkey, err := crypto.Ecrecover(SignDataHash(data), sig)
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if signer := common.BytesToAddress(crypto.Keccak256(pubkey[1:])[12:]); signer != account.Address {
+		t.Errorf("signer mismatch: have %x, want %x", signer, account.Address)
+	}
+	if _, err := api.SignData(context.Background(), member, data); err != accounts.ErrUnknownAccount {
+		t.Errorf("unknown account: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+}
+
+// Tests that only successfully signed requests count against the rate limit of
+// the approving rule.
+func TestRateLimitRefund(t *testing.T) {
+	dir, api, account, _ := testSigner(t)
+	defer os.RemoveAll(dir)
+
+	rules, err := ParseRuleset([]byte(`{"rules": [
+		{"name": "hashes", "methods": ["account_signHash"], "limit": {"count": 1, "period": "1h"}, "action": "approve"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.rules = rules
+
+	hash := crypto.Keccak256([]byte("hello ur"))
+	if _, err := api.SignHash(context.Background(), account.Address, hash[:16]); err != errInvalidHash {
+		t.Errorf("short hash: have %v, want %v", err, errInvalidHash)
+	}
+	if _, err := api.SignHash(context.Background(), member, hash); err != accounts.ErrUnknownAccount {
+		t.Errorf("unknown account: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+	if _, err := api.SignHash(context.Background(), account.Address, hash); err != nil {
+		t.Errorf("failed to sign hash after a failed request: %v", err)
+	}
+	if _, err := api.SignHash(context.Background(), account.Address, hash); err != ErrRequestDenied {
+		t.Errorf("exhausted rule: have %v, want %v", err, ErrRequestDenied)
+	}
+}
+
+// Tests that hashes are only signed if a rule explicitly approves signHash, not
+// by rules applying to any method.
+func TestSignHashExplicit(t *testing.T) {
+	dir, api, account, _ := testSigner(t)
+	defer os.RemoveAll(dir)
+
+	rules, err := ParseRuleset([]byte(`{"rules": [
+		{"name": "any", "from": ["` + account.Address.Hex() + `"], "limit": {"count": 10, "period": "1h"}, "action": "approve"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.rules = rules
+
+	hash := crypto.Keccak256([]byte("hello ur"))
+	if _, err := api.SignHash(context.Background(), account.Address, hash); err != ErrRequestDenied {
+		t.Errorf("method-less rule: have %v, want %v", err, ErrRequestDenied)
+	}
+	if _, err := api.SignData(context.Background(), account.Address, hash); err != nil {
+		t.Errorf("failed to sign data with method-less rule: %v", err)
+	}
+	rules.Rules[0].Methods = []string{"account_signHash"}
+	if _, err := api.SignHash(context.Background(), account.Address, hash); err != nil {
+		t.Errorf("failed to sign hash with explicit rule: %v", err)
+	}
+}
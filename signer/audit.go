@@ -0,0 +1,75 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package signer
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/common/hexutil"
+	"github.com/ur-technology/go-ur/logger"
+	"github.com/ur-technology/go-ur/logger/glog"
+)
+
+// AuditEntry is a single line of the audit log, recording a request, the
+// decision taken on it and its outcome.
+type AuditEntry struct {
+	Time     time.Time       `json:"time"`
+	Method   string          `json:"method"`
+	From     *common.Address `json:"from,omitempty"`
+	To       *common.Address `json:"to,omitempty"`
+	Value    *hexutil.Big    `json:"value,omitempty"`
+	Nonce    *hexutil.Uint64 `json:"nonce,omitempty"`
+	Hash     *common.Hash    `json:"hash,omitempty"` // Hash of the signed transaction or data
+	Rule     string          `json:"rule,omitempty"`
+	Decision Action          `json:"decision,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// AuditLog writes one JSON encoded AuditEntry per line to a writer, typically
+// a file opened for appending.
+type AuditLog struct {
+	out  io.Writer
+	lock sync.Mutex
+}
+
+// NewAuditLog creates an audit log writing into out.
+func NewAuditLog(out io.Writer) *AuditLog {
+	return &AuditLog{out: out}
+}
+
+// Log appends an entry to the audit log. Failures to write are reported but do
+// not abort the request, the log being best effort.
+func (l *AuditLog) Log(entry *AuditEntry) {
+	if l == nil {
+		return
+	}
+	blob, err := json.Marshal(entry)
+	if err != nil {
+		glog.V(logger.Error).Infof("Failed to encode audit entry: %v", err)
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if _, err := l.out.Write(append(blob, '\n')); err != nil {
+		glog.V(logger.Error).Infof("Failed to write audit entry: %v", err)
+	}
+}
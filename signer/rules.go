@@ -0,0 +1,243 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package signer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/common/hexutil"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/params"
+)
+
+// Action is the verdict of a rule on a signing request.
+type Action string
+
+const (
+	ActionApprove Action = "approve" // Sign the request
+	ActionReject  Action = "reject"  // Refuse the request
+)
+
+// defaultRule is the rule name reported when no rule matched a request.
+const defaultRule = "default"
+
+// explicitMethods are the methods whose requests rules without methods don't
+// match. Blindly signed hashes may be those of any transaction, so approving
+// them must be a deliberate choice.
+var explicitMethods = map[string]bool{
+	"account_signHash": true,
+}
+
+// Request is a signing request as seen by the rule engine.
+type Request struct {
+	Method string          // RPC method of the request (e.g. account_signTransaction)
+	From   common.Address  // Account requested to sign
+	To     *common.Address // Recipient of a transaction, nil for contract creations and data
+	Value  *big.Int        // Value of a transaction, nil for data
+	Data   []byte          // Transaction input or signed data
+	Time   time.Time       // Time of the request, used for rate limiting
+}
+
+// RateLimit caps the number of requests a rule approves within a sliding window.
+type RateLimit struct {
+	Count  int    `json:"count"`  // Maximum number of approvals within the period
+	Period string `json:"period"` // Length of the window, e.g. "1h" or "30m"
+
+	period time.Duration
+}
+
+// Rule matches signing requests and decides on them. All the set criteria must
+// hold for a request to match, unset ones match anything. The exception are the
+// explicitMethods, only matched by rules listing them.
+type Rule struct {
+	Name     string           `json:"name"`
+	Methods  []string         `json:"methods"`  // RPC methods the rule applies to
+	From     []common.Address `json:"from"`     // Accounts the rule applies to
+	To       []common.Address `json:"to"`       // Transaction recipients the rule applies to
+	MaxValue *hexutil.Big     `json:"maxValue"` // Maximum transaction value, inclusive
+	Signup   bool             `json:"signup"`   // Whether only UR signup transactions of the chain match
+	Limit    *RateLimit       `json:"limit"`    // Approval rate limit, exhausted rules don't match
+	Action   Action           `json:"action"`
+
+	approvals []time.Time // Approvals within the rate limit window
+}
+
+// Ruleset is an ordered list of rules, the first matching one deciding on a
+// request. Requests matching no rule get the default action.
+type Ruleset struct {
+	Rules   []*Rule `json:"rules"`
+	Default Action  `json:"default"`
+
+	config *params.ChainConfig // Chain rules telling signup transactions apart
+	lock   sync.Mutex
+}
+
+// LoadRuleset reads and validates a JSON rule file.
+func LoadRuleset(file string) (*Ruleset, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRuleset(blob)
+}
+
+// ParseRuleset decodes and validates a JSON rule set. A missing default action
+// rejects unmatched requests. Signups are recognized by the rules of the main
+// network unless set otherwise with SetChainConfig.
+func ParseRuleset(blob []byte) (*Ruleset, error) {
+	rules := &Ruleset{config: params.MainnetChainConfig}
+	if err := json.Unmarshal(blob, rules); err != nil {
+		return nil, err
+	}
+	if rules.Default == "" {
+		rules.Default = ActionReject
+	}
+	if err := checkAction(rules.Default); err != nil {
+		return nil, fmt.Errorf("default: %v", err)
+	}
+	for i, rule := range rules.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("#%d", i)
+		}
+		if err := checkAction(rule.Action); err != nil {
+			return nil, fmt.Errorf("rule %s: %v", rule.Name, err)
+		}
+		if rule.Limit != nil {
+			period, err := time.ParseDuration(rule.Limit.Period)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: invalid period: %v", rule.Name, err)
+			}
+			if period <= 0 || rule.Limit.Count <= 0 {
+				return nil, fmt.Errorf("rule %s: rate limit count and period must be positive", rule.Name)
+			}
+			rule.Limit.period = period
+		}
+	}
+	return rules, nil
+}
+
+// SetChainConfig sets the chain rules whose privileged addresses signup rules
+// match.
+func (rs *Ruleset) SetChainConfig(config *params.ChainConfig) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	rs.config = config
+}
+
+func checkAction(action Action) error {
+	switch action {
+	case ActionApprove, ActionReject:
+		return nil
+	}
+	return fmt.Errorf("unknown action %q", action)
+}
+
+// Evaluate decides on a signing request, returning the action to take and the
+// name of the deciding rule. Approvals are counted against the rate limit of
+// the approving rule until refunded.
+func (rs *Ruleset) Evaluate(req *Request) (Action, string) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	for _, rule := range rs.Rules {
+		if !rule.matches(rs.config, req) {
+			continue
+		}
+		if rule.Limit != nil {
+			// Drop approvals that left the window and skip exhausted rules
+			cutoff := req.Time.Add(-rule.Limit.period)
+			for len(rule.approvals) > 0 && !rule.approvals[0].After(cutoff) {
+				rule.approvals = rule.approvals[1:]
+			}
+			if len(rule.approvals) >= rule.Limit.Count {
+				continue
+			}
+			if rule.Action == ActionApprove {
+				rule.approvals = append(rule.approvals, req.Time)
+			}
+		}
+		return rule.Action, rule.Name
+	}
+	return rs.Default, defaultRule
+}
+
+// Refund withdraws the approval of a request by the given rule from its rate
+// limit, the request having failed to be signed.
+func (rs *Ruleset) Refund(req *Request, rule string) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	for _, r := range rs.Rules {
+		if r.Name != rule || r.Limit == nil {
+			continue
+		}
+		for i := len(r.approvals) - 1; i >= 0; i-- {
+			if r.approvals[i].Equal(req.Time) {
+				r.approvals = append(r.approvals[:i], r.approvals[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// matches reports whether all the criteria of the rule hold for a request.
+func (r *Rule) matches(config *params.ChainConfig, req *Request) bool {
+	if len(r.Methods) == 0 && explicitMethods[req.Method] {
+		return false
+	}
+	if len(r.Methods) > 0 && !containsString(r.Methods, req.Method) {
+		return false
+	}
+	if len(r.From) > 0 && !containsAddress(r.From, req.From) {
+		return false
+	}
+	if len(r.To) > 0 && (req.To == nil || !containsAddress(r.To, *req.To)) {
+		return false
+	}
+	if r.MaxValue != nil && (req.Value == nil || req.Value.Cmp((*big.Int)(r.MaxValue)) > 0) {
+		return false
+	}
+	if r.Signup && (req.Value == nil || req.To == nil || !core.IsSignupTx(config, req.From, req.Value, req.Data)) {
+		return false
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAddress(list []common.Address, addr common.Address) bool {
+	for _, item := range list {
+		if item == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package signer
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core"
+	"github.com/ur-technology/go-ur/params"
+)
+
+var (
+	privileged = common.HexToAddress("0x5d32e21bf3594aa66c205fde8dbee3dc726bd61d")
+	member     = common.HexToAddress("0x59ab9bb134b529709333f7ae68f3f93c204d280b")
+)
+
+const testRules = `{
+	"rules": [
+		{
+			"name": "signups",
+			"methods": ["account_signTransaction"],
+			"from": ["0x5d32e21bf3594aa66c205fde8dbee3dc726bd61d"],
+			"signup": true,
+			"limit": {"count": 2, "period": "1h"},
+			"action": "approve"
+		},
+		{
+			"name": "small",
+			"methods": ["account_signTransaction"],
+			"maxValue": "0x64",
+			"action": "approve"
+		}
+	]
+}`
+
+// Tests that rules are matched in order, rate limits expire over time and that
+// unmatched requests get the default action.
+func TestRulesetEvaluate(t *testing.T) {
+	rules, err := ParseRuleset([]byte(testRules))
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+	rules.SetChainConfig(&params.ChainConfig{
+		Privileged: map[common.Address]params.PrivilegedReceivers{privileged: {}},
+	})
+	now := time.Now()
+	signup := func(at time.Time) *Request {
+		return &Request{Method: "account_signTransaction", From: privileged, To: &member, Value: big.NewInt(1), Data: core.SignupData(0, common.Hash{}), Time: at}
+	}
+	tests := []struct {
+		req    *Request
+		action Action
+		rule   string
+	}{
+		{signup(now), ActionApprove, "signups"},
+		{signup(now.Add(time.Minute)), ActionApprove, "signups"},
+		{signup(now.Add(2 * time.Minute)), ActionApprove, "small"}, // Rate limited, falls through
+		{signup(now.Add(time.Hour + time.Second)), ActionApprove, "signups"},
+		{&Request{Method: "account_signTransaction", From: member, To: &privileged, Value: big.NewInt(1000), Time: now}, ActionReject, defaultRule},
+		{&Request{Method: "account_signData", From: member, Data: []byte("hello"), Time: now}, ActionReject, defaultRule},
+		{&Request{Method: "account_signTransaction", From: member, To: &privileged, Value: big.NewInt(1), Data: core.SignupData(0, common.Hash{}), Time: now}, ActionApprove, "small"},
+	}
+	for i, tt := range tests {
+		action, rule := rules.Evaluate(tt.req)
+		if action != tt.action || rule != tt.rule {
+			t.Errorf("test %d: decision mismatch: have %s by %s, want %s by %s", i, action, rule, tt.action, tt.rule)
+		}
+	}
+}
+
+func TestRulesetValidation(t *testing.T) {
+	invalid := []string{
+		`{"default": "maybe"}`,
+		`{"rules": [{"action": "sign"}]}`,
+		`{"rules": [{"action": "approve", "limit": {"count": 1, "period": "soon"}}]}`,
+		`{"rules": [{"action": "approve", "limit": {"count": 0, "period": "1h"}}]}`,
+	}
+	for i, blob := range invalid {
+		if _, err := ParseRuleset([]byte(blob)); err == nil {
+			t.Errorf("test %d: invalid rule set accepted: %s", i, blob)
+		}
+	}
+	rules, err := ParseRuleset([]byte(`{}`))
+	if err != nil {
+		t.Fatalf("failed to parse empty rule set: %v", err)
+	}
+	if rules.Default != ActionReject {
+		t.Errorf("default action mismatch: have %s, want %s", rules.Default, ActionReject)
+	}
+}
@@ -0,0 +1,425 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package signer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/crypto"
+)
+
+// domainType is the name of the struct type describing the signing domain.
+const domainType = "EIP712Domain"
+
+// maxTypedDataDepth limits the nesting of structs and arrays in typed data.
+const maxTypedDataDepth = 32
+
+var (
+	// typeNameRegexp matches valid struct type names.
+	typeNameRegexp = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*$`)
+
+	// arrayTypeRegexp splits array types into their element type and length.
+	arrayTypeRegexp = regexp.MustCompile(`^(.+)\[([0-9]*)\]$`)
+
+	// domainFields are the fields allowed in the domain and their types.
+	domainFields = map[string]string{
+		"name":              "string",
+		"version":           "string",
+		"chainId":           "uint256",
+		"verifyingContract": "address",
+		"salt":              "bytes32",
+	}
+)
+
+// Type is a single named and typed field of a struct type.
+type Type struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Types are the struct type definitions of a typed data payload.
+type Types map[string][]Type
+
+// TypedData is a structured payload to sign according to EIP-712: a message of
+// the primary struct type, bound to a signing domain so that signatures can't
+// be replayed across applications or chains.
+type TypedData struct {
+	Types       Types                  `json:"types"`
+	PrimaryType string                 `json:"primaryType"`
+	Domain      map[string]interface{} `json:"domain"`
+	Message     map[string]interface{} `json:"message"`
+}
+
+// ParseTypedData decodes and validates a JSON typed data payload.
+func ParseTypedData(blob []byte) (*TypedData, error) {
+	td := new(TypedData)
+	if err := json.Unmarshal(blob, td); err != nil {
+		return nil, err
+	}
+	if err := td.Validate(); err != nil {
+		return nil, err
+	}
+	return td, nil
+}
+
+// UnmarshalJSON decodes typed data, keeping the exact value of large numbers
+// that wouldn't fit into a float64.
+func (td *TypedData) UnmarshalJSON(input []byte) error {
+	type typedData TypedData
+
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+	return dec.Decode((*typedData)(td))
+}
+
+// Validate checks that all the struct types are well formed and only refer to
+// known types, and that the domain and the primary type are defined.
+func (td *TypedData) Validate() error {
+	domain, ok := td.Types[domainType]
+	if !ok {
+		return fmt.Errorf("missing %s type", domainType)
+	}
+	for _, field := range domain {
+		if kind, ok := domainFields[field.Name]; !ok || kind != field.Type {
+			return fmt.Errorf("invalid %s field %s %s", domainType, field.Type, field.Name)
+		}
+	}
+	if td.PrimaryType == "" || td.PrimaryType == domainType {
+		return errors.New("missing or invalid primary type")
+	}
+	if _, ok := td.Types[td.PrimaryType]; !ok {
+		return fmt.Errorf("unknown primary type %s", td.PrimaryType)
+	}
+	for name, fields := range td.Types {
+		if !typeNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid type name %q", name)
+		}
+		seen := make(map[string]bool)
+		for _, field := range fields {
+			if field.Name == "" || seen[field.Name] {
+				return fmt.Errorf("type %s: empty or duplicate field name %q", name, field.Name)
+			}
+			seen[field.Name] = true
+			if !td.validType(field.Type) {
+				return fmt.Errorf("type %s: unknown type %s of field %s", name, field.Type, field.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// validType reports whether kind is an atomic, dynamic, struct or array type.
+func (td *TypedData) validType(kind string) bool {
+	if match := arrayTypeRegexp.FindStringSubmatch(kind); match != nil {
+		return td.validType(match[1])
+	}
+	if _, ok := td.Types[kind]; ok {
+		return true
+	}
+	switch kind {
+	case "address", "bool", "string", "bytes":
+		return true
+	}
+	if size, ok := typeSize(kind, "bytes"); ok {
+		return size >= 1 && size <= 32
+	}
+	for _, prefix := range []string{"uint", "int"} {
+		if size, ok := typeSize(kind, prefix); ok {
+			return size >= 8 && size <= 256 && size%8 == 0
+		}
+	}
+	return false
+}
+
+// typeSize parses the size suffix of a sized atomic type (e.g. bytes32).
+func typeSize(kind, prefix string) (int, bool) {
+	if !strings.HasPrefix(kind, prefix) {
+		return 0, false
+	}
+	suffix := kind[len(prefix):]
+	if suffix == "" && prefix != "bytes" {
+		return 256, true // uint and int are aliases of uint256 and int256
+	}
+	size, err := strconv.Atoi(suffix)
+	if err != nil || strconv.Itoa(size) != suffix {
+		return 0, false
+	}
+	return size, true
+}
+
+// Hash calculates the hash to sign for the typed data:
+// keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message)).
+func (td *TypedData) Hash() ([]byte, error) {
+	if err := td.Validate(); err != nil {
+		return nil, err
+	}
+	domain, err := td.HashStruct(domainType, td.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("domain: %v", err)
+	}
+	message, err := td.HashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		return nil, fmt.Errorf("message: %v", err)
+	}
+	return crypto.Keccak256([]byte("\x19\x01"), domain, message), nil
+}
+
+// HashStruct calculates keccak256(typeHash ‖ encodeData(data)) for a value of
+// the given struct type.
+func (td *TypedData) HashStruct(kind string, data map[string]interface{}) ([]byte, error) {
+	return td.hashStruct(kind, data, 0)
+}
+
+func (td *TypedData) hashStruct(kind string, data map[string]interface{}, depth int) ([]byte, error) {
+	encoded, err := td.encodeData(kind, data, depth)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(encoded), nil
+}
+
+// TypeHash calculates the keccak256 hash of the encoding of a struct type.
+func (td *TypedData) TypeHash(kind string) []byte {
+	return crypto.Keccak256([]byte(td.EncodeType(kind)))
+}
+
+// EncodeType encodes a struct type as its signature followed by the signatures
+// of all the struct types it refers to, sorted by name, e.g.
+// Mail(Person from,Person to,string contents)Person(string name,address wallet).
+func (td *TypedData) EncodeType(kind string) string {
+	deps := td.dependencies(kind, make(map[string]bool))
+	if len(deps) > 1 {
+		sort.Strings(deps[1:])
+	}
+
+	var buf bytes.Buffer
+	for _, dep := range deps {
+		buf.WriteString(dep + "(")
+		for i, field := range td.Types[dep] {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString(field.Type + " " + field.Name)
+		}
+		buf.WriteString(")")
+	}
+	return buf.String()
+}
+
+// dependencies collects a struct type and all the struct types it refers to,
+// the type itself being first.
+func (td *TypedData) dependencies(kind string, found map[string]bool) []string {
+	kind = elementType(kind)
+	if _, ok := td.Types[kind]; !ok || found[kind] {
+		return nil
+	}
+	found[kind] = true
+
+	deps := []string{kind}
+	for _, field := range td.Types[kind] {
+		deps = append(deps, td.dependencies(field.Type, found)...)
+	}
+	return deps
+}
+
+// elementType strips all the array suffixes from a type.
+func elementType(kind string) string {
+	for {
+		match := arrayTypeRegexp.FindStringSubmatch(kind)
+		if match == nil {
+			return kind
+		}
+		kind = match[1]
+	}
+}
+
+// encodeData encodes a struct value as its type hash followed by the 32 byte
+// encoding of every field.
+func (td *TypedData) encodeData(kind string, data map[string]interface{}, depth int) ([]byte, error) {
+	if depth > maxTypedDataDepth {
+		return nil, errors.New("typed data nested too deep")
+	}
+	fields := td.Types[kind]
+	if len(data) > len(fields) {
+		return nil, fmt.Errorf("%s: %d fields provided, %d defined", kind, len(data), len(fields))
+	}
+	buf := bytes.NewBuffer(td.TypeHash(kind))
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing field %s", kind, field.Name)
+		}
+		encoded, err := td.encodeValue(field.Type, value, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", kind, field.Name, err)
+		}
+		buf.Write(encoded)
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeValue encodes a single value of the given type into 32 bytes.
+func (td *TypedData) encodeValue(kind string, value interface{}, depth int) ([]byte, error) {
+	// Arrays are encoded as the hash of the concatenated element encodings
+	if match := arrayTypeRegexp.FindStringSubmatch(kind); match != nil {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array, got %T", value)
+		}
+		if match[2] != "" {
+			if size, _ := strconv.Atoi(match[2]); size != len(items) {
+				return nil, fmt.Errorf("expected %d items, got %d", size, len(items))
+			}
+		}
+		var buf bytes.Buffer
+		for i, item := range items {
+			encoded, err := td.encodeValue(match[1], item, depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %v", i, err)
+			}
+			buf.Write(encoded)
+		}
+		return crypto.Keccak256(buf.Bytes()), nil
+	}
+	// Structs are encoded as their hash
+	if _, ok := td.Types[kind]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object, got %T", value)
+		}
+		return td.hashStruct(kind, data, depth)
+	}
+	// Atomic and dynamic types
+	switch kind {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		return crypto.Keccak256([]byte(str)), nil
+
+	case "bytes":
+		blob, err := parseBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(blob), nil
+
+	case "bool":
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %T", value)
+		}
+		if flag {
+			return common.LeftPadBytes([]byte{1}, 32), nil
+		}
+		return make([]byte, 32), nil
+
+	case "address":
+		str, ok := value.(string)
+		if !ok || !common.IsHexAddress(str) {
+			return nil, fmt.Errorf("invalid address %v", value)
+		}
+		return common.LeftPadBytes(common.HexToAddress(str).Bytes(), 32), nil
+	}
+	if size, ok := typeSize(kind, "bytes"); ok {
+		blob, err := parseBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(blob) != size {
+			return nil, fmt.Errorf("expected %d bytes, got %d", size, len(blob))
+		}
+		return common.RightPadBytes(blob, 32), nil
+	}
+	signed := strings.HasPrefix(kind, "int")
+	size, _ := typeSize(kind, strings.TrimRight(kind, "0123456789"))
+
+	num, err := parseInteger(value)
+	if err != nil {
+		return nil, err
+	}
+	return encodeInteger(num, size, signed)
+}
+
+// parseBytes parses a 0x prefixed hex string.
+func parseBytes(value interface{}) ([]byte, error) {
+	str, ok := value.(string)
+	if !ok || !strings.HasPrefix(str, "0x") {
+		return nil, fmt.Errorf("expected hex string, got %v", value)
+	}
+	blob := common.FromHex(str)
+	if len(str) > 2 && len(blob) == 0 {
+		return nil, fmt.Errorf("invalid hex string %s", str)
+	}
+	return blob, nil
+}
+
+// parseInteger parses a JSON number, decimal string or 0x prefixed hex string.
+func parseInteger(value interface{}) (*big.Int, error) {
+	var str string
+	switch v := value.(type) {
+	case json.Number:
+		str = v.String()
+	case float64:
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("invalid integer %v", v)
+		}
+		return big.NewInt(int64(v)), nil
+	case string:
+		str = v
+	default:
+		return nil, fmt.Errorf("expected integer, got %T", value)
+	}
+	num, ok := new(big.Int), false
+	if strings.HasPrefix(str, "0x") {
+		num, ok = num.SetString(str[2:], 16)
+	} else {
+		num, ok = num.SetString(str, 10)
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %q", str)
+	}
+	return num, nil
+}
+
+// encodeInteger encodes an integer of the given bit size into 32 bytes, using
+// two's complement for negative values.
+func encodeInteger(num *big.Int, size int, signed bool) ([]byte, error) {
+	limit := new(big.Int).Lsh(common.Big1, uint(size))
+	if signed {
+		limit.Rsh(limit, 1)
+		if num.Cmp(limit) >= 0 || num.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("%v overflows int%d", num, size)
+		}
+	} else if num.Sign() < 0 || num.Cmp(limit) >= 0 {
+		return nil, fmt.Errorf("%v overflows uint%d", num, size)
+	}
+	if num.Sign() < 0 {
+		num = new(big.Int).Add(num, new(big.Int).Lsh(common.Big1, 256))
+	}
+	return common.LeftPadBytes(num.Bytes(), 32), nil
+}
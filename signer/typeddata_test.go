@@ -0,0 +1,167 @@
+// Copyright 2017 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package signer
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/crypto"
+)
+
+// mailTypedData is the example payload of the EIP-712 specification.
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+// Tests typed data hashing and signing against the EIP-712 reference example.
+func TestTypedDataHash(t *testing.T) {
+	td, err := ParseTypedData([]byte(mailTypedData))
+	if err != nil {
+		t.Fatalf("failed to parse typed data: %v", err)
+	}
+	if enc := td.EncodeType("Mail"); enc != "Mail(Person from,Person to,string contents)Person(string name,address wallet)" {
+		t.Errorf("type encoding mismatch: have %s", enc)
+	}
+	if hash := hex.EncodeToString(td.TypeHash("Mail")); hash != "a0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2" {
+		t.Errorf("type hash mismatch: have %s", hash)
+	}
+	domain, err := td.HashStruct("EIP712Domain", td.Domain)
+	if err != nil {
+		t.Fatalf("failed to hash domain: %v", err)
+	}
+	if hash := hex.EncodeToString(domain); hash != "f2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f" {
+		t.Errorf("domain separator mismatch: have %s", hash)
+	}
+	message, err := td.HashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		t.Fatalf("failed to hash message: %v", err)
+	}
+	if hash := hex.EncodeToString(message); hash != "c52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e" {
+		t.Errorf("message hash mismatch: have %s", hash)
+	}
+	hash, err := td.Hash()
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	if have := hex.EncodeToString(hash); have != "be609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2" {
+		t.Errorf("signing hash mismatch: have %s", have)
+	}
+	// Recover the signer of the reference signature (v = 28)
+	sig := common.FromHex("0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b9156201")
+	pubkey, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if signer := crypto.PubkeyToAddress(*crypto.ToECDSAPub(pubkey)); signer != common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826") {
+		t.Errorf("signer mismatch: have %x", signer)
+	}
+}
+
+// Tests that malformed schemas and messages not matching them are rejected.
+func TestTypedDataValidation(t *testing.T) {
+	invalid := []struct {
+		from, to string
+	}{
+		{`"primaryType": "Mail"`, `"primaryType": "Letter"`},
+		{`"primaryType": "Mail"`, `"primaryType": "EIP712Domain"`},
+		{`"type": "Person"}`, `"type": "Human"}`},
+		{`"type": "address"}`, `"type": "address40"}`},
+		{`"type": "uint256"}`, `"type": "uint7"}`},
+		{`"name": "chainId", "type": "uint256"`, `"name": "chainId", "type": "string"`},
+		{`"Cow"`, `7`},
+		{`"0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"`, `"0x1234"`},
+		{`"contents": "Hello, Bob!"`, `"contents": "Hello, Bob!", "extra": 1`},
+		{`"contents": "Hello, Bob!"`, `"content": "Hello, Bob!"`},
+		{`"chainId": 1`, `"chainId": -1`},
+	}
+	for i, tt := range invalid {
+		blob := strings.Replace(mailTypedData, tt.from, tt.to, 1)
+		if blob == mailTypedData {
+			t.Fatalf("test %d: replacement %q not found", i, tt.from)
+		}
+		td, err := ParseTypedData([]byte(blob))
+		if err != nil {
+			continue
+		}
+		if _, err := td.Hash(); err == nil {
+			t.Errorf("test %d: invalid typed data accepted", i)
+		}
+	}
+}
+
+func TestTypedDataIntegers(t *testing.T) {
+	tests := []struct {
+		kind  string
+		value string
+		enc   string
+	}{
+		{"uint8", "255", "00000000000000000000000000000000000000000000000000000000000000ff"},
+		{"uint8", "256", ""},
+		{"int8", "-128", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff80"},
+		{"int8", "128", ""},
+		{"uint256", "0x10", "0000000000000000000000000000000000000000000000000000000000000010"},
+		{"int", "-1", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+	}
+	td := &TypedData{Types: Types{}}
+	for i, tt := range tests {
+		enc, err := td.encodeValue(tt.kind, tt.value, 0)
+		if tt.enc == "" {
+			if err == nil {
+				t.Errorf("test %d: %s %s accepted", i, tt.kind, tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to encode %s %s: %v", i, tt.kind, tt.value, err)
+			continue
+		}
+		if have := hex.EncodeToString(enc); have != tt.enc {
+			t.Errorf("test %d: encoding mismatch: have %s, want %s", i, have, tt.enc)
+		}
+	}
+}
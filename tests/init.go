@@ -63,6 +63,50 @@ var (
 	}
 	StateSkipTests = []string{}
 	VmSkipTests    = []string{}
+
+	// VmJitSkipTests are VM tests known to diverge between the interpreter
+	// and the JIT, excluded from differential testing. The JIT charges the
+	// Frontier base costs for BALANCE, EXTCODESIZE, EXTCODECOPY and the CALL
+	// family instead of the ones in the fork's gas table.
+	VmJitSkipTests = []string{
+		// vmEnvironmentalInfoTest
+		"ExtCodeSizeAddressInputTooBigLeftMyAddress",
+		"ExtCodeSizeAddressInputTooBigRightMyAddress",
+		"balance0",
+		"balance01",
+		"balance1",
+		"balanceAddress2",
+		"balanceAddressInputTooBig",
+		"balanceAddressInputTooBigLeftMyAddress",
+		"balanceAddressInputTooBigRightMyAddress",
+		"balanceCaller3",
+		"env1",
+		"extcodecopy0",
+		"extcodecopy0AddressTooBigLeft",
+		"extcodecopy0AddressTooBigRight",
+		"extcodecopyZeroMemExpansion",
+		"extcodecopy_DataIndexTooHigh",
+		"extcodesize0",
+		"extcodesize1",
+
+		// vmSystemOperationsTest
+		"ABAcalls0",
+		"ABAcallsSuicide0",
+		"ABAcallsSuicide1",
+		"CallRecursiveBomb0",
+		"CallRecursiveBomb1",
+		"CallRecursiveBomb2",
+		"CallRecursiveBomb3",
+		"CallToNameRegistrator0",
+		"CallToPrecompiledContract",
+		"CallToReturn1",
+		"PostToNameRegistrator0",
+		"PostToReturn1",
+		"callcodeToNameRegistrator0",
+		"callcodeToReturn1",
+		"callstatelessToNameRegistrator0",
+		"callstatelessToReturn1",
+	}
 )
 
 func readJson(reader io.Reader, value interface{}) error {
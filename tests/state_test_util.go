@@ -220,8 +220,6 @@ func RunState(chainConfig *params.ChainConfig, statedb *state.StateDB, env, tx m
 		t := common.HexToAddress(tx["to"])
 		to = &t
 	}
-	// Set pre compiled contracts
-	vm.Precompiled = vm.PrecompiledContracts()
 	gaspool := new(core.GasPool).AddGas(common.Big(env["currentGasLimit"]))
 
 	key, _ := hex.DecodeString(tx["secretKey"])
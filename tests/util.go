@@ -168,6 +168,7 @@ type Env struct {
 	vmTest bool
 
 	evm *vm.EVM
+	jit *vm.ProgramVm // executes contracts through the JIT only if set
 }
 
 func NewEnv(chainConfig *params.ChainConfig, state *state.StateDB) *Env {
@@ -199,7 +200,6 @@ func NewEnvFromMap(chainConfig *params.ChainConfig, state *state.StateDB, envVal
 }
 
 func (self *Env) ChainConfig() *params.ChainConfig { return self.chainConfig }
-func (self *Env) Vm() vm.Vm                        { return self.evm }
 func (self *Env) Origin() common.Address           { return self.origin }
 func (self *Env) BlockNumber() *big.Int            { return self.number }
 func (self *Env) Coinbase() common.Address         { return self.coinbase }
@@ -208,6 +208,12 @@ func (self *Env) Difficulty() *big.Int             { return self.difficulty }
 func (self *Env) Db() vm.Database                  { return self.state }
 func (self *Env) GasLimit() *big.Int               { return self.gasLimit }
 func (self *Env) VmType() vm.Type                  { return vm.StdVmTy }
+func (self *Env) Vm() vm.Vm {
+	if self.jit != nil {
+		return self.jit
+	}
+	return self.evm
+}
 func (self *Env) GetHash(n uint64) common.Hash {
 	return common.BytesToHash(crypto.Keccak256([]byte(big.NewInt(int64(n)).String())))
 }
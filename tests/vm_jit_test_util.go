@@ -0,0 +1,274 @@
+// Copyright 2016 The go-ur Authors
+// This file is part of the go-ur library.
+//
+// The go-ur library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ur library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ur library. If not, see <http://www.gnu.org/licenses/>.
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/ur-technology/go-ur/common"
+	"github.com/ur-technology/go-ur/core/state"
+	"github.com/ur-technology/go-ur/core/vm"
+	"github.com/ur-technology/go-ur/ethdb"
+	"github.com/ur-technology/go-ur/rlp"
+)
+
+// vmResult is the observable outcome of a single VM execution, used to compare
+// the interpreter against the JIT.
+type vmResult struct {
+	ret   []byte
+	gas   *big.Int
+	err   error
+	logs  []byte
+	root  common.Hash
+	panic interface{}
+}
+
+// RunVmJitTest executes every test of the given VM test file through both the
+// interpreter and the JIT, failing on any divergence in output, gas, logs or
+// post state.
+func RunVmJitTest(p string, skipTests []string) error {
+	tests := make(map[string]VmTest)
+	if err := readJsonFile(p, &tests); err != nil {
+		return err
+	}
+	skipTest := make(map[string]bool, len(skipTests))
+	for _, name := range skipTests {
+		skipTest[name] = true
+	}
+	for _, name := range sortedVmTests(tests) {
+		if skipTest[name] {
+			continue
+		}
+		test := tests[name]
+		if err := DiffVm(test.Pre, vmTestEnv(test), test.Exec); err != nil {
+			return fmt.Errorf("%s %v", name, err)
+		}
+	}
+	return nil
+}
+
+// DiffVm executes the given call both through the interpreter and through the
+// JIT program cache, each starting from its own copy of the pre state, and
+// returns an error describing the first divergence between the two.
+func DiffVm(pre map[string]Account, env, exec map[string]string) error {
+	db, _ := ethdb.NewMemDatabase()
+	interpreted := execVm(makePreState(db, pre), env, exec, false)
+
+	db, _ = ethdb.NewMemDatabase()
+	compiled := execVm(makePreState(db, pre), env, exec, true)
+
+	switch {
+	case interpreted.panic != nil:
+		return fmt.Errorf("interpreter panicked: %v", interpreted.panic)
+	case compiled.panic != nil:
+		return fmt.Errorf("jit panicked: %v", compiled.panic)
+	case (interpreted.err == nil) != (compiled.err == nil):
+		return fmt.Errorf("error mismatch: interpreter %v, jit %v", interpreted.err, compiled.err)
+	case interpreted.err != nil:
+		// Failed executions consume all gas and revert the state, nothing
+		// else is observable.
+		return nil
+	case !bytes.Equal(interpreted.ret, compiled.ret):
+		return fmt.Errorf("return mismatch: interpreter %x, jit %x", interpreted.ret, compiled.ret)
+	case interpreted.gas.Cmp(compiled.gas) != 0:
+		return fmt.Errorf("gas mismatch: interpreter %v, jit %v", interpreted.gas, compiled.gas)
+	case !bytes.Equal(interpreted.logs, compiled.logs):
+		return fmt.Errorf("logs mismatch: interpreter %x, jit %x", interpreted.logs, compiled.logs)
+	case interpreted.root != compiled.root:
+		return fmt.Errorf("post state mismatch: interpreter %x, jit %x", interpreted.root, compiled.root)
+	}
+	return nil
+}
+
+// execVm runs a single VM test call on top of the given state, either via the
+// interpreter or via the JIT.
+func execVm(statedb *state.StateDB, env, exec map[string]string, jit bool) (res *vmResult) {
+	res = new(vmResult)
+	defer func() {
+		if r := recover(); r != nil {
+			res.panic = r
+		}
+	}()
+	var (
+		to    = common.HexToAddress(exec["address"])
+		from  = common.HexToAddress(exec["caller"])
+		data  = common.FromHex(exec["data"])
+		gas   = common.Big(exec["gas"])
+		price = common.Big(exec["gasPrice"])
+		value = common.Big(exec["value"])
+	)
+	vmenv := NewEnvFromMap(vmTestChainConfig(), statedb, env, exec)
+	vmenv.vmTest = true
+	vmenv.skipTransfer = true
+	vmenv.initial = true
+	if jit {
+		vmenv.jit = vm.NewProgramVm(vmenv)
+	}
+	res.ret, res.err = vmenv.Call(statedb.GetOrNewStateObject(from), to, data, gas, price, value)
+	res.gas = vmenv.Gas
+	res.logs, _ = rlp.EncodeToBytes(statedb.Logs())
+	res.root = statedb.IntermediateRoot(false)
+
+	return res
+}
+
+// sortedVmTests returns the names of the given tests in a stable order.
+func sortedVmTests(tests map[string]VmTest) []string {
+	names := make([]string, 0, len(tests))
+	for name := range tests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jitFuzzOps is the set of opcodes random programs are assembled from. PUSH
+// instructions are generated separately to control their immediates.
+var jitFuzzOps = []vm.OpCode{
+	vm.STOP, vm.ADD, vm.MUL, vm.SUB, vm.DIV, vm.SDIV, vm.MOD, vm.SMOD, vm.ADDMOD, vm.MULMOD, vm.EXP, vm.SIGNEXTEND,
+	vm.LT, vm.GT, vm.SLT, vm.SGT, vm.EQ, vm.ISZERO, vm.AND, vm.OR, vm.XOR, vm.NOT, vm.BYTE,
+	vm.SHA3, vm.ADDRESS, vm.ORIGIN, vm.CALLER, vm.CALLVALUE, vm.CALLDATALOAD, vm.CALLDATASIZE,
+	vm.CALLDATACOPY, vm.CODESIZE, vm.CODECOPY, vm.GASPRICE,
+	vm.BLOCKHASH, vm.COINBASE, vm.TIMESTAMP, vm.NUMBER, vm.DIFFICULTY, vm.GASLIMIT,
+	vm.POP, vm.MLOAD, vm.MSTORE, vm.MSTORE8, vm.SLOAD, vm.SSTORE, vm.JUMP, vm.JUMPI, vm.PC, vm.MSIZE, vm.GAS, vm.JUMPDEST,
+	vm.DUP1, vm.DUP2, vm.DUP3, vm.DUP4, vm.SWAP1, vm.SWAP2, vm.SWAP3, vm.SWAP4,
+	vm.LOG0, vm.LOG1, vm.LOG2, vm.RETURN, vm.SUICIDE,
+}
+
+// jitFuzzGasTableOps are opcodes whose cost is taken from the fork's gas table
+// by the interpreter but not by the JIT, a known divergence (see
+// VmJitSkipTests). They are only generated if explicitly requested.
+var jitFuzzGasTableOps = []vm.OpCode{
+	vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.CALL, vm.CALLCODE, vm.DELEGATECALL,
+}
+
+// RandomVmCode generates a random, mostly well formed program of the given
+// length from the given opcodes. Stack operands are biased towards small values so that memory
+// accesses, jumps and comparisons hit interesting code paths instead of
+// failing immediately on out of gas or invalid jump destinations.
+func RandomVmCode(rnd *rand.Rand, length int, ops []vm.OpCode) []byte {
+	code := make([]byte, 0, length+32)
+	for len(code) < length {
+		switch n := rnd.Intn(10); {
+		case n < 4:
+			// Push a small immediate: a memory offset, jump target or size
+			code = append(code, byte(vm.PUSH1), byte(rnd.Intn(length+1)))
+		case n < 5:
+			// Push a wide random immediate
+			size := rnd.Intn(32) + 1
+			code = append(code, byte(vm.PUSH1)+byte(size-1))
+			for i := 0; i < size; i++ {
+				code = append(code, byte(rnd.Intn(256)))
+			}
+		default:
+			code = append(code, byte(ops[rnd.Intn(len(ops))]))
+		}
+	}
+	return code
+}
+
+// FuzzVmJit executes the given number of random programs generated from the
+// given seed through both the interpreter and the JIT, returning an error for
+// the first divergence found, along with the offending program. Opcodes known
+// to diverge are only exercised if gasTable is set.
+func FuzzVmJit(seed int64, runs int, gasTable bool) error {
+	rnd := rand.New(rand.NewSource(seed))
+
+	ops := jitFuzzOps
+	if gasTable {
+		ops = append(append([]vm.OpCode{}, jitFuzzOps...), jitFuzzGasTableOps...)
+	}
+
+	var (
+		contract = "0x0f572e5295c57f15886f9b263e2f6d2d6c7b5ec6"
+		caller   = "0xcd1722f3947def4cf144679da39c4c32bdc35681"
+		env      = map[string]string{
+			"currentCoinbase":   "2adc25665018aa1fe0e6bc666dac8fc2697ff9ba",
+			"currentDifficulty": "256",
+			"currentGasLimit":   "1000000",
+			"currentNumber":     "1",
+			"currentTimestamp":  "1",
+			"previousHash":      "5e20a0453cecd065ea59c37ac63e079ee08998b6045136a8ce6635c7912ec0b6",
+		}
+	)
+	for i := 0; i < runs; i++ {
+		code := RandomVmCode(rnd, 16+rnd.Intn(112), ops)
+		data := make([]byte, rnd.Intn(64))
+		rnd.Read(data)
+
+		pre := map[string]Account{
+			contract: {
+				Balance: "100000000000000000000000",
+				Code:    common.ToHex(code),
+				Nonce:   "0",
+				Storage: map[string]string{"0x00": "0x01"},
+			},
+		}
+		exec := map[string]string{
+			"address":  contract,
+			"caller":   caller,
+			"origin":   caller,
+			"code":     common.ToHex(code),
+			"data":     common.ToHex(data),
+			"gas":      "100000",
+			"gasPrice": "100000000000000",
+			"value":    "1000000000000000000",
+		}
+		if err := DiffVm(pre, env, exec); err != nil {
+			return fmt.Errorf("run %d (seed %d), code %x, input %x: %v", i, seed, code, data, err)
+		}
+	}
+	return nil
+}
+
+// BenchVmJitTests benchmarks every test in the given VM test file, running it
+// through the interpreter and the JIT as separate sub-benchmarks.
+func BenchVmJitTests(p string, b *testing.B) error {
+	tests := make(map[string]VmTest)
+	if err := readJsonFile(p, &tests); err != nil {
+		return err
+	}
+	for _, name := range sortedVmTests(tests) {
+		test := tests[name]
+		env := vmTestEnv(test)
+
+		for _, jit := range []bool{false, true} {
+			kind := "interpreter"
+			if jit {
+				kind = "jit"
+			}
+			b.Run(name+"/"+kind, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					db, _ := ethdb.NewMemDatabase()
+					statedb := makePreState(db, test.Pre)
+					b.StartTimer()
+
+					if res := execVm(statedb, env, test.Exec, jit); res.panic != nil {
+						b.Fatalf("vm panicked: %v", res.panic)
+					}
+				}
+			})
+		}
+	}
+	return nil
+}
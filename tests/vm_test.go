@@ -19,6 +19,8 @@ package tests
 import (
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -136,3 +138,43 @@ func TestVMRandom(t *testing.T) {
 		}
 	}
 }
+
+func TestVmJitDifferential(t *testing.T) {
+	fns, _ := filepath.Glob(filepath.Join(vmTestDir, "*.json"))
+	for _, fn := range fns {
+		if testing.Short() && filepath.Base(fn) == "vmPerformanceTest.json" {
+			continue
+		}
+		if err := RunVmJitTest(fn, VmJitSkipTests); err != nil {
+			t.Errorf("%s: %v", filepath.Base(fn), err)
+		}
+	}
+}
+
+func TestVmJitFuzz(t *testing.T) {
+	seed, runs := int64(1), 1000
+	if testing.Short() {
+		runs = 100
+	}
+	if env := os.Getenv("JITFUZZSEED"); env != "" {
+		seed, _ = strconv.ParseInt(env, 10, 64)
+	}
+	if env := os.Getenv("JITFUZZRUNS"); env != "" {
+		runs, _ = strconv.Atoi(env)
+	}
+	if err := FuzzVmJit(seed, runs, os.Getenv("JITFUZZGASTABLE") == "true"); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkVmTests(b *testing.B) {
+	fns, _ := filepath.Glob(filepath.Join(vmTestDir, "*.json"))
+	for _, fn := range fns {
+		name := strings.TrimSuffix(filepath.Base(fn), ".json")
+		b.Run(name, func(b *testing.B) {
+			if err := BenchVmJitTests(fn, b); err != nil {
+				b.Error(err)
+			}
+		})
+	}
+}
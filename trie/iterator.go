@@ -16,7 +16,12 @@
 
 package trie
 
-import "github.com/ur-technology/go-ur/common"
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ur-technology/go-ur/common"
+)
 
 // Iterator is a key-value trie iterator that traverses a Trie.
 type Iterator struct {
@@ -222,3 +227,77 @@ func (it *NodeIterator) retrieve() bool {
 	}
 	return true
 }
+
+// Walk calls fn with the key-value pairs of the trie in iteration order, starting
+// at the first key not before origin, until fn returns false. Unlike the node
+// iterator it skips the parts of the trie before origin without resolving them,
+// making it suitable for serving ranges of the trie.
+func (t *Trie) Walk(origin []byte, fn func(key, value []byte) bool) error {
+	path := compactHexDecode(origin)
+	_, err := t.walk(t.root, nil, path[:len(path)-1], true, fn)
+	return err
+}
+
+// walk descends into n, found at the given path, returning whether to carry on.
+// As long as bound is set, the path is a prefix of origin and the entries of n
+// before origin are skipped.
+func (t *Trie) walk(n node, path, origin []byte, bound bool, fn func(key, value []byte) bool) (bool, error) {
+	switch n := n.(type) {
+	case nil:
+		return true, nil
+
+	case valueNode:
+		if bound && len(path) < len(origin) {
+			return true, nil
+		}
+		return fn(decodeCompact(path), n), nil
+
+	case *shortNode:
+		key := n.Key
+		if hasTerm(key) {
+			key = key[:len(key)-1]
+		}
+		if bound {
+			rest := origin[len(path):]
+			if len(rest) > len(key) {
+				rest = rest[:len(key)]
+			}
+			switch cmp := bytes.Compare(key, rest); {
+			case cmp < 0:
+				return true, nil
+			case cmp > 0 || len(rest) < len(key):
+				bound = false
+			}
+		}
+		return t.walk(n.Val, append(path, key...), origin, bound, fn)
+
+	case *fullNode:
+		start := 0
+		if bound && len(path) < len(origin) {
+			start = int(origin[len(path)])
+		} else {
+			bound = false
+		}
+		for i := start; i < len(n.Children); i++ {
+			child := path
+			if i < 16 {
+				child = append(path, byte(i))
+			}
+			ok, err := t.walk(n.Children[i], child, origin, bound && i == start, fn)
+			if !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case hashNode:
+		rn, err := t.resolveHash(n, path, nil)
+		if err != nil {
+			return false, err
+		}
+		return t.walk(rn, path, origin, bound, fn)
+
+	default:
+		panic(fmt.Sprintf("%T: invalid node: %v", n, n))
+	}
+}
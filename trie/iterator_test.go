@@ -17,6 +17,7 @@
 package trie
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/ur-technology/go-ur/common"
@@ -116,3 +117,51 @@ func TestNodeIteratorCoverage(t *testing.T) {
 		}
 	}
 }
+
+func TestWalk(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	trie, _ := New(common.Hash{}, db)
+	vals := make(map[string]*kv)
+	for i := 0; i < 1000; i++ {
+		value := &kv{randBytes(32), randBytes(20), false}
+		trie.Update(value.k, value.v)
+		vals[string(value.k)] = value
+	}
+	root, _ := trie.Commit()
+	trie, _ = New(root, db)
+
+	entries := sortedEntries(vals)
+	for _, start := range []int{0, 1, 499, 998, 999} {
+		// Walk both from an existing key and from the gap before it
+		for _, origin := range [][]byte{entries[start].k, decreaseKey(common.CopyBytes(entries[start].k))} {
+			var keys [][]byte
+			err := trie.Walk(origin, func(key, value []byte) bool {
+				if !bytes.Equal(value, vals[string(key)].v) {
+					t.Fatalf("value mismatch for key %x", key)
+				}
+				keys = append(keys, key)
+				return len(keys) < 100
+			})
+			if err != nil {
+				t.Fatalf("walk from %x failed: %v", origin, err)
+			}
+			want := entries[start:]
+			if len(want) > 100 {
+				want = want[:100]
+			}
+			if len(keys) != len(want) {
+				t.Fatalf("walk from %x: key count mismatch: have %d, want %d", origin, len(keys), len(want))
+			}
+			for i, key := range keys {
+				if !bytes.Equal(key, want[i].k) {
+					t.Fatalf("walk from %x: key %d mismatch: have %x, want %x", origin, i, key, want[i].k)
+				}
+			}
+		}
+	}
+	// Walking past the last key yields nothing
+	last := increaseKey(common.CopyBytes(entries[len(entries)-1].k))
+	if err := trie.Walk(last, func(key, value []byte) bool { t.Fatalf("unexpected key %x", key); return false }); err != nil {
+		t.Fatalf("walk past last key failed: %v", err)
+	}
+}
@@ -149,3 +149,366 @@ func get(tn node, key []byte) ([]byte, node) {
 	}
 	return nil, tn.(valueNode)
 }
+
+// proofSet is a set of proof nodes keyed by their hash. It can back a trie,
+// resolving the nodes of the proof on demand.
+type proofSet map[common.Hash][]byte
+
+func newProofSet(proof []rlp.RawValue) proofSet {
+	set := make(proofSet, len(proof))
+	sha := sha3.NewKeccak256()
+	for _, buf := range proof {
+		sha.Reset()
+		sha.Write(buf)
+		set[common.BytesToHash(sha.Sum(nil))] = buf
+	}
+	return set
+}
+
+func (set proofSet) Get(key []byte) ([]byte, error) {
+	return set[common.BytesToHash(key)], nil
+}
+
+func (set proofSet) Put(key, value []byte) error {
+	set[common.BytesToHash(key)] = common.CopyBytes(value)
+	return nil
+}
+
+// VerifyRangeProof checks that the given key-value pairs are all the entries of
+// the trie with the given root hash from origin up to the last key. The proof
+// must contain the merkle proofs of both origin and the last key (a single one
+// if they are equal), unless the range is the whole trie in which case it may
+// be empty. An empty range is proven by the proof of origin alone, which must
+// show that the trie holds no key at or after origin.
+//
+// Keys must be sorted in ascending order and all have the same length, as those
+// of the state tries do; empty values are not allowed. The returned flag tells
+// whether the trie contains more entries after the last key.
+func VerifyRangeProof(rootHash common.Hash, origin []byte, keys, values [][]byte, proof []rlp.RawValue) (more bool, err error) {
+	if len(keys) != len(values) {
+		return false, fmt.Errorf("inconsistent proof data, keys: %d, values: %d", len(keys), len(values))
+	}
+	for i := 1; i < len(keys); i++ {
+		if bytes.Compare(keys[i-1], keys[i]) >= 0 {
+			return false, errors.New("range is not monotonically increasing")
+		}
+	}
+	for _, value := range values {
+		if len(value) == 0 {
+			return false, errors.New("range contains empty value")
+		}
+	}
+	// Without a proof the range must make up the whole trie
+	if len(proof) == 0 {
+		trie := new(Trie)
+		for i, key := range keys {
+			trie.Update(key, values[i])
+		}
+		if hash := trie.Hash(); hash != rootHash {
+			return false, fmt.Errorf("invalid proof, want hash %x, got %x", rootHash, hash)
+		}
+		return false, nil
+	}
+	set := newProofSet(proof)
+
+	// An empty range must prove that there is nothing at or after origin
+	if len(keys) == 0 {
+		root, value, err := proofToPath(rootHash, nil, origin, set, true)
+		if err != nil {
+			return false, err
+		}
+		if value != nil || hasRightElement(root, origin) {
+			return false, errors.New("more entries available")
+		}
+		return false, nil
+	}
+	first, last := keys[0], keys[len(keys)-1]
+	if bytes.Compare(origin, first) > 0 {
+		return false, errors.New("range starts before origin")
+	}
+	// A single entry at origin is proven by its own merkle proof
+	if len(keys) == 1 && bytes.Equal(origin, first) {
+		root, value, err := proofToPath(rootHash, nil, first, set, false)
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(value, values[0]) {
+			return false, errors.New("correct proof but invalid data")
+		}
+		return hasRightElement(root, first), nil
+	}
+	// Otherwise rebuild the two edge paths, drop everything between them and
+	// fill the gap with the range, which must then hash to the root
+	root, _, err := proofToPath(rootHash, nil, origin, set, true)
+	if err != nil {
+		return false, err
+	}
+	root, _, err = proofToPath(rootHash, root, last, set, true)
+	if err != nil {
+		return false, err
+	}
+	empty, err := unsetInternal(root, origin, last)
+	if err != nil {
+		return false, err
+	}
+	trie := &Trie{root: root, db: set}
+	if empty {
+		trie.root = nil
+	}
+	for i, key := range keys {
+		if err := trie.TryUpdate(key, values[i]); err != nil {
+			return false, err
+		}
+	}
+	if hash := trie.Hash(); hash != rootHash {
+		return false, fmt.Errorf("invalid proof, want hash %x, got %x", rootHash, hash)
+	}
+	return hasRightElement(trie.root, last), nil
+}
+
+// proofToPath resolves the path of key from the proof nodes, linking them into
+// the trie rooted at root (resolved from the proof itself if nil). The nodes are
+// decoded without their hashes, so that the trie can be modified and rehashed.
+// The value at key is returned if the trie contains it; its absence is an error
+// unless allowed.
+func proofToPath(rootHash common.Hash, root node, key []byte, set proofSet, allowNonExistent bool) (node, []byte, error) {
+	resolve := func(hash common.Hash) (node, error) {
+		buf := set[hash]
+		if buf == nil {
+			return nil, fmt.Errorf("proof node %x missing", hash)
+		}
+		n, err := decodeNode(nil, buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("bad proof node %x: %v", hash, err)
+		}
+		return n, nil
+	}
+	if root == nil {
+		n, err := resolve(rootHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		root = n
+	}
+	var (
+		parent = root
+		child  node
+		rest   []byte
+		value  []byte
+		err    error
+	)
+	key = compactHexDecode(key)
+	for {
+		rest, child = step(parent, key)
+		switch cld := child.(type) {
+		case nil:
+			// The trie doesn't contain the key
+			if allowNonExistent {
+				return root, nil, nil
+			}
+			return nil, nil, errors.New("key not contained in the trie")
+		case *shortNode, *fullNode:
+			key, parent = rest, child
+			continue
+		case hashNode:
+			if child, err = resolve(common.BytesToHash(cld)); err != nil {
+				return nil, nil, err
+			}
+		case valueNode:
+			value = cld
+		}
+		// Link the resolved child into its parent
+		switch p := parent.(type) {
+		case *shortNode:
+			p.Val = child
+		case *fullNode:
+			p.Children[key[0]] = child
+		default:
+			return nil, nil, fmt.Errorf("%T: invalid proof path node", parent)
+		}
+		if value != nil {
+			return root, value, nil
+		}
+		key, parent = rest, child
+	}
+}
+
+// step descends one node along the path of key, returning the rest of the path
+// and the node reached, or nil if the trie doesn't contain the path.
+func step(n node, key []byte) ([]byte, node) {
+	switch n := n.(type) {
+	case *shortNode:
+		if len(key) < len(n.Key) || !bytes.Equal(n.Key, key[:len(n.Key)]) {
+			return nil, nil
+		}
+		return key[len(n.Key):], n.Val
+	case *fullNode:
+		return key[1:], n.Children[key[0]]
+	case hashNode:
+		return key, n
+	case valueNode:
+		return nil, n
+	}
+	return key, nil
+}
+
+// unsetInternal removes all the nodes strictly between the paths of left and
+// right, both of which must have been resolved from the proof. The two edge
+// paths are kept, except for the values at their ends. It returns whether the
+// whole trie has been removed.
+func unsetInternal(n node, left, right []byte) (bool, error) {
+	left, right = compactHexDecode(left), compactHexDecode(right)
+
+	// Step down to the fork point of the two paths. It's either a short node
+	// whose key doesn't match one of the paths, or a full node where the paths
+	// go separate ways (or end).
+	var (
+		pos    int
+		parent node
+
+		// Comparison of the paths with the key of the forking short node
+		shortForkLeft, shortForkRight int
+	)
+findFork:
+	for {
+		switch rn := n.(type) {
+		case *shortNode:
+			rn.flags = nodeFlag{dirty: true}
+			shortForkLeft = compareSegment(left[pos:], rn.Key)
+			shortForkRight = compareSegment(right[pos:], rn.Key)
+			if shortForkLeft != 0 || shortForkRight != 0 {
+				break findFork
+			}
+			parent = n
+			n, pos = rn.Val, pos+len(rn.Key)
+		case *fullNode:
+			rn.flags = nodeFlag{dirty: true}
+			if left[pos] != right[pos] || rn.Children[left[pos]] == nil {
+				break findFork
+			}
+			parent = n
+			n, pos = rn.Children[left[pos]], pos+1
+		default:
+			return false, fmt.Errorf("%T: invalid fork point", n)
+		}
+	}
+	switch rn := n.(type) {
+	case *shortNode:
+		// Both paths on the same side of the short node leave nothing in between
+		if shortForkLeft == shortForkRight {
+			return false, errors.New("empty range")
+		}
+		// The short node lies between the paths, drop it altogether
+		if shortForkLeft != 0 && shortForkRight != 0 {
+			if parent == nil {
+				return true, nil
+			}
+			parent.(*fullNode).Children[left[pos-1]] = nil
+			return false, nil
+		}
+		// Only one of the paths goes through the short node, which is dropped if
+		// it holds a value, or cleared on the side of the other path
+		if _, ok := rn.Val.(valueNode); ok {
+			if parent == nil {
+				return true, nil
+			}
+			parent.(*fullNode).Children[left[pos-1]] = nil
+			return false, nil
+		}
+		if shortForkRight != 0 {
+			return false, unset(rn, rn.Val, left[pos:], len(rn.Key), false)
+		}
+		return false, unset(rn, rn.Val, right[pos:], len(rn.Key), true)
+
+	case *fullNode:
+		for i := left[pos] + 1; i < right[pos]; i++ {
+			rn.Children[i] = nil
+		}
+		if err := unset(rn, rn.Children[left[pos]], left[pos:], 1, false); err != nil {
+			return false, err
+		}
+		return false, unset(rn, rn.Children[right[pos]], right[pos:], 1, true)
+
+	default:
+		return false, fmt.Errorf("%T: invalid fork point", n)
+	}
+}
+
+// unset removes the nodes on one side of the path of key below the fork point,
+// those on the left if removeLeft is set, those on the right otherwise, along
+// with the value the path ends in.
+func unset(parent node, child node, key []byte, pos int, removeLeft bool) error {
+	switch cld := child.(type) {
+	case *fullNode:
+		if removeLeft {
+			for i := 0; i < int(key[pos]); i++ {
+				cld.Children[i] = nil
+			}
+		} else {
+			for i := key[pos] + 1; i < 16; i++ {
+				cld.Children[i] = nil
+			}
+		}
+		cld.flags = nodeFlag{dirty: true}
+		return unset(cld, cld.Children[key[pos]], key, pos+1, removeLeft)
+
+	case *shortNode:
+		if len(key[pos:]) < len(cld.Key) || !bytes.Equal(cld.Key, key[pos:pos+len(cld.Key)]) {
+			// The path forks off here, the short node lies within the range if
+			// it's on the inner side of the path
+			cmp := bytes.Compare(cld.Key, key[pos:])
+			if (removeLeft && cmp < 0) || (!removeLeft && cmp > 0) {
+				parent.(*fullNode).Children[key[pos-1]] = nil
+			}
+			return nil
+		}
+		if _, ok := cld.Val.(valueNode); ok {
+			parent.(*fullNode).Children[key[pos-1]] = nil
+			return nil
+		}
+		cld.flags = nodeFlag{dirty: true}
+		return unset(cld, cld.Val, key, pos+len(cld.Key), removeLeft)
+
+	case nil:
+		// The path ended at the fork point, nothing to remove
+		return nil
+
+	default:
+		return fmt.Errorf("%T: invalid node on the edge path", child)
+	}
+}
+
+// compareSegment compares the segment of path matching the length of key with
+// the key of a short node.
+func compareSegment(path, key []byte) int {
+	if len(path) < len(key) {
+		return bytes.Compare(path, key)
+	}
+	return bytes.Compare(path[:len(key)], key)
+}
+
+// hasRightElement tells whether the trie resolved along the path of key holds
+// any entry after it.
+func hasRightElement(n node, key []byte) bool {
+	pos, key := 0, compactHexDecode(key)
+	for n != nil {
+		switch rn := n.(type) {
+		case *fullNode:
+			for i := key[pos] + 1; i < 16; i++ {
+				if rn.Children[i] != nil {
+					return true
+				}
+			}
+			n, pos = rn.Children[key[pos]], pos+1
+		case *shortNode:
+			if len(key)-pos < len(rn.Key) || !bytes.Equal(rn.Key, key[pos:pos+len(rn.Key)]) {
+				return bytes.Compare(rn.Key, key[pos:]) > 0
+			}
+			n, pos = rn.Val, pos+len(rn.Key)
+		default:
+			// Reached the value at key (or a node not resolved by the proof)
+			return false
+		}
+	}
+	return false
+}
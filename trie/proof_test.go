@@ -20,6 +20,7 @@ import (
 	"bytes"
 	crand "crypto/rand"
 	mrand "math/rand"
+	"sort"
 	"testing"
 	"time"
 
@@ -94,6 +95,148 @@ func mutateByte(b []byte) {
 	}
 }
 
+func TestRangeProof(t *testing.T) {
+	trie, vals := randomTrie(500)
+	root := trie.Hash()
+	entries := sortedEntries(vals)
+
+	for i := 0; i < 500; i++ {
+		start := mrand.Intn(len(entries))
+		end := start + 1 + mrand.Intn(len(entries)-start)
+
+		// Prove the range either from its first key or from a gap before it
+		origin := entries[start].k
+		if mrand.Intn(2) == 0 {
+			origin = decreaseKey(common.CopyBytes(origin))
+			if bytes.Compare(origin, entries[start].k) > 0 || (start > 0 && bytes.Compare(origin, entries[start-1].k) <= 0) {
+				origin = entries[start].k
+			}
+		}
+		keys, values := rangeData(entries[start:end])
+		proof := append(trie.Prove(origin), trie.Prove(keys[len(keys)-1])...)
+
+		more, err := VerifyRangeProof(root, origin, keys, values, proof)
+		if err != nil {
+			t.Fatalf("range [%d, %d): %v", start, end, err)
+		}
+		if more != (end < len(entries)) {
+			t.Fatalf("range [%d, %d): more flag mismatch: have %v", start, end, more)
+		}
+	}
+}
+
+func TestRangeProofWholeTrie(t *testing.T) {
+	trie, vals := randomTrie(100)
+	root := trie.Hash()
+	keys, values := rangeData(sortedEntries(vals))
+
+	if more, err := VerifyRangeProof(root, nil, keys, values, nil); err != nil || more {
+		t.Fatalf("unproven whole range: more %v, err %v", more, err)
+	}
+	if _, err := VerifyRangeProof(root, nil, keys[1:], values[1:], nil); err == nil {
+		t.Fatal("partial range accepted without proof")
+	}
+	proof := append(trie.Prove(keys[0]), trie.Prove(keys[len(keys)-1])...)
+	if more, err := VerifyRangeProof(root, keys[0], keys, values, proof); err != nil || more {
+		t.Fatalf("proven whole range: more %v, err %v", more, err)
+	}
+}
+
+func TestRangeProofEmpty(t *testing.T) {
+	trie, vals := randomTrie(100)
+	root := trie.Hash()
+	entries := sortedEntries(vals)
+
+	last := entries[len(entries)-1].k
+	origin := increaseKey(common.CopyBytes(last))
+	if more, err := VerifyRangeProof(root, origin, nil, nil, trie.Prove(origin)); err != nil || more {
+		t.Fatalf("empty range after last key: more %v, err %v", more, err)
+	}
+	origin = decreaseKey(common.CopyBytes(last))
+	if _, err := VerifyRangeProof(root, origin, nil, nil, trie.Prove(origin)); err == nil {
+		t.Fatal("empty range accepted with entries after origin")
+	}
+}
+
+func TestBadRangeProof(t *testing.T) {
+	trie, vals := randomTrie(500)
+	root := trie.Hash()
+	entries := sortedEntries(vals)
+
+	for i := 0; i < 500; i++ {
+		start := mrand.Intn(len(entries) - 3)
+		end := start + 3 + mrand.Intn(len(entries)-start-3)
+
+		keys, values := rangeData(entries[start:end])
+		proof := append(trie.Prove(keys[0]), trie.Prove(keys[len(keys)-1])...)
+
+		switch mrand.Intn(4) {
+		case 0:
+			// Drop an inner entry
+			index := 1 + mrand.Intn(len(keys)-2)
+			keys = append(keys[:index:index], keys[index+1:]...)
+			values = append(values[:index:index], values[index+1:]...)
+		case 1:
+			// Modify a value
+			index := mrand.Intn(len(keys))
+			values[index] = randBytes(20)
+		case 2:
+			// Swap two entries
+			index := mrand.Intn(len(keys) - 1)
+			keys[index], keys[index+1] = keys[index+1], keys[index]
+		case 3:
+			// Drop the first entry, still proving from its key
+			keys, values = keys[1:], values[1:]
+		}
+		if _, err := VerifyRangeProof(root, entries[start].k, keys, values, proof); err == nil {
+			t.Fatalf("range [%d, %d): bad range accepted", start, end)
+		}
+	}
+}
+
+func sortedEntries(vals map[string]*kv) []*kv {
+	entries := make([]*kv, 0, len(vals))
+	for _, kv := range vals {
+		entries = append(entries, kv)
+	}
+	sort.Sort(kvSlice(entries))
+	return entries
+}
+
+func rangeData(entries []*kv) (keys, values [][]byte) {
+	for _, kv := range entries {
+		keys = append(keys, kv.k)
+		values = append(values, common.CopyBytes(kv.v))
+	}
+	return keys, values
+}
+
+func increaseKey(key []byte) []byte {
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i]++
+		if key[i] != 0x0 {
+			break
+		}
+	}
+	return key
+}
+
+func decreaseKey(key []byte) []byte {
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i]--
+		if key[i] != 0xff {
+			break
+		}
+	}
+	return key
+}
+
+type kvSlice []*kv
+
+func (s kvSlice) Len() int           { return len(s) }
+func (s kvSlice) Less(i, j int) bool { return bytes.Compare(s[i].k, s[j].k) < 0 }
+func (s kvSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
 func BenchmarkProve(b *testing.B) {
 	trie, vals := randomTrie(100)
 	var keys []string